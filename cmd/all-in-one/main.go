@@ -33,6 +33,7 @@ import (
 	agentRep "github.com/kjschnei001/jaeger/cmd/agent/app/reporter"
 	agentGrpcRep "github.com/kjschnei001/jaeger/cmd/agent/app/reporter/grpc"
 	"github.com/kjschnei001/jaeger/cmd/all-in-one/setupcontext"
+	"github.com/kjschnei001/jaeger/cmd/badger"
 	collectorApp "github.com/kjschnei001/jaeger/cmd/collector/app"
 	collectorFlags "github.com/kjschnei001/jaeger/cmd/collector/app/flags"
 	"github.com/kjschnei001/jaeger/cmd/docs"
@@ -108,6 +109,10 @@ by default uses only in-memory database.`,
 			if err := storageFactory.Initialize(metricsFactory, logger); err != nil {
 				logger.Fatal("Failed to init storage factory", zap.Error(err))
 			}
+			for route, handler := range storageFactory.AdminHandlers() {
+				logger.Info("Mounting storage handler on admin server", zap.String("route", route))
+				svc.Admin.Handle(route, handler)
+			}
 
 			spanReader, err := storageFactory.CreateSpanReader()
 			if err != nil {
@@ -156,18 +161,27 @@ by default uses only in-memory database.`,
 				logger.Fatal("Failed to configure query service", zap.Error(err))
 			}
 
+			var dependencyWriter dependencystore.Writer
+			if cOpts.Dependencies.Enabled {
+				if dependencyWriter, err = storageFactory.CreateDependencyWriter(); err != nil {
+					logger.Fatal("Failed to create dependency writer", zap.Error(err))
+				}
+			}
+
 			tm := tenancy.NewManager(&cOpts.GRPC.Tenancy)
 
 			// collector
 			c := collectorApp.New(&collectorApp.CollectorParams{
-				ServiceName:    "jaeger-collector",
-				Logger:         logger,
-				MetricsFactory: metricsFactory,
-				SpanWriter:     spanWriter,
-				StrategyStore:  strategyStore,
-				Aggregator:     aggregator,
-				HealthCheck:    svc.HC(),
-				TenancyMgr:     tm,
+				ServiceName:        "jaeger-collector",
+				Logger:             logger,
+				MetricsFactory:     metricsFactory,
+				SpanWriter:         spanWriter,
+				StrategyStore:      strategyStore,
+				Aggregator:         aggregator,
+				HealthCheck:        svc.HC(),
+				TenancyMgr:         tm,
+				SpanMetricsFactory: svc.MetricsFactory,
+				DependencyWriter:   dependencyWriter,
 			})
 			if err := c.Start(cOpts); err != nil {
 				log.Fatal(err)
@@ -222,6 +236,7 @@ by default uses only in-memory database.`,
 	command.AddCommand(env.Command())
 	command.AddCommand(docs.Command(v))
 	command.AddCommand(status.Command(v, ports.CollectorAdminHTTP))
+	command.AddCommand(badger.Command(v, ports.CollectorAdminHTTP))
 
 	config.AddFlags(
 		v,
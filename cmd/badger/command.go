@@ -0,0 +1,165 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	badgerStorage "github.com/kjschnei001/jaeger/plugin/storage/badger"
+	"github.com/kjschnei001/jaeger/ports"
+)
+
+const (
+	backupHTTPHostPort    = "backup.http.host-port"
+	backupFile            = "backup.file"
+	backupSince           = "backup.since"
+	restoreFiles          = "restore.files"
+	restoreKeyDirectory   = "restore.directory-key"
+	restoreValueDirectory = "restore.directory-value"
+)
+
+// Command for backing up and restoring the badger storage.
+func Command(v *viper.Viper, adminPort int) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "badger",
+		Short: "Back up or restore the badger storage.",
+		Long:  `Back up the badger storage of a running Jaeger component, or restore a backup into new directories.`,
+	}
+	c.AddCommand(backupCommand(v, adminPort))
+	c.AddCommand(restoreCommand(v))
+	return c
+}
+
+func backupCommand(v *viper.Viper, adminPort int) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the badger storage.",
+		Long: `Back up the badger storage of a running Jaeger component through its admin server. ` +
+			`The backup is incremental when the version printed by the previous backup is given. ` +
+			`The component must run with --badger.admin-backup.enabled.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := v.GetString(backupFile)
+			if file == "" {
+				return fmt.Errorf("the --%s flag is required", backupFile)
+			}
+			since, err := backup(convert(v.GetString(backupHTTPHostPort)), v.GetUint64(backupSince), file)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Backed up the badger storage to %s, use --%s=%s for the next incremental backup\n", file, backupSince, since)
+			return nil
+		},
+	}
+	c.Flags().AddGoFlagSet(backupFlags(&flag.FlagSet{}, adminPort))
+	v.BindPFlags(c.Flags())
+	return c
+}
+
+func backupFlags(flagSet *flag.FlagSet, adminPort int) *flag.FlagSet {
+	adminPortStr := ports.PortToHostPort(adminPort)
+	flagSet.String(backupHTTPHostPort, adminPortStr, fmt.Sprintf(
+		"The host:port (e.g. 127.0.0.1%s or %s) of the admin server", adminPortStr, adminPortStr))
+	flagSet.String(backupFile, "", "The file to write the backup to")
+	flagSet.Uint64(backupSince, 0, "The version printed by the previous backup, to back up the changes since then only")
+	return flagSet
+}
+
+// backup writes the backup to the file, and returns the version for the next incremental backup.
+func backup(url string, since uint64, file string) (string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s%s?%s=%d", url, badgerStorage.BackupPath, badgerStorage.BackupSinceParam, since))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("abnormal value of http status code: %v: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// the backup is written to a temporary file first, not to leave a partial backup
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, resp.Body)
+	if err = errors.Join(err, tmp.Close()); err != nil {
+		return "", err
+	}
+	if msg := resp.Trailer.Get(badgerStorage.BackupErrorTrailer); msg != "" {
+		return "", fmt.Errorf("the backup failed: %s", msg)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", err
+	}
+	return resp.Trailer.Get(badgerStorage.BackupNextSinceTrailer), nil
+}
+
+func restoreCommand(v *viper.Viper) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a badger storage backup.",
+		Long: `Restore badger storage backups into new directories, to be used by a Jaeger component ` +
+			`with the --badger.directory-key and --badger.directory-value flags.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := v.GetString(restoreFiles)
+			keyDirectory, valueDirectory := v.GetString(restoreKeyDirectory), v.GetString(restoreValueDirectory)
+			if files == "" || keyDirectory == "" || valueDirectory == "" {
+				return fmt.Errorf("the --%s, --%s and --%s flags are required", restoreFiles, restoreKeyDirectory, restoreValueDirectory)
+			}
+			var backups []io.Reader
+			for _, file := range strings.Split(files, ",") {
+				f, err := os.Open(strings.TrimSpace(file))
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				backups = append(backups, f)
+			}
+			if err := badgerStorage.Restore(keyDirectory, valueDirectory, backups...); err != nil {
+				return err
+			}
+			fmt.Printf("Restored the badger storage to %s and %s\n", keyDirectory, valueDirectory)
+			return nil
+		},
+	}
+	c.Flags().AddGoFlagSet(restoreFlags(&flag.FlagSet{}))
+	v.BindPFlags(c.Flags())
+	return c
+}
+
+func restoreFlags(flagSet *flag.FlagSet) *flag.FlagSet {
+	flagSet.String(restoreFiles, "", "Comma-separated list of the backup files to restore, the full backup first then the incremental ones in order")
+	flagSet.String(restoreKeyDirectory, "", "The new directory of the keys (indexes), which must be empty or not exist")
+	flagSet.String(restoreValueDirectory, "", "The new directory of the values (spans), which must be empty or not exist")
+	return flagSet
+}
+
+func convert(httpHostPort string) string {
+	if strings.HasPrefix(httpHostPort, ":") {
+		return fmt.Sprintf("http://127.0.0.1%s", httpHostPort)
+	}
+	return fmt.Sprintf("http://%s", httpHostPort)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	badgerStorage "github.com/kjschnei001/jaeger/plugin/storage/badger"
+)
+
+func run(t *testing.T, args ...string) error {
+	cmd := Command(viper.New(), 80)
+	cmd.SetArgs(args)
+	return cmd.Execute()
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	f := badgerStorage.NewFactory()
+	f.Options.Primary.AdminBackup = true
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	defer f.Close()
+	mux := http.NewServeMux()
+	for path, handler := range f.AdminHandlers() {
+		mux.Handle(path, handler)
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	hostPort := strings.TrimPrefix(ts.URL, "http://")
+
+	dir := t.TempDir()
+	full, incremental := filepath.Join(dir, "full"), filepath.Join(dir, "incremental")
+	require.NoError(t, run(t, "backup", "--backup.http.host-port="+hostPort, "--backup.file="+full))
+	require.NoError(t, run(t, "backup", "--backup.http.host-port="+hostPort, "--backup.file="+incremental, "--backup.since=1"))
+	assert.FileExists(t, full)
+	assert.FileExists(t, incremental)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "the temporary files are removed")
+
+	keys, values := filepath.Join(dir, "keys"), filepath.Join(dir, "values")
+	require.NoError(t, run(t, "restore",
+		"--restore.files="+full+", "+incremental,
+		"--restore.directory-key="+keys,
+		"--restore.directory-value="+values,
+	))
+	assert.DirExists(t, keys)
+	assert.DirExists(t, values)
+
+	err = run(t, "restore", "--restore.files="+full, "--restore.directory-key="+keys, "--restore.directory-value="+values)
+	assert.ErrorContains(t, err, "not empty")
+}
+
+func TestBackupErrors(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	hostPort := strings.TrimPrefix(ts.URL, "http://")
+	file := filepath.Join(t.TempDir(), "backup")
+
+	assert.EqualError(t, run(t, "backup", "--backup.http.host-port="+hostPort), "the --backup.file flag is required")
+	err := run(t, "backup", "--backup.http.host-port="+hostPort, "--backup.file="+file)
+	assert.EqualError(t, err, "abnormal value of http status code: 404: 404 page not found")
+	assert.NoFileExists(t, file)
+
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", badgerStorage.BackupErrorTrailer)
+		w.Write([]byte("partial"))
+		w.Header().Set(badgerStorage.BackupErrorTrailer, "disk failure")
+	}))
+	defer ts.Close()
+	err = run(t, "backup", "--backup.http.host-port="+strings.TrimPrefix(ts.URL, "http://"), "--backup.file="+file)
+	assert.EqualError(t, err, "the backup failed: disk failure")
+	assert.NoFileExists(t, file)
+
+	err = run(t, "backup", "--backup.http.host-port=:1", "--backup.file="+file)
+	assert.Error(t, err)
+	assert.Equal(t, "http://127.0.0.1:1", convert(":1"))
+}
+
+func TestRestoreErrors(t *testing.T) {
+	assert.EqualError(t, run(t, "restore"),
+		"the --restore.files, --restore.directory-key and --restore.directory-value flags are required")
+	dir := t.TempDir()
+	err := run(t, "restore", "--restore.files="+filepath.Join(dir, "missing"), "--restore.directory-key="+dir, "--restore.directory-value="+dir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/config"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+)
+
+const (
+	primaryNamespace = "clickhouse"
+	archiveNamespace = "clickhouse-archive"
+	printFlag        = "print"
+)
+
+func main() {
+	logger, _ := zap.NewProduction()
+	v := viper.New()
+	opts := clickhouse.NewOptions(primaryNamespace, archiveNamespace)
+
+	command := &cobra.Command{
+		Use:   "jaeger-clickhouse-schema",
+		Short: "Jaeger clickhouse-schema creates the ClickHouse tables storing spans",
+		Long: "Jaeger clickhouse-schema creates the ClickHouse database and tables storing spans and dependencies, " +
+			"and the archive tables if the archive storage is enabled. Existing tables are left unchanged.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.InitFromViper(v)
+			primary := opts.GetPrimary()
+			archive := opts.Get(archiveNamespace)
+
+			if v.GetBool(printFlag) {
+				if err := printStatements(schema.NewTables(primary.Database, primary.TablePrefix, false), primary.TTL); err != nil {
+					return err
+				}
+				if !archive.Enabled {
+					return nil
+				}
+				return printStatements(schema.NewTables(archive.Database, archive.TablePrefix, true), archive.TTL)
+			}
+
+			client, err := primary.NewClient(logger)
+			if err != nil {
+				return err
+			}
+			tables := schema.NewTables(primary.Database, primary.TablePrefix, false)
+			if err := schema.Create(context.Background(), client, tables, primary.TTL); err != nil {
+				return err
+			}
+			logger.Info("Created the ClickHouse schema", zap.String("database", primary.Database), zap.String("table-prefix", primary.TablePrefix))
+			if !archive.Enabled {
+				return nil
+			}
+
+			client, err = archive.NewClient(logger)
+			if err != nil {
+				return err
+			}
+			tables = schema.NewTables(archive.Database, archive.TablePrefix, true)
+			if err := schema.Create(context.Background(), client, tables, archive.TTL); err != nil {
+				return err
+			}
+			logger.Info("Created the ClickHouse archive schema", zap.String("database", archive.Database), zap.String("table-prefix", archive.TablePrefix))
+			return nil
+		},
+	}
+
+	config.AddFlags(
+		v,
+		command,
+		opts.AddFlags,
+		func(flagSet *flag.FlagSet) {
+			flagSet.Bool(printFlag, false, "Print the statements creating the schema instead of executing them")
+		},
+	)
+
+	if err := command.Execute(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func printStatements(tables schema.Tables, ttl time.Duration) error {
+	statements, err := schema.Statements(tables, ttl)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		fmt.Printf("%s;\n\n", statement)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -29,10 +30,12 @@ import (
 	"github.com/kjschnei001/jaeger/cmd/collector/app/handler"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/sampling/strategystore"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/server"
 	"github.com/kjschnei001/jaeger/pkg/healthcheck"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
 	"github.com/kjschnei001/jaeger/pkg/tenancy"
+	"github.com/kjschnei001/jaeger/storage/dependencystore"
 	"github.com/kjschnei001/jaeger/storage/spanstore"
 )
 
@@ -54,6 +57,10 @@ type Collector struct {
 	spanProcessor  processor.SpanProcessor
 	spanHandlers   *SpanHandlers
 	tenancyMgr     *tenancy.Manager
+	// spanMetricsFactory is not namespaced, see spanMetrics
+	spanMetricsFactory metrics.Factory
+	dependencyWriter   dependencystore.Writer
+	spanReader         spanstore.Reader
 
 	// state, read only
 	hServer                    *http.Server
@@ -63,6 +70,8 @@ type Collector struct {
 	tlsGRPCCertWatcherCloser   io.Closer
 	tlsHTTPCertWatcherCloser   io.Closer
 	tlsZipkinCertWatcherCloser io.Closer
+	spanFilterCloser           io.Closer
+	dependencyAggregator       *dependencyAggregator
 }
 
 // CollectorParams to construct a new Jaeger Collector.
@@ -75,19 +84,31 @@ type CollectorParams struct {
 	Aggregator     strategystore.Aggregator
 	HealthCheck    *healthcheck.HealthCheck
 	TenancyMgr     *tenancy.Manager
+	// SpanMetricsFactory receives the span metrics, it must not be namespaced for their names to
+	// match the queries of the Prometheus metrics storage. MetricsFactory is used when nil.
+	SpanMetricsFactory metrics.Factory
+	// DependencyWriter receives the service dependencies aggregated from the spans, it is
+	// required when the dependencies are enabled.
+	DependencyWriter dependencystore.Writer
+	// SpanReader looks up the parent spans received by the other collectors when the
+	// dependencies are enabled, the spans without parent are not counted when nil.
+	SpanReader spanstore.Reader
 }
 
 // New constructs a new collector component, ready to be started
 func New(params *CollectorParams) *Collector {
 	return &Collector{
-		serviceName:    params.ServiceName,
-		logger:         params.Logger,
-		metricsFactory: params.MetricsFactory,
-		spanWriter:     params.SpanWriter,
-		strategyStore:  params.StrategyStore,
-		aggregator:     params.Aggregator,
-		hCheck:         params.HealthCheck,
-		tenancyMgr:     params.TenancyMgr,
+		serviceName:        params.ServiceName,
+		logger:             params.Logger,
+		metricsFactory:     params.MetricsFactory,
+		spanWriter:         params.SpanWriter,
+		strategyStore:      params.StrategyStore,
+		aggregator:         params.Aggregator,
+		hCheck:             params.HealthCheck,
+		tenancyMgr:         params.TenancyMgr,
+		spanMetricsFactory: params.SpanMetricsFactory,
+		dependencyWriter:   params.DependencyWriter,
+		spanReader:         params.SpanReader,
 	}
 }
 
@@ -100,13 +121,62 @@ func (c *Collector) Start(options *flags.CollectorOptions) error {
 		MetricsFactory: c.metricsFactory,
 		TenancyMgr:     c.tenancyMgr,
 	}
+	if options.AttributeRulesFile != "" {
+		rules, err := sanitizer.LoadAttributeRules(options.AttributeRulesFile)
+		if err != nil {
+			return err
+		}
+		var hashKey []byte
+		if options.AttributeHashKeyFile != "" {
+			if hashKey, err = sanitizer.LoadHashKey(options.AttributeHashKeyFile); err != nil {
+				return err
+			}
+		}
+		if handlerBuilder.AttributeSanitizer, err = sanitizer.NewAttributeSanitizer(rules, hashKey); err != nil {
+			return fmt.Errorf("invalid attribute rules file %v: %w", options.AttributeRulesFile, err)
+		}
+	}
+	if options.SpanFilterFile != "" {
+		spanFilter, err := NewSpanFilter(options.SpanFilterFile, c.metricsFactory, c.logger)
+		if err != nil {
+			return fmt.Errorf("could not load span filter: %w", err)
+		}
+		handlerBuilder.SpanFilter = spanFilter.Filter
+		c.spanFilterCloser = spanFilter
+	}
 
 	var additionalProcessors []ProcessSpan
 	if c.aggregator != nil {
 		additionalProcessors = append(additionalProcessors, handleRootSpan(c.aggregator, c.logger))
 	}
+	if options.SpanMetricsEnabled {
+		spanMetricsFactory := c.spanMetricsFactory
+		if spanMetricsFactory == nil {
+			spanMetricsFactory = c.metricsFactory
+		}
+		additionalProcessors = append(additionalProcessors, newSpanMetrics(spanMetricsFactory).processSpan)
+	}
+	if options.Dependencies.Enabled {
+		if c.dependencyWriter == nil {
+			return errors.New("dependencies are enabled but the storage does not support writing them")
+		}
+		c.dependencyAggregator = newDependencyAggregator(options.Dependencies, c.dependencyWriter, c.spanReader, c.metricsFactory, c.logger)
+		c.dependencyAggregator.start()
+		additionalProcessors = append(additionalProcessors, c.dependencyAggregator.processSpan)
+	}
 
-	c.spanProcessor = handlerBuilder.BuildSpanProcessor(additionalProcessors...)
+	spanProcessor, err := handlerBuilder.BuildSpanProcessor(additionalProcessors...)
+	if err != nil {
+		return fmt.Errorf("could not create span processor: %w", err)
+	}
+	c.spanProcessor = spanProcessor
+	if options.RateLimitsFile != "" {
+		rateLimitingProcessor, err := NewRateLimitingProcessor(c.spanProcessor, options.RateLimitsFile, c.metricsFactory, c.logger)
+		if err != nil {
+			return fmt.Errorf("could not load rate limits: %w", err)
+		}
+		c.spanProcessor = rateLimitingProcessor
+	}
 	c.spanHandlers = handlerBuilder.BuildHandlers(c.spanProcessor)
 
 	grpcServer, err := server.StartGRPCServer(&server.GRPCServerParams{
@@ -158,7 +228,7 @@ func (c *Collector) Start(options *flags.CollectorOptions) error {
 	c.zkServer = zkServer
 
 	if options.OTLP.Enabled {
-		otlpReceiver, err := handler.StartOTLPReceiver(options, c.logger, c.spanProcessor, c.tenancyMgr)
+		otlpReceiver, err := handler.StartOTLPReceiver(options, c.logger, c.spanProcessor, c.tenancyMgr, c.metricsFactory)
 		if err != nil {
 			return fmt.Errorf("could not start OTLP receiver: %w", err)
 		}
@@ -210,8 +280,18 @@ func (c *Collector) Close() error {
 		defer cancel()
 	}
 
-	if err := c.spanProcessor.Close(); err != nil {
-		c.logger.Error("failed to close span processor.", zap.Error(err))
+	// the span processor does not exist if Start failed to create it
+	if c.spanProcessor != nil {
+		if err := c.spanProcessor.Close(); err != nil {
+			c.logger.Error("failed to close span processor.", zap.Error(err))
+		}
+	}
+
+	// the links of the spans processed above are written by Close
+	if c.dependencyAggregator != nil {
+		if err := c.dependencyAggregator.Close(); err != nil {
+			c.logger.Error("failed to close dependency aggregator.", zap.Error(err))
+		}
 	}
 
 	// aggregator does not exist for all strategy stores. only Close() if exists.
@@ -225,6 +305,9 @@ func (c *Collector) Close() error {
 	_ = c.tlsGRPCCertWatcherCloser.Close()
 	_ = c.tlsHTTPCertWatcherCloser.Close()
 	_ = c.tlsZipkinCertWatcherCloser.Close()
+	if c.spanFilterCloser != nil {
+		_ = c.spanFilterCloser.Close()
+	}
 
 	return nil
 }
@@ -17,6 +17,8 @@ package app
 import (
 	"context"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -118,6 +120,28 @@ func TestCollector_StartErrors(t *testing.T) {
 	options = optionsForEphemeralPorts()
 	options.OTLP.HTTP.HostPort = ":-1"
 	run("OTLP/HTTP", options, "could not start OTLP receiver")
+
+	options = optionsForEphemeralPorts()
+	options.RateLimitsFile = "/does/not/exist.json"
+	run("RateLimits", options, "could not load rate limits")
+
+	options = optionsForEphemeralPorts()
+	options.AttributeRulesFile = "/does/not/exist.json"
+	run("AttributeRules", options, "cannot read attribute rules file")
+
+	options = optionsForEphemeralPorts()
+	options.AttributeRulesFile = filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(options.AttributeRulesFile, []byte(`{"rules": []}`), 0o600))
+	options.AttributeHashKeyFile = "/does/not/exist"
+	run("AttributeHashKey", options, "cannot read attribute hash key file")
+
+	options = optionsForEphemeralPorts()
+	options.SpanFilterFile = "/does/not/exist.yaml"
+	run("SpanFilter", options, "could not load span filter")
+
+	options = optionsForEphemeralPorts()
+	options.Dependencies.Enabled = true
+	run("Dependencies", options, "storage does not support writing them")
 }
 
 type mockStrategyStore struct{}
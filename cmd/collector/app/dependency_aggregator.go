@@ -0,0 +1,337 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/pkg/tenancy"
+	"github.com/kjschnei001/jaeger/storage/dependencystore"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const (
+	// parentLookupWorkers is the number of concurrent lookups of the parent spans in the span storage
+	parentLookupWorkers = 8
+	// parentLookupTimeout bounds each lookup of the parent spans in the span storage
+	parentLookupTimeout = 5 * time.Second
+)
+
+type dependencyAggregatorMetrics struct {
+	// BufferedTraces is the number of traces whose spans are remembered
+	BufferedTraces metrics.Gauge `metric:"dependencies.buffered_traces"`
+	// EvictedTraces is the number of traces forgotten before ParentWait because too many traces were buffered
+	EvictedTraces metrics.Counter `metric:"dependencies.evicted_traces"`
+	// OrphanSpans is the number of spans whose parent span was neither received in time nor found in the span storage
+	OrphanSpans metrics.Counter `metric:"dependencies.orphan_spans"`
+	// LookedUpSpans is the number of spans whose parent span was found in the span storage
+	LookedUpSpans metrics.Counter `metric:"dependencies.looked_up_spans"`
+	// LookupErrors is the number of failed lookups of the parent spans in the span storage
+	LookupErrors metrics.Counter `metric:"dependencies.lookup_errors"`
+	// LinksWritten is the number of dependency links written to storage
+	LinksWritten metrics.Counter `metric:"dependencies.links_written"`
+	// WriteErrors is the number of failed writes of the dependency links, which are retried at the next flush
+	WriteErrors metrics.Counter `metric:"dependencies.write_errors"`
+}
+
+type dependencyTraceKey struct {
+	tenant  string
+	traceID model.TraceID
+}
+
+// pendingSpan is a span waiting for its parent span
+type pendingSpan struct {
+	parentID model.SpanID
+	service  string
+}
+
+type dependencyTrace struct {
+	key      dependencyTraceKey
+	services map[model.SpanID]string
+	pending  []pendingSpan
+	deadline time.Time
+}
+
+type dependencyLinkKey struct {
+	parent string
+	child  string
+}
+
+// dependencyAggregator counts the calls between services from the parent/child relationships
+// of the spans, and periodically writes the counts to storage. The spans of each trace are
+// remembered for ParentWait after its last span, for the spans received before their parent.
+//
+// Each collector writes the links of the spans it received, as partial aggregates which are
+// summed by the query service. When the spans of a trace are sent to different collectors,
+// a span can be received without its parent: the pairs of a parent span ID and a child service
+// left when the trace is forgotten are joined with the spans written to the span storage by
+// the other collectors, where the service of the parent span is looked up.
+type dependencyAggregator struct {
+	options    flags.DependenciesOptions
+	writer     dependencystore.Writer
+	spanReader spanstore.Reader // nil if the parent spans are not looked up
+	logger     *zap.Logger
+	metrics    dependencyAggregatorMetrics
+	timeNow    func() time.Time
+
+	mu         sync.Mutex
+	traces     map[dependencyTraceKey]*list.Element
+	byArrival  *list.List // of *dependencyTrace, the first trace has the earliest deadline
+	links      map[dependencyLinkKey]uint64
+	unresolved []*dependencyTrace // forgotten traces with pending spans, whose parents are looked up
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newDependencyAggregator(
+	options flags.DependenciesOptions,
+	writer dependencystore.Writer,
+	spanReader spanstore.Reader,
+	metricsFactory metrics.Factory,
+	logger *zap.Logger,
+) *dependencyAggregator {
+	a := &dependencyAggregator{
+		options:    options,
+		writer:     writer,
+		spanReader: spanReader,
+		logger:     logger,
+		timeNow:    time.Now,
+		traces:     make(map[dependencyTraceKey]*list.Element),
+		byArrival:  list.New(),
+		links:      make(map[dependencyLinkKey]uint64),
+		stopCh:     make(chan struct{}),
+	}
+	metrics.MustInit(&a.metrics, metricsFactory, nil)
+	return a
+}
+
+// start starts the periodic expiry of the traces and writes of the links.
+func (a *dependencyAggregator) start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		expireTicker := time.NewTicker(a.options.ParentWait)
+		defer expireTicker.Stop()
+		flushTicker := time.NewTicker(a.options.FlushInterval)
+		defer flushTicker.Stop()
+		for {
+			select {
+			case <-expireTicker.C:
+				a.expire()
+			case <-flushTicker.C:
+				a.flush()
+			case <-a.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// processSpan is a ProcessSpan counting the link between the span and its parent span,
+// and between the span and its children received before it.
+func (a *dependencyAggregator) processSpan(span *model.Span, tenant string) {
+	service := ""
+	if span.Process != nil {
+		service = span.Process.ServiceName
+	}
+	key := dependencyTraceKey{tenant: tenant, traceID: span.TraceID}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	trace := a.getTrace(key)
+	trace.services[span.SpanID] = service
+
+	pending := trace.pending[:0]
+	for _, child := range trace.pending {
+		if child.parentID == span.SpanID {
+			a.addLink(service, child.service)
+		} else {
+			pending = append(pending, child)
+		}
+	}
+	trace.pending = pending
+
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		if parentService, ok := trace.services[parentID]; ok {
+			a.addLink(parentService, service)
+		} else {
+			trace.pending = append(trace.pending, pendingSpan{parentID: parentID, service: service})
+		}
+	}
+}
+
+// getTrace returns the buffered trace, or buffers a new one, the lock must be held.
+func (a *dependencyAggregator) getTrace(key dependencyTraceKey) *dependencyTrace {
+	deadline := a.timeNow().Add(a.options.ParentWait)
+	if elem, ok := a.traces[key]; ok {
+		trace := elem.Value.(*dependencyTrace)
+		trace.deadline = deadline
+		a.byArrival.MoveToBack(elem)
+		return trace
+	}
+	if len(a.traces) >= a.options.MaxTraces {
+		a.metrics.EvictedTraces.Inc(1)
+		a.removeOldest()
+	}
+	trace := &dependencyTrace{
+		key:      key,
+		services: make(map[model.SpanID]string),
+		deadline: deadline,
+	}
+	a.traces[key] = a.byArrival.PushBack(trace)
+	return trace
+}
+
+// addLink counts a call between two services, the lock must be held.
+func (a *dependencyAggregator) addLink(parent, child string) {
+	// like the other dependencies implementations, the calls within a service are ignored
+	if parent == child {
+		return
+	}
+	a.links[dependencyLinkKey{parent: parent, child: child}]++
+}
+
+// removeOldest forgets the trace with the earliest deadline, keeping its pending spans for the
+// lookup of their parents, the lock must be held.
+func (a *dependencyAggregator) removeOldest() {
+	elem := a.byArrival.Front()
+	trace := a.byArrival.Remove(elem).(*dependencyTrace)
+	delete(a.traces, trace.key)
+	if len(trace.pending) == 0 {
+		return
+	}
+	if a.spanReader == nil || len(a.unresolved) >= a.options.MaxTraces {
+		a.metrics.OrphanSpans.Inc(int64(len(trace.pending)))
+		return
+	}
+	trace.services = nil
+	a.unresolved = append(a.unresolved, trace)
+}
+
+// expire forgets the traces without spans for ParentWait, and looks up the parents of their pending spans.
+func (a *dependencyAggregator) expire() {
+	now := a.timeNow()
+	a.mu.Lock()
+	for a.byArrival.Len() > 0 && !a.byArrival.Front().Value.(*dependencyTrace).deadline.After(now) {
+		a.removeOldest()
+	}
+	a.metrics.BufferedTraces.Update(int64(len(a.traces)))
+	unresolved := a.unresolved
+	a.unresolved = nil
+	a.mu.Unlock()
+	if len(unresolved) == 0 {
+		return
+	}
+
+	traces := make(chan *dependencyTrace)
+	var wg sync.WaitGroup
+	for i := 0; i < parentLookupWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for trace := range traces {
+				a.lookupParents(trace)
+			}
+		}()
+	}
+	for _, trace := range unresolved {
+		traces <- trace
+	}
+	close(traces)
+	wg.Wait()
+}
+
+// lookupParents counts the links between the pending spans of the trace and their parent spans
+// written to the span storage, by the other collectors which received them.
+func (a *dependencyAggregator) lookupParents(trace *dependencyTrace) {
+	ctx, cancel := context.WithTimeout(tenancy.WithTenant(context.Background(), trace.key.tenant), parentLookupTimeout)
+	defer cancel()
+	stored, err := a.spanReader.GetTrace(ctx, trace.key.traceID)
+	if err != nil {
+		if !errors.Is(err, spanstore.ErrTraceNotFound) {
+			a.logger.Warn("failed to look up the parent spans", zap.Stringer("trace_id", trace.key.traceID), zap.Error(err))
+			a.metrics.LookupErrors.Inc(1)
+		}
+		a.metrics.OrphanSpans.Inc(int64(len(trace.pending)))
+		return
+	}
+	services := make(map[model.SpanID]string, len(stored.Spans))
+	for _, span := range stored.Spans {
+		if span.Process != nil {
+			services[span.SpanID] = span.Process.ServiceName
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, child := range trace.pending {
+		if parentService, ok := services[child.parentID]; ok {
+			a.metrics.LookedUpSpans.Inc(1)
+			a.addLink(parentService, child.service)
+		} else {
+			a.metrics.OrphanSpans.Inc(1)
+		}
+	}
+}
+
+// flush writes the links counted since the last successful flush.
+func (a *dependencyAggregator) flush() {
+	a.mu.Lock()
+	links := a.links
+	a.links = make(map[dependencyLinkKey]uint64)
+	a.mu.Unlock()
+	if len(links) == 0 {
+		return
+	}
+
+	dependencies := make([]model.DependencyLink, 0, len(links))
+	for key, callCount := range links {
+		dependencies = append(dependencies, model.DependencyLink{
+			Parent:    key.parent,
+			Child:     key.child,
+			CallCount: callCount,
+			Source:    model.JaegerDependencyLinkSource,
+		})
+	}
+	if err := a.writer.WriteDependencies(a.timeNow(), dependencies); err != nil {
+		a.logger.Error("failed to write the service dependencies", zap.Error(err))
+		a.metrics.WriteErrors.Inc(1)
+		// the links are written with the next flush
+		a.mu.Lock()
+		for key, callCount := range links {
+			a.links[key] += callCount
+		}
+		a.mu.Unlock()
+		return
+	}
+	a.metrics.LinksWritten.Inc(int64(len(dependencies)))
+}
+
+// Close stops the periodic writes and writes the pending links.
+func (a *dependencyAggregator) Close() error {
+	close(a.stopCh)
+	a.wg.Wait()
+	a.flush()
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/tenancy"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+	spanstoremocks "github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+type fakeDependencyWriter struct {
+	mu           sync.Mutex
+	err          error
+	dependencies []model.DependencyLink
+}
+
+func (w *fakeDependencyWriter) WriteDependencies(ts time.Time, dependencies []model.DependencyLink) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.dependencies = append(w.dependencies, dependencies...)
+	return nil
+}
+
+func (w *fakeDependencyWriter) written() []model.DependencyLink {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dependencies := append([]model.DependencyLink(nil), w.dependencies...)
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].Parent != dependencies[j].Parent {
+			return dependencies[i].Parent < dependencies[j].Parent
+		}
+		return dependencies[i].Child < dependencies[j].Child
+	})
+	return dependencies
+}
+
+func newTestDependencyAggregator(maxTraces int) (*dependencyAggregator, *fakeDependencyWriter, *metricstest.Factory) {
+	writer := &fakeDependencyWriter{}
+	metricsFactory := metricstest.NewFactory(0)
+	a := newDependencyAggregator(flags.DependenciesOptions{
+		Enabled:       true,
+		FlushInterval: time.Minute,
+		ParentWait:    30 * time.Second,
+		MaxTraces:     maxTraces,
+	}, writer, nil, metricsFactory, zap.NewNop())
+	return a, writer, metricsFactory
+}
+
+func dependencySpan(traceID uint64, spanID, parentID model.SpanID, service string) *model.Span {
+	span := &model.Span{
+		TraceID: model.NewTraceID(0, traceID),
+		SpanID:  spanID,
+		Process: &model.Process{ServiceName: service},
+	}
+	if parentID != 0 {
+		span.References = []model.SpanRef{model.NewChildOfRef(span.TraceID, parentID)}
+	}
+	return span
+}
+
+func dependencyLink(parent, child string, callCount uint64) model.DependencyLink {
+	return model.DependencyLink{
+		Parent:    parent,
+		Child:     child,
+		CallCount: callCount,
+		Source:    model.JaegerDependencyLinkSource,
+	}
+}
+
+func TestDependencyAggregatorLinks(t *testing.T) {
+	a, writer, metricsFactory := newTestDependencyAggregator(10)
+
+	// parent received before its children
+	a.processSpan(dependencySpan(1, 1, 0, "frontend"), "")
+	a.processSpan(dependencySpan(1, 2, 1, "backend"), "")
+	a.processSpan(dependencySpan(1, 3, 1, "backend"), "")
+	// calls within a service are ignored
+	a.processSpan(dependencySpan(1, 4, 2, "backend"), "")
+	// children received before their parent
+	a.processSpan(dependencySpan(2, 2, 1, "backend"), "")
+	a.processSpan(dependencySpan(2, 3, 2, "db"), "")
+	a.processSpan(dependencySpan(2, 1, 0, "frontend"), "")
+	// the same trace ID of another tenant is another trace
+	a.processSpan(dependencySpan(3, 2, 1, "backend"), "tenant1")
+	a.processSpan(dependencySpan(3, 1, 0, "frontend"), "tenant2")
+
+	a.flush()
+	assert.Equal(t, []model.DependencyLink{
+		dependencyLink("backend", "db", 1),
+		dependencyLink("frontend", "backend", 3),
+	}, writer.written())
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "dependencies.links_written", Value: 2},
+	)
+
+	// the links are written once
+	a.flush()
+	assert.Len(t, writer.written(), 2)
+}
+
+func TestDependencyAggregatorExpire(t *testing.T) {
+	a, writer, metricsFactory := newTestDependencyAggregator(10)
+	now := time.Unix(1000, 0)
+	a.timeNow = func() time.Time { return now }
+
+	a.processSpan(dependencySpan(1, 2, 1, "backend"), "")
+	now = now.Add(20 * time.Second)
+	a.processSpan(dependencySpan(2, 1, 0, "frontend"), "")
+	now = now.Add(20 * time.Second)
+	a.expire()
+
+	// the child of the expired trace is an orphan, its parent is not linked
+	a.processSpan(dependencySpan(1, 1, 0, "frontend"), "")
+	// the other trace is still buffered
+	a.processSpan(dependencySpan(2, 2, 1, "backend"), "")
+	a.flush()
+
+	assert.Equal(t, []model.DependencyLink{dependencyLink("frontend", "backend", 1)}, writer.written())
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "dependencies.orphan_spans", Value: 1},
+	)
+	metricsFactory.AssertGaugeMetrics(t,
+		metricstest.ExpectedMetric{Name: "dependencies.buffered_traces", Value: 1},
+	)
+}
+
+func TestDependencyAggregatorMaxTraces(t *testing.T) {
+	a, writer, metricsFactory := newTestDependencyAggregator(2)
+
+	a.processSpan(dependencySpan(1, 2, 1, "backend"), "")
+	a.processSpan(dependencySpan(2, 2, 1, "backend"), "")
+	// a span of the first trace makes the second trace the oldest
+	a.processSpan(dependencySpan(1, 3, 1, "db"), "")
+	a.processSpan(dependencySpan(3, 1, 0, "frontend"), "")
+
+	a.processSpan(dependencySpan(1, 1, 0, "frontend"), "")
+	a.processSpan(dependencySpan(2, 1, 0, "frontend"), "")
+	a.flush()
+
+	assert.Equal(t, []model.DependencyLink{
+		dependencyLink("frontend", "backend", 1),
+		dependencyLink("frontend", "db", 1),
+	}, writer.written())
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "dependencies.evicted_traces", Value: 2},
+		metricstest.ExpectedMetric{Name: "dependencies.orphan_spans", Value: 1},
+	)
+}
+
+func TestDependencyAggregatorLookupParents(t *testing.T) {
+	a, writer, metricsFactory := newTestDependencyAggregator(10)
+	spanReader := &spanstoremocks.Reader{}
+	a.spanReader = spanReader
+	now := time.Unix(1000, 0)
+	a.timeNow = func() time.Time { return now }
+
+	inTenant := func(tenant string) interface{} {
+		return mock.MatchedBy(func(ctx context.Context) bool { return tenancy.GetTenant(ctx) == tenant })
+	}
+	// the parent was received by another collector
+	spanReader.On("GetTrace", inTenant("tenant1"), model.NewTraceID(0, 1)).Return(&model.Trace{
+		Spans: []*model.Span{dependencySpan(1, 1, 0, "frontend")},
+	}, nil)
+	// the parent is not stored
+	spanReader.On("GetTrace", inTenant(""), model.NewTraceID(0, 2)).Return(nil, spanstore.ErrTraceNotFound)
+	spanReader.On("GetTrace", inTenant(""), model.NewTraceID(0, 3)).Return(nil, errors.New("read error"))
+
+	a.processSpan(dependencySpan(1, 2, 1, "backend"), "tenant1")
+	a.processSpan(dependencySpan(1, 3, 1, "backend"), "tenant1")
+	// a span of the stored trace whose parent is not found
+	a.processSpan(dependencySpan(1, 4, 5, "db"), "tenant1")
+	a.processSpan(dependencySpan(2, 2, 1, "backend"), "")
+	a.processSpan(dependencySpan(3, 2, 1, "backend"), "")
+	// a trace without pending spans is not looked up
+	a.processSpan(dependencySpan(4, 1, 0, "frontend"), "")
+	now = now.Add(time.Minute)
+	a.expire()
+	a.flush()
+
+	assert.Equal(t, []model.DependencyLink{dependencyLink("frontend", "backend", 2)}, writer.written())
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "dependencies.looked_up_spans", Value: 2},
+		metricstest.ExpectedMetric{Name: "dependencies.orphan_spans", Value: 3},
+		metricstest.ExpectedMetric{Name: "dependencies.lookup_errors", Value: 1},
+	)
+	spanReader.AssertNumberOfCalls(t, "GetTrace", 3)
+}
+
+func TestDependencyAggregatorWriteError(t *testing.T) {
+	a, writer, metricsFactory := newTestDependencyAggregator(10)
+	writer.err = errors.New("write error")
+
+	a.processSpan(dependencySpan(1, 1, 0, "frontend"), "")
+	a.processSpan(dependencySpan(1, 2, 1, "backend"), "")
+	a.flush()
+	assert.Empty(t, writer.written())
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "dependencies.write_errors", Value: 1},
+	)
+
+	// the links are written with the next flush
+	writer.err = nil
+	a.processSpan(dependencySpan(2, 1, 0, "frontend"), "")
+	a.processSpan(dependencySpan(2, 2, 1, "backend"), "")
+	a.flush()
+	assert.Equal(t, []model.DependencyLink{dependencyLink("frontend", "backend", 2)}, writer.written())
+}
+
+func TestDependencyAggregatorClose(t *testing.T) {
+	a, writer, _ := newTestDependencyAggregator(10)
+	a.start()
+
+	a.processSpan(dependencySpan(1, 1, 0, "frontend"), "")
+	a.processSpan(dependencySpan(1, 2, 1, "backend"), "")
+	require.NoError(t, a.Close())
+	assert.Equal(t, []model.DependencyLink{dependencyLink("frontend", "backend", 1)}, writer.written())
+}
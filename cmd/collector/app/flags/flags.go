@@ -18,6 +18,7 @@ package flags
 import (
 	"flag"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -35,6 +36,11 @@ const (
 	flagQueueSize              = "collector.queue-size"
 	flagCollectorTags          = "collector.tags"
 	flagSpanSizeMetricsEnabled = "collector.enable-span-size-metrics"
+	flagRateLimitsFile         = "collector.rate-limits.file"
+	flagAttributeRulesFile     = "collector.attribute-rules.file"
+	flagAttributeHashKeyFile   = "collector.attribute-rules.hash-key-file"
+	flagSpanFilterFile         = "collector.span-filter.file"
+	flagSpanMetricsEnabled     = "collector.span-metrics.enabled"
 
 	flagSuffixHostPort = "host-port"
 
@@ -53,12 +59,47 @@ const (
 	flagZipkinAllowedOrigins   = "collector.zipkin.allowed-origins"
 	flagZipkinKeepAliveEnabled = "collector.zipkin.keep-alive"
 
+	flagTailSamplingDecisionWait       = "collector.tail-sampling.decision-wait"
+	flagTailSamplingMaxTraces          = "collector.tail-sampling.max-traces"
+	flagTailSamplingMaxSpansPerTrace   = "collector.tail-sampling.max-spans-per-trace"
+	flagTailSamplingLatencyThreshold   = "collector.tail-sampling.latency-threshold"
+	flagTailSamplingSampleErrors       = "collector.tail-sampling.sample-errors"
+	flagTailSamplingOperations         = "collector.tail-sampling.operations"
+	flagTailSamplingProbability        = "collector.tail-sampling.probability"
+	flagTailSamplingMaxTracesPerSecond = "collector.tail-sampling.max-traces-per-second"
+	flagDependenciesEnabled            = "collector.dependencies.enabled"
+	flagDependenciesFlushInterval      = "collector.dependencies.flush-interval"
+	flagDependenciesParentWait         = "collector.dependencies.parent-wait"
+	flagDependenciesMaxTraces          = "collector.dependencies.max-traces"
+
+	flagQueueType             = "collector.queue-type"
+	flagDiskQueueDirectory    = "collector.queue-disk.directory"
+	flagDiskQueueMaxSize      = "collector.queue-disk.max-size"
+	flagDiskQueueSegmentSize  = "collector.queue-disk.segment-size"
+	flagDiskQueueSyncPolicy   = "collector.queue-disk.sync"
+	flagDiskQueueSyncInterval = "collector.queue-disk.sync-interval"
+
+	// MemoryQueueType is the queue type keeping the spans in memory
+	MemoryQueueType = "memory"
+	// DiskQueueType is the queue type writing the spans to disk
+	DiskQueueType = "disk"
+
 	// DefaultNumWorkers is the default number of workers consuming from the processor queue
 	DefaultNumWorkers = 50
 	// DefaultQueueSize is the size of the processor's queue
 	DefaultQueueSize = 2000
 	// DefaultGRPCMaxReceiveMessageLength is the default max receivable message size for the gRPC Collector
 	DefaultGRPCMaxReceiveMessageLength = 4 * 1024 * 1024
+	// DefaultTailSamplingMaxTraces is the default number of traces buffered by the tail sampler
+	DefaultTailSamplingMaxTraces = 50000
+	// DefaultTailSamplingMaxSpansPerTrace is the default number of spans of a trace buffered by the tail sampler
+	DefaultTailSamplingMaxSpansPerTrace = 10000
+	// DefaultDependenciesMaxTraces is the default number of traces buffered by the dependency aggregation
+	DefaultDependenciesMaxTraces = 50000
+	// DefaultDiskQueueMaxSize is the default maximum size in MiB of the disk queue
+	DefaultDiskQueueMaxSize = 1024
+	// DefaultDiskQueueSegmentSize is the default size in MiB of the segment files of the disk queue
+	DefaultDiskQueueSegmentSize = 64
 )
 
 var grpcServerFlagsCfg = serverFlagsConfig{
@@ -105,6 +146,10 @@ type CollectorOptions struct {
 	DynQueueSizeMemory uint
 	// QueueSize is the size of collector's queue
 	QueueSize int
+	// QueueType is either MemoryQueueType or DiskQueueType
+	QueueType string
+	// DiskQueue section defines options for the queue when QueueType is DiskQueueType
+	DiskQueue DiskQueueOptions
 	// NumWorkers is the number of internal workers in a collector
 	NumWorkers int
 	// HTTP section defines options for HTTP server
@@ -134,6 +179,70 @@ type CollectorOptions struct {
 	CollectorTags map[string]string
 	// SpanSizeMetricsEnabled determines whether to enable metrics based on processed span size
 	SpanSizeMetricsEnabled bool
+	// RateLimitsFile is the path of the file defining the rate limits per tenant and per service
+	RateLimitsFile string
+	// AttributeRulesFile is the path of the file defining the rules applied to the span tags
+	AttributeRulesFile string
+	// AttributeHashKeyFile is the path of the file containing the key of the hash attribute rules
+	AttributeHashKeyFile string
+	// SpanFilterFile is the path of the file defining the rules dropping spans at ingestion
+	SpanFilterFile string
+	// SpanMetricsEnabled determines whether to compute the request, error and duration metrics of the spans
+	SpanMetricsEnabled bool
+	// TailSampling section defines options for the tail-based sampling of traces
+	TailSampling TailSamplingOptions
+	// Dependencies section defines options for the aggregation of the service dependencies
+	Dependencies DependenciesOptions
+}
+
+// DependenciesOptions defines options for the aggregation of the service dependencies from the
+// parent/child relationships of the spans, which are periodically written to the dependencies storage.
+type DependenciesOptions struct {
+	// Enabled determines whether the service dependencies are aggregated
+	Enabled bool
+	// FlushInterval is the period of the writes of the aggregated dependencies
+	FlushInterval time.Duration
+	// ParentWait is how long the spans of a trace are remembered after its last span, waiting for their parent
+	ParentWait time.Duration
+	// MaxTraces is the maximum number of buffered traces, the oldest trace is dropped when it is reached
+	MaxTraces int
+}
+
+// TailSamplingOptions defines options for tail-based sampling, which buffers the spans of each
+// trace for DecisionWait before deciding whether the trace is written to storage.
+// Tail-based sampling is disabled when DecisionWait is zero.
+type TailSamplingOptions struct {
+	// DecisionWait is how long the spans of a trace are buffered, starting from its first span
+	DecisionWait time.Duration
+	// MaxTraces is the maximum number of buffered traces, the oldest trace is decided early when it is reached
+	MaxTraces int
+	// MaxSpansPerTrace is the maximum number of buffered spans of a trace, the trace is decided early when it is reached
+	MaxSpansPerTrace int
+	// LatencyThreshold samples the traces lasting at least this long, unless it is zero
+	LatencyThreshold time.Duration
+	// SampleErrors samples the traces with a span tagged with error=true
+	SampleErrors bool
+	// Operations samples the traces with a span of the services, or service:operation pairs
+	Operations []string
+	// Probability is the probability of sampling the traces not sampled by any other policy
+	Probability float64
+	// MaxTracesPerSecond limits the rate of sampled traces per service of the root span, unless it is zero
+	MaxTracesPerSecond float64
+}
+
+// DiskQueueOptions defines options for the queue writing the spans to disk,
+// so that the spans not written to storage yet survive a restart.
+type DiskQueueOptions struct {
+	// Directory holds the segment files of the queue
+	Directory string
+	// MaxBytes is the maximum size of the segment files, the spans that would exceed it are dropped
+	MaxBytes int64
+	// SegmentBytes is the size after which a new segment file is started
+	SegmentBytes int64
+	// SyncPolicy is one of always, periodic or never
+	SyncPolicy string
+	// SyncInterval is how often the queue is synced to disk with the periodic SyncPolicy
+	SyncInterval time.Duration
 }
 
 type serverFlagsConfig struct {
@@ -180,6 +289,11 @@ func AddFlags(flags *flag.FlagSet) {
 	flags.Uint(flagDynQueueSizeMemory, 0, "(experimental) The max memory size in MiB to use for the dynamic queue.")
 	flags.String(flagCollectorTags, "", "One or more tags to be added to the Process tags of all spans passing through this collector. Ex: key1=value1,key2=${envVar:defaultValue}")
 	flags.Bool(flagSpanSizeMetricsEnabled, false, "Enables metrics based on processed span size, which are more expensive to calculate.")
+	flags.String(flagRateLimitsFile, "", "The path of the JSON file defining the rate limits in spans per second per tenant and per service, reloaded when it changes (disabled by default)")
+	flags.String(flagAttributeRulesFile, "", "The path of the JSON file defining the rules dropping, hashing, redacting, renaming or adding span tags, log fields and process tags (disabled by default)")
+	flags.String(flagAttributeHashKeyFile, "", "The path of the file containing the secret key of the HMAC computed by the hash attribute rules, required by these rules")
+	flags.Bool(flagSpanMetricsEnabled, false, "Enables the calls and latency metrics of the spans by service, operation, span kind and status code, as queried by the Prometheus metrics storage of the query service.")
+	flags.String(flagSpanFilterFile, "", "The path of the YAML file defining the rules dropping spans at ingestion, reloaded when it changes (disabled by default)")
 
 	addHTTPFlags(flags, httpServerFlagsCfg, ports.PortToHostPort(ports.CollectorHTTP))
 	addGRPCFlags(flags, grpcServerFlagsCfg, ports.PortToHostPort(ports.CollectorGRPC))
@@ -194,9 +308,42 @@ func AddFlags(flags *flag.FlagSet) {
 	flags.Bool(flagZipkinKeepAliveEnabled, true, "KeepAlive configures allow Keep-Alive for Zipkin HTTP server (enabled by default)")
 	tlsZipkinFlagsConfig.AddFlags(flags)
 
+	addTailSamplingFlags(flags)
+	addDependenciesFlags(flags)
+	addQueueFlags(flags)
+
 	tenancy.AddFlags(flags)
 }
 
+func addDependenciesFlags(flags *flag.FlagSet) {
+	flags.Bool(flagDependenciesEnabled, false, "(experimental) Enables the aggregation of the service dependencies by the collector, written to the dependencies storage, "+
+		"instead of computing them with the separate Spark job. Each collector writes the dependencies of the spans it receives, and looks up in the span storage the parents of the spans received by other collectors")
+	flags.Duration(flagDependenciesFlushInterval, time.Minute, "(experimental) How often the aggregated service dependencies are written to storage")
+	flags.Duration(flagDependenciesParentWait, 30*time.Second, "(experimental) How long the spans of a trace are remembered after its last span, waiting for their parent span before looking it up in the span storage. "+
+		"It should exceed the delay before the spans are written to storage, e.g. the tail sampling decision wait")
+	flags.Int(flagDependenciesMaxTraces, DefaultDependenciesMaxTraces, "(experimental) The maximum number of traces buffered by the aggregation of the service dependencies")
+}
+
+func addTailSamplingFlags(flags *flag.FlagSet) {
+	flags.Duration(flagTailSamplingDecisionWait, 0, "(experimental) How long the spans of a trace are buffered before deciding whether the trace is sampled. Tail-based sampling is disabled when 0")
+	flags.Int(flagTailSamplingMaxTraces, DefaultTailSamplingMaxTraces, "(experimental) The maximum number of traces buffered by tail-based sampling")
+	flags.Int(flagTailSamplingMaxSpansPerTrace, DefaultTailSamplingMaxSpansPerTrace, "(experimental) The maximum number of spans of a trace buffered by tail-based sampling, the trace is decided when it is reached. Unlimited when 0")
+	flags.Duration(flagTailSamplingLatencyThreshold, 0, "(experimental) Tail-based sampling samples the traces lasting at least this long, unless it is 0")
+	flags.Bool(flagTailSamplingSampleErrors, true, "(experimental) Tail-based sampling samples the traces with a span tagged with error=true")
+	flags.String(flagTailSamplingOperations, "", "(experimental) Comma separated list of services, or service:operation pairs, whose traces are sampled by tail-based sampling")
+	flags.Float64(flagTailSamplingProbability, 0, "(experimental) The probability, between 0 and 1, of sampling the traces not sampled by any other tail-based sampling policy")
+	flags.Float64(flagTailSamplingMaxTracesPerSecond, 0, "(experimental) The maximum number of traces per second sampled by tail-based sampling for each service of a root span, unlimited when 0")
+}
+
+func addQueueFlags(flags *flag.FlagSet) {
+	flags.String(flagQueueType, MemoryQueueType, "The type of the queue of the collector, either memory or disk. The disk queue keeps the spans not written to storage yet across restarts")
+	flags.String(flagDiskQueueDirectory, "", "The directory of the disk queue")
+	flags.Int64(flagDiskQueueMaxSize, DefaultDiskQueueMaxSize, "The maximum size in MiB of the disk queue, the spans exceeding it are dropped")
+	flags.Int64(flagDiskQueueSegmentSize, DefaultDiskQueueSegmentSize, "The size in MiB of the segment files of the disk queue")
+	flags.String(flagDiskQueueSyncPolicy, "periodic", "When the disk queue is synced to disk: always (for each span), periodic (every sync-interval) or never (left to the operating system)")
+	flags.Duration(flagDiskQueueSyncInterval, time.Second, "How often the disk queue is synced to disk with the periodic sync policy")
+}
+
 func addHTTPFlags(flags *flag.FlagSet, cfg serverFlagsConfig, defaultHostPort string) {
 	flags.String(cfg.prefix+"."+flagSuffixHostPort, defaultHostPort, "The host:port (e.g. 127.0.0.1:12345 or :12345) of the collector's HTTP server")
 	flags.Duration(cfg.prefix+"."+flagSuffixHTTPIdleTimeout, 0, "See https://pkg.go.dev/net/http#Server")
@@ -253,6 +400,81 @@ func (opts *GRPCOptions) initFromViper(v *viper.Viper, logger *zap.Logger, cfg s
 	return nil
 }
 
+func (opts *DependenciesOptions) initFromViper(v *viper.Viper) error {
+	opts.Enabled = v.GetBool(flagDependenciesEnabled)
+	opts.FlushInterval = v.GetDuration(flagDependenciesFlushInterval)
+	opts.ParentWait = v.GetDuration(flagDependenciesParentWait)
+	opts.MaxTraces = v.GetInt(flagDependenciesMaxTraces)
+	if !opts.Enabled {
+		return nil
+	}
+	if opts.FlushInterval <= 0 {
+		return fmt.Errorf("%s must be positive, got %v", flagDependenciesFlushInterval, opts.FlushInterval)
+	}
+	if opts.ParentWait <= 0 {
+		return fmt.Errorf("%s must be positive, got %v", flagDependenciesParentWait, opts.ParentWait)
+	}
+	if opts.MaxTraces <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", flagDependenciesMaxTraces, opts.MaxTraces)
+	}
+	return nil
+}
+
+func (opts *TailSamplingOptions) initFromViper(v *viper.Viper) error {
+	opts.DecisionWait = v.GetDuration(flagTailSamplingDecisionWait)
+	opts.MaxTraces = v.GetInt(flagTailSamplingMaxTraces)
+	opts.MaxSpansPerTrace = v.GetInt(flagTailSamplingMaxSpansPerTrace)
+	opts.LatencyThreshold = v.GetDuration(flagTailSamplingLatencyThreshold)
+	opts.SampleErrors = v.GetBool(flagTailSamplingSampleErrors)
+	opts.Operations = nil
+	for _, operation := range strings.Split(v.GetString(flagTailSamplingOperations), ",") {
+		if operation = strings.TrimSpace(operation); operation != "" {
+			opts.Operations = append(opts.Operations, operation)
+		}
+	}
+	opts.Probability = v.GetFloat64(flagTailSamplingProbability)
+	opts.MaxTracesPerSecond = v.GetFloat64(flagTailSamplingMaxTracesPerSecond)
+	if opts.Probability < 0 || opts.Probability > 1 {
+		return fmt.Errorf("%s must be between 0 and 1, got %v", flagTailSamplingProbability, opts.Probability)
+	}
+	if opts.MaxTraces <= 0 {
+		return fmt.Errorf("%s must be positive, got %d", flagTailSamplingMaxTraces, opts.MaxTraces)
+	}
+	return nil
+}
+
+func (cOpts *CollectorOptions) initQueueFromViper(v *viper.Viper) error {
+	cOpts.QueueType = v.GetString(flagQueueType)
+	cOpts.DiskQueue.Directory = v.GetString(flagDiskQueueDirectory)
+	cOpts.DiskQueue.MaxBytes = v.GetInt64(flagDiskQueueMaxSize) * 1024 * 1024 // we receive in MiB and store in bytes
+	cOpts.DiskQueue.SegmentBytes = v.GetInt64(flagDiskQueueSegmentSize) * 1024 * 1024
+	cOpts.DiskQueue.SyncPolicy = v.GetString(flagDiskQueueSyncPolicy)
+	cOpts.DiskQueue.SyncInterval = v.GetDuration(flagDiskQueueSyncInterval)
+	switch cOpts.QueueType {
+	case MemoryQueueType:
+		return nil
+	case DiskQueueType:
+	default:
+		return fmt.Errorf("%s must be %s or %s, got %q", flagQueueType, MemoryQueueType, DiskQueueType, cOpts.QueueType)
+	}
+	if cOpts.DiskQueue.Directory == "" {
+		return fmt.Errorf("%s is required with the disk queue", flagDiskQueueDirectory)
+	}
+	if cOpts.DiskQueue.SegmentBytes <= 0 || cOpts.DiskQueue.SegmentBytes > cOpts.DiskQueue.MaxBytes {
+		return fmt.Errorf("%s must be positive and at most %s", flagDiskQueueSegmentSize, flagDiskQueueMaxSize)
+	}
+	switch cOpts.DiskQueue.SyncPolicy {
+	case "always", "never":
+	case "periodic":
+		if cOpts.DiskQueue.SyncInterval <= 0 {
+			return fmt.Errorf("%s must be positive, got %v", flagDiskQueueSyncInterval, cOpts.DiskQueue.SyncInterval)
+		}
+	default:
+		return fmt.Errorf("%s must be always, periodic or never, got %q", flagDiskQueueSyncPolicy, cOpts.DiskQueue.SyncPolicy)
+	}
+	return nil
+}
+
 // InitFromViper initializes CollectorOptions with properties from viper
 func (cOpts *CollectorOptions) InitFromViper(v *viper.Viper, logger *zap.Logger) (*CollectorOptions, error) {
 	cOpts.CollectorTags = flags.ParseJaegerTags(v.GetString(flagCollectorTags))
@@ -260,6 +482,23 @@ func (cOpts *CollectorOptions) InitFromViper(v *viper.Viper, logger *zap.Logger)
 	cOpts.QueueSize = v.GetInt(flagQueueSize)
 	cOpts.DynQueueSizeMemory = v.GetUint(flagDynQueueSizeMemory) * 1024 * 1024 // we receive in MiB and store in bytes
 	cOpts.SpanSizeMetricsEnabled = v.GetBool(flagSpanSizeMetricsEnabled)
+	cOpts.RateLimitsFile = v.GetString(flagRateLimitsFile)
+	cOpts.AttributeRulesFile = v.GetString(flagAttributeRulesFile)
+	cOpts.AttributeHashKeyFile = v.GetString(flagAttributeHashKeyFile)
+	cOpts.SpanFilterFile = v.GetString(flagSpanFilterFile)
+	cOpts.SpanMetricsEnabled = v.GetBool(flagSpanMetricsEnabled)
+
+	if err := cOpts.TailSampling.initFromViper(v); err != nil {
+		return cOpts, fmt.Errorf("failed to parse tail sampling options: %w", err)
+	}
+
+	if err := cOpts.Dependencies.initFromViper(v); err != nil {
+		return cOpts, fmt.Errorf("failed to parse dependencies options: %w", err)
+	}
+
+	if err := cOpts.initQueueFromViper(v); err != nil {
+		return cOpts, fmt.Errorf("failed to parse queue options: %w", err)
+	}
 
 	if err := cOpts.HTTP.initFromViper(v, logger, httpServerFlagsCfg); err != nil {
 		return cOpts, fmt.Errorf("failed to parse HTTP server options: %w", err)
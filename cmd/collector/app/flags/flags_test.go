@@ -159,3 +159,220 @@ func TestCollectorOptionsWithFlags_CheckZipkinKeepAlive(t *testing.T) {
 
 	assert.Equal(t, false, c.Zipkin.KeepAlive)
 }
+
+func TestCollectorOptionsWithFlags_CheckTailSampling(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.tail-sampling.decision-wait=10s",
+		"--collector.tail-sampling.max-traces=100",
+		"--collector.tail-sampling.max-spans-per-trace=500",
+		"--collector.tail-sampling.latency-threshold=2s",
+		"--collector.tail-sampling.sample-errors=false",
+		"--collector.tail-sampling.operations=frontend, backend:GET /",
+		"--collector.tail-sampling.probability=0.1",
+		"--collector.tail-sampling.max-traces-per-second=5",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, TailSamplingOptions{
+		DecisionWait:       10 * time.Second,
+		MaxTraces:          100,
+		MaxSpansPerTrace:   500,
+		LatencyThreshold:   2 * time.Second,
+		SampleErrors:       false,
+		Operations:         []string{"frontend", "backend:GET /"},
+		Probability:        0.1,
+		MaxTracesPerSecond: 5,
+	}, c.TailSampling)
+}
+
+func TestCollectorOptionsWithFlags_CheckTailSamplingDefaults(t *testing.T) {
+	c := &CollectorOptions{}
+	v, _ := config.Viperize(AddFlags)
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, TailSamplingOptions{
+		MaxTraces:        DefaultTailSamplingMaxTraces,
+		MaxSpansPerTrace: DefaultTailSamplingMaxSpansPerTrace,
+		SampleErrors:     true,
+	}, c.TailSampling)
+}
+
+func TestCollectorOptionsWithFlags_CheckInvalidTailSampling(t *testing.T) {
+	tests := []struct {
+		flags       []string
+		expectedErr string
+	}{
+		{
+			flags:       []string{"--collector.tail-sampling.probability=2"},
+			expectedErr: "failed to parse tail sampling options: collector.tail-sampling.probability must be between 0 and 1, got 2",
+		},
+		{
+			flags:       []string{"--collector.tail-sampling.max-traces=0"},
+			expectedErr: "failed to parse tail sampling options: collector.tail-sampling.max-traces must be positive, got 0",
+		},
+	}
+	for _, test := range tests {
+		c := &CollectorOptions{}
+		v, command := config.Viperize(AddFlags)
+		command.ParseFlags(test.flags)
+		_, err := c.InitFromViper(v, zap.NewNop())
+		assert.EqualError(t, err, test.expectedErr)
+	}
+}
+
+func TestCollectorOptionsWithFlags_CheckDependencies(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.dependencies.enabled=true",
+		"--collector.dependencies.flush-interval=5m",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, DependenciesOptions{
+		Enabled:       true,
+		FlushInterval: 5 * time.Minute,
+		ParentWait:    30 * time.Second,
+		MaxTraces:     DefaultDependenciesMaxTraces,
+	}, c.Dependencies)
+}
+
+func TestCollectorOptionsWithFlags_CheckInvalidDependencies(t *testing.T) {
+	tests := []struct {
+		flags       []string
+		expectedErr string
+	}{
+		{
+			flags:       []string{"--collector.dependencies.enabled=true", "--collector.dependencies.flush-interval=0"},
+			expectedErr: "failed to parse dependencies options: collector.dependencies.flush-interval must be positive, got 0s",
+		},
+		{
+			flags:       []string{"--collector.dependencies.enabled=true", "--collector.dependencies.parent-wait=0"},
+			expectedErr: "failed to parse dependencies options: collector.dependencies.parent-wait must be positive, got 0s",
+		},
+		{
+			flags:       []string{"--collector.dependencies.enabled=true", "--collector.dependencies.max-traces=0"},
+			expectedErr: "failed to parse dependencies options: collector.dependencies.max-traces must be positive, got 0",
+		},
+	}
+	for _, test := range tests {
+		c := &CollectorOptions{}
+		v, command := config.Viperize(AddFlags)
+		command.ParseFlags(test.flags)
+		_, err := c.InitFromViper(v, zap.NewNop())
+		assert.EqualError(t, err, test.expectedErr)
+	}
+}
+
+func TestCollectorOptionsWithFlags_CheckRateLimitsFile(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.rate-limits.file=/etc/jaeger/rate_limits.json",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, "/etc/jaeger/rate_limits.json", c.RateLimitsFile)
+}
+
+func TestCollectorOptionsWithFlags_CheckAttributeRulesFile(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.attribute-rules.file=/etc/jaeger/attribute_rules.json",
+		"--collector.attribute-rules.hash-key-file=/etc/jaeger/secrets/hash_key",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, "/etc/jaeger/attribute_rules.json", c.AttributeRulesFile)
+	assert.Equal(t, "/etc/jaeger/secrets/hash_key", c.AttributeHashKeyFile)
+}
+
+func TestCollectorOptionsWithFlags_CheckSpanFilterFile(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.span-filter.file=/etc/jaeger/span_filter.yaml",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, "/etc/jaeger/span_filter.yaml", c.SpanFilterFile)
+}
+
+func TestCollectorOptionsWithFlags_CheckSpanMetrics(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.span-metrics.enabled=true",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.True(t, c.SpanMetricsEnabled)
+}
+
+func TestCollectorOptionsWithFlags_CheckDiskQueue(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.queue-type=disk",
+		"--collector.queue-disk.directory=/var/lib/jaeger/queue",
+		"--collector.queue-disk.max-size=100",
+		"--collector.queue-disk.segment-size=10",
+		"--collector.queue-disk.sync=always",
+	})
+	_, err := c.InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, DiskQueueType, c.QueueType)
+	assert.Equal(t, DiskQueueOptions{
+		Directory:    "/var/lib/jaeger/queue",
+		MaxBytes:     100 * 1024 * 1024,
+		SegmentBytes: 10 * 1024 * 1024,
+		SyncPolicy:   "always",
+		SyncInterval: time.Second,
+	}, c.DiskQueue)
+}
+
+func TestCollectorOptionsWithFlags_CheckInvalidDiskQueue(t *testing.T) {
+	tests := []struct {
+		flags       []string
+		expectedErr string
+	}{
+		{
+			flags:       []string{"--collector.queue-type=kafka"},
+			expectedErr: `failed to parse queue options: collector.queue-type must be memory or disk, got "kafka"`,
+		},
+		{
+			flags:       []string{"--collector.queue-type=disk"},
+			expectedErr: "failed to parse queue options: collector.queue-disk.directory is required with the disk queue",
+		},
+		{
+			flags:       []string{"--collector.queue-type=disk", "--collector.queue-disk.directory=/tmp", "--collector.queue-disk.segment-size=2048"},
+			expectedErr: "failed to parse queue options: collector.queue-disk.segment-size must be positive and at most collector.queue-disk.max-size",
+		},
+		{
+			flags:       []string{"--collector.queue-type=disk", "--collector.queue-disk.directory=/tmp", "--collector.queue-disk.sync=sometimes"},
+			expectedErr: `failed to parse queue options: collector.queue-disk.sync must be always, periodic or never, got "sometimes"`,
+		},
+		{
+			flags:       []string{"--collector.queue-type=disk", "--collector.queue-disk.directory=/tmp", "--collector.queue-disk.sync-interval=0s"},
+			expectedErr: "failed to parse queue options: collector.queue-disk.sync-interval must be positive, got 0s",
+		},
+	}
+	for _, test := range tests {
+		c := &CollectorOptions{}
+		v, command := config.Viperize(AddFlags)
+		command.ParseFlags(test.flags)
+		_, err := c.InitFromViper(v, zap.NewNop())
+		assert.EqualError(t, err, test.expectedErr)
+	}
+}
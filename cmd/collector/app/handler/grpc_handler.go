@@ -91,7 +91,7 @@ func (c *batchConsumer) consume(ctx context.Context, batch *model.Batch) error {
 		Tenant:           tenant,
 	})
 	if err != nil {
-		if err == processor.ErrBusy {
+		if err == processor.ErrBusy || err == processor.ErrRateLimited {
 			return status.Errorf(codes.ResourceExhausted, err.Error())
 		}
 		c.logger.Error("cannot process spans", zap.Error(err))
@@ -47,6 +47,9 @@ func (p *mockSpanProcessor) ProcessSpans(spans []*model.Span, opts processor.Spa
 	defer p.mux.Unlock()
 	p.spans = append(p.spans, spans...)
 	oks := make([]bool, len(spans))
+	for i := range oks {
+		oks[i] = p.expectedError == nil
+	}
 	if p.tenants == nil {
 		p.tenants = make(map[string]bool)
 	}
@@ -165,6 +168,10 @@ func TestPostSpansWithError(t *testing.T) {
 			processorError: processor.ErrBusy,
 			expectedError:  "server busy",
 		},
+		{
+			processorError: processor.ErrRateLimited,
+			expectedError:  "rate limit exceeded",
+		},
 	}
 	for _, test := range testCases {
 		t.Run(test.expectedError, func(t *testing.T) {
@@ -53,6 +53,14 @@ func NewAPIHandler(
 	}
 }
 
+// SubmitErrorStatusCode returns the HTTP status code of an error returned by SubmitBatches.
+func SubmitErrorStatusCode(err error) int {
+	if err == processor.ErrBusy || err == processor.ErrRateLimited {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
 // RegisterRoutes registers routes for this handler on the given router
 func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/traces", aH.SaveSpan).Methods(http.MethodPost)
@@ -87,7 +95,7 @@ func (aH *APIHandler) SaveSpan(w http.ResponseWriter, r *http.Request) {
 	batches := []*tJaeger.Batch{batch}
 	opts := SubmitBatchOptions{InboundTransport: processor.HTTPTransport}
 	if _, err = aH.jaegerBatchesHandler.SubmitBatches(batches, opts); err != nil {
-		http.Error(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), SubmitErrorStatusCode(err))
 		return
 	}
 
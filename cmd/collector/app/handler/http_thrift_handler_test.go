@@ -32,6 +32,7 @@ import (
 	jaegerClient "github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/transport"
 
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
 	"github.com/kjschnei001/jaeger/thrift-gen/jaeger"
 )
 
@@ -94,6 +95,12 @@ func TestThriftFormat(t *testing.T) {
 	assert.NoError(t, err)
 	assert.EqualValues(t, http.StatusInternalServerError, statusCode)
 	assert.EqualValues(t, "Cannot submit Jaeger batch: Bad times ahead\n", resBodyStr)
+
+	handler.jaegerBatchesHandler.(*mockJaegerHandler).err = processor.ErrRateLimited
+	statusCode, resBodyStr, err = postBytes("application/x-thrift", server.URL+`/api/traces`, someBytes)
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusTooManyRequests, statusCode)
+	assert.EqualValues(t, "Cannot submit Jaeger batch: rate limit exceeded\n", resBodyStr)
 }
 
 func TestViaClient(t *testing.T) {
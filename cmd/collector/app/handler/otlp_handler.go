@@ -0,0 +1,396 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/model/converter/otlp"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/pkg/tenancy"
+)
+
+// The reasons of the rejected OTLP spans, used as the reason tag of the metrics.
+const (
+	otlpRejectReasonTenancy     = "tenancy"
+	otlpRejectReasonInvalid     = "invalid"
+	otlpRejectReasonQueueFull   = "queue_full"
+	otlpRejectReasonRateLimited = "rate_limited"
+	otlpRejectReasonInternal    = "internal"
+)
+
+var (
+	otlpTransports    = []processor.InboundTransport{processor.GRPCTransport, processor.HTTPTransport}
+	otlpRejectReasons = []string{
+		otlpRejectReasonTenancy,
+		otlpRejectReasonInvalid,
+		otlpRejectReasonQueueFull,
+		otlpRejectReasonRateLimited,
+		otlpRejectReasonInternal,
+	}
+)
+
+type otlpRejectedKey struct {
+	transport processor.InboundTransport
+	reason    string
+}
+
+// otlpHandler processes the OTLP export requests of both transports. The spans rejected
+// while other spans of the request were accepted are reported in the partial_success
+// field of the response, and the requests whose spans were all rejected fail.
+type otlpHandler struct {
+	logger        *zap.Logger
+	spanProcessor processor.SpanProcessor
+	tenancyMgr    *tenancy.Manager
+	// protoFromTraces converts the OTLP traces, it is replaced in tests
+	protoFromTraces func(td ptrace.Traces) ([]*model.Batch, error)
+	// rejectedSpans counts the rejected spans by transport and reason
+	rejectedSpans map[otlpRejectedKey]metrics.Counter
+}
+
+func newOTLPHandler(logger *zap.Logger, spanProcessor processor.SpanProcessor, tm *tenancy.Manager, metricsFactory metrics.Factory) *otlpHandler {
+	rejectedSpans := make(map[otlpRejectedKey]metrics.Counter)
+	for _, transport := range otlpTransports {
+		for _, reason := range otlpRejectReasons {
+			rejectedSpans[otlpRejectedKey{transport: transport, reason: reason}] = metricsFactory.Counter(metrics.Options{
+				Name: "otlp.spans.rejected",
+				Tags: map[string]string{"transport": string(transport), "reason": reason},
+				Help: "Number of the OTLP spans rejected by the collector",
+			})
+		}
+	}
+	return &otlpHandler{
+		logger:          logger,
+		spanProcessor:   spanProcessor,
+		tenancyMgr:      tm,
+		protoFromTraces: otlp.ProtoFromTraces,
+		rejectedSpans:   rejectedSpans,
+	}
+}
+
+// otlpRejection accumulates the spans rejected from an export request.
+type otlpRejection struct {
+	spans    int
+	messages []string
+	// err is the error of the last rejection, returned when no span was accepted
+	err error
+}
+
+func (r *otlpRejection) add(spans int, err error) {
+	r.spans += spans
+	r.err = err
+	message := err.Error()
+	if s, ok := status.FromError(err); ok {
+		message = s.Message()
+	}
+	for _, m := range r.messages {
+		if m == message {
+			return
+		}
+	}
+	r.messages = append(r.messages, message)
+}
+
+// export processes the spans of an export request, the tenants are the values of the tenancy header.
+func (h *otlpHandler) export(td ptrace.Traces, transport processor.InboundTransport, tenants []string) (ptraceotlp.ExportResponse, error) {
+	response := ptraceotlp.NewExportResponse()
+	spanCount := td.SpanCount()
+	if spanCount == 0 {
+		return response, nil
+	}
+
+	var rejection otlpRejection
+	reject := func(spans int, reason string, err error) {
+		h.rejectedSpans[otlpRejectedKey{transport: transport, reason: reason}].Inc(int64(spans))
+		rejection.add(spans, err)
+	}
+
+	tenant, err := h.validateTenant(tenants)
+	if err != nil {
+		h.logger.Debug("rejecting spans (tenancy)", zap.Error(err))
+		reject(spanCount, otlpRejectReasonTenancy, err)
+		return response, err
+	}
+
+	for _, batch := range h.convert(td, reject) {
+		for _, span := range batch.Spans {
+			if span.GetProcess() == nil {
+				span.Process = batch.Process
+			}
+		}
+		oks, err := h.spanProcessor.ProcessSpans(batch.Spans, processor.SpansOptions{
+			InboundTransport: transport,
+			SpanFormat:       processor.OTLPSpanFormat,
+			Tenant:           tenant,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, processor.ErrBusy):
+				reject(len(batch.Spans), otlpRejectReasonQueueFull, status.Error(codes.ResourceExhausted, err.Error()))
+			case errors.Is(err, processor.ErrRateLimited):
+				reject(len(batch.Spans), otlpRejectReasonRateLimited, status.Error(codes.ResourceExhausted, err.Error()))
+			default:
+				h.logger.Error("cannot process spans", zap.Error(err))
+				reject(len(batch.Spans), otlpRejectReasonInternal, status.Error(codes.Internal, err.Error()))
+			}
+			continue
+		}
+		dropped := 0
+		for _, ok := range oks {
+			if !ok {
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			reject(dropped, otlpRejectReasonQueueFull, status.Error(codes.ResourceExhausted, processor.ErrBusy.Error()))
+		}
+	}
+
+	if rejection.spans == 0 {
+		return response, nil
+	}
+	if rejection.spans == spanCount {
+		return response, rejection.err
+	}
+	partialSuccess := response.PartialSuccess()
+	partialSuccess.SetRejectedSpans(int64(rejection.spans))
+	partialSuccess.SetErrorMessage(strings.Join(rejection.messages, "; "))
+	return response, nil
+}
+
+// convert converts the traces to batches. When the traces cannot be converted, each resource
+// is converted separately, to only reject the spans of the resources which cannot be converted.
+func (h *otlpHandler) convert(td ptrace.Traces, reject func(spans int, reason string, err error)) []*model.Batch {
+	batches, err := h.protoFromTraces(td)
+	if err == nil {
+		return batches
+	}
+	batches = nil
+	resourceSpans := td.ResourceSpans()
+	for i := 0; i < resourceSpans.Len(); i++ {
+		resourceTraces := ptrace.NewTraces()
+		resourceSpans.At(i).CopyTo(resourceTraces.ResourceSpans().AppendEmpty())
+		resourceBatches, err := h.protoFromTraces(resourceTraces)
+		if err != nil {
+			h.logger.Debug("cannot convert OTLP spans", zap.Error(err))
+			reject(resourceTraces.SpanCount(), otlpRejectReasonInvalid,
+				status.Errorf(codes.InvalidArgument, "cannot convert spans: %v", err))
+			continue
+		}
+		batches = append(batches, resourceBatches...)
+	}
+	return batches
+}
+
+func (h *otlpHandler) validateTenant(tenants []string) (string, error) {
+	if !h.tenancyMgr.Enabled {
+		return "", nil
+	}
+	if len(tenants) < 1 {
+		return "", status.Errorf(codes.PermissionDenied, "missing tenant header")
+	} else if len(tenants) > 1 {
+		return "", status.Errorf(codes.PermissionDenied, "extra tenant header")
+	}
+	if !h.tenancyMgr.Valid(tenants[0]) {
+		return "", status.Errorf(codes.PermissionDenied, "unknown tenant")
+	}
+	return tenants[0], nil
+}
+
+// otlpGRPCServer implements the OTLP/gRPC trace service.
+type otlpGRPCServer struct {
+	ptraceotlp.UnimplementedGRPCServer
+	handler *otlpHandler
+}
+
+func (s *otlpGRPCServer) Export(ctx context.Context, request ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	var tenants []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && s.handler.tenancyMgr.Enabled {
+		tenants = md.Get(s.handler.tenancyMgr.Header)
+	}
+	return s.handler.export(request.Traces(), processor.GRPCTransport, tenants)
+}
+
+const (
+	otlpProtobufContentType = "application/x-protobuf"
+	otlpJSONContentType     = "application/json"
+)
+
+// otlpHTTPEncoder encodes the OTLP/HTTP messages of a content type
+type otlpHTTPEncoder interface {
+	unmarshalRequest(buf []byte) (ptraceotlp.ExportRequest, error)
+	marshalResponse(response ptraceotlp.ExportResponse) ([]byte, error)
+	marshalStatus(s *status.Status) ([]byte, error)
+	contentType() string
+}
+
+type otlpProtobufEncoder struct{}
+
+func (otlpProtobufEncoder) unmarshalRequest(buf []byte) (ptraceotlp.ExportRequest, error) {
+	request := ptraceotlp.NewExportRequest()
+	err := request.UnmarshalProto(buf)
+	return request, err
+}
+
+func (otlpProtobufEncoder) marshalResponse(response ptraceotlp.ExportResponse) ([]byte, error) {
+	return response.MarshalProto()
+}
+
+func (otlpProtobufEncoder) marshalStatus(s *status.Status) ([]byte, error) {
+	return proto.Marshal(s.Proto())
+}
+
+func (otlpProtobufEncoder) contentType() string {
+	return otlpProtobufContentType
+}
+
+type otlpJSONEncoder struct{}
+
+func (otlpJSONEncoder) unmarshalRequest(buf []byte) (ptraceotlp.ExportRequest, error) {
+	request := ptraceotlp.NewExportRequest()
+	err := request.UnmarshalJSON(buf)
+	return request, err
+}
+
+func (otlpJSONEncoder) marshalResponse(response ptraceotlp.ExportResponse) ([]byte, error) {
+	return response.MarshalJSON()
+}
+
+func (otlpJSONEncoder) marshalStatus(s *status.Status) ([]byte, error) {
+	return protojson.Marshal(s.Proto())
+}
+
+func (otlpJSONEncoder) contentType() string {
+	return otlpJSONContentType
+}
+
+func otlpHTTPEncoderFor(contentType string) (otlpHTTPEncoder, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	switch mediaType {
+	case otlpProtobufContentType:
+		return otlpProtobufEncoder{}, true
+	case otlpJSONContentType:
+		return otlpJSONEncoder{}, true
+	}
+	return nil, false
+}
+
+// serveHTTP implements the OTLP/HTTP trace service, with binary protobuf and JSON encodings.
+func (h *otlpHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	encoder, ok := otlpHTTPEncoderFor(r.Header.Get("Content-Type"))
+	if !ok {
+		writeOTLPHTTPError(w, otlpJSONEncoder{}, status.Newf(codes.InvalidArgument,
+			"unsupported content type %q", r.Header.Get("Content-Type")), http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeOTLPHTTPError(w, encoder, status.Newf(codes.InvalidArgument,
+			"unsupported method %s", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOTLPHTTPError(w, encoder, status.Newf(codes.InvalidArgument,
+			"cannot read body: %v", err), http.StatusBadRequest)
+		return
+	}
+	request, err := encoder.unmarshalRequest(body)
+	if err != nil {
+		writeOTLPHTTPError(w, encoder, status.Newf(codes.InvalidArgument,
+			"cannot parse request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var tenants []string
+	if h.tenancyMgr.Enabled {
+		tenants = r.Header.Values(h.tenancyMgr.Header)
+	}
+	response, err := h.export(request.Traces(), processor.HTTPTransport, tenants)
+	if err != nil {
+		s := status.Convert(err)
+		writeOTLPHTTPError(w, encoder, s, otlpHTTPStatusCode(s.Code()))
+		return
+	}
+	msg, err := encoder.marshalResponse(response)
+	if err != nil {
+		writeOTLPHTTPError(w, encoder, status.New(codes.Internal, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	writeOTLPHTTPResponse(w, encoder.contentType(), http.StatusOK, msg)
+}
+
+// otlpHTTPStatusCode returns the HTTP status code of the error code of an export request.
+func otlpHTTPStatusCode(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.PermissionDenied:
+		// like the tenancy of the other HTTP endpoints
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeOTLPHTTPError writes the error as a google.rpc.Status message, as required by the OTLP protocol.
+func writeOTLPHTTPError(w http.ResponseWriter, encoder otlpHTTPEncoder, s *status.Status, statusCode int) {
+	msg, err := encoder.marshalStatus(s)
+	if err != nil {
+		http.Error(w, s.Message(), statusCode)
+		return
+	}
+	writeOTLPHTTPResponse(w, encoder.contentType(), statusCode, msg)
+}
+
+// writeOTLPHTTPErrorMessage writes the errors of the HTTP server, e.g. of the body decompression.
+func writeOTLPHTTPErrorMessage(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
+	encoder, ok := otlpHTTPEncoderFor(r.Header.Get("Content-Type"))
+	if !ok {
+		encoder = otlpJSONEncoder{}
+	}
+	code := codes.Unknown
+	if statusCode == http.StatusBadRequest {
+		code = codes.InvalidArgument
+	}
+	writeOTLPHTTPError(w, encoder, status.New(code, message), statusCode)
+}
+
+func writeOTLPHTTPResponse(w http.ResponseWriter, contentType string, statusCode int, msg []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	// nothing to do when the response cannot be written
+	_, _ = w.Write(msg)
+}
@@ -0,0 +1,436 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/tenancy"
+)
+
+// otlpSpanProcessor drops the spans of the dropped services, and records the transports.
+type otlpSpanProcessor struct {
+	mockSpanProcessor
+	dropped    map[string]bool
+	transports []processor.InboundTransport
+}
+
+func (p *otlpSpanProcessor) ProcessSpans(spans []*model.Span, opts processor.SpansOptions) ([]bool, error) {
+	oks, err := p.mockSpanProcessor.ProcessSpans(spans, opts)
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.transports = append(p.transports, opts.InboundTransport)
+	for i, span := range spans {
+		if err == nil && p.dropped[span.Process.ServiceName] {
+			oks[i] = false
+		}
+	}
+	return oks, err
+}
+
+func loadOTLPFixture(t *testing.T) []byte {
+	data, err := os.ReadFile("testdata/otlp_traces.json")
+	require.NoError(t, err)
+	return data
+}
+
+func loadOTLPRequest(t *testing.T) ptraceotlp.ExportRequest {
+	request := ptraceotlp.NewExportRequest()
+	require.NoError(t, request.UnmarshalJSON(loadOTLPFixture(t)))
+	return request
+}
+
+func rejectedSpansMetric(transport, reason string, value int) metricstest.ExpectedMetric {
+	return metricstest.ExpectedMetric{
+		Name:  "otlp.spans.rejected",
+		Tags:  map[string]string{"transport": transport, "reason": reason},
+		Value: value,
+	}
+}
+
+func newOTLPTestClient(t *testing.T, h *otlpHandler) ptraceotlp.GRPCClient {
+	server, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		ptraceotlp.RegisterGRPCServer(s, &otlpGRPCServer{handler: h})
+	})
+	t.Cleanup(server.Stop)
+	conn, err := grpc.Dial(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return ptraceotlp.NewGRPCClient(conn)
+}
+
+func TestOTLPHandlerGRPC(t *testing.T) {
+	tests := []struct {
+		name          string
+		processorErr  error
+		dropped       map[string]bool
+		expectedCode  codes.Code
+		rejectedSpans int64
+		expectedMsg   string
+		metric        metricstest.ExpectedMetric
+	}{
+		{
+			name: "accepted",
+		},
+		{
+			name:          "partially dropped",
+			dropped:       map[string]bool{"customer": true},
+			rejectedSpans: 1,
+			expectedMsg:   "server busy",
+			metric:        rejectedSpansMetric("grpc", "queue_full", 1),
+		},
+		{
+			name:         "busy",
+			processorErr: processor.ErrBusy,
+			expectedCode: codes.ResourceExhausted,
+			metric:       rejectedSpansMetric("grpc", "queue_full", 3),
+		},
+		{
+			name:         "rate limited",
+			processorErr: processor.ErrRateLimited,
+			expectedCode: codes.ResourceExhausted,
+			metric:       rejectedSpansMetric("grpc", "rate_limited", 3),
+		},
+		{
+			name:         "internal error",
+			processorErr: errors.New("storage error"),
+			expectedCode: codes.Internal,
+			metric:       rejectedSpansMetric("grpc", "internal", 3),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spanProcessor := &otlpSpanProcessor{
+				mockSpanProcessor: mockSpanProcessor{expectedError: test.processorErr},
+				dropped:           test.dropped,
+			}
+			metricsFactory := metricstest.NewFactory(0)
+			h := newOTLPHandler(zap.NewNop(), spanProcessor, &tenancy.Manager{}, metricsFactory)
+			client := newOTLPTestClient(t, h)
+
+			response, err := client.Export(context.Background(), loadOTLPRequest(t))
+			if test.expectedCode != codes.OK {
+				assert.Equal(t, test.expectedCode, status.Code(err))
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.rejectedSpans, response.PartialSuccess().RejectedSpans())
+				assert.Equal(t, test.expectedMsg, response.PartialSuccess().ErrorMessage())
+			}
+			assert.Len(t, spanProcessor.getSpans(), 3)
+			assert.Equal(t, []processor.InboundTransport{processor.GRPCTransport, processor.GRPCTransport}, spanProcessor.transports)
+			if test.metric.Name != "" {
+				metricsFactory.AssertCounterMetrics(t, test.metric)
+			}
+		})
+	}
+}
+
+// withZeroTraceIDs clears the trace IDs of the spans of a service, which cannot be converted.
+func withZeroTraceIDs(td ptrace.Traces, service string) ptrace.Traces {
+	resourceSpans := td.ResourceSpans()
+	for i := 0; i < resourceSpans.Len(); i++ {
+		if name, _ := resourceSpans.At(i).Resource().Attributes().Get("service.name"); name.Str() != service {
+			continue
+		}
+		scopeSpans := resourceSpans.At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				spans.At(k).SetTraceID(pcommon.NewTraceIDEmpty())
+			}
+		}
+	}
+	return td
+}
+
+func TestOTLPHandlerConversionError(t *testing.T) {
+	spanProcessor := &mockSpanProcessor{}
+	metricsFactory := metricstest.NewFactory(0)
+	h := newOTLPHandler(zap.NewNop(), spanProcessor, &tenancy.Manager{}, metricsFactory)
+
+	response, err := h.export(withZeroTraceIDs(loadOTLPRequest(t).Traces(), "customer"), processor.GRPCTransport, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, response.PartialSuccess().RejectedSpans())
+	assert.Contains(t, response.PartialSuccess().ErrorMessage(), "cannot convert spans")
+	assert.Contains(t, response.PartialSuccess().ErrorMessage(), "GET /customer")
+	assert.Len(t, spanProcessor.getSpans(), 2)
+	metricsFactory.AssertCounterMetrics(t, rejectedSpansMetric("grpc", "invalid", 1))
+
+	td := withZeroTraceIDs(withZeroTraceIDs(loadOTLPRequest(t).Traces(), "customer"), "frontend")
+	_, err = h.export(td, processor.GRPCTransport, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	metricsFactory.AssertCounterMetrics(t, rejectedSpansMetric("grpc", "invalid", 4))
+}
+
+func TestOTLPHandlerEmptyRequest(t *testing.T) {
+	spanProcessor := &mockSpanProcessor{}
+	h := newOTLPHandler(zap.NewNop(), spanProcessor, &tenancy.Manager{}, metricstest.NewFactory(0))
+	response, err := h.export(ptrace.NewTraces(), processor.GRPCTransport, nil)
+	require.NoError(t, err)
+	assert.Zero(t, response.PartialSuccess().RejectedSpans())
+	assert.Empty(t, spanProcessor.getSpans())
+}
+
+func TestOTLPHandlerGRPCTenancy(t *testing.T) {
+	tm := tenancy.NewManager(&tenancy.Options{
+		Enabled: true,
+		Header:  "x-tenant",
+		Tenants: []string{"acme"},
+	})
+	tests := []struct {
+		name         string
+		tenants      []string
+		expectedCode codes.Code
+	}{
+		{name: "valid tenant", tenants: []string{"acme"}},
+		{name: "missing tenant", expectedCode: codes.PermissionDenied},
+		{name: "extra tenant", tenants: []string{"acme", "acme"}, expectedCode: codes.PermissionDenied},
+		{name: "unknown tenant", tenants: []string{"megacorp"}, expectedCode: codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spanProcessor := &mockSpanProcessor{}
+			metricsFactory := metricstest.NewFactory(0)
+			client := newOTLPTestClient(t, newOTLPHandler(zap.NewNop(), spanProcessor, tm, metricsFactory))
+
+			ctx := context.Background()
+			for _, tenant := range test.tenants {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-tenant", tenant)
+			}
+			_, err := client.Export(ctx, loadOTLPRequest(t))
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode == codes.OK {
+				assert.Len(t, spanProcessor.getSpans(), 3)
+				assert.Equal(t, map[string]bool{"acme": true}, spanProcessor.getTenants())
+			} else {
+				assert.Empty(t, spanProcessor.getSpans())
+				metricsFactory.AssertCounterMetrics(t, rejectedSpansMetric("grpc", "tenancy", 3))
+			}
+		})
+	}
+}
+
+func sendOTLP(t *testing.T, method, url, contentType string, body []byte, header http.Header) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, resBody
+}
+
+func TestOTLPHandlerHTTP(t *testing.T) {
+	request := loadOTLPRequest(t)
+	protoBody, err := request.MarshalProto()
+	require.NoError(t, err)
+
+	encodings := []struct {
+		contentType       string
+		body              []byte
+		unmarshalResponse func(response ptraceotlp.ExportResponse, buf []byte) error
+	}{
+		{
+			contentType: "application/json",
+			body:        loadOTLPFixture(t),
+			unmarshalResponse: func(response ptraceotlp.ExportResponse, buf []byte) error {
+				return response.UnmarshalJSON(buf)
+			},
+		},
+		{
+			contentType: "application/x-protobuf",
+			body:        protoBody,
+			unmarshalResponse: func(response ptraceotlp.ExportResponse, buf []byte) error {
+				return response.UnmarshalProto(buf)
+			},
+		},
+	}
+	for _, encoding := range encodings {
+		t.Run(encoding.contentType, func(t *testing.T) {
+			spanProcessor := &otlpSpanProcessor{dropped: map[string]bool{"customer": true}}
+			metricsFactory := metricstest.NewFactory(0)
+			h := newOTLPHandler(zap.NewNop(), spanProcessor, &tenancy.Manager{}, metricsFactory)
+			server := httptest.NewServer(http.HandlerFunc(h.serveHTTP))
+			defer server.Close()
+
+			res, body := sendOTLP(t, http.MethodPost, server.URL+otlpTracesURLPath, encoding.contentType, encoding.body, nil)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, encoding.contentType, res.Header.Get("Content-Type"))
+			response := ptraceotlp.NewExportResponse()
+			require.NoError(t, encoding.unmarshalResponse(response, body))
+			assert.EqualValues(t, 1, response.PartialSuccess().RejectedSpans())
+			assert.Equal(t, "server busy", response.PartialSuccess().ErrorMessage())
+
+			assert.Len(t, spanProcessor.getSpans(), 3)
+			assert.Equal(t, []processor.InboundTransport{processor.HTTPTransport, processor.HTTPTransport}, spanProcessor.transports)
+			metricsFactory.AssertCounterMetrics(t, rejectedSpansMetric("http", "queue_full", 1))
+		})
+	}
+}
+
+func TestOTLPHandlerHTTPErrors(t *testing.T) {
+	tm := tenancy.NewManager(&tenancy.Options{
+		Enabled: true,
+		Header:  "x-tenant",
+		Tenants: []string{"acme"},
+	})
+	validTenant := http.Header{"X-Tenant": []string{"acme"}}
+	tests := []struct {
+		name         string
+		method       string
+		contentType  string
+		body         []byte
+		header       http.Header
+		processorErr error
+		expectedCode int
+		expectedMsg  string
+	}{
+		{
+			name:         "unsupported content type",
+			contentType:  "text/plain",
+			header:       validTenant,
+			expectedCode: http.StatusUnsupportedMediaType,
+			expectedMsg:  `unsupported content type "text/plain"`,
+		},
+		{
+			name:         "unsupported method",
+			method:       http.MethodGet,
+			header:       validTenant,
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedMsg:  "unsupported method GET",
+		},
+		{
+			name:         "malformed body",
+			body:         []byte("{"),
+			header:       validTenant,
+			expectedCode: http.StatusBadRequest,
+			expectedMsg:  "cannot parse request",
+		},
+		{
+			name:         "missing tenant",
+			expectedCode: http.StatusUnauthorized,
+			expectedMsg:  "missing tenant header",
+		},
+		{
+			name:         "unknown tenant",
+			header:       http.Header{"X-Tenant": []string{"megacorp"}},
+			expectedCode: http.StatusUnauthorized,
+			expectedMsg:  "unknown tenant",
+		},
+		{
+			name:         "busy",
+			header:       validTenant,
+			processorErr: processor.ErrBusy,
+			expectedCode: http.StatusTooManyRequests,
+			expectedMsg:  "server busy",
+		},
+		{
+			name:         "internal error",
+			header:       validTenant,
+			processorErr: errors.New("storage error"),
+			expectedCode: http.StatusInternalServerError,
+			expectedMsg:  "storage error",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spanProcessor := &mockSpanProcessor{expectedError: test.processorErr}
+			h := newOTLPHandler(zap.NewNop(), spanProcessor, tm, metricstest.NewFactory(0))
+			server := httptest.NewServer(http.HandlerFunc(h.serveHTTP))
+			defer server.Close()
+
+			method, contentType, body := test.method, test.contentType, test.body
+			if method == "" {
+				method = http.MethodPost
+			}
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			if body == nil {
+				body = loadOTLPFixture(t)
+			}
+			res, resBody := sendOTLP(t, method, server.URL+otlpTracesURLPath, contentType, body, test.header)
+			assert.Equal(t, test.expectedCode, res.StatusCode)
+			assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+			s := status.New(codes.OK, "").Proto()
+			require.NoError(t, protojson.Unmarshal(resBody, s))
+			assert.Contains(t, s.GetMessage(), test.expectedMsg)
+		})
+	}
+}
+
+func TestOTLPHandlerHTTPProtobufError(t *testing.T) {
+	h := newOTLPHandler(zap.NewNop(), &mockSpanProcessor{}, &tenancy.Manager{}, metricstest.NewFactory(0))
+	server := httptest.NewServer(http.HandlerFunc(h.serveHTTP))
+	defer server.Close()
+
+	res, body := sendOTLP(t, http.MethodPost, server.URL+otlpTracesURLPath, "application/x-protobuf", []byte("not protobuf"), nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, "application/x-protobuf", res.Header.Get("Content-Type"))
+	s := status.New(codes.Unknown, "").Proto()
+	require.NoError(t, proto.Unmarshal(body, s))
+	assert.Equal(t, int32(codes.InvalidArgument), s.GetCode())
+	assert.Contains(t, s.GetMessage(), "cannot parse request")
+}
+
+func TestWriteOTLPHTTPErrorMessage(t *testing.T) {
+	for _, test := range []struct {
+		statusCode   int
+		expectedCode codes.Code
+	}{
+		{statusCode: http.StatusBadRequest, expectedCode: codes.InvalidArgument},
+		{statusCode: http.StatusInternalServerError, expectedCode: codes.Unknown},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, otlpTracesURLPath, nil)
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		writeOTLPHTTPErrorMessage(w, req, "cannot decompress", test.statusCode)
+
+		assert.Equal(t, test.statusCode, w.Code)
+		s := status.New(codes.OK, "").Proto()
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), s))
+		assert.Equal(t, int32(test.expectedCode), s.GetCode())
+		assert.Equal(t, "cannot decompress", s.GetMessage())
+	}
+}
@@ -16,89 +16,134 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
-	otlp2jaeger "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/jaeger"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/config/configtls"
-	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/extension"
-	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
 	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/receiver/otlpreceiver"
 	"go.opentelemetry.io/otel"
 	noopmetric "go.opentelemetry.io/otel/metric/noop"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
 	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
-	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/config/tlscfg"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
 	"github.com/kjschnei001/jaeger/pkg/tenancy"
 )
 
-var _ component.Host = (*otelHost)(nil) // API check
+var (
+	_ component.Host  = (*otelHost)(nil)     // API check
+	_ receiver.Traces = (*otlpReceiver)(nil) // API check
+)
 
-// StartOTLPReceiver starts OpenTelemetry OTLP receiver listening on gRPC and HTTP ports.
-func StartOTLPReceiver(options *flags.CollectorOptions, logger *zap.Logger, spanProcessor processor.SpanProcessor, tm *tenancy.Manager) (receiver.Traces, error) {
-	otlpFactory := otlpreceiver.NewFactory()
-	return startOTLPReceiver(
-		options,
-		logger,
-		spanProcessor,
-		tm,
-		otlpFactory,
-		consumer.NewTraces,
-		otlpFactory.CreateTracesReceiver,
-	)
-}
+// otlpTracesURLPath is the path of the OTLP/HTTP trace service
+const otlpTracesURLPath = "/v1/traces"
 
-// Some of OTELCOL constructor functions return errors when passed nil arguments,
-// which is a situation we cannot reproduce. To test our own error handling, this
-// function allows to mock those constructors.
-func startOTLPReceiver(
+// StartOTLPReceiver starts OpenTelemetry OTLP receiver listening on gRPC and HTTP ports.
+func StartOTLPReceiver(
 	options *flags.CollectorOptions,
 	logger *zap.Logger,
 	spanProcessor processor.SpanProcessor,
 	tm *tenancy.Manager,
-	// from here: params that can be mocked in tests
-	otlpFactory receiver.Factory,
-	newTraces func(consume consumer.ConsumeTracesFunc, options ...consumer.Option) (consumer.Traces, error),
-	createTracesReceiver func(ctx context.Context, set receiver.CreateSettings,
-		cfg component.Config, nextConsumer consumer.Traces) (receiver.Traces, error),
+	metricsFactory metrics.Factory,
 ) (receiver.Traces, error) {
-	otlpReceiverConfig := otlpFactory.CreateDefaultConfig().(*otlpreceiver.Config)
+	// The servers are configured like the ones of the OpenTelemetry OTLP receiver,
+	// but that receiver cannot report the partially accepted requests to the clients.
+	otlpReceiverConfig := otlpreceiver.NewFactory().CreateDefaultConfig().(*otlpreceiver.Config)
 	applyGRPCSettings(otlpReceiverConfig.GRPC, &options.OTLP.GRPC)
 	applyHTTPSettings(otlpReceiverConfig.HTTP, &options.OTLP.HTTP)
-	otlpReceiverSettings := receiver.CreateSettings{
-		TelemetrySettings: component.TelemetrySettings{
+	otlpReceiver := &otlpReceiver{
+		config: otlpReceiverConfig,
+		settings: component.TelemetrySettings{
 			Logger:         logger,
 			TracerProvider: otel.GetTracerProvider(),      // TODO we may always want no-op here, not the global default
 			MeterProvider:  noopmetric.NewMeterProvider(), // TODO wire this with jaegerlib metrics?
 		},
+		handler: newOTLPHandler(logger, spanProcessor, tm, metricsFactory),
+	}
+	if err := otlpReceiver.Start(context.Background(), &otelHost{logger: logger}); err != nil {
+		return nil, fmt.Errorf("could not start the OTLP receiver: %w", err)
+	}
+	return otlpReceiver, nil
+}
+
+// otlpReceiver serves the OTLP trace service over gRPC and HTTP.
+type otlpReceiver struct {
+	config     *otlpreceiver.Config
+	settings   component.TelemetrySettings
+	handler    *otlpHandler
+	grpcServer *grpc.Server
+	httpServer *http.Server
+}
+
+func (r *otlpReceiver) Start(_ context.Context, host component.Host) error {
+	if err := r.startGRPCServer(host); err != nil {
+		return err
+	}
+	if err := r.startHTTPServer(host); err != nil {
+		r.grpcServer.Stop()
+		return err
 	}
+	return nil
+}
 
-	otlpConsumer := newConsumerDelegate(logger, spanProcessor, tm)
-	// the following two constructors never return errors given non-nil arguments, so we ignore errors
-	nextConsumer, err := newTraces(otlpConsumer.consume)
+func (r *otlpReceiver) startGRPCServer(host component.Host) error {
+	listener, err := r.config.GRPC.ToListener()
 	if err != nil {
-		return nil, fmt.Errorf("could not create the OTLP consumer: %w", err)
-	}
-	otlpReceiver, err := createTracesReceiver(
-		context.Background(),
-		otlpReceiverSettings,
-		otlpReceiverConfig,
-		nextConsumer,
-	)
+		return err
+	}
+	r.grpcServer, err = r.config.GRPC.ToServer(host, r.settings)
 	if err != nil {
-		return nil, fmt.Errorf("could not create the OTLP receiver: %w", err)
+		listener.Close()
+		return err
 	}
-	if err := otlpReceiver.Start(context.Background(), &otelHost{logger: logger}); err != nil {
-		return nil, fmt.Errorf("could not start the OTLP receiver: %w", err)
+	ptraceotlp.RegisterGRPCServer(r.grpcServer, &otlpGRPCServer{handler: r.handler})
+	go func() {
+		if err := r.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			host.ReportFatalError(err)
+		}
+	}()
+	return nil
+}
+
+func (r *otlpReceiver) startHTTPServer(host component.Host) error {
+	listener, err := r.config.HTTP.ToListener()
+	if err != nil {
+		return err
 	}
-	return otlpReceiver, nil
+	mux := http.NewServeMux()
+	mux.HandleFunc(otlpTracesURLPath, r.handler.serveHTTP)
+	r.httpServer, err = r.config.HTTP.ToServer(host, r.settings, mux, confighttp.WithErrorHandler(writeOTLPHTTPErrorMessage))
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	go func() {
+		if err := r.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			host.ReportFatalError(err)
+		}
+	}()
+	return nil
+}
+
+func (r *otlpReceiver) Shutdown(ctx context.Context) error {
+	var err error
+	if r.httpServer != nil {
+		err = r.httpServer.Shutdown(ctx)
+	}
+	if r.grpcServer != nil {
+		r.grpcServer.GracefulStop()
+	}
+	return err
 }
 
 func applyGRPCSettings(cfg *configgrpc.GRPCServerSettings, opts *flags.GRPCOptions) {
@@ -143,36 +188,6 @@ func applyTLSSettings(opts *tlscfg.Options) *configtls.TLSServerSetting {
 	}
 }
 
-func newConsumerDelegate(logger *zap.Logger, spanProcessor processor.SpanProcessor, tm *tenancy.Manager) *consumerDelegate {
-	return &consumerDelegate{
-		batchConsumer: newBatchConsumer(logger,
-			spanProcessor,
-			processor.UnknownTransport, // could be gRPC or HTTP
-			processor.OTLPSpanFormat,
-			tm),
-		protoFromTraces: otlp2jaeger.ProtoFromTraces,
-	}
-}
-
-type consumerDelegate struct {
-	batchConsumer   batchConsumer
-	protoFromTraces func(td ptrace.Traces) ([]*model.Batch, error)
-}
-
-func (c *consumerDelegate) consume(ctx context.Context, td ptrace.Traces) error {
-	batches, err := c.protoFromTraces(td)
-	if err != nil {
-		return err
-	}
-	for _, batch := range batches {
-		err := c.batchConsumer.consume(ctx, batch)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // otelHost is a mostly no-op implementation of OTEL component.Host
 type otelHost struct {
 	logger *zap.Logger
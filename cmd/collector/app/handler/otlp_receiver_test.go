@@ -23,14 +23,18 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component"
-	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
-	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
 	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/config/tlscfg"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
 	"github.com/kjschnei001/jaeger/pkg/tenancy"
 	"github.com/kjschnei001/jaeger/pkg/testutils"
 )
@@ -50,14 +54,16 @@ func TestStartOtlpReceiver(t *testing.T) {
 	spanProcessor := &mockSpanProcessor{}
 	logger, _ := testutils.NewLogger()
 	tm := &tenancy.Manager{}
-	rec, err := StartOTLPReceiver(optionsWithPorts(":0"), logger, spanProcessor, tm)
+	rec, err := StartOTLPReceiver(optionsWithPorts("localhost:0"), logger, spanProcessor, tm, metrics.NullFactory)
 	require.NoError(t, err)
 	defer func() {
 		assert.NoError(t, rec.Shutdown(context.Background()))
 	}()
 
-	// Ideally, we want to test with a real gRPC client, but OTEL repos only have those as internal packages.
-	// So we will rely on otlpreceiver being tested in the OTEL repos, and we only test the consumer function.
+	// the servers are tested with otlpHandler, as their listeners use ephemeral ports
+	r := rec.(*otlpReceiver)
+	assert.NotNil(t, r.grpcServer)
+	assert.NotNil(t, r.httpServer)
 }
 
 func makeTracesOneSpan() ptrace.Traces {
@@ -66,6 +72,8 @@ func makeTracesOneSpan() ptrace.Traces {
 	sSpans := rSpans.ScopeSpans().AppendEmpty()
 	span := sSpans.Spans().AppendEmpty()
 	span.SetName("test")
+	span.SetTraceID(pcommon.TraceID([16]byte{1}))
+	span.SetSpanID(pcommon.SpanID([8]byte{1}))
 	return traces
 }
 
@@ -81,12 +89,13 @@ func TestConsumerDelegate(t *testing.T) {
 		t.Run(test.expectLog, func(t *testing.T) {
 			logger, logBuf := testutils.NewLogger()
 			spanProcessor := &mockSpanProcessor{expectedError: test.expectErr}
-			consumer := newConsumerDelegate(logger, spanProcessor, &tenancy.Manager{})
+			h := newOTLPHandler(logger, spanProcessor, &tenancy.Manager{}, metrics.NullFactory)
 
-			err := consumer.consume(context.Background(), makeTracesOneSpan())
+			_, err := h.export(makeTracesOneSpan(), processor.GRPCTransport, nil)
 
 			if test.expectErr != nil {
-				require.Equal(t, test.expectErr, err)
+				assert.Equal(t, codes.Internal, status.Code(err))
+				assert.Contains(t, err.Error(), test.expectLog)
 				assert.Contains(t, logBuf.String(), test.expectLog)
 			} else {
 				require.NoError(t, err)
@@ -96,42 +105,34 @@ func TestConsumerDelegate(t *testing.T) {
 	}
 }
 
+func TestProtoFromTracesError(t *testing.T) {
+	mockErr := errors.New("mock error")
+	spanProcessor := &mockSpanProcessor{}
+	h := newOTLPHandler(zap.NewNop(), spanProcessor, &tenancy.Manager{}, metrics.NullFactory)
+	h.protoFromTraces = func(td ptrace.Traces) ([]*model.Batch, error) {
+		return nil, mockErr
+	}
+	_, err := h.export(makeTracesOneSpan(), processor.GRPCTransport, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), mockErr.Error())
+	assert.Empty(t, spanProcessor.getSpans())
+}
+
 func TestStartOtlpReceiver_Error(t *testing.T) {
 	spanProcessor := &mockSpanProcessor{}
 	logger, _ := testutils.NewLogger()
-	opts := optionsWithPorts(":-1")
 	tm := &tenancy.Manager{}
-	_, err := StartOTLPReceiver(opts, logger, spanProcessor, tm)
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "could not start the OTLP receiver")
 
-	newTraces := func(consumer.ConsumeTracesFunc, ...consumer.Option) (consumer.Traces, error) {
-		return nil, errors.New("mock error")
-	}
-	f := otlpreceiver.NewFactory()
-	_, err = startOTLPReceiver(opts, logger, spanProcessor, &tenancy.Manager{}, f, newTraces, f.CreateTracesReceiver)
+	opts := optionsWithPorts(":-1")
+	_, err := StartOTLPReceiver(opts, logger, spanProcessor, tm, metrics.NullFactory)
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "could not create the OTLP consumer")
+	assert.Contains(t, err.Error(), "could not start the OTLP receiver")
 
-	createTracesReceiver := func(
-		context.Context, receiver.CreateSettings, component.Config, consumer.Traces,
-	) (receiver.Traces, error) {
-		return nil, errors.New("mock error")
-	}
-	_, err = startOTLPReceiver(opts, logger, spanProcessor, &tenancy.Manager{}, f, consumer.NewTraces, createTracesReceiver)
+	opts = optionsWithPorts("localhost:0")
+	opts.OTLP.HTTP.HostPort = ":-1"
+	_, err = StartOTLPReceiver(opts, logger, spanProcessor, tm, metrics.NullFactory)
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "could not create the OTLP receiver")
-}
-
-func TestProtoFromTracesError(t *testing.T) {
-	mockErr := errors.New("mock error")
-	c := &consumerDelegate{
-		protoFromTraces: func(td ptrace.Traces) ([]*model.Batch, error) {
-			return nil, mockErr
-		},
-	}
-	err := c.consume(context.Background(), ptrace.Traces{})
-	assert.Equal(t, mockErr, err)
+	assert.Contains(t, err.Error(), "could not start the OTLP receiver")
 }
 
 func TestOtelHost_ReportFatalError(t *testing.T) {
@@ -23,6 +23,7 @@ import (
 	"github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer"
 	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/pkg/queue"
 )
 
 type options struct {
@@ -31,6 +32,7 @@ type options struct {
 	hostMetrics            metrics.Factory
 	preProcessSpans        ProcessSpans // see docs in PreProcessSpans option.
 	sanitizer              sanitizer.SanitizeSpan
+	attributeSanitizer     sanitizer.SanitizeSpan
 	preSave                ProcessSpan
 	spanFilter             FilterSpan
 	numWorkers             int
@@ -43,6 +45,8 @@ type options struct {
 	collectorTags          map[string]string
 	spanSizeMetricsEnabled bool
 	onDroppedSpan          func(span *model.Span)
+	tailSampling           *TailSamplingConfig
+	persistentQueue        *queue.PersistentQueueOptions
 }
 
 // Option is a function that sets some option on StorageBuilder.
@@ -88,6 +92,14 @@ func (options) Sanitizer(sanitizer sanitizer.SanitizeSpan) Option {
 	}
 }
 
+// AttributeSanitizer creates an Option that initializes the sanitizer applied to the span tags
+// before the spans are queued, so that the tags it removes are never buffered or written to disk.
+func (options) AttributeSanitizer(attributeSanitizer sanitizer.SanitizeSpan) Option {
+	return func(b *options) {
+		b.attributeSanitizer = attributeSanitizer
+	}
+}
+
 // PreSave creates an Option that initializes the preSave function
 func (options) PreSave(preSave ProcessSpan) Option {
 	return func(b *options) {
@@ -172,6 +184,21 @@ func (options) OnDroppedSpan(onDroppedSpan func(span *model.Span)) Option {
 	}
 }
 
+// TailSampling creates an Option that enables tail-based sampling of the traces before they are written
+func (options) TailSampling(tailSampling TailSamplingConfig) Option {
+	return func(b *options) {
+		b.tailSampling = &tailSampling
+	}
+}
+
+// PersistentQueue creates an Option that replaces the in-memory queue with a queue writing the spans to disk,
+// its capacity is still set by QueueSize
+func (options) PersistentQueue(persistentQueue queue.PersistentQueueOptions) Option {
+	return func(b *options) {
+		b.persistentQueue = &persistentQueue
+	}
+}
+
 func (o options) apply(opts ...Option) options {
 	ret := options{}
 	for _, opt := range opts {
@@ -192,6 +219,9 @@ func (o options) apply(opts ...Option) options {
 	if ret.sanitizer == nil {
 		ret.sanitizer = func(span *model.Span) *model.Span { return span }
 	}
+	if ret.attributeSanitizer == nil {
+		ret.attributeSanitizer = func(span *model.Span) *model.Span { return span }
+	}
 	if ret.preSave == nil {
 		ret.preSave = func(span *model.Span, tenant string) {}
 	}
@@ -40,6 +40,7 @@ func TestAllOptionSet(t *testing.T) {
 		Options.NumWorkers(5),
 		Options.PreProcessSpans(func(spans []*model.Span, tenant string) {}),
 		Options.Sanitizer(func(span *model.Span) *model.Span { return span }),
+		Options.AttributeSanitizer(func(span *model.Span) *model.Span { return span }),
 		Options.QueueSize(10),
 		Options.DynQueueSizeWarmup(1000),
 		Options.DynQueueSizeMemory(1024),
@@ -69,6 +70,7 @@ func TestNoOptionsSet(t *testing.T) {
 	assert.True(t, opts.spanFilter(nil))
 	span := model.Span{}
 	assert.EqualValues(t, &span, opts.sanitizer(&span))
+	assert.EqualValues(t, &span, opts.attributeSanitizer(&span))
 	assert.EqualValues(t, 0, opts.dynQueueSizeWarmup)
 	assert.False(t, opts.spanSizeMetricsEnabled)
 	assert.Nil(t, opts.onDroppedSpan)
@@ -21,8 +21,12 @@ import (
 	"github.com/kjschnei001/jaeger/model"
 )
 
-// ErrBusy signalizes that processor cannot process incoming data
-var ErrBusy = errors.New("server busy")
+var (
+	// ErrBusy signalizes that processor cannot process incoming data
+	ErrBusy = errors.New("server busy")
+	// ErrRateLimited signalizes that the incoming data exceeds the rate limits of its tenant or service
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
 
 // SpansOptions additional options passed to processor along with the spans.
 type SpansOptions struct {
@@ -0,0 +1,310 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/fswatcher"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+)
+
+// RateLimit is a token bucket refilled with SpansPerSecond tokens per second up to Burst tokens.
+// A batch of spans costs one token per span. A batch larger than Burst is only admitted when the
+// bucket is full, and the bucket is then refilled for the spans beyond Burst before the next batch.
+type RateLimit struct {
+	SpansPerSecond float64 `json:"spans_per_second"`
+	// Burst defaults to SpansPerSecond, or to 1 if SpansPerSecond is lower.
+	Burst float64 `json:"burst,omitempty"`
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return math.Max(l.SpansPerSecond, 1)
+}
+
+func (l RateLimit) newLimiter() *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(l.SpansPerSecond), int(math.Ceil(l.burst())))
+}
+
+func (l RateLimit) update(limiter *rate.Limiter, now time.Time) {
+	limiter.SetLimitAt(now, rate.Limit(l.SpansPerSecond))
+	limiter.SetBurstAt(now, int(math.Ceil(l.burst())))
+}
+
+// RateLimits is the content of the rate limits file. The tenant limits apply to all the spans of a
+// tenant, and the service limits apply to the spans of each service within each tenant.
+// The spans without a specific or a default limit are not limited.
+type RateLimits struct {
+	DefaultTenant  *RateLimit           `json:"default_tenant,omitempty"`
+	Tenants        map[string]RateLimit `json:"tenants,omitempty"`
+	DefaultService *RateLimit           `json:"default_service,omitempty"`
+	Services       map[string]RateLimit `json:"services,omitempty"`
+}
+
+func (l *RateLimits) tenantLimit(tenant string) *RateLimit {
+	if limit, ok := l.Tenants[tenant]; ok {
+		return &limit
+	}
+	return l.DefaultTenant
+}
+
+func (l *RateLimits) serviceLimit(service string) *RateLimit {
+	if limit, ok := l.Services[service]; ok {
+		return &limit
+	}
+	return l.DefaultService
+}
+
+func loadRateLimits(path string) (*RateLimits, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rate limits file %v: %w", path, err)
+	}
+	var limits RateLimits
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits file %v: %w", path, err)
+	}
+	return &limits, nil
+}
+
+// maxRateLimitBuckets bounds the number of token buckets, the full ones being dropped first.
+const maxRateLimitBuckets = 10000
+
+// rateLimitKey identifies a token bucket, service is empty for the bucket of the whole tenant.
+type rateLimitKey struct {
+	tenant  string
+	service string
+}
+
+// rateLimitingProcessor is a SpanProcessor rejecting the batches of spans exceeding the rate limits
+// of their tenant or service with processor.ErrRateLimited, before they are queued.
+type rateLimitingProcessor struct {
+	processor.SpanProcessor
+	path           string
+	logger         *zap.Logger
+	metricsFactory metrics.Factory
+	watcher        *fswatcher.FSWatcher
+
+	mu       sync.Mutex
+	limits   *RateLimits
+	buckets  map[rateLimitKey]*rate.Limiter
+	rejected map[rateLimitKey]metrics.Counter
+	timeNow  func() time.Time
+}
+
+// NewRateLimitingProcessor wraps spanProcessor to enforce the rate limits of the file at path,
+// which is reloaded when it changes.
+func NewRateLimitingProcessor(
+	spanProcessor processor.SpanProcessor,
+	path string,
+	metricsFactory metrics.Factory,
+	logger *zap.Logger,
+) (processor.SpanProcessor, error) {
+	limits, err := loadRateLimits(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &rateLimitingProcessor{
+		SpanProcessor:  spanProcessor,
+		path:           path,
+		logger:         logger,
+		metricsFactory: metricsFactory.Namespace(metrics.NSOptions{Name: "rate_limits"}),
+		limits:         limits,
+		buckets:        make(map[rateLimitKey]*rate.Limiter),
+		rejected:       make(map[rateLimitKey]metrics.Counter),
+		timeNow:        time.Now,
+	}
+	watcher, err := fswatcher.New([]string{path}, p.reload, logger)
+	if err != nil {
+		return nil, err
+	}
+	p.watcher = watcher
+	return p, nil
+}
+
+func (p *rateLimitingProcessor) reload() {
+	p.logger.Info("reloading rate limits", zap.String("filename", p.path))
+	limits, err := loadRateLimits(p.path)
+	if err != nil {
+		p.logger.Error("error while reloading the rate limits, keeping the previous ones", zap.Error(err))
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limits = limits
+	// the buckets keep their balance, capped to their new burst
+	now := p.timeNow()
+	for key, bucket := range p.buckets {
+		if limit := p.limit(key); limit != nil {
+			limit.update(bucket, now)
+		} else {
+			delete(p.buckets, key)
+		}
+	}
+	p.logger.Info("reloaded rate limits", zap.String("filename", p.path))
+}
+
+func (p *rateLimitingProcessor) limit(key rateLimitKey) *RateLimit {
+	if key.service == "" {
+		return p.limits.tenantLimit(key.tenant)
+	}
+	return p.limits.serviceLimit(key.service)
+}
+
+// ProcessSpans implements processor.SpanProcessor.
+func (p *rateLimitingProcessor) ProcessSpans(spans []*model.Span, options processor.SpansOptions) ([]bool, error) {
+	counts := make(map[rateLimitKey]int)
+	for _, span := range spans {
+		service := ""
+		if span.Process != nil {
+			service = span.Process.ServiceName
+		}
+		counts[rateLimitKey{tenant: options.Tenant, service: service}]++
+	}
+	counts[rateLimitKey{tenant: options.Tenant}] = len(spans)
+	if !p.allow(counts) {
+		return nil, processor.ErrRateLimited
+	}
+	return p.SpanProcessor.ProcessSpans(spans, options)
+}
+
+// allow charges the buckets of the tenant and of the services, and counts the rejected spans of
+// the buckets without enough tokens. It does not charge any bucket if one of them is short.
+func (p *rateLimitingProcessor) allow(counts map[rateLimitKey]int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.timeNow()
+	allowed := true
+	reservations := make([]*rate.Reservation, 0, len(counts))
+	for key, count := range counts {
+		bucket := p.bucket(key, now)
+		if bucket == nil {
+			continue
+		}
+		if bucketReservations, ok := reserve(bucket, now, count); ok {
+			reservations = append(reservations, bucketReservations...)
+			continue
+		}
+		allowed = false
+		p.rejectedCounter(key).Inc(int64(count))
+	}
+	if !allowed {
+		// refund the buckets charged for the rejected batch, up to their burst
+		cancelReservations(reservations, now)
+	}
+	return allowed
+}
+
+// reserve takes count tokens from the bucket, or none if it does not have enough tokens. The
+// bucket cannot hold more than its burst, so a larger count is only taken from a full bucket,
+// and the tokens beyond the burst are borrowed from the future, i.e. the bucket stays short
+// until it has been refilled for them.
+func reserve(bucket *rate.Limiter, now time.Time, count int) ([]*rate.Reservation, bool) {
+	var reservations []*rate.Reservation
+	for count > 0 {
+		n := count
+		if burst := bucket.Burst(); n > burst {
+			n = burst
+		}
+		reservation := bucket.ReserveN(now, n)
+		// only the first reservation must be acted on now, the next ones are the borrowed tokens
+		if !reservation.OK() || (len(reservations) == 0 && reservation.DelayFrom(now) > 0) {
+			reservation.CancelAt(now)
+			cancelReservations(reservations, now)
+			return nil, false
+		}
+		reservations = append(reservations, reservation)
+		count -= n
+	}
+	return reservations, true
+}
+
+// cancelReservations returns the tokens of the reservations, in the reverse order of the
+// reservations so that each of them returns all its tokens.
+func cancelReservations(reservations []*rate.Reservation, now time.Time) {
+	for i := len(reservations) - 1; i >= 0; i-- {
+		reservations[i].CancelAt(now)
+	}
+}
+
+func (p *rateLimitingProcessor) bucket(key rateLimitKey, now time.Time) *rate.Limiter {
+	if bucket, ok := p.buckets[key]; ok {
+		return bucket
+	}
+	limit := p.limit(key)
+	if limit == nil {
+		return nil
+	}
+	if len(p.buckets) >= maxRateLimitBuckets {
+		p.evictBuckets(now)
+	}
+	bucket := limit.newLimiter()
+	p.buckets[key] = bucket
+	return bucket
+}
+
+// evictBuckets drops the full buckets, which are the same as new ones. If none is full,
+// one bucket is dropped anyway, its spans getting a full burst the next time.
+func (p *rateLimitingProcessor) evictBuckets(now time.Time) {
+	for key, bucket := range p.buckets {
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(p.buckets, key)
+		}
+	}
+	for key := range p.buckets {
+		if len(p.buckets) < maxRateLimitBuckets {
+			break
+		}
+		delete(p.buckets, key)
+	}
+}
+
+func (p *rateLimitingProcessor) rejectedCounter(key rateLimitKey) metrics.Counter {
+	if key.service != "" && len(p.rejected) >= maxServiceNames {
+		if _, ok := p.rejected[key]; !ok {
+			key.service = otherServices
+		}
+	}
+	if counter, ok := p.rejected[key]; ok {
+		return counter
+	}
+	tags := map[string]string{"tenant": key.tenant, "service": key.service}
+	if key.service == "" {
+		tags["service"] = "all"
+	}
+	counter := p.metricsFactory.Counter(metrics.Options{Name: "spans_rejected", Tags: tags})
+	p.rejected[key] = counter
+	return counter
+}
+
+// Close closes the file watcher and the wrapped SpanProcessor.
+func (p *rateLimitingProcessor) Close() error {
+	p.watcher.Close()
+	return p.SpanProcessor.Close()
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+)
+
+type countingSpanProcessor struct {
+	mu     sync.Mutex
+	spans  int
+	closed bool
+}
+
+func (p *countingSpanProcessor) ProcessSpans(spans []*model.Span, _ processor.SpansOptions) ([]bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans += len(spans)
+	return make([]bool, len(spans)), nil
+}
+
+func (p *countingSpanProcessor) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *countingSpanProcessor) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.spans
+}
+
+func writeRateLimits(t *testing.T, path string, content string) {
+	// swap the file like a configuration management tool would
+	temp := path + ".tmp"
+	require.NoError(t, os.WriteFile(temp, []byte(content), 0o600))
+	require.NoError(t, os.Rename(temp, path))
+}
+
+func spansOf(services ...string) []*model.Span {
+	spans := make([]*model.Span, 0, len(services))
+	for _, service := range services {
+		spans = append(spans, &model.Span{Process: &model.Process{ServiceName: service}})
+	}
+	return spans
+}
+
+func newTestRateLimitingProcessor(t *testing.T, content string) (string, *countingSpanProcessor, processor.SpanProcessor, *metricstest.Factory) {
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+	writeRateLimits(t, path, content)
+	inner := &countingSpanProcessor{}
+	metricsFactory := metricstest.NewFactory(0)
+	p, err := NewRateLimitingProcessor(inner, path, metricsFactory, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { p.Close() })
+	return path, inner, p, metricsFactory
+}
+
+func TestRateLimitingProcessorTenantLimits(t *testing.T) {
+	_, inner, p, metricsFactory := newTestRateLimitingProcessor(t, `{
+		"default_tenant": {"spans_per_second": 0.001, "burst": 3},
+		"tenants": {"big": {"spans_per_second": 0.001, "burst": 10}}
+	}`)
+
+	_, err := p.ProcessSpans(spansOf("a", "b", "c"), processor.SpansOptions{Tenant: "small"})
+	require.NoError(t, err)
+	_, err = p.ProcessSpans(spansOf("a"), processor.SpansOptions{Tenant: "small"})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+	_, err = p.ProcessSpans(spansOf("a", "b", "c", "d", "e"), processor.SpansOptions{Tenant: "big"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 8, inner.count())
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{
+		Name:  "rate_limits.spans_rejected",
+		Tags:  map[string]string{"tenant": "small", "service": "all"},
+		Value: 1,
+	})
+}
+
+func TestRateLimitingProcessorServiceLimits(t *testing.T) {
+	_, inner, p, metricsFactory := newTestRateLimitingProcessor(t, `{
+		"tenants": {"acme": {"spans_per_second": 0.001, "burst": 10}},
+		"services": {"noisy": {"spans_per_second": 0.001, "burst": 2}}
+	}`)
+
+	_, err := p.ProcessSpans(spansOf("noisy", "quiet"), processor.SpansOptions{Tenant: "acme"})
+	require.NoError(t, err)
+	// the batch is rejected as a whole, and the tenant bucket is refunded
+	_, err = p.ProcessSpans(spansOf("noisy", "noisy", "quiet"), processor.SpansOptions{Tenant: "acme"})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+	// services are limited within each tenant
+	_, err = p.ProcessSpans(spansOf("noisy", "noisy"), processor.SpansOptions{Tenant: "other"})
+	require.NoError(t, err)
+	_, err = p.ProcessSpans(spansOf("quiet", "quiet", "quiet", "quiet", "quiet", "quiet", "quiet", "quiet"), processor.SpansOptions{Tenant: "acme"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 12, inner.count())
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{
+		Name:  "rate_limits.spans_rejected",
+		Tags:  map[string]string{"tenant": "acme", "service": "noisy"},
+		Value: 2,
+	})
+}
+
+func TestRateLimitingProcessorRefund(t *testing.T) {
+	_, inner, p, _ := newTestRateLimitingProcessor(t, `{
+		"default_tenant": {"spans_per_second": 1000, "burst": 10},
+		"services": {"noisy": {"spans_per_second": 0.001, "burst": 1}}
+	}`)
+	now := time.Now()
+	p.(*rateLimitingProcessor).timeNow = func() time.Time { return now }
+
+	_, err := p.ProcessSpans(spansOf("noisy"), processor.SpansOptions{})
+	require.NoError(t, err)
+	now = now.Add(time.Second)
+	// the refund of the rejected batch does not exceed the burst of the tenant
+	_, err = p.ProcessSpans(spansOf("noisy", "noisy"), processor.SpansOptions{})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+	_, err = p.ProcessSpans(spansOf("a", "a", "a", "a", "a", "a", "a", "a", "a", "a"), processor.SpansOptions{})
+	require.NoError(t, err)
+	_, err = p.ProcessSpans(spansOf("a"), processor.SpansOptions{})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+	assert.Equal(t, 11, inner.count())
+}
+
+func TestRateLimitingProcessorBatchLargerThanBurst(t *testing.T) {
+	_, inner, p, _ := newTestRateLimitingProcessor(t, `{
+		"default_tenant": {"spans_per_second": 10, "burst": 10},
+		"services": {"noisy": {"spans_per_second": 0.001, "burst": 1}}
+	}`)
+	now := time.Now()
+	p.(*rateLimitingProcessor).timeNow = func() time.Time { return now }
+	batch := func(size int) []*model.Span {
+		services := make([]string, size)
+		for i := range services {
+			services[i] = "a"
+		}
+		return spansOf(services...)
+	}
+
+	_, err := p.ProcessSpans(batch(1), processor.SpansOptions{})
+	require.NoError(t, err)
+	// the batch larger than the burst waits for the bucket to be full
+	_, err = p.ProcessSpans(batch(25), processor.SpansOptions{})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+	now = now.Add(100 * time.Millisecond)
+	_, err = p.ProcessSpans(batch(25), processor.SpansOptions{})
+	require.NoError(t, err)
+	// the 15 spans beyond the burst are refilled before the next span, i.e. after 1.5s
+	now = now.Add(1500 * time.Millisecond)
+	_, err = p.ProcessSpans(batch(1), processor.SpansOptions{})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+	now = now.Add(100 * time.Millisecond)
+	_, err = p.ProcessSpans(batch(1), processor.SpansOptions{})
+	require.NoError(t, err)
+
+	// a rejected batch larger than the burst returns the borrowed tokens
+	now = now.Add(time.Second)
+	_, err = p.ProcessSpans(spansOf("noisy"), processor.SpansOptions{})
+	require.NoError(t, err)
+	now = now.Add(time.Second)
+	_, err = p.ProcessSpans(append(batch(20), spansOf("noisy")...), processor.SpansOptions{})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+	_, err = p.ProcessSpans(batch(10), processor.SpansOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 38, inner.count())
+}
+
+func TestRateLimitingProcessorBounds(t *testing.T) {
+	_, _, p, metricsFactory := newTestRateLimitingProcessor(t, `{"default_service": {"spans_per_second": 0.001, "burst": 1}}`)
+	rp := p.(*rateLimitingProcessor)
+	for i := 0; i <= maxRateLimitBuckets; i++ {
+		_, err := p.ProcessSpans(spansOf(strconv.Itoa(i)), processor.SpansOptions{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, rp.buckets, maxRateLimitBuckets)
+
+	for i := 0; i <= maxServiceNames; i++ {
+		rp.rejectedCounter(rateLimitKey{service: strconv.Itoa(i)}).Inc(1)
+	}
+	assert.Len(t, rp.rejected, maxServiceNames+1)
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{
+		Name:  "rate_limits.spans_rejected",
+		Tags:  map[string]string{"tenant": "", "service": otherServices},
+		Value: 1,
+	})
+}
+
+func TestRateLimitingProcessorReload(t *testing.T) {
+	path, inner, p, _ := newTestRateLimitingProcessor(t, `{"default_tenant": {"spans_per_second": 0.001, "burst": 1}}`)
+
+	_, err := p.ProcessSpans(spansOf("a"), processor.SpansOptions{})
+	require.NoError(t, err)
+	_, err = p.ProcessSpans(spansOf("a", "b"), processor.SpansOptions{})
+	assert.ErrorIs(t, err, processor.ErrRateLimited)
+
+	writeRateLimits(t, path, `{"default_tenant": {"spans_per_second": 1000, "burst": 1000}}`)
+	require.Eventually(t, func() bool {
+		_, err := p.ProcessSpans(spansOf("a", "b"), processor.SpansOptions{})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// removing the limit removes the bucket
+	writeRateLimits(t, path, `{}`)
+	require.Eventually(t, func() bool {
+		rp := p.(*rateLimitingProcessor)
+		rp.mu.Lock()
+		defer rp.mu.Unlock()
+		return len(rp.buckets) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = p.ProcessSpans(spansOf("a", "b"), processor.SpansOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, inner.count())
+
+	// an invalid file keeps the previous limits
+	writeRateLimits(t, path, `{"default_tenant": `)
+	time.Sleep(50 * time.Millisecond)
+	_, err = p.ProcessSpans(spansOf("a", "b"), processor.SpansOptions{})
+	require.NoError(t, err)
+}
+
+func TestRateLimitingProcessorErrors(t *testing.T) {
+	_, err := NewRateLimitingProcessor(&countingSpanProcessor{}, "/does/not/exist.json", metricstest.NewFactory(0), zap.NewNop())
+	assert.ErrorContains(t, err, "cannot read rate limits file")
+
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+	writeRateLimits(t, path, `[]`)
+	_, err = NewRateLimitingProcessor(&countingSpanProcessor{}, path, metricstest.NewFactory(0), zap.NewNop())
+	assert.ErrorContains(t, err, "cannot parse rate limits file")
+}
+
+func TestRateLimitingProcessorClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+	writeRateLimits(t, path, `{}`)
+	inner := &countingSpanProcessor{}
+	p, err := NewRateLimitingProcessor(inner, path, metricstest.NewFactory(0), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	assert.True(t, inner.closed)
+}
+
+func TestRateLimitBurst(t *testing.T) {
+	assert.Equal(t, 5.0, RateLimit{SpansPerSecond: 1, Burst: 5}.burst())
+	assert.Equal(t, 100.0, RateLimit{SpansPerSecond: 100}.burst())
+	assert.Equal(t, 1.0, RateLimit{SpansPerSecond: 0.5}.burst())
+}
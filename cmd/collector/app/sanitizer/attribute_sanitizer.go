@@ -0,0 +1,252 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// The actions of the attribute rules.
+const (
+	// DropAction removes the tags matching Key or KeyPattern.
+	DropAction = "drop"
+	// HashAction replaces the value of the tag Key with its hex-encoded HMAC-SHA256, keyed with the
+	// hash key so that the values cannot be recovered by hashing guessed values.
+	HashAction = "hash"
+	// RedactAction replaces the matches of Pattern in the string value of the tag Key with Replacement.
+	RedactAction = "redact"
+	// RenameAction renames the tag Key to NewKey.
+	RenameAction = "rename"
+	// AddAction sets the tag Key to the string Value.
+	AddAction = "add"
+)
+
+// AttributeRule is a rule applied to the tags, the log fields and the process tags of the spans of
+// Services and Operations, or of all the spans if they are empty. AddAction only adds span tags.
+type AttributeRule struct {
+	Action      string   `json:"action"`
+	Services    []string `json:"services,omitempty"`
+	Operations  []string `json:"operations,omitempty"`
+	Key         string   `json:"key,omitempty"`
+	KeyPattern  string   `json:"key_pattern,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Replacement string   `json:"replacement,omitempty"`
+	NewKey      string   `json:"new_key,omitempty"`
+	Value       string   `json:"value,omitempty"`
+}
+
+// AttributeRules is the content of the attribute rules file, the rules are applied in order.
+type AttributeRules struct {
+	Rules []AttributeRule `json:"rules"`
+}
+
+// LoadAttributeRules reads the attribute rules from a JSON file.
+func LoadAttributeRules(path string) (*AttributeRules, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read attribute rules file %v: %w", path, err)
+	}
+	var rules AttributeRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("cannot parse attribute rules file %v: %w", path, err)
+	}
+	return &rules, nil
+}
+
+// LoadHashKey reads the key of the hash action from a file, without the surrounding white space.
+func LoadHashKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read attribute hash key file %v: %w", path, err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("attribute hash key file %v is empty", path)
+	}
+	return key, nil
+}
+
+type attributeRule struct {
+	AttributeRule
+	services   map[string]bool
+	operations map[string]bool
+	keyPattern *regexp.Regexp
+	pattern    *regexp.Regexp
+	hashKey    []byte
+}
+
+// NewAttributeSanitizer creates a sanitizer applying the rules to the spans, the hash action
+// requires the hash key. A process shared by the spans of a batch is copied before its tags
+// are changed.
+func NewAttributeSanitizer(rules *AttributeRules, hashKey []byte) (SanitizeSpan, error) {
+	sanitizer := attributeSanitizer{}
+	for i, rule := range rules.Rules {
+		r, err := newAttributeRule(rule, hashKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute rule #%d: %w", i, err)
+		}
+		sanitizer.rules = append(sanitizer.rules, r)
+	}
+	return sanitizer.Sanitize, nil
+}
+
+func newAttributeRule(rule AttributeRule, hashKey []byte) (*attributeRule, error) {
+	r := &attributeRule{
+		AttributeRule: rule,
+		services:      ToSet(rule.Services),
+		operations:    ToSet(rule.Operations),
+	}
+	var err error
+	switch rule.Action {
+	case DropAction:
+		if rule.Key == "" && rule.KeyPattern == "" {
+			return nil, fmt.Errorf("%s requires a key or a key_pattern", rule.Action)
+		}
+		if rule.KeyPattern != "" {
+			if r.keyPattern, err = regexp.Compile(rule.KeyPattern); err != nil {
+				return nil, err
+			}
+		}
+	case RedactAction:
+		if rule.Key == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("%s requires a key and a pattern", rule.Action)
+		}
+		if r.pattern, err = regexp.Compile(rule.Pattern); err != nil {
+			return nil, err
+		}
+	case RenameAction:
+		if rule.Key == "" || rule.NewKey == "" {
+			return nil, fmt.Errorf("%s requires a key and a new_key", rule.Action)
+		}
+	case HashAction:
+		if rule.Key == "" {
+			return nil, fmt.Errorf("%s requires a key", rule.Action)
+		}
+		if len(hashKey) == 0 {
+			return nil, errors.New("hash requires the hash key to be configured")
+		}
+		r.hashKey = hashKey
+	case AddAction:
+		if rule.Key == "" {
+			return nil, fmt.Errorf("%s requires a key", rule.Action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", rule.Action)
+	}
+	return r, nil
+}
+
+// ToSet returns the set of the values, or nil if there are none, so that a nil
+// set of services or operations can scope a rule to all the spans.
+func ToSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+type attributeSanitizer struct {
+	rules []*attributeRule
+}
+
+// Sanitize applies the matching rules to the tags, the log fields and the process tags of the span.
+func (s *attributeSanitizer) Sanitize(span *model.Span) *model.Span {
+	processCopied := false
+	for _, rule := range s.rules {
+		if !rule.matches(span) {
+			continue
+		}
+		span.Tags = rule.apply(span.Tags)
+		if rule.Action == AddAction {
+			continue
+		}
+		for i := range span.Logs {
+			span.Logs[i].Fields = rule.apply(span.Logs[i].Fields)
+		}
+		if span.Process == nil {
+			continue
+		}
+		if !processCopied {
+			span.Process = &model.Process{
+				ServiceName: span.Process.ServiceName,
+				Tags:        append([]model.KeyValue(nil), span.Process.Tags...),
+			}
+			processCopied = true
+		}
+		span.Process.Tags = rule.apply(span.Process.Tags)
+	}
+	return span
+}
+
+func (r *attributeRule) matches(span *model.Span) bool {
+	if r.services != nil && (span.Process == nil || !r.services[span.Process.ServiceName]) {
+		return false
+	}
+	return r.operations == nil || r.operations[span.OperationName]
+}
+
+func (r *attributeRule) apply(tags []model.KeyValue) []model.KeyValue {
+	switch r.Action {
+	case DropAction:
+		kept := tags[:0]
+		for _, tag := range tags {
+			if tag.Key != r.Key && (r.keyPattern == nil || !r.keyPattern.MatchString(tag.Key)) {
+				kept = append(kept, tag)
+			}
+		}
+		return kept
+	case AddAction:
+		for i := range tags {
+			if tags[i].Key == r.Key {
+				tags[i] = model.String(r.Key, r.Value)
+				return tags
+			}
+		}
+		return append(tags, model.String(r.Key, r.Value))
+	}
+	for i := range tags {
+		if tags[i].Key != r.Key {
+			continue
+		}
+		switch r.Action {
+		case HashAction:
+			mac := hmac.New(sha256.New, r.hashKey)
+			mac.Write([]byte(tags[i].AsString()))
+			tags[i] = model.String(r.Key, hex.EncodeToString(mac.Sum(nil)))
+		case RedactAction:
+			if tags[i].VType == model.StringType {
+				tags[i].VStr = r.pattern.ReplaceAllString(tags[i].VStr, r.Replacement)
+			}
+		case RenameAction:
+			tags[i].Key = r.NewKey
+		}
+	}
+	return tags
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+func newTestSpan() *model.Span {
+	return &model.Span{
+		OperationName: "query",
+		Process:       model.NewProcess("db-client", []model.KeyValue{model.String("user.email", "process@example.com")}),
+		Logs: []model.Log{
+			{Fields: []model.KeyValue{model.String("user.email", "log@example.com"), model.String("debug.id", "3")}},
+		},
+		Tags: []model.KeyValue{
+			model.String("user.email", "jane@example.com"),
+			model.String("db.statement", "SELECT * FROM cards WHERE number = '4111111111111111'"),
+			model.String("debug.id", "1"),
+			model.String("debug.trace", "2"),
+			model.String("http.status", "200"),
+			model.Int64("retries", 3),
+		},
+	}
+}
+
+func TestAttributeSanitizer(t *testing.T) {
+	s, err := NewAttributeSanitizer(&AttributeRules{Rules: []AttributeRule{
+		{Action: DropAction, KeyPattern: `^debug\.`},
+		{Action: HashAction, Key: "user.email"},
+		{Action: RedactAction, Key: "db.statement", Pattern: `\d{13,16}`, Replacement: "****"},
+		{Action: RedactAction, Key: "retries", Pattern: `\d`, Replacement: "*"},
+		{Action: RenameAction, Key: "http.status", NewKey: "http.status_code"},
+		{Action: AddAction, Key: "env", Value: "prod"},
+	}}, []byte("secret"))
+	require.NoError(t, err)
+
+	original := newTestSpan()
+	process := original.Process
+	span := s(original)
+	assert.Equal(t, []model.KeyValue{
+		// the HMAC-SHA256 of jane@example.com keyed by "secret"
+		model.String("user.email", "fb817989d942e7ffb3d4b8b204f7abca29f4c25c3fa46574da84c50f30d07513"),
+		model.String("db.statement", "SELECT * FROM cards WHERE number = '****'"),
+		model.String("http.status_code", "200"),
+		// only string values are redacted
+		model.Int64("retries", 3),
+		model.String("env", "prod"),
+	}, span.Tags)
+	assert.Equal(t, []model.KeyValue{
+		model.String("user.email", "eae393958acd086560355265812a02d1d703ea742bba19ec4bc24f88ceef420d"),
+	}, span.Logs[0].Fields)
+	assert.Equal(t, []model.KeyValue{
+		model.String("user.email", "af2f10e779cd46dadda70a824e5d40287a4ab23c40919aef82dd61a6776db141"),
+	}, span.Process.Tags)
+	// the process shared with the other spans of the batch is not changed
+	assert.Equal(t, "process@example.com", process.Tags[0].VStr)
+}
+
+func TestAttributeSanitizerOverridesTag(t *testing.T) {
+	s, err := NewAttributeSanitizer(&AttributeRules{Rules: []AttributeRule{
+		{Action: AddAction, Key: "retries", Value: "many"},
+		{Action: DropAction, Key: "user.email"},
+	}}, nil)
+	require.NoError(t, err)
+
+	span := s(newTestSpan())
+	assert.Len(t, span.Tags, 5)
+	assert.Equal(t, model.String("retries", "many"), span.Tags[4])
+	_, found := model.KeyValues(span.Tags).FindByKey("user.email")
+	assert.False(t, found)
+}
+
+func TestAttributeSanitizerScope(t *testing.T) {
+	s, err := NewAttributeSanitizer(&AttributeRules{Rules: []AttributeRule{
+		{Action: AddAction, Key: "service", Value: "matched", Services: []string{"db-client"}},
+		{Action: AddAction, Key: "operation", Value: "matched", Operations: []string{"query"}},
+		{Action: AddAction, Key: "other", Value: "matched", Services: []string{"db-client"}, Operations: []string{"insert"}},
+	}}, nil)
+	require.NoError(t, err)
+
+	span := s(newTestSpan())
+	_, found := model.KeyValues(span.Tags).FindByKey("service")
+	assert.True(t, found)
+	_, found = model.KeyValues(span.Tags).FindByKey("operation")
+	assert.True(t, found)
+	_, found = model.KeyValues(span.Tags).FindByKey("other")
+	assert.False(t, found)
+
+	span = s(&model.Span{OperationName: "insert"})
+	assert.Empty(t, span.Tags)
+}
+
+func TestNewAttributeSanitizerErrors(t *testing.T) {
+	tests := []struct {
+		rule AttributeRule
+		err  string
+	}{
+		{rule: AttributeRule{Action: "encrypt", Key: "a"}, err: `unknown action "encrypt"`},
+		{rule: AttributeRule{Action: DropAction}, err: "drop requires a key or a key_pattern"},
+		{rule: AttributeRule{Action: DropAction, KeyPattern: "("}, err: "error parsing regexp"},
+		{rule: AttributeRule{Action: RedactAction, Key: "a"}, err: "redact requires a key and a pattern"},
+		{rule: AttributeRule{Action: RedactAction, Key: "a", Pattern: "("}, err: "error parsing regexp"},
+		{rule: AttributeRule{Action: RenameAction, Key: "a"}, err: "rename requires a key and a new_key"},
+		{rule: AttributeRule{Action: HashAction}, err: "hash requires a key"},
+		{rule: AttributeRule{Action: HashAction, Key: "a"}, err: "hash requires the hash key to be configured"},
+		{rule: AttributeRule{Action: AddAction}, err: "add requires a key"},
+	}
+	for _, test := range tests {
+		t.Run(test.err, func(t *testing.T) {
+			_, err := NewAttributeSanitizer(&AttributeRules{Rules: []AttributeRule{test.rule}}, nil)
+			assert.ErrorContains(t, err, "invalid attribute rule #0: "+test.err)
+		})
+	}
+}
+
+func TestLoadAttributeRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": [
+		{"action": "hash", "key": "user.email", "services": ["checkout"]},
+		{"action": "rename", "key": "a", "new_key": "b"}
+	]}`), 0o600))
+	rules, err := LoadAttributeRules(path)
+	require.NoError(t, err)
+	assert.Equal(t, &AttributeRules{Rules: []AttributeRule{
+		{Action: HashAction, Key: "user.email", Services: []string{"checkout"}},
+		{Action: RenameAction, Key: "a", NewKey: "b"},
+	}}, rules)
+
+	_, err = LoadAttributeRules(filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "cannot read attribute rules file")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules": {}}`), 0o600))
+	_, err = LoadAttributeRules(path)
+	assert.ErrorContains(t, err, "cannot parse attribute rules file")
+}
+
+func TestLoadHashKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(path, []byte("secret\n"), 0o600))
+	key, err := LoadHashKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), key)
+
+	_, err = LoadHashKey(filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, err, "cannot read attribute hash key file")
+
+	require.NoError(t, os.WriteFile(path, []byte(" \n"), 0o600))
+	_, err = LoadHashKey(path)
+	assert.ErrorContains(t, err, "is empty")
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/fswatcher"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+)
+
+// SpanFilterRule drops the spans matching all of its conditions. Services restricts the rule
+// to the spans of these services, and Tags to the spans having these tags, with any value
+// when the value is empty.
+type SpanFilterRule struct {
+	Name        string            `yaml:"name"`
+	Services    []string          `yaml:"services"`
+	Operations  []string          `yaml:"operations"`
+	ShorterThan time.Duration     `yaml:"shorter_than"`
+	Tags        map[string]string `yaml:"tags"`
+}
+
+// SpanFilterRules is the content of the span filter file.
+type SpanFilterRules struct {
+	Rules []SpanFilterRule `yaml:"rules"`
+}
+
+func loadSpanFilterRules(path string) (*SpanFilterRules, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read span filter file %v: %w", path, err)
+	}
+	var rules SpanFilterRules
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("cannot parse span filter file %v: %w", path, err)
+	}
+	names := make(map[string]bool, len(rules.Rules))
+	for i, rule := range rules.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("span filter rule #%d has no name", i)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate span filter rule %s", rule.Name)
+		}
+		names[rule.Name] = true
+		if len(rule.Operations) == 0 && rule.ShorterThan <= 0 && len(rule.Tags) == 0 && len(rule.Services) == 0 {
+			return nil, fmt.Errorf("span filter rule %s has no condition", rule.Name)
+		}
+	}
+	return &rules, nil
+}
+
+type spanFilterRule struct {
+	services   map[string]bool
+	operations map[string]bool
+	rule       SpanFilterRule
+	dropped    metrics.Counter
+}
+
+func (r *spanFilterRule) matches(span *model.Span) bool {
+	if r.services != nil && (span.Process == nil || !r.services[span.Process.ServiceName]) {
+		return false
+	}
+	if r.operations != nil && !r.operations[span.OperationName] {
+		return false
+	}
+	if r.rule.ShorterThan > 0 && span.Duration >= r.rule.ShorterThan {
+		return false
+	}
+	for key, value := range r.rule.Tags {
+		tag, ok := model.KeyValues(span.Tags).FindByKey(key)
+		if !ok || (value != "" && tag.AsString() != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// SpanFilter drops the spans matching the rules of a YAML file, which is reloaded when it changes.
+type SpanFilter struct {
+	path           string
+	logger         *zap.Logger
+	metricsFactory metrics.Factory
+	watcher        *fswatcher.FSWatcher
+
+	mu    sync.RWMutex
+	rules []*spanFilterRule
+}
+
+// NewSpanFilter creates a SpanFilter from the rules of the file at path.
+func NewSpanFilter(path string, metricsFactory metrics.Factory, logger *zap.Logger) (*SpanFilter, error) {
+	rules, err := loadSpanFilterRules(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &SpanFilter{
+		path:           path,
+		logger:         logger,
+		metricsFactory: metricsFactory.Namespace(metrics.NSOptions{Name: "span_filter"}),
+	}
+	f.setRules(rules)
+	watcher, err := fswatcher.New([]string{path}, f.reload, logger)
+	if err != nil {
+		return nil, err
+	}
+	f.watcher = watcher
+	return f, nil
+}
+
+func (f *SpanFilter) setRules(rules *SpanFilterRules) {
+	compiled := make([]*spanFilterRule, 0, len(rules.Rules))
+	for _, rule := range rules.Rules {
+		compiled = append(compiled, &spanFilterRule{
+			services:   sanitizer.ToSet(rule.Services),
+			operations: sanitizer.ToSet(rule.Operations),
+			rule:       rule,
+			dropped: f.metricsFactory.Counter(metrics.Options{
+				Name: "spans_dropped",
+				Tags: map[string]string{"rule": rule.Name},
+			}),
+		})
+	}
+	f.mu.Lock()
+	f.rules = compiled
+	f.mu.Unlock()
+}
+
+func (f *SpanFilter) reload() {
+	f.logger.Info("reloading span filter rules", zap.String("filename", f.path))
+	rules, err := loadSpanFilterRules(f.path)
+	if err != nil {
+		f.logger.Error("error while reloading the span filter rules, keeping the previous ones", zap.Error(err))
+		return
+	}
+	f.setRules(rules)
+	f.logger.Info("reloaded span filter rules", zap.String("filename", f.path), zap.Int("rules", len(rules.Rules)))
+}
+
+// Filter implements FilterSpan, it returns false for the spans matching a rule.
+func (f *SpanFilter) Filter(span *model.Span) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, rule := range f.rules {
+		if rule.matches(span) {
+			rule.dropped.Inc(1)
+			return false
+		}
+	}
+	return true
+}
+
+// Close stops watching the file.
+func (f *SpanFilter) Close() error {
+	return f.watcher.Close()
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+)
+
+const testSpanFilterRules = `
+rules:
+  - name: health-checks
+    operations: ["/health", "/ready"]
+  - name: short-frontend-spans
+    services: [frontend]
+    shorter_than: 100us
+  - name: debug
+    tags:
+      debug: ""
+      sampler.type: const
+`
+
+func writeSpanFilterRules(t *testing.T, path string, content string) {
+	// swap the file like a configuration management tool would
+	temp := path + ".tmp"
+	require.NoError(t, os.WriteFile(temp, []byte(content), 0o600))
+	require.NoError(t, os.Rename(temp, path))
+}
+
+func newTestSpanFilter(t *testing.T, content string) (string, *SpanFilter, *metricstest.Factory) {
+	path := filepath.Join(t.TempDir(), "span_filter.yaml")
+	writeSpanFilterRules(t, path, content)
+	metricsFactory := metricstest.NewFactory(0)
+	f, err := NewSpanFilter(path, metricsFactory, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return path, f, metricsFactory
+}
+
+func TestSpanFilter(t *testing.T) {
+	_, f, metricsFactory := newTestSpanFilter(t, testSpanFilterRules)
+
+	frontend := &model.Process{ServiceName: "frontend"}
+	backend := &model.Process{ServiceName: "backend"}
+	tests := []struct {
+		name string
+		span *model.Span
+		keep bool
+	}{
+		{
+			name: "health check",
+			span: &model.Span{OperationName: "/health", Process: backend, Duration: time.Second},
+		},
+		{
+			name: "other operation",
+			span: &model.Span{OperationName: "/checkout", Process: backend, Duration: time.Second},
+			keep: true,
+		},
+		{
+			name: "short frontend span",
+			span: &model.Span{OperationName: "render", Process: frontend, Duration: 99 * time.Microsecond},
+		},
+		{
+			name: "long frontend span",
+			span: &model.Span{OperationName: "render", Process: frontend, Duration: 100 * time.Microsecond},
+			keep: true,
+		},
+		{
+			name: "short backend span",
+			span: &model.Span{OperationName: "render", Process: backend, Duration: time.Microsecond},
+			keep: true,
+		},
+		{
+			name: "debug tags",
+			span: &model.Span{OperationName: "render", Process: backend, Duration: time.Second, Tags: []model.KeyValue{
+				model.Bool("debug", true),
+				model.String("sampler.type", "const"),
+			}},
+		},
+		{
+			name: "partial debug tags",
+			span: &model.Span{OperationName: "render", Process: backend, Duration: time.Second, Tags: []model.KeyValue{
+				model.Bool("debug", true),
+				model.String("sampler.type", "probabilistic"),
+			}},
+			keep: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.keep, f.Filter(test.span))
+		})
+	}
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "span_filter.spans_dropped", Tags: map[string]string{"rule": "health-checks"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "span_filter.spans_dropped", Tags: map[string]string{"rule": "short-frontend-spans"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "span_filter.spans_dropped", Tags: map[string]string{"rule": "debug"}, Value: 1},
+	)
+}
+
+func TestSpanFilterReload(t *testing.T) {
+	path, f, metricsFactory := newTestSpanFilter(t, testSpanFilterRules)
+	span := &model.Span{OperationName: "/metrics", Process: &model.Process{ServiceName: "backend"}}
+	assert.True(t, f.Filter(span))
+
+	writeSpanFilterRules(t, path, "rules:\n  - name: metrics\n    operations: [/metrics]\n")
+	require.Eventually(t, func() bool {
+		return !f.Filter(span)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// an invalid file keeps the previous rules
+	writeSpanFilterRules(t, path, "rules:\n  - operations: [/other]\n")
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, f.Filter(span))
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "span_filter.spans_dropped", Tags: map[string]string{"rule": "metrics"}, Value: 2},
+	)
+}
+
+func TestSpanFilterErrors(t *testing.T) {
+	_, err := NewSpanFilter("/does/not/exist.yaml", metricstest.NewFactory(0), zap.NewNop())
+	assert.ErrorContains(t, err, "cannot read span filter file")
+
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{content: "rules: {}", err: "cannot parse span filter file"},
+		{content: "rules:\n  - name: a\n    unknown: b\n", err: "cannot parse span filter file"},
+		{content: "rules:\n  - operations: [a]\n", err: "span filter rule #0 has no name"},
+		{content: "rules:\n  - name: a\n    operations: [a]\n  - name: a\n    operations: [b]\n", err: "duplicate span filter rule a"},
+		{content: "rules:\n  - name: a\n", err: "span filter rule a has no condition"},
+	}
+	for _, test := range tests {
+		t.Run(test.err, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "span_filter.yaml")
+			writeSpanFilterRules(t, path, test.content)
+			_, err := NewSpanFilter(path, metricstest.NewFactory(0), zap.NewNop())
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestSpanHandlerBuilderSpanFilter(t *testing.T) {
+	builder := &SpanHandlerBuilder{}
+	assert.True(t, builder.spanFilter()(&model.Span{}))
+	builder.SpanFilter = func(*model.Span) bool { return false }
+	assert.False(t, builder.spanFilter()(&model.Span{}))
+}
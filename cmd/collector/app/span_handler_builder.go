@@ -23,9 +23,11 @@ import (
 	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/handler"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer"
 	zs "github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer/zipkin"
 	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/pkg/queue"
 	"github.com/kjschnei001/jaeger/pkg/tenancy"
 	"github.com/kjschnei001/jaeger/storage/spanstore"
 )
@@ -37,6 +39,10 @@ type SpanHandlerBuilder struct {
 	Logger         *zap.Logger
 	MetricsFactory metrics.Factory
 	TenancyMgr     *tenancy.Manager
+	// AttributeSanitizer is applied to the span tags before the spans are queued, if not nil
+	AttributeSanitizer sanitizer.SanitizeSpan
+	// SpanFilter replaces the default span filter, if not nil
+	SpanFilter FilterSpan
 }
 
 // SpanHandlers holds instances to the span handlers built by the SpanHandlerBuilder
@@ -47,25 +53,39 @@ type SpanHandlers struct {
 }
 
 // BuildSpanProcessor builds the span processor to be used with the handlers
-func (b *SpanHandlerBuilder) BuildSpanProcessor(additional ...ProcessSpan) processor.SpanProcessor {
+func (b *SpanHandlerBuilder) BuildSpanProcessor(additional ...ProcessSpan) (processor.SpanProcessor, error) {
 	hostname, _ := os.Hostname()
 	svcMetrics := b.metricsFactory()
 	hostMetrics := svcMetrics.Namespace(metrics.NSOptions{Tags: map[string]string{"host": hostname}})
 
-	return NewSpanProcessor(
-		b.SpanWriter,
-		additional,
+	opts := []Option{
 		Options.ServiceMetrics(svcMetrics),
 		Options.HostMetrics(hostMetrics),
 		Options.Logger(b.logger()),
-		Options.SpanFilter(defaultSpanFilter),
+		Options.SpanFilter(b.spanFilter()),
 		Options.NumWorkers(b.CollectorOpts.NumWorkers),
 		Options.QueueSize(b.CollectorOpts.QueueSize),
 		Options.CollectorTags(b.CollectorOpts.CollectorTags),
 		Options.DynQueueSizeWarmup(uint(b.CollectorOpts.QueueSize)), // same as queue size for now
 		Options.DynQueueSizeMemory(b.CollectorOpts.DynQueueSizeMemory),
 		Options.SpanSizeMetricsEnabled(b.CollectorOpts.SpanSizeMetricsEnabled),
-	)
+	}
+	if b.AttributeSanitizer != nil {
+		opts = append(opts, Options.AttributeSanitizer(b.AttributeSanitizer))
+	}
+	if b.CollectorOpts.TailSampling.DecisionWait > 0 {
+		opts = append(opts, Options.TailSampling(NewTailSamplingConfig(b.CollectorOpts.TailSampling)))
+	}
+	if b.CollectorOpts.QueueType == flags.DiskQueueType {
+		opts = append(opts, Options.PersistentQueue(queue.PersistentQueueOptions{
+			Directory:    b.CollectorOpts.DiskQueue.Directory,
+			MaxBytes:     b.CollectorOpts.DiskQueue.MaxBytes,
+			SegmentBytes: b.CollectorOpts.DiskQueue.SegmentBytes,
+			SyncPolicy:   queue.SyncPolicy(b.CollectorOpts.DiskQueue.SyncPolicy),
+			SyncInterval: b.CollectorOpts.DiskQueue.SyncInterval,
+		}))
+	}
+	return NewSpanProcessor(b.SpanWriter, additional, opts...)
 }
 
 // BuildHandlers builds span handlers (Zipkin, Jaeger)
@@ -81,6 +101,13 @@ func (b *SpanHandlerBuilder) BuildHandlers(spanProcessor processor.SpanProcessor
 	}
 }
 
+func (b *SpanHandlerBuilder) spanFilter() FilterSpan {
+	if b.SpanFilter != nil {
+		return b.SpanFilter
+	}
+	return defaultSpanFilter
+}
+
 func defaultSpanFilter(*model.Span) bool {
 	return true
 }
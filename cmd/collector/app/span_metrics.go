@@ -0,0 +1,165 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+)
+
+const (
+	// maxOperationNames limits the number of operations tracked per service by the span metrics
+	maxOperationNames = 1000
+	// otherOperations is the catch-all label when the number of operations of a service exceeds maxOperationNames
+	otherOperations = "other-operations"
+
+	otelStatusCode      = "otel.status_code"
+	statusCodeError     = "STATUS_CODE_ERROR"
+	statusCodeOK        = "STATUS_CODE_OK"
+	statusCodeUnset     = "STATUS_CODE_UNSET"
+	spanKindUnspecified = "SPAN_KIND_UNSPECIFIED"
+	spanKindPrefix      = "SPAN_KIND_"
+	callsMetricName     = "calls"
+	latencyMetricName   = "latency"
+)
+
+// latencyBucketsMs are the default latency buckets, in milliseconds, of the OpenTelemetry spanmetrics processor.
+var latencyBucketsMs = []float64{2, 4, 6, 8, 10, 50, 100, 200, 400, 800, 1000, 1400, 2000, 5000, 10000, 15000}
+
+type spanMetricsKey struct {
+	service    string
+	operation  string
+	spanKind   string
+	statusCode string
+}
+
+type spanMetricsSeries struct {
+	calls   metrics.Counter
+	latency metrics.Histogram
+}
+
+// spanMetrics computes the request, error and duration (RED) metrics of the spans, with the names
+// and labels of the OpenTelemetry spanmetrics processor queried by the Prometheus metrics reader
+// of the query service: calls_total and latency, in milliseconds, by service_name, operation,
+// span_kind and status_code.
+type spanMetrics struct {
+	// metricsFactory must not be namespaced, for the names to match the queries
+	metricsFactory metrics.Factory
+
+	mu         sync.Mutex
+	series     map[spanMetricsKey]*spanMetricsSeries
+	operations map[string]map[string]bool
+}
+
+func newSpanMetrics(metricsFactory metrics.Factory) *spanMetrics {
+	return &spanMetrics{
+		metricsFactory: metricsFactory,
+		series:         make(map[spanMetricsKey]*spanMetricsSeries),
+		operations:     make(map[string]map[string]bool),
+	}
+}
+
+// processSpan is a ProcessSpan updating the metrics of the span.
+func (m *spanMetrics) processSpan(span *model.Span, _ string) {
+	service := ""
+	if span.Process != nil {
+		service = span.Process.ServiceName
+	}
+	series := m.getSeries(spanMetricsKey{
+		service:    service,
+		operation:  span.OperationName,
+		spanKind:   spanKindLabel(span),
+		statusCode: statusCodeLabel(span),
+	})
+	series.calls.Inc(1)
+	series.latency.Record(float64(span.Duration.Microseconds()) / 1000)
+}
+
+func (m *spanMetrics) getSeries(key spanMetricsKey) *spanMetricsSeries {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if series, ok := m.series[key]; ok {
+		return series
+	}
+	key = m.limitCardinality(key)
+	if series, ok := m.series[key]; ok {
+		return series
+	}
+	tags := map[string]string{
+		"service_name": key.service,
+		"operation":    key.operation,
+		"span_kind":    key.spanKind,
+		"status_code":  key.statusCode,
+	}
+	series := &spanMetricsSeries{
+		calls: m.metricsFactory.Counter(metrics.Options{
+			Name: callsMetricName,
+			Tags: tags,
+			Help: "Number of spans by service, operation, span kind and status code",
+		}),
+		latency: m.metricsFactory.Histogram(metrics.HistogramOptions{
+			Name:    latencyMetricName,
+			Tags:    tags,
+			Help:    "Duration of the spans in milliseconds by service, operation, span kind and status code",
+			Buckets: latencyBucketsMs,
+		}),
+	}
+	m.series[key] = series
+	return series
+}
+
+// limitCardinality replaces the service and operation of the key by catch-all labels once
+// maxServiceNames services, or maxOperationNames operations of the service, are tracked.
+func (m *spanMetrics) limitCardinality(key spanMetricsKey) spanMetricsKey {
+	operations, ok := m.operations[key.service]
+	if !ok {
+		if len(m.operations) >= maxServiceNames {
+			key.service = otherServices
+			key.operation = otherOperations
+			return key
+		}
+		operations = make(map[string]bool)
+		m.operations[key.service] = operations
+	}
+	if !operations[key.operation] {
+		if len(operations) >= maxOperationNames {
+			key.operation = otherOperations
+			return key
+		}
+		operations[key.operation] = true
+	}
+	return key
+}
+
+func spanKindLabel(span *model.Span) string {
+	kind, ok := span.GetSpanKind()
+	if !ok || kind == "" {
+		return spanKindUnspecified
+	}
+	return spanKindPrefix + strings.ToUpper(kind)
+}
+
+func statusCodeLabel(span *model.Span) string {
+	if span.IsError() {
+		return statusCodeError
+	}
+	if tag, ok := model.KeyValues(span.Tags).FindByKey(otelStatusCode); ok && tag.AsString() == "OK" {
+		return statusCodeOK
+	}
+	return statusCodeUnset
+}
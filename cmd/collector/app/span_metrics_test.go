@@ -0,0 +1,136 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promModel "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jprom "github.com/kjschnei001/jaeger/internal/metrics/prometheus"
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+)
+
+func TestSpanMetricsPrometheusNames(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := newSpanMetrics(jprom.New(jprom.WithRegisterer(registry)))
+
+	process := &model.Process{ServiceName: "frontend"}
+	m.processSpan(&model.Span{
+		OperationName: "GET /",
+		Process:       process,
+		Duration:      3 * time.Millisecond,
+		Tags:          []model.KeyValue{model.String("span.kind", "server")},
+	}, "")
+	m.processSpan(&model.Span{
+		OperationName: "GET /",
+		Process:       process,
+		Duration:      30 * time.Millisecond,
+		Tags:          []model.KeyValue{model.String("span.kind", "server"), model.Bool("error", true)},
+	}, "")
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	byName := make(map[string]*promModel.MetricFamily)
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+
+	require.Contains(t, byName, "calls_total")
+	calls := byName["calls_total"].GetMetric()
+	require.Len(t, calls, 2)
+	for _, metric := range calls {
+		labels := labelsOf(metric)
+		assert.Equal(t, "frontend", labels["service_name"])
+		assert.Equal(t, "GET /", labels["operation"])
+		assert.Equal(t, "SPAN_KIND_SERVER", labels["span_kind"])
+		assert.Contains(t, []string{"STATUS_CODE_ERROR", "STATUS_CODE_UNSET"}, labels["status_code"])
+		assert.Equal(t, 1.0, metric.GetCounter().GetValue())
+	}
+
+	require.Contains(t, byName, "latency")
+	for _, metric := range byName["latency"].GetMetric() {
+		histogram := metric.GetHistogram()
+		assert.Equal(t, uint64(1), histogram.GetSampleCount())
+		if labelsOf(metric)["status_code"] == "STATUS_CODE_ERROR" {
+			assert.Equal(t, 30.0, histogram.GetSampleSum())
+		} else {
+			assert.Equal(t, 3.0, histogram.GetSampleSum())
+		}
+		assert.Equal(t, len(latencyBucketsMs), len(histogram.GetBucket()))
+	}
+}
+
+func labelsOf(metric *promModel.Metric) map[string]string {
+	labels := make(map[string]string)
+	for _, label := range metric.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return labels
+}
+
+func TestSpanMetricsLabels(t *testing.T) {
+	tests := []struct {
+		tags       []model.KeyValue
+		spanKind   string
+		statusCode string
+	}{
+		{spanKind: "SPAN_KIND_UNSPECIFIED", statusCode: "STATUS_CODE_UNSET"},
+		{
+			tags:       []model.KeyValue{model.String("span.kind", "client"), model.String("error", "true")},
+			spanKind:   "SPAN_KIND_CLIENT",
+			statusCode: "STATUS_CODE_ERROR",
+		},
+		{
+			tags:       []model.KeyValue{model.String("span.kind", "producer"), model.String("otel.status_code", "OK")},
+			spanKind:   "SPAN_KIND_PRODUCER",
+			statusCode: "STATUS_CODE_OK",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.spanKind, func(t *testing.T) {
+			span := &model.Span{Tags: test.tags}
+			assert.Equal(t, test.spanKind, spanKindLabel(span))
+			assert.Equal(t, test.statusCode, statusCodeLabel(span))
+		})
+	}
+}
+
+func TestSpanMetricsCardinality(t *testing.T) {
+	metricsFactory := metricstest.NewFactory(0)
+	m := newSpanMetrics(metricsFactory)
+	process := &model.Process{ServiceName: "frontend"}
+	for i := 0; i < maxOperationNames+2; i++ {
+		m.processSpan(&model.Span{OperationName: fmt.Sprintf("op-%d", i), Process: process}, "")
+	}
+	tags := func(operation string) map[string]string {
+		return map[string]string{
+			"service_name": "frontend",
+			"operation":    operation,
+			"span_kind":    "SPAN_KIND_UNSPECIFIED",
+			"status_code":  "STATUS_CODE_UNSET",
+		}
+	}
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "calls", Tags: tags("op-0"), Value: 1},
+		metricstest.ExpectedMetric{Name: "calls", Tags: tags("other-operations"), Value: 2},
+	)
+}
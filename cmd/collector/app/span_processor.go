@@ -17,6 +17,9 @@ package app
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -40,12 +43,13 @@ const (
 )
 
 type spanProcessor struct {
-	queue              *queue.BoundedQueue
+	queue              queue.Queue
 	queueResizeMu      sync.Mutex
 	metrics            *SpanProcessorMetrics
 	preProcessSpans    ProcessSpans
 	filterSpan         FilterSpan             // filter is called before the sanitizer but after preProcessSpans
 	sanitizer          sanitizer.SanitizeSpan // sanitizer is called before processSpan
+	attributeSanitizer sanitizer.SanitizeSpan // attributeSanitizer is called after filter, before the span is queued
 	processSpan        ProcessSpan
 	logger             *zap.Logger
 	spanWriter         spanstore.Writer
@@ -57,6 +61,7 @@ type spanProcessor struct {
 	bytesProcessed     *atomic.Uint64
 	spansProcessed     *atomic.Uint64
 	stopCh             chan struct{}
+	tailSampler        *tailSampler
 }
 
 type queueItem struct {
@@ -65,13 +70,53 @@ type queueItem struct {
 	tenant     string
 }
 
+// marshalQueueItem encodes the queued time, the length of the tenant, the tenant and the span
+// for the persistent queue.
+func marshalQueueItem(item interface{}) ([]byte, error) {
+	value := item.(*queueItem)
+	span, err := value.span.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 8, 8+binary.MaxVarintLen64+len(value.tenant)+len(span))
+	binary.BigEndian.PutUint64(data, uint64(value.queuedTime.UnixNano()))
+	data = binary.AppendUvarint(data, uint64(len(value.tenant)))
+	data = append(data, value.tenant...)
+	return append(data, span...), nil
+}
+
+func unmarshalQueueItem(data []byte) (interface{}, error) {
+	if len(data) < 8 {
+		return nil, errors.New("queue item too short")
+	}
+	queuedTime := time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+	data = data[8:]
+	tenantLen, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < tenantLen {
+		return nil, errors.New("invalid queue item tenant")
+	}
+	tenant := string(data[n : n+int(tenantLen)])
+	span := &model.Span{}
+	if err := span.Unmarshal(data[n+int(tenantLen):]); err != nil {
+		return nil, err
+	}
+	return &queueItem{
+		queuedTime: queuedTime,
+		span:       span,
+		tenant:     tenant,
+	}, nil
+}
+
 // NewSpanProcessor returns a SpanProcessor that preProcesses, filters, queues, sanitizes, and processes spans.
 func NewSpanProcessor(
 	spanWriter spanstore.Writer,
 	additional []ProcessSpan,
 	opts ...Option,
-) processor.SpanProcessor {
-	sp := newSpanProcessor(spanWriter, additional, opts...)
+) (processor.SpanProcessor, error) {
+	sp, err := newSpanProcessor(spanWriter, additional, opts...)
+	if err != nil {
+		return nil, err
+	}
 
 	sp.queue.StartConsumers(sp.numWorkers, func(item interface{}) {
 		value := item.(*queueItem)
@@ -80,14 +125,18 @@ func NewSpanProcessor(
 
 	sp.background(1*time.Second, sp.updateGauges)
 
+	if sp.tailSampler != nil {
+		sp.background(tailSamplingTick(sp.tailSampler.config.DecisionWait), sp.tailSampler.decideExpired)
+	}
+
 	if sp.dynQueueSizeMemory > 0 {
 		sp.background(1*time.Minute, sp.updateQueueSize)
 	}
 
-	return sp
+	return sp, nil
 }
 
-func newSpanProcessor(spanWriter spanstore.Writer, additional []ProcessSpan, opts ...Option) *spanProcessor {
+func newSpanProcessor(spanWriter spanstore.Writer, additional []ProcessSpan, opts ...Option) (*spanProcessor, error) {
 	options := Options.apply(opts...)
 	handlerMetrics := NewSpanProcessorMetrics(
 		options.serviceMetrics,
@@ -99,7 +148,26 @@ func newSpanProcessor(spanWriter spanstore.Writer, additional []ProcessSpan, opt
 			options.onDroppedSpan(item.(*queueItem).span)
 		}
 	}
-	boundedQueue := queue.NewBoundedQueue(options.queueSize, droppedItemHandler)
+	var spanQueue queue.Queue
+	if options.persistentQueue != nil {
+		persistentOptions := *options.persistentQueue
+		persistentOptions.Capacity = options.queueSize
+		persistentQueue, err := queue.NewPersistentQueue(
+			persistentOptions,
+			marshalQueueItem,
+			unmarshalQueueItem,
+			droppedItemHandler,
+			options.serviceMetrics,
+			options.logger,
+		)
+		if err != nil {
+			// falling back to the in-memory queue would silently lose the spans the disk queue is meant to keep
+			return nil, fmt.Errorf("failed to open the persistent queue: %w", err)
+		}
+		spanQueue = persistentQueue
+	} else {
+		spanQueue = queue.NewBoundedQueue(options.queueSize, droppedItemHandler)
+	}
 
 	sanitizers := sanitizer.NewStandardSanitizers()
 	if options.sanitizer != nil {
@@ -107,12 +175,13 @@ func newSpanProcessor(spanWriter spanstore.Writer, additional []ProcessSpan, opt
 	}
 
 	sp := spanProcessor{
-		queue:              boundedQueue,
+		queue:              spanQueue,
 		metrics:            handlerMetrics,
 		logger:             options.logger,
 		preProcessSpans:    options.preProcessSpans,
 		filterSpan:         options.spanFilter,
 		sanitizer:          sanitizer.NewChainedSanitizer(sanitizers...),
+		attributeSanitizer: options.attributeSanitizer,
 		reportBusy:         options.reportBusy,
 		numWorkers:         options.numWorkers,
 		spanWriter:         spanWriter,
@@ -124,7 +193,12 @@ func newSpanProcessor(spanWriter spanstore.Writer, additional []ProcessSpan, opt
 		spansProcessed:     atomic.NewUint64(0),
 	}
 
-	processSpanFuncs := []ProcessSpan{options.preSave, sp.saveSpan}
+	saveSpan := sp.saveSpan
+	if options.tailSampling != nil && options.tailSampling.DecisionWait > 0 {
+		sp.tailSampler = newTailSampler(*options.tailSampling, sp.saveSpan, options.serviceMetrics)
+		saveSpan = sp.tailSampler.processSpan
+	}
+	processSpanFuncs := []ProcessSpan{options.preSave, saveSpan}
 	if options.dynQueueSizeMemory > 0 {
 		options.logger.Info("Dynamically adjusting the queue size at runtime.",
 			zap.Uint("memory-mib", options.dynQueueSizeMemory/1024/1024),
@@ -138,12 +212,16 @@ func newSpanProcessor(spanWriter spanstore.Writer, additional []ProcessSpan, opt
 	processSpanFuncs = append(processSpanFuncs, additional...)
 
 	sp.processSpan = ChainedProcessSpan(processSpanFuncs...)
-	return &sp
+	return &sp, nil
 }
 
 func (sp *spanProcessor) Close() error {
 	close(sp.stopCh)
 	sp.queue.Stop()
+	if sp.tailSampler != nil {
+		// the buffered traces are decided now rather than lost
+		sp.tailSampler.flush()
+	}
 
 	return nil
 }
@@ -236,6 +314,7 @@ func (sp *spanProcessor) enqueueSpan(span *model.Span, originalFormat processor.
 		spanCounts.RejectedBySvc.ReportServiceNameForSpan(span)
 		return true // as in "not dropped", because it's actively rejected
 	}
+	span = sp.attributeSanitizer(span)
 
 	// add format tag
 	span.Tags = append(span.Tags, model.String("internal.span.format", string(originalFormat)))
@@ -310,4 +389,20 @@ func (sp *spanProcessor) updateGauges() {
 	sp.metrics.SpansBytes.Update(int64(sp.bytesProcessed.Load()))
 	sp.metrics.QueueLength.Update(int64(sp.queue.Size()))
 	sp.metrics.QueueCapacity.Update(int64(sp.queue.Capacity()))
+	if sp.tailSampler != nil {
+		sp.tailSampler.updateGauges()
+	}
+}
+
+// tailSamplingTick returns how often the expired traces are decided, so that
+// they are buffered for at most 10% longer than the decision wait.
+func tailSamplingTick(decisionWait time.Duration) time.Duration {
+	tick := decisionWait / 10
+	if tick > time.Second {
+		return time.Second
+	}
+	if tick <= 0 {
+		return decisionWait
+	}
+	return tick
 }
@@ -19,6 +19,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
@@ -31,10 +33,12 @@ import (
 
 	"github.com/kjschnei001/jaeger/cmd/collector/app/handler"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer"
 	zipkinsanitizer "github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer/zipkin"
 	"github.com/kjschnei001/jaeger/internal/metricstest"
 	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/pkg/queue"
 	"github.com/kjschnei001/jaeger/pkg/tenancy"
 	"github.com/kjschnei001/jaeger/pkg/testutils"
 	"github.com/kjschnei001/jaeger/thrift-gen/jaeger"
@@ -86,7 +90,7 @@ func TestBySvcMetrics(t *testing.T) {
 		logger := zap.NewNop()
 		serviceMetrics := mb.Namespace(metrics.NSOptions{Name: "service", Tags: nil})
 		hostMetrics := mb.Namespace(metrics.NSOptions{Name: "host", Tags: nil})
-		sp := newSpanProcessor(
+		sp, err := newSpanProcessor(
 			&fakeSpanWriter{},
 			nil,
 			Options.ServiceMetrics(serviceMetrics),
@@ -97,6 +101,7 @@ func TestBySvcMetrics(t *testing.T) {
 			Options.ReportBusy(false),
 			Options.SpanFilter(isSpanAllowed),
 		)
+		require.NoError(t, err)
 		var metricPrefix, format string
 		switch test.format {
 		case processor.ZipkinSpanFormat:
@@ -239,7 +244,9 @@ func makeJaegerSpan(service string, rootSpan bool, debugEnabled bool) (*jaeger.S
 
 func TestSpanProcessor(t *testing.T) {
 	w := &fakeSpanWriter{}
-	p := NewSpanProcessor(w, nil, Options.QueueSize(1)).(*spanProcessor)
+	sp, err := NewSpanProcessor(w, nil, Options.QueueSize(1))
+	require.NoError(t, err)
+	p := sp.(*spanProcessor)
 
 	res, err := p.ProcessSpans(
 		[]*model.Span{{}}, // empty span should be enriched by sanitizers
@@ -259,12 +266,14 @@ func TestSpanProcessorErrors(t *testing.T) {
 	}
 	mb := metricstest.NewFactory(time.Hour)
 	serviceMetrics := mb.Namespace(metrics.NSOptions{Name: "service", Tags: nil})
-	p := NewSpanProcessor(w,
+	sp, err := NewSpanProcessor(w,
 		nil,
 		Options.Logger(logger),
 		Options.ServiceMetrics(serviceMetrics),
 		Options.QueueSize(1),
-	).(*spanProcessor)
+	)
+	require.NoError(t, err)
+	p := sp.(*spanProcessor)
 
 	res, err := p.ProcessSpans([]*model.Span{
 		{
@@ -305,12 +314,14 @@ func (w *blockingWriter) WriteSpan(ctx context.Context, span *model.Span) error
 
 func TestSpanProcessorBusy(t *testing.T) {
 	w := &blockingWriter{}
-	p := NewSpanProcessor(w,
+	sp, err := NewSpanProcessor(w,
 		nil,
 		Options.NumWorkers(1),
 		Options.QueueSize(1),
 		Options.ReportBusy(true),
-	).(*spanProcessor)
+	)
+	require.NoError(t, err)
+	p := sp.(*spanProcessor)
 	defer assert.NoError(t, p.Close())
 
 	// block the writer so that the first span is read from the queue and blocks the processor,
@@ -345,7 +356,9 @@ func TestSpanProcessorWithNilProcess(t *testing.T) {
 	serviceMetrics := mb.Namespace(metrics.NSOptions{Name: "service", Tags: nil})
 
 	w := &fakeSpanWriter{}
-	p := NewSpanProcessor(w, nil, Options.ServiceMetrics(serviceMetrics)).(*spanProcessor)
+	sp, err := NewSpanProcessor(w, nil, Options.ServiceMetrics(serviceMetrics))
+	require.NoError(t, err)
+	p := sp.(*spanProcessor)
 	defer assert.NoError(t, p.Close())
 
 	p.saveSpan(&model.Span{}, "")
@@ -364,7 +377,9 @@ func TestSpanProcessorWithCollectorTags(t *testing.T) {
 	}
 
 	w := &fakeSpanWriter{}
-	p := NewSpanProcessor(w, nil, Options.CollectorTags(testCollectorTags)).(*spanProcessor)
+	sp, err := NewSpanProcessor(w, nil, Options.CollectorTags(testCollectorTags))
+	require.NoError(t, err)
+	p := sp.(*spanProcessor)
 
 	defer assert.NoError(t, p.Close())
 	span := &model.Span{
@@ -449,7 +464,9 @@ func TestSpanProcessorCountSpan(t *testing.T) {
 			} else {
 				opts = append(opts, Options.DynQueueSizeMemory(0))
 			}
-			p := NewSpanProcessor(w, nil, opts...).(*spanProcessor)
+			sp, err := NewSpanProcessor(w, nil, opts...)
+			require.NoError(t, err)
+			p := sp.(*spanProcessor)
 			defer func() {
 				assert.NoError(t, p.Close())
 			}()
@@ -555,7 +572,8 @@ func TestUpdateDynQueueSize(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := &fakeSpanWriter{}
-			p := newSpanProcessor(w, nil, Options.QueueSize(tt.initialCapacity), Options.DynQueueSizeWarmup(tt.warmup), Options.DynQueueSizeMemory(tt.sizeInBytes))
+			p, err := newSpanProcessor(w, nil, Options.QueueSize(tt.initialCapacity), Options.DynQueueSizeWarmup(tt.warmup), Options.DynQueueSizeMemory(tt.sizeInBytes))
+			require.NoError(t, err)
 			assert.EqualValues(t, tt.initialCapacity, p.queue.Capacity())
 
 			p.spansProcessed = atomic.NewUint64(tt.spansProcessed)
@@ -569,14 +587,16 @@ func TestUpdateDynQueueSize(t *testing.T) {
 
 func TestUpdateQueueSizeNoActivityYet(t *testing.T) {
 	w := &fakeSpanWriter{}
-	p := newSpanProcessor(w, nil, Options.QueueSize(1), Options.DynQueueSizeWarmup(1), Options.DynQueueSizeMemory(1))
+	p, err := newSpanProcessor(w, nil, Options.QueueSize(1), Options.DynQueueSizeWarmup(1), Options.DynQueueSizeMemory(1))
+	require.NoError(t, err)
 	assert.NotPanics(t, p.updateQueueSize)
 }
 
 func TestStartDynQueueSizeUpdater(t *testing.T) {
 	w := &fakeSpanWriter{}
 	oneGiB := uint(1024 * 1024 * 1024)
-	p := newSpanProcessor(w, nil, Options.QueueSize(100), Options.DynQueueSizeWarmup(1000), Options.DynQueueSizeMemory(oneGiB))
+	p, err := newSpanProcessor(w, nil, Options.QueueSize(100), Options.DynQueueSizeWarmup(1000), Options.DynQueueSizeMemory(oneGiB))
+	require.NoError(t, err)
 	assert.EqualValues(t, 100, p.queue.Capacity())
 
 	p.spansProcessed = atomic.NewUint64(1000)
@@ -602,7 +622,8 @@ func TestAdditionalProcessors(t *testing.T) {
 	w := &fakeSpanWriter{}
 
 	// nil doesn't fail
-	p := NewSpanProcessor(w, nil, Options.QueueSize(1))
+	p, err := NewSpanProcessor(w, nil, Options.QueueSize(1))
+	require.NoError(t, err)
 	res, err := p.ProcessSpans([]*model.Span{
 		{
 			Process: &model.Process{
@@ -619,7 +640,8 @@ func TestAdditionalProcessors(t *testing.T) {
 	f := func(s *model.Span, t string) {
 		count++
 	}
-	p = NewSpanProcessor(w, []ProcessSpan{f}, Options.QueueSize(1))
+	p, err = NewSpanProcessor(w, []ProcessSpan{f}, Options.QueueSize(1))
+	require.NoError(t, err)
 	res, err = p.ProcessSpans([]*model.Span{
 		{
 			Process: &model.Process{
@@ -635,7 +657,8 @@ func TestAdditionalProcessors(t *testing.T) {
 
 func TestSpanProcessorContextPropagation(t *testing.T) {
 	w := &fakeSpanWriter{}
-	p := NewSpanProcessor(w, nil, Options.QueueSize(1))
+	p, err := NewSpanProcessor(w, nil, Options.QueueSize(1))
+	require.NoError(t, err)
 
 	dummyTenant := "context-prop-test-tenant"
 
@@ -665,13 +688,15 @@ func TestSpanProcessorWithOnDroppedSpanOption(t *testing.T) {
 	}
 
 	w := &blockingWriter{}
-	p := NewSpanProcessor(w,
+	sp, err := NewSpanProcessor(w,
 		nil,
 		Options.NumWorkers(1),
 		Options.QueueSize(1),
 		Options.OnDroppedSpan(customOnDroppedSpan),
 		Options.ReportBusy(true),
-	).(*spanProcessor)
+	)
+	require.NoError(t, err)
+	p := sp.(*spanProcessor)
 	defer p.Close()
 
 	// Acquire the lock externally to force the writer to block.
@@ -679,7 +704,7 @@ func TestSpanProcessorWithOnDroppedSpanOption(t *testing.T) {
 	defer w.Unlock()
 
 	opts := processor.SpansOptions{SpanFormat: processor.JaegerSpanFormat}
-	_, err := p.ProcessSpans([]*model.Span{
+	_, err = p.ProcessSpans([]*model.Span{
 		{OperationName: "op1"},
 	}, opts)
 	require.NoError(t, err)
@@ -698,3 +723,101 @@ func TestSpanProcessorWithOnDroppedSpanOption(t *testing.T) {
 	assert.EqualError(t, err, processor.ErrBusy.Error())
 	assert.Equal(t, []string{"op3"}, droppedOperations)
 }
+
+func TestSpanProcessorPersistentQueue(t *testing.T) {
+	queueOptions := queue.PersistentQueueOptions{
+		Directory:    t.TempDir(),
+		MaxBytes:     1024 * 1024,
+		SegmentBytes: 1024,
+		SyncPolicy:   queue.SyncNever,
+	}
+
+	// the spans are queued but not consumed before the processor is closed
+	p, err := newSpanProcessor(&fakeSpanWriter{}, nil, Options.QueueSize(10), Options.PersistentQueue(queueOptions))
+	require.NoError(t, err)
+	res, err := p.ProcessSpans([]*model.Span{
+		{OperationName: "a", Process: &model.Process{ServiceName: "svc"}},
+		{OperationName: "b", Process: &model.Process{ServiceName: "svc"}},
+	}, processor.SpansOptions{SpanFormat: processor.JaegerSpanFormat, Tenant: "acme"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, res)
+	assert.Equal(t, 2, p.queue.Size())
+	require.NoError(t, p.Close())
+
+	// the queued spans are consumed once the processor restarts
+	w := &fakeSpanWriter{}
+	sp, err := NewSpanProcessor(w, nil, Options.QueueSize(10), Options.PersistentQueue(queueOptions))
+	require.NoError(t, err)
+	p = sp.(*spanProcessor)
+	assert.Eventually(t, func() bool {
+		w.spansLock.Lock()
+		defer w.spansLock.Unlock()
+		return len(w.spans) == 2
+	}, time.Second, time.Millisecond)
+	require.NoError(t, p.Close())
+	assert.ElementsMatch(t, []string{"a", "b"}, []string{w.spans[0].OperationName, w.spans[1].OperationName})
+	assert.Equal(t, map[string]bool{"acme": true}, w.tenants)
+}
+
+func TestSpanProcessorAttributeSanitizerBeforeQueue(t *testing.T) {
+	queueOptions := queue.PersistentQueueOptions{
+		Directory:    t.TempDir(),
+		MaxBytes:     1024 * 1024,
+		SegmentBytes: 1024 * 1024,
+		SyncPolicy:   queue.SyncAlways,
+	}
+	attributeSanitizer, err := sanitizer.NewAttributeSanitizer(&sanitizer.AttributeRules{Rules: []sanitizer.AttributeRule{
+		{Action: sanitizer.RedactAction, Key: "db.statement", Pattern: `\d{16}`, Replacement: "****"},
+	}}, nil)
+	require.NoError(t, err)
+	p, err := newSpanProcessor(&fakeSpanWriter{}, nil,
+		Options.QueueSize(10),
+		Options.PersistentQueue(queueOptions),
+		Options.AttributeSanitizer(attributeSanitizer),
+	)
+	require.NoError(t, err)
+	defer p.Close()
+
+	_, err = p.ProcessSpans([]*model.Span{{
+		Process: &model.Process{ServiceName: "svc"},
+		Tags:    model.KeyValues{model.String("db.statement", "card = 4111111111111111")},
+	}}, processor.SpansOptions{SpanFormat: processor.JaegerSpanFormat})
+	require.NoError(t, err)
+
+	// the card number is redacted before the span is written to the disk queue
+	segments, err := filepath.Glob(filepath.Join(queueOptions.Directory, "*.segment"))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	data, err := os.ReadFile(segments[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "4111111111111111")
+	assert.Contains(t, string(data), "card = ****")
+}
+
+func TestSpanProcessorPersistentQueueError(t *testing.T) {
+	queueOptions := queue.PersistentQueueOptions{
+		Directory:    t.TempDir(),
+		MaxBytes:     1024 * 1024,
+		SegmentBytes: 0,
+	}
+	_, err := NewSpanProcessor(&fakeSpanWriter{}, nil, Options.QueueSize(10), Options.PersistentQueue(queueOptions))
+	assert.EqualError(t, err, "failed to open the persistent queue: invalid segment size 0")
+}
+
+func TestQueueItemMarshalling(t *testing.T) {
+	item := &queueItem{
+		queuedTime: time.Unix(0, 1234),
+		span:       &model.Span{TraceID: model.NewTraceID(1, 2), OperationName: "op"},
+		tenant:     "acme",
+	}
+	data, err := marshalQueueItem(item)
+	require.NoError(t, err)
+	unmarshalled, err := unmarshalQueueItem(data)
+	require.NoError(t, err)
+	assert.Equal(t, item, unmarshalled)
+
+	_, err = unmarshalQueueItem(data[:4])
+	assert.EqualError(t, err, "queue item too short")
+	_, err = unmarshalQueueItem(data[:10])
+	assert.EqualError(t, err, "invalid queue item tenant")
+}
@@ -0,0 +1,412 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"container/list"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-client-go/utils"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/cache"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+)
+
+// SamplingPolicy is evaluated by tail-based sampling on all the spans of a trace.
+type SamplingPolicy interface {
+	// Name identifies the policy in the metrics.
+	Name() string
+	// Sample returns true if the trace must be written to storage.
+	Sample(spans []*model.Span) bool
+}
+
+// TailSamplingConfig configures tail-based sampling, which buffers the spans of each trace for
+// DecisionWait, then writes the trace if any of the Policies samples it. Spans arriving after
+// the decision for their trace are written or dropped according to that decision.
+type TailSamplingConfig struct {
+	// DecisionWait is how long the spans of a trace are buffered, starting from its first span.
+	DecisionWait time.Duration
+	// MaxTraces is the maximum number of buffered traces, the oldest trace is decided early
+	// when it is reached. As many decisions are remembered for the late spans.
+	MaxTraces int
+	// MaxSpansPerTrace is the maximum number of buffered spans of a trace, the trace is decided
+	// early when it is reached, unless it is zero.
+	MaxSpansPerTrace int
+	// Policies are evaluated in order, until one samples the trace.
+	Policies []SamplingPolicy
+	// MaxTracesPerSecond limits the rate of sampled traces per service of the root span, unless it is zero.
+	MaxTracesPerSecond float64
+}
+
+// NewTailSamplingConfig returns the TailSamplingConfig with the standard policies enabled by the options.
+func NewTailSamplingConfig(opts flags.TailSamplingOptions) TailSamplingConfig {
+	var policies []SamplingPolicy
+	if opts.LatencyThreshold > 0 {
+		policies = append(policies, LatencyPolicy(opts.LatencyThreshold))
+	}
+	if opts.SampleErrors {
+		policies = append(policies, ErrorPolicy())
+	}
+	if len(opts.Operations) > 0 {
+		policies = append(policies, OperationPolicy(opts.Operations))
+	}
+	if opts.Probability > 0 {
+		policies = append(policies, ProbabilisticPolicy(opts.Probability))
+	}
+	return TailSamplingConfig{
+		DecisionWait:       opts.DecisionWait,
+		MaxTraces:          opts.MaxTraces,
+		MaxSpansPerTrace:   opts.MaxSpansPerTrace,
+		Policies:           policies,
+		MaxTracesPerSecond: opts.MaxTracesPerSecond,
+	}
+}
+
+type samplingPolicyFunc struct {
+	name   string
+	sample func(spans []*model.Span) bool
+}
+
+func (p samplingPolicyFunc) Name() string                    { return p.name }
+func (p samplingPolicyFunc) Sample(spans []*model.Span) bool { return p.sample(spans) }
+
+// LatencyPolicy samples the traces lasting at least threshold, from the start of the first span to the end of the last.
+func LatencyPolicy(threshold time.Duration) SamplingPolicy {
+	return samplingPolicyFunc{name: "latency", sample: func(spans []*model.Span) bool {
+		var start, end time.Time
+		for i, span := range spans {
+			if i == 0 || span.StartTime.Before(start) {
+				start = span.StartTime
+			}
+			if spanEnd := span.StartTime.Add(span.Duration); spanEnd.After(end) {
+				end = spanEnd
+			}
+		}
+		return end.Sub(start) >= threshold
+	}}
+}
+
+// ErrorPolicy samples the traces with a span tagged with error=true.
+func ErrorPolicy() SamplingPolicy {
+	return samplingPolicyFunc{name: "error", sample: func(spans []*model.Span) bool {
+		for _, span := range spans {
+			if tag, ok := model.KeyValues(span.Tags).FindByKey("error"); ok && tag.AsString() == "true" {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// OperationPolicy samples the traces with a span of one of the services, or of one of the
+// operations given as service:operation.
+func OperationPolicy(operations []string) SamplingPolicy {
+	allOperations := make(map[string]bool)
+	serviceOperations := make(map[string]map[string]bool)
+	for _, operation := range operations {
+		service, name, found := strings.Cut(operation, ":")
+		if !found {
+			allOperations[service] = true
+			continue
+		}
+		if serviceOperations[service] == nil {
+			serviceOperations[service] = make(map[string]bool)
+		}
+		serviceOperations[service][name] = true
+	}
+	return samplingPolicyFunc{name: "operation", sample: func(spans []*model.Span) bool {
+		for _, span := range spans {
+			if span.Process == nil {
+				continue
+			}
+			service := span.Process.ServiceName
+			if allOperations[service] || serviceOperations[service][span.OperationName] {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// ProbabilisticPolicy samples the traces with the given probability. The decision depends
+// on the trace ID only, so that all the collectors make the same decision for a trace.
+func ProbabilisticPolicy(probability float64) SamplingPolicy {
+	return samplingPolicyFunc{name: "probabilistic", sample: func(spans []*model.Span) bool {
+		if len(spans) == 0 {
+			return false
+		}
+		// the 53 high bits of the low half of the trace ID as a float in [0, 1)
+		return float64(spans[0].TraceID.Low>>11)/(1<<53) < probability
+	}}
+}
+
+type tailSamplingMetrics struct {
+	// BufferedTraces is the number of traces waiting for a decision
+	BufferedTraces metrics.Gauge `metric:"tail_sampling.buffered_traces"`
+	// BufferedSpans is the number of spans waiting for the decision of their trace
+	BufferedSpans metrics.Gauge `metric:"tail_sampling.buffered_spans"`
+	// EarlyDecisions is the number of traces decided before DecisionWait because too many traces,
+	// or too many spans of the trace, were buffered
+	EarlyDecisions metrics.Counter `metric:"tail_sampling.early_decisions"`
+	// RateLimited is the number of sampled traces dropped by the per-service rate limit
+	RateLimited metrics.Counter `metric:"tail_sampling.rate_limited_traces"`
+	// NotSampled is the number of traces no policy sampled
+	NotSampled metrics.Counter `metric:"tail_sampling.decisions" tags:"policy=none,sampled=false"`
+	// LateSpansSampled is the number of spans written after the decision for their trace
+	LateSpansSampled metrics.Counter `metric:"tail_sampling.late_spans" tags:"sampled=true"`
+	// LateSpansDropped is the number of spans dropped after the decision for their trace
+	LateSpansDropped metrics.Counter `metric:"tail_sampling.late_spans" tags:"sampled=false"`
+}
+
+type tailSamplingKey struct {
+	tenant  string
+	traceID model.TraceID
+}
+
+func (k tailSamplingKey) String() string {
+	return k.tenant + "/" + k.traceID.String()
+}
+
+type bufferedTrace struct {
+	key      tailSamplingKey
+	spans    []*model.Span
+	deadline time.Time
+	// sampled is the decision for the trace, set when it is removed from the buffer
+	sampled bool
+}
+
+// tailSampler buffers the spans per trace until the decision for the trace, and passes
+// the spans of the sampled traces to write.
+type tailSampler struct {
+	config  TailSamplingConfig
+	write   ProcessSpan
+	metrics tailSamplingMetrics
+	// sampledBy counts the traces sampled by each policy
+	sampledBy map[string]metrics.Counter
+	timeNow   func() time.Time
+
+	mu            sync.Mutex
+	traces        map[tailSamplingKey]*list.Element
+	byArrival     *list.List // of *bufferedTrace, the first trace has the earliest deadline
+	bufferedSpans int
+	// decisions remembers whether the recently decided traces were sampled, for their late spans
+	decisions *cache.LRU
+	limiters  map[string]*serviceLimiter
+	// nextLimitersSweep is when the idle limiters are next removed
+	nextLimitersSweep time.Time
+}
+
+type serviceLimiter struct {
+	*utils.ReconfigurableRateLimiter
+	lastUsed time.Time
+}
+
+func newTailSampler(config TailSamplingConfig, write ProcessSpan, metricsFactory metrics.Factory) *tailSampler {
+	ts := &tailSampler{
+		config:    config,
+		write:     write,
+		sampledBy: make(map[string]metrics.Counter, len(config.Policies)),
+		timeNow:   time.Now,
+		traces:    make(map[tailSamplingKey]*list.Element),
+		byArrival: list.New(),
+		decisions: cache.NewLRU(config.MaxTraces),
+		limiters:  make(map[string]*serviceLimiter),
+	}
+	metrics.MustInit(&ts.metrics, metricsFactory, nil)
+	for _, policy := range config.Policies {
+		ts.sampledBy[policy.Name()] = metricsFactory.Counter(metrics.Options{
+			Name: "tail_sampling.decisions",
+			Tags: map[string]string{"policy": policy.Name(), "sampled": "true"},
+		})
+	}
+	return ts
+}
+
+// processSpan buffers the span until the decision for its trace, or handles it according
+// to the decision if the trace was already decided.
+func (ts *tailSampler) processSpan(span *model.Span, tenant string) {
+	key := tailSamplingKey{tenant: tenant, traceID: span.TraceID}
+	ts.mu.Lock()
+	if sampled, ok := ts.decisions.Get(key.String()).(bool); ok {
+		ts.mu.Unlock()
+		if sampled {
+			ts.metrics.LateSpansSampled.Inc(1)
+			ts.write(span, tenant)
+		} else {
+			ts.metrics.LateSpansDropped.Inc(1)
+		}
+		return
+	}
+	ts.bufferedSpans++
+	if elem, ok := ts.traces[key]; ok {
+		trace := elem.Value.(*bufferedTrace)
+		trace.spans = append(trace.spans, span)
+		if ts.config.MaxSpansPerTrace <= 0 || len(trace.spans) < ts.config.MaxSpansPerTrace {
+			ts.mu.Unlock()
+			return
+		}
+		ts.remove(elem)
+		ts.mu.Unlock()
+		ts.metrics.EarlyDecisions.Inc(1)
+		ts.decide(trace)
+		return
+	}
+	var evicted *bufferedTrace
+	if len(ts.traces) >= ts.config.MaxTraces {
+		evicted = ts.remove(ts.byArrival.Front())
+	}
+	ts.traces[key] = ts.byArrival.PushBack(&bufferedTrace{
+		key:      key,
+		spans:    []*model.Span{span},
+		deadline: ts.timeNow().Add(ts.config.DecisionWait),
+	})
+	ts.mu.Unlock()
+	if evicted != nil {
+		ts.metrics.EarlyDecisions.Inc(1)
+		ts.decide(evicted)
+	}
+}
+
+// remove removes the trace from the buffer and makes the decision for it, the lock must be held.
+func (ts *tailSampler) remove(elem *list.Element) *bufferedTrace {
+	trace := ts.byArrival.Remove(elem).(*bufferedTrace)
+	delete(ts.traces, trace.key)
+	ts.bufferedSpans -= len(trace.spans)
+	trace.sampled = ts.sample(trace)
+	// the decision is remembered before the trace is decided, so that
+	// its spans arriving in between are not buffered as a new trace
+	ts.decisions.Put(trace.key.String(), trace.sampled)
+	return trace
+}
+
+// decideExpired decides the traces that were buffered for DecisionWait.
+func (ts *tailSampler) decideExpired() {
+	now := ts.timeNow()
+	var expired []*bufferedTrace
+	ts.mu.Lock()
+	for ts.byArrival.Len() > 0 && !ts.byArrival.Front().Value.(*bufferedTrace).deadline.After(now) {
+		expired = append(expired, ts.remove(ts.byArrival.Front()))
+	}
+	ts.removeIdleLimiters(now)
+	ts.mu.Unlock()
+	for _, trace := range expired {
+		ts.decide(trace)
+	}
+}
+
+// flush decides all the buffered traces.
+func (ts *tailSampler) flush() {
+	var traces []*bufferedTrace
+	ts.mu.Lock()
+	for ts.byArrival.Len() > 0 {
+		traces = append(traces, ts.remove(ts.byArrival.Front()))
+	}
+	ts.mu.Unlock()
+	for _, trace := range traces {
+		ts.decide(trace)
+	}
+}
+
+// decide writes the spans of the trace if it was sampled. The decision is not read back from
+// the decisions, where it may already have been evicted by the decisions of other traces.
+func (ts *tailSampler) decide(trace *bufferedTrace) {
+	if !trace.sampled {
+		return
+	}
+	for _, span := range trace.spans {
+		ts.write(span, trace.key.tenant)
+	}
+}
+
+// sample evaluates the policies and the rate limit, the lock must be held.
+func (ts *tailSampler) sample(trace *bufferedTrace) bool {
+	for _, policy := range ts.config.Policies {
+		if !policy.Sample(trace.spans) {
+			continue
+		}
+		if !ts.allow(rootServiceName(trace.spans)) {
+			ts.metrics.RateLimited.Inc(1)
+			return false
+		}
+		ts.sampledBy[policy.Name()].Inc(1)
+		return true
+	}
+	ts.metrics.NotSampled.Inc(1)
+	return false
+}
+
+func (ts *tailSampler) allow(service string) bool {
+	if ts.config.MaxTracesPerSecond <= 0 {
+		return true
+	}
+	limiter, ok := ts.limiters[service]
+	if !ok {
+		// the balance must allow at least one trace, for rates below one trace per second
+		limiter = &serviceLimiter{
+			ReconfigurableRateLimiter: utils.NewRateLimiter(ts.config.MaxTracesPerSecond, ts.limiterBalance()),
+		}
+		ts.limiters[service] = limiter
+	}
+	limiter.lastUsed = ts.timeNow()
+	return limiter.CheckCredit(1)
+}
+
+func (ts *tailSampler) limiterBalance() float64 {
+	return math.Max(ts.config.MaxTracesPerSecond, 1)
+}
+
+// removeIdleLimiters removes the limiters of the services without sampled traces for as long as
+// it takes to refill the balance of a limiter, since they would behave as new limiters.
+// The lock must be held.
+func (ts *tailSampler) removeIdleLimiters(now time.Time) {
+	if ts.config.MaxTracesPerSecond <= 0 || now.Before(ts.nextLimitersSweep) {
+		return
+	}
+	refill := time.Duration(ts.limiterBalance() / ts.config.MaxTracesPerSecond * float64(time.Second))
+	for service, limiter := range ts.limiters {
+		if now.Sub(limiter.lastUsed) >= refill {
+			delete(ts.limiters, service)
+		}
+	}
+	ts.nextLimitersSweep = now.Add(refill)
+}
+
+func (ts *tailSampler) updateGauges() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.metrics.BufferedTraces.Update(int64(len(ts.traces)))
+	ts.metrics.BufferedSpans.Update(int64(ts.bufferedSpans))
+}
+
+// rootServiceName returns the service of the span without parent, or of the first span
+// when the root span has not been received.
+func rootServiceName(spans []*model.Span) string {
+	root := spans[0]
+	for _, span := range spans {
+		if span.ParentSpanID() == 0 {
+			root = span
+			break
+		}
+	}
+	if root.Process == nil {
+		return ""
+	}
+	return root.Process.ServiceName
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/cache"
+)
+
+type tailSamplerTest struct {
+	sampler *tailSampler
+	metrics *metricstest.Factory
+	now     time.Time
+
+	mu      sync.Mutex
+	written map[string][]*model.Span // by tenant
+}
+
+func newTailSamplerTest(config TailSamplingConfig) *tailSamplerTest {
+	test := &tailSamplerTest{
+		metrics: metricstest.NewFactory(time.Hour),
+		now:     time.Unix(1000, 0),
+		written: make(map[string][]*model.Span),
+	}
+	test.sampler = newTailSampler(config, func(span *model.Span, tenant string) {
+		test.mu.Lock()
+		defer test.mu.Unlock()
+		test.written[tenant] = append(test.written[tenant], span)
+	}, test.metrics)
+	test.sampler.timeNow = func() time.Time { return test.now }
+	return test
+}
+
+func tailSamplingSpan(traceID uint64, spanID uint64, service string, tags ...model.KeyValue) *model.Span {
+	return &model.Span{
+		TraceID:       model.NewTraceID(0, traceID),
+		SpanID:        model.NewSpanID(spanID),
+		OperationName: "op",
+		StartTime:     time.Unix(0, 0),
+		Duration:      time.Millisecond,
+		Tags:          tags,
+		Process:       &model.Process{ServiceName: service},
+	}
+}
+
+func TestTailSamplerDecision(t *testing.T) {
+	test := newTailSamplerTest(TailSamplingConfig{
+		DecisionWait: 10 * time.Second,
+		MaxTraces:    10,
+		Policies:     []SamplingPolicy{ErrorPolicy()},
+	})
+	ts := test.sampler
+
+	ts.processSpan(tailSamplingSpan(1, 1, "svc"), "")
+	ts.processSpan(tailSamplingSpan(2, 1, "svc"), "")
+	test.now = test.now.Add(5 * time.Second)
+	ts.processSpan(tailSamplingSpan(1, 2, "svc", model.Bool("error", true)), "")
+	ts.processSpan(tailSamplingSpan(3, 1, "svc", model.Bool("error", true)), "")
+
+	ts.updateGauges()
+	test.metrics.AssertGaugeMetrics(t,
+		metricstest.ExpectedMetric{Name: "tail_sampling.buffered_traces", Value: 3},
+		metricstest.ExpectedMetric{Name: "tail_sampling.buffered_spans", Value: 4},
+	)
+
+	// traces 1 and 2 are decided, trace 3 is still buffered
+	test.now = test.now.Add(5 * time.Second)
+	ts.decideExpired()
+	require.Len(t, test.written[""], 2)
+	assert.Equal(t, model.NewTraceID(0, 1), test.written[""][0].TraceID)
+	assert.Equal(t, model.NewTraceID(0, 1), test.written[""][1].TraceID)
+
+	// late spans follow the decision of their trace
+	ts.processSpan(tailSamplingSpan(1, 3, "svc"), "")
+	ts.processSpan(tailSamplingSpan(2, 2, "svc", model.Bool("error", true)), "")
+	assert.Len(t, test.written[""], 3)
+
+	ts.flush()
+	assert.Len(t, test.written[""], 4)
+
+	ts.updateGauges()
+	test.metrics.AssertGaugeMetrics(t,
+		metricstest.ExpectedMetric{Name: "tail_sampling.buffered_traces", Value: 0},
+		metricstest.ExpectedMetric{Name: "tail_sampling.buffered_spans", Value: 0},
+	)
+	test.metrics.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "tail_sampling.decisions", Tags: map[string]string{"policy": "error", "sampled": "true"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "tail_sampling.decisions", Tags: map[string]string{"policy": "none", "sampled": "false"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "tail_sampling.late_spans", Tags: map[string]string{"sampled": "true"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "tail_sampling.late_spans", Tags: map[string]string{"sampled": "false"}, Value: 1},
+	)
+}
+
+func TestTailSamplerTenants(t *testing.T) {
+	test := newTailSamplerTest(TailSamplingConfig{
+		DecisionWait: time.Second,
+		MaxTraces:    10,
+		Policies:     []SamplingPolicy{OperationPolicy([]string{"sampled"})},
+	})
+	test.sampler.processSpan(tailSamplingSpan(1, 1, "sampled"), "acme")
+	test.sampler.processSpan(tailSamplingSpan(1, 1, "other"), "megacorp")
+	test.sampler.flush()
+	assert.Len(t, test.written["acme"], 1)
+	assert.Empty(t, test.written["megacorp"])
+}
+
+func TestTailSamplerMaxTraces(t *testing.T) {
+	test := newTailSamplerTest(TailSamplingConfig{
+		DecisionWait: time.Minute,
+		MaxTraces:    2,
+		Policies:     []SamplingPolicy{ProbabilisticPolicy(1)},
+	})
+	for i := uint64(1); i <= 3; i++ {
+		test.sampler.processSpan(tailSamplingSpan(i, 1, "svc"), "")
+	}
+	require.Len(t, test.written[""], 1)
+	assert.Equal(t, model.NewTraceID(0, 1), test.written[""][0].TraceID)
+	test.metrics.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "tail_sampling.early_decisions", Value: 1})
+}
+
+func TestTailSamplerMaxSpansPerTrace(t *testing.T) {
+	test := newTailSamplerTest(TailSamplingConfig{
+		DecisionWait:     time.Minute,
+		MaxTraces:        10,
+		MaxSpansPerTrace: 2,
+		Policies:         []SamplingPolicy{ErrorPolicy()},
+	})
+	test.sampler.processSpan(tailSamplingSpan(1, 1, "svc", model.Bool("error", true)), "")
+	test.sampler.processSpan(tailSamplingSpan(2, 1, "svc"), "")
+	assert.Empty(t, test.written[""])
+	test.sampler.processSpan(tailSamplingSpan(1, 2, "svc"), "")
+	// trace 1 is decided when its second span is buffered, its late spans follow the decision
+	assert.Len(t, test.written[""], 2)
+	test.sampler.processSpan(tailSamplingSpan(1, 3, "svc"), "")
+	assert.Len(t, test.written[""], 3)
+	test.sampler.updateGauges()
+	test.metrics.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "tail_sampling.early_decisions", Value: 1})
+	test.metrics.AssertGaugeMetrics(t,
+		metricstest.ExpectedMetric{Name: "tail_sampling.buffered_traces", Value: 1},
+		metricstest.ExpectedMetric{Name: "tail_sampling.buffered_spans", Value: 1})
+}
+
+func TestTailSamplerDecisionsEvicted(t *testing.T) {
+	test := newTailSamplerTest(TailSamplingConfig{
+		DecisionWait: time.Minute,
+		MaxTraces:    3,
+		Policies:     []SamplingPolicy{ErrorPolicy()},
+	})
+	test.sampler.decisions = cache.NewLRU(1)
+	test.sampler.processSpan(tailSamplingSpan(1, 1, "svc", model.Bool("error", true)), "")
+	test.sampler.processSpan(tailSamplingSpan(2, 1, "svc"), "")
+	test.sampler.processSpan(tailSamplingSpan(3, 1, "svc"), "")
+	// the decision of trace 1 is evicted by the other decisions before trace 1 is written
+	test.sampler.flush()
+	require.Len(t, test.written[""], 1)
+	assert.Equal(t, model.NewTraceID(0, 1), test.written[""][0].TraceID)
+}
+
+func TestTailSamplerRateLimit(t *testing.T) {
+	test := newTailSamplerTest(TailSamplingConfig{
+		DecisionWait:       time.Second,
+		MaxTraces:          10,
+		Policies:           []SamplingPolicy{ProbabilisticPolicy(1)},
+		MaxTracesPerSecond: 1,
+	})
+	test.sampler.processSpan(tailSamplingSpan(1, 1, "svc"), "")
+	test.sampler.processSpan(tailSamplingSpan(2, 1, "svc"), "")
+	test.sampler.processSpan(tailSamplingSpan(3, 1, "other-svc"), "")
+	test.sampler.flush()
+	assert.Len(t, test.written[""], 2)
+	test.metrics.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "tail_sampling.rate_limited_traces", Value: 1})
+}
+
+func TestTailSamplerIdleLimitersRemoved(t *testing.T) {
+	test := newTailSamplerTest(TailSamplingConfig{
+		DecisionWait:       time.Second,
+		MaxTraces:          10,
+		Policies:           []SamplingPolicy{ProbabilisticPolicy(1)},
+		MaxTracesPerSecond: 0.5,
+	})
+	ts := test.sampler
+	ts.processSpan(tailSamplingSpan(1, 1, "svc"), "")
+	ts.processSpan(tailSamplingSpan(2, 1, "other-svc"), "")
+	test.now = test.now.Add(time.Second)
+	ts.decideExpired()
+	assert.Len(t, ts.limiters, 2)
+
+	// a limiter with a balance of one trace refills in 2s at 0.5 traces per second
+	ts.processSpan(tailSamplingSpan(3, 1, "svc"), "")
+	test.now = test.now.Add(time.Second)
+	ts.decideExpired()
+	assert.Len(t, ts.limiters, 2)
+	test.now = test.now.Add(time.Second)
+	ts.decideExpired()
+	assert.Len(t, ts.limiters, 1)
+	assert.Contains(t, ts.limiters, "svc")
+}
+
+func TestSamplingPolicies(t *testing.T) {
+	span := func(service, operation string, start time.Duration, duration time.Duration, tags ...model.KeyValue) *model.Span {
+		return &model.Span{
+			TraceID:       model.NewTraceID(0, 1),
+			OperationName: operation,
+			StartTime:     time.Unix(0, 0).Add(start),
+			Duration:      duration,
+			Tags:          tags,
+			Process:       &model.Process{ServiceName: service},
+		}
+	}
+	trace := []*model.Span{
+		span("frontend", "GET /", time.Second, 2*time.Second),
+		span("backend", "query", 0, time.Second, model.String("error", "false")),
+	}
+	tests := []struct {
+		policy   SamplingPolicy
+		expected bool
+	}{
+		{policy: LatencyPolicy(3 * time.Second), expected: true},
+		{policy: LatencyPolicy(3*time.Second + 1), expected: false},
+		{policy: ErrorPolicy(), expected: false},
+		{policy: OperationPolicy([]string{"backend"}), expected: true},
+		{policy: OperationPolicy([]string{"frontend:GET /"}), expected: true},
+		{policy: OperationPolicy([]string{"frontend:query", "db"}), expected: false},
+		{policy: ProbabilisticPolicy(1), expected: true},
+		{policy: ProbabilisticPolicy(0), expected: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.policy.Sample(trace), test.policy.Name())
+	}
+	assert.True(t, ErrorPolicy().Sample([]*model.Span{span("frontend", "GET /", 0, 0, model.Bool("error", true))}))
+	assert.False(t, ProbabilisticPolicy(1).Sample(nil))
+}
+
+func TestNewTailSamplingConfig(t *testing.T) {
+	config := NewTailSamplingConfig(flags.TailSamplingOptions{
+		DecisionWait:       time.Second,
+		MaxTraces:          10,
+		LatencyThreshold:   time.Second,
+		SampleErrors:       true,
+		Operations:         []string{"frontend"},
+		Probability:        0.5,
+		MaxTracesPerSecond: 2,
+	})
+	var names []string
+	for _, policy := range config.Policies {
+		names = append(names, policy.Name())
+	}
+	assert.Equal(t, []string{"latency", "error", "operation", "probabilistic"}, names)
+	assert.Equal(t, time.Second, config.DecisionWait)
+	assert.Equal(t, 10, config.MaxTraces)
+	assert.Equal(t, 2.0, config.MaxTracesPerSecond)
+
+	assert.Empty(t, NewTailSamplingConfig(flags.TailSamplingOptions{}).Policies)
+}
+
+func TestSpanProcessorTailSampling(t *testing.T) {
+	w := &fakeSpanWriter{}
+	sp, err := NewSpanProcessor(w, nil,
+		Options.QueueSize(10),
+		Options.TailSampling(TailSamplingConfig{
+			DecisionWait: time.Hour,
+			MaxTraces:    10,
+			Policies:     []SamplingPolicy{ErrorPolicy()},
+		}),
+	)
+	require.NoError(t, err)
+	p := sp.(*spanProcessor)
+
+	res, err := p.ProcessSpans([]*model.Span{
+		tailSamplingSpan(1, 1, "svc"),
+		tailSamplingSpan(1, 2, "svc", model.Bool("error", true)),
+		tailSamplingSpan(2, 1, "svc"),
+	}, processor.SpansOptions{SpanFormat: processor.JaegerSpanFormat})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true}, res)
+
+	// the buffered traces are decided on close
+	require.NoError(t, p.Close())
+	w.spansLock.Lock()
+	defer w.spansLock.Unlock()
+	require.Len(t, w.spans, 2)
+	assert.Equal(t, model.NewTraceID(0, 1), w.spans[0].TraceID)
+}
+
+func TestTailSamplingTick(t *testing.T) {
+	assert.Equal(t, time.Second, tailSamplingTick(time.Minute))
+	assert.Equal(t, 100*time.Millisecond, tailSamplingTick(time.Second))
+	assert.Equal(t, time.Duration(5), tailSamplingTick(5))
+}
@@ -37,6 +37,7 @@ import (
 	zipkinTransport "github.com/uber/jaeger-client-go/transport/zipkin"
 
 	"github.com/kjschnei001/jaeger/cmd/collector/app/handler"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/processor"
 	zm "github.com/kjschnei001/jaeger/cmd/collector/app/zipkin/zipkindeser/zipkindesermocks"
 	zipkinTrift "github.com/kjschnei001/jaeger/model/converter/thrift/zipkin"
 	zipkinProto "github.com/kjschnei001/jaeger/proto-gen/zipkin"
@@ -302,6 +303,12 @@ func TestSaveSpansV2(t *testing.T) {
 	require.NoError(t, err)
 	assert.EqualValues(t, http.StatusInternalServerError, statusCode)
 	assert.EqualValues(t, "Cannot submit Zipkin batch: Bad times ahead\n", resBody)
+
+	handler.zipkinSpansHandler.(*mockZipkinHandler).err = processor.ErrRateLimited
+	statusCode, resBody, err = postBytes(server.URL+`/api/v2/spans`, []byte(`[{"id":"1111111111111111", "traceId":"1111111111111111"}]`), createHeader("application/json"))
+	require.NoError(t, err)
+	assert.EqualValues(t, http.StatusTooManyRequests, statusCode)
+	assert.EqualValues(t, "Cannot submit Zipkin batch: rate limit exceeded\n", resBody)
 }
 
 func TestSaveProtoSpansV2(t *testing.T) {
@@ -120,6 +120,12 @@ func TestEndpointValueErrs(t *testing.T) {
 	}
 }
 
+func TestEndpointWithoutIPv4(t *testing.T) {
+	endpoint, err := protoEndpointV2ToThrift(&zipkinProto.Endpoint{ServiceName: "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, &zipkincore.Endpoint{ServiceName: "foo"}, endpoint)
+}
+
 func TestProtoKindToThrift(t *testing.T) {
 	tests := []struct {
 		ts       int64
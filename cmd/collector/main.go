@@ -26,6 +26,7 @@ import (
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/zap"
 
+	"github.com/kjschnei001/jaeger/cmd/badger"
 	"github.com/kjschnei001/jaeger/cmd/collector/app"
 	"github.com/kjschnei001/jaeger/cmd/collector/app/flags"
 	"github.com/kjschnei001/jaeger/cmd/docs"
@@ -41,6 +42,8 @@ import (
 	ss "github.com/kjschnei001/jaeger/plugin/sampling/strategystore"
 	"github.com/kjschnei001/jaeger/plugin/storage"
 	"github.com/kjschnei001/jaeger/ports"
+	"github.com/kjschnei001/jaeger/storage/dependencystore"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
 )
 
 const serviceName = "jaeger-collector"
@@ -81,6 +84,10 @@ func main() {
 			if err := storageFactory.Initialize(baseFactory, logger); err != nil {
 				logger.Fatal("Failed to init storage factory", zap.Error(err))
 			}
+			for route, handler := range storageFactory.AdminHandlers() {
+				logger.Info("Mounting storage handler on admin server", zap.String("route", route))
+				svc.Admin.Handle(route, handler)
+			}
 			spanWriter, err := storageFactory.CreateSpanWriter()
 			if err != nil {
 				logger.Fatal("Failed to create span writer", zap.Error(err))
@@ -103,17 +110,30 @@ func main() {
 			if err != nil {
 				logger.Fatal("Failed to initialize collector", zap.Error(err))
 			}
+			var dependencyWriter dependencystore.Writer
+			var spanReader spanstore.Reader
+			if collectorOpts.Dependencies.Enabled {
+				if dependencyWriter, err = storageFactory.CreateDependencyWriter(); err != nil {
+					logger.Fatal("Failed to create dependency writer", zap.Error(err))
+				}
+				if spanReader, err = storageFactory.CreateSpanReader(); err != nil {
+					logger.Fatal("Failed to create span reader", zap.Error(err))
+				}
+			}
 			tm := tenancy.NewManager(&collectorOpts.GRPC.Tenancy)
 
 			collector := app.New(&app.CollectorParams{
-				ServiceName:    serviceName,
-				Logger:         logger,
-				MetricsFactory: metricsFactory,
-				SpanWriter:     spanWriter,
-				StrategyStore:  strategyStore,
-				Aggregator:     aggregator,
-				HealthCheck:    svc.HC(),
-				TenancyMgr:     tm,
+				ServiceName:        serviceName,
+				Logger:             logger,
+				MetricsFactory:     metricsFactory,
+				SpanWriter:         spanWriter,
+				StrategyStore:      strategyStore,
+				Aggregator:         aggregator,
+				HealthCheck:        svc.HC(),
+				TenancyMgr:         tm,
+				SpanMetricsFactory: svc.MetricsFactory,
+				DependencyWriter:   dependencyWriter,
+				SpanReader:         spanReader,
 			})
 			// Start all Collector services
 			if err := collector.Start(collectorOpts); err != nil {
@@ -142,6 +162,7 @@ func main() {
 	command.AddCommand(env.Command())
 	command.AddCommand(docs.Command(v))
 	command.AddCommand(status.Command(v, ports.CollectorAdminHTTP))
+	command.AddCommand(badger.Command(v, ports.CollectorAdminHTTP))
 
 	config.AddFlags(
 		v,
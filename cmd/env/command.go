@@ -41,9 +41,18 @@ The following configuration options are only available via environment variables
 `
 	storageTypeDescription = `The type of backend [%s] used for trace storage.
 Multiple backends can be specified as comma-separated list, e.g. "cassandra,elasticsearch"
-(currently only for writing spans). Note that "kafka" is only valid in jaeger-collector;
+(only the first one is searched unless SPAN_READER_TYPE is set). Note that "kafka" is only valid in jaeger-collector;
 it is not a replacement for a proper storage backend, and only used as a buffer for spans
 when Jaeger is deployed in the collector+ingester configuration.
+`
+
+	readerTypeDescription = `The types of backend [%s] searched for traces, as a comma-separated list,
+e.g. "cassandra,elasticsearch" during a migration. The traces found in several backends are merged,
+and a failing backend only adds warnings to the traces found in the others.
+`
+
+	coldStorageTypeDescription = `The type of backend [%s] the spans of the first span storage type
+are copied to when they are older than --tiered.demote-after, and searched in with the recent spans.
 `
 
 	samplingTypeDescription = `The method [%s] used for determining the sampling rates served
@@ -76,6 +85,22 @@ func Command() *cobra.Command {
 		"${SPAN_STORAGE_TYPE}",
 		"The type of backend used for service dependencies storage.",
 	)
+	fs.String(
+		storage.SpanReaderTypeEnvVar,
+		"${SPAN_STORAGE_TYPE}",
+		fmt.Sprintf(
+			strings.ReplaceAll(readerTypeDescription, "\n", " "),
+			strings.Join(storage.AllStorageTypes, ", "),
+		),
+	)
+	fs.String(
+		storage.ColdSpanStorageTypeEnvVar,
+		"",
+		fmt.Sprintf(
+			strings.ReplaceAll(coldStorageTypeDescription, "\n", " "),
+			strings.Join(storage.AllStorageTypes, ", "),
+		),
+	)
 	fs.String(
 		strategystore.SamplingTypeEnvVar,
 		"file",
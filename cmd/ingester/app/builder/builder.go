@@ -20,13 +20,13 @@ import (
 
 	"go.uber.org/zap"
 
-	"github.com/jaegertracing/jaeger/cmd/ingester/app"
-	"github.com/jaegertracing/jaeger/cmd/ingester/app/consumer"
-	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
-	kafkaConsumer "github.com/jaegertracing/jaeger/pkg/kafka/consumer"
-	"github.com/jaegertracing/jaeger/pkg/metrics"
-	"github.com/jaegertracing/jaeger/plugin/storage/kafka"
-	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/cmd/ingester/app"
+	"github.com/kjschnei001/jaeger/cmd/ingester/app/consumer"
+	"github.com/kjschnei001/jaeger/cmd/ingester/app/processor"
+	kafkaConsumer "github.com/kjschnei001/jaeger/pkg/kafka/consumer"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/kafka"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
 )
 
 // CreateConsumer creates a new span consumer for the ingester
@@ -39,6 +39,12 @@ func CreateConsumer(logger *zap.Logger, metricsFactory metrics.Factory, spanWrit
 		unmarshaller = kafka.NewProtobufUnmarshaller()
 	case kafka.EncodingZipkinThrift:
 		unmarshaller = kafka.NewZipkinThriftUnmarshaller()
+	case kafka.EncodingZipkinJSON:
+		unmarshaller = kafka.NewZipkinJSONUnmarshaller()
+	case kafka.EncodingZipkinProto:
+		unmarshaller = kafka.NewZipkinProtoUnmarshaller()
+	case kafka.EncodingOTLPProto:
+		unmarshaller = kafka.NewOTLPProtoUnmarshaller()
 	default:
 		return nil, fmt.Errorf(`encoding '%s' not recognised, use one of ("%s")`,
 			options.Encoding, strings.Join(kafka.AllEncodings, "\", \""))
@@ -19,8 +19,8 @@ import (
 	"math/rand"
 	"time"
 
-	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
-	"github.com/jaegertracing/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/cmd/ingester/app/processor"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
 )
 
 type retryDecorator struct {
@@ -18,7 +18,7 @@ import (
 	"io"
 	"time"
 
-	"github.com/jaegertracing/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
 )
 
 type metricsDecorator struct {
@@ -20,9 +20,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
-	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor/mocks"
-	"github.com/jaegertracing/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/cmd/ingester/app/processor"
+	"github.com/kjschnei001/jaeger/cmd/ingester/app/processor/mocks"
+	"github.com/kjschnei001/jaeger/internal/metricstest"
 )
 
 type fakeMsg struct{}
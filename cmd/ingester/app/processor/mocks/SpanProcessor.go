@@ -17,7 +17,7 @@
 package mocks
 
 import mock "github.com/stretchr/testify/mock"
-import processor "github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
+import processor "github.com/kjschnei001/jaeger/cmd/ingester/app/processor"
 
 // SpanProcessor is an autogenerated mock type for the SpanProcessor type
 type SpanProcessor struct {
@@ -20,8 +20,8 @@ import (
 
 	"go.uber.org/zap"
 
-	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
-	mockProcessor "github.com/jaegertracing/jaeger/cmd/ingester/app/processor/mocks"
+	"github.com/kjschnei001/jaeger/cmd/ingester/app/processor"
+	mockProcessor "github.com/kjschnei001/jaeger/cmd/ingester/app/processor/mocks"
 )
 
 type fakeMessage struct{}
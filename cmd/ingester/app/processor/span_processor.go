@@ -19,9 +19,10 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer"
-	"github.com/jaegertracing/jaeger/plugin/storage/kafka"
-	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/plugin/storage/kafka"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
 )
 
 //go:generate mockery -name=KafkaSpanProcessor
@@ -60,13 +61,30 @@ func NewSpanProcessor(params SpanProcessorParams) *KafkaSpanProcessor {
 	}
 }
 
-// Process unmarshals and writes a single kafka message
+// Process unmarshals and writes the spans of a single kafka message
 func (s KafkaSpanProcessor) Process(message Message) error {
-	span, err := s.unmarshaller.Unmarshal(message.Value())
+	spans, err := s.unmarshal(message.Value())
 	if err != nil {
 		return fmt.Errorf("cannot unmarshall byte array into span: %w", err)
 	}
 
-	// TODO context should be propagated from upstream components
-	return s.writer.WriteSpan(context.TODO(), s.sanitizer(span))
+	for _, span := range spans {
+		// TODO context should be propagated from upstream components
+		if err := s.writer.WriteSpan(context.TODO(), s.sanitizer(span)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshal returns all the spans of messages which can hold several of them, e.g. Zipkin lists
+func (s KafkaSpanProcessor) unmarshal(msg []byte) ([]*model.Span, error) {
+	if unmarshaller, ok := s.unmarshaller.(kafka.SpansUnmarshaller); ok {
+		return unmarshaller.UnmarshalSpans(msg)
+	}
+	span, err := s.unmarshaller.Unmarshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return []*model.Span{span}, nil
 }
@@ -22,10 +22,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
-	cmocks "github.com/jaegertracing/jaeger/cmd/ingester/app/consumer/mocks"
-	"github.com/jaegertracing/jaeger/model"
-	umocks "github.com/jaegertracing/jaeger/pkg/kafka/mocks"
-	smocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+	cmocks "github.com/kjschnei001/jaeger/cmd/ingester/app/consumer/mocks"
+	"github.com/kjschnei001/jaeger/model"
+	umocks "github.com/kjschnei001/jaeger/pkg/kafka/mocks"
+	smocks "github.com/kjschnei001/jaeger/storage/spanstore/mocks"
 )
 
 func TestNewSpanProcessor(t *testing.T) {
@@ -82,3 +82,55 @@ func TestSpanProcessor_ProcessError(t *testing.T) {
 	writer.AssertExpectations(t)
 	writer.AssertNotCalled(t, "WriteSpan")
 }
+
+type fakeSpansUnmarshaller struct {
+	umocks.Unmarshaller
+	spans []*model.Span
+	err   error
+}
+
+func (u *fakeSpansUnmarshaller) UnmarshalSpans([]byte) ([]*model.Span, error) {
+	return u.spans, u.err
+}
+
+func TestSpanProcessor_ProcessSpans(t *testing.T) {
+	spans := []*model.Span{
+		{OperationName: "first", Process: &model.Process{ServiceName: "frontend"}},
+		{OperationName: "second", Process: &model.Process{ServiceName: "db"}},
+	}
+	mockWriter := &smocks.Writer{}
+	processor := NewSpanProcessor(SpanProcessorParams{
+		Unmarshaller: &fakeSpansUnmarshaller{spans: spans},
+		Writer:       mockWriter,
+	})
+
+	message := &cmocks.Message{}
+	message.On("Value").Return([]byte("irrelevant"))
+	mockWriter.On("WriteSpan", context.TODO(), spans[0]).Return(nil)
+	mockWriter.On("WriteSpan", context.TODO(), spans[1]).Return(nil)
+
+	assert.NoError(t, processor.Process(message))
+	mockWriter.AssertExpectations(t)
+}
+
+func TestSpanProcessor_ProcessSpansErrors(t *testing.T) {
+	message := &cmocks.Message{}
+	message.On("Value").Return([]byte("irrelevant"))
+
+	processor := NewSpanProcessor(SpanProcessorParams{
+		Unmarshaller: &fakeSpansUnmarshaller{err: errors.New("moocow")},
+		Writer:       &smocks.Writer{},
+	})
+	assert.ErrorContains(t, processor.Process(message), "moocow")
+
+	spans := []*model.Span{{OperationName: "first"}, {OperationName: "second"}}
+	mockWriter := &smocks.Writer{}
+	processor = NewSpanProcessor(SpanProcessorParams{
+		Unmarshaller: &fakeSpansUnmarshaller{spans: spans},
+		Writer:       mockWriter,
+	})
+	mockWriter.On("WriteSpan", context.TODO(), spans[0]).Return(errors.New("storage error"))
+
+	assert.ErrorContains(t, processor.Process(message), "storage error")
+	mockWriter.AssertNumberOfCalls(t, "WriteSpan", 1)
+}
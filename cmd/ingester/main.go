@@ -25,6 +25,7 @@ import (
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/zap"
 
+	"github.com/kjschnei001/jaeger/cmd/badger"
 	"github.com/kjschnei001/jaeger/cmd/docs"
 	"github.com/kjschnei001/jaeger/cmd/env"
 	"github.com/kjschnei001/jaeger/cmd/flags"
@@ -64,6 +65,10 @@ func main() {
 			if err := storageFactory.Initialize(baseFactory, logger); err != nil {
 				logger.Fatal("Failed to init storage factory", zap.Error(err))
 			}
+			for route, handler := range storageFactory.AdminHandlers() {
+				logger.Info("Mounting storage handler on admin server", zap.String("route", route))
+				svc.Admin.Handle(route, handler)
+			}
 			spanWriter, err := storageFactory.CreateSpanWriter()
 			if err != nil {
 				logger.Fatal("Failed to create span writer", zap.Error(err))
@@ -102,6 +107,7 @@ func main() {
 	command.AddCommand(env.Command())
 	command.AddCommand(docs.Command(v))
 	command.AddCommand(status.Command(v, ports.IngesterAdminHTTP))
+	command.AddCommand(badger.Command(v, ports.IngesterAdminHTTP))
 
 	config.AddFlags(
 		v,
@@ -77,6 +77,7 @@ func setupGRPCGateway(t *testing.T, basePath string, serverTLS tlscfg.Options, c
 		QueryService: q,
 	}
 	api_v3.RegisterQueryServiceServer(grpcServer, h)
+	api_v3.RegisterBatchQueryServiceServer(grpcServer, h)
 	lis, _ := net.Listen("tcp", ":0")
 	go func() {
 		err := grpcServer.Serve(lis)
@@ -167,6 +168,43 @@ func TestGRPCGateway_TLS_with_base_path(t *testing.T) {
 	testGRPCGateway(t, "/jaeger", serverTLS, clientTLS)
 }
 
+func TestGRPCGatewayGetTraces(t *testing.T) {
+	reader, httpLis, grpcServer, cancel, httpServer := setupGRPCGateway(t, "/", tlscfg.Options{}, tlscfg.Options{}, tenancy.Options{})
+	defer grpcServer.Stop()
+	defer cancel()
+	defer httpServer.Shutdown(context.Background())
+
+	for _, traceID := range []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2)} {
+		reader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), traceID).Return(
+			&model.Trace{
+				Spans: []*model.Span{
+					{
+						TraceID:       traceID,
+						SpanID:        model.NewSpanID(1),
+						OperationName: "foobar",
+					},
+				},
+			}, nil).Once()
+	}
+
+	response, err := http.Get(fmt.Sprintf("http://localhost%s/api/v3/batch/traces?trace_ids=1&trace_ids=2", strings.Replace(httpLis.Addr().String(), "[::]", "", 1)))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	jsonpb := &runtime.JSONPb{}
+	decoder := json.NewDecoder(response.Body)
+	var traceIDs [][]byte
+	for decoder.More() {
+		var envelope envelope
+		require.NoError(t, decoder.Decode(&envelope))
+		var spansResponse api_v3.SpansResponseChunk
+		require.NoError(t, jsonpb.Unmarshal(envelope.Result, &spansResponse))
+		traceIDs = append(traceIDs, spansResponse.GetResourceSpans()[0].GetInstrumentationLibrarySpans()[0].GetSpans()[0].GetTraceId())
+	}
+	assert.Equal(t, [][]byte{uint64ToTraceID(0, 1), uint64ToTraceID(0, 2)}, traceIDs)
+}
+
 // For more details why this is needed see https://github.com/grpc-ecosystem/grpc-gateway/issues/2189
 type envelope struct {
 	Result json.RawMessage `json:"result"`
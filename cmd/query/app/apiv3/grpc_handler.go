@@ -16,6 +16,7 @@ package apiv3
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/gogo/protobuf/types"
@@ -33,7 +34,10 @@ type Handler struct {
 	QueryService *querysvc.QueryService
 }
 
-var _ api_v3.QueryServiceServer = (*Handler)(nil)
+var (
+	_ api_v3.QueryServiceServer      = (*Handler)(nil)
+	_ api_v3.BatchQueryServiceServer = (*Handler)(nil)
+)
 
 // GetTrace implements api_v3.QueryServiceServer's GetTrace
 func (h *Handler) GetTrace(request *api_v3.GetTraceRequest, stream api_v3.QueryService_GetTraceServer) error {
@@ -46,10 +50,64 @@ func (h *Handler) GetTrace(request *api_v3.GetTraceRequest, stream api_v3.QueryS
 	if err != nil {
 		return err
 	}
-	resourceSpans := jaegerSpansToOTLP(trace.GetSpans())
-	return stream.Send(&api_v3.SpansResponseChunk{
-		ResourceSpans: resourceSpans,
-	})
+	chunk := &api_v3.SpansResponseChunk{
+		ResourceSpans: jaegerSpansToOTLP(trace.GetSpans()),
+	}
+	if request.GetIncludeCriticalPath() {
+		chunk.CriticalPath, err = criticalPathToProto(querysvc.CriticalPath(trace))
+		if err != nil {
+			return err
+		}
+	}
+	return stream.Send(chunk)
+}
+
+// GetTraces implements api_v3.BatchQueryServiceServer's GetTraces. It sends one chunk per
+// trace found in the primary or archive storage, the traces not found are skipped.
+func (h *Handler) GetTraces(request *api_v3.GetTracesRequest, stream api_v3.BatchQueryService_GetTracesServer) error {
+	traceIDs := make([]model.TraceID, len(request.GetTraceIds()))
+	for i, id := range request.GetTraceIds() {
+		traceID, err := model.TraceIDFromString(id)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		traceIDs[i] = traceID
+	}
+	traces, err := h.QueryService.GetTraces(stream.Context(), traceIDs)
+	if err != nil {
+		return err
+	}
+	for _, trace := range traces {
+		if err := stream.Send(&api_v3.SpansResponseChunk{
+			ResourceSpans: jaegerSpansToOTLP(trace.GetSpans()),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// criticalPathToProto converts the critical path to api_v3. Since the critical path is computed
+// on the spans as returned, i.e. without adjustments, the sections can differ from the HTTP API
+// for traces with clock skew.
+func criticalPathToProto(sections []querysvc.CriticalPathSection) ([]*api_v3.CriticalPathSection, error) {
+	protoSections := make([]*api_v3.CriticalPathSection, len(sections))
+	for i, section := range sections {
+		start, err := types.TimestampProto(section.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := types.TimestampProto(section.End)
+		if err != nil {
+			return nil, err
+		}
+		protoSections[i] = &api_v3.CriticalPathSection{
+			SpanId:    uint64ToSpanID(uint64(section.SpanID)),
+			StartTime: start,
+			EndTime:   end,
+		}
+	}
+	return protoSections, nil
 }
 
 // FindTraces implements api_v3.QueryServiceServer's FindTraces
@@ -68,6 +126,19 @@ func (h *Handler) FindTraces(request *api_v3.FindTracesRequest, stream api_v3.Qu
 		OperationName: query.GetOperationName(),
 		Tags:          query.GetAttributes(),
 		NumTraces:     int(query.GetNumTraces()),
+		PageToken:     query.GetPageToken(),
+	}
+	tagMatchMode, err := toTagMatchMode(query.GetAttributeMatchMode())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	queryParams.TagMatchMode = tagMatchMode
+	for _, filter := range query.GetAttributeFilters() {
+		tagFilter, err := spanstore.ParseTagFilter(filter)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid attribute filter %q: %v", filter, err)
+		}
+		queryParams.TagFilters = append(queryParams.TagFilters, tagFilter)
 	}
 	if query.GetStartTimeMin() != nil {
 		startTimeMin, err := types.TimestampFromProto(query.GetStartTimeMin())
@@ -98,19 +169,42 @@ func (h *Handler) FindTraces(request *api_v3.FindTracesRequest, stream api_v3.Qu
 		queryParams.DurationMax = durationMax
 	}
 
-	traces, err := h.QueryService.FindTraces(stream.Context(), queryParams)
+	page, err := h.QueryService.FindTracesPage(stream.Context(), queryParams)
+	if errors.Is(err, spanstore.ErrInvalidPageToken) || errors.Is(err, spanstore.ErrPaginationNotSupported) ||
+		errors.Is(err, spanstore.ErrUnsupportedTagFilter) || errors.Is(err, spanstore.ErrUnsupportedTagMatchMode) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		return err
 	}
-	for _, t := range traces {
-		resourceSpans := jaegerSpansToOTLP(t.GetSpans())
-		stream.Send(&api_v3.SpansResponseChunk{
-			ResourceSpans: resourceSpans,
-		})
+	for i, t := range page.Traces {
+		chunk := &api_v3.SpansResponseChunk{
+			ResourceSpans: jaegerSpansToOTLP(t.GetSpans()),
+		}
+		if i == len(page.Traces)-1 {
+			chunk.NextPageToken = page.NextPageToken
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	if len(page.Traces) == 0 && page.NextPageToken != "" {
+		// the traces of the page may all have been filtered out
+		return stream.Send(&api_v3.SpansResponseChunk{NextPageToken: page.NextPageToken})
 	}
 	return nil
 }
 
+func toTagMatchMode(mode api_v3.AttributeMatchMode) (spanstore.TagMatchMode, error) {
+	switch mode {
+	case api_v3.AttributeMatchMode_SAME_SPAN:
+		return spanstore.TagMatchSameSpan, nil
+	case api_v3.AttributeMatchMode_ANY_SPAN_IN_TRACE:
+		return spanstore.TagMatchAnySpanInTrace, nil
+	}
+	return spanstore.TagMatchSameSpan, fmt.Errorf("unknown attribute match mode %s", mode)
+}
+
 // GetServices implements api_v3.QueryServiceServer's GetServices
 func (h *Handler) GetServices(ctx context.Context, _ *api_v3.GetServicesRequest) (*api_v3.GetServicesResponse, error) {
 	services, err := h.QueryService.GetServices(ctx)
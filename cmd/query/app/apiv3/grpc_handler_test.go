@@ -17,19 +17,24 @@ package apiv3
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/gogo/protobuf/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	"github.com/kjschnei001/jaeger/cmd/query/app/querysvc"
 	"github.com/kjschnei001/jaeger/model"
 	_ "github.com/kjschnei001/jaeger/pkg/gogocodec" // force gogo codec registration
+	"github.com/kjschnei001/jaeger/plugin/storage/memory"
 	"github.com/kjschnei001/jaeger/proto-gen/api_v3"
 	dependencyStoreMocks "github.com/kjschnei001/jaeger/storage/dependencystore/mocks"
 	"github.com/kjschnei001/jaeger/storage/spanstore"
@@ -39,6 +44,7 @@ import (
 func newGrpcServer(t *testing.T, handler *Handler) (*grpc.Server, net.Addr) {
 	server := grpc.NewServer()
 	api_v3.RegisterQueryServiceServer(server, handler)
+	api_v3.RegisterBatchQueryServiceServer(server, handler)
 
 	lis, _ := net.Listen("tcp", ":0")
 	go func() {
@@ -81,6 +87,84 @@ func TestGetTrace(t *testing.T) {
 	assert.Equal(t, "foobar", spansChunk.GetResourceSpans()[0].GetInstrumentationLibrarySpans()[0].GetSpans()[0].GetName())
 }
 
+func TestGetTraces(t *testing.T) {
+	primary := &spanstoremocks.Reader{}
+	primary.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(
+		&model.Trace{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 1), OperationName: "primary"}}}, nil).Once()
+	primary.On("GetTrace", mock.Anything, mock.AnythingOfType("model.TraceID")).Return(nil, spanstore.ErrTraceNotFound).Twice()
+	archive := &spanstoremocks.Reader{}
+	archive.On("GetTrace", mock.Anything, model.NewTraceID(0, 2)).Return(
+		&model.Trace{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 2), OperationName: "archive"}}}, nil).Once()
+	archive.On("GetTrace", mock.Anything, model.NewTraceID(0, 3)).Return(nil, spanstore.ErrTraceNotFound).Once()
+
+	q := querysvc.NewQueryService(primary, &dependencyStoreMocks.Reader{}, querysvc.QueryServiceOptions{ArchiveSpanReader: archive})
+	server, addr := newGrpcServer(t, &Handler{QueryService: q})
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v3.NewBatchQueryServiceClient(conn)
+
+	stream, err := client.GetTraces(context.Background(), &api_v3.GetTracesRequest{TraceIds: []string{"1", "2", "3"}})
+	require.NoError(t, err)
+	var operations []string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		operations = append(operations, chunk.GetResourceSpans()[0].GetInstrumentationLibrarySpans()[0].GetSpans()[0].GetName())
+	}
+	assert.Equal(t, []string{"primary", "archive"}, operations)
+
+	stream, err = client.GetTraces(context.Background(), &api_v3.GetTracesRequest{TraceIds: []string{"Z"}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetTraceCriticalPath(t *testing.T) {
+	start := time.Unix(1, 0)
+	r := &spanstoremocks.Reader{}
+	r.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).Return(
+		&model.Trace{
+			Spans: []*model.Span{
+				{
+					SpanID:    model.NewSpanID(1),
+					StartTime: start,
+					Duration:  time.Second,
+				},
+			},
+		}, nil).Twice()
+
+	q := querysvc.NewQueryService(r, &dependencyStoreMocks.Reader{}, querysvc.QueryServiceOptions{})
+	server, addr := newGrpcServer(t, &Handler{QueryService: q})
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v3.NewQueryServiceClient(conn)
+
+	getTraceStream, err := client.GetTrace(context.Background(), &api_v3.GetTraceRequest{TraceId: "156", IncludeCriticalPath: true})
+	require.NoError(t, err)
+	chunk, err := getTraceStream.Recv()
+	require.NoError(t, err)
+	require.Len(t, chunk.GetCriticalPath(), 1)
+	section := chunk.GetCriticalPath()[0]
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, section.GetSpanId())
+	assert.Equal(t, &types.Timestamp{Seconds: 1}, section.GetStartTime())
+	assert.Equal(t, &types.Timestamp{Seconds: 2}, section.GetEndTime())
+
+	getTraceStream, err = client.GetTrace(context.Background(), &api_v3.GetTraceRequest{TraceId: "156"})
+	require.NoError(t, err)
+	chunk, err = getTraceStream.Recv()
+	require.NoError(t, err)
+	assert.Empty(t, chunk.GetCriticalPath())
+}
+
 func TestGetTrace_storage_error(t *testing.T) {
 	r := &spanstoremocks.Reader{}
 	r.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).Return(
@@ -176,6 +260,69 @@ func TestFindTraces(t *testing.T) {
 	assert.Equal(t, 1, len(recv.GetResourceSpans()))
 }
 
+func TestFindTracesPageToken(t *testing.T) {
+	store := memory.NewStore()
+	startTime := time.Now().Add(-time.Minute)
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, store.WriteSpan(context.Background(), &model.Span{
+			TraceID:       model.NewTraceID(0, uint64(i)),
+			SpanID:        model.SpanID(i),
+			OperationName: "name",
+			Process:       &model.Process{ServiceName: "myservice"},
+			StartTime:     startTime.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	q := querysvc.NewQueryService(store, &dependencyStoreMocks.Reader{}, querysvc.QueryServiceOptions{})
+	server, addr := newGrpcServer(t, &Handler{QueryService: q})
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v3.NewQueryServiceClient(conn)
+	findPage := func(pageToken string) ([]*api_v3.SpansResponseChunk, string) {
+		responseStream, err := client.FindTraces(context.Background(), &api_v3.FindTracesRequest{
+			Query: &api_v3.TraceQueryParameters{
+				ServiceName:  "myservice",
+				PageToken:    pageToken,
+				StartTimeMin: &types.Timestamp{Seconds: startTime.Add(-time.Minute).Unix()},
+				StartTimeMax: &types.Timestamp{Seconds: startTime.Add(time.Minute).Unix()},
+				NumTraces:    1,
+			},
+		})
+		require.NoError(t, err)
+		var chunks []*api_v3.SpansResponseChunk
+		for {
+			chunk, err := responseStream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			chunks = append(chunks, chunk)
+		}
+		return chunks, chunks[len(chunks)-1].GetNextPageToken()
+	}
+
+	chunks, token := findPage("")
+	require.Len(t, chunks, 1)
+	require.NotEmpty(t, token)
+	chunks, token = findPage(token)
+	require.Len(t, chunks, 1)
+	assert.Empty(t, token)
+
+	responseStream, err := client.FindTraces(context.Background(), &api_v3.FindTracesRequest{
+		Query: &api_v3.TraceQueryParameters{
+			PageToken:    "invalid",
+			StartTimeMin: &types.Timestamp{},
+			StartTimeMax: &types.Timestamp{},
+		},
+	})
+	require.NoError(t, err)
+	_, err = responseStream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestFindTraces_query_nil(t *testing.T) {
 	q := querysvc.NewQueryService(&spanstoremocks.Reader{}, &dependencyStoreMocks.Reader{}, querysvc.QueryServiceOptions{})
 	h := &Handler{QueryService: q}
@@ -330,3 +477,70 @@ func TestGetOperations_storage_error(t *testing.T) {
 	assert.Contains(t, err.Error(), "storage_error")
 	assert.Nil(t, response)
 }
+
+func TestFindTracesAttributeFilters(t *testing.T) {
+	r := &spanstoremocks.Reader{}
+	r.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+		return query.Tags["http.status_code>="] == "500" && len(query.TagFilters) == 2 &&
+			query.TagFilters[0].String() == `span.http.status_code >= "500"` &&
+			query.TagFilters[1].String() == `span.db.statement =~ "SELECT.*"`
+	})).Return(nil, fmt.Errorf("%w: %s", spanstore.ErrUnsupportedTagFilter, "span.db.statement =~")).Once()
+
+	q := querysvc.NewQueryService(r, &dependencyStoreMocks.Reader{}, querysvc.QueryServiceOptions{})
+	server, addr := newGrpcServer(t, &Handler{QueryService: q})
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v3.NewQueryServiceClient(conn)
+	for _, filters := range [][]string{
+		{"http.status_code>=500", `db.statement=~"SELECT.*"`},
+		{"http.status_code>abc"},
+	} {
+		responseStream, err := client.FindTraces(context.Background(), &api_v3.FindTracesRequest{
+			Query: &api_v3.TraceQueryParameters{
+				ServiceName:      "myservice",
+				Attributes:       map[string]string{"http.status_code>=": "500"},
+				AttributeFilters: filters,
+				StartTimeMin:     &types.Timestamp{},
+				StartTimeMax:     &types.Timestamp{},
+			},
+		})
+		require.NoError(t, err)
+		_, err = responseStream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), filters)
+	}
+	r.AssertExpectations(t)
+}
+
+func TestFindTracesAttributeMatchMode(t *testing.T) {
+	r := &spanstoremocks.Reader{}
+	r.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+		return query.TagMatchMode == spanstore.TagMatchAnySpanInTrace
+	})).Return(nil, fmt.Errorf("%w: %s", spanstore.ErrUnsupportedTagMatchMode, spanstore.TagMatchAnySpanInTrace)).Once()
+
+	q := querysvc.NewQueryService(r, &dependencyStoreMocks.Reader{}, querysvc.QueryServiceOptions{})
+	server, addr := newGrpcServer(t, &Handler{QueryService: q})
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v3.NewQueryServiceClient(conn)
+	for _, mode := range []api_v3.AttributeMatchMode{api_v3.AttributeMatchMode_ANY_SPAN_IN_TRACE, api_v3.AttributeMatchMode(5)} {
+		responseStream, err := client.FindTraces(context.Background(), &api_v3.FindTracesRequest{
+			Query: &api_v3.TraceQueryParameters{
+				ServiceName:        "myservice",
+				Attributes:         map[string]string{"foo": "bar", "baz": "qux"},
+				AttributeMatchMode: mode,
+				StartTimeMin:       &types.Timestamp{},
+				StartTimeMax:       &types.Timestamp{},
+			},
+		})
+		require.NoError(t, err)
+		_, err = responseStream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), mode)
+	}
+	r.AssertExpectations(t)
+}
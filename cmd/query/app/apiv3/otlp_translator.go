@@ -43,6 +43,12 @@ const (
 	tagW3CTraceState = "w3c.tracestate"
 )
 
+// TracesDataFromSpans converts Jaeger spans to OTLP. The TracesData is wire compatible with
+// the current OTLP protocol, where instrumentation libraries were renamed to scopes.
+func TracesDataFromSpans(spans []*model.Span) *tracev1.TracesData {
+	return &tracev1.TracesData{ResourceSpans: jaegerSpansToOTLP(spans)}
+}
+
 // OpenTelemetry collector implements translator from Jaeger model to pdata (wrapper around OTLP).
 // However, it cannot be used because the imported OTLP in the translator is in the collector's private package.
 func jaegerSpansToOTLP(spans []*model.Span) []*tracev1.ResourceSpans {
@@ -47,6 +47,17 @@ const (
 	queryTokenPropagation      = "query.bearer-token-propagation"
 	queryAdditionalHeaders     = "query.additional-headers"
 	queryMaxClockSkewAdjust    = "query.max-clock-skew-adjustment"
+	queryImportStorage         = "query.import-storage"
+	queryImportMaxBytes        = "query.import-max-bytes"
+)
+
+const (
+	// importStorageArchive writes imported traces to the archive storage, if it is configured
+	importStorageArchive = "archive"
+	// importStoragePrimary writes imported traces to the primary storage
+	importStoragePrimary = "primary"
+	// importStorageNone disables trace import
+	importStorageNone = "none"
 )
 
 var tlsGRPCFlagsConfig = tlscfg.ServerFlagsConfig{
@@ -85,6 +96,10 @@ type QueryOptions struct {
 	MaxClockSkewAdjust time.Duration
 	// Tenancy configures tenancy for query
 	Tenancy tenancy.Options
+	// ImportStorage is the storage, archive, primary or none, the imported traces are written to
+	ImportStorage string
+	// ImportMaxBytes is the maximum size of the request body of trace imports
+	ImportMaxBytes int64
 }
 
 // AddFlags adds flags for QueryOptions
@@ -98,6 +113,8 @@ func AddFlags(flagSet *flag.FlagSet) {
 	flagSet.String(queryUIConfig, "", "The path to the UI configuration file in JSON format")
 	flagSet.Bool(queryTokenPropagation, false, "Allow propagation of bearer token to be used by storage plugins")
 	flagSet.Duration(queryMaxClockSkewAdjust, 0, "The maximum delta by which span timestamps may be adjusted in the UI due to clock skew; set to 0s to disable clock skew adjustments")
+	flagSet.String(queryImportStorage, importStorageNone, fmt.Sprintf("The storage the traces imported through the HTTP API are written to, one of %s, %s or %s; %s disables trace import", importStorageArchive, importStoragePrimary, importStorageNone, importStorageNone))
+	flagSet.Int64(queryImportMaxBytes, defaultImportMaxBytes, "The maximum size in bytes of the request body of the traces imported through the HTTP API")
 	tlsGRPCFlagsConfig.AddFlags(flagSet)
 	tlsHTTPFlagsConfig.AddFlags(flagSet)
 }
@@ -131,10 +148,20 @@ func (qOpts *QueryOptions) InitFromViper(v *viper.Viper, logger *zap.Logger) (*Q
 		qOpts.AdditionalHeaders = headers
 	}
 	qOpts.Tenancy = tenancy.InitFromViper(v)
+	qOpts.ImportStorage = v.GetString(queryImportStorage)
+	switch qOpts.ImportStorage {
+	case importStorageArchive, importStoragePrimary, importStorageNone:
+	default:
+		return qOpts, fmt.Errorf("unsupported value '%s' for %s", qOpts.ImportStorage, queryImportStorage)
+	}
+	qOpts.ImportMaxBytes = v.GetInt64(queryImportMaxBytes)
+	if qOpts.ImportMaxBytes <= 0 {
+		return qOpts, fmt.Errorf("%s must be positive, got %d", queryImportMaxBytes, qOpts.ImportMaxBytes)
+	}
 	return qOpts, nil
 }
 
-// BuildQueryServiceOptions creates a QueryServiceOptions struct with appropriate adjusters and archive config
+// BuildQueryServiceOptions creates a QueryServiceOptions struct with appropriate adjusters, archive and import config
 func (qOpts *QueryOptions) BuildQueryServiceOptions(storageFactory storage.Factory, logger *zap.Logger) *querysvc.QueryServiceOptions {
 	opts := &querysvc.QueryServiceOptions{}
 	if !opts.InitArchiveStorage(storageFactory, logger) {
@@ -143,6 +170,21 @@ func (qOpts *QueryOptions) BuildQueryServiceOptions(storageFactory storage.Facto
 
 	opts.Adjuster = adjuster.Sequence(querysvc.StandardAdjusters(qOpts.MaxClockSkewAdjust)...)
 
+	switch qOpts.ImportStorage {
+	case importStorageArchive:
+		opts.ImportSpanWriter = opts.ArchiveSpanWriter
+	case importStoragePrimary:
+		writer, err := storageFactory.CreateSpanWriter()
+		if err != nil {
+			logger.Error("Cannot init import span writer", zap.Error(err))
+		} else {
+			opts.ImportSpanWriter = writer
+		}
+	}
+	if opts.ImportSpanWriter == nil {
+		logger.Info("Trace import not enabled", zap.String("import-storage", qOpts.ImportStorage))
+	}
+
 	return opts
 }
 
@@ -16,6 +16,7 @@
 package app
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"testing"
@@ -97,7 +98,8 @@ func TestStringSliceAsHeader(t *testing.T) {
 }
 
 func TestBuildQueryServiceOptions(t *testing.T) {
-	v, _ := config.Viperize(AddFlags)
+	v, command := config.Viperize(AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--query.import-storage=archive"}))
 	qOpts, err := new(QueryOptions).InitFromViper(v, zap.NewNop())
 	require.NoError(t, err)
 	assert.NotNil(t, qOpts)
@@ -124,6 +126,48 @@ func TestBuildQueryServiceOptions(t *testing.T) {
 	assert.NotNil(t, qSvcOpts.Adjuster)
 	assert.NotNil(t, qSvcOpts.ArchiveSpanReader)
 	assert.NotNil(t, qSvcOpts.ArchiveSpanWriter)
+	assert.Equal(t, qSvcOpts.ArchiveSpanWriter, qSvcOpts.ImportSpanWriter)
+}
+
+func TestBuildQueryServiceOptionsImportStorage(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--query.import-storage=primary"}))
+	qOpts, err := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+
+	writer := &spanstore_mocks.Writer{}
+	factory := &mocks.Factory{}
+	factory.On("CreateSpanWriter").Return(writer, nil).Once()
+	qSvcOpts := qOpts.BuildQueryServiceOptions(factory, zap.NewNop())
+	assert.Equal(t, writer, qSvcOpts.ImportSpanWriter)
+
+	factory.On("CreateSpanWriter").Return(nil, errors.New("no writer")).Once()
+	qSvcOpts = qOpts.BuildQueryServiceOptions(factory, zap.NewNop())
+	assert.Nil(t, qSvcOpts.ImportSpanWriter)
+
+	qOpts.ImportStorage = importStorageNone
+	qSvcOpts = qOpts.BuildQueryServiceOptions(factory, zap.NewNop())
+	assert.Nil(t, qSvcOpts.ImportSpanWriter)
+}
+
+func TestQueryOptionsImportDefaults(t *testing.T) {
+	v, _ := config.Viperize(AddFlags)
+	qOpts, err := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, importStorageNone, qOpts.ImportStorage)
+	assert.EqualValues(t, defaultImportMaxBytes, qOpts.ImportMaxBytes)
+}
+
+func TestQueryOptionsInvalidImportStorage(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--query.import-storage=elsewhere"}))
+	_, err := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	assert.EqualError(t, err, "unsupported value 'elsewhere' for query.import-storage")
+
+	v, command = config.Viperize(AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--query.import-max-bytes=0"}))
+	_, err = new(QueryOptions).InitFromViper(v, zap.NewNop())
+	assert.EqualError(t, err, "query.import-max-bytes must be positive, got 0")
 }
 
 func TestQueryOptionsPortAllocationFromFlags(t *testing.T) {
@@ -19,6 +19,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"testing"
 	"time"
@@ -147,14 +148,16 @@ type grpcClient struct {
 
 func newGRPCServer(t *testing.T, q *querysvc.QueryService, mq querysvc.MetricsQueryService, logger *zap.Logger, tracer opentracing.Tracer, tenancyMgr *tenancy.Manager) (*grpc.Server, net.Addr) {
 	lis, _ := net.Listen("tcp", ":0")
-	var grpcOpts []grpc.ServerOption
+	streamInterceptors := []grpc.StreamServerInterceptor{newStorageFailuresStreamInterceptor()}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{newStorageFailuresUnaryInterceptor()}
 	if tenancyMgr.Enabled {
-		grpcOpts = append(grpcOpts,
-			grpc.StreamInterceptor(tenancy.NewGuardingStreamInterceptor(tenancyMgr)),
-			grpc.UnaryInterceptor(tenancy.NewGuardingUnaryInterceptor(tenancyMgr)),
-		)
+		streamInterceptors = append(streamInterceptors, tenancy.NewGuardingStreamInterceptor(tenancyMgr))
+		unaryInterceptors = append(unaryInterceptors, tenancy.NewGuardingUnaryInterceptor(tenancyMgr))
 	}
-	grpcServer := grpc.NewServer(grpcOpts...)
+	grpcServer := grpc.NewServer(
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+	)
 	grpcHandler := NewGRPCHandler(q, mq, GRPCHandlerOptions{
 		Logger: logger,
 		Tracer: tracer,
@@ -463,6 +466,33 @@ func TestGetServicesSuccessGRPC(t *testing.T) {
 	})
 }
 
+func TestStoragePartialFailuresGRPC(t *testing.T) {
+	withServerAndClient(t, func(server *grpcServer, client *grpcClient) {
+		reportFailure := func(args mock.Arguments) {
+			spanstore.ReportFailure(args.Get(0).(context.Context), errors.New("the cassandra storage failed: unavailable"))
+		}
+		server.spanReader.On("GetServices", mock.AnythingOfType("*context.valueCtx")).
+			Run(reportFailure).Return([]string{"trifle"}, nil).Once()
+		server.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Run(reportFailure).Return(nil, nil).Once()
+
+		var trailer metadata.MD
+		res, err := client.GetServices(context.Background(), &api_v2.GetServicesRequest{}, grpc.Trailer(&trailer))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"trifle"}, res.Services)
+		assert.Equal(t, []string{"the cassandra storage failed: unavailable"}, trailer.Get(storageFailuresTrailer))
+
+		// the failures are returned when nothing was found
+		stream, err := client.FindTraces(context.Background(), &api_v2.FindTracesRequest{
+			Query: &api_v2.TraceQueryParameters{ServiceName: "service"},
+		})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, []string{"the cassandra storage failed: unavailable"}, stream.Trailer().Get(storageFailuresTrailer))
+	})
+}
+
 func TestGetServicesFailureGRPC(t *testing.T) {
 	withServerAndClient(t, func(server *grpcServer, client *grpcClient) {
 		server.spanReader.On("GetServices", mock.AnythingOfType("*context.valueCtx")).Return(nil, errStorageGRPC).Once()
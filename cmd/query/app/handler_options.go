@@ -74,3 +74,10 @@ func (handlerOptions) MetricsQueryService(mqs querysvc.MetricsQueryService) Hand
 		apiHandler.metricsQueryService = mqs
 	}
 }
+
+// ImportMaxBytes creates a HandlerOption that initializes the maximum size of the request body of trace imports
+func (handlerOptions) ImportMaxBytes(maxBytes int64) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.importMaxBytes = maxBytes
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -44,6 +45,11 @@ import (
 
 const (
 	traceIDParam          = "traceID"
+	traceIDAParam         = "a"
+	traceIDBParam         = "b"
+	criticalPathParam     = "criticalPath"
+	viewParam             = "view"
+	formatParam           = "format"
 	endTsParam            = "endTs"
 	lookbackParam         = "lookback"
 	stepParam             = "step"
@@ -51,8 +57,14 @@ const (
 	quantileParam         = "quantile"
 	groupByOperationParam = "groupByOperation"
 
+	// summaryView makes search respond with trace summaries instead of full traces
+	summaryView = "summary"
+	fullView    = "full"
+
 	defaultAPIPrefix  = "api"
 	prettyPrintIndent = "    "
+
+	defaultImportMaxBytes = 10 << 20
 )
 
 // HTTPHandler handles http requests
@@ -61,11 +73,12 @@ type HTTPHandler interface {
 }
 
 type structuredResponse struct {
-	Data   interface{}       `json:"data"`
-	Total  int               `json:"total"`
-	Limit  int               `json:"limit"`
-	Offset int               `json:"offset"`
-	Errors []structuredError `json:"errors"`
+	Data          interface{}       `json:"data"`
+	Total         int               `json:"total"`
+	Limit         int               `json:"limit"`
+	Offset        int               `json:"offset"`
+	Errors        []structuredError `json:"errors"`
+	NextPageToken string            `json:"nextPageToken,omitempty"`
 }
 
 type structuredError struct {
@@ -89,6 +102,7 @@ type APIHandler struct {
 	apiPrefix           string
 	logger              *zap.Logger
 	tracer              opentracing.Tracer
+	importMaxBytes      int64
 }
 
 // NewAPIHandler returns an APIHandler
@@ -114,12 +128,20 @@ func NewAPIHandler(queryService *querysvc.QueryService, tm *tenancy.Manager, opt
 	if aH.tracer == nil {
 		aH.tracer = opentracing.NoopTracer{}
 	}
+	if aH.importMaxBytes <= 0 {
+		aH.importMaxBytes = defaultImportMaxBytes
+	}
 	return aH
 }
 
 // RegisterRoutes registers routes for this handler on the given router
 func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
+	// must be registered before /traces/{traceID}, which would match them otherwise
+	aH.handleFunc(router, aH.compareTraces, "/traces/compare").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.aggregateTraces, "/traces/aggregate").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.importTraces, "/traces/import").Methods(http.MethodPost)
 	aH.handleFunc(router, aH.getTrace, "/traces/{%s}", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.exportTrace, "/traces/{%s}/export", traceIDParam).Methods(http.MethodGet)
 	aH.handleFunc(router, aH.archiveTrace, "/archive/{%s}", traceIDParam).Methods(http.MethodPost)
 	aH.handleFunc(router, aH.search, "/traces").Methods(http.MethodGet)
 	aH.handleFunc(router, aH.getServices, "/services").Methods(http.MethodGet)
@@ -142,7 +164,10 @@ func (aH *APIHandler) handleFunc(
 ) *mux.Route {
 	route = aH.route(route, args...)
 	var handler http.Handler
-	handler = http.HandlerFunc(f)
+	handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the partial failures of the storages are returned in the errors of the response, see writeJSON
+		f(w, r.WithContext(spanstore.WithFailures(r.Context())))
+	})
 	if aH.tenancyMgr.Enabled {
 		handler = tenancy.ExtractTenantHTTPHandler(aH.tenancyMgr, handler)
 	}
@@ -230,24 +255,39 @@ func (aH *APIHandler) search(w http.ResponseWriter, r *http.Request) {
 	if aH.handleError(w, err, http.StatusBadRequest) {
 		return
 	}
+	switch view := r.FormValue(viewParam); view {
+	case "", fullView:
+	case summaryView:
+		aH.searchSummaries(w, r, tQuery)
+		return
+	default:
+		aH.handleError(w, fmt.Errorf("unsupported value '%s' for parameter '%s'", view, viewParam), http.StatusBadRequest)
+		return
+	}
 
 	var uiErrors []structuredError
 	var tracesFromStorage []*model.Trace
+	var nextPageToken string
 	if len(tQuery.traceIDs) > 0 {
 		tracesFromStorage, uiErrors, err = aH.tracesByIDs(r.Context(), tQuery.traceIDs)
 		if aH.handleError(w, err, http.StatusInternalServerError) {
 			return
 		}
 	} else {
-		tracesFromStorage, err = aH.queryService.FindTraces(r.Context(), &tQuery.TraceQueryParameters)
-		if aH.handleError(w, err, http.StatusInternalServerError) {
+		page, err := aH.queryService.FindTracesPage(r.Context(), &tQuery.TraceQueryParameters)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, spanstore.ErrInvalidPageToken) || errors.Is(err, spanstore.ErrPaginationNotSupported) {
+			statusCode = http.StatusBadRequest
+		}
+		if aH.handleError(w, err, statusCode) {
 			return
 		}
+		tracesFromStorage, nextPageToken = page.Traces, page.NextPageToken
 	}
 
 	uiTraces := make([]*ui.Trace, len(tracesFromStorage))
 	for i, v := range tracesFromStorage {
-		uiTrace, uiErr := aH.convertModelToUI(v, true)
+		uiTrace, uiErr := aH.convertModelToUI(v, true, false)
 		if uiErr != nil {
 			uiErrors = append(uiErrors, *uiErr)
 		}
@@ -255,29 +295,114 @@ func (aH *APIHandler) search(w http.ResponseWriter, r *http.Request) {
 	}
 
 	structuredRes := structuredResponse{
-		Data:   uiTraces,
+		Data:          uiTraces,
+		Errors:        uiErrors,
+		NextPageToken: nextPageToken,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// searchSummaries responds to /traces?view=summary with the summaries of the found traces.
+func (aH *APIHandler) searchSummaries(w http.ResponseWriter, r *http.Request, tQuery *traceQueryParameters) {
+	var uiErrors []structuredError
+	var summaries []*spanstore.TraceSummary
+	if len(tQuery.traceIDs) > 0 {
+		tracesFromStorage, errs, err := aH.tracesByIDs(r.Context(), tQuery.traceIDs)
+		if aH.handleError(w, err, http.StatusInternalServerError) {
+			return
+		}
+		uiErrors = errs
+		summaries = spanstore.SummarizeTraces(tracesFromStorage)
+	} else {
+		var err error
+		summaries, err = aH.queryService.FindTraceSummaries(r.Context(), &tQuery.TraceQueryParameters)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, spanstore.ErrPaginationNotSupported) {
+			statusCode = http.StatusBadRequest
+		}
+		if aH.handleError(w, err, statusCode) {
+			return
+		}
+	}
+
+	uiSummaries := make([]*ui.TraceSummary, len(summaries))
+	for i, summary := range summaries {
+		uiSummaries[i] = &ui.TraceSummary{
+			TraceID:           ui.TraceID(summary.TraceID.String()),
+			RootServiceName:   summary.RootServiceName,
+			RootOperationName: summary.RootOperationName,
+			StartTime:         model.TimeAsEpochMicroseconds(summary.StartTime),
+			Duration:          model.DurationAsMicroseconds(summary.Duration),
+			SpanCount:         summary.SpanCount,
+			ServiceSpanCounts: summary.ServiceSpanCounts,
+			ErrorCount:        summary.ErrorCount,
+		}
+	}
+	structuredRes := structuredResponse{
+		Data:   uiSummaries,
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// aggregateTraces implements the REST API /traces/aggregate, which takes the same parameters
+// as /traces and responds with the statistics of the spans of the found traces.
+func (aH *APIHandler) aggregateTraces(w http.ResponseWriter, r *http.Request) {
+	tQuery, err := aH.queryParser.parseTraceQueryParams(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+
+	var uiErrors []structuredError
+	var aggregation *querysvc.TraceAggregation
+	if len(tQuery.traceIDs) > 0 {
+		var tracesFromStorage []*model.Trace
+		tracesFromStorage, uiErrors, err = aH.tracesByIDs(r.Context(), tQuery.traceIDs)
+		if aH.handleError(w, err, http.StatusInternalServerError) {
+			return
+		}
+		var warnings []string
+		for i, trace := range tracesFromStorage {
+			tracesFromStorage[i], err = aH.queryService.Adjust(trace)
+			if err != nil {
+				warnings = append(warnings, err.Error())
+			}
+		}
+		aggregation = querysvc.NewTraceAggregation(tracesFromStorage)
+		aggregation.Warnings = warnings
+	} else {
+		aggregation, err = aH.queryService.AggregateTraces(r.Context(), &tQuery.TraceQueryParameters)
+		if aH.handleError(w, err, http.StatusInternalServerError) {
+			return
+		}
+	}
+
+	structuredRes := structuredResponse{
+		Data:   aggregation,
 		Errors: uiErrors,
 	}
 	aH.writeJSON(w, r, &structuredRes)
 }
 
 func (aH *APIHandler) tracesByIDs(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, []structuredError, error) {
+	traces, err := aH.queryService.GetTraces(ctx, traceIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	found := make(map[model.TraceID]struct{}, len(traces))
+	for _, trace := range traces {
+		found[spanstore.TraceIDOf(trace)] = struct{}{}
+	}
 	var errors []structuredError
-	retMe := make([]*model.Trace, 0, len(traceIDs))
-	for _, traceID := range traceIDs {
-		if trace, err := aH.queryService.GetTrace(ctx, traceID); err != nil {
-			if err != spanstore.ErrTraceNotFound {
-				return nil, nil, err
-			}
+	for _, traceID := range spanstore.UniqueTraceIDs(traceIDs) {
+		if _, ok := found[traceID]; !ok {
 			errors = append(errors, structuredError{
-				Msg:     err.Error(),
+				Msg:     spanstore.ErrTraceNotFound.Error(),
 				TraceID: ui.TraceID(traceID.String()),
 			})
-		} else {
-			retMe = append(retMe, trace)
 		}
 	}
-	return retMe, errors, nil
+	return traces, errors, nil
 }
 
 func (aH *APIHandler) dependencies(w http.ResponseWriter, r *http.Request) {
@@ -353,7 +478,7 @@ func (aH *APIHandler) metrics(w http.ResponseWriter, r *http.Request, getMetrics
 	aH.writeJSON(w, r, m)
 }
 
-func (aH *APIHandler) convertModelToUI(trace *model.Trace, adjust bool) (*ui.Trace, *structuredError) {
+func (aH *APIHandler) convertModelToUI(trace *model.Trace, adjust, criticalPath bool) (*ui.Trace, *structuredError) {
 	var errs []error
 	if adjust {
 		var err error
@@ -363,6 +488,9 @@ func (aH *APIHandler) convertModelToUI(trace *model.Trace, adjust bool) (*ui.Tra
 		}
 	}
 	uiTrace := uiconv.FromDomain(trace)
+	if criticalPath {
+		uiTrace.CriticalPath = criticalPathToUI(querysvc.CriticalPath(trace))
+	}
 	var uiError *structuredError
 	if err := errors.Join(errs...); err != nil {
 		uiError = &structuredError{
@@ -423,12 +551,18 @@ func (aH *APIHandler) parseTraceID(w http.ResponseWriter, r *http.Request) (mode
 // getTrace implements the REST API /traces/{trace-id}
 // It parses trace ID from the path, fetches the trace from QueryService,
 // formats it in the UI JSON format, and responds to the client.
+// With ?criticalPath=true the response includes the critical path of the trace.
 func (aH *APIHandler) getTrace(w http.ResponseWriter, r *http.Request) {
 	traceID, ok := aH.parseTraceID(w, r)
 	if !ok {
 		return
 	}
-	trace, err := aH.queryService.GetTrace(r.Context(), traceID)
+	adjust := shouldAdjust(r)
+	ctx := r.Context()
+	if !adjust {
+		ctx = spanstore.WithRawTraces(ctx)
+	}
+	trace, err := aH.queryService.GetTrace(ctx, traceID)
 	if err == spanstore.ErrTraceNotFound {
 		aH.handleError(w, err, http.StatusNotFound)
 		return
@@ -438,7 +572,7 @@ func (aH *APIHandler) getTrace(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var uiErrors []structuredError
-	uiTrace, uiErr := aH.convertModelToUI(trace, shouldAdjust(r))
+	uiTrace, uiErr := aH.convertModelToUI(trace, adjust, shouldComputeCriticalPath(r))
 	if uiErr != nil {
 		uiErrors = append(uiErrors, *uiErr)
 	}
@@ -452,12 +586,147 @@ func (aH *APIHandler) getTrace(w http.ResponseWriter, r *http.Request) {
 	aH.writeJSON(w, r, &structuredRes)
 }
 
+// exportTrace implements the REST API /traces/{trace-id}/export?format={format}.
+// It responds with the stored trace, without adjustments, encoded in the format.
+func (aH *APIHandler) exportTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	format, ok := aH.parseFormat(w, r)
+	if !ok {
+		return
+	}
+	trace, err := aH.queryService.GetTrace(r.Context(), traceID)
+	if err == spanstore.ErrTraceNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	data, contentType, err := encodeTrace(trace, format)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+// importTraces implements the REST API POST /traces/import?format={format}.
+// It writes the spans of the traces in the request body to the import span storage
+// and responds with the IDs of the imported traces.
+func (aH *APIHandler) importTraces(w http.ResponseWriter, r *http.Request) {
+	format, ok := aH.parseFormat(w, r)
+	if !ok {
+		return
+	}
+	if !aH.queryService.ImportEnabled() {
+		aH.handleError(w, querysvc.ErrNoImportSpanStorage, http.StatusNotImplemented)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, aH.importMaxBytes))
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			statusCode = http.StatusRequestEntityTooLarge
+		}
+		aH.handleError(w, fmt.Errorf("failed reading request body: %w", err), statusCode)
+		return
+	}
+	spans, err := decodeSpans(body, format)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	if aH.handleError(w, aH.queryService.ImportSpans(r.Context(), spans), http.StatusInternalServerError) {
+		return
+	}
+
+	traceIDs := []string{}
+	seen := make(map[model.TraceID]bool)
+	for _, span := range spans {
+		if !seen[span.TraceID] {
+			seen[span.TraceID] = true
+			traceIDs = append(traceIDs, span.TraceID.String())
+		}
+	}
+	structuredRes := structuredResponse{
+		Data:   traceIDs,
+		Total:  len(traceIDs),
+		Errors: []structuredError{},
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) parseFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := r.FormValue(formatParam)
+	if format == "" {
+		aH.handleError(w, fmt.Errorf("parameter '%s' is required", formatParam), http.StatusBadRequest)
+		return "", false
+	}
+	switch format {
+	case otlpJSONFormat, otlpProtoFormat, jaegerJSONFormat, zipkinV2Format:
+		return format, true
+	}
+	aH.handleError(w, unsupportedFormatError(format), http.StatusBadRequest)
+	return "", false
+}
+
+// compareTraces implements the REST API /traces/compare?a={trace-id}&b={trace-id}.
+// It responds with the structural differences of trace b relative to trace a.
+func (aH *APIHandler) compareTraces(w http.ResponseWriter, r *http.Request) {
+	var traceIDs [2]model.TraceID
+	for i, param := range []string{traceIDAParam, traceIDBParam} {
+		value := r.FormValue(param)
+		if value == "" {
+			aH.handleError(w, fmt.Errorf("parameter '%s' is required", param), http.StatusBadRequest)
+			return
+		}
+		traceID, err := model.TraceIDFromString(value)
+		if aH.handleError(w, err, http.StatusBadRequest) {
+			return
+		}
+		traceIDs[i] = traceID
+	}
+	comparison, err := aH.queryService.CompareTraces(r.Context(), traceIDs[0], traceIDs[1])
+	if err == spanstore.ErrTraceNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+
+	structuredRes := structuredResponse{
+		Data: comparison,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
 func shouldAdjust(r *http.Request) bool {
 	raw := r.FormValue("raw")
 	isRaw, _ := strconv.ParseBool(raw)
 	return !isRaw
 }
 
+func shouldComputeCriticalPath(r *http.Request) bool {
+	criticalPath, _ := strconv.ParseBool(r.FormValue(criticalPathParam))
+	return criticalPath
+}
+
+func criticalPathToUI(sections []querysvc.CriticalPathSection) []ui.CriticalPathSection {
+	uiSections := make([]ui.CriticalPathSection, len(sections))
+	for i, section := range sections {
+		uiSections[i] = ui.CriticalPathSection{
+			SpanID:       ui.SpanID(section.SpanID.String()),
+			SectionStart: model.TimeAsEpochMicroseconds(section.Start),
+			SectionEnd:   model.TimeAsEpochMicroseconds(section.End),
+		}
+	}
+	return uiSections
+}
+
 // archiveTrace implements the REST API POST:/archive/{trace-id}.
 // It passes the traceID to queryService.ArchiveTrace for writing.
 func (aH *APIHandler) archiveTrace(w http.ResponseWriter, r *http.Request) {
@@ -490,6 +759,9 @@ func (aH *APIHandler) handleError(w http.ResponseWriter, err error, statusCode i
 	if errors.Is(err, disabled.ErrDisabled) {
 		statusCode = http.StatusNotImplemented
 	}
+	if errors.Is(err, spanstore.ErrUnsupportedTagFilter) || errors.Is(err, spanstore.ErrUnsupportedTagMatchMode) {
+		statusCode = http.StatusBadRequest
+	}
 	if statusCode == http.StatusInternalServerError {
 		aH.logger.Error("HTTP handler, Internal Server Error", zap.Error(err))
 	}
@@ -510,6 +782,12 @@ func (aH *APIHandler) writeJSON(w http.ResponseWriter, r *http.Request, response
 	prettyPrintValue := r.FormValue(prettyPrintParam)
 	prettyPrint := prettyPrintValue != "" && prettyPrintValue != "false"
 
+	if res, ok := response.(*structuredResponse); ok {
+		for _, failure := range spanstore.ReportedFailures(r.Context()) {
+			res.Errors = append(res.Errors, structuredError{Msg: failure.Error()})
+		}
+	}
+
 	var marshal jsonMarshaler
 	switch response.(type) {
 	case proto.Message:
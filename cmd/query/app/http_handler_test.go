@@ -25,12 +25,14 @@ import (
 	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/gogo/protobuf/jsonpb"
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	testHttp "github.com/stretchr/testify/http"
 	"github.com/stretchr/testify/mock"
@@ -45,6 +47,7 @@ import (
 	ui "github.com/kjschnei001/jaeger/model/json"
 	"github.com/kjschnei001/jaeger/pkg/tenancy"
 	"github.com/kjschnei001/jaeger/plugin/metrics/disabled"
+	"github.com/kjschnei001/jaeger/plugin/storage/memory"
 	"github.com/kjschnei001/jaeger/proto-gen/api_v2/metrics"
 	depsmocks "github.com/kjschnei001/jaeger/storage/dependencystore/mocks"
 	metricsmocks "github.com/kjschnei001/jaeger/storage/metricsstore/mocks"
@@ -81,6 +84,17 @@ var (
 	}
 )
 
+// mockTraceWithID can be returned by the mocked GetTrace to load mockTrace with the requested trace ID.
+func mockTraceWithID(ctx context.Context, traceID model.TraceID) *model.Trace {
+	trace := &model.Trace{Warnings: mockTrace.Warnings}
+	for _, span := range mockTrace.Spans {
+		s := *span
+		s.TraceID = traceID
+		trace.Spans = append(trace.Spans, &s)
+	}
+	return trace
+}
+
 // structuredTraceResponse is similar to structuredResponse but defines `data`
 // explicitly as []*ui.Trace, making it easier to parse & validate.
 type structuredTraceResponse struct {
@@ -272,9 +286,10 @@ func TestGetTrace(t *testing.T) {
 	testCases := []struct {
 		suffix      string
 		numSpanRefs int
+		raw         bool
 	}{
 		{suffix: "", numSpanRefs: 0},
-		{suffix: "?raw=true", numSpanRefs: 1}, // bad span reference is not filtered out
+		{suffix: "?raw=true", numSpanRefs: 1, raw: true}, // bad span reference is not filtered out
 		{suffix: "?raw=false", numSpanRefs: 0},
 	}
 
@@ -309,7 +324,9 @@ func TestGetTrace(t *testing.T) {
 			ts := initializeTestServer(HandlerOptions.Tracer(jaegerTracer))
 			defer ts.server.Close()
 
-			ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0x123456abc)).
+			// the storage returns the spans as they are stored for the raw traces
+			isRaw := mock.MatchedBy(func(ctx context.Context) bool { return spanstore.RawTraces(ctx) == testCase.raw })
+			ts.spanReader.On("GetTrace", isRaw, model.NewTraceID(0, 0x123456abc)).
 				Return(makeMockTrace(t), nil).Once()
 
 			var response structuredResponse
@@ -327,6 +344,48 @@ func TestGetTrace(t *testing.T) {
 	}
 }
 
+func TestGetTraceCriticalPath(t *testing.T) {
+	ts := initializeTestServer()
+	defer ts.server.Close()
+	start := time.Unix(1, 0)
+	ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mockTraceID).
+		Return(&model.Trace{
+			Spans: []*model.Span{
+				{
+					TraceID:   mockTraceID,
+					SpanID:    model.NewSpanID(1),
+					Process:   &model.Process{ServiceName: "service"},
+					StartTime: start,
+					Duration:  time.Second,
+				},
+				{
+					TraceID:    mockTraceID,
+					SpanID:     model.NewSpanID(2),
+					References: []model.SpanRef{model.NewChildOfRef(mockTraceID, model.NewSpanID(1))},
+					Process:    &model.Process{ServiceName: "service"},
+					StartTime:  start.Add(200 * time.Millisecond),
+					Duration:   300 * time.Millisecond,
+				},
+			},
+		}, nil).Twice()
+
+	var response structuredTraceResponse
+	err := getJSON(ts.server.URL+`/api/traces/`+mockTraceID.String()+`?criticalPath=true`, &response)
+	require.NoError(t, err)
+	require.Len(t, response.Traces, 1)
+	assert.Equal(t, []ui.CriticalPathSection{
+		{SpanID: "0000000000000001", SectionStart: 1000000, SectionEnd: 1200000},
+		{SpanID: "0000000000000002", SectionStart: 1200000, SectionEnd: 1500000},
+		{SpanID: "0000000000000001", SectionStart: 1500000, SectionEnd: 2000000},
+	}, response.Traces[0].CriticalPath)
+
+	var noCriticalPathResponse structuredTraceResponse
+	err = getJSON(ts.server.URL+`/api/traces/`+mockTraceID.String(), &noCriticalPathResponse)
+	require.NoError(t, err)
+	require.Len(t, noCriticalPathResponse.Traces, 1)
+	assert.Empty(t, noCriticalPathResponse.Traces[0].CriticalPath)
+}
+
 func TestGetTraceDBFailure(t *testing.T) {
 	ts := initializeTestServer()
 	defer ts.server.Close()
@@ -393,7 +452,7 @@ func TestSearchByTraceIDSuccess(t *testing.T) {
 	ts := initializeTestServer()
 	defer ts.server.Close()
 	ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
-		Return(mockTrace, nil).Twice()
+		Return(mockTraceWithID, nil).Twice()
 
 	var response structuredResponse
 	err := getJSON(ts.server.URL+`/api/traces?traceID=1&traceID=2`, &response)
@@ -411,7 +470,7 @@ func TestSearchByTraceIDSuccessWithArchive(t *testing.T) {
 	ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
 		Return(nil, spanstore.ErrTraceNotFound).Twice()
 	archiveReadMock.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
-		Return(mockTrace, nil).Twice()
+		Return(mockTraceWithID, nil).Twice()
 
 	var response structuredResponse
 	err := getJSON(ts.server.URL+`/api/traces?traceID=1&traceID=2`, &response)
@@ -433,6 +492,27 @@ func TestSearchByTraceIDNotFound(t *testing.T) {
 	assert.Equal(t, structuredError{Msg: "trace not found", TraceID: ui.TraceID("0000000000000001")}, response.Errors[0])
 }
 
+func TestSearchByTraceIDPrimaryAndArchive(t *testing.T) {
+	archiveReadMock := &spanstoremocks.Reader{}
+	ts := initializeTestServerWithOptions(&tenancy.Manager{}, querysvc.QueryServiceOptions{
+		ArchiveSpanReader: archiveReadMock,
+	})
+	defer ts.server.Close()
+	ts.spanReader.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(mockTraceWithID, nil).Once()
+	ts.spanReader.On("GetTrace", mock.Anything, mock.AnythingOfType("model.TraceID")).
+		Return(nil, spanstore.ErrTraceNotFound).Twice()
+	archiveReadMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 2)).Return(mockTraceWithID, nil).Once()
+	archiveReadMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 3)).Return(nil, spanstore.ErrTraceNotFound).Once()
+
+	var response structuredTraceResponse
+	err := getJSON(ts.server.URL+`/api/traces?traceID=1&traceID=2&traceID=3`, &response)
+	require.NoError(t, err)
+	require.Len(t, response.Traces, 2)
+	assert.Equal(t, ui.TraceID("0000000000000001"), response.Traces[0].TraceID)
+	assert.Equal(t, ui.TraceID("0000000000000002"), response.Traces[1].TraceID)
+	assert.Equal(t, []structuredError{{Msg: "trace not found", TraceID: ui.TraceID("0000000000000003")}}, response.Errors)
+}
+
 func TestSearchByTraceIDFailure(t *testing.T) {
 	ts := initializeTestServer()
 	defer ts.server.Close()
@@ -475,6 +555,283 @@ func TestSearchDBFailure(t *testing.T) {
 	assert.EqualError(t, err, parsedError(500, "whatsamattayou"))
 }
 
+func TestSearchUnsupportedByStorage(t *testing.T) {
+	for _, storageErr := range []error{
+		fmt.Errorf("%w: %s", spanstore.ErrUnsupportedTagFilter, "span.error != true"),
+		fmt.Errorf("%w: %s", spanstore.ErrUnsupportedTagMatchMode, spanstore.TagMatchAnySpanInTrace),
+	} {
+		ts := initializeTestServer()
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(nil, storageErr).Once()
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+`/api/traces?service=service&tagFilter=error!=true`, &response)
+		assert.EqualError(t, err, parsedError(400, storageErr.Error()))
+		ts.server.Close()
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	store := memory.NewStore()
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, store.WriteSpan(context.Background(), &model.Span{
+			TraceID:       model.NewTraceID(0, uint64(i)),
+			SpanID:        model.SpanID(i),
+			OperationName: "operation",
+			Process:       &model.Process{ServiceName: "service"},
+			StartTime:     time.Now().Add(time.Duration(-i) * time.Second),
+		}))
+	}
+	qs := querysvc.NewQueryService(store, &depsmocks.Reader{}, querysvc.QueryServiceOptions{})
+	r := NewRouter()
+	NewAPIHandler(qs, &tenancy.Manager{}, HandlerOptions.Logger(zap.NewNop())).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var traceIDs []string
+	pageToken := ""
+	for pages := 1; ; pages++ {
+		var response structuredResponse
+		err := getJSON(server.URL+`/api/traces?service=service&limit=2&pageToken=`+url.QueryEscape(pageToken), &response)
+		require.NoError(t, err)
+		for _, trace := range response.Data.([]interface{}) {
+			traceIDs = append(traceIDs, trace.(map[string]interface{})["traceID"].(string))
+		}
+		if response.NextPageToken == "" {
+			assert.Equal(t, 2, pages)
+			break
+		}
+		pageToken = response.NextPageToken
+	}
+	assert.Equal(t, []string{"0000000000000001", "0000000000000002", "0000000000000003"}, traceIDs)
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&pageToken=invalid`, &response)
+	assert.EqualError(t, err, parsedError(400, "invalid page token"))
+}
+
+func TestSearchPaginationNotSupported(t *testing.T) {
+	ts := initializeTestServer()
+	defer ts.server.Close()
+
+	var response structuredResponse
+	err := getJSON(ts.server.URL+`/api/traces?service=service&pageToken=token`, &response)
+	assert.EqualError(t, err, parsedError(400, "pagination is not supported"))
+}
+
+func TestCompareTraces(t *testing.T) {
+	store := memory.NewStore()
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, store.WriteSpan(context.Background(), &model.Span{
+			TraceID:       model.NewTraceID(0, uint64(i)),
+			SpanID:        model.SpanID(1),
+			OperationName: "operation",
+			Process:       &model.Process{ServiceName: "service"},
+			StartTime:     time.Now(),
+			Duration:      time.Duration(i) * time.Millisecond,
+		}))
+	}
+	qs := querysvc.NewQueryService(store, &depsmocks.Reader{}, querysvc.QueryServiceOptions{})
+	r := NewRouter()
+	NewAPIHandler(qs, &tenancy.Manager{}, HandlerOptions.Logger(zap.NewNop())).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces/compare?a=1&b=2`, &response)
+	require.NoError(t, err)
+	assert.Empty(t, response.Errors)
+	comparison := response.Data.(map[string]interface{})
+	assert.Equal(t, "0000000000000001", comparison["traceIDA"])
+	assert.Equal(t, "0000000000000002", comparison["traceIDB"])
+	roots := comparison["roots"].([]interface{})
+	require.Len(t, roots, 1)
+	root := roots[0].(map[string]interface{})
+	assert.Equal(t, "service", root["serviceName"])
+	assert.Equal(t, "both", root["status"])
+	assert.EqualValues(t, 1000, root["durationDelta"])
+
+	tests := []struct {
+		urlStr string
+		errMsg string
+	}{
+		{`/api/traces/compare?a=1`, parsedError(400, "parameter 'b' is required")},
+		{`/api/traces/compare?a=xyz&b=2`, parsedError(400, `strconv.ParseUint: parsing \"xyz\": invalid syntax`)},
+		{`/api/traces/compare?a=1&b=3`, parsedError(404, "trace not found")},
+	}
+	for _, test := range tests {
+		err := getJSON(server.URL+test.urlStr, &response)
+		assert.EqualError(t, err, test.errMsg)
+	}
+}
+
+func TestAggregateTraces(t *testing.T) {
+	store := memory.NewStore()
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, store.WriteSpan(context.Background(), &model.Span{
+			TraceID:       model.NewTraceID(0, uint64(i)),
+			SpanID:        model.SpanID(1),
+			OperationName: "operation",
+			Process:       &model.Process{ServiceName: "service"},
+			StartTime:     time.Now().Add(-time.Minute),
+			Duration:      time.Duration(i) * time.Millisecond,
+		}))
+	}
+	qs := querysvc.NewQueryService(store, &depsmocks.Reader{}, querysvc.QueryServiceOptions{})
+	r := NewRouter()
+	NewAPIHandler(qs, &tenancy.Manager{}, HandlerOptions.Logger(zap.NewNop())).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	tests := []struct {
+		urlStr     string
+		traceCount float64
+		errors     int
+	}{
+		{urlStr: `/api/traces/aggregate?service=service`, traceCount: 2},
+		{urlStr: `/api/traces/aggregate?traceID=1&traceID=3`, traceCount: 1, errors: 1},
+	}
+	for _, test := range tests {
+		var response structuredResponse
+		err := getJSON(server.URL+test.urlStr, &response)
+		require.NoError(t, err)
+		assert.Len(t, response.Errors, test.errors)
+		aggregation := response.Data.(map[string]interface{})
+		assert.Equal(t, test.traceCount, aggregation["traceCount"])
+		operations := aggregation["operations"].([]interface{})
+		require.Len(t, operations, 1)
+		assert.Equal(t, "operation", operations[0].(map[string]interface{})["operationName"])
+	}
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces/aggregate`, &response)
+	assert.EqualError(t, err, parsedError(400, "parameter 'service' is required"))
+}
+
+func TestExportImportTrace(t *testing.T) {
+	source := memory.NewStore()
+	for _, span := range formatsTestTrace().Spans {
+		require.NoError(t, source.WriteSpan(context.Background(), span))
+	}
+	exportServer := httptest.NewServer(newTestImportRouter(source, nil))
+	defer exportServer.Close()
+
+	for _, format := range []string{otlpJSONFormat, otlpProtoFormat, jaegerJSONFormat, zipkinV2Format} {
+		t.Run(format, func(t *testing.T) {
+			resp, err := http.Get(exportServer.URL + "/api/traces/" + formatsTestTraceID.String() + "/export?format=" + format)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			exported, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			target := memory.NewStore()
+			importServer := httptest.NewServer(newTestImportRouter(target, target))
+			defer importServer.Close()
+			importResp, err := http.Post(importServer.URL+"/api/traces/import?format="+format, resp.Header.Get("Content-Type"), bytes.NewReader(exported))
+			require.NoError(t, err)
+			defer importResp.Body.Close()
+			require.Equal(t, http.StatusOK, importResp.StatusCode)
+			var response structuredResponse
+			require.NoError(t, json.NewDecoder(importResp.Body).Decode(&response))
+			assert.Equal(t, []interface{}{formatsTestTraceID.String()}, response.Data)
+
+			trace, err := target.GetTrace(context.Background(), formatsTestTraceID)
+			require.NoError(t, err)
+			assert.Len(t, trace.Spans, 2)
+		})
+	}
+
+	tests := []struct {
+		urlStr string
+		err    string
+	}{
+		{urlStr: `/api/traces/1/export`, err: parsedError(400, "parameter 'format' is required")},
+		{urlStr: `/api/traces/1/export?format=xml`, err: parsedError(400, "unsupported value 'xml' for parameter 'format', expected one of otlp-json, otlp-proto, jaeger-json, zipkin-v2")},
+		{urlStr: `/api/traces/x/export?format=otlp-json`, err: parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`)},
+		{urlStr: `/api/traces/3/export?format=otlp-json`, err: parsedError(404, "trace not found")},
+	}
+	for _, test := range tests {
+		var response structuredResponse
+		err := getJSON(exportServer.URL+test.urlStr, &response)
+		assert.EqualError(t, err, test.err)
+	}
+
+	var response structuredResponse
+	err := postJSON(exportServer.URL+`/api/traces/import?format=jaeger-json`, jaegerJSONTraces{}, &response)
+	assert.EqualError(t, err, parsedError(501, "import span storage was not configured"))
+
+	importServer := httptest.NewServer(newTestImportRouter(source, source, HandlerOptions.ImportMaxBytes(16)))
+	defer importServer.Close()
+	err = postJSON(importServer.URL+`/api/traces/import?format=zipkin-v2`, map[string]string{}, &response)
+	assert.EqualError(t, err, parsedError(400, "json: cannot unmarshal object into Go value of type models.ListOfSpans"))
+	err = postJSON(importServer.URL+`/api/traces/import?format=zipkin-v2`, []map[string]string{{"traceId": "1234567890abcdef"}}, &response)
+	assert.EqualError(t, err, parsedError(413, "failed reading request body: http: request body too large"))
+}
+
+func newTestImportRouter(store *memory.Store, importWriter spanstore.Writer, options ...HandlerOption) *mux.Router {
+	qs := querysvc.NewQueryService(store, &depsmocks.Reader{}, querysvc.QueryServiceOptions{ImportSpanWriter: importWriter})
+	r := NewRouter()
+	NewAPIHandler(qs, &tenancy.Manager{}, append([]HandlerOption{HandlerOptions.Logger(zap.NewNop())}, options...)...).RegisterRoutes(r)
+	return r
+}
+
+func TestSearchSummaries(t *testing.T) {
+	store := memory.NewStore()
+	start := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	for i := 1; i <= 2; i++ {
+		require.NoError(t, store.WriteSpan(context.Background(), &model.Span{
+			TraceID:       model.NewTraceID(0, 1),
+			SpanID:        model.SpanID(i),
+			References:    []model.SpanRef{model.NewChildOfRef(model.NewTraceID(0, 1), model.SpanID(i-1))},
+			OperationName: fmt.Sprintf("operation-%d", i),
+			Process:       &model.Process{ServiceName: "service"},
+			StartTime:     start.Add(time.Duration(i) * time.Millisecond),
+			Duration:      time.Millisecond,
+			Tags:          model.KeyValues{model.Bool("error", i == 2)},
+		}))
+	}
+	qs := querysvc.NewQueryService(store, &depsmocks.Reader{}, querysvc.QueryServiceOptions{})
+	r := NewRouter()
+	NewAPIHandler(qs, &tenancy.Manager{}, HandlerOptions.Logger(zap.NewNop())).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	expected := []*ui.TraceSummary{{
+		TraceID:           "0000000000000001",
+		RootServiceName:   "service",
+		RootOperationName: "operation-1",
+		StartTime:         model.TimeAsEpochMicroseconds(start.Add(time.Millisecond)),
+		Duration:          2000,
+		SpanCount:         2,
+		ServiceSpanCounts: map[string]int{"service": 2},
+		ErrorCount:        1,
+	}}
+	tests := []struct {
+		urlStr string
+		errors int
+	}{
+		{urlStr: `/api/traces?service=service&view=summary`},
+		{urlStr: `/api/traces?traceID=1&traceID=2&view=summary`, errors: 1},
+	}
+	for _, test := range tests {
+		var response struct {
+			Summaries []*ui.TraceSummary `json:"data"`
+			Errors    []structuredError  `json:"errors"`
+		}
+		err := getJSON(server.URL+test.urlStr, &response)
+		require.NoError(t, err)
+		assert.Equal(t, expected, response.Summaries)
+		assert.Len(t, response.Errors, test.errors)
+	}
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&view=summary&pageToken=token`, &response)
+	assert.EqualError(t, err, parsedError(400, "pagination is not supported"))
+	err = getJSON(server.URL+`/api/traces?service=service&view=spans`, &response)
+	assert.EqualError(t, err, parsedError(400, "unsupported value 'spans' for parameter 'view'"))
+}
+
 func TestSearchFailures(t *testing.T) {
 	tests := []struct {
 		urlStr string
@@ -521,6 +878,31 @@ func TestGetServicesSuccess(t *testing.T) {
 	assert.Equal(t, expectedServices, actualServices)
 }
 
+func TestStoragePartialFailures(t *testing.T) {
+	ts := initializeTestServer()
+	defer ts.server.Close()
+	reportFailure := func(args mock.Arguments) {
+		spanstore.ReportFailure(args.Get(0).(context.Context), errors.New("the cassandra storage failed: unavailable"))
+	}
+	ts.spanReader.On("GetServices", mock.AnythingOfType("*context.valueCtx")).
+		Run(reportFailure).Return([]string{"trifle"}, nil).Once()
+	ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Run(reportFailure).Return(nil, nil).Once()
+
+	var response structuredResponse
+	err := getJSON(ts.server.URL+"/api/services", &response)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"trifle"}, response.Data)
+	assert.Equal(t, []structuredError{{Msg: "the cassandra storage failed: unavailable"}}, response.Errors)
+
+	// the failures are returned when nothing was found
+	response = structuredResponse{}
+	err = getJSON(ts.server.URL+`/api/traces?service=service&start=0&end=0`, &response)
+	require.NoError(t, err)
+	assert.Empty(t, response.Data)
+	assert.Equal(t, []structuredError{{Msg: "the cassandra storage failed: unavailable"}}, response.Errors)
+}
+
 func TestGetServicesStorageFailure(t *testing.T) {
 	ts := initializeTestServer()
 	defer ts.server.Close()
@@ -890,7 +1272,7 @@ func TestSearchTenancyHTTP(t *testing.T) {
 		querysvc.QueryServiceOptions{})
 	defer ts.server.Close()
 	ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
-		Return(mockTrace, nil).Twice()
+		Return(mockTraceWithID, nil).Twice()
 
 	var response structuredResponse
 	err := getJSON(ts.server.URL+`/api/traces?traceID=1&traceID=2`, &response)
@@ -949,7 +1331,7 @@ func TestSearchTenancyFlowTenantHTTP(t *testing.T) {
 			return false
 		}
 		return true
-	}), mock.AnythingOfType("model.TraceID")).Return(mockTrace, nil).Twice()
+	}), mock.AnythingOfType("model.TraceID")).Return(mockTraceWithID, nil).Twice()
 	ts.spanReader.On("GetTrace", mock.MatchedBy(func(v interface{}) bool {
 		ctx, ok := v.(context.Context)
 		if !ok || tenancy.GetTenant(ctx) != "megacorp" {
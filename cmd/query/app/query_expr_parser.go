@@ -0,0 +1,287 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const (
+	spanFieldPrefix = "span."
+
+	// maxExprDepth is the maximum nesting depth of parentheses and negations in an expression.
+	maxExprDepth = 32
+)
+
+type exprTokenKind int
+
+const (
+	tokenEOF exprTokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type exprToken struct {
+	kind  exprTokenKind
+	text  string
+	start int
+}
+
+var exprOperators = map[string]spanstore.CompareOperator{
+	"=":  spanstore.OpEqual,
+	"==": spanstore.OpEqual,
+	"!=": spanstore.OpNotEqual,
+	">":  spanstore.OpGreater,
+	">=": spanstore.OpGreaterOrEqual,
+	"<":  spanstore.OpLess,
+	"<=": spanstore.OpLessOrEqual,
+	"=~": spanstore.OpRegex,
+	"!~": spanstore.OpNotRegex,
+	"^=": spanstore.OpPrefix,
+}
+
+// parseQueryExpression parses a span query expression into an AST.
+//
+// Expression syntax:
+//
+//	expr ::= and | and '||' expr
+//	and ::= unary | unary '&&' and
+//	unary ::= '!' unary | '(' expr ')' | predicate
+//	predicate ::= field op value
+//	field ::= 'service' | 'operation' | 'duration' | 'span.' tagKey
+//	op ::= '=' | '==' | '!=' | '>' | '>=' | '<' | '<=' | '=~' | '!~' | '^='
+//	value ::= quotedString | number | durationValue (e.g. 300ms) | bareWord
+func parseQueryExpression(input string) (spanstore.Expr, error) {
+	tokens, err := tokenizeQueryExpression(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.start)
+	}
+	return expr, nil
+}
+
+func tokenizeQueryExpression(input string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, exprToken{kind: tokenLParen, text: "(", start: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, exprToken{kind: tokenRParen, text: ")", start: i})
+			i++
+		case strings.HasPrefix(input[i:], "&&"):
+			tokens = append(tokens, exprToken{kind: tokenAnd, text: "&&", start: i})
+			i += 2
+		case strings.HasPrefix(input[i:], "||"):
+			tokens = append(tokens, exprToken{kind: tokenOr, text: "||", start: i})
+			i += 2
+		case c == '"':
+			s, err := strconv.QuotedPrefix(input[i:])
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			unquoted, _ := strconv.Unquote(s)
+			tokens = append(tokens, exprToken{kind: tokenString, text: unquoted, start: i})
+			i += len(s)
+		case strings.ContainsRune("=!<>^", rune(c)):
+			op := input[i : i+1]
+			if i+1 < len(input) {
+				if _, ok := exprOperators[input[i:i+2]]; ok {
+					op = input[i : i+2]
+				}
+			}
+			if op == "!" {
+				tokens = append(tokens, exprToken{kind: tokenNot, text: op, start: i})
+			} else {
+				tokens = append(tokens, exprToken{kind: tokenOperator, text: op, start: i})
+			}
+			i += len(op)
+		default:
+			start := i
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if !isWordChar(r) {
+					break
+				}
+				i += size
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			word := input[start:i]
+			kind := tokenIdent
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				kind = tokenNumber
+			}
+			tokens = append(tokens, exprToken{kind: kind, text: word, start: start})
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, start: len(input)}), nil
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-:/@µ", r)
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	depth  int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) parseOr() (spanstore.Expr, error) {
+	var operands []spanstore.Expr
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, expr)
+		if p.peek().kind != tokenOr {
+			break
+		}
+		p.next()
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &spanstore.OrExpr{Operands: operands}, nil
+}
+
+func (p *exprParser) parseAnd() (spanstore.Expr, error) {
+	var operands []spanstore.Expr
+	for {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, expr)
+		if p.peek().kind != tokenAnd {
+			break
+		}
+		p.next()
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &spanstore.AndExpr{Operands: operands}, nil
+}
+
+func (p *exprParser) parseUnary() (spanstore.Expr, error) {
+	tok := p.peek()
+	if tok.kind == tokenNot || tok.kind == tokenLParen {
+		if p.depth == maxExprDepth {
+			return nil, fmt.Errorf("expression nested deeper than %d levels at position %d", maxExprDepth, tok.start)
+		}
+		p.depth++
+		defer func() { p.depth-- }()
+	}
+	switch tok.kind {
+	case tokenNot:
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &spanstore.NotExpr{Operand: expr}, nil
+	case tokenLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expecting ')' at position %d", closing.start)
+		}
+		return expr, nil
+	default:
+		return p.parsePredicate()
+	}
+}
+
+func (p *exprParser) parsePredicate() (spanstore.Expr, error) {
+	fieldTok := p.next()
+	if fieldTok.kind != tokenIdent {
+		return nil, fmt.Errorf("expecting field name at position %d", fieldTok.start)
+	}
+	var field spanstore.FieldKind
+	var key string
+	switch {
+	case fieldTok.text == "service":
+		field = spanstore.ServiceField
+	case fieldTok.text == "operation":
+		field = spanstore.OperationField
+	case fieldTok.text == "duration":
+		field = spanstore.DurationField
+	case strings.HasPrefix(fieldTok.text, spanFieldPrefix):
+		field = spanstore.TagField
+		key = strings.TrimPrefix(fieldTok.text, spanFieldPrefix)
+	default:
+		return nil, fmt.Errorf("unknown field %q at position %d", fieldTok.text, fieldTok.start)
+	}
+
+	opTok := p.next()
+	op, ok := exprOperators[opTok.text]
+	if opTok.kind != tokenOperator || !ok {
+		return nil, fmt.Errorf("expecting comparison operator at position %d", opTok.start)
+	}
+
+	valueTok := p.next()
+	if valueTok.kind != tokenString && valueTok.kind != tokenNumber && valueTok.kind != tokenIdent {
+		return nil, fmt.Errorf("expecting value at position %d", valueTok.start)
+	}
+	predicate, err := spanstore.NewPredicate(field, key, op, valueTok.text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid predicate at position %d: %w", fieldTok.start, err)
+	}
+	return predicate, nil
+}
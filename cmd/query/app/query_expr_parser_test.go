@@ -0,0 +1,119 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+func TestParseQueryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`service="checkout"`, `service = "checkout"`},
+		{`service == checkout`, `service = "checkout"`},
+		{
+			`service="checkout" && span.http.status_code >= 500 && duration > 300ms`,
+			`(service = "checkout") && (span.http.status_code >= "500") && (duration > 300ms)`,
+		},
+		{
+			`operation=~"^GET" || !(span.error = true)`,
+			`(operation =~ "^GET") || (!(span.error = "true"))`,
+		},
+		{
+			`(service="a" || service="b") && duration<=1.5s`,
+			`((service = "a") || (service = "b")) && (duration <= 1.5s)`,
+		},
+		{`duration > 300µs`, `duration > 300µs`},
+		{`span.db.statement !~ "SELECT \"x\""`, `span.db.statement !~ "SELECT \"x\""`},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, err := parseQueryExpression(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, expr.String())
+		})
+	}
+}
+
+func TestParseQueryExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{``, `expecting field name at position 0`},
+		{`service`, `expecting comparison operator at position 7`},
+		{`service =`, `expecting value at position 9`},
+		{`host = "x"`, `unknown field "host" at position 0`},
+		{`service = "x" service = "y"`, `unexpected "service" at position 14`},
+		{`(service = "x"`, `expecting ')' at position 14`},
+		{`service = "x`, `unterminated string at position 10`},
+		{`service = x $`, `unexpected character '$' at position 12`},
+		{`service > x`, `invalid predicate at position 0: operator > is not supported for service`},
+		{`duration > 3`, `invalid predicate at position 0: invalid duration "3": time: missing unit in duration "3"`},
+		{strings.Repeat("!(", 17) + `service = "x"` + strings.Repeat(")", 17), `expression nested deeper than 32 levels at position 32`},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			_, err := parseQueryExpression(test.input)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestParseQueryExpressionMaxDepth(t *testing.T) {
+	expr, err := parseQueryExpression(strings.Repeat("(", maxExprDepth) + `service = "x"` + strings.Repeat(")", maxExprDepth))
+	require.NoError(t, err)
+	assert.Equal(t, `service = "x"`, expr.String())
+}
+
+func TestParseTraceQueryExpressionParam(t *testing.T) {
+	parser := &queryParser{timeNow: time.Now}
+
+	request, err := http.NewRequest(http.MethodGet, `x?query=service%3D%22checkout%22+%26%26+duration+%3E+300ms`, nil)
+	require.NoError(t, err)
+	query, err := parser.parseTraceQueryParams(request)
+	require.NoError(t, err)
+	require.NotNil(t, query.Expression)
+	assert.Equal(t, `(service = "checkout") && (duration > 300ms)`, query.Expression.String())
+	assert.Len(t, spanstore.Conjuncts(query.Expression), 2)
+
+	// the storage backends require a service name
+	for _, expr := range []string{`duration+%3E+300ms`, `service+!%3D+%22checkout%22`, `service%3D%22a%22+||+service%3D%22b%22`} {
+		request, err = http.NewRequest(http.MethodGet, `x?query=`+expr, nil)
+		require.NoError(t, err)
+		_, err = parser.parseTraceQueryParams(request)
+		assert.Equal(t, errServiceParameterRequired, err, expr)
+	}
+
+	request, err = http.NewRequest(http.MethodGet, `x?service=checkout&query=duration+%3E+300ms`, nil)
+	require.NoError(t, err)
+	_, err = parser.parseTraceQueryParams(request)
+	require.NoError(t, err)
+
+	request, err = http.NewRequest(http.MethodGet, `x?service=checkout&query=duration`, nil)
+	require.NoError(t, err)
+	_, err = parser.parseTraceQueryParams(request)
+	assert.EqualError(t, err, `unable to parse param 'query': expecting comparison operator at position 8`)
+}
@@ -36,6 +36,8 @@ const (
 	operationParam   = "operation"
 	tagParam         = "tag"
 	tagsParam        = "tags"
+	tagFilterParam   = "tagFilter"
+	tagMatchParam    = "tagMatch"
 	startTimeParam   = "start"
 	limitParam       = "limit"
 	minDurationParam = "minDuration"
@@ -44,6 +46,8 @@ const (
 	spanKindParam    = "spanKind"
 	endTimeParam     = "end"
 	prettyPrintParam = "prettyPrint"
+	queryExprParam   = "query"
+	pageTokenParam   = "pageToken"
 )
 
 var (
@@ -112,7 +116,7 @@ func newDurationUnitsParser(units time.Duration) durationParser {
 // Trace query syntax:
 //
 //	query ::= param | param '&' query
-//	param ::= service | operation | limit | start | end | minDuration | maxDuration | tag | tags
+//	param ::= service | operation | limit | start | end | minDuration | maxDuration | tag | tags | tagFilter | tagMatch | expr
 //	service ::= 'service=' strValue
 //	operation ::= 'operation=' strValue
 //	limit ::= 'limit=' intValue
@@ -124,6 +128,11 @@ func newDurationUnitsParser(units time.Duration) durationParser {
 //	key := strValue
 //	keyValue := strValue ':' strValue
 //	tags :== 'tags=' jsonMap
+//	tagFilter ::= 'tagFilter=' strValue tagOperator strValue
+//	tagOperator ::= '=' | '==' | '!=' | '>' | '>=' | '<' | '<=' | '~' | '=~' | '!~' | '^='
+//	tagMatch ::= 'tagMatch=' ('SameSpan' | 'AnySpanInTrace'), defaults to 'SameSpan'
+//	expr ::= 'query=' strValue (see parseQueryExpression)
+//	pageToken ::= 'pageToken=' strValue, the nextPageToken of the previous page
 func (p *queryParser) parseTraceQueryParams(r *http.Request) (*traceQueryParameters, error) {
 	service := r.FormValue(serviceParam)
 	operation := r.FormValue(operationParam)
@@ -142,6 +151,16 @@ func (p *queryParser) parseTraceQueryParams(r *http.Request) (*traceQueryParamet
 		return nil, err
 	}
 
+	tagFilters, err := p.parseTagFilters(r.Form[tagFilterParam])
+	if err != nil {
+		return nil, err
+	}
+
+	tagMatchMode, err := spanstore.ParseTagMatchMode(r.FormValue(tagMatchParam))
+	if err != nil {
+		return nil, newParseError(err, tagMatchParam)
+	}
+
 	limitParam := r.FormValue(limitParam)
 	limit := defaultQueryLimit
 	if limitParam != "" {
@@ -163,6 +182,14 @@ func (p *queryParser) parseTraceQueryParams(r *http.Request) (*traceQueryParamet
 		return nil, err
 	}
 
+	var expression spanstore.Expr
+	if exprParam := r.FormValue(queryExprParam); exprParam != "" {
+		expression, err = parseQueryExpression(exprParam)
+		if err != nil {
+			return nil, newParseError(err, queryExprParam)
+		}
+	}
+
 	var traceIDs []model.TraceID
 	for _, id := range r.Form[traceIDParam] {
 		if traceID, err := model.TraceIDFromString(id); err == nil {
@@ -179,9 +206,13 @@ func (p *queryParser) parseTraceQueryParams(r *http.Request) (*traceQueryParamet
 			StartTimeMin:  startTime,
 			StartTimeMax:  endTime,
 			Tags:          tags,
+			TagFilters:    tagFilters,
+			TagMatchMode:  tagMatchMode,
 			NumTraces:     limit,
 			DurationMin:   minDuration,
 			DurationMax:   maxDuration,
+			Expression:    expression,
+			PageToken:     r.FormValue(pageTokenParam),
 		},
 		traceIDs: traceIDs,
 	}
@@ -370,7 +401,7 @@ func mapSpanKindsToOpenTelemetry(spanKinds []string) ([]string, error) {
 }
 
 func (p *queryParser) validateQuery(traceQuery *traceQueryParameters) error {
-	if len(traceQuery.traceIDs) == 0 && traceQuery.ServiceName == "" {
+	if len(traceQuery.traceIDs) == 0 && traceQuery.ServiceName == "" && expressionService(traceQuery.Expression) == "" {
 		return errServiceParameterRequired
 	}
 	if traceQuery.DurationMin != 0 && traceQuery.DurationMax != 0 {
@@ -381,6 +412,20 @@ func (p *queryParser) validateQuery(traceQuery *traceQueryParameters) error {
 	return nil
 }
 
+// expressionService returns the service that a top-level conjunct of the expression
+// restricts the search to, since the storage backends require a service name.
+func expressionService(expr spanstore.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	for _, conjunct := range spanstore.Conjuncts(expr) {
+		if p, ok := conjunct.(*spanstore.Predicate); ok && p.Field == spanstore.ServiceField && p.Operator == spanstore.OpEqual {
+			return p.Value
+		}
+	}
+	return ""
+}
+
 func (p *queryParser) parseTags(simpleTags []string, jsonTags []string) (map[string]string, error) {
 	retMe := make(map[string]string)
 	for _, tag := range simpleTags {
@@ -403,6 +448,20 @@ func (p *queryParser) parseTags(simpleTags []string, jsonTags []string) (map[str
 	return retMe, nil
 }
 
+// parseTagFilters parses tag filters such as `http.status_code>=500`, `db.statement~"SELECT.*users"`
+// or `error!=false`. The value may be enclosed in double quotes.
+func (p *queryParser) parseTagFilters(filters []string) ([]*spanstore.Predicate, error) {
+	var retMe []*spanstore.Predicate
+	for _, filter := range filters {
+		tagFilter, err := spanstore.ParseTagFilter(filter)
+		if err != nil {
+			return nil, newParseError(err, tagFilterParam)
+		}
+		retMe = append(retMe, tagFilter)
+	}
+	return retMe, nil
+}
+
 func newParseError(err error, paramName string) error {
 	return fmt.Errorf("unable to parse param '%s': %w", paramName, err)
 }
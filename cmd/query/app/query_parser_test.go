@@ -323,3 +323,47 @@ func TestParameterErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTraceQueryTagFilters(t *testing.T) {
+	parser := &queryParser{timeNow: time.Now}
+
+	request, err := http.NewRequest(http.MethodGet, `x?service=service&tagFilter=http.status_code%3E%3D500&tagFilter=db.statement~%22SELECT.*%22&tagFilter=error!%3Dfalse`, nil)
+	require.NoError(t, err)
+	query, err := parser.parseTraceQueryParams(request)
+	require.NoError(t, err)
+	require.Len(t, query.TagFilters, 3)
+	assert.Equal(t, `span.http.status_code >= "500"`, query.TagFilters[0].String())
+	assert.Equal(t, `span.db.statement =~ "SELECT.*"`, query.TagFilters[1].String())
+	assert.Equal(t, `span.error != "false"`, query.TagFilters[2].String())
+
+	request, err = http.NewRequest(http.MethodGet, `x?service=service&tagFilter=http.status_code%3Eabc`, nil)
+	require.NoError(t, err)
+	_, err = parser.parseTraceQueryParams(request)
+	assert.EqualError(t, err, `unable to parse param 'tagFilter': operator > requires a numeric value for span.http.status_code, got "abc"`)
+}
+
+func TestParseTraceQueryTagMatch(t *testing.T) {
+	parser := &queryParser{timeNow: time.Now}
+
+	request, err := http.NewRequest(http.MethodGet, `x?service=service&tag=a:1&tag=b:2&tagMatch=AnySpanInTrace`, nil)
+	require.NoError(t, err)
+	query, err := parser.parseTraceQueryParams(request)
+	require.NoError(t, err)
+	assert.Equal(t, spanstore.TagMatchAnySpanInTrace, query.TagMatchMode)
+
+	request, err = http.NewRequest(http.MethodGet, `x?service=service&tagMatch=sometimes`, nil)
+	require.NoError(t, err)
+	_, err = parser.parseTraceQueryParams(request)
+	assert.EqualError(t, err, `unable to parse param 'tagMatch': unknown tag match mode "sometimes", expecting SameSpan or AnySpanInTrace`)
+}
+
+func TestParseTraceQueryPageToken(t *testing.T) {
+	parser := &queryParser{timeNow: time.Now}
+
+	request, err := http.NewRequest(http.MethodGet, `x?service=service&limit=20&pageToken=abc_-1`, nil)
+	require.NoError(t, err)
+	query, err := parser.parseTraceQueryParams(request)
+	require.NoError(t, err)
+	assert.Equal(t, "abc_-1", query.PageToken)
+	assert.Equal(t, 20, query.NumTraces)
+}
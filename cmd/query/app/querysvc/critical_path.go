@@ -0,0 +1,155 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// CriticalPathSection is the time interval in which a span was on the critical path.
+type CriticalPathSection struct {
+	SpanID model.SpanID
+	Start  time.Time
+	End    time.Time
+}
+
+// criticalPathSpan is a span with its interval clipped to the interval of its parent.
+type criticalPathSpan struct {
+	span     *model.Span
+	parent   *criticalPathSpan
+	start    time.Time
+	end      time.Time
+	children []*criticalPathSpan
+	// next is the index of the first child in children, sorted by end time descending,
+	// that can still be on the critical path.
+	next int
+}
+
+// CriticalPath returns the critical path of the trace in chronological order, i.e. the
+// chain of spans that determined the latency of the root span. A span is on the critical
+// path when it is not waiting for one of its children, walking back from the end of the
+// root span to the last finishing child before each point in time.
+//
+// Only CHILD_OF children block their parent, FOLLOWS_FROM children and their descendants
+// are asynchronous and never on the critical path. Children are clipped to the interval
+// of their parent. The root is the earliest span, the longest on ties, whose parent is
+// not in the trace.
+func CriticalPath(trace *model.Trace) []CriticalPathSection {
+	root := buildCriticalPathTree(trace)
+	if root == nil {
+		return nil
+	}
+	var sections []CriticalPathSection
+	current, bound, returning := root, root.end, false
+	for {
+		if child := current.lastFinishingChild(bound, returning); child != nil {
+			if child.end.Before(bound) {
+				sections = append(sections, CriticalPathSection{SpanID: current.span.SpanID, Start: child.end, End: bound})
+			}
+			current, bound, returning = child, child.end, false
+			continue
+		}
+		sections = append(sections, CriticalPathSection{SpanID: current.span.SpanID, Start: current.start, End: bound})
+		if current.parent == nil {
+			break
+		}
+		current, bound, returning = current.parent, current.start, true
+	}
+	for i, j := 0, len(sections)-1; i < j; i, j = i+1, j-1 {
+		sections[i], sections[j] = sections[j], sections[i]
+	}
+	return sections
+}
+
+// lastFinishingChild returns the child that finished last before the bound, or at the
+// bound if the walk is not returning from a child of s.
+func (s *criticalPathSpan) lastFinishingChild(bound time.Time, returning bool) *criticalPathSpan {
+	// bounds only decrease while walking back, so skipped children are never needed again
+	for ; s.next < len(s.children); s.next++ {
+		end := s.children[s.next].end
+		if end.Before(bound) || (!returning && end.Equal(bound)) {
+			return s.children[s.next]
+		}
+	}
+	return nil
+}
+
+func buildCriticalPathTree(trace *model.Trace) *criticalPathSpan {
+	spans := make(map[model.SpanID]*model.Span, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spans[span.SpanID] = span
+	}
+	var root *model.Span
+	children := make(map[model.SpanID][]*model.Span)
+	for _, span := range trace.Spans {
+		parentID := span.ParentSpanID()
+		if _, ok := spans[parentID]; !ok || parentID == span.SpanID {
+			if root == nil || span.StartTime.Before(root.StartTime) ||
+				(span.StartTime.Equal(root.StartTime) && span.Duration > root.Duration) {
+				root = span
+			}
+			continue
+		}
+		if isChildOf(span, parentID) {
+			children[parentID] = append(children[parentID], span)
+		}
+	}
+	if root == nil {
+		return nil
+	}
+	rootNode := &criticalPathSpan{span: root, start: root.StartTime, end: root.StartTime.Add(root.Duration)}
+	visited := map[model.SpanID]struct{}{root.SpanID: {}}
+	queue := []*criticalPathSpan{rootNode}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, child := range children[node.span.SpanID] {
+			if _, ok := visited[child.SpanID]; ok {
+				continue
+			}
+			visited[child.SpanID] = struct{}{}
+			start, end := child.StartTime, child.StartTime.Add(child.Duration)
+			if start.After(node.end) || end.Before(node.start) {
+				continue
+			}
+			if start.Before(node.start) {
+				start = node.start
+			}
+			if end.After(node.end) {
+				end = node.end
+			}
+			childNode := &criticalPathSpan{span: child, parent: node, start: start, end: end}
+			node.children = append(node.children, childNode)
+			queue = append(queue, childNode)
+		}
+		sort.SliceStable(node.children, func(i, j int) bool {
+			return node.children[i].end.After(node.children[j].end)
+		})
+	}
+	return rootNode
+}
+
+// isChildOf tells whether the span has a CHILD_OF reference to parentID, rather than only FOLLOWS_FROM.
+func isChildOf(span *model.Span, parentID model.SpanID) bool {
+	for _, ref := range span.References {
+		if ref.TraceID == span.TraceID && ref.SpanID == parentID && ref.RefType == model.ChildOf {
+			return true
+		}
+	}
+	return false
+}
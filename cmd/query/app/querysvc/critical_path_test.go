@@ -0,0 +1,129 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+var criticalPathTraceID = model.NewTraceID(0, 1)
+
+// criticalPathTestSpan returns a span starting and ending at the given milliseconds.
+func criticalPathTestSpan(spanID, parentID uint64, refType model.SpanRefType, start, end int) *model.Span {
+	span := &model.Span{
+		TraceID:   criticalPathTraceID,
+		SpanID:    model.NewSpanID(spanID),
+		StartTime: time.Unix(0, 0).Add(time.Duration(start) * time.Millisecond),
+		Duration:  time.Duration(end-start) * time.Millisecond,
+	}
+	if parentID != 0 {
+		span.References = []model.SpanRef{{TraceID: criticalPathTraceID, SpanID: model.NewSpanID(parentID), RefType: refType}}
+	}
+	return span
+}
+
+func criticalPathTestSection(spanID uint64, start, end int) CriticalPathSection {
+	return CriticalPathSection{
+		SpanID: model.NewSpanID(spanID),
+		Start:  time.Unix(0, 0).Add(time.Duration(start) * time.Millisecond),
+		End:    time.Unix(0, 0).Add(time.Duration(end) * time.Millisecond),
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spans    []*model.Span
+		expected []CriticalPathSection
+	}{
+		{
+			name: "sequential children",
+			spans: []*model.Span{
+				criticalPathTestSpan(1, 0, model.ChildOf, 0, 100),
+				criticalPathTestSpan(2, 1, model.ChildOf, 10, 40),
+				criticalPathTestSpan(3, 1, model.ChildOf, 50, 90),
+			},
+			expected: []CriticalPathSection{
+				criticalPathTestSection(1, 0, 10),
+				criticalPathTestSection(2, 10, 40),
+				criticalPathTestSection(1, 40, 50),
+				criticalPathTestSection(3, 50, 90),
+				criticalPathTestSection(1, 90, 100),
+			},
+		},
+		{
+			name: "parallel children and grandchildren",
+			spans: []*model.Span{
+				criticalPathTestSpan(1, 0, model.ChildOf, 0, 100),
+				criticalPathTestSpan(2, 1, model.ChildOf, 10, 80),
+				criticalPathTestSpan(3, 1, model.ChildOf, 20, 50),
+				criticalPathTestSpan(4, 2, model.ChildOf, 30, 70),
+			},
+			expected: []CriticalPathSection{
+				criticalPathTestSection(1, 0, 10),
+				criticalPathTestSection(2, 10, 30),
+				criticalPathTestSection(4, 30, 70),
+				criticalPathTestSection(2, 70, 80),
+				criticalPathTestSection(1, 80, 100),
+			},
+		},
+		{
+			name: "follows from children are asynchronous",
+			spans: []*model.Span{
+				criticalPathTestSpan(1, 0, model.ChildOf, 0, 100),
+				criticalPathTestSpan(2, 1, model.FollowsFrom, 50, 200),
+				criticalPathTestSpan(3, 2, model.ChildOf, 60, 90),
+			},
+			expected: []CriticalPathSection{
+				criticalPathTestSection(1, 0, 100),
+			},
+		},
+		{
+			name: "children are clipped to the parent",
+			spans: []*model.Span{
+				criticalPathTestSpan(1, 0, model.ChildOf, 0, 100),
+				criticalPathTestSpan(2, 1, model.ChildOf, 90, 150),
+				criticalPathTestSpan(3, 1, model.ChildOf, 120, 130),
+			},
+			expected: []CriticalPathSection{
+				criticalPathTestSection(1, 0, 90),
+				criticalPathTestSection(2, 90, 100),
+			},
+		},
+		{
+			name: "earliest span without parent in the trace is the root",
+			spans: []*model.Span{
+				criticalPathTestSpan(2, 9, model.ChildOf, 10, 20),
+				criticalPathTestSpan(1, 0, model.ChildOf, 0, 30),
+			},
+			expected: []CriticalPathSection{
+				criticalPathTestSection(1, 0, 30),
+			},
+		},
+		{
+			name: "empty trace",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, CriticalPath(&model.Trace{Spans: testCase.spans}))
+		})
+	}
+}
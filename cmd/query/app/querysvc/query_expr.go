@@ -0,0 +1,174 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const (
+	// postFilterFetchFactor is how many times more traces than requested are fetched
+	// from storage when part of the query expression is evaluated in-process, so that
+	// post-filtering does not shrink the result far below the requested limit.
+	postFilterFetchFactor = 4
+
+	// maxPostFilterPages is the maximum number of pages fetched from storage to find
+	// the requested number of traces matching the part of the expression evaluated in-process.
+	maxPostFilterPages = 10
+)
+
+// errPostFilterTruncated is reported as a partial failure when fewer traces than requested
+// matched the query expression and the storage may hold more traces to filter.
+var errPostFilterTruncated = errors.New("the query expression was only evaluated on part of the traces found by the storage, some matching traces may be missing")
+
+// planQueryExpression splits query.Expression between the storage reader and the
+// query service. It returns the query to send to the reader and, if some conjuncts
+// could not be pushed down, the expression to apply to the returned traces.
+//
+// Top-level conjuncts that map onto the legacy query fields (service, operation,
+// duration bounds, exact tag match) are always copied into those fields to narrow
+// the storage search. Since legacy fields may be matched by different spans of a
+// trace, the whole expression is re-evaluated in-process unless the reader declared
+// that it evaluates every conjunct natively.
+func planQueryExpression(reader spanstore.Reader, query *spanstore.TraceQueryParameters) (*spanstore.TraceQueryParameters, spanstore.Expr) {
+	storageQuery := *query
+	storageQuery.Expression = nil
+	if query.Expression == nil {
+		return &storageQuery, nil
+	}
+	storageQuery.Tags = make(map[string]string, len(query.Tags))
+	for k, v := range query.Tags {
+		storageQuery.Tags[k] = v
+	}
+
+	pushdown, _ := reader.(spanstore.ExpressionPushdown)
+	var pushed []spanstore.Expr
+	allPushed := true
+	for _, conjunct := range spanstore.Conjuncts(query.Expression) {
+		lowerToLegacyFields(&storageQuery, conjunct)
+		if pushdown != nil && pushdown.CanPushDown(conjunct) {
+			pushed = append(pushed, conjunct)
+		} else {
+			allPushed = false
+		}
+	}
+	switch len(pushed) {
+	case 0:
+	case 1:
+		storageQuery.Expression = pushed[0]
+	default:
+		storageQuery.Expression = &spanstore.AndExpr{Operands: pushed}
+	}
+	if allPushed {
+		return &storageQuery, nil
+	}
+	storageQuery.NumTraces *= postFilterFetchFactor
+	return &storageQuery, query.Expression
+}
+
+// lowerToLegacyFields narrows the legacy fields of the query using the conjunct,
+// if it can be expressed through them. Fields that are already set are never relaxed.
+func lowerToLegacyFields(query *spanstore.TraceQueryParameters, conjunct spanstore.Expr) {
+	p, ok := conjunct.(*spanstore.Predicate)
+	if !ok {
+		return
+	}
+	switch p.Field {
+	case spanstore.ServiceField:
+		if p.Operator == spanstore.OpEqual && query.ServiceName == "" {
+			query.ServiceName = p.Value
+		}
+	case spanstore.OperationField:
+		if p.Operator == spanstore.OpEqual && query.OperationName == "" {
+			query.OperationName = p.Value
+		}
+	case spanstore.DurationField:
+		switch p.Operator {
+		case spanstore.OpGreater, spanstore.OpGreaterOrEqual:
+			if p.Duration > query.DurationMin {
+				query.DurationMin = p.Duration
+			}
+		case spanstore.OpLess, spanstore.OpLessOrEqual:
+			if query.DurationMax == 0 || p.Duration < query.DurationMax {
+				query.DurationMax = p.Duration
+			}
+		case spanstore.OpEqual:
+			if query.DurationMin == 0 && query.DurationMax == 0 {
+				query.DurationMin, query.DurationMax = p.Duration, p.Duration
+			}
+		}
+	case spanstore.TagField:
+		// Numeric literals are not lowered, since backends compare the stored
+		// string representation and e.g. 500 would not match "500.0".
+		if _, err := strconv.ParseFloat(p.Value, 64); err == nil || p.Operator != spanstore.OpEqual {
+			return
+		}
+		if _, exists := query.Tags[p.Key]; !exists {
+			query.Tags[p.Key] = p.Value
+		}
+	}
+}
+
+// filterTraces returns up to limit traces that match the expression, or all of them if limit is not positive.
+func filterTraces(traces []*model.Trace, expr spanstore.Expr, limit int) []*model.Trace {
+	var retMe []*model.Trace
+	for _, trace := range traces {
+		if limit > 0 && len(retMe) == limit {
+			break
+		}
+		if spanstore.MatchTrace(expr, trace) {
+			retMe = append(retMe, trace)
+		}
+	}
+	return retMe
+}
+
+// findFilteredTraces returns up to limit traces found by the storage query that match the
+// expression, fetching further pages while fewer traces matched. When the span reader does
+// not support pagination, or the page limit is reached, matching traces may be missed:
+// errPostFilterTruncated is then reported with spanstore.ReportFailure.
+func findFilteredTraces(ctx context.Context, reader spanstore.Reader, storageQuery *spanstore.TraceQueryParameters, expr spanstore.Expr, limit int) ([]*model.Trace, error) {
+	pageQuery := *storageQuery
+	pageQuery.PageToken = ""
+	var retMe []*model.Trace
+	for i := 0; i < maxPostFilterPages; i++ {
+		page, err := spanstore.FindTracesPage(ctx, reader, &pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		if limit > 0 {
+			retMe = append(retMe, filterTraces(page.Traces, expr, limit-len(retMe))...)
+			if len(retMe) == limit {
+				return retMe, nil
+			}
+		} else {
+			retMe = append(retMe, filterTraces(page.Traces, expr, 0)...)
+		}
+		if page.NextPageToken == "" {
+			if page.Truncated {
+				spanstore.ReportFailure(ctx, errPostFilterTruncated)
+			}
+			return retMe, nil
+		}
+		pageQuery.PageToken = page.NextPageToken
+	}
+	spanstore.ReportFailure(ctx, errPostFilterTruncated)
+	return retMe, nil
+}
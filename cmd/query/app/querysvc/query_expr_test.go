@@ -0,0 +1,244 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+	spanstoremocks "github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+type pushdownReader struct {
+	spanstoremocks.Reader
+	canPushDown func(spanstore.Expr) bool
+}
+
+func (r *pushdownReader) CanPushDown(expr spanstore.Expr) bool {
+	return r.canPushDown(expr)
+}
+
+func newPredicate(t *testing.T, field spanstore.FieldKind, key string, op spanstore.CompareOperator, value string) *spanstore.Predicate {
+	p, err := spanstore.NewPredicate(field, key, op, value)
+	require.NoError(t, err)
+	return p
+}
+
+func TestPlanQueryExpressionLowering(t *testing.T) {
+	expr := &spanstore.AndExpr{Operands: []spanstore.Expr{
+		newPredicate(t, spanstore.ServiceField, "", spanstore.OpEqual, "checkout"),
+		newPredicate(t, spanstore.OperationField, "", spanstore.OpEqual, "GET"),
+		newPredicate(t, spanstore.DurationField, "", spanstore.OpGreater, "300ms"),
+		newPredicate(t, spanstore.DurationField, "", spanstore.OpLess, "2s"),
+		newPredicate(t, spanstore.TagField, "http.method", spanstore.OpEqual, "POST"),
+		newPredicate(t, spanstore.TagField, "http.status_code", spanstore.OpEqual, "500"),
+		newPredicate(t, spanstore.TagField, "error", spanstore.OpNotEqual, "false"),
+		newPredicate(t, spanstore.TagField, "existing", spanstore.OpEqual, "other"),
+	}}
+	query := &spanstore.TraceQueryParameters{
+		Tags:       map[string]string{"existing": "value"},
+		NumTraces:  10,
+		Expression: expr,
+	}
+
+	storageQuery, postFilter := planQueryExpression(&spanstoremocks.Reader{}, query)
+	assert.Equal(t, &spanstore.TraceQueryParameters{
+		ServiceName:   "checkout",
+		OperationName: "GET",
+		DurationMin:   300 * time.Millisecond,
+		DurationMax:   2 * time.Second,
+		Tags:          map[string]string{"existing": "value", "http.method": "POST"},
+		NumTraces:     10 * postFilterFetchFactor,
+	}, storageQuery)
+	assert.Equal(t, expr, postFilter)
+	assert.Equal(t, map[string]string{"existing": "value"}, query.Tags, "original query must not be modified")
+}
+
+func TestPlanQueryExpressionPushdown(t *testing.T) {
+	service := newPredicate(t, spanstore.ServiceField, "", spanstore.OpEqual, "checkout")
+	regex := newPredicate(t, spanstore.TagField, "db.statement", spanstore.OpRegex, "SELECT")
+	query := &spanstore.TraceQueryParameters{
+		NumTraces:  10,
+		Expression: &spanstore.AndExpr{Operands: []spanstore.Expr{service, regex}},
+	}
+
+	all := &pushdownReader{canPushDown: func(spanstore.Expr) bool { return true }}
+	storageQuery, postFilter := planQueryExpression(all, query)
+	assert.Nil(t, postFilter)
+	assert.Equal(t, query.Expression, storageQuery.Expression)
+	assert.Equal(t, "checkout", storageQuery.ServiceName)
+	assert.Equal(t, 10, storageQuery.NumTraces)
+
+	partial := &pushdownReader{canPushDown: func(e spanstore.Expr) bool { return e == service }}
+	storageQuery, postFilter = planQueryExpression(partial, query)
+	assert.Equal(t, query.Expression, postFilter)
+	assert.Equal(t, service, storageQuery.Expression)
+	assert.Equal(t, 10*postFilterFetchFactor, storageQuery.NumTraces)
+}
+
+func TestFindTracesWithExpression(t *testing.T) {
+	tqs := initializeTestService()
+	matching := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}, Duration: time.Second},
+	}}
+	// service and duration are satisfied by different spans
+	crossSpan := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}},
+		{Process: &model.Process{ServiceName: "payment"}, Duration: time.Second},
+	}}
+	tqs.spanReader.On("FindTraces", mock.Anything, mock.MatchedBy(func(q *spanstore.TraceQueryParameters) bool {
+		return q.ServiceName == "checkout" && q.DurationMin == 300*time.Millisecond && q.Expression == nil && q.NumTraces == 4
+	})).Return([]*model.Trace{crossSpan, matching, matching}, nil).Once()
+
+	traces, err := tqs.queryService.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		NumTraces: 1,
+		Expression: &spanstore.AndExpr{Operands: []spanstore.Expr{
+			newPredicate(t, spanstore.ServiceField, "", spanstore.OpEqual, "checkout"),
+			newPredicate(t, spanstore.DurationField, "", spanstore.OpGreaterOrEqual, "300ms"),
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{matching}, traces)
+}
+
+type pagedReader struct {
+	spanstoremocks.Reader
+	pages map[string]*spanstore.TracePage
+}
+
+func (r *pagedReader) FindTracesPage(_ context.Context, query *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	return r.pages[query.PageToken], nil
+}
+
+func TestFindTracesWithExpressionPages(t *testing.T) {
+	matching := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}, Duration: time.Second},
+	}}
+	short := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}},
+	}}
+	query := &spanstore.TraceQueryParameters{
+		NumTraces: 2,
+		Expression: &spanstore.AndExpr{Operands: []spanstore.Expr{
+			newPredicate(t, spanstore.ServiceField, "", spanstore.OpEqual, "checkout"),
+			newPredicate(t, spanstore.DurationField, "", spanstore.OpGreaterOrEqual, "300ms"),
+		}},
+	}
+
+	reader := &pagedReader{pages: map[string]*spanstore.TracePage{
+		"":     {Traces: []*model.Trace{short, matching, short}, NextPageToken: "next"},
+		"next": {Traces: []*model.Trace{short, matching, matching}, NextPageToken: "last"},
+	}}
+	ctx := spanstore.WithFailures(context.Background())
+	traces, err := NewQueryService(reader, nil, QueryServiceOptions{}).FindTraces(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{matching, matching}, traces)
+	assert.Empty(t, spanstore.ReportedFailures(ctx))
+
+	// the reader returned all the traces it found
+	reader.pages["next"].NextPageToken = ""
+	query.NumTraces = 3
+	traces, err = NewQueryService(reader, nil, QueryServiceOptions{}).FindTraces(ctx, query)
+	require.NoError(t, err)
+	assert.Len(t, traces, 3)
+	assert.Empty(t, spanstore.ReportedFailures(ctx))
+}
+
+func TestFindTracesWithExpressionTruncated(t *testing.T) {
+	tqs := initializeTestService()
+	short := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}},
+	}}
+	tqs.spanReader.On("FindTraces", mock.Anything, mock.Anything).
+		Return([]*model.Trace{short, short, short, short}, nil).Once()
+
+	ctx := spanstore.WithFailures(context.Background())
+	traces, err := tqs.queryService.FindTraces(ctx, &spanstore.TraceQueryParameters{
+		NumTraces: 1,
+		Expression: &spanstore.AndExpr{Operands: []spanstore.Expr{
+			newPredicate(t, spanstore.ServiceField, "", spanstore.OpEqual, "checkout"),
+			newPredicate(t, spanstore.DurationField, "", spanstore.OpGreaterOrEqual, "300ms"),
+		}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+	assert.Equal(t, []error{errPostFilterTruncated}, spanstore.ReportedFailures(ctx))
+}
+
+func TestFindTracesPageWithExpression(t *testing.T) {
+	tqs := initializeTestService()
+	matching := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}, Duration: time.Second},
+	}}
+	short := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}},
+	}}
+	// the page size is not increased for post-filtering, so the next page starts after the filtered traces
+	tqs.spanReader.On("FindTraces", mock.Anything, mock.MatchedBy(func(q *spanstore.TraceQueryParameters) bool {
+		return q.ServiceName == "checkout" && q.Expression == nil && q.NumTraces == 2
+	})).Return([]*model.Trace{short, matching}, nil).Once()
+
+	query := &spanstore.TraceQueryParameters{
+		NumTraces: 2,
+		Expression: &spanstore.AndExpr{Operands: []spanstore.Expr{
+			newPredicate(t, spanstore.ServiceField, "", spanstore.OpEqual, "checkout"),
+			newPredicate(t, spanstore.DurationField, "", spanstore.OpGreaterOrEqual, "300ms"),
+		}},
+	}
+	page, err := tqs.queryService.FindTracesPage(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{matching}, page.Traces)
+	assert.Empty(t, page.NextPageToken)
+
+	query.PageToken = "token"
+	_, err = tqs.queryService.FindTracesPage(context.Background(), query)
+	assert.ErrorIs(t, err, spanstore.ErrPaginationNotSupported)
+}
+
+func TestFindTraceSummariesWithExpression(t *testing.T) {
+	tqs := initializeTestService()
+	matching := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}, Duration: time.Second},
+	}}
+	short := &model.Trace{Spans: []*model.Span{
+		{Process: &model.Process{ServiceName: "checkout"}},
+	}}
+	// the duration predicate cannot be evaluated by the reader, so summaries are computed from the filtered traces
+	tqs.spanReader.On("FindTraces", mock.Anything, mock.MatchedBy(func(q *spanstore.TraceQueryParameters) bool {
+		return q.ServiceName == "checkout" && q.Expression == nil
+	})).Return([]*model.Trace{short, matching}, nil).Once()
+
+	query := &spanstore.TraceQueryParameters{
+		NumTraces: 2,
+		Expression: &spanstore.AndExpr{Operands: []spanstore.Expr{
+			newPredicate(t, spanstore.ServiceField, "", spanstore.OpEqual, "checkout"),
+			newPredicate(t, spanstore.DurationField, "", spanstore.OpGreaterOrEqual, "300ms"),
+		}},
+	}
+	summaries, err := tqs.queryService.FindTraceSummaries(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, spanstore.SummarizeTraces([]*model.Trace{matching}), summaries)
+
+	query.PageToken = "token"
+	_, err = tqs.queryService.FindTraceSummaries(context.Background(), query)
+	assert.ErrorIs(t, err, spanstore.ErrPaginationNotSupported)
+}
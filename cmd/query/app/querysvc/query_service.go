@@ -28,7 +28,12 @@ import (
 	"github.com/kjschnei001/jaeger/storage/spanstore"
 )
 
-var errNoArchiveSpanStorage = errors.New("archive span storage was not configured")
+var (
+	errNoArchiveSpanStorage = errors.New("archive span storage was not configured")
+
+	// ErrNoImportSpanStorage is returned by ImportSpans when trace import is not enabled.
+	ErrNoImportSpanStorage = errors.New("import span storage was not configured")
+)
 
 const (
 	defaultMaxClockSkewAdjust = time.Second
@@ -38,7 +43,9 @@ const (
 type QueryServiceOptions struct {
 	ArchiveSpanReader spanstore.Reader
 	ArchiveSpanWriter spanstore.Writer
-	Adjuster          adjuster.Adjuster
+	// ImportSpanWriter is the archive or primary span writer the imported spans are written to.
+	ImportSpanWriter spanstore.Writer
+	Adjuster         adjuster.Adjuster
 }
 
 // QueryService contains span utils required by the query-service.
@@ -74,6 +81,43 @@ func (qs QueryService) GetTrace(ctx context.Context, traceID model.TraceID) (*mo
 	return trace, err
 }
 
+// GetTraces loads the traces with the given IDs, in their order. The traces not found in
+// the primary storage are loaded from the archive storage, if it is configured, and the
+// traces found in neither are omitted.
+func (qs QueryService) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	traces, err := spanstore.GetTraces(ctx, qs.spanReader, traceIDs)
+	if err != nil || qs.options.ArchiveSpanReader == nil || len(traces) == len(traceIDs) {
+		return traces, err
+	}
+	byID := make(map[model.TraceID]*model.Trace, len(traceIDs))
+	for _, trace := range traces {
+		byID[spanstore.TraceIDOf(trace)] = trace
+	}
+	var missingIDs []model.TraceID
+	for _, traceID := range traceIDs {
+		if _, ok := byID[traceID]; !ok {
+			missingIDs = append(missingIDs, traceID)
+		}
+	}
+	if len(missingIDs) == 0 {
+		return traces, nil
+	}
+	archived, err := spanstore.GetTraces(ctx, qs.options.ArchiveSpanReader, missingIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, trace := range archived {
+		byID[spanstore.TraceIDOf(trace)] = trace
+	}
+	merged := make([]*model.Trace, 0, len(byID))
+	for _, traceID := range spanstore.UniqueTraceIDs(traceIDs) {
+		if trace, ok := byID[traceID]; ok {
+			merged = append(merged, trace)
+		}
+	}
+	return merged, nil
+}
+
 // GetServices is the queryService implementation of spanstore.Reader.GetServices
 func (qs QueryService) GetServices(ctx context.Context) ([]string, error) {
 	return qs.spanReader.GetServices(ctx)
@@ -87,9 +131,57 @@ func (qs QueryService) GetOperations(
 	return qs.spanReader.GetOperations(ctx, query)
 }
 
-// FindTraces is the queryService implementation of spanstore.Reader.FindTraces
+// FindTraces is the queryService implementation of spanstore.Reader.FindTraces.
+// The parts of query.Expression the span reader cannot evaluate are applied to
+// the returned traces in-process, see findFilteredTraces.
 func (qs QueryService) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
-	return qs.spanReader.FindTraces(ctx, query)
+	if query.Expression == nil {
+		return qs.spanReader.FindTraces(ctx, query)
+	}
+	storageQuery, postFilter := planQueryExpression(qs.spanReader, query)
+	if postFilter == nil {
+		return qs.spanReader.FindTraces(ctx, storageQuery)
+	}
+	return findFilteredTraces(ctx, qs.spanReader, storageQuery, postFilter, query.NumTraces)
+}
+
+// FindTracesPage is the queryService implementation of spanstore.PagedReader.FindTracesPage.
+// When part of the query expression is evaluated in-process, pages may have fewer
+// traces than requested, since the page cannot extend past the traces received.
+func (qs QueryService) FindTracesPage(ctx context.Context, query *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	if query.Expression == nil {
+		return spanstore.FindTracesPage(ctx, qs.spanReader, query)
+	}
+	storageQuery, postFilter := planQueryExpression(qs.spanReader, query)
+	if postFilter != nil {
+		// fetching more traces would move the next page token past unfiltered traces
+		storageQuery.NumTraces = query.NumTraces
+	}
+	page, err := spanstore.FindTracesPage(ctx, qs.spanReader, storageQuery)
+	if err != nil || postFilter == nil {
+		return page, err
+	}
+	page.Traces = filterTraces(page.Traces, postFilter, 0)
+	return page, nil
+}
+
+// FindTraceSummaries returns the summaries of the traces FindTraces would return, computed
+// from the stored spans without adjustments. The span reader computes the summaries when it
+// implements spanstore.SummaryReader and can evaluate the whole query. Summaries are not
+// paginated, ErrPaginationNotSupported is returned for queries with a page token.
+func (qs QueryService) FindTraceSummaries(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*spanstore.TraceSummary, error) {
+	if query.PageToken != "" {
+		return nil, spanstore.ErrPaginationNotSupported
+	}
+	storageQuery, postFilter := planQueryExpression(qs.spanReader, query)
+	if postFilter == nil {
+		return spanstore.FindTraceSummaries(ctx, qs.spanReader, storageQuery)
+	}
+	traces, err := qs.FindTraces(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.SummarizeTraces(traces), nil
 }
 
 // ArchiveTrace is the queryService utility to archive traces.
@@ -112,6 +204,26 @@ func (qs QueryService) ArchiveTrace(ctx context.Context, traceID model.TraceID)
 	return errors.Join(writeErrors...)
 }
 
+// ImportEnabled returns whether an import span storage is configured.
+func (qs QueryService) ImportEnabled() bool {
+	return qs.options.ImportSpanWriter != nil
+}
+
+// ImportSpans writes spans converted from an exported trace to the import span storage.
+func (qs QueryService) ImportSpans(ctx context.Context, spans []*model.Span) error {
+	if qs.options.ImportSpanWriter == nil {
+		return ErrNoImportSpanStorage
+	}
+	var writeErrors []error
+	for _, span := range spans {
+		err := qs.options.ImportSpanWriter.WriteSpan(ctx, span)
+		if err != nil {
+			writeErrors = append(writeErrors, err)
+		}
+	}
+	return errors.Join(writeErrors...)
+}
+
 // Adjust applies adjusters to the trace.
 func (qs QueryService) Adjust(trace *model.Trace) (*model.Trace, error) {
 	return qs.options.Adjuster.Adjust(trace)
@@ -22,6 +22,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/kjschnei001/jaeger/model"
@@ -153,6 +154,48 @@ func TestGetTraceFromArchiveStorage(t *testing.T) {
 	assert.Equal(t, res, mockTrace)
 }
 
+// Test QueryService.GetTraces() with ArchiveSpanReader
+func TestGetTraces(t *testing.T) {
+	archivedTraceID := model.NewTraceID(0, 2)
+	archivedTrace := &model.Trace{Spans: []*model.Span{{TraceID: archivedTraceID}}}
+	missingTraceID := model.NewTraceID(0, 3)
+	traceIDs := []model.TraceID{archivedTraceID, mockTraceID, missingTraceID}
+
+	tqs := initializeTestService(withArchiveSpanReader())
+	tqs.spanReader.On("GetTrace", mock.Anything, mockTraceID).Return(mockTrace, nil).Once()
+	tqs.spanReader.On("GetTrace", mock.Anything, mock.AnythingOfType("model.TraceID")).
+		Return(nil, spanstore.ErrTraceNotFound).Twice()
+	tqs.archiveSpanReader.On("GetTrace", mock.Anything, archivedTraceID).Return(archivedTrace, nil).Once()
+	tqs.archiveSpanReader.On("GetTrace", mock.Anything, missingTraceID).
+		Return(nil, spanstore.ErrTraceNotFound).Once()
+
+	traces, err := tqs.queryService.GetTraces(context.Background(), traceIDs)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{archivedTrace, mockTrace}, traces)
+}
+
+// Test QueryService.GetTraces() without ArchiveSpanReader
+func TestGetTracesNoArchive(t *testing.T) {
+	tqs := initializeTestService()
+	tqs.spanReader.On("GetTrace", mock.Anything, mockTraceID).Return(mockTrace, nil).Once()
+	tqs.spanReader.On("GetTrace", mock.Anything, mock.AnythingOfType("model.TraceID")).
+		Return(nil, spanstore.ErrTraceNotFound).Once()
+
+	traces, err := tqs.queryService.GetTraces(context.Background(), []model.TraceID{mockTraceID, model.NewTraceID(0, 2)})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{mockTrace}, traces)
+}
+
+// Test QueryService.GetTraces() for errors of the archive storage.
+func TestGetTracesArchiveError(t *testing.T) {
+	tqs := initializeTestService(withArchiveSpanReader())
+	tqs.spanReader.On("GetTrace", mock.Anything, mockTraceID).Return(nil, spanstore.ErrTraceNotFound).Once()
+	tqs.archiveSpanReader.On("GetTrace", mock.Anything, mockTraceID).Return(nil, errors.New("storage error")).Once()
+
+	_, err := tqs.queryService.GetTraces(context.Background(), []model.TraceID{mockTraceID})
+	assert.EqualError(t, err, "storage error")
+}
+
 // Test QueryService.GetServices() for success.
 func TestGetServices(t *testing.T) {
 	tqs := initializeTestService()
@@ -205,6 +248,16 @@ func TestFindTraces(t *testing.T) {
 	assert.Len(t, traces, 1)
 }
 
+func TestFindTraceSummaries(t *testing.T) {
+	tqs := initializeTestService()
+	tqs.spanReader.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return([]*model.Trace{mockTrace}, nil).Once()
+
+	summaries, err := tqs.queryService.FindTraceSummaries(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "service"})
+	assert.NoError(t, err)
+	assert.Equal(t, spanstore.SummarizeTraces([]*model.Trace{mockTrace}), summaries)
+}
+
 // Test QueryService.ArchiveTrace() with no ArchiveSpanWriter.
 func TestArchiveTraceNoOptions(t *testing.T) {
 	tqs := initializeTestService()
@@ -258,6 +311,29 @@ func TestArchiveTraceSuccess(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// Test QueryService.ImportSpans() without ImportSpanWriter.
+func TestImportSpansNoOptions(t *testing.T) {
+	tqs := initializeTestService()
+	err := tqs.queryService.ImportSpans(context.Background(), mockTrace.Spans)
+	assert.Equal(t, ErrNoImportSpanStorage, err)
+	assert.False(t, tqs.queryService.ImportEnabled())
+}
+
+// Test QueryService.ImportSpans() with ImportSpanWriter.
+func TestImportSpans(t *testing.T) {
+	writer := &spanstoremocks.Writer{}
+	tqs := initializeTestService(func(_ *testQueryService, options *QueryServiceOptions) {
+		options.ImportSpanWriter = writer
+	})
+	assert.True(t, tqs.queryService.ImportEnabled())
+	writer.On("WriteSpan", mock.Anything, mockTrace.Spans[0]).Return(nil).Once()
+	writer.On("WriteSpan", mock.Anything, mockTrace.Spans[1]).Return(errors.New("cannot save")).Once()
+
+	err := tqs.queryService.ImportSpans(context.Background(), mockTrace.Spans)
+	assert.EqualError(t, err, "cannot save")
+	writer.AssertExpectations(t)
+}
+
 // Test QueryService.Adjust()
 func TestTraceAdjustmentFailure(t *testing.T) {
 	tqs := initializeTestService(withAdjuster())
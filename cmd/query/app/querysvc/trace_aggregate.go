@@ -0,0 +1,188 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// TraceAggregation holds the statistics of the spans of a set of traces, grouped by service and operation.
+type TraceAggregation struct {
+	TraceCount int              `json:"traceCount"`
+	Operations []OperationStats `json:"operations"`
+	// Warnings are the errors returned by the adjusters, the statistics use the adjusted traces regardless.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// OperationStats are the statistics of the spans of a service and operation.
+type OperationStats struct {
+	ServiceName   string        `json:"serviceName"`
+	OperationName string        `json:"operationName"`
+	SpanCount     int           `json:"spanCount"`
+	ErrorCount    int           `json:"errorCount"`
+	Duration      DurationStats `json:"duration"`
+	// SelfTime is the part of the span duration not covered by any of its children.
+	SelfTime DurationStats `json:"selfTime"`
+}
+
+// DurationStats summarizes a set of durations, in microseconds. Percentiles use the nearest-rank method.
+type DurationStats struct {
+	Total uint64 `json:"total"`
+	Min   uint64 `json:"min"`
+	Max   uint64 `json:"max"`
+	P50   uint64 `json:"p50"`
+	P95   uint64 `json:"p95"`
+	P99   uint64 `json:"p99"`
+}
+
+// AggregateTraces finds the traces matching the query, adjusts them and returns the statistics of their spans.
+func (qs QueryService) AggregateTraces(ctx context.Context, query *spanstore.TraceQueryParameters) (*TraceAggregation, error) {
+	traces, err := qs.FindTraces(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	for i, trace := range traces {
+		traces[i], err = qs.Adjust(trace)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	aggregation := NewTraceAggregation(traces)
+	aggregation.Warnings = warnings
+	return aggregation, nil
+}
+
+type operationSpans struct {
+	stats     OperationStats
+	durations []time.Duration
+	selfTimes []time.Duration
+}
+
+// NewTraceAggregation returns the statistics of the spans of the traces, sorted by service and operation.
+func NewTraceAggregation(traces []*model.Trace) *TraceAggregation {
+	operations := make(map[pathKey]*operationSpans)
+	for _, trace := range traces {
+		selfTimes := spanSelfTimes(trace)
+		for _, span := range trace.Spans {
+			key := pathKey{operation: span.OperationName}
+			if span.Process != nil {
+				key.service = span.Process.ServiceName
+			}
+			op, ok := operations[key]
+			if !ok {
+				op = &operationSpans{stats: OperationStats{ServiceName: key.service, OperationName: key.operation}}
+				operations[key] = op
+			}
+			op.stats.SpanCount++
+			if span.IsError() {
+				op.stats.ErrorCount++
+			}
+			op.durations = append(op.durations, span.Duration)
+			op.selfTimes = append(op.selfTimes, selfTimes[span])
+		}
+	}
+	aggregation := &TraceAggregation{
+		TraceCount: len(traces),
+		Operations: make([]OperationStats, 0, len(operations)),
+	}
+	for _, op := range operations {
+		op.stats.Duration = newDurationStats(op.durations)
+		op.stats.SelfTime = newDurationStats(op.selfTimes)
+		aggregation.Operations = append(aggregation.Operations, op.stats)
+	}
+	sort.Slice(aggregation.Operations, func(i, j int) bool {
+		a, b := aggregation.Operations[i], aggregation.Operations[j]
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.OperationName < b.OperationName
+	})
+	return aggregation
+}
+
+// spanSelfTimes returns the duration of each span minus the union of the intervals of its children.
+func spanSelfTimes(trace *model.Trace) map[*model.Span]time.Duration {
+	spans := make(map[model.SpanID]*model.Span, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spans[span.SpanID] = span
+	}
+	children := make(map[*model.Span][]*model.Span)
+	for _, span := range trace.Spans {
+		if parent, ok := spans[span.ParentSpanID()]; ok && parent != span {
+			children[parent] = append(children[parent], span)
+		}
+	}
+	selfTimes := make(map[*model.Span]time.Duration, len(trace.Spans))
+	for _, span := range trace.Spans {
+		selfTimes[span] = span.Duration - childrenOverlap(span, children[span])
+	}
+	return selfTimes
+}
+
+// childrenOverlap returns how long at least one of the children ran during the span.
+func childrenOverlap(span *model.Span, children []*model.Span) time.Duration {
+	spanEnd := span.StartTime.Add(span.Duration)
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].StartTime.Before(children[j].StartTime)
+	})
+	var overlap time.Duration
+	covered := span.StartTime
+	for _, child := range children {
+		start, end := child.StartTime, child.StartTime.Add(child.Duration)
+		if start.Before(covered) {
+			start = covered
+		}
+		if end.After(spanEnd) {
+			end = spanEnd
+		}
+		if end.After(start) {
+			overlap += end.Sub(start)
+			covered = end
+		}
+	}
+	return overlap
+}
+
+func newDurationStats(durations []time.Duration) DurationStats {
+	if len(durations) == 0 {
+		return DurationStats{}
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	percentile := func(p int) uint64 {
+		// nearest rank is ceil(p/100 * n)
+		rank := (p*len(durations) + 99) / 100
+		return model.DurationAsMicroseconds(durations[rank-1])
+	}
+	return DurationStats{
+		Total: model.DurationAsMicroseconds(total),
+		Min:   model.DurationAsMicroseconds(durations[0]),
+		Max:   model.DurationAsMicroseconds(durations[len(durations)-1]),
+		P50:   percentile(50),
+		P95:   percentile(95),
+		P99:   percentile(99),
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+func TestNewTraceAggregation(t *testing.T) {
+	traceIDA, traceIDB := model.NewTraceID(0, 1), model.NewTraceID(0, 2)
+	traces := []*model.Trace{
+		{Spans: []*model.Span{
+			compareTestSpan(traceIDA, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+			// overlapping children cover 10ms-50ms of the parent
+			compareTestSpan(traceIDA, 2, 1, "backend", "query", 10*time.Millisecond, 30*time.Millisecond),
+			compareTestSpan(traceIDA, 3, 1, "backend", "query", 20*time.Millisecond, 30*time.Millisecond, model.Bool("error", true)),
+		}},
+		{Spans: []*model.Span{
+			// the child overflows the parent by 10ms
+			compareTestSpan(traceIDB, 1, 0, "frontend", "GET /", 0, 50*time.Millisecond, model.String("error", "true")),
+			compareTestSpan(traceIDB, 2, 1, "backend", "query", 40*time.Millisecond, 20*time.Millisecond, model.Bool("error", false)),
+		}},
+	}
+
+	aggregation := NewTraceAggregation(traces)
+	assert.Equal(t, &TraceAggregation{
+		TraceCount: 2,
+		Operations: []OperationStats{
+			{
+				ServiceName:   "backend",
+				OperationName: "query",
+				SpanCount:     3,
+				ErrorCount:    1,
+				Duration:      DurationStats{Total: 80000, Min: 20000, Max: 30000, P50: 30000, P95: 30000, P99: 30000},
+				SelfTime:      DurationStats{Total: 80000, Min: 20000, Max: 30000, P50: 30000, P95: 30000, P99: 30000},
+			},
+			{
+				ServiceName:   "frontend",
+				OperationName: "GET /",
+				SpanCount:     2,
+				ErrorCount:    1,
+				Duration:      DurationStats{Total: 150000, Min: 50000, Max: 100000, P50: 50000, P95: 100000, P99: 100000},
+				SelfTime:      DurationStats{Total: 100000, Min: 40000, Max: 60000, P50: 40000, P95: 60000, P99: 60000},
+			},
+		},
+	}, aggregation)
+}
+
+func TestNewDurationStatsPercentiles(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i > 0; i-- {
+		durations = append(durations, time.Duration(i)*time.Microsecond)
+	}
+	assert.Equal(t, DurationStats{Total: 5050, Min: 1, Max: 100, P50: 50, P95: 95, P99: 99}, newDurationStats(durations))
+	assert.Equal(t, DurationStats{}, newDurationStats(nil))
+}
+
+func TestAggregateTraces(t *testing.T) {
+	tqs := initializeTestService(withAdjuster())
+	tqs.spanReader.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return([]*model.Trace{mockTrace}, nil).Once()
+
+	aggregation, err := tqs.queryService.AggregateTraces(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "service"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, aggregation.TraceCount)
+	require.Len(t, aggregation.Operations, 1)
+	assert.Equal(t, 2, aggregation.Operations[0].SpanCount)
+	assert.Equal(t, []string{errAdjustment.Error()}, aggregation.Warnings)
+
+	tqs.spanReader.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return(nil, assert.AnError).Once()
+	_, err = tqs.queryService.AggregateTraces(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "service"})
+	assert.Equal(t, assert.AnError, err)
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// ComparisonStatus tells in which of the compared traces a ComparisonNode was found.
+type ComparisonStatus string
+
+const (
+	// ComparisonBoth means the node is in both traces.
+	ComparisonBoth ComparisonStatus = "both"
+	// ComparisonAdded means the node is only in the second trace.
+	ComparisonAdded ComparisonStatus = "added"
+	// ComparisonMissing means the node is only in the first trace.
+	ComparisonMissing ComparisonStatus = "missing"
+)
+
+// TraceComparison is the structural difference between trace A and trace B.
+type TraceComparison struct {
+	TraceIDA string            `json:"traceIDA"`
+	TraceIDB string            `json:"traceIDB"`
+	Roots    []*ComparisonNode `json:"roots"`
+	// Warnings are the errors returned by the adjusters, the comparison uses the adjusted traces regardless.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ComparisonNode groups the spans with the same service and operation path from
+// the root span, i.e. sibling spans with the same service and operation are aligned
+// together. Durations are the sum of the span durations, in microseconds.
+type ComparisonNode struct {
+	ServiceName   string            `json:"serviceName"`
+	OperationName string            `json:"operationName"`
+	Status        ComparisonStatus  `json:"status"`
+	SpanCountA    int               `json:"spanCountA"`
+	SpanCountB    int               `json:"spanCountB"`
+	DurationA     uint64            `json:"durationA"`
+	DurationB     uint64            `json:"durationB"`
+	DurationDelta int64             `json:"durationDelta"`
+	TagDiffs      []TagDiff         `json:"tagDiffs,omitempty"`
+	Children      []*ComparisonNode `json:"children,omitempty"`
+}
+
+// TagDiff lists the distinct values of a span tag that differ between the aligned spans of A and B.
+type TagDiff struct {
+	Key     string   `json:"key"`
+	ValuesA []string `json:"valuesA"`
+	ValuesB []string `json:"valuesB"`
+}
+
+// CompareTraces fetches and adjusts traces A and B and returns their structural difference.
+func (qs QueryService) CompareTraces(ctx context.Context, traceIDA, traceIDB model.TraceID) (*TraceComparison, error) {
+	comparison := &TraceComparison{TraceIDA: traceIDA.String(), TraceIDB: traceIDB.String()}
+	traces := make([]*model.Trace, 2)
+	for i, traceID := range []model.TraceID{traceIDA, traceIDB} {
+		trace, err := qs.GetTrace(ctx, traceID)
+		if err != nil {
+			return nil, err
+		}
+		trace, err = qs.Adjust(trace)
+		if err != nil {
+			comparison.Warnings = append(comparison.Warnings, err.Error())
+		}
+		traces[i] = trace
+	}
+	comparison.Roots = compareNodes(buildPathNodes(traces[0]), buildPathNodes(traces[1]))
+	return comparison, nil
+}
+
+type pathKey struct {
+	service   string
+	operation string
+}
+
+// pathNode holds the spans of a trace with the same service and operation path.
+type pathNode struct {
+	key      pathKey
+	spans    []*model.Span
+	children []*pathNode
+}
+
+// buildPathNodes returns the root path nodes of the trace. Spans whose parent is
+// not in the trace are roots.
+func buildPathNodes(trace *model.Trace) []*pathNode {
+	spans := make([]*model.Span, len(trace.Spans))
+	copy(spans, trace.Spans)
+	// siblings are grouped in the order of their first span
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+	spanIDs := make(map[model.SpanID]struct{}, len(spans))
+	for _, span := range spans {
+		spanIDs[span.SpanID] = struct{}{}
+	}
+	var roots []*model.Span
+	children := make(map[model.SpanID][]*model.Span)
+	for _, span := range spans {
+		parentID := span.ParentSpanID()
+		if _, ok := spanIDs[parentID]; ok && parentID != span.SpanID {
+			children[parentID] = append(children[parentID], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+	return groupSpans(roots, children)
+}
+
+func groupSpans(spans []*model.Span, children map[model.SpanID][]*model.Span) []*pathNode {
+	var nodes []*pathNode
+	byKey := make(map[pathKey]*pathNode)
+	for _, span := range spans {
+		key := pathKey{operation: span.OperationName}
+		if span.Process != nil {
+			key.service = span.Process.ServiceName
+		}
+		node, ok := byKey[key]
+		if !ok {
+			node = &pathNode{key: key}
+			byKey[key] = node
+			nodes = append(nodes, node)
+		}
+		node.spans = append(node.spans, span)
+	}
+	for _, node := range nodes {
+		var nodeChildren []*model.Span
+		for _, span := range node.spans {
+			nodeChildren = append(nodeChildren, children[span.SpanID]...)
+		}
+		sort.SliceStable(nodeChildren, func(i, j int) bool {
+			return nodeChildren[i].StartTime.Before(nodeChildren[j].StartTime)
+		})
+		node.children = groupSpans(nodeChildren, children)
+	}
+	return nodes
+}
+
+// compareNodes aligns the nodes of A and B by service and operation. Nodes only
+// in A come first, in the order of A, followed by the nodes only in B.
+func compareNodes(nodesA, nodesB []*pathNode) []*ComparisonNode {
+	byKeyB := make(map[pathKey]*pathNode, len(nodesB))
+	for _, node := range nodesB {
+		byKeyB[node.key] = node
+	}
+	var compared []*ComparisonNode
+	matched := make(map[pathKey]struct{}, len(nodesA))
+	for _, nodeA := range nodesA {
+		nodeB, ok := byKeyB[nodeA.key]
+		if !ok {
+			compared = append(compared, newComparisonNode(nodeA, nil))
+			continue
+		}
+		matched[nodeA.key] = struct{}{}
+		compared = append(compared, newComparisonNode(nodeA, nodeB))
+	}
+	for _, nodeB := range nodesB {
+		if _, ok := matched[nodeB.key]; !ok {
+			compared = append(compared, newComparisonNode(nil, nodeB))
+		}
+	}
+	return compared
+}
+
+// newComparisonNode compares nodeA and nodeB, either of which can be nil.
+func newComparisonNode(nodeA, nodeB *pathNode) *ComparisonNode {
+	var compared *ComparisonNode
+	switch {
+	case nodeB == nil:
+		compared = &ComparisonNode{Status: ComparisonMissing, Children: compareNodes(nodeA.children, nil)}
+	case nodeA == nil:
+		compared = &ComparisonNode{Status: ComparisonAdded, Children: compareNodes(nil, nodeB.children)}
+	default:
+		compared = &ComparisonNode{
+			Status:   ComparisonBoth,
+			TagDiffs: compareTags(nodeA.spans, nodeB.spans),
+			Children: compareNodes(nodeA.children, nodeB.children),
+		}
+	}
+	if nodeA != nil {
+		compared.ServiceName, compared.OperationName = nodeA.key.service, nodeA.key.operation
+		compared.SpanCountA = len(nodeA.spans)
+		compared.DurationA = totalDuration(nodeA.spans)
+	}
+	if nodeB != nil {
+		compared.ServiceName, compared.OperationName = nodeB.key.service, nodeB.key.operation
+		compared.SpanCountB = len(nodeB.spans)
+		compared.DurationB = totalDuration(nodeB.spans)
+	}
+	compared.DurationDelta = int64(compared.DurationB) - int64(compared.DurationA)
+	return compared
+}
+
+func totalDuration(spans []*model.Span) uint64 {
+	var total time.Duration
+	for _, span := range spans {
+		total += span.Duration
+	}
+	return model.DurationAsMicroseconds(total)
+}
+
+// compareTags returns the span tags whose distinct values differ between spansA and spansB.
+func compareTags(spansA, spansB []*model.Span) []TagDiff {
+	valuesA, valuesB := tagValues(spansA), tagValues(spansB)
+	keys := make(map[string]struct{}, len(valuesA)+len(valuesB))
+	for k := range valuesA {
+		keys[k] = struct{}{}
+	}
+	for k := range valuesB {
+		keys[k] = struct{}{}
+	}
+	var diffs []TagDiff
+	for k := range keys {
+		a, b := sortedValues(valuesA[k]), sortedValues(valuesB[k])
+		if !equalValues(a, b) {
+			diffs = append(diffs, TagDiff{Key: k, ValuesA: a, ValuesB: b})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Key < diffs[j].Key
+	})
+	return diffs
+}
+
+func tagValues(spans []*model.Span) map[string]map[string]struct{} {
+	values := make(map[string]map[string]struct{})
+	for _, span := range spans {
+		for _, tag := range span.Tags {
+			if values[tag.Key] == nil {
+				values[tag.Key] = make(map[string]struct{})
+			}
+			values[tag.Key][tag.AsString()] = struct{}{}
+		}
+	}
+	return values
+}
+
+func sortedValues(values map[string]struct{}) []string {
+	sorted := make([]string, 0, len(values))
+	for v := range values {
+		sorted = append(sorted, v)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+func compareTestSpan(traceID model.TraceID, spanID, parentID uint64, service, operation string, offset, duration time.Duration, tags ...model.KeyValue) *model.Span {
+	span := &model.Span{
+		TraceID:       traceID,
+		SpanID:        model.NewSpanID(spanID),
+		OperationName: operation,
+		Process:       &model.Process{ServiceName: service},
+		StartTime:     time.Unix(1000, 0).Add(offset),
+		Duration:      duration,
+		Tags:          tags,
+	}
+	if parentID != 0 {
+		span.References = []model.SpanRef{model.NewChildOfRef(traceID, model.NewSpanID(parentID))}
+	}
+	return span
+}
+
+func TestCompareTraces(t *testing.T) {
+	traceIDA, traceIDB := model.NewTraceID(0, 1), model.NewTraceID(0, 2)
+	traceA := &model.Trace{Spans: []*model.Span{
+		compareTestSpan(traceIDA, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+		compareTestSpan(traceIDA, 2, 1, "backend", "query", time.Millisecond, 10*time.Millisecond, model.String("db", "users")),
+		compareTestSpan(traceIDA, 3, 1, "backend", "query", 20*time.Millisecond, 10*time.Millisecond, model.String("db", "users")),
+		compareTestSpan(traceIDA, 4, 1, "cache", "get", 40*time.Millisecond, time.Millisecond),
+		compareTestSpan(traceIDA, 5, 4, "cache", "lookup", 40*time.Millisecond, time.Millisecond),
+	}}
+	traceB := &model.Trace{Spans: []*model.Span{
+		compareTestSpan(traceIDB, 3, 1, "backend", "query", 2*time.Millisecond, 50*time.Millisecond, model.String("db", "orders")),
+		compareTestSpan(traceIDB, 1, 0, "frontend", "GET /", 0, 150*time.Millisecond),
+		compareTestSpan(traceIDB, 6, 1, "auth", "check", time.Millisecond, 5*time.Millisecond),
+	}}
+
+	tqs := initializeTestService()
+	tqs.spanReader.On("GetTrace", mock.Anything, traceIDA).Return(traceA, nil).Once()
+	tqs.spanReader.On("GetTrace", mock.Anything, traceIDB).Return(traceB, nil).Once()
+
+	comparison, err := tqs.queryService.CompareTraces(context.Background(), traceIDA, traceIDB)
+	require.NoError(t, err)
+	assert.Equal(t, traceIDA.String(), comparison.TraceIDA)
+	assert.Equal(t, traceIDB.String(), comparison.TraceIDB)
+	assert.Empty(t, comparison.Warnings)
+
+	expected := []*ComparisonNode{
+		{
+			ServiceName: "frontend", OperationName: "GET /", Status: ComparisonBoth,
+			SpanCountA: 1, SpanCountB: 1, DurationA: 100000, DurationB: 150000, DurationDelta: 50000,
+			Children: []*ComparisonNode{
+				{
+					ServiceName: "backend", OperationName: "query", Status: ComparisonBoth,
+					SpanCountA: 2, SpanCountB: 1, DurationA: 20000, DurationB: 50000, DurationDelta: 30000,
+					TagDiffs: []TagDiff{{Key: "db", ValuesA: []string{"users"}, ValuesB: []string{"orders"}}},
+				},
+				{
+					ServiceName: "cache", OperationName: "get", Status: ComparisonMissing,
+					SpanCountA: 1, DurationA: 1000, DurationDelta: -1000,
+					Children: []*ComparisonNode{
+						{
+							ServiceName: "cache", OperationName: "lookup", Status: ComparisonMissing,
+							SpanCountA: 1, DurationA: 1000, DurationDelta: -1000,
+						},
+					},
+				},
+				{
+					ServiceName: "auth", OperationName: "check", Status: ComparisonAdded,
+					SpanCountB: 1, DurationB: 5000, DurationDelta: 5000,
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, comparison.Roots)
+}
+
+func TestCompareTracesAdjusterWarnings(t *testing.T) {
+	tqs := initializeTestService(withAdjuster())
+	tqs.spanReader.On("GetTrace", mock.Anything, mockTraceID).Return(mockTrace, nil).Twice()
+
+	comparison, err := tqs.queryService.CompareTraces(context.Background(), mockTraceID, mockTraceID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{errAdjustment.Error(), errAdjustment.Error()}, comparison.Warnings)
+	require.Len(t, comparison.Roots, 1)
+	assert.Equal(t, ComparisonBoth, comparison.Roots[0].Status)
+	assert.Equal(t, 2, comparison.Roots[0].SpanCountA)
+	assert.Equal(t, 2, comparison.Roots[0].SpanCountB)
+}
+
+func TestCompareTracesNotFound(t *testing.T) {
+	tqs := initializeTestService()
+	tqs.spanReader.On("GetTrace", mock.Anything, mockTraceID).Return(mockTrace, nil).Once()
+	tqs.spanReader.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(nil, spanstore.ErrTraceNotFound).Once()
+
+	_, err := tqs.queryService.CompareTraces(context.Background(), mockTraceID, model.NewTraceID(0, 1))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// storageFailuresTrailer is the gRPC trailer carrying the failures of the storage backends
+// that did not prevent the request from succeeding, e.g. a federated backend being down.
+const storageFailuresTrailer = "jaeger-storage-failures"
+
+// failuresServerStream is a wrapper for ServerStream providing settable context
+type failuresServerStream struct {
+	grpc.ServerStream
+	context context.Context
+}
+
+func (fss *failuresServerStream) Context() context.Context {
+	return fss.context
+}
+
+func failuresTrailer(ctx context.Context) metadata.MD {
+	failures := spanstore.ReportedFailures(ctx)
+	if len(failures) == 0 {
+		return nil
+	}
+	md := metadata.MD{}
+	for _, failure := range failures {
+		md.Append(storageFailuresTrailer, failure.Error())
+	}
+	return md
+}
+
+// newStorageFailuresUnaryInterceptor returns the storage failures reported while handling an RPC in its trailer.
+func newStorageFailuresUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = spanstore.WithFailures(ctx)
+		resp, err := handler(ctx, req)
+		if md := failuresTrailer(ctx); md != nil {
+			grpc.SetTrailer(ctx, md)
+		}
+		return resp, err
+	}
+}
+
+// newStorageFailuresStreamInterceptor returns the storage failures reported while handling a stream in its trailer.
+func newStorageFailuresStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := spanstore.WithFailures(ss.Context())
+		err := handler(srv, &failuresServerStream{
+			ServerStream: ss,
+			context:      ctx,
+		})
+		if md := failuresTrailer(ctx); md != nil {
+			ss.SetTrailer(md)
+		}
+		return err
+	}
+}
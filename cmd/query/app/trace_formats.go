@@ -0,0 +1,235 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/gogo/protobuf/proto"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/zipkin/zipkindeser"
+	"github.com/kjschnei001/jaeger/cmd/query/app/apiv3"
+	"github.com/kjschnei001/jaeger/model"
+	uiconv "github.com/kjschnei001/jaeger/model/converter/json"
+	"github.com/kjschnei001/jaeger/model/converter/otlp"
+	zipkinconv "github.com/kjschnei001/jaeger/model/converter/thrift/zipkin"
+	ui "github.com/kjschnei001/jaeger/model/json"
+	"github.com/kjschnei001/jaeger/swagger-gen/models"
+)
+
+const (
+	otlpJSONFormat   = "otlp-json"
+	otlpProtoFormat  = "otlp-proto"
+	jaegerJSONFormat = "jaeger-json"
+	zipkinV2Format   = "zipkin-v2"
+
+	jsonContentType     = "application/json"
+	protobufContentType = "application/x-protobuf"
+)
+
+// jaegerJSONTraces is the body of a jaeger-json export, the same as the response of GET /api/traces/{traceID}.
+type jaegerJSONTraces struct {
+	Data []*ui.Trace `json:"data"`
+}
+
+// encodeTrace encodes the trace in the format and returns it with its content type.
+func encodeTrace(trace *model.Trace, format string) ([]byte, string, error) {
+	switch format {
+	case otlpJSONFormat, otlpProtoFormat:
+		data, err := proto.Marshal(apiv3.TracesDataFromSpans(trace.Spans))
+		if err != nil {
+			return nil, "", err
+		}
+		if format == otlpProtoFormat {
+			return data, protobufContentType, nil
+		}
+		// the generated OTLP types predate the renaming of instrumentation libraries
+		// to scopes, the OTLP JSON encoding is taken from pdata instead
+		traces, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(data)
+		if err != nil {
+			return nil, "", err
+		}
+		data, err = (&ptrace.JSONMarshaler{}).MarshalTraces(traces)
+		return data, jsonContentType, err
+	case jaegerJSONFormat:
+		data, err := json.Marshal(jaegerJSONTraces{Data: []*ui.Trace{uiconv.FromDomain(trace)}})
+		return data, jsonContentType, err
+	case zipkinV2Format:
+		data, err := json.Marshal(spansToZipkinV2(trace.Spans))
+		return data, jsonContentType, err
+	}
+	return nil, "", unsupportedFormatError(format)
+}
+
+// decodeSpans decodes the spans of the traces encoded in the format.
+func decodeSpans(data []byte, format string) ([]*model.Span, error) {
+	switch format {
+	case otlpJSONFormat:
+		traces, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(data)
+		if err != nil {
+			return nil, err
+		}
+		return otlpToSpans(traces)
+	case otlpProtoFormat:
+		traces, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(data)
+		if err != nil {
+			return nil, err
+		}
+		return otlpToSpans(traces)
+	case jaegerJSONFormat:
+		var traces jaegerJSONTraces
+		if err := json.Unmarshal(data, &traces); err != nil {
+			return nil, err
+		}
+		var spans []*model.Span
+		for _, uiTrace := range traces.Data {
+			trace, err := uiconv.ToDomain(uiTrace)
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, trace.Spans...)
+		}
+		return spans, nil
+	case zipkinV2Format:
+		return zipkinV2ToSpans(data)
+	}
+	return nil, unsupportedFormatError(format)
+}
+
+// otlpToSpans converts the traces the same way the collector does for OTLP.
+func otlpToSpans(traces ptrace.Traces) ([]*model.Span, error) {
+	batches, err := otlp.ProtoFromTraces(traces)
+	if err != nil {
+		return nil, err
+	}
+	var spans []*model.Span
+	for _, batch := range batches {
+		for _, span := range batch.Spans {
+			if span.Process == nil {
+				span.Process = batch.Process
+			}
+			spans = append(spans, span)
+		}
+	}
+	return spans, nil
+}
+
+func unsupportedFormatError(format string) error {
+	return fmt.Errorf("unsupported value '%s' for parameter '%s', expected one of %s",
+		format, formatParam, strings.Join([]string{otlpJSONFormat, otlpProtoFormat, jaegerJSONFormat, zipkinV2Format}, ", "))
+}
+
+// zipkinV2ToSpans converts the spans the same way the collector does for Zipkin v2 JSON.
+func zipkinV2ToSpans(data []byte) ([]*model.Span, error) {
+	var zSpans models.ListOfSpans
+	if err := swag.ReadJSON(data, &zSpans); err != nil {
+		return nil, err
+	}
+	if err := zSpans.Validate(strfmt.Default); err != nil {
+		return nil, err
+	}
+	tSpans, err := zipkindeser.SpansV2ToThrift(zSpans)
+	if err != nil {
+		return nil, err
+	}
+	sanitizer := zipkin.NewChainedSanitizer(zipkin.NewStandardSanitizers()...)
+	var spans []*model.Span
+	for _, tSpan := range tSpans {
+		// the conversion errors describe issues in the data, the spans are valid regardless
+		converted, _ := zipkinconv.ToDomainSpan(sanitizer.Sanitize(tSpan))
+		spans = append(spans, converted...)
+	}
+	return spans, nil
+}
+
+// spansToZipkinV2 converts the spans to Zipkin v2. The process tags other than the IP
+// address have no Zipkin equivalent and are dropped, the other tags become strings.
+func spansToZipkinV2(spans []*model.Span) models.ListOfSpans {
+	zSpans := make(models.ListOfSpans, len(spans))
+	for i, span := range spans {
+		traceID, spanID := span.TraceID.String(), span.SpanID.String()
+		zSpan := &models.Span{
+			TraceID:   &traceID,
+			ID:        &spanID,
+			Name:      span.OperationName,
+			Debug:     span.Flags.IsDebug(),
+			Timestamp: int64(model.TimeAsEpochMicroseconds(span.StartTime)),
+			Duration:  int64(model.DurationAsMicroseconds(span.Duration)),
+		}
+		if parentID := span.ParentSpanID(); parentID != 0 {
+			zSpan.ParentID = parentID.String()
+		}
+		if span.Process != nil {
+			zSpan.LocalEndpoint = &models.Endpoint{ServiceName: span.Process.ServiceName}
+			if ip, ok := model.KeyValues(span.Process.Tags).FindByKey(zipkinconv.IPTagName); ok {
+				zSpan.LocalEndpoint.IPV4 = strfmt.IPv4(processIPv4(ip))
+			}
+		}
+		for _, tag := range span.Tags {
+			if tag.Key == "span.kind" {
+				zSpan.Kind = strings.ToUpper(tag.AsString())
+				continue
+			}
+			if zSpan.Tags == nil {
+				zSpan.Tags = make(models.Tags)
+			}
+			zSpan.Tags[tag.Key] = tag.AsString()
+		}
+		for _, log := range span.Logs {
+			zSpan.Annotations = append(zSpan.Annotations, &models.Annotation{
+				Timestamp: int64(model.TimeAsEpochMicroseconds(log.Timestamp)),
+				Value:     logToZipkinAnnotation(log),
+			})
+		}
+		zSpans[i] = zSpan
+	}
+	return zSpans
+}
+
+// processIPv4 returns the dotted IPv4 address of the ip process tag, which is an integer
+// for spans received in Zipkin format, or an empty string.
+func processIPv4(ip model.KeyValue) string {
+	if ip.VType == model.Int64Type {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(ip.Int64()))
+		return net.IP(b[:]).String()
+	}
+	if parsed := net.ParseIP(ip.AsString()).To4(); parsed != nil {
+		return parsed.String()
+	}
+	return ""
+}
+
+// logToZipkinAnnotation returns the value of the log event, or the JSON encoding of the
+// log fields, which the Zipkin conversion decodes back into fields.
+func logToZipkinAnnotation(log model.Log) string {
+	if len(log.Fields) == 1 && log.Fields[0].Key == zipkinconv.DefaultLogFieldKey {
+		return log.Fields[0].AsString()
+	}
+	fields := make(map[string]string, len(log.Fields))
+	for _, field := range log.Fields {
+		fields[field.Key] = field.AsString()
+	}
+	value, _ := json.Marshal(fields)
+	return string(value)
+}
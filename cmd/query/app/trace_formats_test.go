@@ -0,0 +1,138 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+var formatsTestTraceID = model.NewTraceID(1, 2)
+
+func formatsTestTrace() *model.Trace {
+	start := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	return &model.Trace{Spans: []*model.Span{
+		{
+			TraceID:       formatsTestTraceID,
+			SpanID:        model.NewSpanID(1),
+			OperationName: "GET /",
+			StartTime:     start,
+			Duration:      100 * time.Millisecond,
+			Tags:          []model.KeyValue{model.String("span.kind", "server"), model.String("http.method", "GET")},
+			Process: &model.Process{
+				ServiceName: "frontend",
+				Tags:        []model.KeyValue{model.String("ip", "10.0.0.1")},
+			},
+		},
+		{
+			TraceID:       formatsTestTraceID,
+			SpanID:        model.NewSpanID(2),
+			OperationName: "query",
+			References:    []model.SpanRef{model.NewChildOfRef(formatsTestTraceID, model.NewSpanID(1))},
+			StartTime:     start.Add(10 * time.Millisecond),
+			Duration:      50 * time.Millisecond,
+			Tags:          []model.KeyValue{model.String("span.kind", "client")},
+			Logs: []model.Log{{
+				Timestamp: start.Add(20 * time.Millisecond),
+				Fields:    []model.KeyValue{model.String("event", "retry")},
+			}},
+			Process: &model.Process{ServiceName: "backend"},
+		},
+	}}
+}
+
+func TestTraceFormatsRoundTrip(t *testing.T) {
+	for _, format := range []string{otlpJSONFormat, otlpProtoFormat, jaegerJSONFormat, zipkinV2Format} {
+		t.Run(format, func(t *testing.T) {
+			expected := formatsTestTrace()
+			data, contentType, err := encodeTrace(expected, format)
+			require.NoError(t, err)
+			if format == otlpProtoFormat {
+				assert.Equal(t, protobufContentType, contentType)
+			} else {
+				assert.Equal(t, jsonContentType, contentType)
+			}
+
+			spans, err := decodeSpans(data, format)
+			require.NoError(t, err)
+			require.Len(t, spans, len(expected.Spans))
+			sort.Slice(spans, func(i, j int) bool {
+				return spans[i].SpanID < spans[j].SpanID
+			})
+			for i, span := range spans {
+				expectedSpan := expected.Spans[i]
+				assert.Equal(t, expectedSpan.TraceID, span.TraceID)
+				assert.Equal(t, expectedSpan.SpanID, span.SpanID)
+				assert.Equal(t, expectedSpan.ParentSpanID(), span.ParentSpanID())
+				assert.Equal(t, expectedSpan.OperationName, span.OperationName)
+				assert.True(t, expectedSpan.StartTime.Equal(span.StartTime))
+				assert.Equal(t, expectedSpan.Duration, span.Duration)
+				assert.Equal(t, expectedSpan.Process.ServiceName, span.Process.ServiceName)
+				assert.Equal(t, expectedSpan.Logs, span.Logs)
+				kind, _ := span.GetSpanKind()
+				expectedKind, _ := expectedSpan.GetSpanKind()
+				assert.Equal(t, expectedKind, kind)
+			}
+		})
+	}
+}
+
+func TestTraceFormatsUnsupported(t *testing.T) {
+	expectedErr := "unsupported value 'xml' for parameter 'format', expected one of otlp-json, otlp-proto, jaeger-json, zipkin-v2"
+	_, _, err := encodeTrace(formatsTestTrace(), "xml")
+	assert.EqualError(t, err, expectedErr)
+	_, err = decodeSpans(nil, "xml")
+	assert.EqualError(t, err, expectedErr)
+}
+
+func TestDecodeSpansInvalid(t *testing.T) {
+	for _, format := range []string{otlpJSONFormat, otlpProtoFormat, jaegerJSONFormat, zipkinV2Format} {
+		t.Run(format, func(t *testing.T) {
+			_, err := decodeSpans([]byte("{invalid"), format)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := decodeSpans([]byte(`{"data":[{"spans":[{"traceID":"1","spanID":"2","processID":"p1"}]}]}`), jaegerJSONFormat)
+	assert.EqualError(t, err, "span 2 references unknown process 'p1'")
+
+	_, err = decodeSpans([]byte(`[{"traceId":"1","id":"2"}]`), zipkinV2Format)
+	assert.Error(t, err)
+	_, err = decodeSpans([]byte(`{"resourceSpans":[{"scopeSpans":[{"spans":[{"name":"op","spanId":"0000000000000001"}]}]}]}`), otlpJSONFormat)
+	assert.ErrorContains(t, err, `invalid span "op"`)
+}
+
+func TestSpansToZipkinV2(t *testing.T) {
+	trace := formatsTestTrace()
+	trace.Spans[1].Process.Tags = []model.KeyValue{model.Int64("ip", 167772162)}
+	trace.Spans[1].Logs[0].Fields = append(trace.Spans[1].Logs[0].Fields, model.Int64("attempt", 2))
+
+	zSpans := spansToZipkinV2(trace.Spans)
+	require.Len(t, zSpans, 2)
+	assert.Equal(t, "00000000000000010000000000000002", *zSpans[0].TraceID)
+	assert.Equal(t, "SERVER", zSpans[0].Kind)
+	assert.Equal(t, "10.0.0.1", zSpans[0].LocalEndpoint.IPV4.String())
+	assert.Empty(t, zSpans[0].ParentID)
+	assert.Equal(t, "0000000000000001", zSpans[1].ParentID)
+	assert.Equal(t, "10.0.0.2", zSpans[1].LocalEndpoint.IPV4.String())
+	assert.Equal(t, int64(50000), zSpans[1].Duration)
+	assert.Equal(t, `{"attempt":"2","event":"retry"}`, zSpans[1].Annotations[0].Value)
+}
@@ -24,6 +24,7 @@ import (
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/zap"
 
+	"github.com/kjschnei001/jaeger/cmd/badger"
 	"github.com/kjschnei001/jaeger/cmd/docs"
 	"github.com/kjschnei001/jaeger/cmd/env"
 	"github.com/kjschnei001/jaeger/cmd/flags"
@@ -74,6 +75,10 @@ func main() {
 			if err := storageFactory.Initialize(baseFactory, logger); err != nil {
 				logger.Fatal("Failed to init storage factory", zap.Error(err))
 			}
+			for route, handler := range storageFactory.AdminHandlers() {
+				logger.Info("Mounting storage handler on admin server", zap.String("route", route))
+				svc.Admin.Handle(route, handler)
+			}
 
 			tm := tenancy.NewManager(&opts.Tenancy)
 			server, err := app.NewServer(opts, storageFactory, tm, svc.Logger)
@@ -105,6 +110,7 @@ func main() {
 	command.AddCommand(env.Command())
 	command.AddCommand(docs.Command(v))
 	command.AddCommand(status.Command(v, ports.QueryAdminHTTP))
+	command.AddCommand(badger.Command(v, ports.QueryAdminHTTP))
 
 	config.AddFlags(
 		v,
@@ -0,0 +1,61 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spanstoretest provides the fixtures of the tests of the span readers
+// that combine several storages.
+package spanstoretest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// Span returns a span of the service lasting a millisecond. The span.kind tag is only set
+// if kind is not empty.
+func Span(traceID uint64, spanID uint64, service string, start time.Time, kind string) *model.Span {
+	span := &model.Span{
+		TraceID:       model.NewTraceID(0, traceID),
+		SpanID:        model.NewSpanID(spanID),
+		OperationName: "op-" + service,
+		StartTime:     start,
+		Duration:      time.Millisecond,
+		Process:       &model.Process{ServiceName: service},
+	}
+	if kind != "" {
+		span.Tags = model.KeyValues{model.String("span.kind", kind)}
+	}
+	return span
+}
+
+// WriteSpans writes the spans with the writer.
+func WriteSpans(t *testing.T, writer spanstore.Writer, spans ...*model.Span) {
+	for _, span := range spans {
+		require.NoError(t, writer.WriteSpan(context.Background(), span))
+	}
+}
+
+// SpanIDs returns the IDs of the spans of the trace, in their order.
+func SpanIDs(trace *model.Trace) []model.SpanID {
+	var ids []model.SpanID
+	for _, span := range trace.Spans {
+		ids = append(ids, span.SpanID)
+	}
+	return ids
+}
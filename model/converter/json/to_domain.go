@@ -0,0 +1,237 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/kjschnei001/jaeger/model"
+	jModel "github.com/kjschnei001/jaeger/model/json"
+)
+
+// ToDomain converts json.Trace, as produced by FromDomain and decoded with
+// encoding/json, back into model.Trace. Spans may reference a process of the
+// trace by ProcessID or embed their Process. Numeric values may be decoded as
+// float64 or json.Number, int64 values may also be strings, as FromDomain
+// writes the integers that JavaScript cannot represent exactly as strings.
+// Empty lists of references, tags and logs are converted to nil.
+func ToDomain(trace *jModel.Trace) (*model.Trace, error) {
+	processes := make(map[jModel.ProcessID]*model.Process, len(trace.Processes))
+	for id, process := range trace.Processes {
+		p, err := convertProcess(&process)
+		if err != nil {
+			return nil, err
+		}
+		processes[id] = p
+	}
+	spans := make([]*model.Span, len(trace.Spans))
+	for i := range trace.Spans {
+		span, err := convertSpan(&trace.Spans[i], processes)
+		if err != nil {
+			return nil, err
+		}
+		spans[i] = span
+	}
+	return &model.Trace{Spans: spans, Warnings: trace.Warnings}, nil
+}
+
+func convertSpan(jSpan *jModel.Span, processes map[jModel.ProcessID]*model.Process) (*model.Span, error) {
+	traceID, err := model.TraceIDFromString(string(jSpan.TraceID))
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := model.SpanIDFromString(string(jSpan.SpanID))
+	if err != nil {
+		return nil, err
+	}
+	refs, err := convertReferences(jSpan.References)
+	if err != nil {
+		return nil, err
+	}
+	if jSpan.ParentSpanID != "" {
+		parentSpanID, err := model.SpanIDFromString(string(jSpan.ParentSpanID))
+		if err != nil {
+			return nil, err
+		}
+		refs = model.MaybeAddParentSpanID(traceID, parentSpanID, refs)
+	}
+	tags, err := convertKeyValues(jSpan.Tags)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := convertLogs(jSpan.Logs)
+	if err != nil {
+		return nil, err
+	}
+	var process *model.Process
+	if jSpan.Process != nil {
+		if process, err = convertProcess(jSpan.Process); err != nil {
+			return nil, err
+		}
+	} else if process = processes[jSpan.ProcessID]; process == nil {
+		return nil, fmt.Errorf("span %s references unknown process '%s'", jSpan.SpanID, jSpan.ProcessID)
+	}
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: jSpan.OperationName,
+		References:    refs,
+		Flags:         model.Flags(jSpan.Flags),
+		StartTime:     model.EpochMicrosecondsAsTime(jSpan.StartTime),
+		Duration:      model.MicrosecondsAsDuration(jSpan.Duration),
+		Tags:          tags,
+		Logs:          logs,
+		Process:       process,
+		Warnings:      jSpan.Warnings,
+	}, nil
+}
+
+func convertReferences(refs []jModel.Reference) ([]model.SpanRef, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	out := make([]model.SpanRef, len(refs))
+	for i, ref := range refs {
+		var refType model.SpanRefType
+		switch ref.RefType {
+		case jModel.ChildOf:
+			refType = model.ChildOf
+		case jModel.FollowsFrom:
+			refType = model.FollowsFrom
+		default:
+			return nil, fmt.Errorf("not a valid SpanRefType string %s", string(ref.RefType))
+		}
+		traceID, err := model.TraceIDFromString(string(ref.TraceID))
+		if err != nil {
+			return nil, err
+		}
+		spanID, err := model.SpanIDFromString(string(ref.SpanID))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = model.SpanRef{RefType: refType, TraceID: traceID, SpanID: spanID}
+	}
+	return out, nil
+}
+
+func convertProcess(process *jModel.Process) (*model.Process, error) {
+	tags, err := convertKeyValues(process.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Process{ServiceName: process.ServiceName, Tags: tags}, nil
+}
+
+func convertLogs(logs []jModel.Log) ([]model.Log, error) {
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	out := make([]model.Log, len(logs))
+	for i, log := range logs {
+		fields, err := convertKeyValues(log.Fields)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = model.Log{
+			Timestamp: model.EpochMicrosecondsAsTime(log.Timestamp),
+			Fields:    fields,
+		}
+	}
+	return out, nil
+}
+
+func convertKeyValues(keyValues []jModel.KeyValue) (model.KeyValues, error) {
+	if len(keyValues) == 0 {
+		return nil, nil
+	}
+	out := make(model.KeyValues, len(keyValues))
+	for i := range keyValues {
+		kv, err := convertKeyValue(&keyValues[i])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = kv
+	}
+	return out, nil
+}
+
+func convertKeyValue(kv *jModel.KeyValue) (model.KeyValue, error) {
+	switch kv.Type {
+	case jModel.StringType, "":
+		if value, ok := kv.Value.(string); ok {
+			return model.String(kv.Key, value), nil
+		}
+	case jModel.BoolType:
+		switch value := kv.Value.(type) {
+		case bool:
+			return model.Bool(kv.Key, value), nil
+		case string:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Bool(kv.Key, b), nil
+		}
+	case jModel.Int64Type:
+		switch value := kv.Value.(type) {
+		case float64:
+			return model.Int64(kv.Key, int64(value)), nil
+		case json.Number:
+			n, err := value.Int64()
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Int64(kv.Key, n), nil
+		case string:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Int64(kv.Key, n), nil
+		}
+	case jModel.Float64Type:
+		switch value := kv.Value.(type) {
+		case float64:
+			return model.Float64(kv.Key, value), nil
+		case json.Number:
+			f, err := value.Float64()
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Float64(kv.Key, f), nil
+		case string:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Float64(kv.Key, f), nil
+		}
+	case jModel.BinaryType:
+		// []byte values are encoded as base64 strings by encoding/json
+		if value, ok := kv.Value.(string); ok {
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Binary(kv.Key, b), nil
+		}
+	default:
+		return model.KeyValue{}, fmt.Errorf("not a valid ValueType string %s", string(kv.Type))
+	}
+	return model.KeyValue{}, fmt.Errorf("invalid %s value %v for key '%s'", kv.Type, kv.Value, kv.Key)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	jModel "github.com/kjschnei001/jaeger/model/json"
+)
+
+func TestToDomain(t *testing.T) {
+	for i := 1; i <= NumberOfFixtures; i++ {
+		_, jsonStr := loadFixturesUI(t, i)
+
+		var uiTrace jModel.Trace
+		require.NoError(t, json.Unmarshal(jsonStr, &uiTrace))
+		trace, err := ToDomain(&uiTrace)
+		require.NoError(t, err)
+
+		// the UI model only has microsecond precision, so compare in that model
+		testJSONEncoding(t, i, jsonStr, FromDomain(trace), false)
+	}
+}
+
+func TestToDomainEmbeddedProcess(t *testing.T) {
+	jSpan := jModel.Span{
+		TraceID:      "1",
+		SpanID:       "2",
+		ParentSpanID: "3",
+		Process:      &jModel.Process{ServiceName: "service"},
+		Tags: []jModel.KeyValue{
+			{Key: "big", Type: jModel.Int64Type, Value: "9007199254740993"},
+			{Key: "number", Type: jModel.Int64Type, Value: json.Number("42")},
+			{Key: "ratio", Type: jModel.Float64Type, Value: json.Number("0.5")},
+			{Key: "untyped", Value: "value"},
+		},
+	}
+	trace, err := ToDomain(&jModel.Trace{Spans: []jModel.Span{jSpan}})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	span := trace.Spans[0]
+	assert.Equal(t, &model.Process{ServiceName: "service"}, span.Process)
+	assert.Equal(t, model.NewSpanID(3), span.ParentSpanID())
+	assert.Equal(t, []model.KeyValue{
+		model.Int64("big", 9007199254740993),
+		model.Int64("number", 42),
+		model.Float64("ratio", 0.5),
+		model.String("untyped", "value"),
+	}, span.Tags)
+}
+
+func TestToDomainErrors(t *testing.T) {
+	validSpan := func() jModel.Span {
+		return jModel.Span{TraceID: "1", SpanID: "2", ProcessID: "p1"}
+	}
+	processes := map[jModel.ProcessID]jModel.Process{"p1": {ServiceName: "service"}}
+	testCases := []struct {
+		name   string
+		modify func(span *jModel.Span)
+		err    string
+	}{
+		{
+			name:   "invalid trace ID",
+			modify: func(span *jModel.Span) { span.TraceID = "x" },
+			err:    "strconv.ParseUint: parsing \"x\": invalid syntax",
+		},
+		{
+			name:   "unknown process",
+			modify: func(span *jModel.Span) { span.ProcessID = "p2" },
+			err:    "span 2 references unknown process 'p2'",
+		},
+		{
+			name: "invalid reference type",
+			modify: func(span *jModel.Span) {
+				span.References = []jModel.Reference{{RefType: "SIBLING", TraceID: "1", SpanID: "3"}}
+			},
+			err: "not a valid SpanRefType string SIBLING",
+		},
+		{
+			name: "invalid value type",
+			modify: func(span *jModel.Span) {
+				span.Tags = []jModel.KeyValue{{Key: "k", Type: "complex", Value: "1+i"}}
+			},
+			err: "not a valid ValueType string complex",
+		},
+		{
+			name: "mismatched value",
+			modify: func(span *jModel.Span) {
+				span.Tags = []jModel.KeyValue{{Key: "k", Type: jModel.BoolType, Value: 1.0}}
+			},
+			err: "invalid bool value 1 for key 'k'",
+		},
+		{
+			name: "invalid binary value",
+			modify: func(span *jModel.Span) {
+				span.Logs = []jModel.Log{{Fields: []jModel.KeyValue{{Key: "k", Type: jModel.BinaryType, Value: "%"}}}}
+			},
+			err: "illegal base64 data at input byte 0",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			span := validSpan()
+			testCase.modify(&span)
+			_, err := ToDomain(&jModel.Trace{Spans: []jModel.Span{span}, Processes: processes})
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
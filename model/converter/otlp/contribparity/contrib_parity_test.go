@@ -0,0 +1,51 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contribparity checks the OpenTelemetry collector-contrib translator against the
+// test data of the otlp converter. The translator produces the upstream Jaeger model, whose
+// protobuf types conflict with the ones of this module, so it is tested in its own package.
+package contribparity
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/jsonpb"
+	upstreamModel "github.com/jaegertracing/jaeger/model"
+	otlp2jaeger "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/jaeger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestProtoFromTraces(t *testing.T) {
+	otlpTraces, err := os.ReadFile("../testdata/otlp_traces.json")
+	require.NoError(t, err)
+	traces, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(otlpTraces)
+	require.NoError(t, err)
+	expected, err := os.ReadFile("../testdata/jaeger_batches.json")
+	require.NoError(t, err)
+
+	var batches []*upstreamModel.Batch
+	batches, err = otlp2jaeger.ProtoFromTraces(traces)
+	require.NoError(t, err)
+	var actual []string
+	for _, batch := range batches {
+		b, err := new(jsonpb.Marshaler).MarshalToString(batch)
+		require.NoError(t, err)
+		actual = append(actual, b)
+	}
+	assert.JSONEq(t, string(expected), "["+strings.Join(actual, ",")+"]")
+}
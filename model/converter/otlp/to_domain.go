@@ -0,0 +1,243 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp allows converting OpenTelemetry OTLP traces to model.Batch.
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.5.0"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// The tags use the same keys as the OpenTelemetry collector translator.
+const (
+	noServiceName      = "OTLPResourceNoServiceName"
+	tagSpanKind        = "span.kind"
+	tagError           = "error"
+	tagW3CTraceState   = "w3c.tracestate"
+	tagEvent           = "event"
+	attrRefType        = "opentracing.ref_type"
+	attrRefTypeChildOf = "child_of"
+	statusError        = "ERROR"
+	statusOk           = "OK"
+)
+
+var (
+	errZeroTraceID = errors.New("span has an all zeros trace ID")
+	errZeroSpanID  = errors.New("span has an all zeros span ID")
+)
+
+// ProtoFromTraces converts OTLP traces to batches, one per resource except the empty ones.
+// It fails with the first span which cannot be stored, e.g. because its trace or span ID
+// is all zeros.
+//
+// The OpenTelemetry collector-contrib translator implements the same translation, but it
+// cannot be linked with this module: it produces the upstream Jaeger model, whose protobuf
+// types are registered under the same names as ours. The conversions of the valid traces
+// are checked against the translator by the contribparity package.
+func ProtoFromTraces(td ptrace.Traces) ([]*model.Batch, error) {
+	resourceSpans := td.ResourceSpans()
+	batches := make([]*model.Batch, 0, resourceSpans.Len())
+	for i := 0; i < resourceSpans.Len(); i++ {
+		rs := resourceSpans.At(i)
+		if rs.Resource().Attributes().Len() == 0 && rs.ScopeSpans().Len() == 0 {
+			continue
+		}
+		batch, err := resourceSpansToBatch(rs)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+func resourceSpansToBatch(rs ptrace.ResourceSpans) (*model.Batch, error) {
+	batch := &model.Batch{
+		Process: resourceToProcess(rs.Resource()),
+	}
+	scopeSpans := rs.ScopeSpans()
+	for i := 0; i < scopeSpans.Len(); i++ {
+		spans := scopeSpans.At(i).Spans()
+		for j := 0; j < spans.Len(); j++ {
+			span, err := spanToDomain(spans.At(j), scopeSpans.At(i).Scope())
+			if err != nil {
+				return nil, fmt.Errorf("invalid span %q: %w", spans.At(j).Name(), err)
+			}
+			batch.Spans = append(batch.Spans, span)
+		}
+	}
+	return batch, nil
+}
+
+// resourceToProcess converts the resource attributes, like the translator the service
+// name is only replaced by a placeholder when the resource has no attributes at all.
+func resourceToProcess(resource pcommon.Resource) *model.Process {
+	if resource.Attributes().Len() == 0 {
+		return &model.Process{ServiceName: noServiceName}
+	}
+	process := &model.Process{}
+	resource.Attributes().Range(func(key string, value pcommon.Value) bool {
+		if key == semconv.AttributeServiceName {
+			process.ServiceName = value.Str()
+			return true
+		}
+		process.Tags = append(process.Tags, attributeToTag(key, value))
+		return true
+	})
+	return process
+}
+
+func spanToDomain(span ptrace.Span, scope pcommon.InstrumentationScope) (*model.Span, error) {
+	if span.TraceID().IsEmpty() {
+		return nil, errZeroTraceID
+	}
+	if span.SpanID().IsEmpty() {
+		return nil, errZeroSpanID
+	}
+	traceID := traceIDToDomain(span.TraceID())
+	startTime := span.StartTimestamp().AsTime()
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanIDToDomain(span.SpanID()),
+		OperationName: span.Name(),
+		References:    references(span, traceID),
+		StartTime:     startTime,
+		Duration:      span.EndTimestamp().AsTime().Sub(startTime),
+		Tags:          spanTags(span, scope),
+		Logs:          eventsToLogs(span.Events()),
+	}, nil
+}
+
+// references returns the parent span first, as usually expected from the first CHILD_OF reference.
+func references(span ptrace.Span, traceID model.TraceID) []model.SpanRef {
+	var refs []model.SpanRef
+	if parentID := span.ParentSpanID(); !parentID.IsEmpty() {
+		refs = append(refs, model.NewChildOfRef(traceID, spanIDToDomain(parentID)))
+	}
+	links := span.Links()
+	for i := 0; i < links.Len(); i++ {
+		link := links.At(i)
+		refType := model.FollowsFrom
+		if value, ok := link.Attributes().Get(attrRefType); ok && value.Str() == attrRefTypeChildOf {
+			refType = model.ChildOf
+		}
+		refs = append(refs, model.SpanRef{
+			TraceID: traceIDToDomain(link.TraceID()),
+			SpanID:  spanIDToDomain(link.SpanID()),
+			RefType: refType,
+		})
+	}
+	return refs
+}
+
+func spanTags(span ptrace.Span, scope pcommon.InstrumentationScope) []model.KeyValue {
+	var tags []model.KeyValue
+	if scope.Name() != "" {
+		tags = append(tags, model.String(semconv.InstrumentationLibraryName, scope.Name()))
+	}
+	if scope.Version() != "" {
+		tags = append(tags, model.String(semconv.InstrumentationLibraryVersion, scope.Version()))
+	}
+	span.Attributes().Range(func(key string, value pcommon.Value) bool {
+		tags = append(tags, attributeToTag(key, value))
+		return true
+	})
+	if kind := spanKindTag(span.Kind()); kind != "" {
+		tags = append(tags, model.String(tagSpanKind, kind))
+	}
+	switch span.Status().Code() {
+	case ptrace.StatusCodeError:
+		tags = append(tags, model.String(semconv.OtelStatusCode, statusError), model.Bool(tagError, true))
+	case ptrace.StatusCodeOk:
+		tags = append(tags, model.String(semconv.OtelStatusCode, statusOk))
+	}
+	if message := span.Status().Message(); message != "" {
+		tags = append(tags, model.String(semconv.OtelStatusDescription, message))
+	}
+	if traceState := span.TraceState().AsRaw(); traceState != "" {
+		tags = append(tags, model.String(tagW3CTraceState, traceState))
+	}
+	return tags
+}
+
+func spanKindTag(kind ptrace.SpanKind) string {
+	switch kind {
+	case ptrace.SpanKindClient:
+		return "client"
+	case ptrace.SpanKindServer:
+		return "server"
+	case ptrace.SpanKindProducer:
+		return "producer"
+	case ptrace.SpanKindConsumer:
+		return "consumer"
+	case ptrace.SpanKindInternal:
+		return "internal"
+	}
+	return ""
+}
+
+func eventsToLogs(events ptrace.SpanEventSlice) []model.Log {
+	var logs []model.Log
+	for i := 0; i < events.Len(); i++ {
+		event := events.At(i)
+		fields := make([]model.KeyValue, 0, event.Attributes().Len()+1)
+		if _, ok := event.Attributes().Get(tagEvent); event.Name() != "" && !ok {
+			fields = append(fields, model.String(tagEvent, event.Name()))
+		}
+		event.Attributes().Range(func(key string, value pcommon.Value) bool {
+			fields = append(fields, attributeToTag(key, value))
+			return true
+		})
+		logs = append(logs, model.Log{
+			Timestamp: event.Timestamp().AsTime(),
+			Fields:    fields,
+		})
+	}
+	return logs
+}
+
+func attributeToTag(key string, value pcommon.Value) model.KeyValue {
+	switch value.Type() {
+	case pcommon.ValueTypeInt:
+		return model.Int64(key, value.Int())
+	case pcommon.ValueTypeBool:
+		return model.Bool(key, value.Bool())
+	case pcommon.ValueTypeDouble:
+		return model.Float64(key, value.Double())
+	case pcommon.ValueTypeBytes:
+		// like the OpenTelemetry collector, since Jaeger binary tags become base64 strings in OTLP
+		return model.String(key, base64.StdEncoding.EncodeToString(value.Bytes().AsRaw()))
+	case pcommon.ValueTypeStr:
+		return model.String(key, value.Str())
+	}
+	// Jaeger has no arrays or maps, keep their text representation
+	return model.String(key, value.AsString())
+}
+
+func traceIDToDomain(traceID pcommon.TraceID) model.TraceID {
+	return model.NewTraceID(binary.BigEndian.Uint64(traceID[:8]), binary.BigEndian.Uint64(traceID[8:]))
+}
+
+func spanIDToDomain(spanID pcommon.SpanID) model.SpanID {
+	return model.NewSpanID(binary.BigEndian.Uint64(spanID[:]))
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+var (
+	testTraceID  = pcommon.TraceID([16]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2})
+	testSpanID   = pcommon.SpanID([8]byte{0, 0, 0, 0, 0, 0, 0, 3})
+	testParentID = pcommon.SpanID([8]byte{0, 0, 0, 0, 0, 0, 0, 4})
+	testLinkID   = pcommon.SpanID([8]byte{0, 0, 0, 0, 0, 0, 0, 5})
+	testStart    = time.Unix(1500, 0).UTC()
+)
+
+func makeTraces() (ptrace.Traces, ptrace.Span) {
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "frontend")
+	rs.Resource().Attributes().PutStr("host.name", "host-1")
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName("instrumentation")
+	ss.Scope().SetVersion("1.0")
+	span := ss.Spans().AppendEmpty()
+	span.SetTraceID(testTraceID)
+	span.SetSpanID(testSpanID)
+	span.SetName("GET /")
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(testStart))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(testStart.Add(time.Second)))
+	return traces, span
+}
+
+func TestProtoFromTraces(t *testing.T) {
+	traces, span := makeTraces()
+	span.SetParentSpanID(testParentID)
+	span.SetKind(ptrace.SpanKindServer)
+	span.Status().SetCode(ptrace.StatusCodeError)
+	span.Status().SetMessage("not found")
+	span.TraceState().FromRaw("vendor=value")
+	span.Attributes().PutStr("str", "value")
+	span.Attributes().PutInt("int", 42)
+	span.Attributes().PutDouble("double", 1.5)
+	span.Attributes().PutBool("bool", true)
+	span.Attributes().PutEmptyBytes("bytes").FromRaw([]byte{1, 2})
+	span.Attributes().PutEmptySlice("slice").AppendEmpty().SetStr("a")
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(testTraceID)
+	link.SetSpanID(testLinkID)
+	event := span.Events().AppendEmpty()
+	event.SetName("retry")
+	event.SetTimestamp(pcommon.NewTimestampFromTime(testStart.Add(time.Millisecond)))
+	event.Attributes().PutInt("attempt", 2)
+
+	batches, err := ProtoFromTraces(traces)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, &model.Process{
+		ServiceName: "frontend",
+		Tags:        []model.KeyValue{model.String("host.name", "host-1")},
+	}, batches[0].Process)
+	require.Len(t, batches[0].Spans, 1)
+
+	traceID := model.NewTraceID(1, 2)
+	assert.Equal(t, &model.Span{
+		TraceID:       traceID,
+		SpanID:        model.NewSpanID(3),
+		OperationName: "GET /",
+		References: []model.SpanRef{
+			model.NewChildOfRef(traceID, model.NewSpanID(4)),
+			model.NewFollowsFromRef(traceID, model.NewSpanID(5)),
+		},
+		StartTime: testStart,
+		Duration:  time.Second,
+		Tags: []model.KeyValue{
+			model.String("otel.library.name", "instrumentation"),
+			model.String("otel.library.version", "1.0"),
+			model.String("str", "value"),
+			model.Int64("int", 42),
+			model.Float64("double", 1.5),
+			model.Bool("bool", true),
+			model.String("bytes", "AQI="),
+			model.String("slice", `["a"]`),
+			model.String("span.kind", "server"),
+			model.String("otel.status_code", "ERROR"),
+			model.Bool("error", true),
+			model.String("otel.status_description", "not found"),
+			model.String("w3c.tracestate", "vendor=value"),
+		},
+		Logs: []model.Log{{
+			Timestamp: testStart.Add(time.Millisecond),
+			Fields:    []model.KeyValue{model.String("event", "retry"), model.Int64("attempt", 2)},
+		}},
+	}, batches[0].Spans[0])
+}
+
+func TestProtoFromTracesChildOfLink(t *testing.T) {
+	traces, span := makeTraces()
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(testTraceID)
+	link.SetSpanID(testLinkID)
+	link.Attributes().PutStr("opentracing.ref_type", "child_of")
+
+	batches, err := ProtoFromTraces(traces)
+	require.NoError(t, err)
+	span0 := batches[0].Spans[0]
+	assert.Equal(t, []model.SpanRef{model.NewChildOfRef(model.NewTraceID(1, 2), model.NewSpanID(5))}, span0.References)
+	assert.Equal(t, model.NewSpanID(5), span0.ParentSpanID())
+}
+
+// TestProtoFromTracesContribParity checks the conversion of the test data, whose conversion
+// by the collector-contrib translator is checked by the contribparity package.
+func TestProtoFromTracesContribParity(t *testing.T) {
+	otlpTraces, err := os.ReadFile("testdata/otlp_traces.json")
+	require.NoError(t, err)
+	traces, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(otlpTraces)
+	require.NoError(t, err)
+	expected, err := os.ReadFile("testdata/jaeger_batches.json")
+	require.NoError(t, err)
+
+	batches, err := ProtoFromTraces(traces)
+	require.NoError(t, err)
+	var actual []string
+	for _, batch := range batches {
+		b, err := new(jsonpb.Marshaler).MarshalToString(batch)
+		require.NoError(t, err)
+		actual = append(actual, b)
+	}
+	assert.JSONEq(t, string(expected), "["+strings.Join(actual, ",")+"]")
+}
+
+func TestProtoFromTracesNoServiceName(t *testing.T) {
+	traces := ptrace.NewTraces()
+	// the empty resources are skipped
+	traces.ResourceSpans().AppendEmpty()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	traces.ResourceSpans().AppendEmpty().Resource().Attributes().PutStr("host.name", "host-1")
+	batches, err := ProtoFromTraces(traces)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, "OTLPResourceNoServiceName", batches[0].Process.ServiceName)
+	assert.Empty(t, batches[0].Spans)
+	// like the translator, the service name is empty when the resource has other attributes
+	assert.Equal(t, &model.Process{Tags: []model.KeyValue{model.String("host.name", "host-1")}}, batches[1].Process)
+}
+
+func TestProtoFromTracesInvalidIDs(t *testing.T) {
+	traces, span := makeTraces()
+	span.SetTraceID(pcommon.NewTraceIDEmpty())
+	_, err := ProtoFromTraces(traces)
+	require.ErrorIs(t, err, errZeroTraceID)
+	assert.Contains(t, err.Error(), `invalid span "GET /"`)
+
+	traces, span = makeTraces()
+	span.SetSpanID(pcommon.NewSpanIDEmpty())
+	_, err = ProtoFromTraces(traces)
+	require.ErrorIs(t, err, errZeroSpanID)
+}
@@ -54,6 +54,29 @@ type Trace struct {
 	Spans     []Span                `json:"spans"`
 	Processes map[ProcessID]Process `json:"processes"`
 	Warnings  []string              `json:"warnings"`
+	// CriticalPath is only set when requested.
+	CriticalPath []CriticalPathSection `json:"criticalPath,omitempty"`
+}
+
+// CriticalPathSection is the time interval in which a span was on the critical path of the trace.
+// SectionStart and SectionEnd are in microseconds since Unix epoch.
+type CriticalPathSection struct {
+	SpanID       SpanID `json:"spanID"`
+	SectionStart uint64 `json:"sectionStart"`
+	SectionEnd   uint64 `json:"sectionEnd"`
+}
+
+// TraceSummary is an overview of a trace without its spans.
+// StartTime is in microseconds since Unix epoch, Duration is in microseconds.
+type TraceSummary struct {
+	TraceID           TraceID        `json:"traceID"`
+	RootServiceName   string         `json:"rootServiceName"`
+	RootOperationName string         `json:"rootOperationName"`
+	StartTime         uint64         `json:"startTime"`
+	Duration          uint64         `json:"duration"`
+	SpanCount         int            `json:"spanCount"`
+	ServiceSpanCounts map[string]int `json:"serviceSpanCounts"`
+	ErrorCount        int            `json:"errorCount"`
 }
 
 // Span is a span denoting a piece of work in some infrastructure
@@ -88,6 +88,17 @@ func (s *Span) IsRPCServer() bool {
 	return s.HasSpanKind(ext.SpanKindRPCServerEnum)
 }
 
+// IsError returns true if the span has an `error` tag set to true.
+func (s *Span) IsError() bool {
+	if tag, ok := KeyValues(s.Tags).FindByKey(string(ext.Error)); ok {
+		if tag.VType == ValueType_BOOL {
+			return tag.Bool()
+		}
+		return tag.AsString() == "true"
+	}
+	return false
+}
+
 // NormalizeTimestamps changes all timestamps in this span to UTC.
 func (s *Span) NormalizeTimestamps() {
 	s.StartTime = s.StartTime.UTC()
@@ -198,6 +198,23 @@ func TestIsRPCClientServer(t *testing.T) {
 	assert.False(t, span2.IsRPCServer())
 }
 
+func TestIsError(t *testing.T) {
+	testCases := []struct {
+		tags     model.KeyValues
+		expected bool
+	}{
+		{tags: model.KeyValues{model.Bool(string(ext.Error), true)}, expected: true},
+		{tags: model.KeyValues{model.String(string(ext.Error), "true")}, expected: true},
+		{tags: model.KeyValues{model.Bool(string(ext.Error), false)}, expected: false},
+		{tags: model.KeyValues{model.String(string(ext.Error), "false")}, expected: false},
+		{tags: model.KeyValues{}, expected: false},
+	}
+	for _, testCase := range testCases {
+		span := &model.Span{Tags: testCase.tags}
+		assert.Equal(t, testCase.expected, span.IsError(), testCase.tags)
+	}
+}
+
 func TestIsDebug(t *testing.T) {
 	flags := model.Flags(0)
 	flags.SetDebug()
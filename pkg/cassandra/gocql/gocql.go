@@ -88,6 +88,11 @@ func (q CQLQuery) PageSize(n int) cassandra.Query {
 	return WrapCQLQuery(q.query.PageSize(n))
 }
 
+// PageState delegates to gocql.Query#PageState and wraps the result as Query.
+func (q CQLQuery) PageState(state []byte) cassandra.Query {
+	return WrapCQLQuery(q.query.PageState(state))
+}
+
 // ---
 
 // CQLIterator is a wrapper around gocql.Iter.
@@ -109,3 +114,8 @@ func (i CQLIterator) Scan(dest ...interface{}) bool {
 func (i CQLIterator) Close() error {
 	return i.iter.Close()
 }
+
+// PageState delegates to gocql.Iter#PageState.
+func (i CQLIterator) PageState() []byte {
+	return i.iter.PageState()
+}
@@ -71,6 +71,22 @@ func (_m *Query) PageSize(n int) cassandra.Query {
 	return r0
 }
 
+// PageState provides a mock function with given fields: state
+func (_m *Query) PageState(state []byte) cassandra.Query {
+	ret := _m.Called(state)
+
+	var r0 cassandra.Query
+	if rf, ok := ret.Get(0).(func([]byte) cassandra.Query); ok {
+		r0 = rf(state)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cassandra.Query)
+		}
+	}
+
+	return r0
+}
+
 // Exec provides a mock function with given fields:
 func (_m *Query) Exec() error {
 	ret := _m.Called()
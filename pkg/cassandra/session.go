@@ -64,10 +64,12 @@ type Query interface {
 	Bind(v ...interface{}) Query
 	Consistency(level Consistency) Query
 	PageSize(int) Query
+	PageState([]byte) Query
 }
 
 // Iterator is an abstraction of gocql.Iter
 type Iterator interface {
 	Scan(dest ...interface{}) bool
 	Close() error
+	PageState() []byte
 }
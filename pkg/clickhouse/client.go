@@ -0,0 +1,160 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clickhouse provides a client for the HTTP interface of ClickHouse.
+package clickhouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxErrorLength limits how much of the body of a failed request is kept in the error.
+const maxErrorLength = 4096
+
+var errNoServers = errors.New("no ClickHouse servers configured")
+
+// Params holds the values of the query parameters of a statement, e.g. "service"
+// for {service:String}. ClickHouse substitutes them on the server, so they are
+// never interpolated into the statement text.
+type Params map[string]string
+
+// Client executes statements against ClickHouse.
+type Client interface {
+	// Exec executes a statement that returns no rows. If data is not nil it is sent
+	// as the input of the statement, e.g. the rows of an INSERT ... FORMAT RowBinary.
+	Exec(ctx context.Context, query string, params Params, data []byte) error
+
+	// Query executes a statement and calls scan for every row of the result, encoded as a JSON object.
+	Query(ctx context.Context, query string, params Params, scan func(row []byte) error) error
+}
+
+// HTTPClient implements Client with the HTTP interface of ClickHouse.
+// Servers are tried in turn until one of them can be reached.
+type HTTPClient struct {
+	Servers  []string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+var _ Client = (*HTTPClient)(nil)
+
+// Exec implements Client.
+func (c *HTTPClient) Exec(ctx context.Context, query string, params Params, data []byte) error {
+	resp, err := c.post(ctx, query, params, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// Query implements Client.
+func (c *HTTPClient) Query(ctx context.Context, query string, params Params, scan func(row []byte) error) error {
+	resp, err := c.post(ctx, query, params, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var row json.RawMessage
+		if err := decoder.Decode(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("cannot decode the result of the query: %w", err)
+		}
+		if err := scan(row); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *HTTPClient) post(ctx context.Context, query string, params Params, data []byte) (*http.Response, error) {
+	values := url.Values{}
+	values.Set("default_format", "JSONEachRow")
+	values.Set("output_format_json_quote_64bit_integers", "0")
+	values.Set("enable_http_compression", "1")
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+	body := []byte(query)
+	gzipped := false
+	if data != nil {
+		// the statement cannot be in the body with its input
+		values.Set("query", query)
+		compressed, err := compress(data)
+		if err != nil {
+			return nil, err
+		}
+		body, gzipped = compressed, true
+	}
+	if len(c.Servers) == 0 {
+		return nil, errNoServers
+	}
+
+	var errs []error
+	for _, server := range c.Servers {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(server, "/")+"/?"+values.Encode(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if gzipped {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		if c.Username != "" {
+			req.Header.Set("X-ClickHouse-User", c.Username)
+			req.Header.Set("X-ClickHouse-Key", c.Password)
+		}
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			// try the next server
+			errs = append(errs, err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+			resp.Body.Close()
+			return nil, fmt.Errorf("ClickHouse server %s returned %s: %s", server, resp.Status, strings.TrimSpace(string(message)))
+		}
+		return resp, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	query    string
+	params   map[string]string
+	body     string
+	user     string
+	password string
+}
+
+func newTestServer(t *testing.T, status int, response string) (*httptest.Server, *[]recordedRequest) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+		data, err := io.ReadAll(body)
+		require.NoError(t, err)
+		req := recordedRequest{
+			params:   map[string]string{},
+			user:     r.Header.Get("X-ClickHouse-User"),
+			password: r.Header.Get("X-ClickHouse-Key"),
+		}
+		for name, values := range r.URL.Query() {
+			req.params[name] = values[0]
+		}
+		if query, ok := req.params["query"]; ok {
+			req.query, req.body = query, string(data)
+		} else {
+			req.query = string(data)
+		}
+		requests = append(requests, req)
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPClientQuery(t *testing.T) {
+	server, requests := newTestServer(t, http.StatusOK, "{\"name\":\"a\"}\n{\"name\":\"b\"}\n")
+	c := &HTTPClient{Servers: []string{server.URL + "/"}, Username: "user", Password: "secret", Client: server.Client()}
+
+	var rows []string
+	err := c.Query(context.Background(), "SELECT name FROM t WHERE x = {x:String}", Params{"x": "y"}, func(row []byte) error {
+		rows = append(rows, string(row))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"name":"a"}`, `{"name":"b"}`}, rows)
+
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "SELECT name FROM t WHERE x = {x:String}", req.query)
+	assert.Equal(t, "y", req.params["param_x"])
+	assert.Equal(t, "JSONEachRow", req.params["default_format"])
+	assert.Equal(t, "user", req.user)
+	assert.Equal(t, "secret", req.password)
+}
+
+func TestHTTPClientQueryErrors(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusOK, "{\"name\":")
+	c := &HTTPClient{Servers: []string{server.URL}, Client: server.Client()}
+	err := c.Query(context.Background(), "SELECT 1", nil, func(row []byte) error { return nil })
+	assert.ErrorContains(t, err, "cannot decode the result of the query")
+
+	server, _ = newTestServer(t, http.StatusOK, "{}")
+	c = &HTTPClient{Servers: []string{server.URL}, Client: server.Client()}
+	scanErr := errors.New("scan error")
+	err = c.Query(context.Background(), "SELECT 1", nil, func(row []byte) error { return scanErr })
+	assert.ErrorIs(t, err, scanErr)
+
+	server, _ = newTestServer(t, http.StatusBadRequest, "Code: 62. DB::Exception: Syntax error\n")
+	c = &HTTPClient{Servers: []string{server.URL}, Client: server.Client()}
+	err = c.Query(context.Background(), "SELEC 1", nil, func(row []byte) error { return nil })
+	assert.ErrorContains(t, err, "400 Bad Request: Code: 62. DB::Exception: Syntax error")
+}
+
+func TestHTTPClientExec(t *testing.T) {
+	server, requests := newTestServer(t, http.StatusOK, "")
+	c := &HTTPClient{Servers: []string{server.URL}, Client: server.Client()}
+
+	require.NoError(t, c.Exec(context.Background(), "INSERT INTO t FORMAT JSONEachRow", nil, []byte(`{"name":"a"}`)))
+	require.NoError(t, c.Exec(context.Background(), "TRUNCATE TABLE t", nil, nil))
+
+	require.Len(t, *requests, 2)
+	assert.Equal(t, "INSERT INTO t FORMAT JSONEachRow", (*requests)[0].query)
+	assert.Equal(t, `{"name":"a"}`, (*requests)[0].body)
+	assert.Equal(t, "TRUNCATE TABLE t", (*requests)[1].query)
+	assert.Empty(t, (*requests)[1].user)
+}
+
+func TestHTTPClientFailover(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	server, requests := newTestServer(t, http.StatusOK, "")
+	c := &HTTPClient{Servers: []string{down.URL, server.URL}, Client: server.Client()}
+
+	require.NoError(t, c.Exec(context.Background(), "SELECT 1", nil, nil))
+	assert.Len(t, *requests, 1)
+
+	c.Servers = []string{down.URL}
+	assert.Error(t, c.Exec(context.Background(), "SELECT 1", nil, nil))
+
+	c.Servers = nil
+	assert.ErrorIs(t, c.Exec(context.Background(), "SELECT 1", nil, nil), errNoServers)
+}
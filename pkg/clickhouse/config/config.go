@@ -0,0 +1,91 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/pkg/config/tlscfg"
+)
+
+// Configuration describes the configuration properties needed to connect to ClickHouse
+// and to store the spans in it.
+type Configuration struct {
+	Servers            []string       `mapstructure:"servers"`
+	Database           string         `mapstructure:"database"`
+	Username           string         `mapstructure:"username"`
+	Password           string         `mapstructure:"password" json:"-"`
+	Timeout            time.Duration  `mapstructure:"timeout"`
+	TablePrefix        string         `mapstructure:"table_prefix"`
+	TTL                time.Duration  `mapstructure:"ttl"`
+	CreateSchema       bool           `mapstructure:"create_schema"`
+	BatchSize          int            `mapstructure:"batch_size"`
+	BatchFlushInterval time.Duration  `mapstructure:"batch_flush_interval"`
+	Enabled            bool           `mapstructure:"-"`
+	TLS                tlscfg.Options `mapstructure:"tls"`
+}
+
+// ApplyDefaults copies settings from source unless its own value is non-zero.
+func (c *Configuration) ApplyDefaults(source *Configuration) {
+	if len(c.Servers) == 0 {
+		c.Servers = source.Servers
+	}
+	if c.Database == "" {
+		c.Database = source.Database
+	}
+	if c.Username == "" {
+		c.Username = source.Username
+	}
+	if c.Password == "" {
+		c.Password = source.Password
+	}
+	if c.Timeout == 0 {
+		c.Timeout = source.Timeout
+	}
+	if c.TablePrefix == "" {
+		c.TablePrefix = source.TablePrefix
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = source.BatchSize
+	}
+	if c.BatchFlushInterval == 0 {
+		c.BatchFlushInterval = source.BatchFlushInterval
+	}
+}
+
+// NewClient creates a client for the HTTP interface of the ClickHouse servers.
+func (c *Configuration) NewClient(logger *zap.Logger) (*clickhouse.HTTPClient, error) {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if c.TLS.Enabled {
+		tlsConfig, err := c.TLS.Config(logger)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &clickhouse.HTTPClient{
+		Servers:  c.Servers,
+		Username: c.Username,
+		Password: c.Password,
+		Client: &http.Client{
+			Timeout:   c.Timeout,
+			Transport: transport,
+		},
+	}, nil
+}
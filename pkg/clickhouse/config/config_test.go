@@ -0,0 +1,71 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/config/tlscfg"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	source := &Configuration{
+		Servers:            []string{"http://primary:8123"},
+		Database:           "jaeger",
+		Username:           "user",
+		Password:           "secret",
+		Timeout:            time.Second,
+		TablePrefix:        "jaeger",
+		TTL:                time.Hour,
+		BatchSize:          100,
+		BatchFlushInterval: time.Second,
+	}
+	cfg := &Configuration{Database: "archive"}
+	cfg.ApplyDefaults(source)
+	assert.Equal(t, source.Servers, cfg.Servers)
+	assert.Equal(t, "archive", cfg.Database)
+	assert.Equal(t, "user", cfg.Username)
+	assert.Equal(t, "secret", cfg.Password)
+	assert.Equal(t, time.Second, cfg.Timeout)
+	assert.Equal(t, "jaeger", cfg.TablePrefix)
+	assert.Equal(t, 100, cfg.BatchSize)
+	assert.Equal(t, time.Second, cfg.BatchFlushInterval)
+	// the retention of a namespace is never inherited
+	assert.Zero(t, cfg.TTL)
+}
+
+func TestNewClient(t *testing.T) {
+	cfg := &Configuration{
+		Servers:  []string{"http://localhost:8123"},
+		Username: "user",
+		Password: "secret",
+		Timeout:  time.Second,
+	}
+	client, err := cfg.NewClient(zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Servers, client.Servers)
+	assert.Equal(t, "user", client.Username)
+	assert.Equal(t, "secret", client.Password)
+	assert.Equal(t, time.Second, client.Client.Timeout)
+
+	cfg.TLS = tlscfg.Options{Enabled: true, CAPath: "/does/not/exist"}
+	_, err = cfg.NewClient(zap.NewNop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+)
+
+// RowBinary encodes rows in the RowBinary format, the input of an INSERT ... FORMAT RowBinary.
+// The values of the columns of every row are appended in the order of the columns of the
+// statement, without any delimiter. ClickHouse parses it much faster than the text formats.
+type RowBinary struct {
+	data []byte
+}
+
+// Bytes returns the encoded rows.
+func (b *RowBinary) Bytes() []byte {
+	return b.data
+}
+
+// Len returns the length of the encoded rows.
+func (b *RowBinary) Len() int {
+	return len(b.data)
+}
+
+// Reset removes the encoded rows.
+func (b *RowBinary) Reset() {
+	b.data = b.data[:0]
+}
+
+// Append appends the rows encoded by other.
+func (b *RowBinary) Append(other *RowBinary) {
+	b.data = append(b.data, other.data...)
+}
+
+// String appends a String or LowCardinality(String) value.
+func (b *RowBinary) String(value string) {
+	b.data = binary.AppendUvarint(b.data, uint64(len(value)))
+	b.data = append(b.data, value...)
+}
+
+// UInt64 appends a UInt64 value.
+func (b *RowBinary) UInt64(value uint64) {
+	b.data = binary.LittleEndian.AppendUint64(b.data, value)
+}
+
+// DateTime64 appends a DateTime64(precision) value, the number of 10^-precision seconds since the epoch.
+func (b *RowBinary) DateTime64(value time.Time, precision int) {
+	ticks := value.UnixNano()
+	for i := precision; i < 9; i++ {
+		ticks /= 10
+	}
+	b.data = binary.LittleEndian.AppendUint64(b.data, uint64(ticks))
+}
+
+// StringArrayMap appends a Map(String, Array(String)) value, sorted by key.
+func (b *RowBinary) StringArrayMap(value map[string][]string) {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	b.data = binary.AppendUvarint(b.data, uint64(len(keys)))
+	for _, key := range keys {
+		b.String(key)
+		b.data = binary.AppendUvarint(b.data, uint64(len(value[key])))
+		for _, v := range value[key] {
+			b.String(v)
+		}
+	}
+}
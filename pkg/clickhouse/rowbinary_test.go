@@ -0,0 +1,49 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowBinary(t *testing.T) {
+	var b RowBinary
+	b.String("ab")
+	b.UInt64(0x0102)
+	b.DateTime64(time.Unix(1, 2003000), 6)
+	b.DateTime64(time.Unix(1, 2003000), 3)
+	b.StringArrayMap(map[string][]string{"k2": {}, "k1": {"a", "b"}})
+	assert.Equal(t, []byte{
+		2, 'a', 'b',
+		0x02, 0x01, 0, 0, 0, 0, 0, 0,
+		0x13, 0x4a, 0x0f, 0, 0, 0, 0, 0, // 1002003 microseconds
+		0xea, 0x03, 0, 0, 0, 0, 0, 0, // 1002 milliseconds
+		2, 2, 'k', '1', 2, 1, 'a', 1, 'b', 2, 'k', '2', 0,
+	}, b.Bytes())
+
+	var other RowBinary
+	other.String(strings.Repeat("x", 200))
+	// the lengths are variable-length integers
+	assert.Equal(t, []byte{0xc8, 0x01, 'x'}, other.Bytes()[:3])
+	b.Append(&other)
+	assert.Equal(t, 40+202, b.Len())
+
+	b.Reset()
+	assert.Zero(t, b.Len())
+}
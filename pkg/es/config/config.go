@@ -76,6 +76,8 @@ type Configuration struct {
 	Version                        uint           `mapstructure:"version"`
 	LogLevel                       string         `mapstructure:"log_level"`
 	SendGetBodyAs                  string         `mapstructure:"send_get_body_as"`
+	// BulkFailureHandler is called after each bulk request which failed, altogether or in part
+	BulkFailureHandler func(requests []elastic.BulkableRequest, response *elastic.BulkResponse, err error) `mapstructure:"-" json:"-"`
 }
 
 // TagsAsFields holds configuration for tag schema.
@@ -133,6 +135,10 @@ func NewClient(c *Configuration, logger *zap.Logger, metricsFactory metrics.Fact
 				}
 			}
 
+			if c.BulkFailureHandler != nil && (err != nil || (response != nil && response.Errors)) {
+				c.BulkFailureHandler(requests, response, err)
+			}
+
 			sm.Emit(err, time.Since(start.(time.Time)))
 			if err != nil {
 				var failed int
@@ -0,0 +1,608 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+)
+
+const (
+	segmentSuffix  = ".segment"
+	checkpointFile = "checkpoint"
+	// recordHeaderSize is the size of the length and the CRC-32 of the payload preceding each record
+	recordHeaderSize = 8
+)
+
+// SyncPolicy defines when the items written to the queue are synced to disk.
+type SyncPolicy string
+
+const (
+	// SyncAlways syncs each item before Produce returns, and checkpoints each item passed to a consumer.
+	SyncAlways SyncPolicy = "always"
+	// SyncPeriodically syncs the items and the checkpoint every SyncInterval.
+	SyncPeriodically SyncPolicy = "periodic"
+	// SyncNever lets the operating system write the items to disk, and checkpoints when a segment is deleted.
+	SyncNever SyncPolicy = "never"
+)
+
+// PersistentQueueOptions configures a PersistentQueue.
+type PersistentQueueOptions struct {
+	// Directory holds the segment files of the queue.
+	Directory string
+	// Capacity is the maximum number of items in the queue.
+	Capacity int
+	// MaxBytes is the maximum size of the segment files, the items that would exceed it are dropped.
+	MaxBytes int64
+	// SegmentBytes is the size after which a new segment file is started.
+	SegmentBytes int64
+	// SyncPolicy defines when the items are synced to disk.
+	SyncPolicy SyncPolicy
+	// SyncInterval is the period of SyncPeriodically.
+	SyncInterval time.Duration
+}
+
+type persistentQueueMetrics struct {
+	// DiskBytes is the size of the segment files
+	DiskBytes metrics.Gauge `metric:"persistent_queue.disk_bytes"`
+	// ReplayedItems is the number of items found in the segment files when the queue is opened
+	ReplayedItems metrics.Counter `metric:"persistent_queue.replayed_items"`
+	// CorruptSegments is the number of segment files truncated at an invalid record when the queue is opened
+	CorruptSegments metrics.Counter `metric:"persistent_queue.corrupt_segments"`
+	// CorruptItems is the number of records that could not be unmarshalled
+	CorruptItems metrics.Counter `metric:"persistent_queue.corrupt_items"`
+}
+
+// position is the offset of a record in a segment file.
+type position struct {
+	segment uint64
+	offset  int64
+}
+
+// PersistentQueue is a Queue writing its items to segment files on disk, so that the items
+// not consumed when the process stops or crashes are consumed after it restarts. The items
+// are written by marshal and read back by unmarshal. The segment files are deleted once all
+// of their items have been passed to the consumers. The delivery is at-least-once: on crash
+// the items being consumed can be lost, and the items passed to the consumers since the last
+// checkpoint are consumed again, which is at most one segment with SyncNever.
+type PersistentQueue struct {
+	options       PersistentQueueOptions
+	marshal       func(item interface{}) ([]byte, error)
+	unmarshal     func(data []byte) (interface{}, error)
+	onDroppedItem func(item interface{})
+	logger        *zap.Logger
+	metrics       persistentQueueMetrics
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	capacity int
+	size     int   // number of items not yet passed to a consumer
+	bytes    int64 // size of the segment files
+	stopped  bool
+	segments []uint64 // ids of the segment files, ascending
+
+	writer     *os.File
+	writerSize int64
+
+	reader    *bufio.Reader
+	readFile  *os.File
+	readPos   position // position of the next record to read
+	delivered position // position following the last record passed to a consumer
+
+	items    chan interface{}
+	stopCh   chan struct{}
+	stopWG   sync.WaitGroup
+	started  bool
+	syncDone chan struct{}
+}
+
+// NewPersistentQueue opens the queue in options.Directory, which is created if needed. The
+// items left in the segment files are consumed first once the consumers are started.
+func NewPersistentQueue(
+	options PersistentQueueOptions,
+	marshal func(item interface{}) ([]byte, error),
+	unmarshal func(data []byte) (interface{}, error),
+	onDroppedItem func(item interface{}),
+	metricsFactory metrics.Factory,
+	logger *zap.Logger,
+) (*PersistentQueue, error) {
+	if options.MaxBytes <= 0 {
+		return nil, fmt.Errorf("invalid max size %d", options.MaxBytes)
+	}
+	if options.SegmentBytes <= 0 {
+		return nil, fmt.Errorf("invalid segment size %d", options.SegmentBytes)
+	}
+	if options.SyncPolicy == SyncPeriodically && options.SyncInterval <= 0 {
+		return nil, fmt.Errorf("invalid sync interval %v", options.SyncInterval)
+	}
+	if err := os.MkdirAll(options.Directory, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create queue directory: %w", err)
+	}
+	q := &PersistentQueue{
+		options:       options,
+		marshal:       marshal,
+		unmarshal:     unmarshal,
+		onDroppedItem: onDroppedItem,
+		logger:        logger,
+		capacity:      options.Capacity,
+		items:         make(chan interface{}),
+		stopCh:        make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	metrics.MustInit(&q.metrics, metricsFactory, nil)
+	if err := q.recover(); err != nil {
+		q.closeFiles()
+		return nil, err
+	}
+	if err := q.startSegment(); err != nil {
+		q.closeFiles()
+		return nil, err
+	}
+	q.metrics.DiskBytes.Update(q.bytes)
+	return q, nil
+}
+
+// recover validates the existing segment files and positions the reader after the checkpoint.
+func (q *PersistentQueue) recover() error {
+	entries, err := os.ReadDir(q.options.Directory)
+	if err != nil {
+		return fmt.Errorf("cannot list queue directory: %w", err)
+	}
+	for _, entry := range entries {
+		id, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), segmentSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(entry.Name(), segmentSuffix) {
+			continue
+		}
+		q.segments = append(q.segments, id)
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i] < q.segments[j] })
+
+	checkpoint, err := q.readCheckpoint()
+	if err != nil {
+		return err
+	}
+	var remaining []uint64
+	for _, id := range q.segments {
+		if id < checkpoint.segment {
+			// consumed before the checkpoint was written
+			if err := os.Remove(q.segmentPath(id)); err != nil {
+				return fmt.Errorf("cannot delete consumed segment: %w", err)
+			}
+			continue
+		}
+		start := int64(0)
+		if id == checkpoint.segment {
+			start = checkpoint.offset
+		}
+		count, size, err := q.validateSegment(id, start)
+		if err != nil {
+			return err
+		}
+		q.size += count
+		q.bytes += size
+		remaining = append(remaining, id)
+	}
+	q.segments = remaining
+	q.metrics.ReplayedItems.Inc(int64(q.size))
+
+	if len(q.segments) > 0 {
+		q.readPos = position{segment: q.segments[0]}
+		if q.segments[0] == checkpoint.segment {
+			q.readPos.offset = checkpoint.offset
+		}
+		q.delivered = q.readPos
+		return q.openReader()
+	}
+	return nil
+}
+
+// validateSegment counts the records from start, and truncates the segment at the first invalid record.
+func (q *PersistentQueue) validateSegment(id uint64, start int64) (count int, size int64, err error) {
+	f, err := os.OpenFile(q.segmentPath(id), os.O_RDWR, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot open segment: %w", err)
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		payload, err := readRecord(reader, q.options.MaxBytes)
+		if err == io.EOF {
+			return count, offset, nil
+		}
+		if err != nil {
+			q.logger.Warn("Truncating corrupt queue segment",
+				zap.String("segment", q.segmentPath(id)), zap.Int64("offset", offset), zap.Error(err))
+			q.metrics.CorruptSegments.Inc(1)
+			if err := f.Truncate(offset); err != nil {
+				return 0, 0, fmt.Errorf("cannot truncate corrupt segment: %w", err)
+			}
+			return count, offset, nil
+		}
+		if offset >= start {
+			count++
+		}
+		offset += int64(recordHeaderSize + len(payload))
+	}
+}
+
+func (q *PersistentQueue) readCheckpoint() (position, error) {
+	data, err := os.ReadFile(filepath.Join(q.options.Directory, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return position{}, nil
+	}
+	if err != nil {
+		return position{}, fmt.Errorf("cannot read queue checkpoint: %w", err)
+	}
+	if len(data) != 16 {
+		q.logger.Warn("Ignoring invalid queue checkpoint")
+		return position{}, nil
+	}
+	return position{
+		segment: binary.BigEndian.Uint64(data[:8]),
+		offset:  int64(binary.BigEndian.Uint64(data[8:])),
+	}, nil
+}
+
+// writeCheckpoint records the position of the first item not passed to a consumer, the lock must be held.
+func (q *PersistentQueue) writeCheckpoint() error {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[:8], q.delivered.segment)
+	binary.BigEndian.PutUint64(data[8:], uint64(q.delivered.offset))
+	path := filepath.Join(q.options.Directory, checkpointFile)
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (q *PersistentQueue) segmentPath(id uint64) string {
+	return filepath.Join(q.options.Directory, fmt.Sprintf("%020d%s", id, segmentSuffix))
+}
+
+// startSegment closes the current segment file and starts a new one, the lock must be held.
+func (q *PersistentQueue) startSegment() error {
+	if q.writer != nil {
+		if err := q.writer.Sync(); err != nil {
+			return err
+		}
+		if err := q.writer.Close(); err != nil {
+			return err
+		}
+	}
+	var id uint64 = 1
+	if len(q.segments) > 0 {
+		id = q.segments[len(q.segments)-1] + 1
+	}
+	writer, err := os.OpenFile(q.segmentPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot create segment: %w", err)
+	}
+	q.writer = writer
+	q.writerSize = 0
+	q.segments = append(q.segments, id)
+	if q.readFile == nil {
+		q.readPos = position{segment: id}
+		q.delivered = q.readPos
+		return q.openReader()
+	}
+	return nil
+}
+
+// openReader opens the segment of readPos at its offset, the lock must be held.
+func (q *PersistentQueue) openReader() error {
+	f, err := os.Open(q.segmentPath(q.readPos.segment))
+	if err != nil {
+		return fmt.Errorf("cannot open segment: %w", err)
+	}
+	if _, err := f.Seek(q.readPos.offset, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot seek segment: %w", err)
+	}
+	q.readFile = f
+	q.reader = bufio.NewReader(f)
+	return nil
+}
+
+// readRecord reads the next record, whose size cannot exceed maxBytes as Produce would have dropped it.
+func readRecord(reader *bufio.Reader, maxBytes int64) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated record header")
+		}
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if int64(length) > maxBytes-recordHeaderSize {
+		// a corrupt length must not be allocated
+		return nil, fmt.Errorf("record length %d exceeds the maximum size", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, errors.New("truncated record")
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errors.New("record checksum mismatch")
+	}
+	return payload, nil
+}
+
+// StartConsumers starts a given number of goroutines consuming items from the queue
+// and passing them into the consumer callback.
+func (q *PersistentQueue) StartConsumers(num int, callback func(item interface{})) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+	for i := 0; i < num; i++ {
+		q.stopWG.Add(1)
+		go func() {
+			defer q.stopWG.Done()
+			for {
+				select {
+				case item := <-q.items:
+					callback(item)
+				case <-q.stopCh:
+					return
+				}
+			}
+		}()
+	}
+	q.stopWG.Add(1)
+	go q.dispatch()
+	if q.options.SyncPolicy == SyncPeriodically {
+		q.syncDone = make(chan struct{})
+		go q.syncPeriodically()
+	}
+}
+
+// dispatch reads the items in order and passes them to the consumers.
+func (q *PersistentQueue) dispatch() {
+	defer q.stopWG.Done()
+	for {
+		q.mu.Lock()
+		for q.size == 0 && !q.stopped {
+			q.notEmpty.Wait()
+		}
+		if q.stopped {
+			q.mu.Unlock()
+			return
+		}
+		payload, next, err := q.next()
+		q.mu.Unlock()
+		if err != nil {
+			q.logger.Error("Failed to read the queue", zap.Error(err))
+			// avoid spinning on a persistent error
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-q.stopCh:
+				return
+			}
+		}
+		item, err := q.unmarshal(payload)
+		if err != nil {
+			q.metrics.CorruptItems.Inc(1)
+			q.logger.Error("Failed to unmarshal queue item", zap.Error(err))
+			q.markDelivered(next)
+			continue
+		}
+		select {
+		case q.items <- item:
+			q.markDelivered(next)
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+// next reads the next record, deleting the segments that were read completely, the lock must be held.
+func (q *PersistentQueue) next() ([]byte, position, error) {
+	for {
+		payload, err := readRecord(q.reader, q.options.MaxBytes)
+		if err == io.EOF && q.readPos.segment != q.segments[len(q.segments)-1] {
+			if err := q.deleteReadSegment(); err != nil {
+				return nil, position{}, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, position{}, err
+		}
+		q.size--
+		q.readPos.offset += int64(recordHeaderSize + len(payload))
+		return payload, q.readPos, nil
+	}
+}
+
+// deleteReadSegment deletes the segment that was read completely and opens the next one, the lock must be held.
+func (q *PersistentQueue) deleteReadSegment() error {
+	q.readFile.Close()
+	if err := os.Remove(q.segmentPath(q.readPos.segment)); err != nil {
+		return fmt.Errorf("cannot delete consumed segment: %w", err)
+	}
+	q.bytes -= q.readPos.offset
+	q.metrics.DiskBytes.Update(q.bytes)
+	q.segments = q.segments[1:]
+	q.readPos = position{segment: q.segments[0]}
+	q.delivered = q.readPos
+	if err := q.writeCheckpoint(); err != nil {
+		q.logger.Error("Failed to write the queue checkpoint", zap.Error(err))
+	}
+	return q.openReader()
+}
+
+func (q *PersistentQueue) markDelivered(pos position) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.delivered = pos
+	if q.options.SyncPolicy == SyncAlways {
+		if err := q.writeCheckpoint(); err != nil {
+			q.logger.Error("Failed to write the queue checkpoint", zap.Error(err))
+		}
+	}
+}
+
+func (q *PersistentQueue) syncPeriodically() {
+	defer close(q.syncDone)
+	ticker := time.NewTicker(q.options.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.mu.Lock()
+			if err := q.sync(); err != nil {
+				q.logger.Error("Failed to sync the queue", zap.Error(err))
+			}
+			q.mu.Unlock()
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+// sync syncs the current segment and the checkpoint, the lock must be held.
+func (q *PersistentQueue) sync() error {
+	if err := q.writer.Sync(); err != nil {
+		return err
+	}
+	return q.writeCheckpoint()
+}
+
+// Produce writes the item to the queue. It returns false if the item could not be
+// written, or if it exceeds the capacity or the maximum size of the queue.
+func (q *PersistentQueue) Produce(item interface{}) bool {
+	payload, err := q.marshal(item)
+	if err != nil {
+		q.logger.Error("Failed to marshal queue item", zap.Error(err))
+		q.drop(item)
+		return false
+	}
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped || q.size >= q.capacity || q.bytes+int64(len(record)) > q.options.MaxBytes {
+		q.drop(item)
+		return false
+	}
+	if _, err := q.writer.Write(record); err != nil {
+		q.logger.Error("Failed to write queue item", zap.Error(err))
+		q.drop(item)
+		return false
+	}
+	if q.options.SyncPolicy == SyncAlways {
+		if err := q.writer.Sync(); err != nil {
+			q.logger.Error("Failed to sync the queue", zap.Error(err))
+		}
+	}
+	q.writerSize += int64(len(record))
+	q.bytes += int64(len(record))
+	q.size++
+	q.metrics.DiskBytes.Update(q.bytes)
+	q.notEmpty.Signal()
+	if q.writerSize >= q.options.SegmentBytes {
+		if err := q.startSegment(); err != nil {
+			q.logger.Error("Failed to start a new queue segment", zap.Error(err))
+		}
+	}
+	return true
+}
+
+func (q *PersistentQueue) drop(item interface{}) {
+	if q.onDroppedItem != nil {
+		q.onDroppedItem(item)
+	}
+}
+
+// Stop stops the consumers, syncs the items not consumed yet and closes the segment files.
+func (q *PersistentQueue) Stop() {
+	q.mu.Lock()
+	if q.stopped {
+		q.mu.Unlock()
+		return
+	}
+	q.stopped = true
+	close(q.stopCh)
+	q.notEmpty.Broadcast()
+	q.mu.Unlock()
+
+	q.stopWG.Wait()
+	if q.syncDone != nil {
+		<-q.syncDone
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.sync(); err != nil {
+		q.logger.Error("Failed to sync the queue", zap.Error(err))
+	}
+	q.closeFiles()
+}
+
+func (q *PersistentQueue) closeFiles() {
+	if q.writer != nil {
+		q.writer.Close()
+	}
+	if q.readFile != nil {
+		q.readFile.Close()
+	}
+}
+
+// Size returns the number of items not passed to a consumer yet.
+func (q *PersistentQueue) Size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// Capacity returns the maximum number of items in the queue.
+func (q *PersistentQueue) Capacity() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.capacity
+}
+
+// Resize changes the maximum number of items in the queue, the items already in the queue are kept.
+func (q *PersistentQueue) Resize(capacity int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if capacity == q.capacity {
+		return false
+	}
+	q.capacity = capacity
+	return true
+}
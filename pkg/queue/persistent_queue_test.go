@@ -0,0 +1,296 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+)
+
+type persistentQueueTest struct {
+	queue   *PersistentQueue
+	metrics *metricstest.Factory
+	dropped []string
+
+	mu       sync.Mutex
+	consumed []string
+}
+
+func openPersistentQueue(t *testing.T, options PersistentQueueOptions) *persistentQueueTest {
+	test := &persistentQueueTest{metrics: metricstest.NewFactory(0)}
+	q, err := NewPersistentQueue(
+		options,
+		func(item interface{}) ([]byte, error) {
+			if item.(string) == "" {
+				return nil, errors.New("empty item")
+			}
+			return []byte(item.(string)), nil
+		},
+		func(data []byte) (interface{}, error) { return string(data), nil },
+		func(item interface{}) { test.dropped = append(test.dropped, item.(string)) },
+		test.metrics,
+		zap.NewNop(),
+	)
+	require.NoError(t, err)
+	test.queue = q
+	return test
+}
+
+func (test *persistentQueueTest) startConsumers() {
+	test.queue.StartConsumers(1, func(item interface{}) {
+		test.mu.Lock()
+		defer test.mu.Unlock()
+		test.consumed = append(test.consumed, item.(string))
+	})
+}
+
+func (test *persistentQueueTest) waitConsumed(t *testing.T, n int) []string {
+	require.Eventually(t, func() bool {
+		test.mu.Lock()
+		defer test.mu.Unlock()
+		return len(test.consumed) == n
+	}, time.Second, time.Millisecond)
+	test.mu.Lock()
+	defer test.mu.Unlock()
+	return test.consumed
+}
+
+func persistentQueueOptions(t *testing.T) PersistentQueueOptions {
+	return PersistentQueueOptions{
+		Directory:    t.TempDir(),
+		Capacity:     100,
+		MaxBytes:     1024,
+		SegmentBytes: 1024,
+		SyncPolicy:   SyncAlways,
+	}
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	require.NoError(t, err)
+	return files
+}
+
+func TestPersistentQueueReplay(t *testing.T) {
+	options := persistentQueueOptions(t)
+	options.SegmentBytes = 20 // three items of 9 bytes per segment
+
+	test := openPersistentQueue(t, options)
+	for _, item := range []string{"a", "b", "c", "d", "e"} {
+		assert.True(t, test.queue.Produce(item))
+	}
+	assert.Equal(t, 5, test.queue.Size())
+	test.queue.Stop()
+	assert.False(t, test.queue.Produce("f"))
+	assert.Equal(t, []string{"f"}, test.dropped)
+	assert.Len(t, segmentFiles(t, options.Directory), 2)
+
+	test = openPersistentQueue(t, options)
+	assert.Equal(t, 5, test.queue.Size())
+	test.metrics.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "persistent_queue.replayed_items", Value: 5})
+	test.metrics.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "persistent_queue.disk_bytes", Value: 45})
+
+	test.startConsumers()
+	assert.True(t, test.queue.Produce("f"))
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, test.waitConsumed(t, 6))
+	test.queue.Stop()
+
+	// the consumed segments are deleted and the consumed items are not replayed
+	assert.Len(t, segmentFiles(t, options.Directory), 1)
+	test = openPersistentQueue(t, options)
+	assert.Equal(t, 0, test.queue.Size())
+	test.queue.Stop()
+}
+
+func TestPersistentQueueCorruptSegment(t *testing.T) {
+	options := persistentQueueOptions(t)
+	test := openPersistentQueue(t, options)
+	for _, item := range []string{"a", "b", "c"} {
+		assert.True(t, test.queue.Produce(item))
+	}
+	test.queue.Stop()
+
+	// corrupt the payload of the last item
+	segments := segmentFiles(t, options.Directory)
+	require.Len(t, segments, 1)
+	data, err := os.ReadFile(segments[0])
+	require.NoError(t, err)
+	data[len(data)-1] = 'x'
+	require.NoError(t, os.WriteFile(segments[0], data, 0o600))
+
+	test = openPersistentQueue(t, options)
+	assert.Equal(t, 2, test.queue.Size())
+	test.metrics.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "persistent_queue.replayed_items", Value: 2},
+		metricstest.ExpectedMetric{Name: "persistent_queue.corrupt_segments", Value: 1},
+	)
+	test.startConsumers()
+	assert.Equal(t, []string{"a", "b"}, test.waitConsumed(t, 2))
+	test.queue.Stop()
+}
+
+func TestPersistentQueueCorruptLength(t *testing.T) {
+	options := persistentQueueOptions(t)
+	test := openPersistentQueue(t, options)
+	for _, item := range []string{"a", "b"} {
+		assert.True(t, test.queue.Produce(item))
+	}
+	test.queue.Stop()
+
+	// the length of the last item exceeds the maximum size
+	segments := segmentFiles(t, options.Directory)
+	require.Len(t, segments, 1)
+	data, err := os.ReadFile(segments[0])
+	require.NoError(t, err)
+	copy(data[9:13], []byte{0xff, 0xff, 0xff, 0xff})
+	require.NoError(t, os.WriteFile(segments[0], data, 0o600))
+
+	test = openPersistentQueue(t, options)
+	assert.Equal(t, 1, test.queue.Size())
+	test.metrics.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "persistent_queue.corrupt_segments", Value: 1})
+	test.queue.Stop()
+}
+
+// copyQueueDirectory copies the files of the queue as they would be found after a crash.
+func copyQueueDirectory(t *testing.T, dir string) string {
+	crashed := t.TempDir()
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(crashed, filepath.Base(file)), data, 0o600))
+	}
+	return crashed
+}
+
+func checkpointAt(t *testing.T, dir string, segment uint64, offset int64) func() bool {
+	return func() bool {
+		data, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+		return err == nil && len(data) == 16 &&
+			binary.BigEndian.Uint64(data[:8]) == segment && binary.BigEndian.Uint64(data[8:]) == uint64(offset)
+	}
+}
+
+func TestPersistentQueueCheckpointOnDelivery(t *testing.T) {
+	options := persistentQueueOptions(t)
+	test := openPersistentQueue(t, options)
+	defer test.queue.Stop()
+	test.startConsumers()
+	for _, item := range []string{"a", "b", "c"} {
+		assert.True(t, test.queue.Produce(item))
+	}
+	test.waitConsumed(t, 3)
+	assert.Eventually(t, checkpointAt(t, options.Directory, 1, 27), time.Second, time.Millisecond)
+
+	// the consumed items are not replayed after a crash
+	options.Directory = copyQueueDirectory(t, options.Directory)
+	crashed := openPersistentQueue(t, options)
+	assert.Equal(t, 0, crashed.queue.Size())
+	crashed.queue.Stop()
+}
+
+func TestPersistentQueueCheckpointOnSegmentDeletion(t *testing.T) {
+	options := persistentQueueOptions(t)
+	options.SegmentBytes = 20 // three items of 9 bytes per segment
+	options.SyncPolicy = SyncNever
+	test := openPersistentQueue(t, options)
+	defer test.queue.Stop()
+	test.startConsumers()
+	for _, item := range []string{"a", "b", "c", "d", "e"} {
+		assert.True(t, test.queue.Produce(item))
+	}
+	test.waitConsumed(t, 5)
+	assert.Eventually(t, checkpointAt(t, options.Directory, 2, 0), time.Second, time.Millisecond)
+
+	// the items consumed from the current segment are replayed after a crash
+	options.Directory = copyQueueDirectory(t, options.Directory)
+	crashed := openPersistentQueue(t, options)
+	assert.Equal(t, 2, crashed.queue.Size())
+	crashed.startConsumers()
+	assert.Equal(t, []string{"d", "e"}, crashed.waitConsumed(t, 2))
+	crashed.queue.Stop()
+}
+
+func TestPersistentQueueLimits(t *testing.T) {
+	options := persistentQueueOptions(t)
+	options.Capacity = 2
+	options.MaxBytes = 30
+	test := openPersistentQueue(t, options)
+	defer test.queue.Stop()
+
+	assert.True(t, test.queue.Produce("a"))
+	assert.True(t, test.queue.Produce("b"))
+	assert.False(t, test.queue.Produce("c"), "capacity exceeded")
+	assert.True(t, test.queue.Resize(3))
+	assert.False(t, test.queue.Resize(3))
+	assert.Equal(t, 3, test.queue.Capacity())
+	assert.False(t, test.queue.Produce("long item"), "size exceeded")
+	assert.True(t, test.queue.Produce("d"))
+	assert.False(t, test.queue.Produce(""), "marshalling failed")
+	assert.Equal(t, []string{"c", "long item", ""}, test.dropped)
+}
+
+func TestPersistentQueuePeriodicSync(t *testing.T) {
+	options := persistentQueueOptions(t)
+	options.SyncPolicy = SyncPeriodically
+	options.SyncInterval = time.Millisecond
+	test := openPersistentQueue(t, options)
+	test.startConsumers()
+	assert.True(t, test.queue.Produce("a"))
+	test.waitConsumed(t, 1)
+
+	// the checkpoint is written without stopping the queue
+	checkpoint := filepath.Join(options.Directory, checkpointFile)
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(checkpoint)
+		return err == nil && len(data) == 16 && data[15] == 9
+	}, time.Second, time.Millisecond)
+	test.queue.Stop()
+}
+
+func TestNewPersistentQueueErrors(t *testing.T) {
+	options := persistentQueueOptions(t)
+	options.SegmentBytes = 0
+	_, err := NewPersistentQueue(options, nil, nil, nil, metricstest.NewFactory(0), zap.NewNop())
+	assert.EqualError(t, err, "invalid segment size 0")
+
+	options = persistentQueueOptions(t)
+	options.MaxBytes = 0
+	_, err = NewPersistentQueue(options, nil, nil, nil, metricstest.NewFactory(0), zap.NewNop())
+	assert.EqualError(t, err, "invalid max size 0")
+
+	options = persistentQueueOptions(t)
+	options.SyncPolicy = SyncPeriodically
+	_, err = NewPersistentQueue(options, nil, nil, nil, metricstest.NewFactory(0), zap.NewNop())
+	assert.EqualError(t, err, "invalid sync interval 0s")
+
+	options = persistentQueueOptions(t)
+	options.Directory = filepath.Join(options.Directory, "file")
+	require.NoError(t, os.WriteFile(options.Directory, nil, 0o600))
+	_, err = NewPersistentQueue(options, nil, nil, nil, metricstest.NewFactory(0), zap.NewNop())
+	assert.ErrorContains(t, err, "cannot create queue directory")
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/dgraph-io/badger/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// BackupPath is the path of the backup endpoint on the admin server.
+	BackupPath = "/storage/badger/backup"
+	// BackupSinceParam is the query parameter of the version after which the backup is incremental.
+	BackupSinceParam = "since"
+	// BackupNextSinceTrailer is the trailer of the version after which the next backup is incremental.
+	BackupNextSinceTrailer = "X-Badger-Backup-Next-Since"
+	// BackupErrorTrailer is the trailer of the error interrupting the backup.
+	BackupErrorTrailer = "X-Badger-Backup-Error"
+
+	maxPendingWrites = 256
+)
+
+// AdminHandlers implements storage.AdminHandlerFactory. The backup endpoint is
+// only served if enabled by the admin-backup.enabled flag.
+func (f *Factory) AdminHandlers() map[string]http.Handler {
+	if !f.Options.Primary.AdminBackup {
+		return nil
+	}
+	return map[string]http.Handler{
+		BackupPath: http.HandlerFunc(f.backup),
+	}
+}
+
+// backup streams an online backup of the storage. Since the response has already
+// started when the backup fails, the error is returned in a trailer.
+func (f *Factory) backup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var since uint64
+	if param := r.URL.Query().Get(BackupSinceParam); param != "" {
+		var err error
+		if since, err = strconv.ParseUint(param, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("malformed parameter %s: %v", BackupSinceParam, err), http.StatusBadRequest)
+			return
+		}
+	}
+	if f.store.IsClosed() {
+		http.Error(w, badger.ErrDBClosed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Trailer", BackupNextSinceTrailer+", "+BackupErrorTrailer)
+	version, err := f.store.Backup(w, since)
+	if err != nil {
+		f.logger.Error("Failed to back up the badger storage", zap.Error(err))
+		w.Header().Set(BackupErrorTrailer, err.Error())
+		return
+	}
+	// the version is the one of the latest entry in the backup, and the backups
+	// only include the entries newer than the since version
+	w.Header().Set(BackupNextSinceTrailer, strconv.FormatUint(version, 10))
+	f.logger.Info("Backed up the badger storage", zap.Uint64("since", since), zap.Uint64("version", version))
+}
+
+// Restore loads the backups, the full backup first then the incremental ones,
+// into a new storage in the key and value directories, which must be empty or
+// not exist yet.
+func Restore(keyDirectory, valueDirectory string, backups ...io.Reader) error {
+	for _, dir := range []string{keyDirectory, valueDirectory} {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("cannot restore into the directory %s, which is not empty", dir)
+		}
+	}
+	opts := badger.DefaultOptions(keyDirectory)
+	opts.ValueDir = valueDirectory
+	store, err := badger.Open(opts)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		if err := store.Load(backup, maxPendingWrites); err != nil {
+			store.Close()
+			return err
+		}
+	}
+	return store.Close()
+}
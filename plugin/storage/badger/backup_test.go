@@ -0,0 +1,172 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/config"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+)
+
+func newTestFactory(t *testing.T, flags ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags(flags))
+	f.InitFromViper(v, zap.NewNop())
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	return f
+}
+
+func writeTestSpan(t *testing.T, f *Factory, traceID uint64) {
+	sw, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	require.NoError(t, sw.WriteSpan(context.Background(), &model.Span{
+		TraceID:       model.NewTraceID(0, traceID),
+		SpanID:        model.NewSpanID(1),
+		OperationName: "operation",
+		Process:       &model.Process{ServiceName: "service"},
+		StartTime:     time.Now(),
+		Duration:      time.Millisecond,
+	}))
+}
+
+func backup(t *testing.T, handler http.Handler, query string) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, BackupPath+query, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func TestBackupDisabled(t *testing.T) {
+	f := newTestFactory(t)
+	defer f.Close()
+	assert.Empty(t, f.AdminHandlers())
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	f := newTestFactory(t, "--badger.admin-backup.enabled=true")
+	defer f.Close()
+	handler := f.AdminHandlers()[BackupPath]
+	require.NotNil(t, handler)
+
+	writeTestSpan(t, f, 1)
+	full := backup(t, handler, "")
+	require.Equal(t, http.StatusOK, full.StatusCode)
+	assert.Empty(t, full.Trailer.Get(BackupErrorTrailer))
+	since, err := strconv.ParseUint(full.Trailer.Get(BackupNextSinceTrailer), 10, 64)
+	require.NoError(t, err)
+	var fullBackup bytes.Buffer
+	_, err = fullBackup.ReadFrom(full.Body)
+	require.NoError(t, err)
+
+	writeTestSpan(t, f, 2)
+	writeTestSpan(t, f, 3)
+	incremental := backup(t, handler, "?since="+strconv.FormatUint(since, 10))
+	require.Equal(t, http.StatusOK, incremental.StatusCode)
+	var incrementalBackup bytes.Buffer
+	_, err = incrementalBackup.ReadFrom(incremental.Body)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyDir, valueDir := filepath.Join(dir, "keys"), filepath.Join(dir, "values")
+	require.NoError(t, Restore(keyDir, valueDir, bytes.NewReader(fullBackup.Bytes())))
+	assert.EqualError(t, Restore(keyDir, valueDir, bytes.NewReader(incrementalBackup.Bytes())),
+		"cannot restore into the directory "+keyDir+", which is not empty")
+
+	restored := newTestFactory(t,
+		"--badger.ephemeral=false",
+		"--badger.directory-key="+keyDir,
+		"--badger.directory-value="+valueDir,
+	)
+	sr, err := restored.CreateSpanReader()
+	require.NoError(t, err)
+	trace, err := sr.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+	_, err = sr.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.Error(t, err)
+	require.NoError(t, restored.Close())
+
+	// the incremental backup only has the spans written after the full backup
+	dir = t.TempDir()
+	require.NoError(t, Restore(dir, dir, bytes.NewReader(incrementalBackup.Bytes())))
+	restored = newTestFactory(t, "--badger.ephemeral=false", "--badger.directory-key="+dir, "--badger.directory-value="+dir)
+	sr, err = restored.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = sr.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+	_, err = sr.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.NoError(t, err)
+	require.NoError(t, restored.Close())
+
+	dir = t.TempDir()
+	keyDir, valueDir = filepath.Join(dir, "keys"), filepath.Join(dir, "values")
+	require.NoError(t, Restore(keyDir, valueDir, &fullBackup, &incrementalBackup))
+
+	restored = newTestFactory(t,
+		"--badger.ephemeral=false",
+		"--badger.directory-key="+keyDir,
+		"--badger.directory-value="+valueDir,
+	)
+	defer restored.Close()
+	sr, err = restored.CreateSpanReader()
+	require.NoError(t, err)
+	for _, traceID := range []uint64{1, 2, 3} {
+		trace, err = sr.GetTrace(context.Background(), model.NewTraceID(0, traceID))
+		require.NoError(t, err)
+		assert.Len(t, trace.Spans, 1)
+	}
+	services, err := sr.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service"}, services)
+}
+
+func TestBackupErrors(t *testing.T) {
+	f := newTestFactory(t, "--badger.admin-backup.enabled=true")
+	handler := f.AdminHandlers()[BackupPath]
+
+	req := httptest.NewRequest(http.MethodPost, BackupPath, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	resp := backup(t, handler, "?since=abc")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	require.NoError(t, f.store.Close())
+	resp = backup(t, handler, "")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	os.RemoveAll(f.tmpDir)
+}
+
+func TestRestoreErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, []byte("data"), 0o600))
+	assert.Error(t, Restore(file, file, bytes.NewReader(nil)))
+}
@@ -15,11 +15,15 @@
 package badger
 
 import (
+	"errors"
 	"expvar"
 	"flag"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
@@ -30,6 +34,7 @@ import (
 	"github.com/kjschnei001/jaeger/plugin"
 	depStore "github.com/kjschnei001/jaeger/plugin/storage/badger/dependencystore"
 	badgerStore "github.com/kjschnei001/jaeger/plugin/storage/badger/spanstore"
+	"github.com/kjschnei001/jaeger/storage"
 	"github.com/kjschnei001/jaeger/storage/dependencystore"
 	"github.com/kjschnei001/jaeger/storage/spanstore"
 )
@@ -39,11 +44,18 @@ const (
 	keyLogSpaceAvailableName   = "badger_key_log_bytes_available"
 	lastMaintenanceRunName     = "badger_storage_maintenance_last_run"
 	lastValueLogCleanedName    = "badger_storage_valueloggc_last_run"
+	keyCountName               = "badger_key_count"
+	evictedKeysName            = "badger_evicted_keys"
+
+	// diskSizeRefreshInterval is how often the maintenance thread measures the size of the
+	// files of the storage when the eviction is enabled
+	diskSizeRefreshInterval = time.Minute
 )
 
 var (
-	_ io.Closer           = (*Factory)(nil)
-	_ plugin.Configurable = (*Factory)(nil)
+	_ io.Closer                   = (*Factory)(nil)
+	_ plugin.Configurable         = (*Factory)(nil)
+	_ storage.AdminHandlerFactory = (*Factory)(nil)
 )
 
 // Factory implements storage.Factory for Badger backend.
@@ -55,6 +67,15 @@ type Factory struct {
 
 	tmpDir          string
 	maintenanceDone chan bool
+	maintenanceWG   sync.WaitGroup
+
+	// lastEviction and lastValueLogGC are the times of the previous eviction and of the end
+	// of the previous ValueLogGC run, and diskSize is the size of the files of the storage
+	// measured at diskSizeTime, only accessed by the maintenance thread
+	lastEviction   time.Time
+	lastValueLogGC time.Time
+	diskSize       int64
+	diskSizeTime   time.Time
 
 	// TODO initialize via reflection; convert comments to tag 'description'.
 	metrics struct {
@@ -66,6 +87,10 @@ type Factory struct {
 		LastMaintenanceRun metrics.Gauge
 		// LastValueLogCleaned stores the timestamp (UnixNano) of the previous ValueLogGC run
 		LastValueLogCleaned metrics.Gauge
+		// KeyCounts stores the number of keys of the spans and of each index, by key type
+		KeyCounts map[string]metrics.Gauge
+		// EvictedKeys counts the keys deleted because the storage exceeded its maximum size
+		EvictedKeys metrics.Counter
 
 		// Expose badger's internal expvar metrics, which are all gauge's at this point
 		badgerMetrics map[string]metrics.Gauge
@@ -136,9 +161,18 @@ func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger)
 	f.metrics.KeyLogSpaceAvailable = metricsFactory.Gauge(metrics.Options{Name: keyLogSpaceAvailableName})
 	f.metrics.LastMaintenanceRun = metricsFactory.Gauge(metrics.Options{Name: lastMaintenanceRunName})
 	f.metrics.LastValueLogCleaned = metricsFactory.Gauge(metrics.Options{Name: lastValueLogCleanedName})
+	f.metrics.KeyCounts = make(map[string]metrics.Gauge, len(badgerStore.KeyTypes))
+	for _, keyType := range badgerStore.KeyTypes {
+		f.metrics.KeyCounts[keyType] = metricsFactory.Gauge(metrics.Options{
+			Name: keyCountName,
+			Tags: map[string]string{"index_type": keyType},
+		})
+	}
+	f.metrics.EvictedKeys = metricsFactory.Counter(metrics.Options{Name: evictedKeysName})
 
 	f.registerBadgerExpvarMetrics(metricsFactory)
 
+	f.maintenanceWG.Add(2)
 	go f.maintenance()
 	go f.metricsCopier()
 
@@ -173,6 +207,8 @@ func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
 // Close Implements io.Closer and closes the underlying storage
 func (f *Factory) Close() error {
 	close(f.maintenanceDone)
+	// the maintenance must not read the store while it is closed
+	f.maintenanceWG.Wait()
 	if f.store == nil {
 		return nil
 	}
@@ -191,32 +227,154 @@ func (f *Factory) Close() error {
 
 // Maintenance starts a background maintenance job for the badger K/V store, such as ValueLogGC
 func (f *Factory) maintenance() {
+	defer f.maintenanceWG.Done()
 	maintenanceTicker := time.NewTicker(f.Options.Primary.MaintenanceInterval)
 	defer maintenanceTicker.Stop()
+	// the disk size is measured on its own schedule, so that the eviction does not depend
+	// on the outcome of ValueLogGC nor on when badger refreshes the size returned by DB.Size
+	var diskSizeRefresh <-chan time.Time
+	if f.evictionEnabled() {
+		f.refreshDiskSize(time.Now())
+		diskSizeTicker := time.NewTicker(diskSizeRefreshInterval)
+		defer diskSizeTicker.Stop()
+		diskSizeRefresh = diskSizeTicker.C
+	}
 	for {
 		select {
 		case <-f.maintenanceDone:
 			return
 		case t := <-maintenanceTicker.C:
-			var err error
+			f.runMaintenance(t)
+		case t := <-diskSizeRefresh:
+			f.refreshDiskSize(t)
+		}
+	}
+}
 
-			// After there's nothing to clean, the err is raised
-			for err == nil {
-				err = f.store.RunValueLogGC(0.5) // 0.5 is selected to rewrite a file if half of it can be discarded
-			}
-			if err == badger.ErrNoRewrite {
-				f.metrics.LastValueLogCleaned.Update(t.UnixNano())
-			} else {
-				f.logger.Error("Failed to run ValueLogGC", zap.Error(err))
-			}
+func (f *Factory) runMaintenance(t time.Time) {
+	f.keysMaintenance(t, f.diskSize)
+
+	var err error
+
+	// After there's nothing to clean, the err is raised
+	for err == nil {
+		err = f.store.RunValueLogGC(0.5) // 0.5 is selected to rewrite a file if half of it can be discarded
+	}
+	// the evicted keys may have been reclaimed even if ValueLogGC failed afterwards
+	f.lastValueLogGC = time.Now()
+	if err == badger.ErrNoRewrite {
+		f.metrics.LastValueLogCleaned.Update(t.UnixNano())
+	} else {
+		f.logger.Error("Failed to run ValueLogGC", zap.Error(err))
+	}
+
+	f.metrics.LastMaintenanceRun.Update(t.UnixNano())
+	f.diskStatisticsUpdate()
+}
+
+// keysMaintenance updates the key counts if enabled, and evicts the spans of the oldest hours
+// if the disk size exceeds the maximum size. The space is reclaimed by ValueLogGC.
+func (f *Factory) keysMaintenance(now time.Time, diskSize int64) {
+	var excess int64
+	if f.evictionEnabled() && f.diskSizeRefreshed() {
+		excess = diskSize - f.Options.Primary.MaxDiskBytes
+	}
+	if excess <= 0 && !f.Options.Primary.KeyCountMetrics {
+		return
+	}
+	stats, err := badgerStore.ScanKeys(f.store)
+	if err != nil {
+		f.logger.Error("Failed to scan the keys", zap.Error(err))
+		return
+	}
+	if f.Options.Primary.KeyCountMetrics {
+		for keyType, count := range stats.Counts {
+			f.metrics.KeyCounts[keyType].Update(count)
+		}
+	}
+	if excess <= 0 {
+		return
+	}
+	evicted, err := f.evictOldest(stats, excess)
+	if err != nil {
+		f.logger.Error("Failed to evict the oldest spans", zap.Error(err))
+		return
+	}
+	if evicted > 0 {
+		f.lastEviction = now
+		f.metrics.EvictedKeys.Inc(evicted)
+		f.logger.Info("Evicted the oldest spans", zap.Int64("keys", evicted))
+	}
+}
+
+func (f *Factory) evictionEnabled() bool {
+	return f.Options.Primary.MaxDiskBytes > 0 && !f.Options.Primary.ReadOnly
+}
+
+// diskSizeRefreshed returns whether the measured disk size accounts for the previous eviction,
+// so that the same excess is not evicted twice: the space of the evicted keys is only reclaimed
+// by the next ValueLogGC, so the size must have been measured after it.
+func (f *Factory) diskSizeRefreshed() bool {
+	if f.lastEviction.IsZero() {
+		return true
+	}
+	return f.lastValueLogGC.After(f.lastEviction) && f.diskSizeTime.After(f.lastValueLogGC)
+}
 
-			f.metrics.LastMaintenanceRun.Update(t.UnixNano())
-			f.diskStatisticsUpdate()
+// refreshDiskSize measures the size of the files in the key and value directories.
+func (f *Factory) refreshDiskSize(now time.Time) {
+	dirs := []string{f.Options.Primary.KeyDirectory}
+	if f.Options.Primary.ValueDirectory != f.Options.Primary.KeyDirectory {
+		dirs = append(dirs, f.Options.Primary.ValueDirectory)
+	}
+	var size int64
+	for _, dir := range dirs {
+		dirSize, err := dirSize(dir)
+		if err != nil {
+			f.logger.Error("Failed to measure the disk size", zap.String("directory", dir), zap.Error(err))
+			return
 		}
+		size += dirSize
+	}
+	f.diskSize = size
+	f.diskSizeTime = now
+}
+
+// dirSize returns the total size of the files in the directory. The files removed while
+// walking the directory, such as the tables replaced by a compaction, are skipped.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			var info fs.FileInfo
+			if info, err = entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		if errors.Is(err, fs.ErrNotExist) && path != dir {
+			return nil
+		}
+		return err
+	})
+	return size, err
+}
+
+// evictOldest deletes the spans of the oldest hours until their estimated size covers the
+// excess, and returns the number of deleted keys. The spans of the current hour are kept.
+func (f *Factory) evictOldest(stats *badgerStore.KeyStats, excess int64) (int64, error) {
+	var before time.Time
+	for i := 0; excess > 0 && i < len(stats.Buckets)-1; i++ {
+		excess -= stats.Buckets[i].Bytes
+		before = stats.Buckets[i+1].Start
+	}
+	if before.IsZero() {
+		return 0, nil
 	}
+	return badgerStore.DeleteBefore(f.store, before)
 }
 
 func (f *Factory) metricsCopier() {
+	defer f.maintenanceWG.Done()
 	metricsTicker := time.NewTicker(f.Options.Primary.MetricsUpdateInterval)
 	defer metricsTicker.Stop()
 	for {
@@ -15,10 +15,12 @@
 package badger
 
 import (
+	"context"
 	"expvar"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -26,8 +28,10 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/config"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
+	badgerStore "github.com/kjschnei001/jaeger/plugin/storage/badger/spanstore"
 )
 
 func TestInitializationErrors(t *testing.T) {
@@ -125,26 +129,16 @@ func TestMaintenanceRun(t *testing.T) {
 func TestMaintenanceCodecov(t *testing.T) {
 	// For Codecov - this does not test anything
 	f := NewFactory()
-	v, command := config.Viperize(f.AddFlags)
-	// Lets speed up the maintenance ticker..
-	command.ParseFlags([]string{
-		"--badger.maintenance-interval=10ms",
-	})
+	v, _ := config.Viperize(f.AddFlags)
 	f.InitFromViper(v, zap.NewNop())
 	mFactory := metricstest.NewFactory(0)
 	f.Initialize(mFactory, zap.NewNop())
 	defer f.Close()
 
-	waiter := func() {
-		for sleeps := 0; sleeps < 8; sleeps++ {
-			// Wait for the scheduler
-			time.Sleep(time.Duration(50) * time.Millisecond)
-		}
-	}
-
 	err := f.store.Close()
 	assert.NoError(t, err)
-	waiter() // This should trigger the logging of error
+	// The maintenance is run directly, since the store cannot be closed while the ticker runs it
+	f.runMaintenance(time.Now()) // This should trigger the logging of error
 }
 
 func TestBadgerMetrics(t *testing.T) {
@@ -195,3 +189,159 @@ func TestInitFromOptions(t *testing.T) {
 	f.InitFromOptions(opts)
 	assert.Equal(t, &opts, f.Options)
 }
+
+func TestKeysMaintenance(t *testing.T) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags([]string{
+		"--badger.maintenance-interval=1h",
+		"--badger.key-count-metrics=true",
+	})
+	f.InitFromViper(v, zap.NewNop())
+	mFactory := metricstest.NewFactory(0)
+	assert.NoError(t, f.Initialize(mFactory, zap.NewNop()))
+	defer f.Close()
+
+	sw, err := f.CreateSpanWriter()
+	assert.NoError(t, err)
+	now := time.Now().Truncate(time.Hour)
+	for i, start := range []time.Time{now.Add(-3 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Hour), now} {
+		assert.NoError(t, sw.WriteSpan(context.Background(), &model.Span{
+			TraceID:       model.NewTraceID(0, uint64(i+1)),
+			SpanID:        model.NewSpanID(1),
+			OperationName: "operation",
+			Process:       &model.Process{ServiceName: "service"},
+			StartTime:     start,
+			Duration:      time.Millisecond,
+		}))
+	}
+
+	f.keysMaintenance(now, 0)
+	_, gs := mFactory.Snapshot()
+	assert.Equal(t, int64(4), gs["badger_key_count|index_type=span"])
+	assert.Equal(t, int64(4), gs["badger_key_count|index_type=service_name"])
+	assert.Equal(t, int64(0), gs["badger_key_count|index_type=tag"])
+
+	stats, err := badgerStore.ScanKeys(f.store)
+	assert.NoError(t, err)
+	assert.Len(t, stats.Buckets, 4)
+	bucketSize := stats.Buckets[0].Bytes
+
+	// the excess is the size of the oldest hour and a half
+	evicted, err := f.evictOldest(stats, bucketSize*3/2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), evicted)
+
+	// the spans of the current hour are never evicted
+	stats, err = badgerStore.ScanKeys(f.store)
+	assert.NoError(t, err)
+	assert.Len(t, stats.Buckets, 2)
+	evicted, err = f.evictOldest(stats, 10*bucketSize)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), evicted)
+	stats, err = badgerStore.ScanKeys(f.store)
+	assert.NoError(t, err)
+	assert.Len(t, stats.Buckets, 1)
+	assert.Equal(t, now.UTC(), stats.Buckets[0].Start)
+	evicted, err = f.evictOldest(stats, 10*bucketSize)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), evicted)
+}
+
+// stopMaintenance stops the maintenance thread, so that the test runs the maintenance instead.
+func stopMaintenance(f *Factory) {
+	close(f.maintenanceDone)
+	f.maintenanceWG.Wait()
+	f.maintenanceDone = make(chan bool)
+}
+
+func TestKeysMaintenanceEviction(t *testing.T) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags([]string{
+		"--badger.maintenance-interval=1h",
+		"--badger.max-disk-bytes=1000",
+	})
+	f.InitFromViper(v, zap.NewNop())
+	mFactory := metricstest.NewFactory(0)
+	assert.NoError(t, f.Initialize(mFactory, zap.NewNop()))
+	defer f.Close()
+	stopMaintenance(f)
+
+	sw, err := f.CreateSpanWriter()
+	assert.NoError(t, err)
+	now := time.Now().Truncate(time.Hour)
+	writeSpan := func(id uint64, start time.Time) {
+		assert.NoError(t, sw.WriteSpan(context.Background(), &model.Span{
+			TraceID:       model.NewTraceID(0, id),
+			SpanID:        model.NewSpanID(1),
+			OperationName: "operation",
+			Process:       &model.Process{ServiceName: "service"},
+			StartTime:     start,
+			Duration:      time.Millisecond,
+		}))
+	}
+	writeSpan(1, now.Add(-2*time.Hour))
+	writeSpan(2, now)
+
+	// nothing is evicted below the maximum size
+	f.keysMaintenance(now, 1000)
+	c, gs := mFactory.Snapshot()
+	assert.Zero(t, c["badger_evicted_keys"])
+
+	f.keysMaintenance(now, 1001)
+	c, gs = mFactory.Snapshot()
+	assert.Equal(t, int64(4), c["badger_evicted_keys"])
+	// the key counts are only reported if enabled
+	assert.NotContains(t, gs, "badger_key_count|index_type=span")
+
+	// the eviction waits for the disk size to be measured after the next ValueLogGC
+	writeSpan(3, now.Add(-time.Hour))
+	f.diskSizeTime = now.Add(time.Minute)
+	f.keysMaintenance(now.Add(time.Minute), 1001)
+	f.lastValueLogGC = now.Add(2 * time.Minute)
+	f.keysMaintenance(now.Add(2*time.Minute), 1001)
+	c, _ = mFactory.Snapshot()
+	assert.Equal(t, int64(4), c["badger_evicted_keys"])
+
+	f.diskSizeTime = now.Add(3 * time.Minute)
+	f.keysMaintenance(now.Add(3*time.Minute), 1001)
+	c, _ = mFactory.Snapshot()
+	assert.Equal(t, int64(8), c["badger_evicted_keys"])
+}
+
+func TestRefreshDiskSize(t *testing.T) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags([]string{
+		"--badger.maintenance-interval=1h",
+		"--badger.max-disk-bytes=1000",
+	})
+	f.InitFromViper(v, zap.NewNop())
+	assert.NoError(t, f.Initialize(metricstest.NewFactory(0), zap.NewNop()))
+	defer f.Close()
+	stopMaintenance(f) // the maintenance thread measured the size when it started
+	assert.False(t, f.diskSizeTime.IsZero())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(f.tmpDir, "test"), make([]byte, 1000), 0o600))
+	previous := f.diskSize
+	now := time.Now()
+	f.refreshDiskSize(now)
+	assert.Equal(t, previous+1000, f.diskSize)
+	assert.Equal(t, now, f.diskSizeTime)
+
+	// the size is kept when it cannot be measured
+	f.Options.Primary.KeyDirectory = filepath.Join(f.tmpDir, "missing")
+	f.refreshDiskSize(now.Add(time.Minute))
+	assert.Equal(t, previous+1000, f.diskSize)
+	assert.Equal(t, now, f.diskSizeTime)
+
+	// the eviction waits for the size measured after ValueLogGC, even if ValueLogGC fails
+	f.lastEviction = now
+	assert.NoError(t, f.store.Close())
+	f.runMaintenance(now)
+	assert.True(t, f.lastValueLogGC.After(now))
+	assert.False(t, f.diskSizeRefreshed())
+	f.diskSizeTime = time.Now()
+	assert.True(t, f.diskSizeRefreshed())
+}
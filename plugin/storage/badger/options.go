@@ -42,6 +42,12 @@ type NamespaceConfig struct {
 	MaintenanceInterval   time.Duration `mapstructure:"maintenance_interval"`
 	MetricsUpdateInterval time.Duration `mapstructure:"metrics_update_interval"`
 	ReadOnly              bool          `mapstructure:"read_only"`
+	// MaxDiskBytes is the size above which the oldest spans are evicted, 0 disables the eviction
+	MaxDiskBytes int64 `mapstructure:"max_disk_bytes"`
+	// KeyCountMetrics enables the key counts, which are computed by scanning all the keys
+	KeyCountMetrics bool `mapstructure:"key_count_metrics"`
+	// AdminBackup enables the backup endpoint on the admin server
+	AdminBackup bool `mapstructure:"admin_backup"`
 }
 
 const (
@@ -59,6 +65,9 @@ const (
 	suffixMaintenanceInterval = ".maintenance-interval"
 	suffixMetricsInterval     = ".metrics-update-interval" // Intended only for testing purposes
 	suffixReadOnly            = ".read-only"
+	suffixMaxDiskBytes        = ".max-disk-bytes"
+	suffixKeyCountMetrics     = ".key-count-metrics"
+	suffixAdminBackup         = ".admin-backup.enabled"
 	defaultDataDir            = string(os.PathSeparator) + "data"
 	defaultValueDir           = defaultDataDir + string(os.PathSeparator) + "values"
 	defaultKeysDir            = defaultDataDir + string(os.PathSeparator) + "keys"
@@ -136,6 +145,21 @@ func addFlags(flagSet *flag.FlagSet, nsConfig NamespaceConfig) {
 		nsConfig.ReadOnly,
 		"Allows to open badger database in read only mode. Multiple instances can open same database in read-only mode. Values still in the write-ahead-log must be replayed before opening.",
 	)
+	flagSet.Int64(
+		nsConfig.namespace+suffixMaxDiskBytes,
+		nsConfig.MaxDiskBytes,
+		"The size in bytes of the storage above which the spans of the oldest hours are evicted by the maintenance thread, in addition to the TTL. Zero disables the eviction.",
+	)
+	flagSet.Bool(
+		nsConfig.namespace+suffixKeyCountMetrics,
+		nsConfig.KeyCountMetrics,
+		"Whether the maintenance thread reports the number of keys by type. Counting the keys scans all of them on every maintenance run.",
+	)
+	flagSet.Bool(
+		nsConfig.namespace+suffixAdminBackup,
+		nsConfig.AdminBackup,
+		"Whether the admin server serves the backups of the storage on "+BackupPath+". The endpoint is not authenticated, so the admin port must not be exposed when it is enabled.",
+	)
 }
 
 // InitFromViper initializes Options with properties from viper
@@ -152,6 +176,9 @@ func initFromViper(cfg *NamespaceConfig, v *viper.Viper, logger *zap.Logger) {
 	cfg.MaintenanceInterval = v.GetDuration(cfg.namespace + suffixMaintenanceInterval)
 	cfg.MetricsUpdateInterval = v.GetDuration(cfg.namespace + suffixMetricsInterval)
 	cfg.ReadOnly = v.GetBool(cfg.namespace + suffixReadOnly)
+	cfg.MaxDiskBytes = v.GetInt64(cfg.namespace + suffixMaxDiskBytes)
+	cfg.KeyCountMetrics = v.GetBool(cfg.namespace + suffixKeyCountMetrics)
+	cfg.AdminBackup = v.GetBool(cfg.namespace + suffixAdminBackup)
 }
 
 // GetPrimary returns the primary namespace configuration
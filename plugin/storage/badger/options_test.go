@@ -33,6 +33,9 @@ func TestDefaultOptionsParsing(t *testing.T) {
 	assert.True(t, opts.GetPrimary().Ephemeral)
 	assert.False(t, opts.GetPrimary().SyncWrites)
 	assert.Equal(t, time.Duration(72*time.Hour), opts.GetPrimary().SpanStoreTTL)
+	assert.Zero(t, opts.GetPrimary().MaxDiskBytes)
+	assert.False(t, opts.GetPrimary().KeyCountMetrics)
+	assert.False(t, opts.GetPrimary().AdminBackup)
 }
 
 func TestParseOptions(t *testing.T) {
@@ -44,6 +47,9 @@ func TestParseOptions(t *testing.T) {
 		"--badger.directory-key=/var/lib/badger",
 		"--badger.directory-value=/mnt/slow/badger",
 		"--badger.span-store-ttl=168h",
+		"--badger.max-disk-bytes=1073741824",
+		"--badger.key-count-metrics=true",
+		"--badger.admin-backup.enabled=true",
 	})
 	opts.InitFromViper(v, zap.NewNop())
 
@@ -53,6 +59,9 @@ func TestParseOptions(t *testing.T) {
 	assert.Equal(t, "/var/lib/badger", opts.GetPrimary().KeyDirectory)
 	assert.Equal(t, "/mnt/slow/badger", opts.GetPrimary().ValueDirectory)
 	assert.False(t, opts.GetPrimary().ReadOnly)
+	assert.EqualValues(t, 1<<30, opts.GetPrimary().MaxDiskBytes)
+	assert.True(t, opts.GetPrimary().KeyCountMetrics)
+	assert.True(t, opts.GetPrimary().AdminBackup)
 }
 
 func TestReadOnlyOptions(t *testing.T) {
@@ -0,0 +1,170 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// Key types reported by ScanKeys.
+const (
+	SpanKeyType          = "span"
+	ServiceNameKeyType   = "service_name"
+	OperationNameKeyType = "operation_name"
+	TagKeyType           = "tag"
+	DurationKeyType      = "duration"
+)
+
+// deleteBatchSize is the number of keys deleted at once by DeleteBefore.
+var deleteBatchSize = 1000
+
+// KeyTypes are all the key types reported by ScanKeys.
+var KeyTypes = []string{SpanKeyType, ServiceNameKeyType, OperationNameKeyType, TagKeyType, DurationKeyType}
+
+// keyTypes maps the first byte of the keys to their type.
+var keyTypes = map[byte]string{
+	spanKeyPrefix:         SpanKeyType,
+	serviceNameIndexKey:   ServiceNameKeyType,
+	operationNameIndexKey: OperationNameKeyType,
+	tagIndexKey:           TagKeyType,
+	durationIndexKey:      DurationKeyType,
+}
+
+// TimeBucket is the estimated size of the spans and indexes of an hour.
+type TimeBucket struct {
+	Start time.Time
+	Bytes int64
+}
+
+// KeyStats are the statistics of the keys of the spans and indexes.
+type KeyStats struct {
+	// Counts is the number of keys by key type.
+	Counts map[string]int64
+	// Buckets are the sizes by hour of the span start times, the oldest first.
+	Buckets []TimeBucket
+}
+
+// ScanKeys iterates over all the keys, without reading the values, to compute their statistics.
+func ScanKeys(db *badger.DB) (*KeyStats, error) {
+	stats := &KeyStats{Counts: make(map[string]int64, len(KeyTypes))}
+	for _, keyType := range KeyTypes {
+		stats.Counts[keyType] = 0
+	}
+	buckets := make(map[int64]int64)
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := item.Key()
+			keyType, ok := keyTypes[key[0]]
+			if !ok {
+				continue
+			}
+			stats.Counts[keyType]++
+			if startTime, ok := keyStartTime(key); ok {
+				buckets[startTime.Truncate(time.Hour).Unix()] += item.EstimatedSize()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for start, bytes := range buckets {
+		stats.Buckets = append(stats.Buckets, TimeBucket{Start: time.Unix(start, 0).UTC(), Bytes: bytes})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool {
+		return stats.Buckets[i].Start.Before(stats.Buckets[j].Start)
+	})
+	return stats, nil
+}
+
+// DeleteBefore deletes the spans and indexes of the spans started before the
+// time, and returns the number of deleted keys.
+func DeleteBefore(db *badger.DB, before time.Time) (int64, error) {
+	var deleted int64
+	keys := make([][]byte, 0, deleteBatchSize)
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			key := it.Item().Key()
+			if _, ok := keyTypes[key[0]]; !ok {
+				continue
+			}
+			if startTime, ok := keyStartTime(key); !ok || !startTime.Before(before) {
+				continue
+			}
+			keys = append(keys, it.Item().KeyCopy(nil))
+			if len(keys) == deleteBatchSize {
+				if err := deleteKeys(db, keys); err != nil {
+					return err
+				}
+				deleted += int64(len(keys))
+				keys = keys[:0]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return deleted, err
+	}
+	if err := deleteKeys(db, keys); err != nil {
+		return deleted, err
+	}
+	return deleted + int64(len(keys)), nil
+}
+
+// deleteKeys deletes a batch of keys.
+func deleteKeys(db *badger.DB, keys [][]byte) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	batch := db.NewWriteBatch()
+	defer batch.Cancel()
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return batch.Flush()
+}
+
+// keyStartTime returns the span start time stored in a span or index key.
+func keyStartTime(key []byte) (time.Time, bool) {
+	var pos int
+	if key[0] == spanKeyPrefix {
+		// KEY: ti<trace-id><startTime><span-id>
+		pos = 1 + sizeOfTraceID
+	} else {
+		// KEY: indexKey<indexValue><startTime><traceId>
+		pos = len(key) - sizeOfTraceID - 8
+	}
+	if pos < 1 || pos+8 > len(key) {
+		return time.Time{}, false
+	}
+	return model.EpochMicrosecondsAsTime(binary.BigEndian.Uint64(key[pos : pos+8])), true
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+func TestScanKeysAndDeleteBefore(t *testing.T) {
+	runWithBadger(t, func(store *badger.DB, t *testing.T) {
+		cache := NewCacheStore(store, time.Hour, true)
+		sw := NewSpanWriter(store, cache, time.Hour)
+		sr := NewTraceReader(store, cache)
+
+		now := time.Now().UTC().Truncate(time.Hour)
+		for i, start := range []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Hour), now.Add(time.Minute)} {
+			span := createDummySpan()
+			span.TraceID = model.NewTraceID(0, uint64(i+1))
+			span.StartTime = start
+			require.NoError(t, sw.WriteSpan(context.Background(), &span))
+		}
+		// keys unknown to the span store are ignored
+		require.NoError(t, store.Update(func(txn *badger.Txn) error {
+			return txn.Set([]byte{0x01, 0x02}, []byte("value"))
+		}))
+
+		stats, err := ScanKeys(store)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{
+			SpanKeyType:          3,
+			ServiceNameKeyType:   3,
+			OperationNameKeyType: 3,
+			TagKeyType:           3,
+			DurationKeyType:      3,
+		}, stats.Counts)
+		require.Len(t, stats.Buckets, 3)
+		assert.Equal(t, now.Add(-2*time.Hour), stats.Buckets[0].Start)
+		assert.Equal(t, now.Add(-time.Hour), stats.Buckets[1].Start)
+		assert.Equal(t, now, stats.Buckets[2].Start)
+		for _, bucket := range stats.Buckets {
+			assert.Positive(t, bucket.Bytes)
+		}
+
+		// the keys are deleted in several batches
+		defer func(size int) { deleteBatchSize = size }(deleteBatchSize)
+		deleteBatchSize = 2
+		deleted, err := DeleteBefore(store, now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, deleted)
+
+		stats, err = ScanKeys(store)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, stats.Counts[SpanKeyType])
+		require.Len(t, stats.Buckets, 2)
+		assert.Equal(t, now.Add(-time.Hour), stats.Buckets[0].Start)
+
+		_, err = sr.GetTrace(context.Background(), model.NewTraceID(0, 1))
+		assert.Error(t, err)
+		trace, err := sr.GetTrace(context.Background(), model.NewTraceID(0, 2))
+		require.NoError(t, err)
+		assert.Len(t, trace.Spans, 1)
+
+		deleted, err = DeleteBefore(store, now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+}
@@ -90,6 +90,38 @@ func TestWriteReadBack(t *testing.T) {
 	})
 }
 
+func TestGetTraces(t *testing.T) {
+	runFactoryTest(t, func(tb testing.TB, sw spanstore.Writer, sr spanstore.Reader) {
+		tid := time.Now()
+		for i := 1; i <= 3; i++ {
+			for j := 0; j < i; j++ {
+				err := sw.WriteSpan(context.Background(), &model.Span{
+					TraceID:       model.TraceID{Low: uint64(i), High: 1},
+					SpanID:        model.SpanID(j),
+					OperationName: "operation",
+					Process:       &model.Process{ServiceName: "service"},
+					StartTime:     tid,
+				})
+				assert.NoError(t, err)
+			}
+		}
+
+		traceIDs := []model.TraceID{
+			{Low: 3, High: 1},
+			{Low: 4, High: 1},
+			{Low: 1, High: 1},
+			{Low: 3, High: 1},
+		}
+		traces, err := spanstore.GetTraces(context.Background(), sr, traceIDs)
+		require.NoError(t, err)
+		require.Len(t, traces, 2)
+		assert.Equal(t, traceIDs[0], spanstore.TraceIDOf(traces[0]))
+		assert.Len(t, traces[0].Spans, 3)
+		assert.Equal(t, traceIDs[2], spanstore.TraceIDOf(traces[1]))
+		assert.Len(t, traces[1].Spans, 1)
+	})
+}
+
 func TestValidation(t *testing.T) {
 	runFactoryTest(t, func(tb testing.TB, sw spanstore.Writer, sr spanstore.Reader) {
 		tid := time.Now()
@@ -289,6 +321,183 @@ func TestIndexSeeks(t *testing.T) {
 	})
 }
 
+func TestFindTracesWithTagFilters(t *testing.T) {
+	runFactoryTest(t, func(tb testing.TB, sw spanstore.Writer, sr spanstore.Reader) {
+		startT := time.Now()
+		for i := 0; i < 10; i++ {
+			s := model.Span{
+				TraceID:       model.NewTraceID(1, uint64(i)),
+				SpanID:        model.SpanID(1),
+				OperationName: "operation",
+				Process: &model.Process{
+					ServiceName: "service",
+				},
+				StartTime: startT.Add(time.Duration(i) * time.Millisecond),
+				Duration:  time.Millisecond,
+				Tags: model.KeyValues{
+					model.Int64("http.status_code", int64(200+100*(i%4))),
+					model.String("db.statement", fmt.Sprintf("SELECT * FROM table%d", i)),
+				},
+			}
+			require.NoError(t, sw.WriteSpan(context.Background(), &s))
+		}
+
+		find := func(numTraces int, filters ...string) []*model.Trace {
+			var tagFilters []*spanstore.Predicate
+			for _, f := range filters {
+				tagFilter, err := spanstore.ParseTagFilter(f)
+				require.NoError(t, err)
+				tagFilters = append(tagFilters, tagFilter)
+			}
+			params := &spanstore.TraceQueryParameters{
+				ServiceName:  "service",
+				StartTimeMin: startT,
+				StartTimeMax: startT.Add(time.Second),
+				NumTraces:    numTraces,
+				TagFilters:   tagFilters,
+			}
+			traces, err := sr.FindTraces(context.Background(), params)
+			require.NoError(t, err)
+			ids, err := sr.FindTraceIDs(context.Background(), params)
+			require.NoError(t, err)
+			require.Len(t, ids, len(traces))
+			return traces
+		}
+
+		assert.Len(t, find(0, "http.status_code>=400"), 4)
+		assert.Len(t, find(0, "http.status_code!=200"), 7)
+		assert.Len(t, find(0, "http.status_code>=400", `db.statement~"table[0-4]$"`), 2)
+		assert.Len(t, find(0, "db.statement^=SELECT"), 10)
+		assert.Len(t, find(0, "db.statement^=INSERT"), 0)
+
+		// most recent traces are returned first
+		traces := find(2, "http.status_code=500")
+		require.Len(t, traces, 2)
+		assert.Equal(t, uint64(7), traces[0].Spans[0].TraceID.Low)
+		assert.Equal(t, uint64(3), traces[1].Spans[0].TraceID.Low)
+	})
+}
+
+func TestFindTracesTagMatchMode(t *testing.T) {
+	runFactoryTest(t, func(tb testing.TB, sw spanstore.Writer, sr spanstore.Reader) {
+		startT := time.Now()
+		// trace 1 has both tags on the same span, trace 2 on different spans
+		spans := []model.Span{
+			{TraceID: model.NewTraceID(1, 1), SpanID: model.SpanID(1), Tags: model.KeyValues{model.String("a", "1"), model.String("b", "2")}},
+			{TraceID: model.NewTraceID(1, 2), SpanID: model.SpanID(1), Tags: model.KeyValues{model.String("a", "1")}},
+			{TraceID: model.NewTraceID(1, 2), SpanID: model.SpanID(2), Tags: model.KeyValues{model.String("b", "2")}},
+		}
+		for i := range spans {
+			spans[i].OperationName = "operation"
+			spans[i].Process = &model.Process{ServiceName: "service"}
+			spans[i].StartTime = startT.Add(time.Duration(i) * time.Millisecond)
+			spans[i].Duration = time.Millisecond
+			require.NoError(t, sw.WriteSpan(context.Background(), &spans[i]))
+		}
+
+		find := func(mode spanstore.TagMatchMode) []model.TraceID {
+			params := &spanstore.TraceQueryParameters{
+				ServiceName:  "service",
+				Tags:         map[string]string{"a": "1", "b": "2"},
+				StartTimeMin: startT,
+				StartTimeMax: startT.Add(time.Second),
+				TagMatchMode: mode,
+			}
+			traces, err := sr.FindTraces(context.Background(), params)
+			require.NoError(t, err)
+			ids, err := sr.FindTraceIDs(context.Background(), params)
+			require.NoError(t, err)
+			require.Len(t, ids, len(traces))
+			return ids
+		}
+
+		assert.Equal(t, []model.TraceID{model.NewTraceID(1, 1)}, find(spanstore.TagMatchSameSpan))
+		assert.ElementsMatch(t, []model.TraceID{model.NewTraceID(1, 1), model.NewTraceID(1, 2)}, find(spanstore.TagMatchAnySpanInTrace))
+	})
+}
+
+func TestFindTracesPage(t *testing.T) {
+	runFactoryTest(t, func(tb testing.TB, sw spanstore.Writer, sr spanstore.Reader) {
+		startT := time.Now()
+		// trace i has two spans, the second one starting after the first span of trace i+1
+		for i := 0; i < 5; i++ {
+			for j := 0; j < 2; j++ {
+				s := model.Span{
+					TraceID:       model.NewTraceID(1, uint64(i)),
+					SpanID:        model.SpanID(j),
+					OperationName: "operation",
+					Process:       &model.Process{ServiceName: "service"},
+					Tags:          model.KeyValues{model.Int64("index", int64(i))},
+					StartTime:     startT.Add(time.Duration(i+j*2) * time.Millisecond),
+					Duration:      time.Millisecond,
+				}
+				require.NoError(t, sw.WriteSpan(context.Background(), &s))
+			}
+		}
+		expected := []model.TraceID{
+			model.NewTraceID(1, 4), model.NewTraceID(1, 3), model.NewTraceID(1, 2), model.NewTraceID(1, 1), model.NewTraceID(1, 0),
+		}
+
+		findAll := func(params *spanstore.TraceQueryParameters) []model.TraceID {
+			var ids []model.TraceID
+			pagedReader := sr.(spanstore.PagedReader)
+			for pages := 0; pages < 10; pages++ {
+				page, err := pagedReader.FindTracesPage(context.Background(), params)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(page.Traces), 2)
+				for _, trace := range page.Traces {
+					ids = append(ids, trace.Spans[0].TraceID)
+				}
+				if page.NextPageToken == "" {
+					return ids
+				}
+				params.PageToken = page.NextPageToken
+			}
+			require.Fail(t, "too many pages")
+			return nil
+		}
+
+		tagFilter, err := spanstore.ParseTagFilter("index>=0")
+		require.NoError(t, err)
+		tests := []struct {
+			name   string
+			params *spanstore.TraceQueryParameters
+		}{
+			{
+				name:   "service index",
+				params: &spanstore.TraceQueryParameters{ServiceName: "service"},
+			},
+			{
+				name:   "time range scan",
+				params: &spanstore.TraceQueryParameters{},
+			},
+			{
+				name:   "tag verification",
+				params: &spanstore.TraceQueryParameters{ServiceName: "service", TagFilters: []*spanstore.Predicate{tagFilter}},
+			},
+		}
+		for _, test := range tests {
+			test.params.StartTimeMin = startT
+			test.params.StartTimeMax = startT.Add(time.Second)
+			test.params.NumTraces = 2
+			if test.name == "time range scan" {
+				// the full table scan orders traces by their first span
+				assert.ElementsMatch(t, expected, findAll(test.params), test.name)
+				continue
+			}
+			assert.Equal(t, expected, findAll(test.params), test.name)
+		}
+
+		_, err = sr.(spanstore.PagedReader).FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName:  "service",
+			StartTimeMin: startT,
+			StartTimeMax: startT.Add(time.Second),
+			PageToken:    "invalid",
+		})
+		assert.ErrorIs(t, err, spanstore.ErrInvalidPageToken)
+	})
+}
+
 func TestFindNothing(t *testing.T) {
 	runFactoryTest(t, func(tb testing.TB, sw spanstore.Writer, sr spanstore.Reader) {
 		startT := time.Now()
@@ -60,7 +60,12 @@ var (
 
 const (
 	defaultNumTraces = 100
-	sizeOfTraceID    = 16
+	// tagVerificationBatchSize is how many candidate traces are loaded at a time when verifying tags
+	tagVerificationBatchSize = 100
+	sizeOfTraceID            = 16
+	// sizeOfTraceKey is the size of a trace key, the 8 byte start timestamp followed by the traceID.
+	// Trace keys sort in the order traces are returned by FindTraces when compared in reverse.
+	sizeOfTraceKey   = 8 + sizeOfTraceID
 	encodingTypeBits = 0x0F
 )
 
@@ -77,6 +82,9 @@ type executionPlan struct {
 
 	limit int
 
+	// resumeKey is the trace key of the last trace of the previous page, if any
+	resumeKey []byte
+
 	// mergeOuter is the result of merge-join of inner and outer result sets
 	mergeOuter [][]byte
 
@@ -172,8 +180,14 @@ func (r *TraceReader) GetTrace(ctx context.Context, traceID model.TraceID) (*mod
 	return nil, ErrInternalConsistencyError
 }
 
-// scanTimeRange returns all the Traces found between startTs and endTs
-func (r *TraceReader) scanTimeRange(plan *executionPlan) ([]model.TraceID, error) {
+// GetTraces implements spanstore.BatchReader#GetTraces. All the traces are
+// read in a single transaction.
+func (r *TraceReader) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	return r.getTraces(spanstore.UniqueTraceIDs(traceIDs))
+}
+
+// scanTimeRange returns the trace keys of all the Traces found between startTs and endTs
+func (r *TraceReader) scanTimeRange(plan *executionPlan) ([][]byte, error) {
 	// We need to do a full table scan
 	traceKeys := make([][]byte, 0)
 	err := r.store.View(func(txn *badger.Txn) error {
@@ -187,23 +201,25 @@ func (r *TraceReader) scanTimeRange(plan *executionPlan) ([]model.TraceID, error
 		for it.Seek(startIndex); it.ValidForPrefix(startIndex); it.Next() {
 			item := it.Item()
 
-			key := []byte{}
-			key = item.KeyCopy(key)
+			key := item.Key()
 
 			timestamp := key[sizeOfTraceID+1 : sizeOfTraceID+1+8]
 			traceID := key[1 : sizeOfTraceID+1]
 
 			if bytes.Compare(timestamp, plan.startTimeMin) >= 0 && bytes.Compare(timestamp, plan.startTimeMax) <= 0 {
 				if !bytes.Equal(traceID, prevTraceID) {
+					traceKey := make([]byte, 0, sizeOfTraceKey)
+					traceKey = append(traceKey, timestamp...)
+					traceKey = append(traceKey, traceID...)
 					if plan.hashOuter != nil {
 						trID := bytesToTraceID(traceID)
 						if _, exists := plan.hashOuter[trID]; exists {
-							traceKeys = append(traceKeys, key)
+							traceKeys = append(traceKeys, traceKey)
 						}
 					} else {
-						traceKeys = append(traceKeys, key)
+						traceKeys = append(traceKeys, traceKey)
 					}
-					prevTraceID = traceID
+					prevTraceID = traceKey[8:]
 				}
 			}
 		}
@@ -212,21 +228,23 @@ func (r *TraceReader) scanTimeRange(plan *executionPlan) ([]model.TraceID, error
 	})
 
 	sort.Slice(traceKeys, func(k, h int) bool {
-		// This sorts by timestamp to descending order
-		return bytes.Compare(traceKeys[k][sizeOfTraceID+1:sizeOfTraceID+1+8], traceKeys[h][sizeOfTraceID+1:sizeOfTraceID+1+8]) > 0
+		// This sorts by timestamp, then traceID, to descending order
+		return bytes.Compare(traceKeys[k], traceKeys[h]) > 0
 	})
 
-	sizeCount := len(traceKeys)
-	if plan.limit > 0 && plan.limit < sizeCount {
-		sizeCount = plan.limit
+	if plan.resumeKey != nil {
+		// Skip the keys returned in previous pages
+		skip := sort.Search(len(traceKeys), func(i int) bool {
+			return bytes.Compare(traceKeys[i], plan.resumeKey) < 0
+		})
+		traceKeys = traceKeys[skip:]
 	}
-	traceIDs := make([]model.TraceID, sizeCount)
 
-	for i := 0; i < sizeCount; i++ {
-		traceIDs[i] = bytesToTraceID(traceKeys[i][1 : sizeOfTraceID+1])
+	if plan.limit > 0 && plan.limit < len(traceKeys) {
+		traceKeys = traceKeys[:plan.limit]
 	}
 
-	return traceIDs, err
+	return traceKeys, err
 }
 
 func createPrimaryKeySeekPrefix(traceID model.TraceID) []byte {
@@ -290,8 +308,8 @@ func serviceQueries(query *spanstore.TraceQueryParameters, indexSeeks [][]byte)
 	return indexSeeks
 }
 
-// indexSeeksToTraceIDs does the index scanning against badger based on the parsed index queries
-func (r *TraceReader) indexSeeksToTraceIDs(plan *executionPlan, indexSeeks [][]byte) ([]model.TraceID, error) {
+// indexSeeksToTraceKeys does the index scanning against badger based on the parsed index queries
+func (r *TraceReader) indexSeeksToTraceKeys(plan *executionPlan, indexSeeks [][]byte) ([][]byte, error) {
 	for i := len(indexSeeks) - 1; i > 0; i-- {
 		indexResults, err := r.scanIndexKeys(indexSeeks[i], plan)
 		if err != nil {
@@ -299,14 +317,14 @@ func (r *TraceReader) indexSeeksToTraceIDs(plan *executionPlan, indexSeeks [][]b
 		}
 
 		sort.Slice(indexResults, func(k, h int) bool {
-			return bytes.Compare(indexResults[k], indexResults[h]) < 0
+			return bytes.Compare(indexResults[k][8:], indexResults[h][8:]) < 0
 		})
 
 		// Same traceID can be returned multiple times, but always in sorted order so checking the previous key is enough
 		prevTraceID := []byte{}
 		innerIDs := make([][]byte, 0, len(indexSeeks))
 		for j := 0; j < len(indexResults); j++ {
-			traceID := indexResults[j]
+			traceID := indexResults[j][8:]
 			if !bytes.Equal(prevTraceID, traceID) {
 				innerIDs = append(innerIDs, traceID)
 				prevTraceID = traceID
@@ -322,7 +340,7 @@ func (r *TraceReader) indexSeeksToTraceIDs(plan *executionPlan, indexSeeks [][]b
 	}
 
 	// Last scan should get us in correct timestamp order
-	ids, err := r.scanIndexKeys(indexSeeks[0], plan)
+	keys, err := r.scanIndexKeys(indexSeeks[0], plan)
 	if err != nil {
 		return nil, err
 	}
@@ -333,32 +351,43 @@ func (r *TraceReader) indexSeeksToTraceIDs(plan *executionPlan, indexSeeks [][]b
 		plan.mergeOuter = nil
 	} else {
 		// We filter the last elements
+		ids := make([][]byte, len(keys))
+		for i, key := range keys {
+			ids[i] = key[8:]
+		}
 		plan.hashOuter = buildHash(plan, ids)
 	}
 
-	traceIDs := filterIDs(plan, ids)
-	return traceIDs, nil
+	return filterKeys(plan, keys), nil
 }
 
-func filterIDs(plan *executionPlan, innerIDs [][]byte) []model.TraceID {
-	traces := make([]model.TraceID, 0, plan.limit)
+// filterKeys returns, in order, the first key of each trace that is in plan.hashOuter,
+// skipping the traces returned in previous pages.
+func filterKeys(plan *executionPlan, innerKeys [][]byte) [][]byte {
+	traceKeys := make([][]byte, 0, plan.limit)
 
 	items := 0
-	for i := 0; i < len(innerIDs); i++ {
-		trID := bytesToTraceID(innerIDs[i])
+	for i := 0; i < len(innerKeys); i++ {
+		trID := bytesToTraceID(innerKeys[i][8:])
+
+		if plan.resumeKey != nil && bytes.Compare(innerKeys[i], plan.resumeKey) >= 0 {
+			// The keys are in descending order, so this trace was in a previous page
+			delete(plan.hashOuter, trID)
+			continue
+		}
 
 		if _, found := plan.hashOuter[trID]; found {
-			traces = append(traces, trID)
+			traceKeys = append(traceKeys, innerKeys[i])
 			delete(plan.hashOuter, trID) // Prevent duplicate add
 			items++
 		}
 
-		if items == plan.limit {
-			return traces
+		if plan.limit > 0 && items == plan.limit {
+			return traceKeys
 		}
 	}
 
-	return traces
+	return traceKeys
 }
 
 func bytesToTraceID(key []byte) model.TraceID {
@@ -456,6 +485,15 @@ func mergeJoinIds(left, right [][]byte) [][]byte {
 
 // FindTraces retrieves traces that match the traceQuery
 func (r *TraceReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	if query != nil && needsTagVerification(query) {
+		if err := validateQuery(query); err != nil {
+			return nil, err
+		}
+		setQueryDefaults(query)
+		traces, _, err := r.findVerifiedTraces(query, nil)
+		return traces, err
+	}
+
 	keys, err := r.FindTraceIDs(ctx, query)
 	if err != nil {
 		return nil, err
@@ -464,6 +502,66 @@ func (r *TraceReader) FindTraces(ctx context.Context, query *spanstore.TraceQuer
 	return r.getTraces(keys)
 }
 
+// pageCursor is the page token content, the trace key of the last trace of a page.
+type pageCursor struct {
+	ResumeKey []byte
+}
+
+// FindTracesPage implements spanstore.PagedReader. The next page token holds the
+// trace key the index scans resume after.
+func (r *TraceReader) FindTracesPage(ctx context.Context, query *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	setQueryDefaults(query)
+
+	var resumeKey []byte
+	if query.PageToken != "" {
+		var cursor pageCursor
+		if err := spanstore.DecodePageToken(query.PageToken, &cursor); err != nil {
+			return nil, err
+		}
+		if len(cursor.ResumeKey) != sizeOfTraceKey {
+			return nil, spanstore.ErrInvalidPageToken
+		}
+		resumeKey = cursor.ResumeKey
+	}
+
+	var traces []*model.Trace
+	var lastKey []byte
+	if needsTagVerification(query) {
+		var err error
+		traces, lastKey, err = r.findVerifiedTraces(query, resumeKey)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Fetch one extra key to know if there is a next page
+		keys, err := r.findTraceKeys(query, query.NumTraces+1, resumeKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > query.NumTraces {
+			keys = keys[:query.NumTraces]
+			lastKey = keys[len(keys)-1]
+		}
+		traces, err = r.getTraces(traceKeysToIDs(keys))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page := &spanstore.TracePage{Traces: traces}
+	if lastKey != nil {
+		token, err := spanstore.EncodePageToken(pageCursor{ResumeKey: lastKey})
+		if err != nil {
+			return nil, err
+		}
+		page.NextPageToken = token
+	}
+	return page, nil
+}
+
 // FindTraceIDs retrieves only the TraceIDs that match the traceQuery, but not the trace data
 func (r *TraceReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
 	// Validate and set query defaults which were not defined
@@ -473,6 +571,76 @@ func (r *TraceReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQu
 
 	setQueryDefaults(query)
 
+	if needsTagVerification(query) {
+		traces, _, err := r.findVerifiedTraces(query, nil)
+		if err != nil {
+			return nil, err
+		}
+		traceIDs := make([]model.TraceID, len(traces))
+		for i, trace := range traces {
+			traceIDs[i] = trace.Spans[0].TraceID
+		}
+		return traceIDs, nil
+	}
+
+	keys, err := r.findTraceKeys(query, query.NumTraces, nil)
+	if err != nil {
+		return nil, err
+	}
+	return traceKeysToIDs(keys), nil
+}
+
+// needsTagVerification returns true if the indexes alone cannot answer the query.
+// Tag filters are not indexed, and intersecting the tag indexes matches tags
+// found on different spans, which is only correct for TagMatchAnySpanInTrace.
+func needsTagVerification(query *spanstore.TraceQueryParameters) bool {
+	if len(query.TagFilters) > 0 {
+		return true
+	}
+	return query.TagMatchMode == spanstore.TagMatchSameSpan && len(query.Tags) > 1
+}
+
+// findVerifiedTraces uses the indexes to find candidate traces for the query
+// without its tag filters, then loads the candidates newest first and keeps those
+// whose spans of the queried service satisfy the tags according to the TagMatchMode.
+// It also returns the trace key of the last trace if more candidates remain.
+func (r *TraceReader) findVerifiedTraces(query *spanstore.TraceQueryParameters, resumeKey []byte) ([]*model.Trace, []byte, error) {
+	// limit 0 returns every candidate in the time range
+	candidates, err := r.findTraceKeys(query, 0, resumeKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	retMe := make([]*model.Trace, 0, query.NumTraces)
+	for start := 0; start < len(candidates); start += tagVerificationBatchSize {
+		end := start + tagVerificationBatchSize
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		batch := candidates[start:end]
+		traces, err := r.getTraces(traceKeysToIDs(batch))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, trace := range traces {
+			if spanstore.MatchTraceTags(trace, query) {
+				retMe = append(retMe, trace)
+				if len(retMe) == query.NumTraces {
+					lastKey := traceKey(batch, trace.Spans[0].TraceID)
+					if bytes.Equal(lastKey, candidates[len(candidates)-1]) {
+						return retMe, nil, nil
+					}
+					return retMe, lastKey, nil
+				}
+			}
+		}
+	}
+	return retMe, nil, nil
+}
+
+// findTraceKeys returns up to limit trace keys, or all of them if limit is 0,
+// matching the indexed fields of the query and sorting after resumeKey, if set.
+func (r *TraceReader) findTraceKeys(query *spanstore.TraceQueryParameters, limit int, resumeKey []byte) ([][]byte, error) {
 	// Find matches using indexes that are using service as part of the key
 	indexSeeks := make([][]byte, 0, 1)
 	indexSeeks = serviceQueries(query, indexSeeks)
@@ -486,7 +654,8 @@ func (r *TraceReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQu
 	plan := &executionPlan{
 		startTimeMin: startStampBytes,
 		startTimeMax: endStampBytes,
-		limit:        query.NumTraces,
+		limit:        limit,
+		resumeKey:    resumeKey,
 	}
 
 	if query.DurationMax != 0 || query.DurationMin != 0 {
@@ -494,7 +663,7 @@ func (r *TraceReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQu
 	}
 
 	if len(indexSeeks) > 0 {
-		keys, err := r.indexSeeksToTraceIDs(plan, indexSeeks)
+		keys, err := r.indexSeeksToTraceKeys(plan, indexSeeks)
 		if err != nil {
 			return nil, err
 		}
@@ -505,6 +674,24 @@ func (r *TraceReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQu
 	return r.scanTimeRange(plan)
 }
 
+// traceKey returns the key of traceID among keys.
+func traceKey(keys [][]byte, traceID model.TraceID) []byte {
+	for _, key := range keys {
+		if bytesToTraceID(key[8:]) == traceID {
+			return key
+		}
+	}
+	return nil
+}
+
+func traceKeysToIDs(keys [][]byte) []model.TraceID {
+	traceIDs := make([]model.TraceID, len(keys))
+	for i, key := range keys {
+		traceIDs[i] = bytesToTraceID(key[8:])
+	}
+	return traceIDs
+}
+
 // validateQuery returns an error if certain restrictions are not met
 func validateQuery(p *spanstore.TraceQueryParameters) error {
 	if p == nil {
@@ -528,7 +715,8 @@ func validateQuery(p *spanstore.TraceQueryParameters) error {
 	return nil
 }
 
-// scanIndexKeys scans the time range for index keys matching the given prefix.
+// scanIndexKeys scans the time range for index keys matching the given prefix
+// and returns their trace keys in descending order.
 func (r *TraceReader) scanIndexKeys(indexKeyValue []byte, plan *executionPlan) ([][]byte, error) {
 	indexResults := make([][]byte, 0)
 
@@ -552,11 +740,9 @@ func (r *TraceReader) scanIndexKeys(indexKeyValue []byte, plan *executionPlan) (
 			// Now we need to match only the exact key if we want to add it
 			timestampStartIndex := len(it.Item().Key()) - (sizeOfTraceID + 8) // timestamp is stored with 8 bytes
 			if bytes.Equal(indexKeyValue, it.Item().Key()[:timestampStartIndex]) {
-				traceIDBytes := item.Key()[len(item.Key())-sizeOfTraceID:]
-
-				traceIDCopy := make([]byte, sizeOfTraceID)
-				copy(traceIDCopy, traceIDBytes)
-				indexResults = append(indexResults, traceIDCopy)
+				traceKeyCopy := make([]byte, sizeOfTraceKey)
+				copy(traceKeyCopy, item.Key()[timestampStartIndex:])
+				indexResults = append(indexResults, traceKeyCopy)
 			}
 		}
 		return nil
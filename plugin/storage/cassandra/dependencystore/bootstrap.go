@@ -25,5 +25,8 @@ func GetDependencyVersion(s cassandra.Session) Version {
 	if err := s.Query("SELECT ts from dependencies_v2 limit 1;").Exec(); err != nil {
 		return V1
 	}
-	return V2
+	if err := s.Query("SELECT writer_id from dependencies_v2 limit 1;").Exec(); err != nil {
+		return V2
+	}
+	return V3
 }
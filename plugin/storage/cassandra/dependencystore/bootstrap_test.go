@@ -41,7 +41,20 @@ func TestGetDependencyVersionV2(t *testing.T) {
 		session = &mocks.Session{}
 		query   = &mocks.Query{}
 	)
-	session.On("Query", mock.AnythingOfType("string"), mock.Anything).Return(query)
+	writerIDQuery := &mocks.Query{}
+	session.On("Query", "SELECT ts from dependencies_v2 limit 1;", mock.Anything).Return(query)
+	session.On("Query", "SELECT writer_id from dependencies_v2 limit 1;", mock.Anything).Return(writerIDQuery)
 	query.On("Exec").Return(nil)
+	writerIDQuery.On("Exec").Return(errors.New("error"))
 	assert.Equal(t, V2, GetDependencyVersion(session))
 }
+
+func TestGetDependencyVersionV3(t *testing.T) {
+	var (
+		session = &mocks.Session{}
+		query   = &mocks.Query{}
+	)
+	session.On("Query", mock.AnythingOfType("string"), mock.Anything).Return(query)
+	query.On("Exec").Return(nil)
+	assert.Equal(t, V3, GetDependencyVersion(session))
+}
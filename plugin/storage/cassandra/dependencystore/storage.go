@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gocql/gocql"
 	"go.uber.org/zap"
 
 	"github.com/kjschnei001/jaeger/model"
@@ -43,10 +44,15 @@ const (
 
 	// V2 is used when the dependency table is NOT SASI indexed.
 	V2
+
+	// V3 is used when the dependency table is NOT SASI indexed and the writer ID is part of
+	// its primary key, so that the links written at the same time by several collectors are kept.
+	V3
 	versionEnumEnd
 
 	depsInsertStmtV1 = "INSERT INTO dependencies(ts, ts_index, dependencies) VALUES (?, ?, ?)"
 	depsInsertStmtV2 = "INSERT INTO dependencies_v2(ts, ts_bucket, dependencies) VALUES (?, ?, ?)"
+	depsInsertStmtV3 = "INSERT INTO dependencies_v2(ts, ts_bucket, writer_id, dependencies) VALUES (?, ?, ?, ?)"
 	depsSelectStmtV1 = "SELECT ts, dependencies FROM dependencies WHERE ts_index >= ? AND ts_index < ?"
 	depsSelectStmtV2 = "SELECT ts, dependencies FROM dependencies_v2 WHERE ts_bucket IN ? AND ts >= ? AND ts < ?"
 
@@ -62,6 +68,7 @@ type DependencyStore struct {
 	dependenciesTableMetrics *casMetrics.Table
 	logger                   *zap.Logger
 	version                  Version
+	writerID                 gocql.UUID
 }
 
 // NewDependencyStore returns a DependencyStore
@@ -79,6 +86,7 @@ func NewDependencyStore(
 		dependenciesTableMetrics: casMetrics.NewTable(metricsFactory, "dependencies"),
 		logger:                   logger,
 		version:                  version,
+		writerID:                 gocql.TimeUUID(),
 	}, nil
 }
 
@@ -100,6 +108,8 @@ func (s *DependencyStore) WriteDependencies(ts time.Time, dependencies []model.D
 		query = s.session.Query(depsInsertStmtV1, ts, ts, deps)
 	case V2:
 		query = s.session.Query(depsInsertStmtV2, ts, ts.Truncate(tsBucket), deps)
+	case V3:
+		query = s.session.Query(depsInsertStmtV3, ts, ts.Truncate(tsBucket), s.writerID, deps)
 	}
 	return s.dependenciesTableMetrics.Exec(query, s.logger)
 }
@@ -111,7 +121,7 @@ func (s *DependencyStore) GetDependencies(ctx context.Context, endTs time.Time,
 	switch s.version {
 	case V1:
 		query = s.session.Query(depsSelectStmtV1, startTs, endTs)
-	case V2:
+	case V2, V3:
 		query = s.session.Query(depsSelectStmtV2, getBuckets(startTs, endTs), startTs, endTs)
 	}
 	iter := query.Consistency(cassandra.One).Iter()
@@ -65,6 +65,7 @@ var (
 func TestVersionIsValid(t *testing.T) {
 	assert.True(t, V1.IsValid())
 	assert.True(t, V2.IsValid())
+	assert.True(t, V3.IsValid())
 	assert.False(t, versionEnumEnd.IsValid())
 }
 
@@ -86,6 +87,10 @@ func TestDependencyStoreWrite(t *testing.T) {
 			caption: "V2",
 			version: V2,
 		},
+		{
+			caption: "V3",
+			version: V3,
+		},
 	}
 	for _, tc := range testCases {
 		testCase := tc // capture loop var
@@ -114,13 +119,18 @@ func TestDependencyStoreWrite(t *testing.T) {
 				err := s.storage.WriteDependencies(ts, dependencies)
 				assert.NoError(t, err)
 
+				if testCase.version == V3 {
+					assert.Len(t, args, 4)
+					assert.Equal(t, s.storage.writerID, args[2])
+					args = append(args[:2], args[3])
+				}
 				assert.Len(t, args, 3)
 				if d, ok := args[0].(time.Time); ok {
 					assert.Equal(t, ts, d)
 				} else {
 					assert.Fail(t, "expecting first arg as time.Time", "received: %+v", args)
 				}
-				if testCase.version == V2 {
+				if testCase.version != V1 {
 					if d, ok := args[1].(time.Time); ok {
 						assert.Equal(t, time.Date(2017, time.January, 24, 0, 0, 0, 0, time.UTC), d)
 					} else {
@@ -166,6 +176,10 @@ func TestDependencyStoreGetDependencies(t *testing.T) {
 			caption: "success V2",
 			version: V2,
 		},
+		{
+			caption: "success V3",
+			version: V3,
+		},
 		{
 			caption:       "failure V1",
 			queryError:    errors.New("query error"),
@@ -87,6 +87,9 @@ func TestCassandraFactory(t *testing.T) {
 	_, err = f.CreateDependencyReader()
 	assert.NoError(t, err)
 
+	_, err = f.CreateDependencyWriter()
+	assert.NoError(t, err)
+
 	_, err = f.CreateArchiveSpanReader()
 	assert.EqualError(t, err, "archive storage not configured")
 
@@ -0,0 +1,99 @@
+#!/usr/bin/env bash
+
+# Recreate the dependencies_v2 table with the writer_id clustering column, so that the dependency links
+# written at the same time by several collectors do not overwrite each other, and copy its data.
+# Sample usage: KEYSPACE=jaeger_v1 CQL_CMD='cqlsh host 9042 -u test_user -p test_password --request-timeout=3000' bash
+# ./V004toV004WriterID.sh
+
+set -euo pipefail
+
+function usage {
+    >&2 echo "Error: $1"
+    >&2 echo ""
+    >&2 echo "Usage: KEYSPACE={keyspace} CQL_CMD={cql_cmd} $0"
+    >&2 echo ""
+    >&2 echo "The following parameters can be set via environment:"
+    >&2 echo "  KEYSPACE           - keyspace"
+    >&2 echo "  CQL_CMD            - cqlsh host port -u user -p password"
+    >&2 echo ""
+    exit 1
+}
+
+confirm() {
+    read -r -p "${1:-Continue? [y/N]} " response
+    case "$response" in
+        [yY][eE][sS]|[yY])
+            true
+            ;;
+        *)
+            exit 1
+            ;;
+    esac
+}
+
+if [[ ${KEYSPACE} == "" ]]; then
+   usage "missing KEYSPACE parameter"
+fi
+
+if [[ ${KEYSPACE} =~ [^a-zA-Z0-9_] ]]; then
+    usage "invalid characters in KEYSPACE=$KEYSPACE parameter, please use letters, digits or underscores"
+fi
+
+keyspace=${KEYSPACE}
+table=dependencies_v2
+cqlsh_cmd=${CQL_CMD}
+
+if [[ ${cqlsh_cmd} == "" ]]; then
+   cqlsh_cmd=cqlsh
+fi
+
+echo "Using cql command: $cqlsh_cmd"
+
+row_count=$(${cqlsh_cmd} -e "select count(*) from $keyspace.$table;"|head -4|tail -1| tr -d ' ')
+
+echo "About to export $row_count rows and recreate the table $keyspace.$table..."
+
+confirm
+
+${cqlsh_cmd} -e "COPY $keyspace.$table (ts_bucket, ts, dependencies) to '$table.csv';"
+
+if [[ ! -f ${table}.csv ]]; then
+    echo "Could not find $table.csv. Backup from cassandra was probably not successful"
+    exit 1
+fi
+
+# the rows written before the migration all get the same writer ID
+writer_id=$(${cqlsh_cmd} -e "select uuid() from system.local;"|head -4|tail -1|tr -d ' ')
+
+echo "Generating data for new table..."
+while IFS= read -r line; do
+    ts_bucket_ts=$(echo "$line" | cut -d, -f1-2)
+    dependencies=$(echo "$line" | cut -d, -f3-)
+    echo "$ts_bucket_ts,$writer_id,$dependencies"
+done < ${table}.csv > ${table}_writer_id.csv
+
+ttl=$(${cqlsh_cmd} -e "select default_time_to_live from system_schema.tables WHERE keyspace_name='$keyspace' AND table_name='$table';"|head -4|tail -1|tr -d ' ')
+
+echo "Recreating table $table with ttl: $ttl"
+
+${cqlsh_cmd} -e "DROP TABLE IF EXISTS $keyspace.$table;"
+${cqlsh_cmd} -e "CREATE TABLE IF NOT EXISTS $keyspace.$table (
+    ts_bucket    timestamp,
+    ts           timestamp,
+    writer_id    uuid,
+    dependencies list<frozen<dependency>>,
+    PRIMARY KEY (ts_bucket, ts, writer_id)
+) WITH CLUSTERING ORDER BY (ts DESC, writer_id ASC)
+    AND compaction = {
+        'min_threshold': '4',
+        'max_threshold': '32',
+        'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy'
+    }
+    AND default_time_to_live = $ttl;"
+
+echo "Import data to table: $keyspace.$table from ${table}_writer_id.csv"
+
+${cqlsh_cmd} -e "COPY $keyspace.$table (ts_bucket, ts, writer_id, dependencies)
+    FROM '${table}_writer_id.csv';"
+
+echo "Data are successfully imported to the recreated table!"
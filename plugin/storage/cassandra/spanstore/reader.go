@@ -16,6 +16,7 @@
 package spanstore
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -40,6 +41,15 @@ const (
 		SELECT trace_id, span_id, parent_id, operation_name, flags, start_time, duration, tags, logs, refs, process
 		FROM traces
 		WHERE trace_id = ?`
+	querySpansByTraceIDs = `
+		SELECT trace_id, span_id, parent_id, operation_name, flags, start_time, duration, tags, logs, refs, process
+		FROM traces
+		WHERE trace_id IN ?`
+	// querySpanSummaryByTraceID skips the logs, which are not needed to summarize a trace
+	querySpanSummaryByTraceID = `
+		SELECT span_id, parent_id, operation_name, start_time, duration, tags, refs, process
+		FROM traces
+		WHERE trace_id = ?`
 	queryByTag = `
 		SELECT trace_id
 		FROM tag_index
@@ -63,6 +73,17 @@ const (
 		FROM duration_index
 		WHERE bucket = ? AND service_name = ? AND operation_name = ? AND duration > ? AND duration < ?
 		LIMIT ?`
+	// The paged queries have no LIMIT, the page size limits the rows returned at a time
+	queryByTagPaged = `
+		SELECT trace_id, start_time
+		FROM tag_index
+		WHERE service_name = ? AND tag_key = ? AND tag_value = ? and start_time > ? and start_time < ?
+		ORDER BY start_time DESC`
+	queryByServiceAndOperationNamePaged = `
+		SELECT trace_id, start_time
+		FROM service_operation_index
+		WHERE service_name = ? AND operation_name = ? AND start_time > ? AND start_time < ?
+		ORDER BY start_time DESC`
 
 	defaultNumTraces = 100
 	// limitMultiple exists because many spans that are returned from indices can have the same trace, limitMultiple increases
@@ -162,8 +183,19 @@ func (s *SpanReader) readTrace(ctx context.Context, traceID dbmodel.TraceID) (*m
 
 func (s *SpanReader) readTraceInSpan(ctx context.Context, traceID dbmodel.TraceID) (*model.Trace, error) {
 	start := time.Now()
-	q := s.session.Query(querySpanByTraceID, traceID)
-	i := q.Iter()
+	spans, err := s.scanSpans(s.session.Query(querySpanByTraceID, traceID).Iter())
+	s.metrics.readTraces.Emit(err, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return &model.Trace{Spans: spans}, nil
+}
+
+// scanSpans reads all the spans of the iterator and closes it.
+func (s *SpanReader) scanSpans(i cassandra.Iterator) ([]*model.Span, error) {
 	var traceIDFromSpan dbmodel.TraceID
 	var startTime, spanID, duration, parentID int64
 	var flags int32
@@ -172,7 +204,7 @@ func (s *SpanReader) readTraceInSpan(ctx context.Context, traceID dbmodel.TraceI
 	var refs []dbmodel.SpanRef
 	var tags []dbmodel.KeyValue
 	var logs []dbmodel.Log
-	retMe := &model.Trace{}
+	var spans []*model.Span
 	for i.Scan(&traceIDFromSpan, &spanID, &parentID, &operationName, &flags, &startTime, &duration, &tags, &logs, &refs, &dbProcess) {
 		dbSpan := dbmodel.Span{
 			TraceID:       traceIDFromSpan,
@@ -190,21 +222,15 @@ func (s *SpanReader) readTraceInSpan(ctx context.Context, traceID dbmodel.TraceI
 		}
 		span, err := dbmodel.ToDomain(&dbSpan)
 		if err != nil {
-			s.metrics.readTraces.Emit(err, time.Since(start))
+			i.Close()
 			return nil, err
 		}
-		retMe.Spans = append(retMe.Spans, span)
+		spans = append(spans, span)
 	}
-
-	err := i.Close()
-	s.metrics.readTraces.Emit(err, time.Since(start))
-	if err != nil {
+	if err := i.Close(); err != nil {
 		return nil, fmt.Errorf("error reading traces from storage: %w", err)
 	}
-	if len(retMe.Spans) == 0 {
-		return nil, spanstore.ErrTraceNotFound
-	}
-	return retMe, nil
+	return spans, nil
 }
 
 // GetTrace takes a traceID and returns a Trace associated with that traceID
@@ -212,6 +238,30 @@ func (s *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*mode
 	return s.readTrace(ctx, dbmodel.TraceIDFromDomain(traceID))
 }
 
+// GetTraces implements spanstore.BatchReader#GetTraces. The spans of all the
+// traces are read with a single IN query on the traces table.
+func (s *SpanReader) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	span, _ := startSpanForQuery(ctx, "readTraces", querySpansByTraceIDs)
+	defer span.Finish()
+	span.LogFields(otlog.String("event", "searching"), otlog.Int("trace_count", len(traceIDs)))
+
+	if len(traceIDs) == 0 {
+		return nil, nil
+	}
+	dbTraceIDs := make([]dbmodel.TraceID, len(traceIDs))
+	for i, traceID := range traceIDs {
+		dbTraceIDs[i] = dbmodel.TraceIDFromDomain(traceID)
+	}
+	start := time.Now()
+	spans, err := s.scanSpans(s.session.Query(querySpansByTraceIDs, dbTraceIDs).Iter())
+	s.metrics.readTraces.Emit(err, time.Since(start))
+	if err != nil {
+		logErrorToSpan(span, err)
+		return nil, err
+	}
+	return spanstore.TracesInOrder(spans, traceIDs), nil
+}
+
 func validateQuery(p *spanstore.TraceQueryParameters) error {
 	if p == nil {
 		return ErrMalformedRequestObject
@@ -236,12 +286,17 @@ func validateQuery(p *spanstore.TraceQueryParameters) error {
 
 // FindTraces retrieves traces that match the traceQuery
 func (s *SpanReader) FindTraces(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
-	uniqueTraceIDs, err := s.FindTraceIDs(ctx, traceQuery)
+	uniqueTraceIDs, verifiedTraces, err := s.findVerifiedTraceIDs(ctx, traceQuery)
 	if err != nil {
 		return nil, err
 	}
 	var retMe []*model.Trace
 	for _, traceID := range uniqueTraceIDs {
+		// the traces read to verify the tags are not read again
+		if jTrace, ok := verifiedTraces[traceID]; ok {
+			retMe = append(retMe, jTrace)
+			continue
+		}
 		jTrace, err := s.GetTrace(ctx, traceID)
 		if err != nil {
 			s.logger.Error("Failure to read trace", zap.String("trace_id", traceID.String()), zap.Error(err))
@@ -254,8 +309,23 @@ func (s *SpanReader) FindTraces(ctx context.Context, traceQuery *spanstore.Trace
 
 // FindTraceIDs retrieve traceIDs that match the traceQuery
 func (s *SpanReader) FindTraceIDs(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	traceIDs, _, err := s.findVerifiedTraceIDs(ctx, traceQuery)
+	return traceIDs, err
+}
+
+// findVerifiedTraceIDs returns the IDs of the traces matching the traceQuery, and the
+// traces that were read to verify that their tags were found on the same span.
+func (s *SpanReader) findVerifiedTraceIDs(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]model.TraceID, map[model.TraceID]*model.Trace, error) {
+	// The tag index only supports exact matches, so tag filters with other operators are rejected.
+	traceQuery, err := spanstore.FoldEqualityTagFilters(traceQuery)
+	if errors.Is(err, spanstore.ErrUnsatisfiableTagFilters) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
 	if err := validateQuery(traceQuery); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if traceQuery.NumTraces == 0 {
 		traceQuery.NumTraces = defaultNumTraces
@@ -263,17 +333,272 @@ func (s *SpanReader) FindTraceIDs(ctx context.Context, traceQuery *spanstore.Tra
 
 	dbTraceIDs, err := s.findTraceIDs(ctx, traceQuery)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	// Each tag, including the equality tag filters folded into the tags, is looked up in
+	// the tag index separately, so with several tags the candidates include traces where
+	// the tags were found on different spans.
+	verifySameSpan := traceQuery.TagMatchMode == spanstore.TagMatchSameSpan && len(traceQuery.Tags) > 1
 	var traceIDs []model.TraceID
+	var verifiedTraces map[model.TraceID]*model.Trace
 	for t := range dbTraceIDs {
 		if len(traceIDs) >= traceQuery.NumTraces {
 			break
 		}
+		if verifySameSpan {
+			trace, err := s.readTrace(ctx, t)
+			if err != nil {
+				s.logger.Error("Failure to read trace", zap.String("trace_id", t.ToDomain().String()), zap.Error(err))
+				continue
+			}
+			if !spanstore.MatchTraceTags(trace, traceQuery) {
+				continue
+			}
+			if verifiedTraces == nil {
+				verifiedTraces = make(map[model.TraceID]*model.Trace)
+			}
+			verifiedTraces[t.ToDomain()] = trace
+		}
 		traceIDs = append(traceIDs, t.ToDomain())
 	}
-	return traceIDs, nil
+	return traceIDs, verifiedTraces, nil
+}
+
+// FindTraceSummaries implements spanstore.SummaryReader. The spans of the traces are read without their logs.
+func (s *SpanReader) FindTraceSummaries(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]*spanstore.TraceSummary, error) {
+	uniqueTraceIDs, err := s.FindTraceIDs(ctx, traceQuery)
+	if err != nil {
+		return nil, err
+	}
+	var retMe []*spanstore.TraceSummary
+	for _, traceID := range uniqueTraceIDs {
+		summary, err := s.readTraceSummary(ctx, dbmodel.TraceIDFromDomain(traceID))
+		if err != nil {
+			s.logger.Error("Failure to read trace", zap.String("trace_id", traceID.String()), zap.Error(err))
+			continue
+		}
+		retMe = append(retMe, summary)
+	}
+	return retMe, nil
+}
+
+func (s *SpanReader) readTraceSummary(ctx context.Context, traceID dbmodel.TraceID) (*spanstore.TraceSummary, error) {
+	span, _ := startSpanForQuery(ctx, "readTraceSummary", querySpanSummaryByTraceID)
+	defer span.Finish()
+	span.LogFields(otlog.String("event", "searching"), otlog.Object("trace_id", traceID))
+
+	start := time.Now()
+	i := s.session.Query(querySpanSummaryByTraceID, traceID).Iter()
+	var startTime, spanID, duration, parentID int64
+	var operationName string
+	var dbProcess dbmodel.Process
+	var refs []dbmodel.SpanRef
+	var tags []dbmodel.KeyValue
+	trace := &model.Trace{}
+	for i.Scan(&spanID, &parentID, &operationName, &startTime, &duration, &tags, &refs, &dbProcess) {
+		dbSpan := dbmodel.Span{
+			TraceID:       traceID,
+			SpanID:        spanID,
+			ParentID:      parentID,
+			OperationName: operationName,
+			StartTime:     startTime,
+			Duration:      duration,
+			Tags:          tags,
+			Refs:          refs,
+			Process:       dbProcess,
+			ServiceName:   dbProcess.ServiceName,
+		}
+		modelSpan, err := dbmodel.ToDomain(&dbSpan)
+		if err != nil {
+			s.metrics.readTraces.Emit(err, time.Since(start))
+			logErrorToSpan(span, err)
+			return nil, err
+		}
+		trace.Spans = append(trace.Spans, modelSpan)
+	}
+
+	err := i.Close()
+	s.metrics.readTraces.Emit(err, time.Since(start))
+	if err != nil {
+		logErrorToSpan(span, err)
+		return nil, fmt.Errorf("error reading traces from storage: %w", err)
+	}
+	if len(trace.Spans) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return spanstore.SummarizeTrace(trace), nil
+}
+
+// pageCursor is the page token content, the paging state of the index query and
+// the last index row of the page.
+type pageCursor struct {
+	PageState []byte
+	StartTime int64
+	TraceID   []byte
+}
+
+// inPreviousPage returns true if one of the spans found by the index query comes before the cursor.
+// Index rows are ordered by start_time descending, then trace_id ascending.
+func (c pageCursor) inPreviousPage(trace *model.Trace, matchSpan func(*model.Span) bool) bool {
+	for _, span := range trace.Spans {
+		if !matchSpan(span) {
+			continue
+		}
+		startTime := int64(model.TimeAsEpochMicroseconds(span.StartTime))
+		traceID := dbmodel.TraceIDFromDomain(span.TraceID)
+		if startTime > c.StartTime || (startTime == c.StartTime && bytes.Compare(traceID[:], c.TraceID) <= 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// pagedIndexQuery is an index query that can be paged through.
+type pagedIndexQuery struct {
+	stmt         string
+	values       []interface{}
+	tableMetrics *casMetrics.Table
+	// matchSpan returns true for the spans the query finds in the index
+	matchSpan func(span *model.Span) bool
+}
+
+// buildPagedIndexQuery returns the index query for traceQuery. The paging state of
+// Cassandra is only usable when a single index partition answers the query, which
+// is the case when searching by service and operation name, or by service and one tag.
+func (s *SpanReader) buildPagedIndexQuery(tq *spanstore.TraceQueryParameters) (*pagedIndexQuery, error) {
+	startTimeMin := model.TimeAsEpochMicroseconds(tq.StartTimeMin)
+	startTimeMax := model.TimeAsEpochMicroseconds(tq.StartTimeMax)
+	switch {
+	case tq.DurationMin != 0 || tq.DurationMax != 0:
+		return nil, fmt.Errorf("%w: %s", spanstore.ErrPaginationNotSupported, "cannot page queries by duration")
+	case tq.OperationName != "" && len(tq.Tags) == 0:
+		return &pagedIndexQuery{
+			stmt:         queryByServiceAndOperationNamePaged,
+			values:       []interface{}{tq.ServiceName, tq.OperationName, startTimeMin, startTimeMax},
+			tableMetrics: s.metrics.queryServiceOperationIndex,
+			matchSpan: func(span *model.Span) bool {
+				return span.Process.ServiceName == tq.ServiceName && span.OperationName == tq.OperationName
+			},
+		}, nil
+	case tq.OperationName == "" && len(tq.Tags) == 1:
+		for k, v := range tq.Tags {
+			return &pagedIndexQuery{
+				stmt:         queryByTagPaged,
+				values:       []interface{}{tq.ServiceName, k, v, startTimeMin, startTimeMax},
+				tableMetrics: s.metrics.queryTagIndex,
+				matchSpan: func(span *model.Span) bool {
+					if span.Process.ServiceName != tq.ServiceName {
+						return false
+					}
+					for _, tag := range dbmodel.GetAllUniqueTags(span, dbmodel.DefaultTagFilter) {
+						if tag.TagKey == k && tag.TagValue == v {
+							return true
+						}
+					}
+					return false
+				},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", spanstore.ErrPaginationNotSupported, "only queries by service and operation name, or by service and one tag, can be paged")
+}
+
+// FindTracesPage implements spanstore.PagedReader with the paging state of the index query.
+// Pages have at most one trace per index row. For the queries buildPagedIndexQuery does
+// not support, the first page has all the results of FindTraces and no next page token.
+func (s *SpanReader) FindTracesPage(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	traceQuery, err := spanstore.FoldEqualityTagFilters(traceQuery)
+	if errors.Is(err, spanstore.ErrUnsatisfiableTagFilters) {
+		return &spanstore.TracePage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuery(traceQuery); err != nil {
+		return nil, err
+	}
+	if traceQuery.NumTraces == 0 {
+		traceQuery.NumTraces = defaultNumTraces
+	}
+	var cursor *pageCursor
+	if traceQuery.PageToken != "" {
+		cursor = &pageCursor{}
+		if err := spanstore.DecodePageToken(traceQuery.PageToken, cursor); err != nil {
+			return nil, err
+		}
+	}
+	indexQuery, err := s.buildPagedIndexQuery(traceQuery)
+	if errors.Is(err, spanstore.ErrPaginationNotSupported) && cursor == nil {
+		// Like readers without pagination, return all the results in the first page
+		traces, err := s.FindTraces(ctx, traceQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &spanstore.TracePage{Traces: traces}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := startSpanForQuery(ctx, "findTracesPage", indexQuery.stmt)
+	defer span.Finish()
+	var pageState []byte
+	if cursor != nil {
+		pageState = cursor.PageState
+	}
+	// Setting the page state, even to nil for the first page, disables automatic paging
+	query := s.session.Query(indexQuery.stmt, indexQuery.values...).
+		PageSize(traceQuery.NumTraces).
+		PageState(pageState)
+
+	start := time.Now()
+	i := query.Iter()
+	var rows []pageCursor
+	var traceID dbmodel.TraceID
+	var startTime int64
+	for i.Scan(&traceID, &startTime) {
+		rows = append(rows, pageCursor{StartTime: startTime, TraceID: append([]byte{}, traceID[:]...)})
+	}
+	nextPageState := i.PageState()
+	err = i.Close()
+	indexQuery.tableMetrics.Emit(err, time.Since(start))
+	if err != nil {
+		logErrorToSpan(span, err)
+		span.LogFields(otlog.String("query", query.String()))
+		s.logger.Error("Failed to exec query", zap.Error(err), zap.String("query", query.String()))
+		return nil, err
+	}
+
+	page := &spanstore.TracePage{}
+	seen := make(map[string]struct{})
+	for _, row := range rows {
+		if _, ok := seen[string(row.TraceID)]; ok {
+			continue
+		}
+		seen[string(row.TraceID)] = struct{}{}
+		var dbTraceID dbmodel.TraceID
+		copy(dbTraceID[:], row.TraceID)
+		trace, err := s.readTrace(ctx, dbTraceID)
+		if err != nil {
+			s.logger.Error("Failure to read trace", zap.String("trace_id", dbTraceID.ToDomain().String()), zap.Error(err))
+			continue
+		}
+		if cursor != nil && cursor.inPreviousPage(trace, indexQuery.matchSpan) {
+			continue
+		}
+		page.Traces = append(page.Traces, trace)
+	}
+	if len(nextPageState) > 0 && len(rows) > 0 {
+		next := rows[len(rows)-1]
+		next.PageState = nextPageState
+		token, err := spanstore.EncodePageToken(next)
+		if err != nil {
+			return nil, err
+		}
+		page.NextPageToken = token
+	}
+	return page, nil
 }
 
 func (s *SpanReader) findTraceIDs(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) (dbmodel.UniqueTraceIDs, error) {
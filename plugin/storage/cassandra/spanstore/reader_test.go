@@ -400,6 +400,55 @@ func TestSpanReaderFindTraces(t *testing.T) {
 	}
 }
 
+func TestSpanReaderFindTraceSummaries(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		indexIter := &mocks.Iterator{}
+		indexIter.On("Scan", matchOnceWithSideEffect(func(args []interface{}) {
+			*args[0].(*dbmodel.TraceID) = dbmodel.TraceIDFromDomain(model.NewTraceID(0, 1))
+		})).Return(true)
+		indexIter.On("Scan", matchEverything()).Return(false)
+		indexIter.On("Close").Return(nil)
+		indexQuery := &mocks.Query{}
+		indexQuery.On("Consistency", cassandra.One).Return(indexQuery)
+		indexQuery.On("PageSize", 0).Return(indexQuery)
+		indexQuery.On("Iter").Return(indexIter)
+		indexQuery.On("String").Return("queryString")
+
+		summaryIter := &mocks.Iterator{}
+		summaryIter.On("Scan", matchOnceWithSideEffect(func(args []interface{}) {
+			*args[0].(*int64) = 1
+			*args[2].(*string) = "operation-b"
+			*args[4].(*int64) = 1000
+			*args[7].(*dbmodel.Process) = dbmodel.Process{ServiceName: "service-a"}
+		})).Return(true)
+		summaryIter.On("Scan", matchEverything()).Return(false)
+		summaryIter.On("Close").Return(nil)
+		summaryQuery := &mocks.Query{}
+		summaryQuery.On("Iter").Return(summaryIter)
+
+		r.session.On("Query", stringMatcher(queryByServiceAndOperationName), matchEverything()).Return(indexQuery)
+		r.session.On("Query", querySpanSummaryByTraceID, matchEverything()).Return(summaryQuery)
+
+		summaries, err := r.reader.FindTraceSummaries(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName:   "service-a",
+			OperationName: "operation-b",
+			StartTimeMin:  time.Now().Add(-time.Hour),
+			StartTimeMax:  time.Now(),
+		})
+		require.NoError(t, err)
+		require.Len(t, summaries, 1)
+		assert.Equal(t, &spanstore.TraceSummary{
+			TraceID:           model.NewTraceID(0, 1),
+			RootServiceName:   "service-a",
+			RootOperationName: "operation-b",
+			StartTime:         model.EpochMicrosecondsAsTime(0),
+			Duration:          time.Millisecond,
+			SpanCount:         1,
+			ServiceSpanCounts: map[string]int{"service-a": 1},
+		}, summaries[0])
+	})
+}
+
 func TestTraceQueryParameterValidation(t *testing.T) {
 	tsp := &spanstore.TraceQueryParameters{
 		ServiceName: "",
@@ -432,3 +481,274 @@ func TestTraceQueryParameterValidation(t *testing.T) {
 	err = validateQuery(tsp)
 	assert.EqualError(t, err, ErrStartAndEndTimeNotSet.Error())
 }
+
+func TestSpanReaderFindTraceIDsUnsupportedTagFilter(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		tagFilter, err := spanstore.NewTagFilter("http.status_code", spanstore.OpGreaterOrEqual, "500")
+		require.NoError(t, err)
+		_, err = r.reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName: "svc",
+			TagFilters:  []*spanstore.Predicate{tagFilter},
+		})
+		assert.ErrorIs(t, err, spanstore.ErrUnsupportedTagFilter)
+	})
+}
+
+func TestSpanReaderFindTraceIDsUnsatisfiableTagFilters(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		tagFilter, err := spanstore.NewTagFilter("http.status_code", spanstore.OpEqual, "500")
+		require.NoError(t, err)
+		query := &spanstore.TraceQueryParameters{
+			ServiceName: "svc",
+			Tags:        map[string]string{"http.status_code": "200"},
+			TagFilters:  []*spanstore.Predicate{tagFilter},
+		}
+		traceIDs, err := r.reader.FindTraceIDs(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Empty(t, traceIDs)
+
+		page, err := r.reader.FindTracesPage(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Empty(t, page.Traces)
+	})
+}
+
+func TestSpanReaderFindTracesPage(t *testing.T) {
+	type indexRow struct {
+		traceID   model.TraceID
+		startTime int64
+	}
+	traceIDs := []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2), model.NewTraceID(0, 3)}
+	// trace 2 is found in both pages, since it has two spans of the operation
+	spanStartTimes := map[model.TraceID][]int64{
+		traceIDs[0]: {300},
+		traceIDs[1]: {200, 100},
+		traceIDs[2]: {50},
+	}
+	pageQuery := func(rows []indexRow, pageState []byte) cassandra.Query {
+		iter := &mocks.Iterator{}
+		for _, row := range rows {
+			row := row
+			iter.On("Scan", matchOnceWithSideEffect(func(args []interface{}) {
+				*args[0].(*dbmodel.TraceID) = dbmodel.TraceIDFromDomain(row.traceID)
+				*args[1].(*int64) = row.startTime
+			})).Return(true).Once()
+		}
+		iter.On("Scan", matchEverything()).Return(false)
+		iter.On("PageState").Return(pageState)
+		iter.On("Close").Return(nil)
+		query := &mocks.Query{}
+		query.On("Iter").Return(iter)
+		return query
+	}
+
+	withSpanReader(func(r *spanReaderTest) {
+		query := &mocks.Query{}
+		query.On("PageSize", 2).Return(query)
+		query.On("PageState", []byte(nil)).Return(pageQuery([]indexRow{{traceIDs[0], 300}, {traceIDs[1], 200}}, []byte("next")))
+		query.On("PageState", []byte("next")).Return(pageQuery([]indexRow{{traceIDs[1], 100}, {traceIDs[2], 50}}, nil))
+		r.session.On("Query", stringMatcher(queryByServiceAndOperationNamePaged), matchEverything()).Return(query)
+		for _, traceID := range traceIDs {
+			dbTraceID := dbmodel.TraceIDFromDomain(traceID)
+			iter := &mocks.Iterator{}
+			for _, startTime := range spanStartTimes[traceID] {
+				startTime := startTime
+				iter.On("Scan", matchOnceWithSideEffect(func(args []interface{}) {
+					*args[0].(*dbmodel.TraceID) = dbTraceID
+					*args[3].(*string) = "op"
+					*args[5].(*int64) = startTime
+					*args[10].(*dbmodel.Process) = dbmodel.Process{ServiceName: "svc"}
+				})).Return(true).Once()
+			}
+			iter.On("Scan", matchEverything()).Return(false)
+			iter.On("Close").Return(nil)
+			query := &mocks.Query{}
+			query.On("Iter").Return(iter)
+			r.session.On("Query", stringMatcher(querySpanByTraceID), mock.MatchedBy(func(args []interface{}) bool {
+				return args[0] == dbTraceID
+			})).Return(query)
+		}
+
+		traceQuery := &spanstore.TraceQueryParameters{
+			ServiceName:   "svc",
+			OperationName: "op",
+			StartTimeMin:  time.Now().Add(-time.Hour),
+			StartTimeMax:  time.Now(),
+			NumTraces:     2,
+		}
+		page, err := r.reader.FindTracesPage(context.Background(), traceQuery)
+		require.NoError(t, err)
+		require.Len(t, page.Traces, 2)
+		assert.Equal(t, traceIDs[0], page.Traces[0].Spans[0].TraceID)
+		assert.Equal(t, traceIDs[1], page.Traces[1].Spans[0].TraceID)
+		require.NotEmpty(t, page.NextPageToken)
+
+		traceQuery.PageToken = page.NextPageToken
+		page, err = r.reader.FindTracesPage(context.Background(), traceQuery)
+		require.NoError(t, err)
+		require.Len(t, page.Traces, 1)
+		assert.Equal(t, traceIDs[2], page.Traces[0].Spans[0].TraceID)
+		assert.Empty(t, page.NextPageToken)
+	})
+}
+
+func TestSpanReaderFindTracesPageNotSupported(t *testing.T) {
+	for _, traceQuery := range []*spanstore.TraceQueryParameters{
+		{ServiceName: "svc"},
+		{ServiceName: "svc", DurationMin: time.Second},
+		{ServiceName: "svc", Tags: map[string]string{"x": "y", "z": "w"}},
+		{ServiceName: "svc", OperationName: "op", Tags: map[string]string{"x": "y"}},
+	} {
+		withSpanReader(func(r *spanReaderTest) {
+			traceQuery.StartTimeMin = time.Now().Add(-time.Hour)
+			traceQuery.StartTimeMax = time.Now()
+			traceQuery.PageToken, _ = spanstore.EncodePageToken(pageCursor{PageState: []byte("next")})
+			_, err := r.reader.FindTracesPage(context.Background(), traceQuery)
+			assert.ErrorIs(t, err, spanstore.ErrPaginationNotSupported)
+		})
+	}
+}
+
+func TestSpanReaderFindTracesTagMatchMode(t *testing.T) {
+	// both trace IDs are returned by each tag index query, but only
+	// trace 1 has the two tags on the same span
+	traceIDs := []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2)}
+	spanTags := map[model.TraceID][]dbmodel.KeyValue{
+		traceIDs[0]: {{Key: "x", ValueType: "string", ValueString: "y"}, {Key: "z", ValueType: "string", ValueString: "w"}},
+		traceIDs[1]: {{Key: "x", ValueType: "string", ValueString: "y"}},
+	}
+	tagFilter, err := spanstore.ParseTagFilter("z=w")
+	require.NoError(t, err)
+	for _, test := range []struct {
+		name       string
+		mode       spanstore.TagMatchMode
+		tags       map[string]string
+		tagFilters []*spanstore.Predicate
+		expected   []model.TraceID
+	}{
+		{"SameSpan", spanstore.TagMatchSameSpan, map[string]string{"x": "y", "z": "w"}, nil, traceIDs[:1]},
+		{"AnySpanInTrace", spanstore.TagMatchAnySpanInTrace, map[string]string{"x": "y", "z": "w"}, nil, traceIDs},
+		{"SameSpan with tag filter", spanstore.TagMatchSameSpan, map[string]string{"x": "y"}, []*spanstore.Predicate{tagFilter}, traceIDs[:1]},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withSpanReader(func(r *spanReaderTest) {
+				r.session.On("Query", stringMatcher(queryByTag), matchEverything()).Return(func(string, ...interface{}) cassandra.Query {
+					remaining := []dbmodel.TraceID{dbmodel.TraceIDFromDomain(traceIDs[0]), dbmodel.TraceIDFromDomain(traceIDs[1])}
+					iter := &mocks.Iterator{}
+					iter.On("Scan", mock.MatchedBy(func(args []interface{}) bool {
+						if len(remaining) == 0 {
+							return false
+						}
+						*args[0].(*dbmodel.TraceID) = remaining[0]
+						remaining = remaining[1:]
+						return true
+					})).Return(true)
+					iter.On("Scan", matchEverything()).Return(false)
+					iter.On("Close").Return(nil)
+					query := &mocks.Query{}
+					query.On("PageSize", 0).Return(query)
+					query.On("Iter").Return(iter)
+					query.On("String").Return("queryString")
+					return query
+				})
+				for _, traceID := range traceIDs {
+					tags := spanTags[traceID]
+					dbTraceID := dbmodel.TraceIDFromDomain(traceID)
+					iter := &mocks.Iterator{}
+					iter.On("Scan", matchOnceWithSideEffect(func(args []interface{}) {
+						*args[0].(*dbmodel.TraceID) = dbTraceID
+						*args[7].(*[]dbmodel.KeyValue) = tags
+						*args[10].(*dbmodel.Process) = dbmodel.Process{ServiceName: "svc"}
+					})).Return(true)
+					iter.On("Scan", matchEverything()).Return(false)
+					iter.On("Close").Return(nil)
+					query := &mocks.Query{}
+					query.On("Consistency", cassandra.One).Return(query)
+					query.On("Iter").Return(iter)
+					r.session.On("Query", stringMatcher("SELECT trace_id"), mock.MatchedBy(func(args []interface{}) bool {
+						return args[0] == dbTraceID
+					})).Return(query)
+				}
+
+				// each trace can only be read once, so the traces read to verify
+				// the tags must be returned without being read again
+				traces, err := r.reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+					ServiceName:  "svc",
+					Tags:         test.tags,
+					TagFilters:   test.tagFilters,
+					StartTimeMin: time.Now().Add(-time.Hour),
+					StartTimeMax: time.Now(),
+					TagMatchMode: test.mode,
+				})
+				require.NoError(t, err)
+				var ids []model.TraceID
+				for _, trace := range traces {
+					ids = append(ids, trace.Spans[0].TraceID)
+				}
+				assert.ElementsMatch(t, test.expected, ids)
+			})
+		})
+	}
+}
+
+func TestSpanReaderGetTraces(t *testing.T) {
+	traceID1, traceID2 := model.NewTraceID(0, 1), model.NewTraceID(0, 2)
+	scanTraceID := func(traceID model.TraceID) interface{} {
+		return matchOnceWithSideEffect(func(args []interface{}) {
+			*args[0].(*dbmodel.TraceID) = dbmodel.TraceIDFromDomain(traceID)
+		})
+	}
+	testCases := []struct {
+		name        string
+		closeErr    error
+		expectedErr string
+	}{
+		{name: "success"},
+		{
+			name:        "close error",
+			closeErr:    errors.New("error on close()"),
+			expectedErr: "error reading traces from storage: error on close()",
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.name, func(t *testing.T) {
+			withSpanReader(func(r *spanReaderTest) {
+				iter := &mocks.Iterator{}
+				iter.On("Scan", scanTraceID(traceID2)).Return(true)
+				iter.On("Scan", scanTraceID(traceID1)).Return(true)
+				iter.On("Scan", matchEverything()).Return(false)
+				iter.On("Close").Return(testCase.closeErr)
+
+				query := &mocks.Query{}
+				query.On("Iter").Return(iter)
+
+				dbTraceIDs := []dbmodel.TraceID{
+					dbmodel.TraceIDFromDomain(traceID1),
+					dbmodel.TraceIDFromDomain(model.NewTraceID(0, 3)),
+					dbmodel.TraceIDFromDomain(traceID2),
+				}
+				r.session.On("Query", querySpansByTraceIDs, []interface{}{dbTraceIDs}).Return(query)
+
+				traces, err := r.reader.GetTraces(context.Background(), []model.TraceID{traceID1, model.NewTraceID(0, 3), traceID2})
+				if testCase.expectedErr != "" {
+					assert.EqualError(t, err, testCase.expectedErr)
+					assert.Nil(t, traces)
+					return
+				}
+				require.NoError(t, err)
+				require.Len(t, traces, 2)
+				assert.Equal(t, traceID1, traces[0].Spans[0].TraceID)
+				assert.Equal(t, traceID2, traces[1].Spans[0].TraceID)
+			})
+		})
+	}
+}
+
+func TestSpanReaderGetTracesEmpty(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		traces, err := r.reader.GetTraces(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Empty(t, traces)
+	})
+}
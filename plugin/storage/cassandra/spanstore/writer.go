@@ -18,11 +18,13 @@ package spanstore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/gocql/gocql"
 	"go.uber.org/zap"
 
 	"github.com/kjschnei001/jaeger/model"
@@ -293,3 +295,22 @@ func (s *SpanWriter) saveServiceNameAndOperationName(operation dbmodel.Operation
 	}
 	return s.operationNamesWriter(operation)
 }
+
+// IsRetryableError tells whether a write error returned by the SpanWriter is transient, such as a
+// timeout or an unavailable or overloaded coordinator, rather than an invalid or unauthorized query.
+func IsRetryableError(err error) bool {
+	var requestErr gocql.RequestError
+	if errors.As(err, &requestErr) {
+		switch requestErr.Code() {
+		case gocql.ErrCodeUnavailable,
+			gocql.ErrCodeOverloaded,
+			gocql.ErrCodeBootstrapping,
+			gocql.ErrCodeWriteTimeout,
+			gocql.ErrCodeReadTimeout,
+			gocql.ErrCodeWriteFailure:
+			return true
+		}
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gocql/gocql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/atomic"
@@ -431,3 +432,28 @@ func TestStorageMode_StoreWithoutIndexing(t *testing.T) {
 		w.session.AssertNotCalled(t, "Query", stringMatcher(serviceNameIndex), matchEverything())
 	}, StoreWithoutIndexing())
 }
+
+type requestError struct {
+	code int
+}
+
+func (e requestError) Code() int       { return e.code }
+func (e requestError) Message() string { return "request error" }
+func (e requestError) Error() string   { return "request error" }
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{err: fmt.Errorf("Failed to insert span: %w", requestError{code: gocql.ErrCodeWriteTimeout}), retryable: true},
+		{err: requestError{code: gocql.ErrCodeOverloaded}, retryable: true},
+		{err: gocql.ErrTimeoutNoResponse, retryable: true},
+		{err: gocql.ErrNoConnections, retryable: true},
+		{err: fmt.Errorf("Failed to insert span: %w", requestError{code: gocql.ErrCodeInvalid}), retryable: false},
+		{err: context.Canceled, retryable: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.retryable, IsRetryableError(test.err), test.err.Error())
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+)
+
+// timestampLayout is the layout of the UTC times of the DateTime64(3, 'UTC') timestamp column.
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+// dependency is a row of the dependencies table read as JSONEachRow.
+type dependency struct {
+	Parent    string `json:"parent"`
+	Child     string `json:"child"`
+	CallCount uint64 `json:"call_count"`
+	Source    string `json:"source"`
+}
+
+// DependencyStore handles all queries and insertions to ClickHouse dependencies
+type DependencyStore struct {
+	client clickhouse.Client
+	logger *zap.Logger
+	tables schema.Tables
+}
+
+// NewDependencyStore returns a DependencyStore
+func NewDependencyStore(client clickhouse.Client, tables schema.Tables, logger *zap.Logger) *DependencyStore {
+	return &DependencyStore{
+		client: client,
+		logger: logger,
+		tables: tables,
+	}
+}
+
+// WriteDependencies implements dependencystore.Writer#WriteDependencies.
+func (s *DependencyStore) WriteDependencies(ts time.Time, dependencies []model.DependencyLink) error {
+	if len(dependencies) == 0 {
+		return nil
+	}
+	var batch clickhouse.RowBinary
+	for _, link := range dependencies {
+		batch.DateTime64(ts, 3)
+		batch.String(link.Parent)
+		batch.String(link.Child)
+		batch.UInt64(link.CallCount)
+		batch.String(link.Source)
+	}
+	query := "INSERT INTO " + s.tables.Dependencies() + " (timestamp, parent, child, call_count, source) FORMAT RowBinary"
+	if err := s.client.Exec(context.Background(), query, nil, batch.Bytes()); err != nil {
+		return fmt.Errorf("cannot insert the dependencies: %w", err)
+	}
+	return nil
+}
+
+// GetDependencies returns all interservice dependencies, the call counts of the
+// links written several times during the lookback are summed.
+func (s *DependencyStore) GetDependencies(ctx context.Context, endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	query := "SELECT parent, child, sum(call_count) AS call_count, source FROM " + s.tables.Dependencies() +
+		" WHERE timestamp >= {start_time:DateTime64(3, 'UTC')} AND timestamp <= {end_time:DateTime64(3, 'UTC')}" +
+		" GROUP BY parent, child, source ORDER BY parent, child, source"
+	params := clickhouse.Params{
+		"start_time": endTs.Add(-lookback).UTC().Format(timestampLayout),
+		"end_time":   endTs.UTC().Format(timestampLayout),
+	}
+	var links []model.DependencyLink
+	err := s.client.Query(ctx, query, params, func(data []byte) error {
+		var row dependency
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		links = append(links, model.DependencyLink{
+			Parent:    row.Parent,
+			Child:     row.Child,
+			CallCount: row.CallCount,
+			Source:    row.Source,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the dependencies: %w", err)
+	}
+	return links, nil
+}
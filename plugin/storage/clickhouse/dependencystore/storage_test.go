@@ -0,0 +1,114 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+)
+
+type fakeClient struct {
+	query  string
+	params clickhouse.Params
+	data   []byte
+	rows   []string
+	err    error
+}
+
+func (c *fakeClient) Exec(ctx context.Context, query string, params clickhouse.Params, data []byte) error {
+	c.query, c.params, c.data = query, params, data
+	return c.err
+}
+
+func (c *fakeClient) Query(ctx context.Context, query string, params clickhouse.Params, scan func(row []byte) error) error {
+	c.query, c.params = query, params
+	if c.err != nil {
+		return c.err
+	}
+	for _, row := range c.rows {
+		if err := scan([]byte(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestStore(client *fakeClient) *DependencyStore {
+	return NewDependencyStore(client, schema.NewTables("jaeger", "jaeger", false), zap.NewNop())
+}
+
+func TestWriteDependencies(t *testing.T) {
+	client := &fakeClient{}
+	ts := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	err := newTestStore(client).WriteDependencies(ts, []model.DependencyLink{
+		{Parent: "a", Child: "b", CallCount: 3},
+		{Parent: "b", Child: "c", CallCount: 1, Source: "jaeger"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO `jaeger`.`jaeger_dependencies` (timestamp, parent, child, call_count, source) FORMAT RowBinary", client.query)
+	var expected clickhouse.RowBinary
+	expected.DateTime64(ts, 3)
+	expected.String("a")
+	expected.String("b")
+	expected.UInt64(3)
+	expected.String("")
+	expected.DateTime64(ts, 3)
+	expected.String("b")
+	expected.String("c")
+	expected.UInt64(1)
+	expected.String("jaeger")
+	assert.Equal(t, expected.Bytes(), client.data)
+
+	client = &fakeClient{}
+	require.NoError(t, newTestStore(client).WriteDependencies(ts, nil))
+	assert.Empty(t, client.query)
+
+	client = &fakeClient{err: errors.New("unreachable")}
+	err = newTestStore(client).WriteDependencies(ts, []model.DependencyLink{{Parent: "a", Child: "b"}})
+	assert.EqualError(t, err, "cannot insert the dependencies: unreachable")
+}
+
+func TestGetDependencies(t *testing.T) {
+	client := &fakeClient{rows: []string{
+		`{"parent":"a","child":"b","call_count":4,"source":""}`,
+		`{"parent":"b","child":"c","call_count":1,"source":"jaeger"}`,
+	}}
+	endTs := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	links, err := newTestStore(client).GetDependencies(context.Background(), endTs, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "a", Child: "b", CallCount: 4},
+		{Parent: "b", Child: "c", CallCount: 1, Source: "jaeger"},
+	}, links)
+	assert.Contains(t, client.query, "sum(call_count) AS call_count")
+	assert.Equal(t, clickhouse.Params{
+		"start_time": "2023-05-01 09:00:00.000",
+		"end_time":   "2023-05-01 10:00:00.000",
+	}, client.params)
+
+	client = &fakeClient{rows: []string{`{"call_count":"x"}`}}
+	_, err = newTestStore(client).GetDependencies(context.Background(), endTs, time.Hour)
+	assert.ErrorContains(t, err, "cannot read the dependencies")
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse/config"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin"
+	chDepStore "github.com/kjschnei001/jaeger/plugin/storage/clickhouse/dependencystore"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+	chSpanStore "github.com/kjschnei001/jaeger/plugin/storage/clickhouse/spanstore"
+	"github.com/kjschnei001/jaeger/storage"
+	"github.com/kjschnei001/jaeger/storage/dependencystore"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const (
+	primaryNamespace = "clickhouse"
+	archiveNamespace = "clickhouse-archive"
+)
+
+var (
+	_ io.Closer                       = (*Factory)(nil)
+	_ plugin.Configurable             = (*Factory)(nil)
+	_ storage.ArchiveFactory          = (*Factory)(nil)
+	_ storage.DependencyWriterFactory = (*Factory)(nil)
+)
+
+// Factory implements storage.Factory for ClickHouse backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+
+	newClientFn func(c *config.Configuration, logger *zap.Logger) (clickhouse.Client, error)
+
+	primaryConfig *config.Configuration
+	primaryClient clickhouse.Client
+	archiveConfig *config.Configuration
+	archiveClient clickhouse.Client
+
+	// writers are closed with the factory to insert their last batches
+	writersMu sync.Mutex
+	writers   []*chSpanStore.SpanWriter
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options: NewOptions(primaryNamespace, archiveNamespace),
+		newClientFn: func(c *config.Configuration, logger *zap.Logger) (clickhouse.Client, error) {
+			return c.NewClient(logger)
+		},
+	}
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper, logger *zap.Logger) {
+	f.Options.InitFromViper(v)
+	f.primaryConfig = f.Options.GetPrimary()
+	f.archiveConfig = f.Options.Get(archiveNamespace)
+}
+
+// InitFromOptions configures factory from Options struct.
+func (f *Factory) InitFromOptions(o Options) {
+	f.Options = &o
+	f.primaryConfig = f.Options.GetPrimary()
+	f.archiveConfig = f.Options.Get(archiveNamespace)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+
+	primaryClient, err := f.newClientFn(f.primaryConfig, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create primary ClickHouse client: %w", err)
+	}
+	f.primaryClient = primaryClient
+	if f.primaryConfig.CreateSchema {
+		if err := schema.Create(context.Background(), primaryClient, primaryTables(f.primaryConfig), f.primaryConfig.TTL); err != nil {
+			return err
+		}
+	}
+	if f.archiveConfig.Enabled {
+		f.archiveClient, err = f.newClientFn(f.archiveConfig, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create archive ClickHouse client: %w", err)
+		}
+		if f.archiveConfig.CreateSchema {
+			if err := schema.Create(context.Background(), f.archiveClient, archiveTables(f.archiveConfig), f.archiveConfig.TTL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func primaryTables(cfg *config.Configuration) schema.Tables {
+	return schema.NewTables(cfg.Database, cfg.TablePrefix, false)
+}
+
+func archiveTables(cfg *config.Configuration) schema.Tables {
+	return schema.NewTables(cfg.Database, cfg.TablePrefix, true)
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return f.createSpanReader(f.primaryClient, primaryTables(f.primaryConfig)), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	return f.createSpanWriter(f.primaryClient, f.primaryConfig, primaryTables(f.primaryConfig)), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return chDepStore.NewDependencyStore(f.primaryClient, primaryTables(f.primaryConfig), f.logger), nil
+}
+
+// CreateDependencyWriter implements storage.DependencyWriterFactory
+func (f *Factory) CreateDependencyWriter() (dependencystore.Writer, error) {
+	return chDepStore.NewDependencyStore(f.primaryClient, primaryTables(f.primaryConfig), f.logger), nil
+}
+
+// CreateArchiveSpanReader implements storage.ArchiveFactory
+func (f *Factory) CreateArchiveSpanReader() (spanstore.Reader, error) {
+	if !f.archiveConfig.Enabled {
+		return nil, nil
+	}
+	return f.createSpanReader(f.archiveClient, archiveTables(f.archiveConfig)), nil
+}
+
+// CreateArchiveSpanWriter implements storage.ArchiveFactory
+func (f *Factory) CreateArchiveSpanWriter() (spanstore.Writer, error) {
+	if !f.archiveConfig.Enabled {
+		return nil, nil
+	}
+	// archived traces are written one at a time, so they are not batched
+	cfg := *f.archiveConfig
+	cfg.BatchSize, cfg.BatchFlushInterval = 0, 0
+	return f.createSpanWriter(f.archiveClient, &cfg, archiveTables(f.archiveConfig)), nil
+}
+
+func (f *Factory) createSpanReader(client clickhouse.Client, tables schema.Tables) spanstore.Reader {
+	return chSpanStore.NewSpanReader(chSpanStore.SpanReaderParams{
+		Client: client,
+		Logger: f.logger,
+		Tables: tables,
+	})
+}
+
+func (f *Factory) createSpanWriter(client clickhouse.Client, cfg *config.Configuration, tables schema.Tables) spanstore.Writer {
+	writer := chSpanStore.NewSpanWriter(chSpanStore.SpanWriterParams{
+		Client:             client,
+		Logger:             f.logger,
+		MetricsFactory:     f.metricsFactory,
+		Tables:             tables,
+		BatchSize:          cfg.BatchSize,
+		BatchFlushInterval: cfg.BatchFlushInterval,
+	})
+	f.writersMu.Lock()
+	f.writers = append(f.writers, writer)
+	f.writersMu.Unlock()
+	return writer
+}
+
+// Close inserts the spans still buffered by the writers and closes the resources held by the factory.
+func (f *Factory) Close() error {
+	f.writersMu.Lock()
+	writers := f.writers
+	f.writers = nil
+	f.writersMu.Unlock()
+
+	var errs []error
+	for _, writer := range writers {
+		if err := writer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg := f.Options.Get(archiveNamespace); cfg != nil {
+		errs = append(errs, cfg.TLS.Close())
+	}
+	errs = append(errs, f.Options.GetPrimary().TLS.Close())
+	return errors.Join(errs...)
+}
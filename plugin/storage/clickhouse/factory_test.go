@@ -0,0 +1,149 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	chcfg "github.com/kjschnei001/jaeger/pkg/clickhouse/config"
+	"github.com/kjschnei001/jaeger/pkg/config"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+type execClient struct {
+	mu         sync.Mutex
+	statements []string
+	err        error
+}
+
+func (c *execClient) Exec(ctx context.Context, query string, params clickhouse.Params, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, query)
+	return c.err
+}
+
+func (c *execClient) Query(ctx context.Context, query string, params clickhouse.Params, scan func(row []byte) error) error {
+	return c.Exec(ctx, query, params, nil)
+}
+
+func newTestFactory(t *testing.T, args ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags(args))
+	f.InitFromViper(v, zap.NewNop())
+	return f
+}
+
+func TestClickHouseFactory(t *testing.T) {
+	f := newTestFactory(t, "--clickhouse-archive.enabled=true")
+
+	f.newClientFn = func(c *chcfg.Configuration, logger *zap.Logger) (clickhouse.Client, error) {
+		return nil, errors.New("made-up error")
+	}
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "failed to create primary ClickHouse client: made-up error")
+
+	f.newClientFn = func(c *chcfg.Configuration, logger *zap.Logger) (clickhouse.Client, error) {
+		// to test archive storage error, pretend that primary client creation is successful
+		// but override newClientFn so it fails for the next invocation
+		f.newClientFn = func(c *chcfg.Configuration, logger *zap.Logger) (clickhouse.Client, error) {
+			return nil, errors.New("made-up error2")
+		}
+		return &execClient{}, nil
+	}
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "failed to create archive ClickHouse client: made-up error2")
+
+	primary, archive := &execClient{}, &execClient{}
+	f.newClientFn = func(c *chcfg.Configuration, logger *zap.Logger) (clickhouse.Client, error) {
+		if c == f.archiveConfig {
+			return archive, nil
+		}
+		return primary, nil
+	}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Contains(t, strings.Join(primary.statements, "\n"), "CREATE TABLE IF NOT EXISTS `jaeger`.`jaeger_spans`")
+	assert.Contains(t, strings.Join(archive.statements, "\n"), "CREATE TABLE IF NOT EXISTS `jaeger`.`jaeger_archive_spans`")
+
+	_, err := f.CreateSpanReader()
+	assert.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	assert.NoError(t, err)
+	_, err = f.CreateDependencyWriter()
+	assert.NoError(t, err)
+	_, err = f.CreateArchiveSpanReader()
+	assert.NoError(t, err)
+
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	archiveWriter, err := f.CreateArchiveSpanWriter()
+	require.NoError(t, err)
+
+	primary.statements, archive.statements = nil, nil
+	span := &model.Span{Process: model.NewProcess("svc", nil)}
+	require.NoError(t, writer.WriteSpan(context.Background(), span))
+	require.NoError(t, archiveWriter.WriteSpan(context.Background(), span))
+	assert.Empty(t, primary.statements, "spans are batched")
+	assert.Len(t, archive.statements, 1, "archived spans are not batched")
+
+	require.NoError(t, f.Close())
+	assert.Len(t, primary.statements, 1, "the batch is inserted on close")
+}
+
+func TestClickHouseFactoryWithoutArchive(t *testing.T) {
+	f := newTestFactory(t, "--clickhouse.create-schema=false")
+	client := &execClient{}
+	f.newClientFn = func(c *chcfg.Configuration, logger *zap.Logger) (clickhouse.Client, error) {
+		return client, nil
+	}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Empty(t, client.statements)
+
+	reader, err := f.CreateArchiveSpanReader()
+	assert.NoError(t, err)
+	assert.Nil(t, reader)
+	writer, err := f.CreateArchiveSpanWriter()
+	assert.NoError(t, err)
+	assert.Nil(t, writer)
+	assert.NoError(t, f.Close())
+}
+
+func TestClickHouseFactorySchemaError(t *testing.T) {
+	f := newTestFactory(t)
+	f.newClientFn = func(c *chcfg.Configuration, logger *zap.Logger) (clickhouse.Client, error) {
+		return &execClient{err: errors.New("unreachable")}, nil
+	}
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "cannot create the ClickHouse schema: unreachable")
+}
+
+func TestClickHouseFactoryInitFromOptions(t *testing.T) {
+	f := NewFactory()
+	o := NewOptions(primaryNamespace, archiveNamespace)
+	f.InitFromOptions(*o)
+	assert.Equal(t, o.GetPrimary(), f.primaryConfig)
+	assert.False(t, f.archiveConfig.Enabled)
+}
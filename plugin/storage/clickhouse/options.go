@@ -0,0 +1,207 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/kjschnei001/jaeger/pkg/clickhouse/config"
+	"github.com/kjschnei001/jaeger/pkg/config/tlscfg"
+)
+
+const (
+	suffixServers            = ".servers"
+	suffixDatabase           = ".database"
+	suffixUsername           = ".username"
+	suffixPassword           = ".password"
+	suffixTimeout            = ".timeout"
+	suffixTablePrefix        = ".table-prefix"
+	suffixTTL                = ".ttl"
+	suffixCreateSchema       = ".create-schema"
+	suffixBatchSize          = ".batch.size"
+	suffixBatchFlushInterval = ".batch.flush-interval"
+	suffixEnabled            = ".enabled"
+
+	defaultServers = "http://127.0.0.1:8123"
+)
+
+// Options contains various type of ClickHouse configs and provides the ability
+// to bind them to command line flag and apply overlays, so that some configurations
+// (e.g. archive) may be underspecified and infer the rest of its parameters from primary.
+type Options struct {
+	Primary namespaceConfig `mapstructure:",squash"`
+
+	others map[string]*namespaceConfig
+}
+
+type namespaceConfig struct {
+	config.Configuration `mapstructure:",squash"`
+	namespace            string
+}
+
+// NewOptions creates a new Options struct.
+func NewOptions(primaryNamespace string, otherNamespaces ...string) *Options {
+	defaultConfig := config.Configuration{
+		Servers:            []string{defaultServers},
+		Database:           "jaeger",
+		Timeout:            30 * time.Second,
+		TablePrefix:        "jaeger",
+		TTL:                72 * time.Hour,
+		CreateSchema:       true,
+		BatchSize:          10_000,
+		BatchFlushInterval: time.Second,
+		Enabled:            true,
+	}
+	options := &Options{
+		Primary: namespaceConfig{
+			Configuration: defaultConfig,
+			namespace:     primaryNamespace,
+		},
+		others: make(map[string]*namespaceConfig, len(otherNamespaces)),
+	}
+
+	// Other namespaces need to be explicitly enabled, keep archived traces forever
+	// and use the servers of the primary namespace unless configured otherwise.
+	defaultConfig.Enabled = false
+	defaultConfig.TTL = 0
+	defaultConfig.Servers = nil
+	for _, namespace := range otherNamespaces {
+		options.others[namespace] = &namespaceConfig{
+			Configuration: defaultConfig,
+			namespace:     namespace,
+		}
+	}
+	return options
+}
+
+func (config *namespaceConfig) getTLSFlagsConfig() tlscfg.ClientFlagsConfig {
+	return tlscfg.ClientFlagsConfig{
+		Prefix: config.namespace,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	addFlags(flagSet, &opt.Primary)
+	for _, cfg := range opt.others {
+		addFlags(flagSet, cfg)
+	}
+}
+
+func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
+	flagSet.String(
+		nsConfig.namespace+suffixServers,
+		strings.Join(nsConfig.Servers, ","),
+		"The comma-separated list of URLs of the HTTP interface of the ClickHouse servers, e.g. http://localhost:8123. "+
+			"The servers are tried in order until one of them can be reached")
+	flagSet.String(
+		nsConfig.namespace+suffixDatabase,
+		nsConfig.Database,
+		"The ClickHouse database storing the tables")
+	flagSet.String(
+		nsConfig.namespace+suffixUsername,
+		nsConfig.Username,
+		"The username required by ClickHouse")
+	flagSet.String(
+		nsConfig.namespace+suffixPassword,
+		nsConfig.Password,
+		"The password required by ClickHouse")
+	flagSet.Duration(
+		nsConfig.namespace+suffixTimeout,
+		nsConfig.Timeout,
+		"Timeout used for queries and inserts. A Timeout of zero means no timeout")
+	flagSet.String(
+		nsConfig.namespace+suffixTablePrefix,
+		nsConfig.TablePrefix,
+		"The prefix of the names of the tables. For example \"production\" creates \"production_spans\"")
+	flagSet.Duration(
+		nsConfig.namespace+suffixTTL,
+		nsConfig.TTL,
+		"The time to live of the spans and dependencies, ClickHouse deletes older rows. A TTL of zero keeps them forever. "+
+			"It is only applied when the tables are created")
+	flagSet.Bool(
+		nsConfig.namespace+suffixCreateSchema,
+		nsConfig.CreateSchema,
+		"Create the database and tables at application startup. Set to false when the schema is created manually")
+	flagSet.Int(
+		nsConfig.namespace+suffixBatchSize,
+		nsConfig.BatchSize,
+		"The number of spans inserted together")
+	flagSet.Duration(
+		nsConfig.namespace+suffixBatchFlushInterval,
+		nsConfig.BatchFlushInterval,
+		"A time.Duration after which the spans are inserted, even if the batch is not full. Set to zero to disable")
+	if nsConfig.namespace == archiveNamespace {
+		flagSet.Bool(
+			nsConfig.namespace+suffixEnabled,
+			nsConfig.Enabled,
+			"Enable extra storage")
+	}
+	nsConfig.getTLSFlagsConfig().AddFlags(flagSet)
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	initFromViper(&opt.Primary, v)
+	for _, cfg := range opt.others {
+		initFromViper(cfg, v)
+	}
+}
+
+func initFromViper(cfg *namespaceConfig, v *viper.Viper) {
+	if servers := strings.ReplaceAll(v.GetString(cfg.namespace+suffixServers), " ", ""); servers != "" {
+		cfg.Servers = strings.Split(servers, ",")
+	}
+	cfg.Database = v.GetString(cfg.namespace + suffixDatabase)
+	cfg.Username = v.GetString(cfg.namespace + suffixUsername)
+	cfg.Password = v.GetString(cfg.namespace + suffixPassword)
+	cfg.Timeout = v.GetDuration(cfg.namespace + suffixTimeout)
+	cfg.TablePrefix = v.GetString(cfg.namespace + suffixTablePrefix)
+	cfg.TTL = v.GetDuration(cfg.namespace + suffixTTL)
+	cfg.CreateSchema = v.GetBool(cfg.namespace + suffixCreateSchema)
+	cfg.BatchSize = v.GetInt(cfg.namespace + suffixBatchSize)
+	cfg.BatchFlushInterval = v.GetDuration(cfg.namespace + suffixBatchFlushInterval)
+	if cfg.namespace == archiveNamespace {
+		cfg.Enabled = v.GetBool(cfg.namespace + suffixEnabled)
+	}
+
+	var err error
+	cfg.TLS, err = cfg.getTLSFlagsConfig().InitFromViper(v)
+	if err != nil {
+		// TODO refactor to be able to return error
+		log.Fatal(err)
+	}
+}
+
+// GetPrimary returns primary configuration.
+func (opt *Options) GetPrimary() *config.Configuration {
+	return &opt.Primary.Configuration
+}
+
+// Get returns auxiliary named configuration.
+func (opt *Options) Get(namespace string) *config.Configuration {
+	nsCfg, ok := opt.others[namespace]
+	if !ok {
+		nsCfg = &namespaceConfig{}
+		opt.others[namespace] = nsCfg
+	}
+	nsCfg.Configuration.ApplyDefaults(&opt.Primary.Configuration)
+	return &nsCfg.Configuration
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/pkg/config"
+)
+
+func TestOptions(t *testing.T) {
+	opts := NewOptions(primaryNamespace, archiveNamespace)
+	primary := opts.GetPrimary()
+	assert.Equal(t, []string{"http://127.0.0.1:8123"}, primary.Servers)
+	assert.Equal(t, "jaeger", primary.Database)
+	assert.Equal(t, "jaeger", primary.TablePrefix)
+	assert.Equal(t, 72*time.Hour, primary.TTL)
+	assert.True(t, primary.CreateSchema)
+	assert.True(t, primary.Enabled)
+
+	archive := opts.Get(archiveNamespace)
+	assert.False(t, archive.Enabled)
+	assert.Equal(t, time.Duration(0), archive.TTL)
+	assert.Equal(t, primary.Servers, archive.Servers)
+
+	other := opts.Get("other")
+	assert.Equal(t, primary.Database, other.Database)
+	assert.Equal(t, primary.BatchSize, other.BatchSize)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions(primaryNamespace, archiveNamespace)
+	v, command := config.Viperize(opts.AddFlags)
+	err := command.ParseFlags([]string{
+		"--clickhouse.servers=http://1.1.1.1:8123, http://2.2.2.2:8123",
+		"--clickhouse.database=traces",
+		"--clickhouse.username=hello",
+		"--clickhouse.password=world",
+		"--clickhouse.timeout=5s",
+		"--clickhouse.table-prefix=production",
+		"--clickhouse.ttl=168h",
+		"--clickhouse.create-schema=false",
+		"--clickhouse.batch.size=500",
+		"--clickhouse.batch.flush-interval=2s",
+		"--clickhouse.tls.enabled=true",
+		"--clickhouse-archive.enabled=true",
+		"--clickhouse-archive.servers=http://3.3.3.3:8123",
+		"--clickhouse-archive.ttl=720h",
+	})
+	require.NoError(t, err)
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, []string{"http://1.1.1.1:8123", "http://2.2.2.2:8123"}, primary.Servers)
+	assert.Equal(t, "traces", primary.Database)
+	assert.Equal(t, "hello", primary.Username)
+	assert.Equal(t, "world", primary.Password)
+	assert.Equal(t, 5*time.Second, primary.Timeout)
+	assert.Equal(t, "production", primary.TablePrefix)
+	assert.Equal(t, 168*time.Hour, primary.TTL)
+	assert.False(t, primary.CreateSchema)
+	assert.Equal(t, 500, primary.BatchSize)
+	assert.Equal(t, 2*time.Second, primary.BatchFlushInterval)
+	assert.True(t, primary.TLS.Enabled)
+	assert.True(t, primary.Enabled)
+
+	archive := opts.Get(archiveNamespace)
+	assert.True(t, archive.Enabled)
+	assert.Equal(t, []string{"http://3.3.3.3:8123"}, archive.Servers)
+	assert.Equal(t, "jaeger", archive.Database)
+	assert.Equal(t, 720*time.Hour, archive.TTL)
+	assert.False(t, archive.TLS.Enabled)
+}
+
+func TestOptionsArchiveServers(t *testing.T) {
+	opts := NewOptions(primaryNamespace, archiveNamespace)
+	v, command := config.Viperize(opts.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{
+		"--clickhouse.servers=http://1.1.1.1:8123",
+		"--clickhouse-archive.enabled=true",
+	}))
+	opts.InitFromViper(v)
+	assert.Equal(t, []string{"http://1.1.1.1:8123"}, opts.Get(archiveNamespace).Servers)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schema defines the ClickHouse tables storing spans and dependencies.
+package schema
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+)
+
+//go:embed create.sql.tmpl
+var createTemplate string
+
+var createStatements = template.Must(template.New("create").Parse(createTemplate))
+
+// Tables names the tables of a storage namespace. The names are quoted and
+// qualified with the database, ready to be used in statements.
+type Tables struct {
+	database string
+	prefix   string
+}
+
+// NewTables returns the tables in database whose names start with prefix.
+// Archived spans are stored in tables of their own.
+func NewTables(database, prefix string, archive bool) Tables {
+	if archive {
+		prefix += "_archive"
+	}
+	return Tables{database: database, prefix: prefix}
+}
+
+// Database returns the quoted name of the database.
+func (t Tables) Database() string {
+	return quoteIdentifier(t.database)
+}
+
+// Spans returns the table storing the spans, one row per span.
+func (t Tables) Spans() string {
+	return t.table("spans")
+}
+
+// TraceIDs returns the table storing the time range of every trace, used to find the partitions of a trace.
+func (t Tables) TraceIDs() string {
+	return t.table("trace_ids")
+}
+
+// TraceIDsView returns the materialized view populating TraceIDs.
+func (t Tables) TraceIDsView() string {
+	return t.table("trace_ids_mv")
+}
+
+// Operations returns the table storing the services and operations seen every day.
+func (t Tables) Operations() string {
+	return t.table("operations")
+}
+
+// OperationsView returns the materialized view populating Operations.
+func (t Tables) OperationsView() string {
+	return t.table("operations_mv")
+}
+
+// Dependencies returns the table storing the dependency links between services.
+func (t Tables) Dependencies() string {
+	return t.table("dependencies")
+}
+
+// All returns the tables, but not the views, in the order they are created.
+func (t Tables) All() []string {
+	return []string{t.Spans(), t.TraceIDs(), t.Operations(), t.Dependencies()}
+}
+
+func (t Tables) table(name string) string {
+	return t.Database() + "." + quoteIdentifier(t.prefix+"_"+name)
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+// Statements returns the statements creating the database and the tables if they do
+// not exist yet. Rows older than ttl are deleted, a zero ttl keeps them forever.
+// The TTL of existing tables is not modified.
+func Statements(tables Tables, ttl time.Duration) ([]string, error) {
+	var buf bytes.Buffer
+	err := createStatements.Execute(&buf, struct {
+		Tables     Tables
+		TTLSeconds int64
+	}{
+		Tables:     tables,
+		TTLSeconds: int64(ttl / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var statements []string
+	for _, statement := range strings.Split(buf.String(), ";\n") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements, nil
+}
+
+// Create executes the Statements.
+func Create(ctx context.Context, client clickhouse.Client, tables Tables, ttl time.Duration) error {
+	statements, err := Statements(tables, ttl)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if err := client.Exec(ctx, statement, nil, nil); err != nil {
+			return fmt.Errorf("cannot create the ClickHouse schema: %w", err)
+		}
+	}
+	return nil
+}
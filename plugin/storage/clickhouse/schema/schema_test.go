@@ -0,0 +1,89 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+)
+
+type execClient struct {
+	clickhouse.Client
+	statements []string
+	err        error
+}
+
+func (c *execClient) Exec(ctx context.Context, query string, params clickhouse.Params, data []byte) error {
+	c.statements = append(c.statements, query)
+	return c.err
+}
+
+func TestTables(t *testing.T) {
+	tables := NewTables("jaeger", "jaeger", false)
+	assert.Equal(t, "`jaeger`", tables.Database())
+	assert.Equal(t, "`jaeger`.`jaeger_spans`", tables.Spans())
+	assert.Equal(t, "`jaeger`.`jaeger_trace_ids`", tables.TraceIDs())
+	assert.Equal(t, "`jaeger`.`jaeger_operations`", tables.Operations())
+	assert.Equal(t, "`jaeger`.`jaeger_dependencies`", tables.Dependencies())
+
+	archive := NewTables("jaeger", "jaeger", true)
+	assert.Equal(t, "`jaeger`.`jaeger_archive_spans`", archive.Spans())
+
+	odd := NewTables("my`db", `a\b`, false)
+	assert.Equal(t, "`my\\`db`.`a\\\\b_spans`", odd.Spans())
+}
+
+func TestStatements(t *testing.T) {
+	tables := NewTables("jaeger", "jaeger", false)
+	statements, err := Statements(tables, 72*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, statements, 7)
+	assert.Equal(t, "CREATE DATABASE IF NOT EXISTS `jaeger`", statements[0])
+	for _, statement := range statements {
+		assert.False(t, strings.HasSuffix(statement, ";"), statement)
+	}
+	assert.Contains(t, statements[1], "CREATE TABLE IF NOT EXISTS `jaeger`.`jaeger_spans`")
+	assert.Contains(t, statements[1], "TTL toDateTime(timestamp) + INTERVAL 259200 SECOND")
+	assert.Contains(t, statements[3], "TO `jaeger`.`jaeger_trace_ids`")
+	assert.Contains(t, statements[3], "FROM `jaeger`.`jaeger_spans`")
+
+	statements, err = Statements(tables, 0)
+	require.NoError(t, err)
+	for _, statement := range statements {
+		assert.NotContains(t, statement, "TTL")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tables := NewTables("jaeger", "jaeger", false)
+	client := &execClient{}
+	require.NoError(t, Create(context.Background(), client, tables, 0))
+	expected, err := Statements(tables, 0)
+	require.NoError(t, err)
+	assert.Equal(t, expected, client.statements)
+
+	client = &execClient{err: errors.New("unreachable")}
+	err = Create(context.Background(), client, tables, 0)
+	assert.ErrorContains(t, err, "cannot create the ClickHouse schema: unreachable")
+	assert.Len(t, client.statements, 1)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbmodel
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+)
+
+// TimestampLayout is the layout of the UTC times of the DateTime64(6, 'UTC') columns.
+const TimestampLayout = "2006-01-02 15:04:05.000000"
+
+// Columns lists the columns of the spans table in the order of AppendRowBinary.
+const Columns = "timestamp, trace_id, span_id, service, operation, span_kind, duration, tags, span"
+
+// Span is a row of the spans table, inserted as RowBinary and read as JSONEachRow.
+//
+// The columns other than Span are only used to search traces. The span itself is
+// stored as base64-encoded protobuf, which ClickHouse compresses well.
+type Span struct {
+	// Timestamp is the start time of the span.
+	Timestamp time.Time `json:"timestamp"`
+	TraceID   string    `json:"trace_id"`
+	SpanID    string    `json:"span_id"`
+	Service   string    `json:"service"`
+	Operation string    `json:"operation"`
+	SpanKind  string    `json:"span_kind"`
+	// Duration is the duration of the span in microseconds.
+	Duration uint64 `json:"duration"`
+	// Tags holds the values of the span tags, process tags and log fields by key.
+	Tags map[string][]string `json:"tags"`
+	Span []byte              `json:"span"`
+}
+
+// FromDomain converts a span to a row of the spans table.
+func FromDomain(span *model.Span) (*Span, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return nil, err
+	}
+	spanKind, _ := span.GetSpanKind()
+	row := &Span{
+		Timestamp: span.StartTime,
+		TraceID:   span.TraceID.String(),
+		SpanID:    span.SpanID.String(),
+		Operation: span.OperationName,
+		SpanKind:  spanKind,
+		Duration:  model.DurationAsMicroseconds(span.Duration),
+		Tags:      make(map[string][]string),
+		Span:      data,
+	}
+	row.addTags(span.Tags)
+	if span.Process != nil {
+		row.Service = span.Process.ServiceName
+		row.addTags(span.Process.Tags)
+	}
+	for _, log := range span.Logs {
+		row.addTags(log.Fields)
+	}
+	return row, nil
+}
+
+func (s *Span) addTags(kvs []model.KeyValue) {
+	for i := range kvs {
+		s.Tags[kvs[i].Key] = append(s.Tags[kvs[i].Key], kvs[i].AsString())
+	}
+}
+
+// AppendRowBinary appends the row to the rows inserted in the Columns.
+func (s *Span) AppendRowBinary(b *clickhouse.RowBinary) {
+	b.DateTime64(s.Timestamp, 6)
+	b.String(s.TraceID)
+	b.String(s.SpanID)
+	b.String(s.Service)
+	b.String(s.Operation)
+	b.String(s.SpanKind)
+	b.UInt64(s.Duration)
+	b.StringArrayMap(s.Tags)
+	// the reader decodes the base64 of the JSON strings into bytes
+	b.String(base64.StdEncoding.EncodeToString(s.Span))
+}
+
+// ToDomain decodes the span stored in a row.
+func ToDomain(data []byte) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal(data, span); err != nil {
+		return nil, err
+	}
+	return span, nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+)
+
+func TestFromDomain(t *testing.T) {
+	span := &model.Span{
+		TraceID:       model.NewTraceID(1, 2),
+		SpanID:        model.NewSpanID(3),
+		OperationName: "GET /users",
+		StartTime:     time.Date(2023, 5, 1, 10, 0, 0, 123456000, time.FixedZone("CEST", 2*3600)),
+		Duration:      1500 * time.Microsecond,
+		Tags: model.KeyValues{
+			model.String("span.kind", "client"),
+			model.Bool("error", true),
+			model.Float64("ratio", 0.5),
+		},
+		Process: model.NewProcess("frontend", model.KeyValues{model.String("ip", "10.0.0.1")}),
+		Logs: []model.Log{
+			{Timestamp: time.Unix(1682928000, 0).UTC(), Fields: model.KeyValues{model.String("error", "timeout")}},
+		},
+	}
+	row, err := FromDomain(span)
+	require.NoError(t, err)
+	assert.True(t, span.StartTime.Equal(row.Timestamp))
+	assert.Equal(t, "00000000000000010000000000000002", row.TraceID)
+	assert.Equal(t, "0000000000000003", row.SpanID)
+	assert.Equal(t, "frontend", row.Service)
+	assert.Equal(t, "GET /users", row.Operation)
+	assert.Equal(t, "client", row.SpanKind)
+	assert.EqualValues(t, 1500, row.Duration)
+	assert.Equal(t, map[string][]string{
+		"span.kind": {"client"},
+		"error":     {"true", "timeout"},
+		"ratio":     {"0.5"},
+		"ip":        {"10.0.0.1"},
+	}, row.Tags)
+
+	decoded, err := ToDomain(row.Span)
+	require.NoError(t, err)
+	assert.True(t, span.StartTime.Equal(decoded.StartTime))
+	assert.Equal(t, span.Tags, decoded.Tags)
+	assert.Equal(t, span.Process, decoded.Process)
+}
+
+func TestAppendRowBinary(t *testing.T) {
+	row := &Span{
+		Timestamp: time.Unix(1, 2000),
+		TraceID:   "01",
+		SpanID:    "02",
+		Service:   "s",
+		Operation: "o",
+		SpanKind:  "k",
+		Duration:  3,
+		Tags:      map[string][]string{"t": {"v"}},
+		Span:      []byte{0xff},
+	}
+	var b clickhouse.RowBinary
+	row.AppendRowBinary(&b)
+	assert.Equal(t, []byte{
+		0x42, 0x42, 0x0f, 0, 0, 0, 0, 0, // 1000002 microseconds
+		2, '0', '1',
+		2, '0', '2',
+		1, 's',
+		1, 'o',
+		1, 'k',
+		3, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 't', 1, 1, 'v',
+		4, '/', 'w', '=', '=',
+	}, b.Bytes())
+}
+
+func TestFromDomainWithoutProcess(t *testing.T) {
+	row, err := FromDomain(&model.Span{OperationName: "op"})
+	require.NoError(t, err)
+	assert.Empty(t, row.Service)
+	assert.Empty(t, row.SpanKind)
+	assert.Empty(t, row.Tags)
+}
+
+func TestToDomainError(t *testing.T) {
+	_, err := ToDomain([]byte("not a span"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/spanstore/dbmodel"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// traceIDsQuery builds the statement finding the IDs of the traces matching a query.
+// The values of the query are passed as parameters, never in the statement itself.
+type traceIDsQuery struct {
+	params clickhouse.Params
+}
+
+func newTraceIDsQuery() *traceIDsQuery {
+	return &traceIDsQuery{params: clickhouse.Params{}}
+}
+
+// param adds a parameter and returns its placeholder, e.g. {service:String}.
+func (q *traceIDsQuery) param(name, typ, value string) string {
+	q.params[name] = value
+	return "{" + name + ":" + typ + "}"
+}
+
+// build returns the statement selecting the most recent traces matching the query.
+//
+// When all the conditions must be satisfied by the same span, they are all in the
+// WHERE clause. Otherwise every span of the queried service in the time range is
+// grouped by trace, and the HAVING clause requires a span for each condition.
+func (q *traceIDsQuery) build(table string, query *spanstore.TraceQueryParameters) string {
+	where := []string{
+		"timestamp >= " + q.param("start_time_min", "DateTime64(6, 'UTC')", formatTime(query.StartTimeMin)),
+		"timestamp <= " + q.param("start_time_max", "DateTime64(6, 'UTC')", formatTime(query.StartTimeMax)),
+	}
+	if query.ServiceName != "" {
+		where = append(where, "service = "+q.param("service", "String", query.ServiceName))
+	}
+
+	var spanConditions []string
+	if query.OperationName != "" {
+		spanConditions = append(spanConditions, "operation = "+q.param("operation", "String", query.OperationName))
+	}
+	if query.DurationMin != 0 {
+		spanConditions = append(spanConditions, "duration >= "+q.param("duration_min", "UInt64", formatDuration(query.DurationMin)))
+	}
+	if query.DurationMax != 0 {
+		spanConditions = append(spanConditions, "duration <= "+q.param("duration_max", "UInt64", formatDuration(query.DurationMax)))
+	}
+	tagConditions := q.tagConditions(query)
+
+	var having []string
+	if query.TagMatchMode == spanstore.TagMatchAnySpanInTrace && len(tagConditions) > 1 {
+		if len(spanConditions) > 0 {
+			having = append(having, "countIf("+strings.Join(spanConditions, " AND ")+") > 0")
+		}
+		for _, condition := range tagConditions {
+			having = append(having, "countIf("+condition+") > 0")
+		}
+	} else {
+		where = append(where, spanConditions...)
+		where = append(where, tagConditions...)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT trace_id FROM " + table)
+	sb.WriteString(" WHERE " + strings.Join(where, " AND "))
+	sb.WriteString(" GROUP BY trace_id")
+	if len(having) > 0 {
+		sb.WriteString(" HAVING " + strings.Join(having, " AND "))
+	}
+	sb.WriteString(" ORDER BY max(timestamp) DESC")
+	sb.WriteString(" LIMIT " + q.param("limit", "UInt32", strconv.Itoa(query.NumTraces)))
+	return sb.String()
+}
+
+// tagConditions returns one condition per tag and tag filter of the query,
+// in a stable order so that the statement is the same for the same query.
+func (q *traceIDsQuery) tagConditions(query *spanstore.TraceQueryParameters) []string {
+	keys := make([]string, 0, len(query.Tags))
+	for k := range query.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, 0, len(query.Tags)+len(query.TagFilters))
+	for i, k := range keys {
+		key := q.param(fmt.Sprintf("tag_key_%d", i), "String", k)
+		value := q.param(fmt.Sprintf("tag_value_%d", i), "String", query.Tags[k])
+		conditions = append(conditions, "has(tags["+key+"], "+value+")")
+	}
+	for i, filter := range query.TagFilters {
+		conditions = append(conditions, q.tagFilterCondition(i, filter))
+	}
+	return conditions
+}
+
+// tagFilterCondition translates a tag filter to ClickHouse with the semantics of
+// spanstore.Predicate: values are compared as numbers when both sides are numeric,
+// and negated operators are also satisfied by spans without the tag.
+func (q *traceIDsQuery) tagFilterCondition(i int, filter *spanstore.Predicate) string {
+	values := "tags[" + q.param(fmt.Sprintf("filter_key_%d", i), "String", filter.Key) + "]"
+	operand := q.param(fmt.Sprintf("filter_value_%d", i), "String", filter.Value)
+	number := ""
+	if _, err := strconv.ParseFloat(filter.Value, 64); err == nil {
+		number = q.param(fmt.Sprintf("filter_number_%d", i), "Float64", filter.Value)
+	}
+
+	var match string
+	switch filter.Operator {
+	case spanstore.OpEqual, spanstore.OpNotEqual:
+		op := "="
+		if filter.Operator == spanstore.OpNotEqual {
+			op = "!="
+		}
+		match = "v " + op + " " + operand
+		if number != "" {
+			match = "ifNull(toFloat64OrNull(v) " + op + " " + number + ", " + match + ")"
+		}
+	case spanstore.OpGreater, spanstore.OpGreaterOrEqual, spanstore.OpLess, spanstore.OpLessOrEqual:
+		match = "ifNull(toFloat64OrNull(v) " + filter.Operator.String() + " " + number + ", 0)"
+	case spanstore.OpRegex:
+		match = "match(v, " + operand + ")"
+	case spanstore.OpNotRegex:
+		match = "NOT match(v, " + operand + ")"
+	case spanstore.OpPrefix:
+		match = "startsWith(v, " + operand + ")"
+	}
+	condition := "arrayExists(v -> " + match + ", " + values + ")"
+	if filter.Operator == spanstore.OpNotEqual || filter.Operator == spanstore.OpNotRegex {
+		condition = "(empty(" + values + ") OR " + condition + ")"
+	}
+	return condition
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dbmodel.TimestampLayout)
+}
+
+func formatDuration(d time.Duration) string {
+	return strconv.FormatUint(model.DurationAsMicroseconds(d), 10)
+}
+
+// formatTraceIDs formats trace IDs as the value of an Array(String) parameter.
+func formatTraceIDs(traceIDs []model.TraceID) string {
+	quoted := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		// trace IDs are hexadecimal and do not need escaping
+		quoted[i] = "'" + traceID.String() + "'"
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/spanstore/dbmodel"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const defaultNumTraces = 100
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service name must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("min start time is above max")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("min duration is above max")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and end time must be set")
+)
+
+var _ spanstore.BatchReader = (*SpanReader)(nil)
+
+// SpanReader reads spans from ClickHouse.
+type SpanReader struct {
+	client clickhouse.Client
+	logger *zap.Logger
+	tables schema.Tables
+}
+
+// SpanReaderParams holds constructor parameters for NewSpanReader
+type SpanReaderParams struct {
+	Client clickhouse.Client
+	Logger *zap.Logger
+	Tables schema.Tables
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(p SpanReaderParams) *SpanReader {
+	return &SpanReader{
+		client: p.Client,
+		logger: p.Logger,
+		tables: p.Tables,
+	}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces, err := r.GetTraces(ctx, []model.TraceID{traceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return traces[0], nil
+}
+
+// GetTraces implements spanstore.BatchReader#GetTraces. The time range of the
+// traces is looked up first, so that only the partitions holding them are read.
+func (r *SpanReader) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	if len(traceIDs) == 0 {
+		return nil, nil
+	}
+	const inTraceIDs = "trace_id IN (SELECT arrayJoin({trace_ids:Array(String)}))"
+	query := fmt.Sprintf(
+		"SELECT span FROM %[1]s WHERE %[3]s"+
+			" AND timestamp >= (SELECT min(start_time) FROM %[2]s WHERE %[3]s)"+
+			" AND timestamp <= (SELECT max(end_time) FROM %[2]s WHERE %[3]s)",
+		r.tables.Spans(), r.tables.TraceIDs(), inTraceIDs)
+	params := clickhouse.Params{"trace_ids": formatTraceIDs(spanstore.UniqueTraceIDs(traceIDs))}
+
+	var spans []*model.Span
+	err := r.client.Query(ctx, query, params, func(data []byte) error {
+		var row dbmodel.Span
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		span, err := dbmodel.ToDomain(row.Span)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, span)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the spans of the traces: %w", err)
+	}
+	return spanstore.TracesInOrder(spans, traceIDs), nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	query := "SELECT DISTINCT service FROM " + r.tables.Operations() + " ORDER BY service"
+	services := []string{}
+	err := r.client.Query(ctx, query, nil, func(data []byte) error {
+		var row struct {
+			Service string `json:"service"`
+		}
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		services = append(services, row.Service)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the services: %w", err)
+	}
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(
+	ctx context.Context,
+	query spanstore.OperationQueryParameters,
+) ([]spanstore.Operation, error) {
+	statement := "SELECT DISTINCT operation, span_kind FROM " + r.tables.Operations() + " WHERE service = {service:String}"
+	params := clickhouse.Params{"service": query.ServiceName}
+	if query.SpanKind != "" {
+		statement += " AND span_kind = {span_kind:String}"
+		params["span_kind"] = query.SpanKind
+	}
+	statement += " ORDER BY operation, span_kind"
+
+	operations := []spanstore.Operation{}
+	err := r.client.Query(ctx, statement, params, func(data []byte) error {
+		var row struct {
+			Operation string `json:"operation"`
+			SpanKind  string `json:"span_kind"`
+		}
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		operations = append(operations, spanstore.Operation{Name: row.Operation, SpanKind: row.SpanKind})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the operations: %w", err)
+	}
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetTraces(ctx, traceIDs)
+}
+
+// FindTraceIDs retrieves traceIDs that match the traceQuery, the most recent first.
+// The tags and tag filters are evaluated by ClickHouse with the map of the tags of the spans.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	if query.NumTraces <= 0 {
+		query.NumTraces = defaultNumTraces
+	}
+
+	q := newTraceIDsQuery()
+	statement := q.build(r.tables.Spans(), query)
+	var traceIDs []model.TraceID
+	err := r.client.Query(ctx, statement, q.params, func(data []byte) error {
+		var row struct {
+			TraceID string `json:"trace_id"`
+		}
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		traceID, err := model.TraceIDFromString(row.TraceID)
+		if err != nil {
+			return err
+		}
+		traceIDs = append(traceIDs, traceID)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the traces: %w", err)
+	}
+	return traceIDs, nil
+}
+
+// validateQuery returns an error if certain restrictions are not met
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" && (p.TagConditionCount() > 0 || p.OperationName != "") {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/spanstore/dbmodel"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+type statement struct {
+	query  string
+	params clickhouse.Params
+	data   []byte
+}
+
+// fakeClient records the statements and returns rows for the queries.
+type fakeClient struct {
+	mu         sync.Mutex
+	statements []statement
+	rows       []string
+	err        error
+}
+
+func (c *fakeClient) Exec(ctx context.Context, query string, params clickhouse.Params, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, statement{query: query, params: params, data: data})
+	return c.err
+}
+
+func (c *fakeClient) Query(ctx context.Context, query string, params clickhouse.Params, scan func(row []byte) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, statement{query: query, params: params})
+	if c.err != nil {
+		return c.err
+	}
+	for _, row := range c.rows {
+		if err := scan([]byte(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var testTables = schema.NewTables("jaeger", "jaeger", false)
+
+func newTestReader(client clickhouse.Client) *SpanReader {
+	return NewSpanReader(SpanReaderParams{Client: client, Logger: zap.NewNop(), Tables: testTables})
+}
+
+func spanRow(t *testing.T, traceID model.TraceID, spanID model.SpanID) string {
+	row, err := dbmodel.FromDomain(&model.Span{
+		TraceID:   traceID,
+		SpanID:    spanID,
+		StartTime: time.Unix(1700000000, 0),
+		Process:   model.NewProcess("svc", nil),
+	})
+	require.NoError(t, err)
+	data, err := json.Marshal(row)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSpanReaderGetTraces(t *testing.T) {
+	trace1, trace2 := model.NewTraceID(0, 1), model.NewTraceID(1, 2)
+	client := &fakeClient{rows: []string{
+		spanRow(t, trace1, 1),
+		spanRow(t, trace2, 2),
+		spanRow(t, trace1, 3),
+	}}
+	traces, err := newTestReader(client).GetTraces(context.Background(), []model.TraceID{trace2, trace1, trace2})
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, trace2, traces[0].Spans[0].TraceID)
+	assert.Len(t, traces[1].Spans, 2)
+
+	require.Len(t, client.statements, 1)
+	assert.Contains(t, client.statements[0].query, "FROM `jaeger`.`jaeger_spans` WHERE trace_id IN (SELECT arrayJoin({trace_ids:Array(String)}))")
+	assert.Contains(t, client.statements[0].query, "SELECT min(start_time) FROM `jaeger`.`jaeger_trace_ids`")
+	assert.Equal(t, "['00000000000000010000000000000002','0000000000000001']", client.statements[0].params["trace_ids"])
+}
+
+func TestSpanReaderGetTrace(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	client := &fakeClient{rows: []string{spanRow(t, traceID, 1)}}
+	trace, err := newTestReader(client).GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+
+	client.rows = nil
+	_, err = newTestReader(client).GetTrace(context.Background(), traceID)
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+
+	client.rows = []string{`{"span":"not base64"}`}
+	_, err = newTestReader(client).GetTrace(context.Background(), traceID)
+	assert.ErrorContains(t, err, "cannot read the spans of the traces")
+
+	client.err = errors.New("unreachable")
+	_, err = newTestReader(client).GetTrace(context.Background(), traceID)
+	assert.ErrorContains(t, err, "unreachable")
+}
+
+func TestSpanReaderGetServicesAndOperations(t *testing.T) {
+	client := &fakeClient{rows: []string{`{"service":"a"}`, `{"service":"b"}`}}
+	services, err := newTestReader(client).GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, services)
+	assert.Equal(t, "SELECT DISTINCT service FROM `jaeger`.`jaeger_operations` ORDER BY service", client.statements[0].query)
+
+	client = &fakeClient{rows: []string{`{"operation":"get","span_kind":"server"}`, `{"operation":"query","span_kind":""}`}}
+	operations, err := newTestReader(client).GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "get", SpanKind: "server"}, {Name: "query"}}, operations)
+	assert.NotContains(t, client.statements[0].query, "span_kind =")
+	assert.Equal(t, clickhouse.Params{"service": "a"}, client.statements[0].params)
+
+	client = &fakeClient{}
+	operations, err = newTestReader(client).GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a", SpanKind: "client"})
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+	assert.Contains(t, client.statements[0].query, "AND span_kind = {span_kind:String}")
+	assert.Equal(t, "client", client.statements[0].params["span_kind"])
+
+	client = &fakeClient{err: errors.New("unreachable")}
+	_, err = newTestReader(client).GetServices(context.Background())
+	assert.ErrorContains(t, err, "cannot read the services: unreachable")
+	_, err = newTestReader(client).GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	assert.ErrorContains(t, err, "cannot read the operations: unreachable")
+}
+
+func TestSpanReaderFindTraceIDs(t *testing.T) {
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Tags:          map[string]string{"b": "2", "a": "1"},
+		StartTimeMin:  start,
+		StartTimeMax:  start.Add(time.Hour),
+		DurationMin:   time.Millisecond,
+	}
+	client := &fakeClient{rows: []string{`{"trace_id":"0000000000000001"}`, `{"trace_id":"00000000000000010000000000000002"}`}}
+	traceIDs, err := newTestReader(client).FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(1, 2)}, traceIDs)
+
+	st := client.statements[0]
+	assert.Equal(t, "SELECT trace_id FROM `jaeger`.`jaeger_spans`"+
+		" WHERE timestamp >= {start_time_min:DateTime64(6, 'UTC')} AND timestamp <= {start_time_max:DateTime64(6, 'UTC')}"+
+		" AND service = {service:String} AND operation = {operation:String} AND duration >= {duration_min:UInt64}"+
+		" AND has(tags[{tag_key_0:String}], {tag_value_0:String}) AND has(tags[{tag_key_1:String}], {tag_value_1:String})"+
+		" GROUP BY trace_id ORDER BY max(timestamp) DESC LIMIT {limit:UInt32}", st.query)
+	assert.Equal(t, clickhouse.Params{
+		"start_time_min": "2023-05-01 10:00:00.000000",
+		"start_time_max": "2023-05-01 11:00:00.000000",
+		"service":        "svc",
+		"operation":      "op",
+		"duration_min":   "1000",
+		"tag_key_0":      "a",
+		"tag_value_0":    "1",
+		"tag_key_1":      "b",
+		"tag_value_1":    "2",
+		"limit":          "100",
+	}, st.params)
+
+	query.TagMatchMode = spanstore.TagMatchAnySpanInTrace
+	client = &fakeClient{}
+	traceIDs, err = newTestReader(client).FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Empty(t, traceIDs)
+	assert.Contains(t, client.statements[0].query, "AND service = {service:String} GROUP BY trace_id"+
+		" HAVING countIf(operation = {operation:String} AND duration >= {duration_min:UInt64}) > 0"+
+		" AND countIf(has(tags[{tag_key_0:String}], {tag_value_0:String})) > 0"+
+		" AND countIf(has(tags[{tag_key_1:String}], {tag_value_1:String})) > 0 ORDER BY")
+
+	client = &fakeClient{rows: []string{`{"trace_id":"xyz"}`}}
+	_, err = newTestReader(client).FindTraceIDs(context.Background(), query)
+	assert.ErrorContains(t, err, "cannot find the traces")
+}
+
+func TestSpanReaderFindTraceIDsTagFilters(t *testing.T) {
+	tests := []struct {
+		filter    string
+		condition string
+	}{
+		{
+			filter:    "http.status_code>=500",
+			condition: "arrayExists(v -> ifNull(toFloat64OrNull(v) >= {filter_number_0:Float64}, 0), tags[{filter_key_0:String}])",
+		},
+		{
+			filter:    "error=true",
+			condition: "arrayExists(v -> v = {filter_value_0:String}, tags[{filter_key_0:String}])",
+		},
+		{
+			filter: "http.status_code=200",
+			condition: "arrayExists(v -> ifNull(toFloat64OrNull(v) = {filter_number_0:Float64}, v = {filter_value_0:String})," +
+				" tags[{filter_key_0:String}])",
+		},
+		{
+			filter:    "error!=true",
+			condition: "(empty(tags[{filter_key_0:String}]) OR arrayExists(v -> v != {filter_value_0:String}, tags[{filter_key_0:String}]))",
+		},
+		{
+			filter:    `db.statement=~"SELECT.*"`,
+			condition: "arrayExists(v -> match(v, {filter_value_0:String}), tags[{filter_key_0:String}])",
+		},
+		{
+			filter:    `db.statement!~"SELECT.*"`,
+			condition: "(empty(tags[{filter_key_0:String}]) OR arrayExists(v -> NOT match(v, {filter_value_0:String}), tags[{filter_key_0:String}]))",
+		},
+		{
+			filter:    "http.url^=https",
+			condition: "arrayExists(v -> startsWith(v, {filter_value_0:String}), tags[{filter_key_0:String}])",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			filter, err := spanstore.ParseTagFilter(test.filter)
+			require.NoError(t, err)
+			client := &fakeClient{}
+			_, err = newTestReader(client).FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+				ServiceName:  "svc",
+				TagFilters:   []*spanstore.Predicate{filter},
+				StartTimeMin: time.Now().Add(-time.Hour),
+				StartTimeMax: time.Now(),
+			})
+			require.NoError(t, err)
+			assert.Contains(t, client.statements[0].query, " AND "+test.condition+" GROUP BY")
+			assert.Equal(t, filter.Key, client.statements[0].params["filter_key_0"])
+			assert.Equal(t, filter.Value, client.statements[0].params["filter_value_0"])
+		})
+	}
+}
+
+func TestSpanReaderFindTraces(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	client := &fakeClient{rows: []string{`{"trace_id":"0000000000000001"}`}}
+	reader := newTestReader(client)
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: time.Now().Add(-time.Hour),
+		StartTimeMax: time.Now(),
+		NumTraces:    20,
+	}
+	// the fake returns the same rows to both queries, they are read as spans of no trace
+	traces, err := reader.FindTraces(context.Background(), query)
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+	require.Len(t, client.statements, 2)
+	assert.Equal(t, "20", client.statements[0].params["limit"])
+	assert.Equal(t, "['"+traceID.String()+"']", client.statements[1].params["trace_ids"])
+
+	traces, err = newTestReader(&fakeClient{}).FindTraces(context.Background(), query)
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+}
+
+func TestValidateQuery(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{query: nil, err: ErrMalformedRequestObject},
+		{query: &spanstore.TraceQueryParameters{OperationName: "op"}, err: ErrServiceNameNotSet},
+		{query: &spanstore.TraceQueryParameters{Tags: map[string]string{"k": "v"}}, err: ErrServiceNameNotSet},
+		{query: &spanstore.TraceQueryParameters{ServiceName: "svc"}, err: ErrStartAndEndTimeNotSet},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: now, StartTimeMax: now.Add(-time.Second)},
+			err:   ErrStartTimeMinGreaterThanMax,
+		},
+		{
+			query: &spanstore.TraceQueryParameters{
+				ServiceName: "svc", StartTimeMin: now, StartTimeMax: now,
+				DurationMin: time.Second, DurationMax: time.Millisecond,
+			},
+			err: ErrDurationMinGreaterThanMax,
+		},
+		{query: &spanstore.TraceQueryParameters{StartTimeMin: now, StartTimeMax: now}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.err, validateQuery(test.query))
+	}
+
+	_, err := newTestReader(&fakeClient{}).FindTraces(context.Background(), nil)
+	assert.Equal(t, ErrMalformedRequestObject, err)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/spanstore/dbmodel"
+	storageMetrics "github.com/kjschnei001/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriter buffers spans and inserts them into ClickHouse in batches,
+// since ClickHouse works best with few large inserts.
+type SpanWriter struct {
+	client    clickhouse.Client
+	logger    *zap.Logger
+	metrics   *storageMetrics.WriteMetrics
+	insert    string
+	batchSize int
+
+	mu    sync.Mutex
+	batch clickhouse.RowBinary
+	count int
+
+	done    chan struct{}
+	flushWG sync.WaitGroup
+}
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Client         clickhouse.Client
+	Logger         *zap.Logger
+	MetricsFactory metrics.Factory
+	Tables         schema.Tables
+	// BatchSize is the number of spans inserted together, they are inserted
+	// as they are written if it is not greater than one.
+	BatchSize int
+	// BatchFlushInterval is how often spans are inserted, even if there are less than BatchSize.
+	BatchFlushInterval time.Duration
+}
+
+// NewSpanWriter creates a SpanWriter, which must be closed to insert the last spans.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	w := &SpanWriter{
+		client:    p.Client,
+		logger:    p.Logger,
+		metrics:   storageMetrics.NewWriteMetrics(p.MetricsFactory, "span_batches"),
+		insert:    "INSERT INTO " + p.Tables.Spans() + " (" + dbmodel.Columns + ") FORMAT RowBinary",
+		batchSize: p.BatchSize,
+		done:      make(chan struct{}),
+	}
+	if p.BatchFlushInterval > 0 {
+		w.flushWG.Add(1)
+		go w.flushPeriodically(p.BatchFlushInterval)
+	}
+	return w
+}
+
+// WriteSpan adds the span to the current batch, and inserts the batch when it is full.
+func (w *SpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	row, err := dbmodel.FromDomain(span)
+	if err != nil {
+		return err
+	}
+	var data clickhouse.RowBinary
+	row.AppendRowBinary(&data)
+
+	w.mu.Lock()
+	w.batch.Append(&data)
+	w.count++
+	if w.count < w.batchSize {
+		w.mu.Unlock()
+		return nil
+	}
+	batch := w.takeBatch()
+	w.mu.Unlock()
+	return w.insertBatch(ctx, batch)
+}
+
+func (w *SpanWriter) takeBatch() []byte {
+	batch := append([]byte(nil), w.batch.Bytes()...)
+	w.batch.Reset()
+	w.count = 0
+	return batch
+}
+
+// Flush inserts the spans of the current batch.
+func (w *SpanWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	if w.count == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	batch := w.takeBatch()
+	w.mu.Unlock()
+	return w.insertBatch(ctx, batch)
+}
+
+func (w *SpanWriter) insertBatch(ctx context.Context, batch []byte) error {
+	start := time.Now()
+	err := w.client.Exec(ctx, w.insert, nil, batch)
+	w.metrics.Emit(err, time.Since(start))
+	return err
+}
+
+func (w *SpanWriter) flushPeriodically(interval time.Duration) {
+	defer w.flushWG.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.Flush(context.Background()); err != nil {
+				w.logger.Error("Failed to insert spans", zap.Error(err))
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Close stops the periodic flushes and inserts the spans of the current batch.
+func (w *SpanWriter) Close() error {
+	close(w.done)
+	w.flushWG.Wait()
+	return w.Flush(context.Background())
+}
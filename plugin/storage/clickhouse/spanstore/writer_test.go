@@ -0,0 +1,112 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/spanstore/dbmodel"
+)
+
+func newTestWriter(client *fakeClient, batchSize int, flushInterval time.Duration) (*SpanWriter, *metricstest.Factory) {
+	mf := metricstest.NewFactory(0)
+	return NewSpanWriter(SpanWriterParams{
+		Client:             client,
+		Logger:             zap.NewNop(),
+		MetricsFactory:     mf,
+		Tables:             testTables,
+		BatchSize:          batchSize,
+		BatchFlushInterval: flushInterval,
+	}), mf
+}
+
+func testSpan(spanID model.SpanID) *model.Span {
+	return &model.Span{
+		TraceID:       model.NewTraceID(0, 1),
+		SpanID:        spanID,
+		OperationName: "op",
+		StartTime:     time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+		Duration:      time.Millisecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server"), model.Int64("http.status_code", 200)},
+		Process:       model.NewProcess("svc", model.KeyValues{model.String("hostname", "h1")}),
+		Logs: []model.Log{
+			{
+				Timestamp: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+				Fields:    model.KeyValues{model.String("event", "retry"), model.String("event", "done")},
+			},
+		},
+	}
+}
+
+func encodeRows(t *testing.T, spans ...*model.Span) []byte {
+	var rows clickhouse.RowBinary
+	for _, span := range spans {
+		row, err := dbmodel.FromDomain(span)
+		require.NoError(t, err)
+		row.AppendRowBinary(&rows)
+	}
+	return rows.Bytes()
+}
+
+func TestSpanWriterBatches(t *testing.T) {
+	client := &fakeClient{}
+	w, mf := newTestWriter(client, 2, 0)
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1)))
+	assert.Empty(t, client.statements)
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(2)))
+	require.Len(t, client.statements, 1)
+	assert.Equal(t, "INSERT INTO `jaeger`.`jaeger_spans` ("+dbmodel.Columns+") FORMAT RowBinary", client.statements[0].query)
+	assert.Equal(t, encodeRows(t, testSpan(1), testSpan(2)), client.statements[0].data)
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(3)))
+	require.NoError(t, w.Close())
+	require.Len(t, client.statements, 2)
+	assert.Equal(t, encodeRows(t, testSpan(3)), client.statements[1].data)
+
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "span_batches.attempts", Value: 2})
+}
+
+func TestSpanWriterUnbatched(t *testing.T) {
+	client := &fakeClient{err: errors.New("unreachable")}
+	w, mf := newTestWriter(client, 0, 0)
+	assert.EqualError(t, w.WriteSpan(context.Background(), testSpan(1)), "unreachable")
+	require.NoError(t, w.Close())
+	assert.Len(t, client.statements, 1)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "span_batches.errors", Value: 1})
+}
+
+func TestSpanWriterFlushPeriodically(t *testing.T) {
+	client := &fakeClient{}
+	w, _ := newTestWriter(client, 100, time.Millisecond)
+	defer w.Close()
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1)))
+	assert.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return len(client.statements) == 1
+	}, time.Second, time.Millisecond)
+}
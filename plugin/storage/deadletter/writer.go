@@ -0,0 +1,93 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deadletter provides the span writers receiving the spans that could not be written to storage.
+package deadletter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/gogo/protobuf/jsonpb"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/kafka/producer"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/kafka"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+var (
+	_ spanstore.Writer = (*FileWriter)(nil)
+	_ io.Closer        = (*FileWriter)(nil)
+)
+
+// FileWriter appends the spans to a file, one span per line in the Protobuf-based JSON
+// encoding also used by the Kafka storage.
+type FileWriter struct {
+	marshaller jsonpb.Marshaler
+	mu         sync.Mutex
+	file       *os.File
+}
+
+// NewFileWriter opens the file at path for appending, creating it if needed.
+func NewFileWriter(path string) (*FileWriter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open dead-letter file: %w", err)
+	}
+	return &FileWriter{file: file}, nil
+}
+
+// WriteSpan appends the span to the file.
+func (w *FileWriter) WriteSpan(_ context.Context, span *model.Span) error {
+	out := new(bytes.Buffer)
+	if err := w.marshaller.Marshal(out, span); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.file.Write(out.Bytes())
+	return err
+}
+
+// Close closes the file.
+func (w *FileWriter) Close() error {
+	return w.file.Close()
+}
+
+// NewKafkaWriter creates a span writer producing the spans to a Kafka topic, encoded as
+// protobuf so that they can be replayed by the ingester.
+func NewKafkaWriter(brokers []string, topic string, metricsFactory metrics.Factory, logger *zap.Logger) (spanstore.Writer, error) {
+	f := kafka.NewFactory()
+	f.InitFromOptions(kafka.Options{
+		Config: producer.Configuration{
+			Brokers:      brokers,
+			RequiredAcks: sarama.WaitForLocal,
+		},
+		Topic:    topic,
+		Encoding: kafka.EncodingProto,
+	})
+	if err := f.Initialize(metricsFactory, logger); err != nil {
+		return nil, fmt.Errorf("cannot create dead-letter Kafka producer: %w", err)
+	}
+	return f.CreateSpanWriter()
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deadletter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+func TestFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letter.json")
+	w, err := NewFileWriter(path)
+	require.NoError(t, err)
+	spans := []*model.Span{
+		{TraceID: model.NewTraceID(0, 1), SpanID: 1, OperationName: "a"},
+		{TraceID: model.NewTraceID(0, 1), SpanID: 2, OperationName: "b"},
+	}
+	for _, span := range spans {
+		require.NoError(t, w.WriteSpan(context.Background(), span))
+	}
+	require.NoError(t, w.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 2)
+	for i, line := range lines {
+		span := &model.Span{}
+		require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(line), span))
+		assert.Equal(t, spans[i].OperationName, span.OperationName)
+		assert.Equal(t, spans[i].SpanID, span.SpanID)
+	}
+}
+
+func TestNewFileWriterError(t *testing.T) {
+	_, err := NewFileWriter(filepath.Join(t.TempDir(), "missing", "dead-letter.json"))
+	assert.ErrorContains(t, err, "cannot open dead-letter file")
+}
@@ -102,17 +102,45 @@ func (s *DependencyStore) writeDependencies(indexName string, ts time.Time, depe
 // GetDependencies returns all interservice dependencies
 func (s *DependencyStore) GetDependencies(ctx context.Context, endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
 	indices := s.getReadIndices(endTs, lookback)
+	retDependencies, err := s.searchDependencies(ctx, indices, endTs.Add(-lookback), endTs, true)
+	if err != nil {
+		return nil, err
+	}
+	return dbmodel.ToDomainDependencies(retDependencies), nil
+}
+
+// searchDependencies returns the dependencies written between start and end. When more than
+// maxDocCount documents match, e.g. written by many collectors, the time range is split in halves
+// which are searched separately, instead of silently truncating the dependencies.
+func (s *DependencyStore) searchDependencies(ctx context.Context, indices []string, start, end time.Time, includeStart bool) ([]dbmodel.DependencyLink, error) {
 	searchResult, err := s.client.Search(indices...).
 		Size(s.maxDocCount).
-		Query(buildTSQuery(endTs, lookback)).
+		Query(buildTSQuery(start, end, includeStart)).
 		IgnoreUnavailable(true).
 		Do(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search for dependencies: %w", err)
 	}
 
-	var retDependencies []dbmodel.DependencyLink
 	hits := searchResult.Hits.Hits
+	if searchResult.Hits.TotalHits > int64(len(hits)) {
+		// the timestamps are stored with a millisecond precision
+		if end.Sub(start) < 2*time.Millisecond {
+			return nil, fmt.Errorf("more than %d dependency documents were written at %v, the max doc count should be increased", len(hits), start)
+		}
+		mid := start.Add(end.Sub(start) / 2)
+		first, err := s.searchDependencies(ctx, indices, start, mid, includeStart)
+		if err != nil {
+			return nil, err
+		}
+		second, err := s.searchDependencies(ctx, indices, mid, end, false)
+		if err != nil {
+			return nil, err
+		}
+		return append(first, second...), nil
+	}
+
+	var retDependencies []dbmodel.DependencyLink
 	for _, hit := range hits {
 		source := hit.Source
 		var tToD dbmodel.TimeDependencies
@@ -121,11 +149,15 @@ func (s *DependencyStore) GetDependencies(ctx context.Context, endTs time.Time,
 		}
 		retDependencies = append(retDependencies, tToD.Dependencies...)
 	}
-	return dbmodel.ToDomainDependencies(retDependencies), nil
+	return retDependencies, nil
 }
 
-func buildTSQuery(endTs time.Time, lookback time.Duration) elastic.Query {
-	return elastic.NewRangeQuery("timestamp").Gte(endTs.Add(-lookback)).Lte(endTs)
+func buildTSQuery(start, end time.Time, includeStart bool) elastic.Query {
+	query := elastic.NewRangeQuery("timestamp").Lte(end)
+	if includeStart {
+		return query.Gte(start)
+	}
+	return query.Gt(start)
 }
 
 func (s *DependencyStore) getReadIndices(ts time.Time, lookback time.Duration) []string {
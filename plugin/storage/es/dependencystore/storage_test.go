@@ -26,6 +26,7 @@ import (
 	"github.com/olivere/elastic"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/kjschnei001/jaeger/model"
@@ -203,6 +204,65 @@ func TestGetDependencies(t *testing.T) {
 	}
 }
 
+func TestGetDependenciesSplitTimeRange(t *testing.T) {
+	withDepStorage("", "2006-01-02", 1, func(r *depStorageTest) {
+		endTs := time.Date(1995, time.April, 21, 4, 0, 0, 0, time.UTC)
+		dependencies := func(parent string) string {
+			return `{"dependencies": [{"parent": "` + parent + `", "child": "world", "callCount": 1}]}`
+		}
+		truncated := createSearchResult(dependencies("hello"))
+		truncated.Hits.TotalHits = 2
+
+		searchService := &mocks.SearchService{}
+		r.client.On("Search", "jaeger-dependencies-1995-04-21").Return(searchService)
+		searchService.On("Size", 1).Return(searchService)
+		var queries []map[string]interface{}
+		searchService.On("Query", mock.Anything).Run(func(args mock.Arguments) {
+			source, err := args.Get(0).(elastic.Query).Source()
+			require.NoError(t, err)
+			queries = append(queries, source.(map[string]interface{})["range"].(map[string]interface{})["timestamp"].(map[string]interface{}))
+		}).Return(searchService)
+		searchService.On("IgnoreUnavailable", true).Return(searchService)
+		searchService.On("Do", mock.Anything).Return(truncated, nil).Once()
+		searchService.On("Do", mock.Anything).Return(createSearchResult(dependencies("first")), nil).Once()
+		searchService.On("Do", mock.Anything).Return(createSearchResult(dependencies("second")), nil).Once()
+
+		actual, err := r.storage.GetDependencies(context.Background(), endTs, 2*time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, []model.DependencyLink{
+			{Parent: "first", Child: "world", CallCount: 1},
+			{Parent: "second", Child: "world", CallCount: 1},
+		}, actual)
+		require.Len(t, queries, 3)
+		assert.Equal(t, endTs.Add(-2*time.Hour), queries[1]["from"])
+		assert.Equal(t, true, queries[1]["include_lower"])
+		assert.Equal(t, endTs.Add(-time.Hour), queries[1]["to"])
+		assert.Equal(t, endTs.Add(-time.Hour), queries[2]["from"])
+		assert.Equal(t, false, queries[2]["include_lower"])
+		assert.Equal(t, endTs, queries[2]["to"])
+	})
+}
+
+func TestGetDependenciesTooManyDocuments(t *testing.T) {
+	withDepStorage("", "2006-01-02", 1, func(r *depStorageTest) {
+		endTs := time.Date(1995, time.April, 21, 4, 0, 0, 0, time.UTC)
+		truncated := createSearchResult(`{"dependencies": []}`)
+		truncated.Hits.TotalHits = 2
+
+		searchService := &mocks.SearchService{}
+		r.client.On("Search", "jaeger-dependencies-1995-04-21").Return(searchService)
+		searchService.On("Size", 1).Return(searchService)
+		searchService.On("Query", mock.Anything).Return(searchService)
+		searchService.On("IgnoreUnavailable", true).Return(searchService)
+		searchService.On("Do", mock.Anything).Return(truncated, nil)
+
+		actual, err := r.storage.GetDependencies(context.Background(), endTs, 2*time.Millisecond)
+		require.ErrorContains(t, err, "more than 1 dependency documents were written at")
+		assert.Nil(t, actual)
+		searchService.AssertNumberOfCalls(t, "Do", 2)
+	})
+}
+
 func createSearchResult(dependencyLink string) *elastic.SearchResult {
 	dependencyLinkRaw := []byte(dependencyLink)
 	hits := make([]*elastic.SearchHit, 1)
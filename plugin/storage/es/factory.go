@@ -19,10 +19,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"sync/atomic"
 
+	"github.com/olivere/elastic"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/es"
 	"github.com/kjschnei001/jaeger/pkg/es/config"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
@@ -57,6 +60,9 @@ type Factory struct {
 	primaryClient es.Client
 	archiveConfig *config.Configuration
 	archiveClient es.Client
+
+	// failedSpanHandler receives the spans of the failed bulk requests, see SetFailedSpanHandler
+	failedSpanHandler atomic.Value // of func(span *model.Span, err error)
 }
 
 // NewFactory creates a new Factory.
@@ -92,6 +98,9 @@ func (f *Factory) InitFromOptions(o Options) {
 func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
 	f.metricsFactory, f.logger = metricsFactory, logger
 
+	if f.primaryConfig != nil {
+		f.primaryConfig.BulkFailureHandler = f.handleBulkFailure
+	}
 	primaryClient, err := f.newClientFn(f.primaryConfig, logger, metricsFactory)
 	if err != nil {
 		return fmt.Errorf("failed to create primary Elasticsearch client: %w", err)
@@ -106,6 +115,47 @@ func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger)
 	return nil
 }
 
+// SetFailedSpanHandler sets the handler of the spans the span writer failed to index. The span
+// writer indexes the spans with a bulk processor, so its failures are not returned by WriteSpan.
+func (f *Factory) SetFailedSpanHandler(handler func(span *model.Span, err error)) {
+	f.failedSpanHandler.Store(handler)
+}
+
+// handleBulkFailure passes the spans of the failed requests of a bulk request to the failed span
+// handler, with the error of each request, or the error of the bulk request when it failed altogether.
+func (f *Factory) handleBulkFailure(requests []elastic.BulkableRequest, response *elastic.BulkResponse, err error) {
+	handler, ok := f.failedSpanHandler.Load().(func(span *model.Span, err error))
+	if !ok {
+		return
+	}
+	handle := func(request elastic.BulkableRequest, err error) {
+		span, decodeErr := esSpanStore.SpanFromBulkRequest(request, f.primaryConfig.Tags.DotReplacement)
+		if decodeErr != nil {
+			f.logger.Error("Cannot retry the failed bulk request", zap.Error(decodeErr))
+			return
+		}
+		if span != nil {
+			handler(span, err)
+		}
+	}
+	// the items of the response are in the order of the requests
+	if response == nil || len(response.Items) != len(requests) {
+		if err != nil {
+			for _, request := range requests {
+				handle(request, err)
+			}
+		}
+		return
+	}
+	for i, item := range response.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				handle(requests[i], &elastic.Error{Status: result.Status, Details: result.Error})
+			}
+		}
+	}
+}
+
 // CreateSpanReader implements storage.Factory
 func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
 	return createSpanReader(f.metricsFactory, f.logger, f.primaryClient, f.primaryConfig, false)
@@ -121,6 +171,11 @@ func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
 	return createDependencyReader(f.logger, f.primaryClient, f.primaryConfig)
 }
 
+// CreateDependencyWriter implements storage.DependencyWriterFactory
+func (f *Factory) CreateDependencyWriter() (dependencystore.Writer, error) {
+	return createDependencyWriter(f.logger, f.primaryClient, f.primaryConfig)
+}
+
 // CreateArchiveSpanReader implements storage.ArchiveFactory
 func (f *Factory) CreateArchiveSpanReader() (spanstore.Reader, error) {
 	if !f.archiveConfig.Enabled {
@@ -235,6 +290,40 @@ func createDependencyReader(
 	return reader, nil
 }
 
+func createDependencyWriter(
+	logger *zap.Logger,
+	client es.Client,
+	cfg *config.Configuration,
+) (dependencystore.Writer, error) {
+	writer := esDepStore.NewDependencyStore(esDepStore.DependencyStoreParams{
+		Client:              client,
+		Logger:              logger,
+		IndexPrefix:         cfg.IndexPrefix,
+		IndexDateLayout:     cfg.IndexDateLayoutDependencies,
+		MaxDocCount:         cfg.MaxDocCount,
+		UseReadWriteAliases: cfg.UseReadWriteAliases,
+	})
+	// Creating a template here would conflict with the one created for ILM resulting to no index rollover
+	if cfg.CreateIndexTemplates && !cfg.UseILM {
+		mappingBuilder := mappings.MappingBuilder{
+			TemplateBuilder: es.TextTemplateBuilder{},
+			Shards:          cfg.NumShards,
+			Replicas:        cfg.NumReplicas,
+			EsVersion:       cfg.Version,
+			IndexPrefix:     cfg.IndexPrefix,
+			UseILM:          cfg.UseILM,
+		}
+		dependenciesMapping, err := mappingBuilder.GetDependenciesMappings()
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.CreateTemplates(dependenciesMapping); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
 var _ io.Closer = (*Factory)(nil)
 
 // Close closes the resources held by the factory
@@ -19,17 +19,21 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/olivere/elastic"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/config"
 	"github.com/kjschnei001/jaeger/pkg/es"
 	escfg "github.com/kjschnei001/jaeger/pkg/es/config"
 	"github.com/kjschnei001/jaeger/pkg/es/mocks"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/es/spanstore/dbmodel"
 	"github.com/kjschnei001/jaeger/storage"
 )
 
@@ -83,6 +87,9 @@ func TestElasticsearchFactory(t *testing.T) {
 	_, err = f.CreateDependencyReader()
 	assert.NoError(t, err)
 
+	_, err = f.CreateDependencyWriter()
+	assert.NoError(t, err)
+
 	_, err = f.CreateArchiveSpanReader()
 	assert.NoError(t, err)
 
@@ -239,3 +246,57 @@ func TestInitFromOptions(t *testing.T) {
 	assert.Equal(t, o.GetPrimary(), f.primaryConfig)
 	assert.Equal(t, o.Get(archiveNamespace), f.archiveConfig)
 }
+
+func TestHandleBulkFailure(t *testing.T) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags([]string{})
+	f.InitFromViper(v, zap.NewNop())
+	f.newClientFn = (&mockClientBuilder{}).NewClient
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	require.NotNil(t, f.primaryConfig.BulkFailureHandler)
+
+	spanRequest := func(spanID uint64) elastic.BulkableRequest {
+		span := &model.Span{
+			TraceID:   model.NewTraceID(0, 1),
+			SpanID:    model.NewSpanID(spanID),
+			StartTime: time.Unix(1, 0),
+			Process:   &model.Process{ServiceName: "svc"},
+		}
+		jsonSpan := dbmodel.NewFromDomain(false, nil, "@").FromDomainEmbedProcess(span)
+		return elastic.NewBulkIndexRequest().Index("jaeger-span-1970-01-01").Type("span").Doc(jsonSpan)
+	}
+	requests := []elastic.BulkableRequest{
+		spanRequest(1),
+		spanRequest(2),
+		spanRequest(3),
+		elastic.NewBulkIndexRequest().Index("jaeger-service-1970-01-01").Type("service").Doc(dbmodel.Service{ServiceName: "svc"}),
+	}
+	response := &elastic.BulkResponse{
+		Errors: true,
+		Items: []map[string]*elastic.BulkResponseItem{
+			{"index": {Status: 429, Error: &elastic.ErrorDetails{Type: "es_rejected_execution_exception"}}},
+			{"index": {Status: 400, Error: &elastic.ErrorDetails{Type: "mapper_parsing_exception"}}},
+			{"index": {Status: 201}},
+			{"index": {Status: 429, Error: &elastic.ErrorDetails{Type: "es_rejected_execution_exception"}}},
+		},
+	}
+
+	// the failures are ignored until there is a handler
+	f.primaryConfig.BulkFailureHandler(requests, response, nil)
+
+	failed := make(map[model.SpanID]error)
+	f.SetFailedSpanHandler(func(span *model.Span, err error) {
+		failed[span.SpanID] = err
+	})
+	f.primaryConfig.BulkFailureHandler(requests, response, nil)
+	assert.Equal(t, map[model.SpanID]error{
+		1: &elastic.Error{Status: 429, Details: &elastic.ErrorDetails{Type: "es_rejected_execution_exception"}},
+		2: &elastic.Error{Status: 400, Details: &elastic.ErrorDetails{Type: "mapper_parsing_exception"}},
+	}, failed)
+
+	failed = make(map[model.SpanID]error)
+	errConnection := errors.New("connection refused")
+	f.primaryConfig.BulkFailureHandler(requests, nil, errConnection)
+	assert.Equal(t, map[model.SpanID]error{1: errConnection, 2: errConnection, 3: errConnection}, failed)
+}
@@ -21,6 +21,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/olivere/elastic"
@@ -37,13 +39,14 @@ import (
 )
 
 const (
-	spanIndex               = "jaeger-span-"
-	serviceIndex            = "jaeger-service-"
-	archiveIndexSuffix      = "archive"
-	archiveReadIndexSuffix  = archiveIndexSuffix + "-read"
-	archiveWriteIndexSuffix = archiveIndexSuffix + "-write"
-	traceIDAggregation      = "traceIDs"
-	indexPrefixSeparator    = "-"
+	spanIndex                 = "jaeger-span-"
+	serviceIndex              = "jaeger-service-"
+	archiveIndexSuffix        = "archive"
+	archiveReadIndexSuffix    = archiveIndexSuffix + "-read"
+	archiveWriteIndexSuffix   = archiveIndexSuffix + "-write"
+	traceIDAggregation        = "traceIDs"
+	allTagsMatchedAggregation = "allTagsMatched"
+	indexPrefixSeparator      = "-"
 
 	traceIDField           = "traceID"
 	durationField          = "duration"
@@ -60,10 +63,35 @@ const (
 	tagValueField          = "value"
 
 	defaultNumTraces = 100
+	// anySpanTraceIDsMultiple is how many more traces are aggregated for TagMatchAnySpanInTrace,
+	// to make up for the traces later removed because not all tags were matched
+	anySpanTraceIDsMultiple = 3
+	// tracePageHitsMultiple is how many span documents are fetched per requested trace
+	// when paging, since a trace can have several matching spans
+	tracePageHitsMultiple = 3
+
+	// traceSummaryServicesSize is the maximum number of services counted per trace summary
+	traceSummaryServicesSize = 1000
+
+	minStartTimeAggregation      = "minStartTime"
+	maxEndTimeAggregation        = "maxEndTime"
+	serviceSpanCountsAggregation = "serviceSpanCounts"
+	errorSpansAggregation        = "errorSpans"
+	rootSpansAggregation         = "rootSpans"
+	firstSpanAggregation         = "firstSpan"
 
 	rolloverMaxSpanAge = time.Hour * 24 * 365 * 50
 )
 
+// tagValueCompareScript compares a keyword tag value numerically with params.value.
+const tagValueCompareScript = `if (doc[params.field].size() == 0) { return false; }
+double v;
+try { v = Double.parseDouble(doc[params.field].value); } catch (NumberFormatException e) { return false; }
+if (params.op == '>') { return v > params.value; }
+if (params.op == '>=') { return v >= params.value; }
+if (params.op == '<') { return v < params.value; }
+return v <= params.value;`
+
 var (
 	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
 	ErrServiceNameNotSet = errors.New("service Name must be set")
@@ -251,6 +279,23 @@ func (s *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*mode
 	return traces[0], nil
 }
 
+// GetTraces implements spanstore.BatchReader#GetTraces. The spans of all the
+// traces are searched with a single multi search request.
+func (s *SpanReader) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "GetTraces")
+	defer span.Finish()
+	currentTime := time.Now()
+	traces, err := s.multiRead(ctx, spanstore.UniqueTraceIDs(traceIDs), currentTime.Add(-s.maxSpanAge), currentTime)
+	if err != nil {
+		return nil, err
+	}
+	var spans []*model.Span
+	for _, trace := range traces {
+		spans = append(spans, trace.Spans...)
+	}
+	return spanstore.TracesInOrder(spans, traceIDs), nil
+}
+
 func (s *SpanReader) collectSpans(esSpansRaw []*elastic.SearchHit) ([]*model.Span, error) {
 	spans := make([]*model.Span, len(esSpansRaw))
 
@@ -359,6 +404,357 @@ func (s *SpanReader) FindTraceIDs(ctx context.Context, traceQuery *spanstore.Tra
 	return convertTraceIDsStringsToModels(esTraceIDs)
 }
 
+// FindTraceSummaries implements spanstore.SummaryReader. The summaries are computed
+// with aggregations over the spans of the found traces, without fetching the spans.
+// The root span of a summary is the earliest span without references, or else the
+// earliest span of the trace.
+func (s *SpanReader) FindTraceSummaries(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]*spanstore.TraceSummary, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "FindTraceSummaries")
+	defer span.Finish()
+
+	traceIDs, err := s.FindTraceIDs(ctx, traceQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(traceIDs) == 0 {
+		return []*spanstore.TraceSummary{}, nil
+	}
+	startTime, endTime := traceQuery.StartTimeMin, traceQuery.StartTimeMax
+	// Add an hour in both directions so that traces that straddle two indexes are summarized, as in multiRead.
+	indices := s.timeRangeIndices(s.spanIndexPrefix, s.spanIndexDateLayout, startTime.Add(-time.Hour), endTime.Add(time.Hour), s.spanIndexRolloverFrequency)
+	query := elastic.NewBoolQuery()
+	for _, traceID := range traceIDs {
+		query = query.Should(buildTraceByIDQuery(traceID))
+	}
+	if s.useReadWriteAliases {
+		query = elastic.NewBoolQuery().Must(query, s.buildStartTimeQuery(startTime.Add(-time.Hour*24), endTime.Add(time.Hour*24)))
+	}
+	searchResult, err := s.client.Search(indices...).
+		Size(0).
+		Aggregation(traceIDAggregation, s.buildTraceSummaryAggregation(len(traceIDs))).
+		IgnoreUnavailable(true).
+		Query(query).
+		Do(ctx)
+	if err != nil {
+		logErrorToSpan(span, err)
+		return nil, fmt.Errorf("search trace summaries failed: %w", err)
+	}
+	if searchResult.Aggregations == nil {
+		return []*spanstore.TraceSummary{}, nil
+	}
+	bucket, found := searchResult.Aggregations.Terms(traceIDAggregation)
+	if !found {
+		return nil, ErrUnableToFindTraceIDAggregation
+	}
+	summariesByID := make(map[model.TraceID]*spanstore.TraceSummary, len(traceIDs))
+	for _, traceBucket := range bucket.Buckets {
+		if err := s.addTraceSummaryBucket(summariesByID, traceBucket); err != nil {
+			logErrorToSpan(span, err)
+			return nil, err
+		}
+	}
+	summaries := make([]*spanstore.TraceSummary, 0, len(summariesByID))
+	for _, traceID := range traceIDs {
+		if summary, ok := summariesByID[traceID]; ok {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries, nil
+}
+
+// buildTraceSummaryAggregation aggregates the spans of each trace into the values of a
+// spanstore.TraceSummary. The trace IDs of numOfTraces traces may be found with and
+// without leading zeros, see buildTraceByIDQuery.
+func (s *SpanReader) buildTraceSummaryAggregation(numOfTraces int) elastic.Aggregation {
+	spanSource := elastic.NewFetchSourceContext(true).Include(operationNameField, serviceNameField)
+	firstSpan := elastic.NewTopHitsAggregation().Size(1).Sort(startTimeField, true).FetchSourceContext(spanSource)
+	rootSpans := elastic.NewBoolQuery().MustNot(
+		elastic.NewNestedQuery("references", elastic.NewExistsQuery("references.spanID")))
+	return elastic.NewTermsAggregation().
+		Size(2*numOfTraces).
+		Field(traceIDField).
+		SubAggregation(minStartTimeAggregation, elastic.NewMinAggregation().Field(startTimeField)).
+		SubAggregation(maxEndTimeAggregation, elastic.NewMaxAggregation().
+			Script(elastic.NewScript("doc['startTime'].value + doc['duration'].value"))).
+		SubAggregation(serviceSpanCountsAggregation, elastic.NewTermsAggregation().
+			Size(traceSummaryServicesSize).Field(serviceNameField)).
+		SubAggregation(errorSpansAggregation, elastic.NewFilterAggregation().
+			Filter(s.buildTagQuery(string(ottag.Error), "true"))).
+		SubAggregation(rootSpansAggregation, elastic.NewFilterAggregation().
+			Filter(rootSpans).SubAggregation(firstSpanAggregation, firstSpan)).
+		SubAggregation(firstSpanAggregation, firstSpan)
+}
+
+// addTraceSummaryBucket adds a trace bucket of buildTraceSummaryAggregation to the summaries,
+// merging the buckets of the same trace ID with and without leading zeros.
+func (s *SpanReader) addTraceSummaryBucket(summaries map[model.TraceID]*spanstore.TraceSummary, bucket *elastic.AggregationBucketKeyItem) error {
+	key, ok := bucket.Key.(string)
+	if !ok {
+		return errors.New("non-string key found in aggregation")
+	}
+	traceID, err := model.TraceIDFromString(key)
+	if err != nil {
+		return fmt.Errorf("making traceID from string '%s' failed: %w", key, err)
+	}
+	summary, ok := summaries[traceID]
+	if !ok {
+		summary = &spanstore.TraceSummary{TraceID: traceID, ServiceSpanCounts: make(map[string]int)}
+		summaries[traceID] = summary
+	}
+	var endTime time.Time
+	if !summary.StartTime.IsZero() {
+		endTime = summary.StartTime.Add(summary.Duration)
+	}
+	if minStartTime, ok := bucket.Min(minStartTimeAggregation); ok && minStartTime.Value != nil {
+		if start := model.EpochMicrosecondsAsTime(uint64(*minStartTime.Value)); summary.StartTime.IsZero() || start.Before(summary.StartTime) {
+			summary.StartTime = start
+		}
+	}
+	if maxEndTime, ok := bucket.Max(maxEndTimeAggregation); ok && maxEndTime.Value != nil {
+		if end := model.EpochMicrosecondsAsTime(uint64(*maxEndTime.Value)); end.After(endTime) {
+			endTime = end
+		}
+	}
+	summary.Duration = endTime.Sub(summary.StartTime)
+	summary.SpanCount += int(bucket.DocCount)
+	if services, ok := bucket.Terms(serviceSpanCountsAggregation); ok {
+		for _, service := range services.Buckets {
+			if name, ok := service.Key.(string); ok {
+				summary.ServiceSpanCounts[name] += int(service.DocCount)
+			}
+		}
+	}
+	if errorSpans, ok := bucket.Filter(errorSpansAggregation); ok {
+		summary.ErrorCount += int(errorSpans.DocCount)
+	}
+	// a span without references in any of the buckets of the trace is preferred as root
+	if rootSpans, ok := bucket.Filter(rootSpansAggregation); ok && rootSpans.DocCount > 0 {
+		return s.setTraceSummaryRoot(summary, rootSpans.Aggregations)
+	}
+	if summary.RootOperationName == "" && summary.RootServiceName == "" {
+		return s.setTraceSummaryRoot(summary, bucket.Aggregations)
+	}
+	return nil
+}
+
+func (s *SpanReader) setTraceSummaryRoot(summary *spanstore.TraceSummary, aggregations elastic.Aggregations) error {
+	firstSpan, ok := aggregations.TopHits(firstSpanAggregation)
+	if !ok || firstSpan.Hits == nil || len(firstSpan.Hits.Hits) == 0 {
+		return nil
+	}
+	root, err := s.unmarshalJSONSpan(firstSpan.Hits.Hits[0])
+	if err != nil {
+		return err
+	}
+	summary.RootOperationName = root.OperationName
+	summary.RootServiceName = root.Process.ServiceName
+	return nil
+}
+
+// pageCursor is the page token content, the sort values of the first matching
+// span of the last trace of a page.
+type pageCursor struct {
+	StartTime uint64
+	TraceID   string
+}
+
+// FindTracesPage implements spanstore.PagedReader. Matching spans are read
+// newest first with search_after, and each trace is returned at its newest
+// matching span, which is the order of FindTraces.
+func (s *SpanReader) FindTracesPage(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "FindTracesPage")
+	defer span.Finish()
+
+	if err := validateQuery(traceQuery); err != nil {
+		return nil, err
+	}
+	if traceQuery.NumTraces == 0 {
+		traceQuery.NumTraces = defaultNumTraces
+	}
+	var pageStart *pageCursor
+	if traceQuery.PageToken != "" {
+		pageStart = &pageCursor{}
+		if err := spanstore.DecodePageToken(traceQuery.PageToken, pageStart); err != nil {
+			return nil, err
+		}
+	}
+
+	boolQuery := s.buildFindTraceIDsQuery(traceQuery)
+	jaegerIndices := s.timeRangeIndices(s.spanIndexPrefix, s.spanIndexDateLayout, traceQuery.StartTimeMin, traceQuery.StartTimeMax, s.spanIndexRolloverFrequency)
+	size := traceQuery.NumTraces * tracePageHitsMultiple
+	if s.maxDocCount > 0 && size > s.maxDocCount {
+		size = s.maxDocCount
+	}
+
+	seen := make(map[string]struct{})
+	var traceIDs []string
+	var last *pageCursor
+	after := pageStart
+	hasMore := false
+	for !hasMore {
+		hits, err := s.searchPageHits(ctx, jaegerIndices, s.buildTracePageSearchSource(boolQuery, size, after))
+		if err != nil {
+			return nil, err
+		}
+		var candidates []string
+		cursors := make([]pageCursor, len(hits))
+		for i, hit := range hits {
+			jsonSpan, err := s.unmarshalJSONSpan(hit)
+			if err != nil {
+				return nil, err
+			}
+			cursors[i] = pageCursor{StartTime: jsonSpan.StartTime, TraceID: string(jsonSpan.TraceID)}
+			if _, ok := seen[cursors[i].TraceID]; !ok {
+				seen[cursors[i].TraceID] = struct{}{}
+				candidates = append(candidates, cursors[i].TraceID)
+			}
+		}
+		// Traces whose newest matching span is before the page start were in previous pages
+		previous := make(map[string]struct{})
+		if pageStart != nil && len(candidates) > 0 {
+			if previous, err = s.findPreviousPagesTraceIDs(ctx, jaegerIndices, boolQuery, *pageStart, candidates); err != nil {
+				return nil, err
+			}
+		}
+		candidateSet := make(map[string]struct{}, len(candidates))
+		for _, traceID := range candidates {
+			if _, ok := previous[traceID]; !ok {
+				candidateSet[traceID] = struct{}{}
+			}
+		}
+		for i := range cursors {
+			cursor := cursors[i]
+			if _, ok := candidateSet[cursor.TraceID]; !ok {
+				continue
+			}
+			delete(candidateSet, cursor.TraceID)
+			if len(traceIDs) == traceQuery.NumTraces {
+				hasMore = true
+				break
+			}
+			traceIDs = append(traceIDs, cursor.TraceID)
+			last = &cursor
+		}
+		if len(hits) < size {
+			break
+		}
+		after = &cursors[len(cursors)-1]
+	}
+
+	page := &spanstore.TracePage{}
+	modelTraceIDs, err := convertTraceIDsStringsToModels(traceIDs)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := s.multiRead(ctx, modelTraceIDs, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
+	if err != nil {
+		return nil, err
+	}
+	tracesByID := make(map[model.TraceID]*model.Trace, len(traces))
+	for _, trace := range traces {
+		tracesByID[trace.Spans[0].TraceID] = trace
+	}
+	anySpan := traceQuery.TagMatchMode == spanstore.TagMatchAnySpanInTrace && traceQuery.TagConditionCount() > 1
+	for _, traceID := range modelTraceIDs {
+		trace, ok := tracesByID[traceID]
+		// the query only requires each span to match one of the tags with TagMatchAnySpanInTrace
+		if !ok || (anySpan && !spanstore.MatchTraceTags(trace, traceQuery)) {
+			continue
+		}
+		page.Traces = append(page.Traces, trace)
+	}
+	if hasMore {
+		token, err := spanstore.EncodePageToken(last)
+		if err != nil {
+			return nil, err
+		}
+		page.NextPageToken = token
+	}
+	return page, nil
+}
+
+// buildTracePageSearchSource returns the search for the next size spans matching
+// boolQuery after the cursor, sorted newest first.
+func (s *SpanReader) buildTracePageSearchSource(boolQuery elastic.Query, size int, after *pageCursor) *elastic.SearchSource {
+	source := elastic.NewSearchSource().
+		Query(boolQuery).
+		Size(size).
+		Sort(startTimeField, false).
+		Sort(traceIDField, false).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include(traceIDField, startTimeField))
+	if after != nil {
+		source.SearchAfter(after.StartTime, after.TraceID)
+	}
+	return source
+}
+
+func (s *SpanReader) searchPageHits(ctx context.Context, indices []string, source *elastic.SearchSource) ([]*elastic.SearchHit, error) {
+	searchRequest := elastic.NewSearchRequest().
+		IgnoreUnavailable(true).
+		Source(source)
+	results, err := s.client.MultiSearch().Add(searchRequest).Index(indices...).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("search spans failed: %w", err)
+	}
+	if len(results.Responses) == 0 || results.Responses[0].Hits == nil {
+		return nil, nil
+	}
+	return results.Responses[0].Hits.Hits, nil
+}
+
+// findPreviousPagesTraceIDs returns which of the traceIDs have a span matching
+// boolQuery that sorts before the cursor, i.e. were returned in previous pages.
+func (s *SpanReader) findPreviousPagesTraceIDs(
+	ctx context.Context,
+	indices []string,
+	boolQuery elastic.Query,
+	cursor pageCursor,
+	traceIDs []string,
+) (map[string]struct{}, error) {
+	searchService := s.client.Search(indices...).
+		Size(0).
+		Aggregation(traceIDAggregation, elastic.NewTermsAggregation().Field(traceIDField).Size(len(traceIDs))).
+		IgnoreUnavailable(true).
+		Query(s.buildPreviousPagesQuery(boolQuery, cursor, traceIDs))
+	searchResult, err := searchService.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("search previous pages failed: %w", err)
+	}
+	previous := make(map[string]struct{})
+	if searchResult.Aggregations == nil {
+		return previous, nil
+	}
+	bucket, found := searchResult.Aggregations.Terms(traceIDAggregation)
+	if !found {
+		return nil, ErrUnableToFindTraceIDAggregation
+	}
+	previousTraceIDs, err := bucketToStringArray(bucket.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	for _, traceID := range previousTraceIDs {
+		previous[traceID] = struct{}{}
+	}
+	return previous, nil
+}
+
+func (s *SpanReader) buildPreviousPagesQuery(boolQuery elastic.Query, cursor pageCursor, traceIDs []string) elastic.Query {
+	terms := make([]interface{}, len(traceIDs))
+	for i, traceID := range traceIDs {
+		terms[i] = traceID
+	}
+	beforeCursor := elastic.NewBoolQuery().Should(
+		elastic.NewRangeQuery(startTimeField).Gt(cursor.StartTime),
+		elastic.NewBoolQuery().Must(
+			elastic.NewTermQuery(startTimeField, cursor.StartTime),
+			elastic.NewRangeQuery(traceIDField).Gte(cursor.TraceID),
+		),
+	)
+	return elastic.NewBoolQuery().
+		Must(boolQuery, beforeCursor).
+		Filter(elastic.NewTermsQuery(traceIDField, terms...))
+}
+
 func (s *SpanReader) multiRead(ctx context.Context, traceIDs []model.TraceID, startTime, endTime time.Time) ([]*model.Trace, error) {
 	childSpan, _ := opentracing.StartSpanFromContext(ctx, "multiRead")
 	childSpan.LogFields(otlog.Object("trace_ids", traceIDs))
@@ -559,6 +955,10 @@ func (s *SpanReader) findTraceIDs(ctx context.Context, traceQuery *spanstore.Tra
 	//      "aggs": { "traceIDs" : { "terms" : {"size": 100,"field": "traceID" }}}
 	//  }
 	aggregation := s.buildTraceIDAggregation(traceQuery.NumTraces)
+	anySpan := traceQuery.TagMatchMode == spanstore.TagMatchAnySpanInTrace && traceQuery.TagConditionCount() > 1
+	if anySpan {
+		aggregation = s.buildAnySpanTraceIDAggregation(traceQuery.NumTraces, s.buildTagQueries(traceQuery))
+	}
 	boolQuery := s.buildFindTraceIDsQuery(traceQuery)
 	jaegerIndices := s.timeRangeIndices(s.spanIndexPrefix, s.spanIndexDateLayout, traceQuery.StartTimeMin, traceQuery.StartTimeMax, s.spanIndexRolloverFrequency)
 
@@ -582,6 +982,9 @@ func (s *SpanReader) findTraceIDs(ctx context.Context, traceQuery *spanstore.Tra
 	}
 
 	traceIDBuckets := bucket.Buckets
+	if anySpan && len(traceIDBuckets) > traceQuery.NumTraces {
+		traceIDBuckets = traceIDBuckets[:traceQuery.NumTraces]
+	}
 	return bucketToStringArray(traceIDBuckets)
 }
 
@@ -598,6 +1001,28 @@ func (s *SpanReader) buildTraceIDSubAggregation() elastic.Aggregation {
 		Field(startTimeField)
 }
 
+// buildAnySpanTraceIDAggregation aggregates trace IDs like buildTraceIDAggregation, but
+// since every span is a separate document, it counts the spans matching each tag query
+// per trace and only keeps the traces where each of them matched at least one span.
+func (s *SpanReader) buildAnySpanTraceIDAggregation(numOfTraces int, tagQueries []elastic.Query) elastic.Aggregation {
+	// buckets are removed by the selector after the terms aggregation is truncated
+	aggregation := elastic.NewTermsAggregation().
+		Size(numOfTraces*anySpanTraceIDsMultiple).
+		Field(traceIDField).
+		Order(startTimeField, false).
+		SubAggregation(startTimeField, s.buildTraceIDSubAggregation())
+	selector := elastic.NewBucketSelectorAggregation()
+	conditions := make([]string, len(tagQueries))
+	for i, tagQuery := range tagQueries {
+		name := fmt.Sprintf("tag%d", i)
+		aggregation.SubAggregation(name, elastic.NewFilterAggregation().Filter(tagQuery))
+		selector.AddBucketsPath(name, name+"._count")
+		conditions[i] = fmt.Sprintf("params.%s > 0", name)
+	}
+	selector.Script(elastic.NewScript(strings.Join(conditions, " && ")))
+	return aggregation.SubAggregation(allTagsMatchedAggregation, selector)
+}
+
 func (s *SpanReader) buildFindTraceIDsQuery(traceQuery *spanstore.TraceQueryParameters) elastic.Query {
 	boolQuery := elastic.NewBoolQuery()
 
@@ -623,13 +1048,27 @@ func (s *SpanReader) buildFindTraceIDsQuery(traceQuery *spanstore.TraceQueryPara
 		boolQuery.Must(operationNameQuery)
 	}
 
-	for k, v := range traceQuery.Tags {
-		tagQuery := s.buildTagQuery(k, v)
-		boolQuery.Must(tagQuery)
+	tagQueries := s.buildTagQueries(traceQuery)
+	if traceQuery.TagMatchMode == spanstore.TagMatchAnySpanInTrace && len(tagQueries) > 1 {
+		// each span only needs to match one tag, buildAnySpanTraceIDAggregation checks that the trace matches all of them
+		boolQuery.Must(elastic.NewBoolQuery().Should(tagQueries...))
+	} else {
+		boolQuery.Must(tagQueries...)
 	}
 	return boolQuery
 }
 
+func (s *SpanReader) buildTagQueries(traceQuery *spanstore.TraceQueryParameters) []elastic.Query {
+	tagQueries := make([]elastic.Query, 0, traceQuery.TagConditionCount())
+	for k, v := range traceQuery.Tags {
+		tagQueries = append(tagQueries, s.buildTagQuery(k, v))
+	}
+	for _, tagFilter := range traceQuery.TagFilters {
+		tagQueries = append(tagQueries, s.buildTagFilterQuery(tagFilter))
+	}
+	return tagQueries
+}
+
 func (s *SpanReader) buildDurationQuery(durationMin time.Duration, durationMax time.Duration) elastic.Query {
 	minDurationMicros := model.DurationAsMicroseconds(durationMin)
 	maxDurationMicros := defaultMaxDuration
@@ -686,6 +1125,58 @@ func (s *SpanReader) buildObjectQuery(field string, k string, v string) elastic.
 	return elastic.NewBoolQuery().Must(keyQuery)
 }
 
+// buildTagFilterQuery builds a query for a typed tag filter. Negated operators match
+// spans that have no tag satisfying the positive condition. Tag values are indexed
+// as keywords, so ordering operators are evaluated by a script that parses them as numbers.
+func (s *SpanReader) buildTagFilterQuery(tagFilter *spanstore.Predicate) elastic.Query {
+	var valueQuery func(field string) elastic.Query
+	switch tagFilter.Operator {
+	case spanstore.OpNotEqual, spanstore.OpNotRegex:
+		positive := *tagFilter
+		positive.Operator = spanstore.OpEqual
+		if tagFilter.Operator == spanstore.OpNotRegex {
+			positive.Operator = spanstore.OpRegex
+		}
+		return elastic.NewBoolQuery().MustNot(s.buildTagFilterQuery(&positive))
+	case spanstore.OpRegex:
+		valueQuery = func(field string) elastic.Query {
+			return elastic.NewRegexpQuery(field, tagFilter.Value)
+		}
+	case spanstore.OpPrefix:
+		valueQuery = func(field string) elastic.Query {
+			return elastic.NewPrefixQuery(field, tagFilter.Value)
+		}
+	case spanstore.OpGreater, spanstore.OpGreaterOrEqual, spanstore.OpLess, spanstore.OpLessOrEqual:
+		// NewPredicate guarantees the value is numeric for ordering operators
+		operand, _ := strconv.ParseFloat(tagFilter.Value, 64)
+		valueQuery = func(field string) elastic.Query {
+			script := elastic.NewScript(tagValueCompareScript).Params(map[string]interface{}{
+				"field": field,
+				"op":    tagFilter.Operator.String(),
+				"value": operand,
+			})
+			return elastic.NewScriptQuery(script)
+		}
+	default:
+		valueQuery = func(field string) elastic.Query {
+			return elastic.NewTermQuery(field, tagFilter.Value)
+		}
+	}
+
+	objectTagListLen := len(objectTagFieldList)
+	queries := make([]elastic.Query, len(nestedTagFieldList)+objectTagListLen)
+	kd := s.spanConverter.ReplaceDot(tagFilter.Key)
+	for i, field := range objectTagFieldList {
+		queries[i] = valueQuery(fmt.Sprintf("%s.%s", field, kd))
+	}
+	for i, field := range nestedTagFieldList {
+		keyQuery := elastic.NewMatchQuery(fmt.Sprintf("%s.%s", field, tagKeyField), tagFilter.Key)
+		tagBoolQuery := elastic.NewBoolQuery().Must(keyQuery, valueQuery(fmt.Sprintf("%s.%s", field, tagValueField)))
+		queries[i+objectTagListLen] = elastic.NewNestedQuery(field, tagBoolQuery)
+	}
+	return elastic.NewBoolQuery().Should(queries...)
+}
+
 func logErrorToSpan(span opentracing.Span, err error) {
 	ottag.Error.Set(span, true)
 	span.LogFields(otlog.Error(err))
@@ -318,6 +318,41 @@ func TestSpanReader_GetTrace(t *testing.T) {
 	})
 }
 
+func TestSpanReader_GetTraces(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		hitsOf := func(traceID string) *elastic.SearchHits {
+			span, err := json.Marshal(dbmodel.Span{SpanID: "1", TraceID: dbmodel.TraceID(traceID)})
+			require.NoError(t, err)
+			return &elastic.SearchHits{
+				Hits:      []*elastic.SearchHit{{Source: (*json.RawMessage)(&span)}},
+				TotalHits: 1,
+			}
+		}
+		mockMultiSearchService(r).Return(&elastic.MultiSearchResult{
+			Responses: []*elastic.SearchResult{
+				{Hits: hitsOf("2")},
+				{Hits: hitsOf("1")},
+			},
+		}, nil)
+
+		traceIDs := []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 3), model.NewTraceID(0, 2), model.NewTraceID(0, 1)}
+		traces, err := r.reader.GetTraces(context.Background(), traceIDs)
+		require.NoError(t, err)
+		require.Len(t, traces, 2)
+		assert.Equal(t, model.NewTraceID(0, 1), traces[0].Spans[0].TraceID)
+		assert.Equal(t, model.NewTraceID(0, 2), traces[1].Spans[0].TraceID)
+	})
+}
+
+func TestSpanReader_GetTracesError(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		mockMultiSearchService(r).Return(nil, errors.New("query error"))
+		traces, err := r.reader.GetTraces(context.Background(), []model.TraceID{model.NewTraceID(0, 1)})
+		assert.EqualError(t, err, "query error")
+		assert.Nil(t, traces)
+	})
+}
+
 func TestSpanReader_multiRead_followUp_query(t *testing.T) {
 	withSpanReader(func(r *spanReaderTest) {
 		date := time.Date(2019, 10, 10, 5, 0, 0, 0, time.UTC)
@@ -746,6 +781,87 @@ func TestSpanReader_FindTraces(t *testing.T) {
 	})
 }
 
+func TestSpanReader_FindTraceSummaries(t *testing.T) {
+	idAggregations := make(map[string]*json.RawMessage)
+	idMessage := []byte(`{"buckets": [{"key": "1","doc_count": 4}]}`)
+	idAggregations[traceIDAggregation] = (*json.RawMessage)(&idMessage)
+
+	summaryAggregations := make(map[string]*json.RawMessage)
+	summaryMessage := []byte(`{"buckets": [
+		{
+			"key": "0000000000000001",
+			"doc_count": 3,
+			"minStartTime": {"value": 1000},
+			"maxEndTime": {"value": 6000},
+			"serviceSpanCounts": {"buckets": [{"key": "frontend", "doc_count": 1}, {"key": "backend", "doc_count": 2}]},
+			"errorSpans": {"doc_count": 1},
+			"rootSpans": {"doc_count": 1, "firstSpan": {"hits": {"hits": [
+				{"_source": {"operationName": "GET /", "process": {"serviceName": "frontend"}}}
+			]}}},
+			"firstSpan": {"hits": {"hits": [
+				{"_source": {"operationName": "GET /", "process": {"serviceName": "frontend"}}}
+			]}}
+		},
+		{
+			"key": "1",
+			"doc_count": 1,
+			"minStartTime": {"value": 500},
+			"maxEndTime": {"value": 2000},
+			"serviceSpanCounts": {"buckets": [{"key": "backend", "doc_count": 1}]},
+			"errorSpans": {"doc_count": 0},
+			"rootSpans": {"doc_count": 0},
+			"firstSpan": {"hits": {"hits": [
+				{"_source": {"operationName": "query", "process": {"serviceName": "backend"}}}
+			]}}
+		}
+	]}`)
+	summaryAggregations[traceIDAggregation] = (*json.RawMessage)(&summaryMessage)
+
+	withSpanReader(func(r *spanReaderTest) {
+		searchCall := mockSearchService(r)
+		// find trace IDs
+		searchCall.Return(&elastic.SearchResult{Aggregations: elastic.Aggregations(idAggregations)}, nil).Once()
+		// summarize traces
+		searchCall.Parent.On("Do", mock.Anything).
+			Return(&elastic.SearchResult{Aggregations: elastic.Aggregations(summaryAggregations)}, nil).Once()
+
+		summaries, err := r.reader.FindTraceSummaries(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName:  serviceName,
+			StartTimeMin: time.Now().Add(-1 * time.Hour),
+			StartTimeMax: time.Now(),
+			NumTraces:    1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*spanstore.TraceSummary{{
+			TraceID:           model.NewTraceID(0, 1),
+			RootServiceName:   "frontend",
+			RootOperationName: "GET /",
+			StartTime:         model.EpochMicrosecondsAsTime(500),
+			Duration:          5500 * time.Microsecond,
+			SpanCount:         4,
+			ServiceSpanCounts: map[string]int{"frontend": 1, "backend": 3},
+			ErrorCount:        1,
+		}}, summaries)
+	})
+}
+
+func TestSpanReader_BuildTraceSummaryAggregation(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		source, err := r.reader.buildTraceSummaryAggregation(2).Source()
+		require.NoError(t, err)
+		terms := source.(map[string]interface{})["terms"].(map[string]interface{})
+		assert.Equal(t, traceIDField, terms["field"])
+		assert.Equal(t, 4, terms["size"])
+		aggs := source.(map[string]interface{})["aggregations"].(map[string]interface{})
+		for _, name := range []string{
+			minStartTimeAggregation, maxEndTimeAggregation, serviceSpanCountsAggregation,
+			errorSpansAggregation, rootSpansAggregation, firstSpanAggregation,
+		} {
+			assert.Contains(t, aggs, name)
+		}
+	})
+}
+
 func TestSpanReader_FindTracesInvalidQuery(t *testing.T) {
 	goodAggregations := make(map[string]*json.RawMessage)
 	rawMessage := []byte(`{"buckets": [{"key": "1","doc_count": 16},{"key": "2","doc_count": 16},{"key": "3","doc_count": 16}]}`)
@@ -1077,6 +1193,166 @@ func TestSpanReader_buildFindTraceIDsQuery(t *testing.T) {
 	})
 }
 
+func TestSpanReader_buildFindTraceIDsQueryAnySpanInTrace(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		tagFilter, err := spanstore.NewTagFilter("http.status_code", spanstore.OpGreaterOrEqual, "500")
+		require.NoError(t, err)
+		traceQuery := &spanstore.TraceQueryParameters{
+			StartTimeMin: time.Time{},
+			StartTimeMax: time.Time{}.Add(time.Second),
+			ServiceName:  "s",
+			Tags: map[string]string{
+				"hello": "world",
+			},
+			TagFilters:   []*spanstore.Predicate{tagFilter},
+			TagMatchMode: spanstore.TagMatchAnySpanInTrace,
+		}
+
+		actual, err := r.reader.buildFindTraceIDsQuery(traceQuery).Source()
+		require.NoError(t, err)
+		expected, err := elastic.NewBoolQuery().
+			Must(
+				r.reader.buildStartTimeQuery(time.Time{}, time.Time{}.Add(time.Second)),
+				r.reader.buildServiceNameQuery("s"),
+				elastic.NewBoolQuery().Should(
+					r.reader.buildTagQuery("hello", "world"),
+					r.reader.buildTagFilterQuery(tagFilter),
+				),
+			).Source()
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestSpanReader_buildAnySpanTraceIDAggregation(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		tagQueries := []elastic.Query{
+			r.reader.buildTagQuery("a", "1"),
+			r.reader.buildTagQuery("b", "2"),
+		}
+		actual, err := r.reader.buildAnySpanTraceIDAggregation(10, tagQueries).Source()
+		require.NoError(t, err)
+
+		expected, err := elastic.NewTermsAggregation().
+			Size(10*anySpanTraceIDsMultiple).
+			Field(traceIDField).
+			Order(startTimeField, false).
+			SubAggregation(startTimeField, r.reader.buildTraceIDSubAggregation()).
+			SubAggregation("tag0", elastic.NewFilterAggregation().Filter(tagQueries[0])).
+			SubAggregation("tag1", elastic.NewFilterAggregation().Filter(tagQueries[1])).
+			SubAggregation(allTagsMatchedAggregation, elastic.NewBucketSelectorAggregation().
+				BucketsPathsMap(map[string]string{"tag0": "tag0._count", "tag1": "tag1._count"}).
+				Script(elastic.NewScript("params.tag0 > 0 && params.tag1 > 0"))).
+			Source()
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestSpanReader_buildTracePageSearchSource(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		boolQuery := elastic.NewBoolQuery().Must(r.reader.buildServiceNameQuery("svc"))
+		actual, err := r.reader.buildTracePageSearchSource(boolQuery, 30, &pageCursor{StartTime: 100, TraceID: "abc"}).Source()
+		require.NoError(t, err)
+
+		expected, err := elastic.NewSearchSource().
+			Query(boolQuery).
+			Size(30).
+			Sort(startTimeField, false).
+			Sort(traceIDField, false).
+			FetchSourceContext(elastic.NewFetchSourceContext(true).Include(traceIDField, startTimeField)).
+			SearchAfter(uint64(100), "abc").
+			Source()
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		actual, err = r.reader.buildTracePageSearchSource(boolQuery, 30, nil).Source()
+		require.NoError(t, err)
+		assert.NotContains(t, actual, "search_after")
+	})
+}
+
+func TestSpanReader_buildPreviousPagesQuery(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		boolQuery := elastic.NewBoolQuery().Must(r.reader.buildServiceNameQuery("svc"))
+		actual, err := r.reader.buildPreviousPagesQuery(boolQuery, pageCursor{StartTime: 100, TraceID: "abc"}, []string{"1", "2"}).Source()
+		require.NoError(t, err)
+
+		expected, err := elastic.NewBoolQuery().
+			Must(boolQuery, elastic.NewBoolQuery().Should(
+				elastic.NewRangeQuery(startTimeField).Gt(uint64(100)),
+				elastic.NewBoolQuery().Must(
+					elastic.NewTermQuery(startTimeField, uint64(100)),
+					elastic.NewRangeQuery(traceIDField).Gte("abc"),
+				),
+			)).
+			Filter(elastic.NewTermsQuery(traceIDField, "1", "2")).
+			Source()
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestSpanReader_FindTracesPage(t *testing.T) {
+	hits := []*elastic.SearchHit{{Source: (*json.RawMessage)(&exampleESSpan)}}
+	startTime := time.Date(2023, time.January, 10, 10, 0, 0, 0, time.UTC)
+	newQuery := func() *spanstore.TraceQueryParameters {
+		return &spanstore.TraceQueryParameters{
+			ServiceName:  serviceName,
+			StartTimeMin: startTime,
+			StartTimeMax: startTime.Add(time.Hour),
+			NumTraces:    1,
+		}
+	}
+	mockMultiSearch := func(r *spanReaderTest) {
+		multiSearchService := &mocks.MultiSearchService{}
+		multiSearchService.On("Add", mock.Anything).Return(multiSearchService)
+		multiSearchService.On("Index", mock.AnythingOfType("string")).Return(multiSearchService)
+		multiSearchService.On("Do", mock.Anything).Return(&elastic.MultiSearchResult{
+			Responses: []*elastic.SearchResult{{Hits: &elastic.SearchHits{Hits: hits}}},
+		}, nil)
+		r.client.On("MultiSearch").Return(multiSearchService)
+	}
+
+	withSpanReader(func(r *spanReaderTest) {
+		mockMultiSearch(r)
+
+		page, err := r.reader.FindTracesPage(context.Background(), newQuery())
+		require.NoError(t, err)
+		require.Len(t, page.Traces, 1)
+		assert.Equal(t, model.NewTraceID(0, 1), page.Traces[0].Spans[0].TraceID)
+		assert.Empty(t, page.NextPageToken)
+	})
+
+	withSpanReader(func(r *spanReaderTest) {
+		mockMultiSearch(r)
+		// the trace had a span before the page start
+		rawMessage := []byte(`{"buckets": [{"key": "1","doc_count": 1}]}`)
+		aggregations := map[string]*json.RawMessage{traceIDAggregation: (*json.RawMessage)(&rawMessage)}
+		searchService := &mocks.SearchService{}
+		searchService.On("Query", mock.Anything).Return(searchService)
+		searchService.On("IgnoreUnavailable", true).Return(searchService)
+		searchService.On("Size", 0).Return(searchService)
+		searchService.On("Aggregation", traceIDAggregation, mock.AnythingOfType("*elastic.TermsAggregation")).Return(searchService)
+		searchService.On("Do", mock.Anything).Return(&elastic.SearchResult{Aggregations: elastic.Aggregations(aggregations)}, nil)
+		r.client.On("Search", mock.AnythingOfType("string")).Return(searchService)
+
+		query := newQuery()
+		query.PageToken, _ = spanstore.EncodePageToken(pageCursor{StartTime: 812965626, TraceID: "2"})
+		page, err := r.reader.FindTracesPage(context.Background(), query)
+		require.NoError(t, err)
+		assert.Empty(t, page.Traces)
+		assert.Empty(t, page.NextPageToken)
+	})
+
+	withSpanReader(func(r *spanReaderTest) {
+		query := newQuery()
+		query.PageToken = "invalid"
+		_, err := r.reader.FindTracesPage(context.Background(), query)
+		assert.ErrorIs(t, err, spanstore.ErrInvalidPageToken)
+	})
+}
+
 func TestSpanReader_buildDurationQuery(t *testing.T) {
 	expectedStr := `{ "range":
 			{ "duration": { "include_lower": true,
@@ -1200,6 +1476,53 @@ func TestSpanReader_buildTagRegexEscapedQuery(t *testing.T) {
 	})
 }
 
+func TestSpanReader_buildTagFilterQuery(t *testing.T) {
+	tests := []struct {
+		filter   string
+		contains []string
+	}{
+		{
+			filter:   "bat.foo=spook",
+			contains: []string{`{"term":{"tag.bat@foo":"spook"}}`, `{"term":{"tags.value":"spook"}}`, `"path":"logs.fields"`},
+		},
+		{
+			filter:   "bat.foo!=spook",
+			contains: []string{`"must_not":{"bool":{"should":[`, `{"term":{"process.tags.value":"spook"}}`},
+		},
+		{
+			filter:   `bat.foo~"spo.*"`,
+			contains: []string{`{"regexp":{"tag.bat@foo":{"value":"spo.*"}}}`},
+		},
+		{
+			filter:   `bat.foo!~"spo.*"`,
+			contains: []string{`"must_not":`, `{"regexp":{"tags.value":{"value":"spo.*"}}}`},
+		},
+		{
+			filter:   "bat.foo^=sp",
+			contains: []string{`{"prefix":{"process.tag.bat@foo":"sp"}}`},
+		},
+		{
+			filter:   "bat.foo>=500",
+			contains: []string{`"params":{"field":"tags.value","op":"\u003e=","value":500}`, `"params":{"field":"tag.bat@foo"`},
+		},
+	}
+	withSpanReader(func(r *spanReaderTest) {
+		for _, test := range tests {
+			t.Run(test.filter, func(t *testing.T) {
+				tagFilter, err := spanstore.ParseTagFilter(test.filter)
+				require.NoError(t, err)
+				source, err := r.reader.buildTagFilterQuery(tagFilter).Source()
+				require.NoError(t, err)
+				actual, err := json.Marshal(source)
+				require.NoError(t, err)
+				for _, s := range test.contains {
+					assert.Contains(t, string(actual), s)
+				}
+			})
+		}
+	})
+}
+
 func TestSpanReader_GetEmptyIndex(t *testing.T) {
 	withSpanReader(func(r *spanReaderTest) {
 		mockSearchService(r).
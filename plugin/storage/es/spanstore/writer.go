@@ -17,9 +17,14 @@ package spanstore
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/olivere/elastic"
 	"go.uber.org/zap"
 
 	"github.com/kjschnei001/jaeger/model"
@@ -177,3 +182,39 @@ func (s *SpanWriter) writeService(indexName string, jsonSpan *dbmodel.Span) {
 func (s *SpanWriter) writeSpan(indexName string, jsonSpan *dbmodel.Span) {
 	s.client.Index().Index(indexName).Type(spanType).BodyJson(&jsonSpan).Add()
 }
+
+// IsRetryableError tells whether an Elasticsearch error is transient, such as a rejection when the
+// cluster is overloaded (429) or a server error, rather than a client error such as a mapping
+// conflict (400). Note that the SpanWriter indexes the spans with a bulk processor, whose
+// failures are reported asynchronously, see SpanFromBulkRequest, rather than returned by WriteSpan.
+func IsRetryableError(err error) bool {
+	var esErr *elastic.Error
+	if errors.As(err, &esErr) {
+		return esErr.Status == http.StatusTooManyRequests || esErr.Status >= http.StatusInternalServerError
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// SpanFromBulkRequest returns the span indexed by a bulk request of the SpanWriter, so that it
+// can be written again when the request failed. It returns nil for the other requests, e.g. the
+// requests indexing the services.
+func SpanFromBulkRequest(request elastic.BulkableRequest, tagDotReplacement string) (*model.Span, error) {
+	lines, err := request.Source()
+	if err != nil {
+		return nil, err
+	}
+	// the index requests consist of the action line and the document line
+	if len(lines) != 2 {
+		return nil, nil
+	}
+	var jsonSpan dbmodel.Span
+	d := json.NewDecoder(strings.NewReader(lines[1]))
+	d.UseNumber()
+	if err := d.Decode(&jsonSpan); err != nil {
+		return nil, fmt.Errorf("cannot decode the document of the bulk request: %w", err)
+	}
+	if jsonSpan.TraceID == "" || jsonSpan.SpanID == "" {
+		return nil, nil
+	}
+	return dbmodel.NewToDomain(tagDotReplacement).SpanToDomain(&jsonSpan)
+}
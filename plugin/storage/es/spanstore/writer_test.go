@@ -18,10 +18,12 @@ package spanstore
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/olivere/elastic"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -492,3 +494,50 @@ func stringMatcher(q string) interface{} {
 	}
 	return mock.MatchedBy(matchFunc)
 }
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{err: &elastic.Error{Status: 429}, retryable: true},
+		{err: fmt.Errorf("index failed: %w", &elastic.Error{Status: 503}), retryable: true},
+		{err: &elastic.Error{Status: 400}, retryable: false},
+		{err: errors.New("connection refused"), retryable: true},
+		{err: context.Canceled, retryable: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.retryable, IsRetryableError(test.err), test.err.Error())
+	}
+}
+
+func TestSpanFromBulkRequest(t *testing.T) {
+	span := &model.Span{
+		TraceID:       model.NewTraceID(0, 1),
+		SpanID:        model.NewSpanID(2),
+		OperationName: "op",
+		StartTime:     time.Unix(1, 0).UTC(),
+		Duration:      time.Second,
+		Tags:          []model.KeyValue{model.String("http.method", "GET"), model.Int64("retries", 2)},
+		Process:       &model.Process{ServiceName: "svc", Tags: []model.KeyValue{}},
+		References:    []model.SpanRef{},
+		Logs:          []model.Log{},
+	}
+	jsonSpan := dbmodel.NewFromDomain(true, nil, "@").FromDomainEmbedProcess(span)
+	request := elastic.NewBulkIndexRequest().Index("jaeger-span-2023-01-01").Type(spanType).Doc(jsonSpan)
+	decoded, err := SpanFromBulkRequest(request, "@")
+	require.NoError(t, err)
+	assert.Equal(t, span, decoded)
+
+	service := dbmodel.Service{ServiceName: "svc", OperationName: "op"}
+	decoded, err = SpanFromBulkRequest(elastic.NewBulkIndexRequest().Index("jaeger-service-2023-01-01").Type(serviceType).Doc(service), "@")
+	require.NoError(t, err)
+	assert.Nil(t, decoded)
+
+	decoded, err = SpanFromBulkRequest(elastic.NewBulkDeleteRequest().Index("jaeger-span-2023-01-01").Id("1"), "@")
+	require.NoError(t, err)
+	assert.Nil(t, decoded)
+
+	_, err = SpanFromBulkRequest(elastic.NewBulkIndexRequest().Index("jaeger-span-2023-01-01").Doc(`"span"`), "@")
+	assert.ErrorContains(t, err, "cannot decode the document of the bulk request")
+}
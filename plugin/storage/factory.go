@@ -20,6 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -28,10 +29,14 @@ import (
 	"github.com/kjschnei001/jaeger/plugin"
 	"github.com/kjschnei001/jaeger/plugin/storage/badger"
 	"github.com/kjschnei001/jaeger/plugin/storage/cassandra"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse"
 	"github.com/kjschnei001/jaeger/plugin/storage/es"
+	"github.com/kjschnei001/jaeger/plugin/storage/federated"
 	"github.com/kjschnei001/jaeger/plugin/storage/grpc"
 	"github.com/kjschnei001/jaeger/plugin/storage/kafka"
 	"github.com/kjschnei001/jaeger/plugin/storage/memory"
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet"
+	"github.com/kjschnei001/jaeger/plugin/storage/tiered"
 	"github.com/kjschnei001/jaeger/storage"
 	"github.com/kjschnei001/jaeger/storage/dependencystore"
 	"github.com/kjschnei001/jaeger/storage/spanstore"
@@ -45,6 +50,8 @@ const (
 	kafkaStorageType         = "kafka"
 	grpcPluginStorageType    = "grpc-plugin"
 	badgerStorageType        = "badger"
+	clickhouseStorageType    = "clickhouse"
+	parquetStorageType       = "parquet"
 
 	downsamplingRatio    = "downsampling.ratio"
 	downsamplingHashSalt = "downsampling.hashsalt"
@@ -65,6 +72,8 @@ var AllStorageTypes = []string{
 	kafkaStorageType,
 	badgerStorageType,
 	grpcPluginStorageType,
+	clickhouseStorageType,
+	parquetStorageType,
 }
 
 // AllSamplingStorageTypes returns all storage backends that implement adaptive sampling
@@ -81,16 +90,20 @@ func AllSamplingStorageTypes() []string {
 }
 
 var (
-	_ io.Closer           = (*Factory)(nil)
-	_ plugin.Configurable = (*Factory)(nil)
+	_ io.Closer                   = (*Factory)(nil)
+	_ plugin.Configurable         = (*Factory)(nil)
+	_ storage.AdminHandlerFactory = (*Factory)(nil)
 )
 
 // Factory implements storage.Factory interface as a meta-factory for storage components.
 type Factory struct {
 	FactoryConfig
 	metricsFactory         metrics.Factory
+	logger                 *zap.Logger
 	factories              map[string]storage.Factory
 	downsamplingFlagsAdded bool
+	retryFlagsAdded        bool
+	deadLetterWriter       spanstore.Writer
 }
 
 // NewFactory creates the meta-factory.
@@ -103,6 +116,9 @@ func NewFactory(config FactoryConfig) (*Factory, error) {
 	for _, storageType := range f.SpanWriterTypes {
 		uniqueTypes[storageType] = struct{}{}
 	}
+	for _, storageType := range f.SpanReaderTypes {
+		uniqueTypes[storageType] = struct{}{}
+	}
 	// skip SamplingStorageType if it is empty. See CreateSamplingStoreFactory for details
 	if f.SamplingStorageType != "" {
 		uniqueTypes[f.SamplingStorageType] = struct{}{}
@@ -115,6 +131,24 @@ func NewFactory(config FactoryConfig) (*Factory, error) {
 		}
 		f.factories[t] = ff
 	}
+	if f.ColdSpanStorageType != "" {
+		if _, ok := uniqueTypes[f.ColdSpanStorageType]; ok {
+			return nil, fmt.Errorf("cold span storage type %s cannot be used by other storages", f.ColdSpanStorageType)
+		}
+		// the spans are copied by the span writer of the hot storage
+		hotWritten := false
+		for _, storageType := range f.SpanWriterTypes {
+			hotWritten = hotWritten || storageType == f.SpanReaderType
+		}
+		if !hotWritten {
+			return nil, fmt.Errorf("cold span storage requires the span reader type %s to be a span storage type, got %v", f.SpanReaderType, f.SpanWriterTypes)
+		}
+		cold, err := f.getFactoryOfType(f.ColdSpanStorageType)
+		if err != nil {
+			return nil, err
+		}
+		f.factories[f.SpanReaderType] = tiered.NewFactory(f.factories[f.SpanReaderType], cold)
+	}
 	return f, nil
 }
 
@@ -132,6 +166,10 @@ func (f *Factory) getFactoryOfType(factoryType string) (storage.Factory, error)
 		return badger.NewFactory(), nil
 	case grpcPluginStorageType:
 		return grpc.NewFactory(), nil
+	case clickhouseStorageType:
+		return clickhouse.NewFactory(), nil
+	case parquetStorageType:
+		return parquet.NewFactory(), nil
 	default:
 		return nil, fmt.Errorf("unknown storage type %s. Valid types are %v", factoryType, AllStorageTypes)
 	}
@@ -139,22 +177,47 @@ func (f *Factory) getFactoryOfType(factoryType string) (storage.Factory, error)
 
 // Initialize implements storage.Factory.
 func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
-	f.metricsFactory = metricsFactory
+	f.metricsFactory, f.logger = metricsFactory, logger
 	for _, factory := range f.factories {
 		if err := factory.Initialize(metricsFactory, logger); err != nil {
 			return err
 		}
 	}
+	deadLetterWriter, err := f.createDeadLetterWriter(metricsFactory, logger)
+	if err != nil {
+		return err
+	}
+	f.deadLetterWriter = deadLetterWriter
 	f.publishOpts()
 
 	return nil
 }
 
-// CreateSpanReader implements storage.Factory.
+// CreateSpanReader implements storage.Factory. The reader of several span reader
+// types queries all of them, see federated.Reader.
 func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
-	factory, ok := f.factories[f.SpanReaderType]
+	if len(f.SpanReaderTypes) <= 1 {
+		return f.createSpanReader(f.SpanReaderType)
+	}
+	backends := make([]federated.Backend, 0, len(f.SpanReaderTypes))
+	for _, storageType := range f.SpanReaderTypes {
+		reader, err := f.createSpanReader(storageType)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, federated.Backend{Name: storageType, Reader: reader})
+	}
+	return federated.NewReader(
+		backends,
+		f.metricsFactory.Namespace(metrics.NSOptions{Name: "federated_reader"}),
+		f.logger,
+	), nil
+}
+
+func (f *Factory) createSpanReader(storageType string) (spanstore.Reader, error) {
+	factory, ok := f.factories[storageType]
 	if !ok {
-		return nil, fmt.Errorf("no %s backend registered for span store", f.SpanReaderType)
+		return nil, fmt.Errorf("no %s backend registered for span store", storageType)
 	}
 	return factory.CreateSpanReader()
 }
@@ -171,6 +234,15 @@ func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
 		if err != nil {
 			return nil, err
 		}
+		// each writer is retried on its own, so that a failure of one backend
+		// does not write the span again to the backends that succeeded
+		if f.SpanWriterRetry.enabled() {
+			retryingWriter := f.newRetryingWriter(storageType, writer)
+			if reporter, ok := factory.(failedSpanReporter); ok {
+				reporter.SetFailedSpanHandler(retryingWriter.RetryFailedSpan)
+			}
+			writer = retryingWriter
+		}
 		writers = append(writers, writer)
 	}
 	var spanWriter spanstore.Writer
@@ -199,7 +271,7 @@ func (f *Factory) CreateSamplingStoreFactory() (storage.SamplingStoreFactory, er
 		if !ok {
 			return nil, fmt.Errorf("no %s backend registered for sampling store", f.SamplingStorageType)
 		}
-		ss, ok := factory.(storage.SamplingStoreFactory)
+		ss, ok := samplingStoreFactory(factory)
 		if !ok {
 			return nil, fmt.Errorf("storage factory of type %s does not support sampling store", f.SamplingStorageType)
 		}
@@ -207,7 +279,7 @@ func (f *Factory) CreateSamplingStoreFactory() (storage.SamplingStoreFactory, er
 	}
 
 	for _, factory := range f.factories {
-		ss, ok := factory.(storage.SamplingStoreFactory)
+		ss, ok := samplingStoreFactory(factory)
 		if ok {
 			return ss, nil
 		}
@@ -218,6 +290,16 @@ func (f *Factory) CreateSamplingStoreFactory() (storage.SamplingStoreFactory, er
 	return nil, nil
 }
 
+// samplingStoreFactory returns the factory as a storage.SamplingStoreFactory, the
+// sampling data of a tiered storage being kept in its hot storage.
+func samplingStoreFactory(factory storage.Factory) (storage.SamplingStoreFactory, bool) {
+	if tieredFactory, ok := factory.(*tiered.Factory); ok {
+		factory = tieredFactory.Hot()
+	}
+	ss, ok := factory.(storage.SamplingStoreFactory)
+	return ss, ok
+}
+
 // CreateDependencyReader implements storage.Factory
 func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
 	factory, ok := f.factories[f.DependenciesStorageType]
@@ -227,6 +309,19 @@ func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
 	return factory.CreateDependencyReader()
 }
 
+// CreateDependencyWriter implements storage.DependencyWriterFactory
+func (f *Factory) CreateDependencyWriter() (dependencystore.Writer, error) {
+	factory, ok := f.factories[f.DependenciesStorageType]
+	if !ok {
+		return nil, fmt.Errorf("no %s backend registered for span store", f.DependenciesStorageType)
+	}
+	writerFactory, ok := factory.(storage.DependencyWriterFactory)
+	if !ok {
+		return nil, storage.ErrDependencyWriterNotSupported
+	}
+	return writerFactory.CreateDependencyWriter()
+}
+
 // AddFlags implements plugin.Configurable
 func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
 	for _, factory := range f.factories {
@@ -237,11 +332,12 @@ func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
 }
 
 // AddPipelineFlags adds all the standard flags as well as the downsampling
-// flags. This is intended to be used in Jaeger pipeline services such as
-// the collector or ingester.
+// and span write retry flags. This is intended to be used in Jaeger pipeline
+// services such as the collector or ingester.
 func (f *Factory) AddPipelineFlags(flagSet *flag.FlagSet) {
 	f.AddFlags(flagSet)
 	f.addDownsamplingFlags(flagSet)
+	f.addRetryFlags(flagSet)
 }
 
 // addDownsamplingFlags add flags for Downsampling params
@@ -267,6 +363,7 @@ func (f *Factory) InitFromViper(v *viper.Viper, logger *zap.Logger) {
 		}
 	}
 	f.initDownsamplingFromViper(v)
+	f.initRetryFromViper(v)
 }
 
 func (f *Factory) initDownsamplingFromViper(v *viper.Viper) {
@@ -312,12 +409,36 @@ func (f *Factory) CreateArchiveSpanWriter() (spanstore.Writer, error) {
 	return archive.CreateArchiveSpanWriter()
 }
 
+// AdminHandlers implements storage.AdminHandlerFactory, and returns the admin handlers of all the storages.
+func (f *Factory) AdminHandlers() map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	for _, factory := range f.factories {
+		if handlerFactory, ok := factory.(storage.AdminHandlerFactory); ok {
+			for path, handler := range handlerFactory.AdminHandlers() {
+				handlers[path] = handler
+			}
+		}
+	}
+	return handlers
+}
+
 var _ io.Closer = (*Factory)(nil)
 
 // Close closes the resources held by the factory
 func (f *Factory) Close() error {
 	var errs []error
-	for _, storageType := range f.SpanWriterTypes {
+	if closer, ok := f.deadLetterWriter.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	storageTypes := append(append([]string(nil), f.SpanWriterTypes...), f.SpanReaderTypes...)
+	closed := make(map[string]struct{}, len(storageTypes))
+	for _, storageType := range storageTypes {
+		if _, ok := closed[storageType]; ok {
+			continue
+		}
+		closed[storageType] = struct{}{}
 		if factory, ok := f.factories[storageType]; ok {
 			if closer, ok := factory.(io.Closer); ok {
 				err := closer.Close()
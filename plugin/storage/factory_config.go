@@ -32,6 +32,16 @@ const (
 	// SamplingStorageTypeEnvVar is the name of the env var that defines the type of backend used for sampling data storage when using adaptive sampling.
 	SamplingStorageTypeEnvVar = "SAMPLING_STORAGE_TYPE"
 
+	// SpanReaderTypeEnvVar is the name of the env var that defines the types of backends searched for spans,
+	// the span storage types are written to but only the first one is searched by default.
+	SpanReaderTypeEnvVar = "SPAN_READER_TYPE"
+
+	// ColdSpanStorageTypeEnvVar is the name of the env var that defines the type of backend the spans are
+	// copied to once they are older than the demote age, turning the span storage into a tiered storage.
+	// The first span reader type is the hot storage, it must also be a span storage type since the
+	// spans are copied by the span writer.
+	ColdSpanStorageTypeEnvVar = "COLD_SPAN_STORAGE_TYPE"
+
 	spanStorageFlag = "--span-storage.type"
 )
 
@@ -39,10 +49,13 @@ const (
 type FactoryConfig struct {
 	SpanWriterTypes         []string
 	SpanReaderType          string
+	SpanReaderTypes         []string
 	SamplingStorageType     string
 	DependenciesStorageType string
+	ColdSpanStorageType     string
 	DownsamplingRatio       float64
 	DownsamplingHashSalt    string
+	SpanWriterRetry         SpanWriterRetryConfig
 }
 
 // FactoryConfigFromEnvAndCLI reads the desired types of storage backends from SPAN_STORAGE_TYPE and
@@ -52,8 +65,17 @@ type FactoryConfig struct {
 // * `elasticsearch` - built-in
 // * `memory` - built-in
 // * `kafka` - built-in
+// * `clickhouse` - built-in
+// * `parquet` - built-in
 // * `plugin` - loads a dynamic plugin that implements storage.Factory interface (not supported at the moment)
 //
+// SPAN_READER_TYPE optionally defines the backends searched for spans, as a comma-separated list of
+// the types above. The spans of all of them are merged, see the federated package. It defaults to
+// the first span storage type.
+//
+// COLD_SPAN_STORAGE_TYPE optionally defines another backend, of one of the types above, where the spans
+// of the first span storage type are copied to when they get old, see the tiered package.
+//
 // For backwards compatibility it also parses the args looking for deprecated --span-storage.type flag.
 // If found, it writes a deprecation warning to the log.
 func FactoryConfigFromEnvAndCLI(args []string, log io.Writer) FactoryConfig {
@@ -69,21 +91,27 @@ func FactoryConfigFromEnvAndCLI(args []string, log io.Writer) FactoryConfig {
 	if len(spanWriterTypes) > 1 {
 		fmt.Fprintf(log,
 			"WARNING: multiple span storage types have been specified. "+
-				"Only the first type (%s) will be used for reading and archiving.\n\n",
+				"Only the first type (%s) will be used for archiving, and for reading unless %s is set.\n\n",
 			spanWriterTypes[0],
+			SpanReaderTypeEnvVar,
 		)
 	}
+	spanReaderTypes := []string{spanWriterTypes[0]}
+	if spanReaderType := os.Getenv(SpanReaderTypeEnvVar); spanReaderType != "" {
+		spanReaderTypes = strings.Split(spanReaderType, ",")
+	}
 	depStorageType := os.Getenv(DependencyStorageTypeEnvVar)
 	if depStorageType == "" {
 		depStorageType = spanWriterTypes[0]
 	}
 	samplingStorageType := os.Getenv(SamplingStorageTypeEnvVar)
-	// TODO support explicit configuration for readers
 	return FactoryConfig{
 		SpanWriterTypes:         spanWriterTypes,
-		SpanReaderType:          spanWriterTypes[0],
+		SpanReaderType:          spanReaderTypes[0],
+		SpanReaderTypes:         spanReaderTypes,
 		DependenciesStorageType: depStorageType,
 		SamplingStorageType:     samplingStorageType,
+		ColdSpanStorageType:     os.Getenv(ColdSpanStorageTypeEnvVar),
 	}
 }
 
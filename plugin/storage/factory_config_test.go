@@ -40,6 +40,13 @@ func TestFactoryConfigFromEnv(t *testing.T) {
 	assert.Equal(t, elasticsearchStorageType, f.SpanReaderType)
 	assert.Equal(t, memoryStorageType, f.DependenciesStorageType)
 	assert.Equal(t, cassandraStorageType, f.SamplingStorageType)
+	assert.Empty(t, f.ColdSpanStorageType)
+
+	t.Setenv(ColdSpanStorageTypeEnvVar, badgerStorageType)
+
+	f = FactoryConfigFromEnvAndCLI(nil, &bytes.Buffer{})
+	assert.Equal(t, elasticsearchStorageType, f.SpanReaderType)
+	assert.Equal(t, badgerStorageType, f.ColdSpanStorageType)
 
 	t.Setenv(SpanStorageTypeEnvVar, elasticsearchStorageType+","+kafkaStorageType)
 
@@ -47,7 +54,16 @@ func TestFactoryConfigFromEnv(t *testing.T) {
 	assert.Equal(t, 2, len(f.SpanWriterTypes))
 	assert.Equal(t, []string{elasticsearchStorageType, kafkaStorageType}, f.SpanWriterTypes)
 	assert.Equal(t, elasticsearchStorageType, f.SpanReaderType)
+	assert.Equal(t, []string{elasticsearchStorageType}, f.SpanReaderTypes)
+
+	t.Setenv(SpanReaderTypeEnvVar, cassandraStorageType+","+elasticsearchStorageType)
+
+	f = FactoryConfigFromEnvAndCLI(nil, &bytes.Buffer{})
+	assert.Equal(t, []string{elasticsearchStorageType, kafkaStorageType}, f.SpanWriterTypes)
+	assert.Equal(t, cassandraStorageType, f.SpanReaderType)
+	assert.Equal(t, []string{cassandraStorageType, elasticsearchStorageType}, f.SpanReaderTypes)
 
+	t.Setenv(SpanReaderTypeEnvVar, "")
 	t.Setenv(SpanStorageTypeEnvVar, badgerStorageType)
 
 	f = FactoryConfigFromEnvAndCLI(nil, nil)
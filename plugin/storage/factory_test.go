@@ -16,6 +16,7 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -32,8 +33,13 @@ import (
 
 	"github.com/kjschnei001/jaeger/internal/metrics/fork"
 	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/config"
 	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/badger"
+	"github.com/kjschnei001/jaeger/plugin/storage/federated"
+	"github.com/kjschnei001/jaeger/plugin/storage/memory"
+	"github.com/kjschnei001/jaeger/plugin/storage/tiered"
 	"github.com/kjschnei001/jaeger/storage"
 	"github.com/kjschnei001/jaeger/storage/dependencystore"
 	depStoreMocks "github.com/kjschnei001/jaeger/storage/dependencystore/mocks"
@@ -43,8 +49,10 @@ import (
 )
 
 var (
-	_ storage.Factory        = new(Factory)
-	_ storage.ArchiveFactory = new(Factory)
+	_ storage.Factory                 = new(Factory)
+	_ storage.ArchiveFactory          = new(Factory)
+	_ storage.DependencyWriterFactory = new(Factory)
+	_ storage.AdminHandlerFactory     = new(Factory)
 )
 
 func defaultCfg() FactoryConfig {
@@ -89,6 +97,101 @@ func TestNewFactory(t *testing.T) {
 	assert.NoError(t, f.Close())
 }
 
+func TestNewFactoryTiered(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.ColdSpanStorageType = memoryStorageType
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	require.IsType(t, &tiered.Factory{}, f.factories[cassandraStorageType])
+	assert.NotContains(t, f.factories, memoryStorageType)
+
+	// the sampling data is kept in the hot storage
+	ssFactory, err := f.CreateSamplingStoreFactory()
+	require.NoError(t, err)
+	assert.Equal(t, f.factories[cassandraStorageType].(*tiered.Factory).Hot(), ssFactory)
+
+	cfg.ColdSpanStorageType = cassandraStorageType
+	_, err = NewFactory(cfg)
+	assert.EqualError(t, err, "cold span storage type cassandra cannot be used by other storages")
+
+	cfg.ColdSpanStorageType = "x"
+	_, err = NewFactory(cfg)
+	assert.ErrorContains(t, err, "unknown storage type x")
+
+	cfg.ColdSpanStorageType = memoryStorageType
+	cfg.SpanWriterTypes = []string{elasticsearchStorageType}
+	_, err = NewFactory(cfg)
+	assert.EqualError(t, err, "cold span storage requires the span reader type cassandra to be a span storage type, got [elasticsearch]")
+}
+
+func TestCreateTiered(t *testing.T) {
+	cfg := FactoryConfig{
+		SpanWriterTypes:         []string{memoryStorageType},
+		SpanReaderType:          memoryStorageType,
+		DependenciesStorageType: memoryStorageType,
+		ColdSpanStorageType:     badgerStorageType,
+		DownsamplingRatio:       1.0,
+	}
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	// both storages are in memory, to not need a badger directory
+	f.factories[memoryStorageType] = tiered.NewFactory(memory.NewFactory(), memory.NewFactory())
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	spanReader, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.IsType(t, &tiered.Reader{}, spanReader)
+
+	spanWriter, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	span := &model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1, Process: &model.Process{ServiceName: "svc"}}
+	require.NoError(t, spanWriter.WriteSpan(context.Background(), span))
+	trace, err := spanReader.GetTrace(context.Background(), span.TraceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+
+	assert.NoError(t, f.Close())
+}
+
+func TestCreateFederatedReader(t *testing.T) {
+	cfg := FactoryConfig{
+		SpanWriterTypes:         []string{elasticsearchStorageType},
+		SpanReaderType:          cassandraStorageType,
+		SpanReaderTypes:         []string{cassandraStorageType, elasticsearchStorageType},
+		DependenciesStorageType: elasticsearchStorageType,
+		DownsamplingRatio:       1.0,
+	}
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, f.factories, cassandraStorageType)
+	assert.Contains(t, f.factories, elasticsearchStorageType)
+
+	mock := new(mocks.Factory)
+	mock2 := new(mocks.Factory)
+	f.factories[cassandraStorageType] = mock
+	f.factories[elasticsearchStorageType] = mock2
+	m, l := metrics.NullFactory, zap.NewNop()
+	mock.On("Initialize", m, l).Return(nil)
+	mock2.On("Initialize", m, l).Return(nil)
+	require.NoError(t, f.Initialize(m, l))
+
+	spanReader := new(spanStoreMocks.Reader)
+	mock.On("CreateSpanReader").Return(spanReader, nil)
+	mock2.On("CreateSpanReader").Return(nil, errors.New("span reader error")).Once()
+	_, err = f.CreateSpanReader()
+	assert.EqualError(t, err, "span reader error")
+
+	mock2.On("CreateSpanReader").Return(spanReader, nil)
+	r, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.IsType(t, &federated.Reader{}, r)
+
+	// the reader-only storages are closed too
+	closeErr := errors.New("close error")
+	f.factories[cassandraStorageType] = &errorFactory{closeErr: closeErr}
+	assert.ErrorIs(t, f.Close(), closeErr)
+}
+
 func TestClose(t *testing.T) {
 	storageType := "foo"
 	err := fmt.Errorf("some error")
@@ -232,6 +335,41 @@ func TestCreateMulti(t *testing.T) {
 	assert.Equal(t, spanstore.NewCompositeWriter(spanWriter, spanWriter2), w)
 }
 
+type dependencyWriterFactory struct {
+	mocks.Factory
+	writer dependencystore.Writer
+	err    error
+}
+
+func (f *dependencyWriterFactory) CreateDependencyWriter() (dependencystore.Writer, error) {
+	return f.writer, f.err
+}
+
+type nopDependencyWriter struct{}
+
+func (nopDependencyWriter) WriteDependencies(time.Time, []model.DependencyLink) error {
+	return nil
+}
+
+func TestCreateDependencyWriter(t *testing.T) {
+	f, err := NewFactory(defaultCfg())
+	require.NoError(t, err)
+
+	f.factories[cassandraStorageType] = new(mocks.Factory)
+	_, err = f.CreateDependencyWriter()
+	assert.ErrorIs(t, err, storage.ErrDependencyWriterNotSupported)
+
+	writer := nopDependencyWriter{}
+	f.factories[cassandraStorageType] = &dependencyWriterFactory{writer: writer, err: errors.New("dep-writer-error")}
+	w, err := f.CreateDependencyWriter()
+	assert.Equal(t, writer, w)
+	assert.EqualError(t, err, "dep-writer-error")
+
+	delete(f.factories, cassandraStorageType)
+	_, err = f.CreateDependencyWriter()
+	assert.EqualError(t, err, "no cassandra backend registered for span store")
+}
+
 func TestCreateArchive(t *testing.T) {
 	f, err := NewFactory(defaultCfg())
 	require.NoError(t, err)
@@ -258,6 +396,26 @@ func TestCreateArchive(t *testing.T) {
 	assert.EqualError(t, err, "archive-span-writer-error")
 }
 
+func TestAdminHandlers(t *testing.T) {
+	f, err := NewFactory(defaultCfg())
+	require.NoError(t, err)
+	assert.Empty(t, f.AdminHandlers())
+
+	f, err = NewFactory(FactoryConfig{
+		SpanWriterTypes:         []string{badgerStorageType, cassandraStorageType},
+		SpanReaderType:          cassandraStorageType,
+		DependenciesStorageType: cassandraStorageType,
+	})
+	require.NoError(t, err)
+	// the badger backup endpoint is disabled by default
+	assert.Empty(t, f.AdminHandlers())
+
+	f.factories[badgerStorageType].(*badger.Factory).Options.Primary.AdminBackup = true
+	handlers := f.AdminHandlers()
+	assert.Len(t, handlers, 1)
+	assert.Contains(t, handlers, badger.BackupPath)
+}
+
 func TestCreateError(t *testing.T) {
 	f, err := NewFactory(defaultCfg())
 	require.NoError(t, err)
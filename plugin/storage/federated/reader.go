@@ -0,0 +1,191 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/model/adjuster"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// Backend is a storage queried by the Reader.
+type Backend struct {
+	// Name identifies the backend in the failures, logs and metrics, such as its storage type.
+	Name   string
+	Reader spanstore.Reader
+}
+
+// Reader queries several backends concurrently, for instance during a migration
+// from a storage to another, and merges their results.
+//
+// The spans of a trace found in several backends are merged, the spans stored in
+// more than one backend being returned once, and the span IDs shared by client and
+// server spans are made unique by adjuster.SpanIDDeduper, unless the context was
+// created by spanstore.WithRawTraces.
+//
+// A failing backend does not fail the request as long as another backend succeeds.
+// The failure is then logged, counted, and reported by spanstore.ReportFailure, whether
+// or not the other backends found anything, so that the query service returns it along
+// with the results.
+type Reader struct {
+	backends []Backend
+	failures []metrics.Counter
+	deduper  adjuster.Adjuster
+	logger   *zap.Logger
+}
+
+// NewReader creates a new Reader of the backends.
+func NewReader(backends []Backend, metricsFactory metrics.Factory, logger *zap.Logger) *Reader {
+	failures := make([]metrics.Counter, len(backends))
+	for i, backend := range backends {
+		failures[i] = metricsFactory.Counter(metrics.Options{
+			Name: "backend_failures",
+			Tags: map[string]string{"backend": backend.Name},
+		})
+	}
+	return &Reader{
+		backends: backends,
+		failures: failures,
+		deduper:  adjuster.SpanIDDeduper(),
+		logger:   logger,
+	}
+}
+
+// query calls all the backends concurrently, and returns the results of the
+// backends that succeeded and the errors of the others, which are reported in
+// the context. It fails only if all the backends failed.
+func query[T any](ctx context.Context, r *Reader, call func(reader spanstore.Reader) (T, error)) ([]T, []error, error) {
+	results := make([]T, len(r.backends))
+	errs := make([]error, len(r.backends))
+	var wg sync.WaitGroup
+	for i, backend := range r.backends {
+		wg.Add(1)
+		go func(i int, reader spanstore.Reader) {
+			defer wg.Done()
+			results[i], errs[i] = call(reader)
+		}(i, backend.Reader)
+	}
+	wg.Wait()
+
+	var succeeded []T
+	var failures []error
+	for i, err := range errs {
+		if err == nil {
+			succeeded = append(succeeded, results[i])
+			continue
+		}
+		name := r.backends[i].Name
+		r.failures[i].Inc(1)
+		r.logger.Warn("Failed to query a federated storage backend", zap.String("backend", name), zap.Error(err))
+		failures = append(failures, fmt.Errorf("the %s storage failed: %w", name, err))
+	}
+	if len(succeeded) == 0 {
+		return nil, nil, errors.Join(failures...)
+	}
+	for _, failure := range failures {
+		spanstore.ReportFailure(ctx, failure)
+	}
+	return succeeded, failures, nil
+}
+
+// GetTrace merges the spans of the trace from all the backends. It fails if the
+// trace is not found and a backend failed, since the trace may be stored in it.
+func (r *Reader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces, failures, err := query(ctx, r, func(reader spanstore.Reader) (*model.Trace, error) {
+		trace, err := reader.GetTrace(ctx, traceID)
+		if errors.Is(err, spanstore.ErrTraceNotFound) {
+			return nil, nil
+		}
+		return trace, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	trace := spanstore.MergeTraces(traces...)
+	if trace == nil {
+		if len(failures) > 0 {
+			return nil, errors.Join(failures...)
+		}
+		return nil, spanstore.ErrTraceNotFound
+	}
+	if len(traces) > 1 && !spanstore.RawTraces(ctx) {
+		trace = r.dedupe(trace)
+	}
+	return trace, nil
+}
+
+// GetServices returns the services of all the backends.
+func (r *Reader) GetServices(ctx context.Context) ([]string, error) {
+	results, _, err := query(ctx, r, func(reader spanstore.Reader) ([]string, error) {
+		return reader.GetServices(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.MergeServices(results...), nil
+}
+
+// GetOperations returns the operations of all the backends.
+func (r *Reader) GetOperations(ctx context.Context, params spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	results, _, err := query(ctx, r, func(reader spanstore.Reader) ([]spanstore.Operation, error) {
+		return reader.GetOperations(ctx, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.MergeOperations(results...), nil
+}
+
+// FindTraces merges the traces found in all the backends, and returns the most
+// recent ones first.
+func (r *Reader) FindTraces(ctx context.Context, params *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	results, _, err := query(ctx, r, func(reader spanstore.Reader) ([]*model.Trace, error) {
+		return reader.FindTraces(ctx, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var onMerged func(*model.Trace) *model.Trace
+	if !spanstore.RawTraces(ctx) {
+		onMerged = r.dedupe
+	}
+	return spanstore.MergeFoundTraces(params.NumTraces, onMerged, results...), nil
+}
+
+// FindTraceIDs returns the trace IDs found in all the backends, in the order of the backends.
+func (r *Reader) FindTraceIDs(ctx context.Context, params *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	results, _, err := query(ctx, r, func(reader spanstore.Reader) ([]model.TraceID, error) {
+		return reader.FindTraceIDs(ctx, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.MergeTraceIDs(params.NumTraces, results...), nil
+}
+
+// dedupe makes the span IDs of a trace merged from several backends unique.
+func (r *Reader) dedupe(trace *model.Trace) *model.Trace {
+	// SpanIDDeduper records its issues in the warnings instead of failing
+	deduped, _ := r.deduper.Adjust(trace)
+	return deduped
+}
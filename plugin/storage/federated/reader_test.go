@@ -0,0 +1,226 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federated
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/internal/spanstoretest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/plugin/storage/memory"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+var (
+	_ spanstore.Reader = (*Reader)(nil)
+
+	testStart = time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+)
+
+func newTestReader(readers ...spanstore.Reader) (*Reader, *metricstest.Factory) {
+	names := []string{"cassandra", "elasticsearch", "badger"}
+	backends := make([]Backend, len(readers))
+	for i, reader := range readers {
+		backends[i] = Backend{Name: names[i], Reader: reader}
+	}
+	metricsFactory := metricstest.NewFactory(0)
+	return NewReader(backends, metricsFactory, zap.NewNop()), metricsFactory
+}
+
+func failingReader() *mocks.Reader {
+	reader := &mocks.Reader{}
+	err := errors.New("unavailable")
+	reader.On("GetTrace", mock.Anything, mock.Anything).Return(nil, err)
+	reader.On("GetServices", mock.Anything).Return(nil, err)
+	reader.On("GetOperations", mock.Anything, mock.Anything).Return(nil, err)
+	reader.On("FindTraces", mock.Anything, mock.Anything).Return(nil, err)
+	reader.On("FindTraceIDs", mock.Anything, mock.Anything).Return(nil, err)
+	return reader
+}
+
+func failureMessages(ctx context.Context) []string {
+	var messages []string
+	for _, failure := range spanstore.ReportedFailures(ctx) {
+		messages = append(messages, failure.Error())
+	}
+	return messages
+}
+
+func TestGetTrace(t *testing.T) {
+	old, current := memory.NewStore(), memory.NewStore()
+	// span 1 was written to both backends during the migration, and the
+	// client span 2 shares its ID with a server span written to the new backend
+	spanstoretest.WriteSpans(t, old, spanstoretest.Span(1, 1, "a", testStart, ""), spanstoretest.Span(1, 2, "a", testStart, "client"))
+	spanstoretest.WriteSpans(t, current, spanstoretest.Span(1, 1, "a", testStart, ""), spanstoretest.Span(1, 2, "b", testStart.Add(time.Millisecond), "server"))
+	spanstoretest.WriteSpans(t, current, spanstoretest.Span(2, 1, "b", testStart, ""))
+	r, _ := newTestReader(old, current)
+
+	trace, err := r.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 3)
+	ids := spanstoretest.SpanIDs(trace)
+	assert.Equal(t, []model.SpanID{1, 2}, ids[:2])
+	assert.NotContains(t, []model.SpanID{1, 2}, ids[2], "the server span gets a new ID")
+
+	// the span IDs of the raw traces are left as they are stored
+	trace, err = r.GetTrace(spanstore.WithRawTraces(context.Background()), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, []model.SpanID{1, 2, 2}, spanstoretest.SpanIDs(trace))
+
+	trace, err = r.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, []model.SpanID{1}, spanstoretest.SpanIDs(trace))
+
+	_, err = r.GetTrace(context.Background(), model.NewTraceID(0, 3))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestGetTracePartialFailure(t *testing.T) {
+	current := memory.NewStore()
+	spanstoretest.WriteSpans(t, current, spanstoretest.Span(1, 1, "a", testStart, ""))
+	r, metricsFactory := newTestReader(failingReader(), current)
+
+	ctx := spanstore.WithFailures(context.Background())
+	trace, err := r.GetTrace(ctx, model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, []model.SpanID{1}, spanstoretest.SpanIDs(trace))
+	assert.Equal(t, []string{"the cassandra storage failed: unavailable"}, failureMessages(ctx))
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{
+		Name: "backend_failures", Tags: map[string]string{"backend": "cassandra"}, Value: 1,
+	})
+
+	// the trace may be in the failing backend
+	_, err = r.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.EqualError(t, err, "the cassandra storage failed: unavailable")
+
+	r, _ = newTestReader(failingReader(), failingReader())
+	_, err = r.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.EqualError(t, err, "the cassandra storage failed: unavailable\nthe elasticsearch storage failed: unavailable")
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	old, current := memory.NewStore(), memory.NewStore()
+	spanstoretest.WriteSpans(t, old, spanstoretest.Span(1, 1, "b", testStart, ""), spanstoretest.Span(1, 2, "c", testStart, "server"))
+	spanstoretest.WriteSpans(t, current, spanstoretest.Span(2, 1, "a", testStart, ""), spanstoretest.Span(2, 2, "c", testStart, "server"))
+	renamed := spanstoretest.Span(2, 3, "c", testStart, "server")
+	renamed.OperationName = "new-op"
+	spanstoretest.WriteSpans(t, current, renamed)
+	r, _ := newTestReader(old, current, failingReader())
+
+	ctx := spanstore.WithFailures(context.Background())
+	services, err := r.GetServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, services)
+	assert.Equal(t, []string{"the badger storage failed: unavailable"}, failureMessages(ctx))
+
+	ctx = spanstore.WithFailures(context.Background())
+	operations, err := r.GetOperations(ctx, spanstore.OperationQueryParameters{ServiceName: "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "new-op", SpanKind: "server"}, {Name: "op-c", SpanKind: "server"}}, operations)
+	assert.Equal(t, []string{"the badger storage failed: unavailable"}, failureMessages(ctx))
+
+	r, _ = newTestReader(failingReader())
+	_, err = r.GetServices(context.Background())
+	assert.EqualError(t, err, "the cassandra storage failed: unavailable")
+	_, err = r.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "c"})
+	assert.EqualError(t, err, "the cassandra storage failed: unavailable")
+}
+
+func TestFindTraces(t *testing.T) {
+	old, current := memory.NewStore(), memory.NewStore()
+	spanstoretest.WriteSpans(t, old, spanstoretest.Span(1, 1, "a", testStart, ""))
+	spanstoretest.WriteSpans(t, old, spanstoretest.Span(2, 1, "a", testStart.Add(time.Minute), ""), spanstoretest.Span(2, 2, "a", testStart, "client"))
+	spanstoretest.WriteSpans(t, current, spanstoretest.Span(2, 1, "a", testStart.Add(time.Minute), ""), spanstoretest.Span(2, 2, "a", testStart, "server"))
+	spanstoretest.WriteSpans(t, current, spanstoretest.Span(3, 1, "a", testStart.Add(time.Hour), ""))
+	query := &spanstore.TraceQueryParameters{ServiceName: "a"}
+
+	r, _ := newTestReader(old, current)
+	traces, err := r.FindTraces(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, traces, 3)
+	assert.Equal(t, model.NewTraceID(0, 3), spanstore.TraceIDOf(traces[0]))
+	assert.Equal(t, model.NewTraceID(0, 2), spanstore.TraceIDOf(traces[1]))
+	assert.Len(t, traces[1].Spans, 3)
+	assert.Equal(t, model.NewTraceID(0, 1), spanstore.TraceIDOf(traces[2]))
+	assert.NotContains(t, spanstoretest.SpanIDs(traces[1])[2:], model.SpanID(2), "the server span gets a new ID")
+
+	traces, err = r.FindTraces(spanstore.WithRawTraces(context.Background()), query)
+	require.NoError(t, err)
+	require.Len(t, traces, 3)
+	assert.Equal(t, []model.SpanID{1, 2, 2}, spanstoretest.SpanIDs(traces[1]))
+
+	query.NumTraces = 2
+	traces, err = r.FindTraces(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 2), spanstore.TraceIDOf(traces[1]))
+
+	r, _ = newTestReader(old, failingReader())
+	ctx := spanstore.WithFailures(context.Background())
+	traces, err = r.FindTraces(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, []string{"the elasticsearch storage failed: unavailable"}, failureMessages(ctx))
+
+	// the failure is reported when no trace is found
+	ctx = spanstore.WithFailures(context.Background())
+	traces, err = r.FindTraces(ctx, &spanstore.TraceQueryParameters{ServiceName: "b"})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+	assert.Equal(t, []string{"the elasticsearch storage failed: unavailable"}, failureMessages(ctx))
+
+	r, _ = newTestReader(failingReader())
+	_, err = r.FindTraces(context.Background(), query)
+	assert.EqualError(t, err, "the cassandra storage failed: unavailable")
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	id1, id2, id3 := model.NewTraceID(0, 1), model.NewTraceID(0, 2), model.NewTraceID(0, 3)
+	old, current := &mocks.Reader{}, &mocks.Reader{}
+	old.On("FindTraceIDs", mock.Anything, mock.Anything).Return([]model.TraceID{id1, id2}, nil)
+	current.On("FindTraceIDs", mock.Anything, mock.Anything).Return([]model.TraceID{id2, id3}, nil)
+
+	r, _ := newTestReader(old, current, failingReader())
+	ctx := spanstore.WithFailures(context.Background())
+	traceIDs, err := r.FindTraceIDs(ctx, &spanstore.TraceQueryParameters{ServiceName: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{id1, id2, id3}, traceIDs)
+	assert.Equal(t, []string{"the badger storage failed: unavailable"}, failureMessages(ctx))
+
+	traceIDs, err = r.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "a", NumTraces: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{id1, id2}, traceIDs)
+
+	empty := &mocks.Reader{}
+	empty.On("FindTraceIDs", mock.Anything, mock.Anything).Return(nil, nil)
+	r, _ = newTestReader(empty)
+	traceIDs, err = r.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "a"})
+	require.NoError(t, err)
+	assert.Nil(t, traceIDs)
+
+	r, _ = newTestReader(failingReader())
+	_, err = r.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "a"})
+	assert.EqualError(t, err, "the cassandra storage failed: unavailable")
+}
@@ -16,6 +16,7 @@ package shared
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -182,17 +183,15 @@ func (c *grpcClient) GetOperations(
 
 // FindTraces retrieves traces that match the traceQuery
 func (c *grpcClient) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	v1Query, err := toStorageV1Query(query)
+	if errors.Is(err, spanstore.ErrUnsatisfiableTagFilters) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 	stream, err := c.readerClient.FindTraces(upgradeContext(ctx), &storage_v1.FindTracesRequest{
-		Query: &storage_v1.TraceQueryParameters{
-			ServiceName:   query.ServiceName,
-			OperationName: query.OperationName,
-			Tags:          query.Tags,
-			StartTimeMin:  query.StartTimeMin,
-			StartTimeMax:  query.StartTimeMax,
-			DurationMin:   query.DurationMin,
-			DurationMax:   query.DurationMax,
-			NumTraces:     int32(query.NumTraces),
-		},
+		Query: v1Query,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("plugin error: %w", err)
@@ -218,19 +217,41 @@ func (c *grpcClient) FindTraces(ctx context.Context, query *spanstore.TraceQuery
 	return traces, nil
 }
 
+// toStorageV1Query converts the query to storage_v1, which only carries exact-match tags
+// that plugins are expected to match on a single span, as the in-tree plugins do.
+// Tag filters with other operators and TagMatchAnySpanInTrace are rejected.
+func toStorageV1Query(query *spanstore.TraceQueryParameters) (*storage_v1.TraceQueryParameters, error) {
+	query, err := spanstore.FoldEqualityTagFilters(query)
+	if err != nil {
+		return nil, err
+	}
+	// the equality tag filters are folded into the tags
+	if query.TagMatchMode == spanstore.TagMatchAnySpanInTrace && len(query.Tags) > 1 {
+		return nil, fmt.Errorf("%w: %s", spanstore.ErrUnsupportedTagMatchMode, query.TagMatchMode)
+	}
+	return &storage_v1.TraceQueryParameters{
+		ServiceName:   query.ServiceName,
+		OperationName: query.OperationName,
+		Tags:          query.Tags,
+		StartTimeMin:  query.StartTimeMin,
+		StartTimeMax:  query.StartTimeMax,
+		DurationMin:   query.DurationMin,
+		DurationMax:   query.DurationMax,
+		NumTraces:     int32(query.NumTraces),
+	}, nil
+}
+
 // FindTraceIDs retrieves traceIDs that match the traceQuery
 func (c *grpcClient) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	v1Query, err := toStorageV1Query(query)
+	if errors.Is(err, spanstore.ErrUnsatisfiableTagFilters) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 	resp, err := c.readerClient.FindTraceIDs(upgradeContext(ctx), &storage_v1.FindTraceIDsRequest{
-		Query: &storage_v1.TraceQueryParameters{
-			ServiceName:   query.ServiceName,
-			OperationName: query.OperationName,
-			Tags:          query.Tags,
-			StartTimeMin:  query.StartTimeMin,
-			StartTimeMax:  query.StartTimeMax,
-			DurationMin:   query.DurationMin,
-			DurationMax:   query.DurationMax,
-			NumTraces:     int32(query.NumTraces),
-		},
+		Query: v1Query,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("plugin error: %w", err)
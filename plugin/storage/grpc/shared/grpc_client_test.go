@@ -23,6 +23,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -293,6 +294,41 @@ func TestGRPCClientFindTraces_RecvError(t *testing.T) {
 	})
 }
 
+func TestGRPCClientFindTraces_UnsupportedQuery(t *testing.T) {
+	withGRPCClient(func(r *grpcClientTest) {
+		prefix, err := spanstore.NewTagFilter("http.url", spanstore.OpPrefix, "https://")
+		require.NoError(t, err)
+		_, err = r.client.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+			TagFilters: []*spanstore.Predicate{prefix},
+		})
+		assert.ErrorIs(t, err, spanstore.ErrUnsupportedTagFilter)
+
+		_, err = r.client.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+			Tags:         map[string]string{"a": "1", "b": "2"},
+			TagMatchMode: spanstore.TagMatchAnySpanInTrace,
+		})
+		assert.ErrorIs(t, err, spanstore.ErrUnsupportedTagMatchMode)
+	})
+}
+
+func TestGRPCClientFindTraces_UnsatisfiableQuery(t *testing.T) {
+	withGRPCClient(func(r *grpcClientTest) {
+		tagFilter, err := spanstore.NewTagFilter("a", spanstore.OpEqual, "2")
+		require.NoError(t, err)
+		query := &spanstore.TraceQueryParameters{
+			Tags:       map[string]string{"a": "1"},
+			TagFilters: []*spanstore.Predicate{tagFilter},
+		}
+		traces, err := r.client.FindTraces(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Empty(t, traces)
+
+		traceIDs, err := r.client.FindTraceIDs(context.Background(), query)
+		assert.NoError(t, err)
+		assert.Empty(t, traceIDs)
+	})
+}
+
 func TestGRPCClientFindTraceIDs(t *testing.T) {
 	withGRPCClient(func(r *grpcClientTest) {
 		r.spanReader.On("FindTraceIDs", mock.Anything, &storage_v1.FindTraceIDsRequest{
@@ -0,0 +1,113 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	chclient "github.com/kjschnei001/jaeger/pkg/clickhouse"
+	"github.com/kjschnei001/jaeger/pkg/config"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/pkg/testutils"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse"
+	"github.com/kjschnei001/jaeger/plugin/storage/clickhouse/schema"
+)
+
+const clickhouseTablePrefix = "integration_test"
+
+type ClickHouseStorageIntegration struct {
+	StorageIntegration
+
+	factory *clickhouse.Factory
+	client  chclient.Client
+	tables  schema.Tables
+	logger  *zap.Logger
+}
+
+func (s *ClickHouseStorageIntegration) initialize() error {
+	s.logger, _ = testutils.NewLogger()
+	s.factory = clickhouse.NewFactory()
+	v, command := config.Viperize(s.factory.AddFlags)
+	if err := command.ParseFlags([]string{
+		"--clickhouse.servers=http://localhost:8123",
+		"--clickhouse.table-prefix=" + clickhouseTablePrefix,
+		// the spans are inserted by Refresh
+		"--clickhouse.batch.flush-interval=0",
+	}); err != nil {
+		return fmt.Errorf("unable to parse flags: %w", err)
+	}
+	s.factory.InitFromViper(v, zap.NewNop())
+	if err := s.factory.Initialize(metrics.NullFactory, s.logger); err != nil {
+		return err
+	}
+
+	primary := s.factory.Options.GetPrimary()
+	client, err := primary.NewClient(s.logger)
+	if err != nil {
+		return err
+	}
+	s.client = client
+	s.tables = schema.NewTables(primary.Database, primary.TablePrefix, false)
+
+	if s.SpanWriter, err = s.factory.CreateSpanWriter(); err != nil {
+		return err
+	}
+	if s.SpanReader, err = s.factory.CreateSpanReader(); err != nil {
+		return err
+	}
+	if s.DependencyReader, err = s.factory.CreateDependencyReader(); err != nil {
+		return err
+	}
+	if s.DependencyWriter, err = s.factory.CreateDependencyWriter(); err != nil {
+		return err
+	}
+	s.Refresh = s.refresh
+	s.CleanUp = s.cleanUp
+	return s.cleanUp()
+}
+
+// refresh inserts the spans still buffered by the writer.
+func (s *ClickHouseStorageIntegration) refresh() error {
+	flusher, ok := s.SpanWriter.(interface{ Flush(context.Context) error })
+	if !ok {
+		return fmt.Errorf("span writer %T cannot be flushed", s.SpanWriter)
+	}
+	return flusher.Flush(context.Background())
+}
+
+func (s *ClickHouseStorageIntegration) cleanUp() error {
+	for _, table := range s.tables.All() {
+		if err := s.client.Exec(context.Background(), "TRUNCATE TABLE IF EXISTS "+table, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestClickHouseStorage(t *testing.T) {
+	if os.Getenv("STORAGE") != "clickhouse" {
+		t.Skip("Integration test against ClickHouse skipped; set STORAGE env var to clickhouse to run this")
+	}
+	s := &ClickHouseStorageIntegration{}
+	require.NoError(t, s.initialize())
+	defer s.factory.Close()
+	s.IntegrationTestAll(t)
+}
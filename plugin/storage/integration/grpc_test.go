@@ -130,6 +130,8 @@ func (s *GRPCStorageIntegrationTestSuite) initialize() error {
 
 	// TODO DependencyWriter is not implemented in grpc store
 
+	// storage_v1 cannot express TagMatchAnySpanInTrace
+	s.SkipList = []string{"AnySpanInTrace"}
 	s.Refresh = s.refresh
 	s.CleanUp = s.cleanUp
 	return nil
@@ -0,0 +1,103 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build parquet_storage_integration
+
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet"
+	parquetStore "github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore"
+)
+
+type ParquetIntegrationStorage struct {
+	StorageIntegration
+	dir     string
+	factory *parquet.Factory
+	writer  *parquetStore.SpanWriter
+}
+
+func (s *ParquetIntegrationStorage) initialize() error {
+	dir, err := os.MkdirTemp("", "parquet")
+	if err != nil {
+		return err
+	}
+	s.dir = dir
+
+	opts := parquet.NewOptions("parquet", "parquet-archive")
+	opts.Primary.Directory = dir
+	// spans are written by refresh, the maintenance is not needed by the tests
+	opts.Primary.BatchFlushInterval = 0
+	opts.Primary.MaintenanceInterval = 0
+	s.factory = parquet.NewFactory()
+	s.factory.InitFromOptions(*opts)
+	if err := s.factory.Initialize(metrics.NullFactory, zap.NewNop()); err != nil {
+		return err
+	}
+
+	sw, err := s.factory.CreateSpanWriter()
+	if err != nil {
+		return err
+	}
+	sr, err := s.factory.CreateSpanReader()
+	if err != nil {
+		return err
+	}
+	dr, err := s.factory.CreateDependencyReader()
+	if err != nil {
+		return err
+	}
+	s.writer = sw.(*parquetStore.SpanWriter)
+	s.SpanWriter = sw
+	s.SpanReader = sr
+	s.DependencyReader = dr
+
+	s.Refresh = s.refresh
+	s.CleanUp = s.cleanUp
+	return nil
+}
+
+func (s *ParquetIntegrationStorage) clear() error {
+	err := s.factory.Close()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(s.dir)
+}
+
+func (s *ParquetIntegrationStorage) cleanUp() error {
+	if err := s.clear(); err != nil {
+		return err
+	}
+	return s.initialize()
+}
+
+func (s *ParquetIntegrationStorage) refresh() error {
+	return s.writer.Flush(context.Background())
+}
+
+func TestParquetStorage(t *testing.T) {
+	s := &ParquetIntegrationStorage{}
+	require.NoError(t, s.initialize())
+	defer s.clear()
+	s.IntegrationTestAll(t)
+}
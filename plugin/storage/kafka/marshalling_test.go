@@ -18,9 +18,15 @@ import (
 	"context"
 	"testing"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 
+	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/model/converter/thrift/zipkin"
+	zipkinProto "github.com/kjschnei001/jaeger/proto-gen/zipkin"
 	"github.com/kjschnei001/jaeger/thrift-gen/zipkincore"
 )
 
@@ -80,3 +86,171 @@ func TestZipkinThriftUnmarshallerErrorCorrupted(t *testing.T) {
 	_, err := unmarshaller.Unmarshal(bytes)
 	assert.Error(t, err)
 }
+
+const zipkinJSONSpans = `[
+	{"traceId": "000000000000000a", "id": "0000000000000001", "name": "get", "localEndpoint": {"serviceName": "frontend"}},
+	{"traceId": "000000000000000a", "id": "0000000000000002", "parentId": "0000000000000001", "name": "query", "localEndpoint": {"serviceName": "db"}}
+]`
+
+func assertUnmarshalledSpans(t *testing.T, unmarshaller interface {
+	Unmarshaller
+	SpansUnmarshaller
+}, msg []byte, services ...string,
+) {
+	spans, err := unmarshaller.UnmarshalSpans(msg)
+	require.NoError(t, err)
+	require.Len(t, spans, len(services))
+	for i, service := range services {
+		assert.Equal(t, model.NewTraceID(0, 10), spans[i].TraceID)
+		assert.Equal(t, model.NewSpanID(uint64(i+1)), spans[i].SpanID)
+		assert.Equal(t, service, spans[i].Process.ServiceName)
+	}
+	assert.Equal(t, model.NewSpanID(1), spans[1].ParentSpanID())
+
+	span, err := unmarshaller.Unmarshal(msg)
+	require.NoError(t, err)
+	assert.Equal(t, spans[0], span)
+}
+
+func TestZipkinThriftUnmarshallerSpans(t *testing.T) {
+	parentID := int64(1)
+	bytes := zipkin.SerializeThrift(context.Background(), []*zipkincore.Span{
+		{
+			TraceID:     10,
+			ID:          1,
+			Name:        "get",
+			Annotations: []*zipkincore.Annotation{{Host: &zipkincore.Endpoint{ServiceName: "frontend"}}},
+		},
+		{
+			TraceID:     10,
+			ID:          2,
+			ParentID:    &parentID,
+			Name:        "query",
+			Annotations: []*zipkincore.Annotation{{Host: &zipkincore.Endpoint{ServiceName: "db"}}},
+		},
+	})
+	assertUnmarshalledSpans(t, NewZipkinThriftUnmarshaller(), bytes, "frontend", "db")
+}
+
+func TestZipkinJSONUnmarshaller(t *testing.T) {
+	assertUnmarshalledSpans(t, NewZipkinJSONUnmarshaller(), []byte(zipkinJSONSpans), "frontend", "db")
+}
+
+func TestZipkinJSONUnmarshallerErrors(t *testing.T) {
+	unmarshaller := NewZipkinJSONUnmarshaller()
+	for _, msg := range []string{
+		`foo`,
+		`[{"id": "0000000000000001", "name": "get"}]`,
+		`[{"traceId": "zz", "id": "0000000000000001", "name": "get"}]`,
+	} {
+		_, err := unmarshaller.Unmarshal([]byte(msg))
+		assert.Error(t, err, msg)
+	}
+	_, err := unmarshaller.Unmarshal([]byte(`[]`))
+	assert.ErrorIs(t, err, errNoSpans)
+}
+
+func TestZipkinProtoUnmarshaller(t *testing.T) {
+	traceID := []byte{0, 0, 0, 0, 0, 0, 0, 10}
+	bytes, err := proto.Marshal(&zipkinProto.ListOfSpans{Spans: []*zipkinProto.Span{
+		{
+			TraceId:       traceID,
+			Id:            []byte{0, 0, 0, 0, 0, 0, 0, 1},
+			Name:          "get",
+			LocalEndpoint: &zipkinProto.Endpoint{ServiceName: "frontend"},
+		},
+		{
+			TraceId:       traceID,
+			Id:            []byte{0, 0, 0, 0, 0, 0, 0, 2},
+			ParentId:      []byte{0, 0, 0, 0, 0, 0, 0, 1},
+			Name:          "query",
+			LocalEndpoint: &zipkinProto.Endpoint{ServiceName: "db"},
+		},
+	}})
+	require.NoError(t, err)
+	assertUnmarshalledSpans(t, NewZipkinProtoUnmarshaller(), bytes, "frontend", "db")
+}
+
+func TestZipkinUnmarshallersSanitizeSpans(t *testing.T) {
+	protoSpans, err := proto.Marshal(&zipkinProto.ListOfSpans{Spans: []*zipkinProto.Span{
+		{
+			TraceId:       []byte{0, 0, 0, 0, 0, 0, 0, 10},
+			Id:            []byte{0, 0, 0, 0, 0, 0, 0, 1},
+			Name:          "get",
+			LocalEndpoint: &zipkinProto.Endpoint{ServiceName: "frontend"},
+			Tags:          map[string]string{"error": "timeout"},
+		},
+	}})
+	require.NoError(t, err)
+	jsonSpans := `[{"traceId": "000000000000000a", "id": "0000000000000001", "name": "get",
+		"localEndpoint": {"serviceName": "frontend"}, "tags": {"error": "timeout"}}]`
+	tests := []struct {
+		name         string
+		unmarshaller Unmarshaller
+		msg          []byte
+	}{
+		{name: "json", unmarshaller: NewZipkinJSONUnmarshaller(), msg: []byte(jsonSpans)},
+		{name: "proto", unmarshaller: NewZipkinProtoUnmarshaller(), msg: protoSpans},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			span, err := test.unmarshaller.Unmarshal(test.msg)
+			require.NoError(t, err)
+			// the error message is moved to its own tag
+			errorTag, found := model.KeyValues(span.Tags).FindByKey("error")
+			require.True(t, found)
+			assert.True(t, errorTag.Bool())
+			errorMessage, found := model.KeyValues(span.Tags).FindByKey("error.message")
+			require.True(t, found)
+			assert.Equal(t, "timeout", errorMessage.AsString())
+		})
+	}
+}
+
+func TestZipkinProtoUnmarshallerErrors(t *testing.T) {
+	unmarshaller := NewZipkinProtoUnmarshaller()
+	_, err := unmarshaller.Unmarshal([]byte("foo"))
+	assert.Error(t, err)
+
+	bytes, err := proto.Marshal(&zipkinProto.ListOfSpans{Spans: []*zipkinProto.Span{
+		{TraceId: []byte{1}, Id: []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+	}})
+	require.NoError(t, err)
+	_, err = unmarshaller.Unmarshal(bytes)
+	assert.Error(t, err)
+}
+
+func TestOTLPProtoUnmarshaller(t *testing.T) {
+	traces := ptrace.NewTraces()
+	for i, service := range []string{"frontend", "db"} {
+		rs := traces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", service)
+		span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		span.SetTraceID(pcommon.TraceID([16]byte{15: 10}))
+		span.SetSpanID(pcommon.SpanID([8]byte{7: byte(i + 1)}))
+		if i > 0 {
+			span.SetParentSpanID(pcommon.SpanID([8]byte{7: 1}))
+		}
+	}
+	bytes, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	require.NoError(t, err)
+	assertUnmarshalledSpans(t, NewOTLPProtoUnmarshaller(), bytes, "frontend", "db")
+}
+
+func TestOTLPProtoUnmarshallerErrors(t *testing.T) {
+	unmarshaller := NewOTLPProtoUnmarshaller()
+	_, err := unmarshaller.Unmarshal([]byte("foo"))
+	assert.Error(t, err)
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	bytes, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	require.NoError(t, err)
+	_, err = unmarshaller.Unmarshal(bytes)
+	assert.Error(t, err)
+
+	bytes, err = (&ptrace.ProtoMarshaler{}).MarshalTraces(ptrace.NewTraces())
+	require.NoError(t, err)
+	_, err = unmarshaller.Unmarshal(bytes)
+	assert.ErrorIs(t, err, errNoSpans)
+}
@@ -34,6 +34,12 @@ const (
 	EncodingProto = "protobuf"
 	// EncodingZipkinThrift is used for spans encoded as Zipkin Thrift.
 	EncodingZipkinThrift = "zipkin-thrift"
+	// EncodingZipkinJSON is used for spans encoded as Zipkin v2 JSON.
+	EncodingZipkinJSON = "zipkin-json"
+	// EncodingZipkinProto is used for spans encoded as Zipkin v2 Protobuf.
+	EncodingZipkinProto = "zipkin-proto"
+	// EncodingOTLPProto is used for spans encoded as OTLP Protobuf, like the OpenTelemetry Kafka exporter does.
+	EncodingOTLPProto = "otlp-proto"
 
 	configPrefix           = "kafka.producer"
 	suffixBrokers          = ".brokers"
@@ -62,7 +68,7 @@ const (
 
 var (
 	// AllEncodings is a list of all supported encodings.
-	AllEncodings = []string{EncodingJSON, EncodingProto, EncodingZipkinThrift}
+	AllEncodings = []string{EncodingJSON, EncodingProto, EncodingZipkinThrift, EncodingZipkinJSON, EncodingZipkinProto, EncodingOTLPProto}
 
 	// requiredAcks is mapping of sarama supported requiredAcks
 	requiredAcks = map[string]sarama.RequiredAcks{
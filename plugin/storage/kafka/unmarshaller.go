@@ -17,19 +17,41 @@ package kafka
 import (
 	"bytes"
 	"context"
+	"errors"
 
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/gogo/protobuf/jsonpb"
 	"github.com/gogo/protobuf/proto"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 
+	zs "github.com/kjschnei001/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/kjschnei001/jaeger/cmd/collector/app/zipkin/zipkindeser"
 	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/model/converter/otlp"
 	"github.com/kjschnei001/jaeger/model/converter/thrift/zipkin"
+	zipkinProto "github.com/kjschnei001/jaeger/proto-gen/zipkin"
+	"github.com/kjschnei001/jaeger/swagger-gen/models"
+	"github.com/kjschnei001/jaeger/thrift-gen/zipkincore"
 )
 
+var errNoSpans = errors.New("message contains no spans")
+
+// zipkinSanitizer fixes the Zipkin spans before their conversion, as the collector does
+var zipkinSanitizer = zs.NewChainedSanitizer(zs.NewStandardSanitizers()...)
+
 // Unmarshaller decodes a byte array to a span
 type Unmarshaller interface {
 	Unmarshal([]byte) (*model.Span, error)
 }
 
+// SpansUnmarshaller decodes a byte array holding several spans, as sent by Zipkin
+// reporters and the OpenTelemetry Kafka exporter. Consumers should prefer it over
+// Unmarshal, which only returns the first span.
+type SpansUnmarshaller interface {
+	UnmarshalSpans([]byte) ([]*model.Span, error)
+}
+
 // ProtobufUnmarshaller implements Unmarshaller
 type ProtobufUnmarshaller struct{}
 
@@ -74,9 +96,130 @@ func (h *ZipkinThriftUnmarshaller) Unmarshal(msg []byte) (*model.Span, error) {
 	if err != nil {
 		return nil, err
 	}
-	mSpans, err := zipkin.ToDomainSpan(tSpans[0])
+	mSpans, err := zipkin.ToDomainSpan(zipkinSanitizer.Sanitize(tSpans[0]))
 	if err != nil {
 		return nil, err
 	}
 	return mSpans[0], err
 }
+
+// UnmarshalSpans decodes a Zipkin Thrift byte array to spans
+func (h *ZipkinThriftUnmarshaller) UnmarshalSpans(msg []byte) ([]*model.Span, error) {
+	tSpans, err := zipkin.DeserializeThrift(context.Background(), msg)
+	if err != nil {
+		return nil, err
+	}
+	return zipkinToDomain(tSpans)
+}
+
+// ZipkinJSONUnmarshaller implements Unmarshaller and SpansUnmarshaller
+type ZipkinJSONUnmarshaller struct{}
+
+// NewZipkinJSONUnmarshaller constructs a ZipkinJSONUnmarshaller
+func NewZipkinJSONUnmarshaller() *ZipkinJSONUnmarshaller {
+	return &ZipkinJSONUnmarshaller{}
+}
+
+// Unmarshal decodes a Zipkin v2 JSON byte array to its first span
+func (h *ZipkinJSONUnmarshaller) Unmarshal(msg []byte) (*model.Span, error) {
+	return firstSpan(h.UnmarshalSpans(msg))
+}
+
+// UnmarshalSpans decodes a Zipkin v2 JSON byte array to spans
+func (h *ZipkinJSONUnmarshaller) UnmarshalSpans(msg []byte) ([]*model.Span, error) {
+	var spans models.ListOfSpans
+	if err := swag.ReadJSON(msg, &spans); err != nil {
+		return nil, err
+	}
+	if err := spans.Validate(strfmt.Default); err != nil {
+		return nil, err
+	}
+	tSpans, err := zipkindeser.SpansV2ToThrift(spans)
+	if err != nil {
+		return nil, err
+	}
+	return zipkinToDomain(tSpans)
+}
+
+// ZipkinProtoUnmarshaller implements Unmarshaller and SpansUnmarshaller
+type ZipkinProtoUnmarshaller struct{}
+
+// NewZipkinProtoUnmarshaller constructs a ZipkinProtoUnmarshaller
+func NewZipkinProtoUnmarshaller() *ZipkinProtoUnmarshaller {
+	return &ZipkinProtoUnmarshaller{}
+}
+
+// Unmarshal decodes a Zipkin v2 protobuf byte array to its first span
+func (h *ZipkinProtoUnmarshaller) Unmarshal(msg []byte) (*model.Span, error) {
+	return firstSpan(h.UnmarshalSpans(msg))
+}
+
+// UnmarshalSpans decodes a Zipkin v2 protobuf byte array to spans
+func (h *ZipkinProtoUnmarshaller) UnmarshalSpans(msg []byte) ([]*model.Span, error) {
+	var spans zipkinProto.ListOfSpans
+	if err := proto.Unmarshal(msg, &spans); err != nil {
+		return nil, err
+	}
+	tSpans, err := zipkindeser.ProtoSpansV2ToThrift(&spans)
+	if err != nil {
+		return nil, err
+	}
+	return zipkinToDomain(tSpans)
+}
+
+// OTLPProtoUnmarshaller implements Unmarshaller and SpansUnmarshaller
+type OTLPProtoUnmarshaller struct{}
+
+// NewOTLPProtoUnmarshaller constructs an OTLPProtoUnmarshaller
+func NewOTLPProtoUnmarshaller() *OTLPProtoUnmarshaller {
+	return &OTLPProtoUnmarshaller{}
+}
+
+// Unmarshal decodes an OTLP protobuf byte array to its first span
+func (h *OTLPProtoUnmarshaller) Unmarshal(msg []byte) (*model.Span, error) {
+	return firstSpan(h.UnmarshalSpans(msg))
+}
+
+// UnmarshalSpans decodes an OTLP protobuf byte array to spans
+func (h *OTLPProtoUnmarshaller) UnmarshalSpans(msg []byte) ([]*model.Span, error) {
+	td, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(msg)
+	if err != nil {
+		return nil, err
+	}
+	batches, err := otlp.ProtoFromTraces(td)
+	if err != nil {
+		return nil, err
+	}
+	var spans []*model.Span
+	for _, batch := range batches {
+		for _, span := range batch.Spans {
+			if span.Process == nil {
+				span.Process = batch.Process
+			}
+			spans = append(spans, span)
+		}
+	}
+	return spans, nil
+}
+
+func zipkinToDomain(tSpans []*zipkincore.Span) ([]*model.Span, error) {
+	var spans []*model.Span
+	for _, tSpan := range tSpans {
+		mSpans, err := zipkin.ToDomainSpan(zipkinSanitizer.Sanitize(tSpan))
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, mSpans...)
+	}
+	return spans, nil
+}
+
+func firstSpan(spans []*model.Span, err error) (*model.Span, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, errNoSpans
+	}
+	return spans[0], nil
+}
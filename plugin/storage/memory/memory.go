@@ -278,29 +278,98 @@ func (st *Store) FindTraces(ctx context.Context, query *spanstore.TraceQueryPara
 	return retMe, nil
 }
 
+// pageCursor is the position of the last trace of a page, in the newest first
+// order of FindTracesPage.
+type pageCursor struct {
+	StartTime time.Time
+	TraceID   model.TraceID
+}
+
+func traceCursor(trace *model.Trace) pageCursor {
+	return pageCursor{StartTime: trace.Spans[0].StartTime, TraceID: trace.Spans[0].TraceID}
+}
+
+// before reports whether c is returned before other.
+func (c pageCursor) before(other pageCursor) bool {
+	if !c.StartTime.Equal(other.StartTime) {
+		return c.StartTime.After(other.StartTime)
+	}
+	if c.TraceID.High != other.TraceID.High {
+		return c.TraceID.High > other.TraceID.High
+	}
+	return c.TraceID.Low > other.TraceID.Low
+}
+
+// FindTracesPage implements spanstore.PagedReader. Traces are returned newest first.
+func (st *Store) FindTracesPage(ctx context.Context, query *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	var after *pageCursor
+	if query.PageToken != "" {
+		after = &pageCursor{}
+		if err := spanstore.DecodePageToken(query.PageToken, after); err != nil {
+			return nil, err
+		}
+	}
+	m := st.getTenant(tenancy.GetTenant(ctx))
+	m.RLock()
+	defer m.RUnlock()
+	var matches []*model.Trace
+	for _, trace := range m.traces {
+		if (after == nil || after.before(traceCursor(trace))) && validTrace(trace, query) {
+			matches = append(matches, trace)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return traceCursor(matches[i]).before(traceCursor(matches[j]))
+	})
+
+	page := &spanstore.TracePage{}
+	if query.NumTraces > 0 && len(matches) > query.NumTraces {
+		matches = matches[:query.NumTraces]
+		token, err := spanstore.EncodePageToken(traceCursor(matches[len(matches)-1]))
+		if err != nil {
+			return nil, err
+		}
+		page.NextPageToken = token
+	}
+	for _, trace := range matches {
+		copied, err := copyTrace(trace)
+		if err != nil {
+			return nil, err
+		}
+		page.Traces = append(page.Traces, copied)
+	}
+	return page, nil
+}
+
+// CanPushDown implements spanstore.ExpressionPushdown. The in-memory store
+// evaluates every query expression natively.
+func (st *Store) CanPushDown(expr spanstore.Expr) bool {
+	return true
+}
+
 // FindTraceIDs is not implemented.
 func (m *Store) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
 	return nil, errors.New("not implemented")
 }
 
 func validTrace(trace *model.Trace, query *spanstore.TraceQueryParameters) bool {
+	if query.TagMatchMode == spanstore.TagMatchAnySpanInTrace {
+		// tags may be satisfied by different spans than the rest of the query
+		for _, span := range trace.Spans {
+			if validSpan(span, query) {
+				return spanstore.MatchTraceTags(trace, query)
+			}
+		}
+		return false
+	}
 	for _, span := range trace.Spans {
-		if validSpan(span, query) {
+		if validSpan(span, query) && spanstore.MatchSpanTags(span, query.Tags, query.TagFilters) {
 			return true
 		}
 	}
 	return false
 }
 
-func findKeyValueMatch(kvs model.KeyValues, key, value string) (model.KeyValue, bool) {
-	for _, kv := range kvs {
-		if kv.Key == key && kv.AsString() == value {
-			return kv, true
-		}
-	}
-	return model.KeyValue{}, false
-}
-
 func validSpan(span *model.Span, query *spanstore.TraceQueryParameters) bool {
 	if query.ServiceName != span.Process.ServiceName {
 		return false
@@ -320,22 +389,8 @@ func validSpan(span *model.Span, query *spanstore.TraceQueryParameters) bool {
 	if !query.StartTimeMax.IsZero() && span.StartTime.After(query.StartTimeMax) {
 		return false
 	}
-	spanKVs := flattenTags(span)
-	for queryK, queryV := range query.Tags {
-		// (NB): we cannot use the KeyValues.FindKey function because there can be multiple tags with the same key
-		if _, ok := findKeyValueMatch(spanKVs, queryK, queryV); !ok {
-			return false
-		}
+	if query.Expression != nil && !query.Expression.MatchSpan(span) {
+		return false
 	}
 	return true
 }
-
-func flattenTags(span *model.Span) model.KeyValues {
-	retMe := []model.KeyValue{}
-	retMe = append(retMe, span.Tags...)
-	retMe = append(retMe, span.Process.Tags...)
-	for _, l := range span.Logs {
-		retMe = append(retMe, l.Fields...)
-	}
-	return retMe
-}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/kjschnei001/jaeger/model"
 	"github.com/kjschnei001/jaeger/pkg/memory/config"
@@ -523,3 +524,95 @@ func makeTestingSpan(traceID model.TraceID, suffix string) *model.Span {
 		StartTime: time.Unix(300, 0).UTC(),
 	}
 }
+
+func TestStoreFindTracesWithExpression(t *testing.T) {
+	withPopulatedMemoryStore(func(store *Store) {
+		assert.True(t, store.CanPushDown(nil))
+		for _, test := range []struct {
+			op       spanstore.CompareOperator
+			value    string
+			expected int
+		}{
+			{spanstore.OpEqual, testingSpan.OperationName, 1},
+			{spanstore.OpNotEqual, testingSpan.OperationName, 0},
+			{spanstore.OpRegex, "^" + testingSpan.OperationName[:1], 1},
+		} {
+			expr, err := spanstore.NewPredicate(spanstore.OperationField, "", test.op, test.value)
+			require.NoError(t, err)
+			traces, err := store.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+				ServiceName: testingSpan.Process.ServiceName,
+				Expression:  expr,
+			})
+			assert.NoError(t, err)
+			assert.Len(t, traces, test.expected)
+		}
+	})
+}
+
+func TestStoreFindTracesPage(t *testing.T) {
+	withMemoryStore(func(store *Store) {
+		startTime := time.Now()
+		// traces 1 and 2 start at the same time and are ordered by trace ID
+		startTimes := []time.Duration{0, time.Second, time.Second, 2 * time.Second, 3 * time.Second}
+		for i, offset := range startTimes {
+			require.NoError(t, store.WriteSpan(context.Background(), &model.Span{
+				TraceID:       model.NewTraceID(0, uint64(i)),
+				SpanID:        model.SpanID(1),
+				OperationName: "operation",
+				Process:       &model.Process{ServiceName: "service"},
+				StartTime:     startTime.Add(offset),
+			}))
+		}
+
+		query := &spanstore.TraceQueryParameters{ServiceName: "service", NumTraces: 2}
+		var traceIDs []model.TraceID
+		for pages := 1; ; pages++ {
+			page, err := store.FindTracesPage(context.Background(), query)
+			require.NoError(t, err)
+			for _, trace := range page.Traces {
+				traceIDs = append(traceIDs, trace.Spans[0].TraceID)
+			}
+			if page.NextPageToken == "" {
+				assert.Equal(t, 3, pages)
+				break
+			}
+			query.PageToken = page.NextPageToken
+		}
+		assert.Equal(t, []model.TraceID{
+			model.NewTraceID(0, 4), model.NewTraceID(0, 3), model.NewTraceID(0, 2), model.NewTraceID(0, 1), model.NewTraceID(0, 0),
+		}, traceIDs)
+
+		query.PageToken = "invalid"
+		_, err := store.FindTracesPage(context.Background(), query)
+		assert.ErrorIs(t, err, spanstore.ErrInvalidPageToken)
+	})
+}
+
+func TestStoreFindTracesTagMatchMode(t *testing.T) {
+	withMemoryStore(func(store *Store) {
+		for _, span := range []*model.Span{childSpan1, childSpan2} {
+			require.NoError(t, store.WriteSpan(context.Background(), span))
+		}
+		// childSpan1 is a server span and childSpan2 a local one
+		local, err := spanstore.NewTagFilter("span.kind", spanstore.OpEqual, "local")
+		require.NoError(t, err)
+		query := &spanstore.TraceQueryParameters{
+			ServiceName: childSpan1.Process.ServiceName,
+			Tags:        map[string]string{"span.kind": "server"},
+			TagFilters:  []*spanstore.Predicate{local},
+		}
+		traces, err := store.FindTraces(context.Background(), query)
+		require.NoError(t, err)
+		assert.Empty(t, traces)
+
+		query.TagMatchMode = spanstore.TagMatchAnySpanInTrace
+		traces, err = store.FindTraces(context.Background(), query)
+		require.NoError(t, err)
+		assert.Len(t, traces, 1)
+
+		query.OperationName = "unknown"
+		traces, err = store.FindTraces(context.Background(), query)
+		require.NoError(t, err)
+		assert.Empty(t, traces)
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin"
+	depStore "github.com/kjschnei001/jaeger/plugin/storage/badger/dependencystore"
+	parquetStore "github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore"
+	"github.com/kjschnei001/jaeger/storage"
+	"github.com/kjschnei001/jaeger/storage/dependencystore"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const (
+	primaryNamespace = "parquet"
+	archiveNamespace = "parquet-archive"
+
+	lastMaintenanceRunName = "parquet_storage_maintenance_last_run"
+)
+
+var (
+	_ io.Closer              = (*Factory)(nil)
+	_ plugin.Configurable    = (*Factory)(nil)
+	_ storage.ArchiveFactory = (*Factory)(nil)
+)
+
+// Factory implements storage.Factory for the Parquet files backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+
+	primaryStore *parquetStore.Store
+	archiveStore *parquetStore.Store
+
+	// writers are closed with the factory to write their last batches
+	writersMu sync.Mutex
+	writers   []*parquetStore.SpanWriter
+
+	maintenanceDone chan struct{}
+	maintenanceWG   sync.WaitGroup
+
+	metrics struct {
+		// LastMaintenanceRun stores the timestamp (UnixNano) of the previous maintenance run
+		LastMaintenanceRun metrics.Gauge
+	}
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options:         NewOptions(primaryNamespace, archiveNamespace),
+		maintenanceDone: make(chan struct{}),
+	}
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper, logger *zap.Logger) {
+	f.Options.InitFromViper(v)
+}
+
+// InitFromOptions initializes Factory from supplied options
+func (f *Factory) InitFromOptions(opts Options) {
+	f.Options = &opts
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+
+	primary := f.Options.GetPrimary()
+	store, err := parquetStore.NewStore(primary.Directory, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open the Parquet storage in %s: %w", primary.Directory, err)
+	}
+	f.primaryStore = store
+	f.metrics.LastMaintenanceRun = metricsFactory.Gauge(metrics.Options{Name: lastMaintenanceRunName})
+	f.startMaintenance(store, primary)
+
+	if archive := f.Options.GetArchive(); archive.Enabled {
+		store, err := parquetStore.NewStore(archive.Directory, logger)
+		if err != nil {
+			return fmt.Errorf("failed to open the Parquet archive storage in %s: %w", archive.Directory, err)
+		}
+		f.archiveStore = store
+		f.startMaintenance(store, archive)
+	}
+
+	logger.Info("Parquet storage configuration", zap.Any("configuration", f.Options))
+	return nil
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return parquetStore.NewSpanReader(f.primaryStore, f.logger), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	primary := f.Options.GetPrimary()
+	return f.createSpanWriter(f.primaryStore, primary.BatchSize, primary.BatchFlushInterval), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	sr, _ := f.CreateSpanReader() // err is always nil
+	return depStore.NewDependencyStore(sr), nil
+}
+
+// CreateArchiveSpanReader implements storage.ArchiveFactory
+func (f *Factory) CreateArchiveSpanReader() (spanstore.Reader, error) {
+	if f.archiveStore == nil {
+		return nil, nil
+	}
+	return parquetStore.NewSpanReader(f.archiveStore, f.logger), nil
+}
+
+// CreateArchiveSpanWriter implements storage.ArchiveFactory
+func (f *Factory) CreateArchiveSpanWriter() (spanstore.Writer, error) {
+	if f.archiveStore == nil {
+		return nil, nil
+	}
+	// archived traces must be readable as soon as they are written, so they are
+	// not batched, their files are compacted by the maintenance
+	return f.createSpanWriter(f.archiveStore, 0, 0), nil
+}
+
+func (f *Factory) createSpanWriter(store *parquetStore.Store, batchSize int, flushInterval time.Duration) *parquetStore.SpanWriter {
+	writer := parquetStore.NewSpanWriter(parquetStore.SpanWriterParams{
+		Store:              store,
+		Logger:             f.logger,
+		MetricsFactory:     f.metricsFactory,
+		BatchSize:          batchSize,
+		BatchFlushInterval: flushInterval,
+	})
+	f.writersMu.Lock()
+	f.writers = append(f.writers, writer)
+	f.writersMu.Unlock()
+	return writer
+}
+
+func (f *Factory) startMaintenance(store *parquetStore.Store, cfg NamespaceConfig) {
+	if cfg.MaintenanceInterval <= 0 {
+		return
+	}
+	f.maintenanceWG.Add(1)
+	go f.maintenance(store, cfg)
+}
+
+// maintenance periodically compacts the files of past hours and deletes the
+// spans past their retention.
+func (f *Factory) maintenance(store *parquetStore.Store, cfg NamespaceConfig) {
+	defer f.maintenanceWG.Done()
+	ticker := time.NewTicker(cfg.MaintenanceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.maintenanceDone:
+			return
+		case t := <-ticker.C:
+			if cfg.Retention > 0 {
+				if err := store.Purge(t.Add(-cfg.Retention)); err != nil {
+					f.logger.Error("Failed to delete the spans past their retention", zap.Error(err))
+				}
+			}
+			// spans of the hour that just ended are still buffered until the next flush
+			if err := store.Compact(t.Add(-cfg.BatchFlushInterval)); err != nil {
+				f.logger.Error("Failed to compact the Parquet files", zap.Error(err))
+			}
+			f.metrics.LastMaintenanceRun.Update(t.UnixNano())
+		}
+	}
+}
+
+// Close writes the spans still buffered by the writers and stops the maintenance.
+func (f *Factory) Close() error {
+	close(f.maintenanceDone)
+	f.maintenanceWG.Wait()
+
+	f.writersMu.Lock()
+	writers := f.writers
+	f.writers = nil
+	f.writersMu.Unlock()
+
+	var errs []error
+	for _, writer := range writers {
+		errs = append(errs, writer.Close())
+	}
+	return errors.Join(errs...)
+}
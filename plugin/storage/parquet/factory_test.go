@@ -0,0 +1,131 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/config"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+func newTestFactory(t *testing.T, args ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags(append([]string{"--parquet.directory=" + t.TempDir()}, args...)))
+	f.InitFromViper(v, zap.NewNop())
+	return f
+}
+
+func TestParquetFactory(t *testing.T) {
+	f := newTestFactory(t, "--parquet-archive.enabled=true", "--parquet.batch.flush-interval=0")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	reader, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	assert.NoError(t, err)
+	archiveReader, err := f.CreateArchiveSpanReader()
+	require.NoError(t, err)
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	archiveWriter, err := f.CreateArchiveSpanWriter()
+	require.NoError(t, err)
+
+	span := &model.Span{
+		TraceID:   model.NewTraceID(0, 1),
+		StartTime: time.Now(),
+		Process:   model.NewProcess("svc", nil),
+	}
+	require.NoError(t, writer.WriteSpan(context.Background(), span))
+	require.NoError(t, archiveWriter.WriteSpan(context.Background(), span))
+
+	_, err = reader.GetTrace(context.Background(), span.TraceID)
+	assert.Error(t, err, "spans are batched")
+	_, err = archiveReader.GetTrace(context.Background(), span.TraceID)
+	assert.NoError(t, err, "archived spans are not batched")
+	assert.DirExists(t, filepath.Join(f.Options.GetPrimary().Directory, "archive", "spans"))
+
+	require.NoError(t, f.Close())
+	_, err = reader.GetTrace(context.Background(), span.TraceID)
+	assert.NoError(t, err, "the batch is written on close")
+}
+
+func TestParquetFactoryWithoutArchive(t *testing.T) {
+	f := newTestFactory(t)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	defer f.Close()
+
+	reader, err := f.CreateArchiveSpanReader()
+	require.NoError(t, err)
+	assert.Nil(t, reader)
+	writer, err := f.CreateArchiveSpanWriter()
+	require.NoError(t, err)
+	assert.Nil(t, writer)
+}
+
+func TestParquetFactoryInitializationError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	f := newTestFactory(t, "--parquet.directory="+file)
+	assert.ErrorContains(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "failed to open the Parquet storage")
+	require.NoError(t, f.Close())
+
+	f = newTestFactory(t, "--parquet-archive.enabled=true", "--parquet-archive.directory="+file)
+	assert.ErrorContains(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "failed to open the Parquet archive storage")
+	require.NoError(t, f.Close())
+}
+
+func TestParquetFactoryMaintenance(t *testing.T) {
+	f := newTestFactory(t, "--parquet.maintenance-interval=1ms", "--parquet.retention=1h")
+	mf := metricstest.NewFactory(0)
+	require.NoError(t, f.Initialize(mf, zap.NewNop()))
+
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	span := &model.Span{
+		TraceID:   model.NewTraceID(0, 1),
+		StartTime: time.Now().Add(-3 * time.Hour),
+		Process:   model.NewProcess("svc", nil),
+	}
+	require.NoError(t, writer.WriteSpan(context.Background(), span))
+	require.NoError(t, writer.(interface{ Flush(context.Context) error }).Flush(context.Background()))
+
+	reader, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := reader.GetTrace(context.Background(), span.TraceID)
+		return err != nil
+	}, time.Second, time.Millisecond, "the spans past their retention are deleted")
+	assert.Eventually(t, func() bool {
+		_, gauges := mf.Snapshot()
+		return gauges[lastMaintenanceRunName] > 0
+	}, time.Second, time.Millisecond)
+	require.NoError(t, f.Close())
+}
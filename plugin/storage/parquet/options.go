@@ -0,0 +1,162 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Options store storage plugin related configs
+type Options struct {
+	Primary NamespaceConfig `mapstructure:",squash"`
+	Archive NamespaceConfig `mapstructure:"archive"`
+}
+
+// NamespaceConfig is the configuration of the Parquet files of a namespace
+type NamespaceConfig struct {
+	namespace string
+	// Directory holds the Parquet files, the trace ID index and the catalogue.
+	Directory string `mapstructure:"directory"`
+	// Retention is how long spans are kept, zero keeps them forever.
+	Retention time.Duration `mapstructure:"retention"`
+	// BatchSize is the number of spans written together into new files.
+	BatchSize int `mapstructure:"batch_size"`
+	// BatchFlushInterval is how often spans are written, even if the batch is not full.
+	BatchFlushInterval time.Duration `mapstructure:"batch_flush_interval"`
+	// MaintenanceInterval is how often files are compacted and spans past their retention deleted.
+	MaintenanceInterval time.Duration `mapstructure:"maintenance_interval"`
+	// Enabled is only used by the archive namespace.
+	Enabled bool `mapstructure:"enabled"`
+}
+
+const (
+	defaultRetention           time.Duration = 30 * 24 * time.Hour
+	defaultBatchSize                         = 100_000
+	defaultBatchFlushInterval  time.Duration = 30 * time.Second
+	defaultMaintenanceInterval time.Duration = 5 * time.Minute
+)
+
+const (
+	suffixDirectory           = ".directory"
+	suffixRetention           = ".retention"
+	suffixBatchSize           = ".batch.size"
+	suffixBatchFlushInterval  = ".batch.flush-interval"
+	suffixMaintenanceInterval = ".maintenance-interval"
+	suffixEnabled             = ".enabled"
+	defaultDataDir            = string(os.PathSeparator) + "data" + string(os.PathSeparator) + "parquet"
+	archiveDir                = "archive"
+)
+
+// NewOptions creates a new Options struct.
+func NewOptions(primaryNamespace, archiveNamespace string) *Options {
+	return &Options{
+		Primary: NamespaceConfig{
+			namespace:           primaryNamespace,
+			Directory:           getCurrentExecutableDir() + defaultDataDir,
+			Retention:           defaultRetention,
+			BatchSize:           defaultBatchSize,
+			BatchFlushInterval:  defaultBatchFlushInterval,
+			MaintenanceInterval: defaultMaintenanceInterval,
+			Enabled:             true,
+		},
+		// Archived traces are kept forever in a subdirectory of the primary directory unless configured otherwise.
+		Archive: NamespaceConfig{
+			namespace:           archiveNamespace,
+			MaintenanceInterval: defaultMaintenanceInterval,
+		},
+	}
+}
+
+func getCurrentExecutableDir() string {
+	// We ignore the error, this will fail later when trying to create the directory
+	exec, _ := os.Executable()
+	return filepath.Dir(exec)
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(
+		opt.Primary.namespace+suffixDirectory,
+		opt.Primary.Directory,
+		"Path to the directory of the Parquet files, which can be a mounted object store")
+	flagSet.Int(
+		opt.Primary.namespace+suffixBatchSize,
+		opt.Primary.BatchSize,
+		"The number of spans buffered before they are written into new Parquet files")
+	flagSet.Duration(
+		opt.Primary.namespace+suffixBatchFlushInterval,
+		opt.Primary.BatchFlushInterval,
+		"How often the buffered spans are written, even if the batch is not full. Spans are not searchable before they are written")
+	addFlags(flagSet, opt.Primary)
+
+	flagSet.Bool(
+		opt.Archive.namespace+suffixEnabled,
+		opt.Archive.Enabled,
+		"Enable extra storage")
+	flagSet.String(
+		opt.Archive.namespace+suffixDirectory,
+		opt.Archive.Directory,
+		"Path to the directory of the Parquet files of archived traces, defaults to the \""+archiveDir+"\" subdirectory of the primary directory")
+	addFlags(flagSet, opt.Archive)
+}
+
+func addFlags(flagSet *flag.FlagSet, nsConfig NamespaceConfig) {
+	flagSet.Duration(
+		nsConfig.namespace+suffixRetention,
+		nsConfig.Retention,
+		"How long to keep the spans, they are deleted by hour. A retention of zero keeps them forever. Format is time.Duration (https://golang.org/pkg/time/#Duration)")
+	flagSet.Duration(
+		nsConfig.namespace+suffixMaintenanceInterval,
+		nsConfig.MaintenanceInterval,
+		"How often the files of past hours are compacted and the spans past their retention deleted. Format is time.Duration (https://golang.org/pkg/time/#Duration)")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	primary, archive := &opt.Primary, &opt.Archive
+	primary.Directory = v.GetString(primary.namespace + suffixDirectory)
+	primary.BatchSize = v.GetInt(primary.namespace + suffixBatchSize)
+	primary.BatchFlushInterval = v.GetDuration(primary.namespace + suffixBatchFlushInterval)
+	initFromViper(primary, v)
+
+	archive.Enabled = v.GetBool(archive.namespace + suffixEnabled)
+	archive.Directory = v.GetString(archive.namespace + suffixDirectory)
+	initFromViper(archive, v)
+}
+
+func initFromViper(cfg *NamespaceConfig, v *viper.Viper) {
+	cfg.Retention = v.GetDuration(cfg.namespace + suffixRetention)
+	cfg.MaintenanceInterval = v.GetDuration(cfg.namespace + suffixMaintenanceInterval)
+}
+
+// GetPrimary returns the primary namespace configuration
+func (opt *Options) GetPrimary() NamespaceConfig {
+	return opt.Primary
+}
+
+// GetArchive returns the archive namespace configuration, with the directory
+// defaulting to a subdirectory of the primary one.
+func (opt *Options) GetArchive() NamespaceConfig {
+	archive := opt.Archive
+	if archive.Directory == "" {
+		archive.Directory = filepath.Join(opt.Primary.Directory, archiveDir)
+	}
+	return archive
+}
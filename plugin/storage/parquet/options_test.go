@@ -0,0 +1,73 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kjschnei001/jaeger/pkg/config"
+)
+
+func TestDefaultOptionsParsing(t *testing.T) {
+	opts := NewOptions(primaryNamespace, archiveNamespace)
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{})
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, getCurrentExecutableDir()+defaultDataDir, primary.Directory)
+	assert.Equal(t, 30*24*time.Hour, primary.Retention)
+	assert.Equal(t, 100_000, primary.BatchSize)
+	assert.Equal(t, 30*time.Second, primary.BatchFlushInterval)
+	assert.Equal(t, 5*time.Minute, primary.MaintenanceInterval)
+
+	archive := opts.GetArchive()
+	assert.False(t, archive.Enabled)
+	assert.Equal(t, filepath.Join(primary.Directory, "archive"), archive.Directory)
+	assert.Zero(t, archive.Retention)
+	assert.Equal(t, 5*time.Minute, archive.MaintenanceInterval)
+}
+
+func TestParseOptions(t *testing.T) {
+	opts := NewOptions(primaryNamespace, archiveNamespace)
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--parquet.directory=/mnt/traces",
+		"--parquet.retention=2160h",
+		"--parquet.batch.size=500",
+		"--parquet.batch.flush-interval=1m",
+		"--parquet.maintenance-interval=1h",
+		"--parquet-archive.enabled=true",
+		"--parquet-archive.directory=/mnt/archive",
+		"--parquet-archive.retention=8760h",
+	})
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, "/mnt/traces", primary.Directory)
+	assert.Equal(t, 2160*time.Hour, primary.Retention)
+	assert.Equal(t, 500, primary.BatchSize)
+	assert.Equal(t, time.Minute, primary.BatchFlushInterval)
+	assert.Equal(t, time.Hour, primary.MaintenanceInterval)
+
+	archive := opts.GetArchive()
+	assert.True(t, archive.Enabled)
+	assert.Equal(t, "/mnt/archive", archive.Directory)
+	assert.Equal(t, 8760*time.Hour, archive.Retention)
+}
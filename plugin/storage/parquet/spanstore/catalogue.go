@@ -0,0 +1,163 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// catalogue holds the services and operations of the stored spans, with the
+// last hour they were seen, so that they can be forgotten with the spans.
+// It is saved as JSON, and loaded when the store is opened.
+type catalogue struct {
+	path string
+
+	mu         sync.Mutex
+	operations map[string]map[spanstore.Operation]time.Time
+	dirty      bool
+}
+
+// catalogueEntry is the JSON representation of an operation of the catalogue.
+type catalogueEntry struct {
+	Service   string    `json:"service"`
+	Operation string    `json:"operation"`
+	SpanKind  string    `json:"span_kind"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+func loadCatalogue(path string) (*catalogue, error) {
+	c := &catalogue{
+		path:       path,
+		operations: make(map[string]map[spanstore.Operation]time.Time),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []catalogueEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		c.add(entry.Service, entry.Operation, entry.SpanKind, entry.LastSeen)
+	}
+	c.dirty = false
+	return c, nil
+}
+
+// add records that the operation of the service was seen during the hour.
+func (c *catalogue) add(service, operation, spanKind string, hour time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	operations, ok := c.operations[service]
+	if !ok {
+		operations = make(map[spanstore.Operation]time.Time)
+		c.operations[service] = operations
+	}
+	op := spanstore.Operation{Name: operation, SpanKind: spanKind}
+	if lastSeen, ok := operations[op]; !ok || hour.After(lastSeen) {
+		operations[op] = hour.UTC()
+		c.dirty = true
+	}
+}
+
+// purge forgets the operations last seen during hours ended before the given
+// time, and the services left without operations.
+func (c *catalogue) purge(before time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for service, operations := range c.operations {
+		for op, lastSeen := range operations {
+			if !lastSeen.Add(time.Hour).After(before) {
+				delete(operations, op)
+				c.dirty = true
+			}
+		}
+		if len(operations) == 0 {
+			delete(c.operations, service)
+		}
+	}
+}
+
+func (c *catalogue) services() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	services := make([]string, 0, len(c.operations))
+	for service := range c.operations {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+func (c *catalogue) getOperations(query spanstore.OperationQueryParameters) []spanstore.Operation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	operations := []spanstore.Operation{}
+	for op := range c.operations[query.ServiceName] {
+		if query.SpanKind == "" || query.SpanKind == op.SpanKind {
+			operations = append(operations, op)
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Name != operations[j].Name {
+			return operations[i].Name < operations[j].Name
+		}
+		return operations[i].SpanKind < operations[j].SpanKind
+	})
+	return operations
+}
+
+// save writes the catalogue if it changed since it was last saved.
+func (c *catalogue) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	entries := []catalogueEntry{}
+	for service, operations := range c.operations {
+		for op, lastSeen := range operations {
+			entries = append(entries, catalogueEntry{
+				Service:   service,
+				Operation: op.Name,
+				SpanKind:  op.SpanKind,
+				LastSeen:  lastSeen,
+			})
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path+tmpExt, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(c.path+tmpExt, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
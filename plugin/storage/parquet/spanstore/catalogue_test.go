@@ -0,0 +1,70 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+func TestCatalogue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), catalogueFile)
+	c, err := loadCatalogue(path)
+	require.NoError(t, err)
+	assert.Empty(t, c.services())
+
+	hour := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	c.add("svc", "op2", "server", hour)
+	c.add("svc", "op1", "client", hour)
+	c.add("svc", "op1", "server", hour.Add(-time.Hour))
+	c.add("other", "op", "", hour.Add(-time.Hour))
+	require.NoError(t, c.save())
+
+	c, err = loadCatalogue(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other", "svc"}, c.services())
+	assert.Equal(t, []spanstore.Operation{
+		{Name: "op1", SpanKind: "client"},
+		{Name: "op1", SpanKind: "server"},
+		{Name: "op2", SpanKind: "server"},
+	}, c.getOperations(spanstore.OperationQueryParameters{ServiceName: "svc"}))
+	assert.Equal(t, []spanstore.Operation{
+		{Name: "op1", SpanKind: "server"},
+		{Name: "op2", SpanKind: "server"},
+	}, c.getOperations(spanstore.OperationQueryParameters{ServiceName: "svc", SpanKind: "server"}))
+	assert.Empty(t, c.getOperations(spanstore.OperationQueryParameters{ServiceName: "unknown"}))
+
+	// the operations of the hours ended before 10:00 are forgotten
+	c.purge(hour)
+	assert.Equal(t, []string{"svc"}, c.services())
+	assert.Equal(t, []spanstore.Operation{
+		{Name: "op1", SpanKind: "client"},
+		{Name: "op2", SpanKind: "server"},
+	}, c.getOperations(spanstore.OperationQueryParameters{ServiceName: "svc"}))
+}
+
+func TestCatalogueCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), catalogueFile)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err := loadCatalogue(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbmodel
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// Span is a row of a Parquet file.
+//
+// The columns other than Span are only used to search traces, and can be read
+// without the span itself. The span is stored as protobuf. The unsigned IDs
+// are stored as INT64 columns.
+type Span struct {
+	TraceIDHigh int64  `parquet:"name=trace_id_high, type=INT64"`
+	TraceIDLow  int64  `parquet:"name=trace_id_low, type=INT64"`
+	SpanID      int64  `parquet:"name=span_id, type=INT64"`
+	Service     string `parquet:"name=service, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Operation   string `parquet:"name=operation, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	SpanKind    string `parquet:"name=span_kind, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	// StartTime is the start time of the span in microseconds since epoch.
+	StartTime int64 `parquet:"name=start_time, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	// Duration is the duration of the span in microseconds.
+	Duration int64  `parquet:"name=duration, type=INT64"`
+	Span     string `parquet:"name=span, type=BYTE_ARRAY"`
+}
+
+// SearchSpan is the projection of Span read to search traces.
+type SearchSpan struct {
+	TraceIDHigh int64  `parquet:"name=trace_id_high, type=INT64"`
+	TraceIDLow  int64  `parquet:"name=trace_id_low, type=INT64"`
+	Operation   string `parquet:"name=operation, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	StartTime   int64  `parquet:"name=start_time, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	Duration    int64  `parquet:"name=duration, type=INT64"`
+}
+
+// TraceID returns the trace ID of the row.
+func (s *Span) TraceID() model.TraceID {
+	return model.NewTraceID(uint64(s.TraceIDHigh), uint64(s.TraceIDLow))
+}
+
+// TraceID returns the trace ID of the row.
+func (s *SearchSpan) TraceID() model.TraceID {
+	return model.NewTraceID(uint64(s.TraceIDHigh), uint64(s.TraceIDLow))
+}
+
+// FromDomain converts a span to a row.
+func FromDomain(span *model.Span) (*Span, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return nil, err
+	}
+	spanKind, _ := span.GetSpanKind()
+	row := &Span{
+		TraceIDHigh: int64(span.TraceID.High),
+		TraceIDLow:  int64(span.TraceID.Low),
+		SpanID:      int64(span.SpanID),
+		Operation:   span.OperationName,
+		SpanKind:    spanKind,
+		StartTime:   int64(model.TimeAsEpochMicroseconds(span.StartTime)),
+		Duration:    int64(model.DurationAsMicroseconds(span.Duration)),
+		Span:        string(data),
+	}
+	if span.Process != nil {
+		row.Service = span.Process.ServiceName
+	}
+	return row, nil
+}
+
+// ToDomain decodes the span stored in a row.
+func ToDomain(row *Span) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal([]byte(row.Span), span); err != nil {
+		return nil, err
+	}
+	return span, nil
+}
+
+// StartTime converts the start time of a row to a time.Time.
+func StartTime(micros int64) time.Time {
+	return model.EpochMicrosecondsAsTime(uint64(micros))
+}
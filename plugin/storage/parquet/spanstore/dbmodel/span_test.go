@@ -0,0 +1,57 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+func TestFromDomain(t *testing.T) {
+	span := &model.Span{
+		TraceID:       model.NewTraceID(1<<63, 2),
+		SpanID:        model.SpanID(1 << 63),
+		OperationName: "op",
+		StartTime:     time.Date(2023, 5, 1, 10, 0, 0, 1000, time.UTC),
+		Duration:      time.Millisecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server")},
+		Process:       model.NewProcess("svc", nil),
+	}
+	row, err := FromDomain(span)
+	require.NoError(t, err)
+	assert.Equal(t, model.NewTraceID(1<<63, 2), row.TraceID())
+	assert.Equal(t, "svc", row.Service)
+	assert.Equal(t, "op", row.Operation)
+	assert.Equal(t, "server", row.SpanKind)
+	assert.EqualValues(t, 1000, row.Duration)
+	assert.Equal(t, span.StartTime, StartTime(row.StartTime).UTC())
+
+	decoded, err := ToDomain(row)
+	require.NoError(t, err)
+	assert.Equal(t, span, decoded)
+
+	_, err = ToDomain(&Span{Span: "\xff"})
+	assert.Error(t, err)
+}
+
+func TestSearchSpanTraceID(t *testing.T) {
+	s := &SearchSpan{TraceIDHigh: -1, TraceIDLow: 1}
+	assert.Equal(t, model.NewTraceID(1<<64-1, 1), s.TraceID())
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore/dbmodel"
+)
+
+// The trace ID index of an hour tells which services have spans of a trace in
+// the partitions of the hour. It is a sequence of entries made of the trace ID,
+// as two big endian uint64, followed by the uvarint length of the service name
+// and the service name.
+
+// indexEntry is an entry of the trace ID index.
+type indexEntry struct {
+	traceID model.TraceID
+	service string
+}
+
+// appendIndex appends an entry for every trace of the rows to the index file.
+func appendIndex(path string, service string, rows []dbmodel.Span) error {
+	seen := make(map[model.TraceID]struct{})
+	var entries []indexEntry
+	for i := range rows {
+		traceID := rows[i].TraceID()
+		if _, ok := seen[traceID]; !ok {
+			seen[traceID] = struct{}{}
+			entries = append(entries, indexEntry{traceID: traceID, service: service})
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(encodeIndex(entries))
+	return errors.Join(err, f.Close())
+}
+
+// readIndex reads the entries of an index file, a missing file has no entries.
+func readIndex(path string) ([]indexEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, err := decodeIndex(data)
+	if err != nil {
+		return nil, fmt.Errorf("corrupted index %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// rewriteIndex replaces an index file with its entries without duplicates.
+func rewriteIndex(path string) error {
+	entries, err := readIndex(path)
+	if err != nil || entries == nil {
+		return err
+	}
+	seen := make(map[indexEntry]struct{}, len(entries))
+	unique := entries[:0]
+	for _, entry := range entries {
+		if _, ok := seen[entry]; !ok {
+			seen[entry] = struct{}{}
+			unique = append(unique, entry)
+		}
+	}
+	if len(unique) == len(entries) {
+		return nil
+	}
+	if err := os.WriteFile(path+tmpExt, encodeIndex(unique), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+tmpExt, path)
+}
+
+func encodeIndex(entries []indexEntry) []byte {
+	var data []byte
+	for _, entry := range entries {
+		data = binary.BigEndian.AppendUint64(data, entry.traceID.High)
+		data = binary.BigEndian.AppendUint64(data, entry.traceID.Low)
+		data = binary.AppendUvarint(data, uint64(len(entry.service)))
+		data = append(data, entry.service...)
+	}
+	return data
+}
+
+func decodeIndex(data []byte) ([]indexEntry, error) {
+	var entries []indexEntry
+	for len(data) > 0 {
+		if len(data) < 16 {
+			return nil, errors.New("truncated trace ID")
+		}
+		entry := indexEntry{traceID: model.NewTraceID(
+			binary.BigEndian.Uint64(data[:8]),
+			binary.BigEndian.Uint64(data[8:16]),
+		)}
+		data = data[16:]
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length {
+			return nil, errors.New("truncated service name")
+		}
+		entry.service = string(data[n : n+int(length)])
+		data = data[n+int(length):]
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
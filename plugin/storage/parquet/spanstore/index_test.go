@@ -0,0 +1,69 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore/dbmodel"
+)
+
+func TestIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), indexFileName)
+	entries, err := readIndex(path)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	rows := []dbmodel.Span{
+		{TraceIDHigh: 1, TraceIDLow: 2},
+		{TraceIDHigh: 1, TraceIDLow: 2},
+		{TraceIDHigh: -1, TraceIDLow: 3},
+	}
+	require.NoError(t, appendIndex(path, "svc", rows))
+	require.NoError(t, appendIndex(path, "service/é", rows[:1]))
+	entries, err = readIndex(path)
+	require.NoError(t, err)
+	assert.Equal(t, []indexEntry{
+		{traceID: model.NewTraceID(1, 2), service: "svc"},
+		{traceID: model.NewTraceID(1<<64-1, 3), service: "svc"},
+		{traceID: model.NewTraceID(1, 2), service: "service/é"},
+	}, entries)
+
+	require.NoError(t, appendIndex(path, "svc", rows[:1]))
+	require.NoError(t, rewriteIndex(path))
+	rewritten, err := readIndex(path)
+	require.NoError(t, err)
+	assert.Equal(t, entries, rewritten)
+}
+
+func TestIndexCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), indexFileName)
+	data := encodeIndex([]indexEntry{{traceID: model.NewTraceID(1, 2), service: "svc"}})
+
+	require.NoError(t, os.WriteFile(path, data[:10], 0o600))
+	_, err := readIndex(path)
+	assert.ErrorContains(t, err, "truncated trace ID")
+
+	require.NoError(t, os.WriteFile(path, data[:len(data)-1], 0o600))
+	_, err = readIndex(path)
+	assert.ErrorContains(t, err, "truncated service name")
+	assert.Error(t, rewriteIndex(path))
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore/dbmodel"
+)
+
+// Compact merges the files of every partition of the hours ended before the
+// given time into a single file, and removes the duplicates of their trace ID index.
+// Spans written later to a compacted hour are merged by the next compaction.
+func (s *Store) Compact(before time.Time) error {
+	hours, err := s.hours()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, hour := range hours {
+		if hour.Add(time.Hour).After(before) {
+			continue
+		}
+		services, err := s.services(hour)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		compacted := false
+		for _, service := range services {
+			merged, err := s.compactPartition(partition{hour: hour, service: service})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cannot compact the spans of %s at %s: %w", service, hour.Format(hourLayout), err))
+			}
+			compacted = compacted || merged
+		}
+		if compacted {
+			s.mu.Lock()
+			err := rewriteIndex(filepath.Join(s.hourDir(hour), indexFileName))
+			s.mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cannot compact the trace ID index at %s: %w", hour.Format(hourLayout), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compactPartition merges the files of a partition, it reports whether there
+// was more than one file to merge.
+func (s *Store) compactPartition(p partition) (bool, error) {
+	files, err := s.files(p)
+	if err != nil || len(files) < 2 {
+		return false, err
+	}
+	var rows []dbmodel.Span
+	for _, file := range files {
+		err := readFile(file, func(batch []dbmodel.Span) error {
+			rows = append(rows, batch...)
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	sortRows(rows)
+	path, err := s.writeFile(s.partitionDir(p), rows)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Rename(path+tmpExt, path); err != nil {
+		return false, errors.Join(err, os.Remove(path+tmpExt))
+	}
+	for i, file := range files {
+		if err := os.Remove(file); err != nil {
+			if i == 0 {
+				return false, errors.Join(err, os.Remove(path))
+			}
+			// the merged file holds the only copy of the spans of the removed files
+			return false, err
+		}
+	}
+	s.logger.Debug("Compacted partition",
+		zap.String("service", p.service),
+		zap.Time("hour", p.hour),
+		zap.Int("files", len(files)),
+		zap.Int("spans", len(rows)))
+	return true, nil
+}
+
+// Purge deletes the spans that started during the hours ended before the given
+// time, and forgets the operations not seen since.
+func (s *Store) Purge(before time.Time) error {
+	hours, err := s.hours()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, hour := range hours {
+		if hour.Add(time.Hour).After(before) {
+			continue
+		}
+		s.mu.Lock()
+		err := os.RemoveAll(s.hourDir(hour))
+		s.mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.catalogue.purge(before)
+	return errors.Join(append(errs, s.catalogue.save())...)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+func TestCompact(t *testing.T) {
+	store := newTestStore(t)
+	writeTestSpans(t, store,
+		testSpan(1, 2, "svc", testHour.Add(time.Minute)),
+		testSpan(1, 1, "svc", testHour),
+		testSpan(2, 1, "svc", testHour.Add(time.Hour)),
+		testSpan(2, 2, "svc", testHour.Add(time.Hour)),
+	)
+	require.Len(t, partitionFiles(t, store, testHour, "svc"), 2)
+
+	// only the hours ended before are compacted
+	require.NoError(t, store.Compact(testHour.Add(90*time.Minute)))
+	assert.Len(t, partitionFiles(t, store, testHour, "svc"), 1)
+	assert.Len(t, partitionFiles(t, store, testHour.Add(time.Hour), "svc"), 2)
+
+	entries, err := readIndex(filepath.Join(store.hourDir(testHour), indexFileName))
+	require.NoError(t, err)
+	assert.Equal(t, []indexEntry{{traceID: model.NewTraceID(0, 1), service: "svc"}}, entries)
+
+	trace, err := NewSpanReader(store, zap.NewNop()).GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 2)
+	// the spans are sorted by start time
+	assert.Equal(t, model.SpanID(1), trace.Spans[0].SpanID)
+
+	// a single file is not rewritten
+	files := partitionFiles(t, store, testHour, "svc")
+	require.NoError(t, store.Compact(testHour.Add(90*time.Minute)))
+	assert.Equal(t, files, partitionFiles(t, store, testHour, "svc"))
+}
+
+func TestCompactCorruptedFile(t *testing.T) {
+	store := newTestStore(t)
+	writeTestSpans(t, store, testSpan(1, 1, "svc", testHour), testSpan(1, 2, "svc", testHour))
+	files := partitionFiles(t, store, testHour, "svc")
+	require.NoError(t, os.WriteFile(files[0], []byte("not parquet"), 0o600))
+
+	assert.ErrorContains(t, store.Compact(testHour.Add(time.Hour)), "cannot compact the spans of svc")
+	assert.Equal(t, files, partitionFiles(t, store, testHour, "svc"))
+}
+
+func TestPurge(t *testing.T) {
+	store := newTestStore(t)
+	writeTestSpans(t, store,
+		testSpan(1, 1, "old", testHour),
+		testSpan(1, 2, "svc", testHour),
+		testSpan(2, 1, "svc", testHour.Add(time.Hour)),
+	)
+
+	require.NoError(t, store.Purge(testHour.Add(90*time.Minute)))
+	hours, err := store.hours()
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{testHour.Add(time.Hour)}, hours)
+	assert.Equal(t, []string{"svc"}, store.catalogue.services())
+
+	r := NewSpanReader(store, zap.NewNop())
+	_, err = r.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+
+	// the catalogue is saved without the purged services
+	reopened, err := NewStore(store.dir, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc"}, reopened.catalogue.services())
+}
@@ -0,0 +1,350 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore/dbmodel"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service name must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("min start time is above max")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("min duration is above max")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and end time must be set")
+)
+
+var _ spanstore.BatchReader = (*SpanReader)(nil)
+
+// SpanReader reads spans from the Parquet files of a Store.
+type SpanReader struct {
+	store  *Store
+	logger *zap.Logger
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(store *Store, logger *zap.Logger) *SpanReader {
+	return &SpanReader{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces, err := r.GetTraces(ctx, []model.TraceID{traceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return traces[0], nil
+}
+
+// GetTraces implements spanstore.BatchReader#GetTraces. The trace ID index of
+// every hour is read to find the partitions holding spans of the traces, only
+// these partitions are read.
+func (r *SpanReader) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	if len(traceIDs) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[model.TraceID]struct{}, len(traceIDs))
+	for _, traceID := range traceIDs {
+		wanted[traceID] = struct{}{}
+	}
+	partitions, err := r.tracePartitions(wanted)
+	if err != nil {
+		return nil, err
+	}
+
+	var spans []*model.Span
+	for _, p := range partitions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		err := readPartition(r.store, p, func(rows []dbmodel.Span) error {
+			for i := range rows {
+				if _, ok := wanted[rows[i].TraceID()]; !ok {
+					continue
+				}
+				span, err := dbmodel.ToDomain(&rows[i])
+				if err != nil {
+					return err
+				}
+				spans = append(spans, span)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spanstore.TracesInOrder(spans, traceIDs), nil
+}
+
+// tracePartitions returns the partitions holding spans of the traces.
+func (r *SpanReader) tracePartitions(traceIDs map[model.TraceID]struct{}) ([]partition, error) {
+	hours, err := r.store.hours()
+	if err != nil {
+		return nil, err
+	}
+	var partitions []partition
+	for _, hour := range hours {
+		r.store.mu.RLock()
+		entries, err := readIndex(filepath.Join(r.store.hourDir(hour), indexFileName))
+		r.store.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]struct{})
+		for _, entry := range entries {
+			if _, ok := traceIDs[entry.traceID]; !ok {
+				continue
+			}
+			if _, ok := seen[entry.service]; !ok {
+				seen[entry.service] = struct{}{}
+				partitions = append(partitions, partition{hour: hour, service: entry.service})
+			}
+		}
+	}
+	return partitions, nil
+}
+
+// readPartition reads the rows of all the files of a partition, T is either
+// dbmodel.Span or one of its projections.
+func readPartition[T any](s *Store, p partition, fn func(rows []T) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	files, err := s.files(p)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := readFile(file, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	return r.store.catalogue.services(), nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(
+	ctx context.Context,
+	query spanstore.OperationQueryParameters,
+) ([]spanstore.Operation, error) {
+	return r.store.catalogue.getOperations(query), nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetTraces(ctx, traceIDs)
+}
+
+// traceMatch is a trace found by FindTraceIDs, with the start time of its
+// most recent matching span in microseconds.
+type traceMatch struct {
+	traceID   model.TraceID
+	startTime int64
+}
+
+// FindTraceIDs retrieves traceIDs that match the traceQuery, the most recent
+// first. All of them are returned if query.NumTraces is not positive.
+//
+// Only the partitions of the queried service and time range are read, the most
+// recent first, and only the columns needed by the query. The spans themselves
+// are only decoded to evaluate tags and tag filters.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	partitions, err := r.store.partitions(query.StartTimeMin, query.StartTimeMax, query.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	// traces matching tags with different spans are checked once they are all found
+	anySpanTags := query.TagConditionCount() > 0 && query.TagMatchMode == spanstore.TagMatchAnySpanInTrace
+	sameSpanTags := query.TagConditionCount() > 0 && !anySpanTags
+	matches := make(map[model.TraceID]int64)
+	match := func(traceID model.TraceID, startTime int64) {
+		if latest, ok := matches[traceID]; !ok || startTime > latest {
+			matches[traceID] = startTime
+		}
+	}
+	for i, p := range partitions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// The partitions are read by hour, the most recent first. The traces found
+		// in the next hours cannot be more recent than the ones already found.
+		if i > 0 && !p.hour.Equal(partitions[i-1].hour) && !anySpanTags &&
+			query.NumTraces > 0 && len(matches) >= query.NumTraces {
+			break
+		}
+		if sameSpanTags {
+			err = readPartition(r.store, p, func(rows []dbmodel.Span) error {
+				for i := range rows {
+					if !matchSearchSpan(query, rows[i].Operation, rows[i].StartTime, rows[i].Duration) {
+						continue
+					}
+					span, err := dbmodel.ToDomain(&rows[i])
+					if err != nil {
+						return err
+					}
+					if spanstore.MatchSpanTags(span, query.Tags, query.TagFilters) {
+						match(span.TraceID, rows[i].StartTime)
+					}
+				}
+				return nil
+			})
+		} else {
+			err = readPartition(r.store, p, func(rows []dbmodel.SearchSpan) error {
+				for i := range rows {
+					if matchSearchSpan(query, rows[i].Operation, rows[i].StartTime, rows[i].Duration) {
+						match(rows[i].TraceID(), rows[i].StartTime)
+					}
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sorted := make([]traceMatch, 0, len(matches))
+	for traceID, startTime := range matches {
+		sorted = append(sorted, traceMatch{traceID: traceID, startTime: startTime})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].startTime != sorted[j].startTime {
+			return sorted[i].startTime > sorted[j].startTime
+		}
+		if sorted[i].traceID.High != sorted[j].traceID.High {
+			return sorted[i].traceID.High > sorted[j].traceID.High
+		}
+		return sorted[i].traceID.Low > sorted[j].traceID.Low
+	})
+	if anySpanTags {
+		return r.matchTraceTags(ctx, query, sorted)
+	}
+
+	var traceIDs []model.TraceID
+	for _, m := range sorted {
+		if query.NumTraces > 0 && len(traceIDs) == query.NumTraces {
+			break
+		}
+		traceIDs = append(traceIDs, m.traceID)
+	}
+	return traceIDs, nil
+}
+
+// matchTraceTags loads the traces in order, and returns the IDs of the first
+// query.NumTraces ones satisfying the tags and tag filters of the query.
+func (r *SpanReader) matchTraceTags(ctx context.Context, query *spanstore.TraceQueryParameters, matches []traceMatch) ([]model.TraceID, error) {
+	batchSize := query.NumTraces
+	if batchSize <= 0 {
+		batchSize = len(matches)
+	}
+	var traceIDs []model.TraceID
+	for len(matches) > 0 {
+		var batch []model.TraceID
+		for len(batch) < batchSize && len(matches) > 0 {
+			batch = append(batch, matches[0].traceID)
+			matches = matches[1:]
+		}
+		traces, err := r.GetTraces(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			if !spanstore.MatchTraceTags(trace, query) {
+				continue
+			}
+			traceIDs = append(traceIDs, spanstore.TraceIDOf(trace))
+			if len(traceIDs) == query.NumTraces {
+				return traceIDs, nil
+			}
+		}
+	}
+	return traceIDs, nil
+}
+
+// matchSearchSpan reports whether a span satisfies the operation, start time
+// and duration of the query, its service is the one of its partition.
+func matchSearchSpan(query *spanstore.TraceQueryParameters, operation string, startTime int64, duration int64) bool {
+	if query.OperationName != "" && operation != query.OperationName {
+		return false
+	}
+	start := dbmodel.StartTime(startTime)
+	if start.Before(query.StartTimeMin) || start.After(query.StartTimeMax) {
+		return false
+	}
+	if query.DurationMin != 0 && duration < int64(model.DurationAsMicroseconds(query.DurationMin)) {
+		return false
+	}
+	if query.DurationMax != 0 && duration > int64(model.DurationAsMicroseconds(query.DurationMax)) {
+		return false
+	}
+	return true
+}
+
+// validateQuery returns an error if certain restrictions are not met
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" && (p.TagConditionCount() > 0 || p.OperationName != "") {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// writeTestSpans writes spans with a writer that flushes every span, so that
+// every span is in its own file.
+func writeTestSpans(t *testing.T, store *Store, spans ...*model.Span) {
+	w, _ := newTestWriter(store, 0, 0)
+	for _, span := range spans {
+		require.NoError(t, w.WriteSpan(context.Background(), span))
+	}
+	require.NoError(t, w.Close())
+}
+
+func traceIDs(ids ...uint64) []model.TraceID {
+	var traceIDs []model.TraceID
+	for _, id := range ids {
+		traceIDs = append(traceIDs, model.NewTraceID(0, id))
+	}
+	return traceIDs
+}
+
+func TestGetTraces(t *testing.T) {
+	store := newTestStore(t)
+	writeTestSpans(t, store,
+		testSpan(1, 1, "svc", testHour.Add(59*time.Minute)),
+		testSpan(1, 2, "other", testHour.Add(61*time.Minute)),
+		testSpan(2, 1, "svc", testHour),
+		testSpan(3, 1, "svc", testHour),
+	)
+	r := NewSpanReader(store, zap.NewNop())
+
+	trace, err := r.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 2)
+	assert.Equal(t, testSpan(1, 1, "svc", testHour.Add(59*time.Minute)).Process, trace.Spans[1].Process)
+
+	traces, err := r.GetTraces(context.Background(), traceIDs(3, 4, 2, 3))
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 3), spanstore.TraceIDOf(traces[0]))
+	assert.Equal(t, model.NewTraceID(0, 2), spanstore.TraceIDOf(traces[1]))
+
+	_, err = r.GetTrace(context.Background(), model.NewTraceID(0, 4))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+
+	traces, err = r.GetTraces(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = r.GetTraces(ctx, traceIDs(1))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	store := newTestStore(t)
+	writeTestSpans(t, store, testSpan(1, 1, "svc", testHour), testSpan(1, 2, "other", testHour))
+	r := NewSpanReader(store, zap.NewNop())
+
+	services, err := r.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other", "svc"}, services)
+
+	operations, err := r.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "op", SpanKind: "server"}}, operations)
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	store := newTestStore(t)
+	slow := testSpan(4, 1, "svc", testHour.Add(2*time.Hour))
+	slow.Duration = time.Second
+	tagged := testSpan(5, 1, "svc", testHour.Add(-time.Hour))
+	tagged.OperationName = "tagged"
+	tagged.Tags = append(tagged.Tags, model.String("http.method", "GET"), model.Int64("http.status_code", 503))
+	otherTagged := testSpan(5, 2, "svc", testHour.Add(-time.Hour))
+	otherTagged.Tags = append(otherTagged.Tags, model.String("error", "true"))
+	writeTestSpans(t, store,
+		testSpan(1, 1, "svc", testHour),
+		testSpan(1, 2, "svc", testHour.Add(3*time.Hour)),
+		testSpan(2, 1, "svc", testHour.Add(time.Hour)),
+		testSpan(3, 1, "other", testHour.Add(time.Hour)),
+		slow, tagged, otherTagged,
+	)
+	r := NewSpanReader(store, zap.NewNop())
+	statusFilter, err := spanstore.ParseTagFilter("http.status_code>=500")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		caption  string
+		query    spanstore.TraceQueryParameters
+		expected []model.TraceID
+	}{
+		{
+			caption:  "most recent first",
+			query:    spanstore.TraceQueryParameters{ServiceName: "svc"},
+			expected: traceIDs(1, 4, 2, 5),
+		},
+		{
+			caption:  "all services",
+			query:    spanstore.TraceQueryParameters{},
+			expected: traceIDs(1, 4, 3, 2, 5),
+		},
+		{
+			caption:  "limit",
+			query:    spanstore.TraceQueryParameters{ServiceName: "svc", NumTraces: 2},
+			expected: traceIDs(1, 4),
+		},
+		{
+			caption: "time range",
+			query: spanstore.TraceQueryParameters{
+				ServiceName:  "svc",
+				StartTimeMin: testHour.Add(time.Hour),
+				StartTimeMax: testHour.Add(2 * time.Hour),
+			},
+			expected: traceIDs(4, 2),
+		},
+		{
+			caption:  "operation",
+			query:    spanstore.TraceQueryParameters{ServiceName: "svc", OperationName: "tagged"},
+			expected: traceIDs(5),
+		},
+		{
+			caption:  "duration",
+			query:    spanstore.TraceQueryParameters{ServiceName: "svc", DurationMin: time.Second, DurationMax: time.Minute},
+			expected: traceIDs(4),
+		},
+		{
+			caption:  "max duration",
+			query:    spanstore.TraceQueryParameters{ServiceName: "other", DurationMax: time.Millisecond},
+			expected: traceIDs(3),
+		},
+		{
+			caption: "tags and tag filters",
+			query: spanstore.TraceQueryParameters{
+				ServiceName: "svc",
+				Tags:        map[string]string{"http.method": "GET"},
+				TagFilters:  []*spanstore.Predicate{statusFilter},
+			},
+			expected: traceIDs(5),
+		},
+		{
+			caption: "tags of different spans",
+			query: spanstore.TraceQueryParameters{
+				ServiceName: "svc",
+				Tags:        map[string]string{"http.method": "GET", "error": "true"},
+			},
+		},
+		{
+			caption: "tags of different spans in the trace",
+			query: spanstore.TraceQueryParameters{
+				ServiceName:  "svc",
+				Tags:         map[string]string{"http.method": "GET", "error": "true"},
+				TagMatchMode: spanstore.TagMatchAnySpanInTrace,
+				NumTraces:    1,
+			},
+			expected: traceIDs(5),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.caption, func(t *testing.T) {
+			query := testCase.query
+			if query.StartTimeMin.IsZero() {
+				query.StartTimeMin = testHour.Add(-24 * time.Hour)
+				query.StartTimeMax = testHour.Add(24 * time.Hour)
+			}
+			found, err := r.FindTraceIDs(context.Background(), &query)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, found)
+		})
+	}
+
+	traces, err := r.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testHour,
+		StartTimeMax: testHour.Add(30 * time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Len(t, traces[0].Spans, 2)
+}
+
+func TestFindTraceIDsValidation(t *testing.T) {
+	r := NewSpanReader(newTestStore(t), zap.NewNop())
+	now := time.Now()
+	testCases := []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{query: nil, err: ErrMalformedRequestObject},
+		{query: &spanstore.TraceQueryParameters{OperationName: "op"}, err: ErrServiceNameNotSet},
+		{query: &spanstore.TraceQueryParameters{ServiceName: "svc"}, err: ErrStartAndEndTimeNotSet},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: now, StartTimeMax: now.Add(-time.Hour)},
+			err:   ErrStartTimeMinGreaterThanMax,
+		},
+		{
+			query: &spanstore.TraceQueryParameters{
+				ServiceName: "svc", StartTimeMin: now, StartTimeMax: now,
+				DurationMin: time.Second, DurationMax: time.Millisecond,
+			},
+			err: ErrDurationMinGreaterThanMax,
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := r.FindTraces(context.Background(), testCase.query)
+		assert.Equal(t, testCase.err, err)
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore/dbmodel"
+)
+
+// The spans are stored in Hive-style partitions, so that other tools can query them:
+//
+//	<directory>/spans/hour=2023-05-01T10/service=frontend/part-<n>.parquet
+//	<directory>/spans/hour=2023-05-01T10/trace_ids.idx
+//	<directory>/catalogue.json
+const (
+	spansDir      = "spans"
+	hourPrefix    = "hour="
+	hourLayout    = "2006-01-02T15"
+	servicePrefix = "service="
+	partPrefix    = "part-"
+	parquetExt    = ".parquet"
+	tmpExt        = ".tmp"
+	indexFileName = "trace_ids.idx"
+	catalogueFile = "catalogue.json"
+
+	// readBatchSize is the number of rows read at once
+	readBatchSize = 1024
+)
+
+// partition identifies the spans of a service that started during an hour.
+type partition struct {
+	hour    time.Time
+	service string
+}
+
+func partitionOf(row *dbmodel.Span) partition {
+	return partition{
+		hour:    dbmodel.StartTime(row.StartTime).Truncate(time.Hour),
+		service: row.Service,
+	}
+}
+
+// Store manages the Parquet files, the trace ID index and the catalogue of
+// services and operations kept in a directory. It is shared by the span
+// readers and writers of the directory.
+type Store struct {
+	dir       string
+	logger    *zap.Logger
+	catalogue *catalogue
+
+	// mu is held for writing while files are replaced or deleted, and for
+	// reading while they are read, so that readers never see a partition
+	// half compacted.
+	mu      sync.RWMutex
+	fileSeq atomic.Uint64
+}
+
+// NewStore opens the store kept in dir, creating the directory if needed.
+func NewStore(dir string, logger *zap.Logger) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, spansDir), 0o700); err != nil {
+		return nil, err
+	}
+	catalogue, err := loadCatalogue(filepath.Join(dir, catalogueFile))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the catalogue: %w", err)
+	}
+	return &Store{
+		dir:       dir,
+		logger:    logger,
+		catalogue: catalogue,
+	}, nil
+}
+
+func (s *Store) hourDir(hour time.Time) string {
+	return filepath.Join(s.dir, spansDir, hourPrefix+hour.UTC().Format(hourLayout))
+}
+
+func (s *Store) partitionDir(p partition) string {
+	return filepath.Join(s.hourDir(p.hour), servicePrefix+url.PathEscape(p.service))
+}
+
+// hours returns the hours that have partitions, the most recent first.
+func (s *Store) hours() ([]time.Time, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, spansDir))
+	if err != nil {
+		return nil, err
+	}
+	var hours []time.Time
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), hourPrefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		hour, err := time.Parse(hourLayout, name)
+		if err != nil {
+			continue
+		}
+		hours = append(hours, hour)
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i].After(hours[j]) })
+	return hours, nil
+}
+
+// services returns the services that have a partition in the hour.
+func (s *Store) services(hour time.Time) ([]string, error) {
+	entries, err := os.ReadDir(s.hourDir(hour))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), servicePrefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		service, err := url.PathUnescape(name)
+		if err != nil {
+			continue
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// partitions returns the partitions of the hours between min and max, included,
+// the most recent first. If service is not empty, only its partitions are returned.
+func (s *Store) partitions(min, max time.Time, service string) ([]partition, error) {
+	hours, err := s.hours()
+	if err != nil {
+		return nil, err
+	}
+	var partitions []partition
+	for _, hour := range hours {
+		if (!max.IsZero() && hour.After(max)) || (!min.IsZero() && !hour.Add(time.Hour).After(min)) {
+			continue
+		}
+		services, err := s.services(hour)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range services {
+			if service == "" || svc == service {
+				partitions = append(partitions, partition{hour: hour, service: svc})
+			}
+		}
+	}
+	return partitions, nil
+}
+
+// files returns the Parquet files of a partition.
+func (s *Store) files(p partition) ([]string, error) {
+	dir := s.partitionDir(p)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), partPrefix) && strings.HasSuffix(entry.Name(), parquetExt) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// writePartition writes rows of a partition into a new Parquet file, then
+// adds them to the trace ID index and the catalogue.
+func (s *Store) writePartition(p partition, rows []dbmodel.Span) error {
+	dir := s.partitionDir(p)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	sortRows(rows)
+	path, err := s.writeFile(dir, rows)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(path+tmpExt, path); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	err = appendIndex(filepath.Join(s.hourDir(p.hour), indexFileName), p.service, rows)
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cannot update the trace ID index: %w", err)
+	}
+
+	for i := range rows {
+		s.catalogue.add(rows[i].Service, rows[i].Operation, rows[i].SpanKind, p.hour)
+	}
+	return s.catalogue.save()
+}
+
+// sortRows sorts rows by start time, so that the row groups of a file cover
+// narrow time ranges.
+func sortRows(rows []dbmodel.Span) {
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].StartTime < rows[j].StartTime })
+}
+
+// writeFile writes rows into a new Parquet file of dir and returns its path.
+// The file is written under the temporary name path+tmpExt, and must be renamed
+// by the caller, so that it is never read incomplete.
+func (s *Store) writeFile(dir string, rows []dbmodel.Span) (string, error) {
+	name := fmt.Sprintf("%s%d-%d%s", partPrefix, time.Now().UnixNano(), s.fileSeq.Add(1), parquetExt)
+	path := filepath.Join(dir, name)
+	f, err := local.NewLocalFileWriter(path + tmpExt)
+	if err != nil {
+		return "", err
+	}
+	err = writeRows(f, rows)
+	if err = errors.Join(err, f.Close()); err != nil {
+		return "", errors.Join(err, os.Remove(path+tmpExt))
+	}
+	return path, nil
+}
+
+func writeRows(f source.ParquetFile, rows []dbmodel.Span) error {
+	w, err := writer.NewParquetWriter(f, new(dbmodel.Span), 1)
+	if err != nil {
+		return err
+	}
+	w.CompressionType = parquet.CompressionCodec_ZSTD
+	for i := range rows {
+		if err := w.Write(&rows[i]); err != nil {
+			return err
+		}
+	}
+	return w.WriteStop()
+}
+
+// readFile reads the rows of a Parquet file in batches. T is either
+// dbmodel.Span or one of its projections, only the columns of T are read.
+func readFile[T any](path string, fn func(rows []T) error) error {
+	f, err := local.NewLocalFileReader(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := reader.NewParquetReader(f, new(T), 1)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", path, err)
+	}
+	defer r.ReadStop()
+	for remaining := r.GetNumRows(); remaining > 0; {
+		rows := make([]T, readBatchSize)
+		if err := r.Read(&rows); err != nil {
+			return fmt.Errorf("cannot read %s: %w", path, err)
+		}
+		if len(rows) == 0 {
+			return fmt.Errorf("cannot read %s: %d rows missing", path, remaining)
+		}
+		remaining -= int64(len(rows))
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
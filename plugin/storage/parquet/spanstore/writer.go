@@ -0,0 +1,145 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/parquet/spanstore/dbmodel"
+	storageMetrics "github.com/kjschnei001/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriter buffers spans and writes them into a new Parquet file per
+// partition when the batch is full or flushed. Spans are not readable
+// before they are written.
+type SpanWriter struct {
+	store     *Store
+	logger    *zap.Logger
+	metrics   *storageMetrics.WriteMetrics
+	batchSize int
+
+	mu    sync.Mutex
+	batch map[partition][]dbmodel.Span
+	count int
+
+	// flushMu serializes the writes of the batches
+	flushMu sync.Mutex
+
+	done    chan struct{}
+	flushWG sync.WaitGroup
+}
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Store          *Store
+	Logger         *zap.Logger
+	MetricsFactory metrics.Factory
+	// BatchSize is the number of spans written together, they are written
+	// as they are received if it is not greater than one.
+	BatchSize int
+	// BatchFlushInterval is how often spans are written, even if there are less than BatchSize.
+	BatchFlushInterval time.Duration
+}
+
+// NewSpanWriter creates a SpanWriter, which must be closed to write the last spans.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	w := &SpanWriter{
+		store:     p.Store,
+		logger:    p.Logger,
+		metrics:   storageMetrics.NewWriteMetrics(p.MetricsFactory, "span_batches"),
+		batchSize: p.BatchSize,
+		batch:     make(map[partition][]dbmodel.Span),
+		done:      make(chan struct{}),
+	}
+	if p.BatchFlushInterval > 0 {
+		w.flushWG.Add(1)
+		go w.flushPeriodically(p.BatchFlushInterval)
+	}
+	return w
+}
+
+// WriteSpan adds the span to the current batch, and writes the batch when it is full.
+func (w *SpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	row, err := dbmodel.FromDomain(span)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	p := partitionOf(row)
+	w.batch[p] = append(w.batch[p], *row)
+	w.count++
+	if w.count < w.batchSize {
+		w.mu.Unlock()
+		return nil
+	}
+	w.mu.Unlock()
+	return w.Flush(ctx)
+}
+
+// Flush writes the spans of the current batch.
+func (w *SpanWriter) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	if w.count == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	batch := w.batch
+	w.batch = make(map[partition][]dbmodel.Span)
+	w.count = 0
+	w.mu.Unlock()
+
+	start := time.Now()
+	var errs []error
+	for p, rows := range batch {
+		errs = append(errs, w.store.writePartition(p, rows))
+	}
+	err := errors.Join(errs...)
+	w.metrics.Emit(err, time.Since(start))
+	return err
+}
+
+func (w *SpanWriter) flushPeriodically(interval time.Duration) {
+	defer w.flushWG.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.Flush(context.Background()); err != nil {
+				w.logger.Error("Failed to write spans", zap.Error(err))
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Close stops the periodic flushes and writes the spans of the current batch.
+func (w *SpanWriter) Close() error {
+	close(w.done)
+	w.flushWG.Wait()
+	return w.Flush(context.Background())
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+)
+
+var testHour = time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) *Store {
+	store, err := NewStore(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	return store
+}
+
+func newTestWriter(store *Store, batchSize int, flushInterval time.Duration) (*SpanWriter, *metricstest.Factory) {
+	mf := metricstest.NewFactory(0)
+	return NewSpanWriter(SpanWriterParams{
+		Store:              store,
+		Logger:             zap.NewNop(),
+		MetricsFactory:     mf,
+		BatchSize:          batchSize,
+		BatchFlushInterval: flushInterval,
+	}), mf
+}
+
+func testSpan(traceID uint64, spanID model.SpanID, service string, startTime time.Time) *model.Span {
+	return &model.Span{
+		TraceID:       model.NewTraceID(0, traceID),
+		SpanID:        spanID,
+		OperationName: "op",
+		StartTime:     startTime,
+		Duration:      time.Millisecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server")},
+		Process:       model.NewProcess(service, nil),
+	}
+}
+
+func partitionFiles(t *testing.T, store *Store, hour time.Time, service string) []string {
+	files, err := store.files(partition{hour: hour, service: service})
+	require.NoError(t, err)
+	return files
+}
+
+func TestSpanWriterBatches(t *testing.T) {
+	store := newTestStore(t)
+	w, mf := newTestWriter(store, 3, 0)
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1, 1, "svc", testHour)))
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1, 2, "svc", testHour.Add(time.Hour))))
+	assert.Empty(t, partitionFiles(t, store, testHour, "svc"))
+
+	// the batch is written into a file per partition
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1, 3, "other/svc", testHour)))
+	assert.Len(t, partitionFiles(t, store, testHour, "svc"), 1)
+	assert.Len(t, partitionFiles(t, store, testHour.Add(time.Hour), "svc"), 1)
+	assert.Len(t, partitionFiles(t, store, testHour, "other/svc"), 1)
+	assert.Equal(t, []string{"other/svc", "svc"}, store.catalogue.services())
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(2, 1, "svc", testHour)))
+	require.NoError(t, w.Close())
+	assert.Len(t, partitionFiles(t, store, testHour, "svc"), 2)
+
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "span_batches.attempts", Value: 2})
+}
+
+func TestSpanWriterUnbatched(t *testing.T) {
+	store := newTestStore(t)
+	w, _ := newTestWriter(store, 0, 0)
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1, 1, "svc", testHour)))
+	assert.Len(t, partitionFiles(t, store, testHour, "svc"), 1)
+	require.NoError(t, w.Close())
+}
+
+func TestSpanWriterFlushPeriodically(t *testing.T) {
+	store := newTestStore(t)
+	w, _ := newTestWriter(store, 100, time.Millisecond)
+	defer w.Close()
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1, 1, "svc", testHour)))
+	assert.Eventually(t, func() bool {
+		store.mu.RLock()
+		defer store.mu.RUnlock()
+		return len(partitionFiles(t, store, testHour, "svc")) == 1
+	}, time.Second, time.Millisecond)
+}
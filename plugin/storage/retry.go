@@ -0,0 +1,164 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	casSpanstore "github.com/kjschnei001/jaeger/plugin/storage/cassandra/spanstore"
+	"github.com/kjschnei001/jaeger/plugin/storage/deadletter"
+	esSpanstore "github.com/kjschnei001/jaeger/plugin/storage/es/spanstore"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const (
+	retryMaxElapsedTime  = "span-writer.retry.max-elapsed-time"
+	retryInitialInterval = "span-writer.retry.initial-interval"
+	retryMaxInterval     = "span-writer.retry.max-interval"
+	retryMultiplier      = "span-writer.retry.multiplier"
+	retryJitter          = "span-writer.retry.jitter"
+	deadLetterType       = "span-writer.dead-letter.type"
+	deadLetterFilePath   = "span-writer.dead-letter.file.path"
+	deadLetterBrokers    = "span-writer.dead-letter.kafka.brokers"
+	deadLetterTopic      = "span-writer.dead-letter.kafka.topic"
+
+	deadLetterNone  = "none"
+	deadLetterFile  = "file"
+	deadLetterKafka = "kafka"
+)
+
+// SpanWriterRetryConfig configures the retries of the failed span writes, and where the spans
+// are written when the retries are given up on.
+type SpanWriterRetryConfig struct {
+	MaxElapsedTime  time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	DeadLetterType  string
+	DeadLetterFile  string
+	DeadLetterKafka []string
+	DeadLetterTopic string
+}
+
+func (c SpanWriterRetryConfig) enabled() bool {
+	return c.MaxElapsedTime > 0 || c.deadLetterEnabled()
+}
+
+func (c SpanWriterRetryConfig) deadLetterEnabled() bool {
+	return c.DeadLetterType != "" && c.DeadLetterType != deadLetterNone
+}
+
+// addRetryFlags adds flags for the retries of the span writes
+func (f *Factory) addRetryFlags(flagSet *flag.FlagSet) {
+	f.retryFlagsAdded = true
+	flagSet.Duration(
+		retryMaxElapsedTime,
+		0,
+		"How long a failed span write is retried before the span is written to the dead-letter writer, or dropped; 0 disables the retries.",
+	)
+	flagSet.Duration(retryInitialInterval, 500*time.Millisecond, "The wait before the first retry of a failed span write.")
+	flagSet.Duration(retryMaxInterval, 30*time.Second, "The maximum wait between two retries of a failed span write.")
+	flagSet.Float64(retryMultiplier, 2, "The factor the wait is multiplied by after each retry of a failed span write.")
+	flagSet.Float64(retryJitter, 0.5, "The fraction, between 0 and 1, by which each wait between retries is randomized.")
+	flagSet.String(
+		deadLetterType,
+		deadLetterNone,
+		fmt.Sprintf("Where the spans that could not be written are written to: %s, %s or %s.", deadLetterNone, deadLetterFile, deadLetterKafka),
+	)
+	flagSet.String(deadLetterFilePath, "", "The file the spans that could not be written are appended to, as JSON lines.")
+	flagSet.String(deadLetterBrokers, "127.0.0.1:9092", "The comma-separated list of Kafka brokers the spans that could not be written are produced to.")
+	flagSet.String(deadLetterTopic, "jaeger-spans-dead-letter", "The Kafka topic the spans that could not be written are produced to.")
+}
+
+func (f *Factory) initRetryFromViper(v *viper.Viper) {
+	// the retries are only configurable in the pipeline services, see AddPipelineFlags
+	if !f.retryFlagsAdded {
+		f.FactoryConfig.SpanWriterRetry = SpanWriterRetryConfig{}
+		return
+	}
+	f.FactoryConfig.SpanWriterRetry = SpanWriterRetryConfig{
+		MaxElapsedTime:  v.GetDuration(retryMaxElapsedTime),
+		InitialInterval: v.GetDuration(retryInitialInterval),
+		MaxInterval:     v.GetDuration(retryMaxInterval),
+		Multiplier:      v.GetFloat64(retryMultiplier),
+		Jitter:          v.GetFloat64(retryJitter),
+		DeadLetterType:  v.GetString(deadLetterType),
+		DeadLetterFile:  v.GetString(deadLetterFilePath),
+		DeadLetterKafka: strings.Split(strings.ReplaceAll(v.GetString(deadLetterBrokers), " ", ""), ","),
+		DeadLetterTopic: v.GetString(deadLetterTopic),
+	}
+}
+
+func (f *Factory) createDeadLetterWriter(metricsFactory metrics.Factory, logger *zap.Logger) (spanstore.Writer, error) {
+	switch f.SpanWriterRetry.DeadLetterType {
+	case "", deadLetterNone:
+		return nil, nil
+	case deadLetterFile:
+		return deadletter.NewFileWriter(f.SpanWriterRetry.DeadLetterFile)
+	case deadLetterKafka:
+		return deadletter.NewKafkaWriter(
+			f.SpanWriterRetry.DeadLetterKafka,
+			f.SpanWriterRetry.DeadLetterTopic,
+			metricsFactory.Namespace(metrics.NSOptions{Name: "dead_letter"}),
+			logger,
+		)
+	default:
+		return nil, fmt.Errorf("unknown dead-letter type %s. Valid types are %v",
+			f.SpanWriterRetry.DeadLetterType, []string{deadLetterNone, deadLetterFile, deadLetterKafka})
+	}
+}
+
+// failedSpanReporter is implemented by the factories whose span writers write asynchronously,
+// and report the spans they failed to write to the handler instead of returning the errors.
+type failedSpanReporter interface {
+	SetFailedSpanHandler(handler func(span *model.Span, err error))
+}
+
+func (f *Factory) newRetryingWriter(storageType string, spanWriter spanstore.Writer) *spanstore.RetryingWriter {
+	return spanstore.NewRetryingWriter(spanWriter, spanstore.RetryOptions{
+		InitialInterval:  f.SpanWriterRetry.InitialInterval,
+		MaxInterval:      f.SpanWriterRetry.MaxInterval,
+		Multiplier:       f.SpanWriterRetry.Multiplier,
+		Jitter:           f.SpanWriterRetry.Jitter,
+		MaxElapsedTime:   f.SpanWriterRetry.MaxElapsedTime,
+		IsRetryable:      isRetryableError(storageType),
+		DeadLetterWriter: f.deadLetterWriter,
+		MetricsFactory: f.metricsFactory.Namespace(metrics.NSOptions{
+			Name: "retrying_writer",
+			Tags: map[string]string{"backend": storageType},
+		}),
+	})
+}
+
+// isRetryableError returns the classification of the write errors of the storage type. The
+// Elasticsearch and OpenSearch errors are reported by their factories, see failedSpanReporter.
+func isRetryableError(storageType string) func(err error) bool {
+	switch storageType {
+	case cassandraStorageType:
+		return casSpanstore.IsRetryableError
+	case elasticsearchStorageType, opensearchStorageType:
+		return esSpanstore.IsRetryableError
+	}
+	return spanstore.IsRetryableError
+}
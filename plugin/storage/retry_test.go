@@ -0,0 +1,206 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/config"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/storage/mocks"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+	spanStoreMocks "github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+func TestParsingRetryFlags(t *testing.T) {
+	f := Factory{}
+	v, command := config.Viperize(f.AddPipelineFlags)
+	err := command.ParseFlags([]string{
+		"--span-writer.retry.max-elapsed-time=1m",
+		"--span-writer.retry.initial-interval=1s",
+		"--span-writer.dead-letter.type=kafka",
+		"--span-writer.dead-letter.kafka.brokers=kafka-1:9092, kafka-2:9092",
+	})
+	require.NoError(t, err)
+	f.InitFromViper(v, zap.NewNop())
+
+	assert.Equal(t, SpanWriterRetryConfig{
+		MaxElapsedTime:  time.Minute,
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		DeadLetterType:  "kafka",
+		DeadLetterKafka: []string{"kafka-1:9092", "kafka-2:9092"},
+		DeadLetterTopic: "jaeger-spans-dead-letter",
+	}, f.SpanWriterRetry)
+}
+
+func TestRetryDisabledWithAddFlags(t *testing.T) {
+	f := Factory{}
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{}))
+	f.InitFromViper(v, zap.NewNop())
+	assert.False(t, f.SpanWriterRetry.enabled())
+}
+
+func TestCreateRetryingWriter(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.SpanWriterRetry = SpanWriterRetryConfig{
+		DeadLetterType: deadLetterFile,
+		DeadLetterFile: filepath.Join(t.TempDir(), "dead-letter.json"),
+	}
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	mockFactory := new(mocks.Factory)
+	f.factories[cassandraStorageType] = mockFactory
+	spanWriter := new(spanStoreMocks.Writer)
+	spanWriter.On("WriteSpan", mock.Anything, mock.Anything).Return(errors.New("storage down"))
+	mockFactory.On("CreateSpanWriter").Return(spanWriter, nil)
+	mockFactory.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	w, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.RetryingWriter{}, w)
+
+	// without retries, the failed span is written to the dead-letter file right away
+	assert.Error(t, w.WriteSpan(context.Background(), &model.Span{OperationName: "op"}))
+	require.NoError(t, f.Close())
+	data, err := os.ReadFile(cfg.SpanWriterRetry.DeadLetterFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"operationName":"op"`)
+}
+
+// failedSpanReporterFactory is a storage factory whose span writer reports its failures asynchronously.
+type failedSpanReporterFactory struct {
+	*mocks.Factory
+	handler func(span *model.Span, err error)
+}
+
+func (f *failedSpanReporterFactory) SetFailedSpanHandler(handler func(span *model.Span, err error)) {
+	f.handler = handler
+}
+
+func TestRetryFailedSpansReported(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.SpanWriterTypes = []string{elasticsearchStorageType}
+	cfg.SpanReaderType = elasticsearchStorageType
+	cfg.DependenciesStorageType = elasticsearchStorageType
+	cfg.SpanWriterRetry = SpanWriterRetryConfig{
+		MaxElapsedTime:  time.Minute,
+		InitialInterval: time.Millisecond,
+		Multiplier:      1,
+		DeadLetterType:  deadLetterFile,
+		DeadLetterFile:  filepath.Join(t.TempDir(), "dead-letter.json"),
+	}
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	mockFactory := &failedSpanReporterFactory{Factory: new(mocks.Factory)}
+	f.factories[elasticsearchStorageType] = mockFactory
+	written := make(chan *model.Span, 1)
+	spanWriter := new(spanStoreMocks.Writer)
+	spanWriter.On("WriteSpan", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		written <- args.Get(1).(*model.Span)
+	})
+	mockFactory.On("CreateSpanWriter").Return(spanWriter, nil)
+	mockFactory.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+	require.NotNil(t, mockFactory.handler)
+
+	// the rejected span is written again, the span with a mapping conflict is dead-lettered
+	mockFactory.handler(&model.Span{OperationName: "rejected"}, &elastic.Error{Status: 429})
+	mockFactory.handler(&model.Span{OperationName: "conflict"}, &elastic.Error{Status: 400})
+	select {
+	case span := <-written:
+		assert.Equal(t, "rejected", span.OperationName)
+	case <-time.After(time.Second):
+		t.Fatal("the rejected span was not written again")
+	}
+	require.NoError(t, f.Close())
+	data, err := os.ReadFile(cfg.SpanWriterRetry.DeadLetterFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"operationName":"conflict"`)
+	assert.NotContains(t, string(data), `"operationName":"rejected"`)
+}
+
+func TestCreateDeadLetterWriterErrors(t *testing.T) {
+	f := Factory{FactoryConfig: FactoryConfig{SpanWriterRetry: SpanWriterRetryConfig{DeadLetterType: "s3"}}}
+	_, err := f.createDeadLetterWriter(metrics.NullFactory, zap.NewNop())
+	assert.EqualError(t, err, "unknown dead-letter type s3. Valid types are [none file kafka]")
+
+	f.SpanWriterRetry = SpanWriterRetryConfig{DeadLetterType: deadLetterFile, DeadLetterFile: t.TempDir()}
+	_, err = f.createDeadLetterWriter(metrics.NullFactory, zap.NewNop())
+	assert.ErrorContains(t, err, "cannot open dead-letter file")
+}
+
+func TestRetryEachWriter(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.SpanWriterTypes = []string{cassandraStorageType, elasticsearchStorageType}
+	cfg.SpanWriterRetry = SpanWriterRetryConfig{
+		MaxElapsedTime:  time.Second,
+		InitialInterval: time.Millisecond,
+		Multiplier:      1,
+	}
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	cassandraWriter := new(spanStoreMocks.Writer)
+	cassandraWriter.On("WriteSpan", mock.Anything, mock.Anything).Return(errors.New("storage down")).Once()
+	cassandraWriter.On("WriteSpan", mock.Anything, mock.Anything).Return(nil).Once()
+	esWriter := new(spanStoreMocks.Writer)
+	esWriter.On("WriteSpan", mock.Anything, mock.Anything).Return(nil).Once()
+	for storageType, writer := range map[string]*spanStoreMocks.Writer{
+		cassandraStorageType:     cassandraWriter,
+		elasticsearchStorageType: esWriter,
+	} {
+		mockFactory := new(mocks.Factory)
+		mockFactory.On("CreateSpanWriter").Return(writer, nil)
+		mockFactory.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+		f.factories[storageType] = mockFactory
+	}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	w, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	// the span is written again only to the backend that failed
+	require.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+	cassandraWriter.AssertNumberOfCalls(t, "WriteSpan", 2)
+	esWriter.AssertNumberOfCalls(t, "WriteSpan", 1)
+}
+
+func TestIsRetryableErrorOfStorageType(t *testing.T) {
+	assert.True(t, isRetryableError(cassandraStorageType)(errors.New("connection refused")))
+	assert.False(t, isRetryableError(cassandraStorageType)(gocql.RequestErrAlreadyExists{}))
+	assert.True(t, isRetryableError(elasticsearchStorageType)(gocql.RequestErrAlreadyExists{}))
+	assert.False(t, isRetryableError(elasticsearchStorageType)(context.Canceled))
+	assert.True(t, isRetryableError(elasticsearchStorageType)(&elastic.Error{Status: 429}))
+	assert.False(t, isRetryableError(opensearchStorageType)(&elastic.Error{Status: 400}))
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin"
+	"github.com/kjschnei001/jaeger/storage"
+	"github.com/kjschnei001/jaeger/storage/dependencystore"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+var errNoWatermarkFile = errors.New("the watermark file is required to copy the spans to the cold storage")
+
+var (
+	_ io.Closer                       = (*Factory)(nil)
+	_ plugin.Configurable             = (*Factory)(nil)
+	_ storage.AdminHandlerFactory     = (*Factory)(nil)
+	_ storage.ArchiveFactory          = (*Factory)(nil)
+	_ storage.DependencyWriterFactory = (*Factory)(nil)
+)
+
+// Factory implements storage.Factory for a hot storage holding the recent spans,
+// and a cold storage the spans older than the demote age are copied to.
+//
+// The spans are written to the hot storage, and searched in both storages.
+// The spans are copied by the single process enabling the copies, when it
+// creates a span writer. The dependencies and the archived traces are kept
+// in the hot storage.
+type Factory struct {
+	Options Options
+
+	hot  storage.Factory
+	cold storage.Factory
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+
+	moverMu      sync.Mutex
+	moverStarted bool
+	moverCancel  context.CancelFunc
+	moverWG      sync.WaitGroup
+}
+
+// NewFactory creates a new Factory of the hot and cold storages.
+func NewFactory(hot, cold storage.Factory) *Factory {
+	return &Factory{
+		Options: Options{
+			DemoteAfter:  defaultDemoteAfter,
+			MoveInterval: defaultMoveInterval,
+		},
+		hot:         hot,
+		cold:        cold,
+		moverCancel: func() {},
+	}
+}
+
+// Hot returns the factory of the hot storage.
+func (f *Factory) Hot() storage.Factory {
+	return f.hot
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	for _, factory := range []storage.Factory{f.hot, f.cold} {
+		if conf, ok := factory.(plugin.Configurable); ok {
+			conf.AddFlags(flagSet)
+		}
+	}
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper, logger *zap.Logger) {
+	for _, factory := range []storage.Factory{f.hot, f.cold} {
+		if conf, ok := factory.(plugin.Configurable); ok {
+			conf.InitFromViper(v, logger)
+		}
+	}
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	if err := f.hot.Initialize(metricsFactory, logger); err != nil {
+		return err
+	}
+	if err := f.cold.Initialize(metricsFactory, logger); err != nil {
+		return err
+	}
+	logger.Info("Tiered storage configuration", zap.Any("configuration", f.Options))
+	return nil
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	hot, err := f.hot.CreateSpanReader()
+	if err != nil {
+		return nil, err
+	}
+	cold, err := f.cold.CreateSpanReader()
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(hot, cold, f.Options.DemoteAfter), nil
+}
+
+// CreateSpanWriter implements storage.Factory, and starts copying the spans to
+// the cold storage with the first writer when the copies are enabled.
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	writer, err := f.hot.CreateSpanWriter()
+	if err != nil {
+		return nil, err
+	}
+	if err := f.startMover(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (f *Factory) startMover() error {
+	f.moverMu.Lock()
+	defer f.moverMu.Unlock()
+	if f.moverStarted || !f.Options.MoveSpans || f.Options.MoveInterval <= 0 {
+		return nil
+	}
+	if f.Options.WatermarkFile == "" {
+		return errNoWatermarkFile
+	}
+	hot, err := f.hot.CreateSpanReader()
+	if err != nil {
+		return err
+	}
+	cold, err := f.cold.CreateSpanWriter()
+	if err != nil {
+		return err
+	}
+	m := newMover(hot, cold, f.Options, f.metricsFactory.Namespace(metrics.NSOptions{Name: "tiered_storage"}), f.logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	f.moverStarted, f.moverCancel = true, cancel
+	f.moverWG.Add(1)
+	go func() {
+		defer f.moverWG.Done()
+		m.run(ctx, f.Options.MoveInterval)
+	}()
+	return nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return f.hot.CreateDependencyReader()
+}
+
+// CreateDependencyWriter implements storage.DependencyWriterFactory
+func (f *Factory) CreateDependencyWriter() (dependencystore.Writer, error) {
+	writerFactory, ok := f.hot.(storage.DependencyWriterFactory)
+	if !ok {
+		return nil, storage.ErrDependencyWriterNotSupported
+	}
+	return writerFactory.CreateDependencyWriter()
+}
+
+// CreateArchiveSpanReader implements storage.ArchiveFactory
+func (f *Factory) CreateArchiveSpanReader() (spanstore.Reader, error) {
+	archive, ok := f.hot.(storage.ArchiveFactory)
+	if !ok {
+		return nil, storage.ErrArchiveStorageNotSupported
+	}
+	return archive.CreateArchiveSpanReader()
+}
+
+// CreateArchiveSpanWriter implements storage.ArchiveFactory
+func (f *Factory) CreateArchiveSpanWriter() (spanstore.Writer, error) {
+	archive, ok := f.hot.(storage.ArchiveFactory)
+	if !ok {
+		return nil, storage.ErrArchiveStorageNotSupported
+	}
+	return archive.CreateArchiveSpanWriter()
+}
+
+// AdminHandlers implements storage.AdminHandlerFactory, and returns the admin handlers of both storages.
+func (f *Factory) AdminHandlers() map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	for _, factory := range []storage.Factory{f.hot, f.cold} {
+		if handlerFactory, ok := factory.(storage.AdminHandlerFactory); ok {
+			for path, handler := range handlerFactory.AdminHandlers() {
+				handlers[path] = handler
+			}
+		}
+	}
+	return handlers
+}
+
+// Close stops copying the spans and closes both storages.
+func (f *Factory) Close() error {
+	f.moverMu.Lock()
+	f.moverCancel()
+	f.moverMu.Unlock()
+	f.moverWG.Wait()
+
+	var errs []error
+	for _, factory := range []storage.Factory{f.hot, f.cold} {
+		if closer, ok := factory.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/spanstoretest"
+	"github.com/kjschnei001/jaeger/pkg/config"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/plugin/storage/memory"
+	"github.com/kjschnei001/jaeger/storage"
+	"github.com/kjschnei001/jaeger/storage/mocks"
+)
+
+var _ storage.Factory = (*Factory)(nil)
+
+func TestFactory(t *testing.T) {
+	hot := memory.NewFactory()
+	// hides the flags of the cold storage, which are the same as the hot storage ones
+	cold := struct{ storage.Factory }{memory.NewFactory()}
+	f := NewFactory(hot, cold)
+	watermark := filepath.Join(t.TempDir(), "watermark")
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{
+		"--tiered.demote-after=48h",
+		"--tiered.move-interval=10ms",
+		"--tiered.move-spans",
+		"--tiered.watermark-file=" + watermark,
+		"--memory.max-traces=100",
+	}))
+	f.InitFromViper(v, zap.NewNop())
+	assert.Equal(t, Options{
+		DemoteAfter:   48 * time.Hour,
+		MoveInterval:  10 * time.Millisecond,
+		MoveSpans:     true,
+		WatermarkFile: watermark,
+	}, f.Options)
+	assert.Same(t, hot, f.Hot())
+
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	reader, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+
+	span := spanstoretest.Span(1, 1, "a", time.Now(), "")
+	require.NoError(t, writer.WriteSpan(context.Background(), span))
+	trace, err := reader.GetTrace(context.Background(), span.TraceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+
+	_, err = f.CreateDependencyReader()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyWriter()
+	assert.Equal(t, storage.ErrDependencyWriterNotSupported, err)
+	_, err = f.CreateArchiveSpanReader()
+	assert.Equal(t, storage.ErrArchiveStorageNotSupported, err)
+	_, err = f.CreateArchiveSpanWriter()
+	assert.Equal(t, storage.ErrArchiveStorageNotSupported, err)
+
+	// stops the copies started with the writer
+	require.NoError(t, f.Close())
+}
+
+func TestFactoryErrors(t *testing.T) {
+	m, l := metrics.NullFactory, zap.NewNop()
+	hot, cold := &mocks.Factory{}, &mocks.Factory{}
+	hot.On("Initialize", m, l).Return(errors.New("hot error")).Once()
+	f := NewFactory(hot, cold)
+	assert.EqualError(t, f.Initialize(m, l), "hot error")
+
+	hot.On("Initialize", m, l).Return(nil)
+	cold.On("Initialize", m, l).Return(errors.New("cold error"))
+	assert.EqualError(t, f.Initialize(m, l), "cold error")
+
+	hot.On("CreateSpanReader").Return(nil, errors.New("hot reader error")).Once()
+	_, err := f.CreateSpanReader()
+	assert.EqualError(t, err, "hot reader error")
+	hot.On("CreateSpanReader").Return(nil, nil)
+	cold.On("CreateSpanReader").Return(nil, errors.New("cold reader error")).Once()
+	_, err = f.CreateSpanReader()
+	assert.EqualError(t, err, "cold reader error")
+
+	hot.On("CreateSpanWriter").Return(nil, errors.New("hot writer error")).Once()
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "hot writer error")
+	hot.On("CreateSpanWriter").Return(nil, nil)
+	// the copies are disabled by default
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+
+	f.Options.MoveSpans = true
+	_, err = f.CreateSpanWriter()
+	assert.Equal(t, errNoWatermarkFile, err)
+	f.Options.WatermarkFile = filepath.Join(t.TempDir(), "watermark")
+	cold.On("CreateSpanWriter").Return(nil, errors.New("cold writer error"))
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "cold writer error")
+	// the copies are started by the next writer
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "cold writer error")
+
+	require.NoError(t, f.Close())
+	hot.AssertExpectations(t)
+	cold.AssertExpectations(t)
+}
+
+type adminHandlerFactory struct {
+	storage.Factory
+	path string
+}
+
+func (f adminHandlerFactory) AdminHandlers() map[string]http.Handler {
+	return map[string]http.Handler{f.path: http.NotFoundHandler()}
+}
+
+func TestAdminHandlers(t *testing.T) {
+	f := NewFactory(memory.NewFactory(), memory.NewFactory())
+	assert.Empty(t, f.AdminHandlers())
+
+	f = NewFactory(adminHandlerFactory{memory.NewFactory(), "/hot"}, adminHandlerFactory{memory.NewFactory(), "/cold"})
+	handlers := f.AdminHandlers()
+	assert.Len(t, handlers, 2)
+	assert.Contains(t, handlers, "/hot")
+	assert.Contains(t, handlers, "/cold")
+}
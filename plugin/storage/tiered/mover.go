@@ -0,0 +1,240 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+const (
+	// maxTracesPerQuery is the number of traces searched at once in the hot storage, the
+	// following traces are paged, or the time range is split in halves when the hot storage
+	// cannot page them.
+	maxTracesPerQuery = 1000
+	// minQueryRange is the shortest time range searched at once in the hot storage, its
+	// traces are searched with a larger limit when the hot storage cannot page them.
+	minQueryRange = time.Millisecond
+	// moveStep is the time range copied before the watermark is advanced, so that a failed
+	// run only copies again the spans of the last step.
+	moveStep = 10 * time.Minute
+)
+
+// moverMetrics are the metrics of the copies to the cold storage.
+type moverMetrics struct {
+	TracesMoved metrics.Counter `metric:"traces_moved"`
+	SpansMoved  metrics.Counter `metric:"spans_moved"`
+	MoveErrors  metrics.Counter `metric:"move_errors"`
+	// LastMoveRun is the timestamp (UnixNano) of the last successful run
+	LastMoveRun metrics.Gauge `metric:"last_move_run"`
+}
+
+// mover copies the spans older than the demote age from the hot storage to the
+// cold storage. Every span is copied once, by the first run after it became
+// older than the demote age, even if its trace is split across several runs.
+//
+// The watermark is recorded in a file after each run, overwriting the previous one,
+// so that the next process resumes the copies where they stopped.
+type mover struct {
+	hot           spanstore.Reader
+	cold          spanstore.Writer
+	demoteAfter   time.Duration
+	moveInterval  time.Duration
+	watermarkFile string
+	logger        *zap.Logger
+	metrics       moverMetrics
+
+	// watermark is the start time before which the spans have been copied,
+	// it is loaded from the watermark file by the first run when zero.
+	watermark time.Time
+}
+
+// moveRun is the time range of the spans copied by a run, and the traces already copied.
+type moveRun struct {
+	start, end time.Time
+	moved      map[model.TraceID]struct{}
+}
+
+func newMover(hot spanstore.Reader, cold spanstore.Writer, opts Options, metricsFactory metrics.Factory, logger *zap.Logger) *mover {
+	m := &mover{
+		hot:           hot,
+		cold:          cold,
+		demoteAfter:   opts.DemoteAfter,
+		moveInterval:  opts.MoveInterval,
+		watermarkFile: opts.WatermarkFile,
+		logger:        logger,
+	}
+	metrics.Init(&m.metrics, metricsFactory, nil)
+	return m
+}
+
+// run copies the spans periodically until the context is canceled.
+func (m *mover) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-ticker.C:
+			if err := m.move(ctx, t); err != nil {
+				m.metrics.MoveErrors.Inc(1)
+				m.logger.Error("Failed to copy the spans to the cold storage", zap.Error(err))
+				continue
+			}
+			m.metrics.LastMoveRun.Update(t.UnixNano())
+		}
+	}
+}
+
+// move copies the spans that became older than the demote age since the watermark. The
+// traces are searched in steps of moveStep after which the watermark is advanced, and a
+// failed run is resumed by the next one from the step that failed. The spans already
+// copied after the watermark are then written twice to the cold storage, and deduplicated
+// by the Reader.
+func (m *mover) move(ctx context.Context, now time.Time) error {
+	if m.watermark.IsZero() {
+		watermark, err := m.loadWatermark(now)
+		if err != nil {
+			return fmt.Errorf("cannot load the watermark: %w", err)
+		}
+		m.watermark = watermark
+	}
+	run := &moveRun{
+		start: m.watermark,
+		end:   now.Add(-m.demoteAfter),
+		moved: make(map[model.TraceID]struct{}),
+	}
+	if !run.end.After(run.start) {
+		return nil
+	}
+	services, err := m.hot.GetServices(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get the services: %w", err)
+	}
+	for m.watermark.Before(run.end) {
+		stepEnd := m.watermark.Add(moveStep)
+		if stepEnd.After(run.end) {
+			stepEnd = run.end
+		}
+		for _, service := range services {
+			if err := m.moveRange(ctx, run, service, m.watermark, stepEnd); err != nil {
+				return fmt.Errorf("cannot copy the spans of %s: %w", service, err)
+			}
+		}
+		if err := m.saveWatermark(stepEnd); err != nil {
+			return fmt.Errorf("cannot save the watermark: %w", err)
+		}
+		m.watermark = stepEnd
+	}
+	return nil
+}
+
+// loadWatermark returns the watermark recorded in the watermark file. Without it, the spans
+// which became older than the demote age before the last interval are expected to have been
+// copied, e.g. when the cold storage was added.
+func (m *mover) loadWatermark(now time.Time) (time.Time, error) {
+	data, err := os.ReadFile(filepath.Clean(m.watermarkFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return now.Add(-m.demoteAfter - m.moveInterval), nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+}
+
+// saveWatermark replaces the watermark recorded in the watermark file.
+func (m *mover) saveWatermark(watermark time.Time) error {
+	tmp := m.watermarkFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(watermark.UTC().Format(time.RFC3339Nano)+"\n"), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.watermarkFile)
+}
+
+// moveRange copies the traces with spans of the service in the time range. The traces
+// beyond maxTracesPerQuery are paged, or searched in the halves of the time range when the
+// hot storage cannot page them.
+func (m *mover) moveRange(ctx context.Context, run *moveRun, service string, start, end time.Time) error {
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:  service,
+		StartTimeMin: start,
+		StartTimeMax: end,
+		NumTraces:    maxTracesPerQuery,
+	}
+	for {
+		page, err := spanstore.FindTracesPage(ctx, m.hot, query)
+		if err != nil {
+			return err
+		}
+		if page.Truncated {
+			if end.Sub(start) >= 2*minQueryRange {
+				middle := start.Add(end.Sub(start) / 2)
+				if err := m.moveRange(ctx, run, service, start, middle); err != nil {
+					return err
+				}
+				return m.moveRange(ctx, run, service, middle, end)
+			}
+			// the time range cannot be split further, all its traces are searched at once
+			query.NumTraces *= 2
+			continue
+		}
+		for _, trace := range page.Traces {
+			if err := m.moveTrace(ctx, run, trace); err != nil {
+				return err
+			}
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		query.PageToken = page.NextPageToken
+	}
+}
+
+// moveTrace copies the spans of the trace in the time range of the run, the
+// other spans are copied by the previous and next runs.
+func (m *mover) moveTrace(ctx context.Context, run *moveRun, trace *model.Trace) error {
+	traceID := spanstore.TraceIDOf(trace)
+	if _, ok := run.moved[traceID]; ok {
+		return nil
+	}
+	var spans int64
+	for _, span := range trace.Spans {
+		if span.StartTime.Before(run.start) || !span.StartTime.Before(run.end) {
+			continue
+		}
+		if err := m.cold.WriteSpan(ctx, span); err != nil {
+			return err
+		}
+		spans++
+	}
+	run.moved[traceID] = struct{}{}
+	m.metrics.TracesMoved.Inc(1)
+	m.metrics.SpansMoved.Inc(spans)
+	return nil
+}
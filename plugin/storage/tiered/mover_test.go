@@ -0,0 +1,235 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/internal/spanstoretest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/plugin/storage/memory"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+func testMoverOptions(t *testing.T) Options {
+	return Options{
+		DemoteAfter:   72 * time.Hour,
+		MoveInterval:  time.Hour,
+		MoveSpans:     true,
+		WatermarkFile: filepath.Join(t.TempDir(), "watermark"),
+	}
+}
+
+func newTestMover(t *testing.T, hot spanstore.Reader, cold spanstore.Writer) (*mover, *metricstest.Factory) {
+	metricsFactory := metricstest.NewFactory(0)
+	m := newMover(hot, cold, testMoverOptions(t), metricsFactory, zap.NewNop())
+	m.watermark = testNow.Add(-73 * time.Hour)
+	return m, metricsFactory
+}
+
+func coldSpanIDs(t *testing.T, cold *memory.Store, traceID uint64) []model.SpanID {
+	trace, err := cold.GetTrace(context.Background(), model.NewTraceID(0, traceID))
+	if errors.Is(err, spanstore.ErrTraceNotFound) {
+		return nil
+	}
+	require.NoError(t, err)
+	return spanstoretest.SpanIDs(trace)
+}
+
+func TestMove(t *testing.T) {
+	hot, cold := memory.NewStore(), memory.NewStore()
+	demoted := testNow.Add(-72 * time.Hour)
+	// trace 1 spans both runs, trace 2 was copied by a previous run and trace 3 is recent
+	spanstoretest.WriteSpans(t, hot,
+		spanstoretest.Span(1, 1, "a", demoted.Add(-30*time.Minute), ""),
+		spanstoretest.Span(1, 2, "b", demoted.Add(-10*time.Minute), ""),
+		spanstoretest.Span(1, 3, "b", demoted.Add(30*time.Minute), ""),
+		spanstoretest.Span(2, 1, "a", demoted.Add(-2*time.Hour), ""),
+		spanstoretest.Span(3, 1, "a", testNow, ""),
+	)
+	m, metricsFactory := newTestMover(t, hot, cold)
+
+	require.NoError(t, m.move(context.Background(), testNow))
+	assert.Equal(t, demoted, m.watermark)
+	assert.ElementsMatch(t, []model.SpanID{1, 2}, coldSpanIDs(t, cold, 1))
+	assert.Empty(t, coldSpanIDs(t, cold, 2))
+	assert.Empty(t, coldSpanIDs(t, cold, 3))
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces_moved", Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_moved", Value: 2},
+	)
+
+	// the next run copies the following spans of the trace
+	require.NoError(t, m.move(context.Background(), testNow.Add(time.Hour)))
+	assert.ElementsMatch(t, []model.SpanID{1, 2, 3}, coldSpanIDs(t, cold, 1))
+	assert.Empty(t, coldSpanIDs(t, cold, 3))
+
+	// a run before the demote age of the next spans does nothing
+	require.NoError(t, m.move(context.Background(), testNow))
+	assert.Equal(t, demoted.Add(time.Hour), m.watermark)
+}
+
+func TestMovePagesTraces(t *testing.T) {
+	hot, cold := memory.NewStore(), memory.NewStore()
+	start := testNow.Add(-73 * time.Hour)
+	for i := 0; i < maxTracesPerQuery+10; i++ {
+		// the traces start in the same millisecond
+		spanstoretest.WriteSpans(t, hot, spanstoretest.Span(uint64(i+1), 1, "a", start.Add(time.Duration(i)*time.Nanosecond), ""))
+	}
+	m, metricsFactory := newTestMover(t, hot, cold)
+
+	require.NoError(t, m.move(context.Background(), testNow))
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces_moved", Value: maxTracesPerQuery + 10},
+	)
+}
+
+func TestMoveSplitsTimeRange(t *testing.T) {
+	// the hot storage cannot page the traces, the ranges with too many traces are split
+	// down to minQueryRange, whose traces are searched with a larger limit
+	crowded := testNow.Add(-73 * time.Hour).Add(5 * time.Minute)
+	traces := make([]*model.Trace, maxTracesPerQuery+10)
+	for i := range traces {
+		traces[i] = &model.Trace{Spans: []*model.Span{spanstoretest.Span(uint64(i+1), 1, "a", crowded, "")}}
+	}
+	var queries int
+	hot := &mocks.Reader{}
+	hot.On("GetServices", mock.Anything).Return([]string{"a"}, nil)
+	hot.On("FindTraces", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, query *spanstore.TraceQueryParameters) []*model.Trace {
+			queries++
+			if crowded.Before(query.StartTimeMin) || !crowded.Before(query.StartTimeMax) {
+				return nil
+			}
+			if len(traces) > query.NumTraces {
+				return traces[:query.NumTraces]
+			}
+			return traces
+		}, nil)
+	m, metricsFactory := newTestMover(t, hot, memory.NewStore())
+
+	require.NoError(t, m.move(context.Background(), testNow))
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces_moved", Value: maxTracesPerQuery + 10},
+	)
+	assert.Equal(t, testNow.Add(-72*time.Hour), m.watermark)
+	// only the halves with the crowded millisecond are split
+	assert.Less(t, queries, 100)
+}
+
+func TestMoveWatermark(t *testing.T) {
+	hot, cold := memory.NewStore(), memory.NewStore()
+	demoted := testNow.Add(-72 * time.Hour)
+	spanstoretest.WriteSpans(t, hot,
+		// the spans older than the interval before the first run are expected to have been copied
+		spanstoretest.Span(1, 1, "a", demoted.Add(-2*time.Hour), ""),
+		spanstoretest.Span(2, 1, "a", demoted.Add(-30*time.Minute), ""),
+		spanstoretest.Span(3, 1, "a", demoted.Add(30*time.Minute), ""),
+	)
+	opts := testMoverOptions(t)
+	m := newMover(hot, cold, opts, metricstest.NewFactory(0), zap.NewNop())
+	require.NoError(t, m.move(context.Background(), testNow))
+	assert.Equal(t, demoted, m.watermark)
+	assert.Empty(t, coldSpanIDs(t, cold, 1))
+	assert.Equal(t, []model.SpanID{1}, coldSpanIDs(t, cold, 2))
+
+	// the next process resumes from the watermark of the file,
+	// which is older than the interval before its first run
+	m = newMover(hot, cold, opts, metricstest.NewFactory(0), zap.NewNop())
+	require.NoError(t, m.move(context.Background(), testNow.Add(3*time.Hour)))
+	assert.Equal(t, demoted.Add(3*time.Hour), m.watermark)
+	assert.Equal(t, []model.SpanID{1}, coldSpanIDs(t, cold, 3))
+
+	// the file only holds the latest watermark
+	data, err := os.ReadFile(opts.WatermarkFile)
+	require.NoError(t, err)
+	assert.Equal(t, demoted.Add(3*time.Hour).UTC().Format(time.RFC3339Nano)+"\n", string(data))
+	services, err := cold.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, services)
+
+	require.NoError(t, os.WriteFile(opts.WatermarkFile, []byte("yesterday"), 0o600))
+	m = newMover(hot, cold, opts, metricstest.NewFactory(0), zap.NewNop())
+	err = m.move(context.Background(), testNow)
+	assert.ErrorContains(t, err, "cannot load the watermark: parsing time")
+	assert.True(t, m.watermark.IsZero())
+}
+
+func TestMoveAdvancesWatermarkPerStep(t *testing.T) {
+	start := testNow.Add(-73 * time.Hour)
+	hot := &mocks.Reader{}
+	hot.On("GetServices", mock.Anything).Return([]string{"a"}, nil)
+	hot.On("FindTraces", mock.Anything, mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+		return query.StartTimeMin.Before(start.Add(2 * moveStep))
+	})).Return(nil, nil)
+	hot.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errors.New("search error"))
+	m, _ := newTestMover(t, hot, memory.NewStore())
+
+	// the steps copied before the failure are not copied again
+	err := m.move(context.Background(), testNow)
+	assert.EqualError(t, err, "cannot copy the spans of a: search error")
+	assert.Equal(t, start.Add(2*moveStep), m.watermark)
+	watermark, err := m.loadWatermark(testNow)
+	require.NoError(t, err)
+	assert.True(t, start.Add(2*moveStep).Equal(watermark))
+}
+
+func TestMoveErrors(t *testing.T) {
+	hot := &mocks.Reader{}
+	hot.On("GetServices", mock.Anything).Return(nil, errors.New("services error")).Once()
+	m, _ := newTestMover(t, hot, memory.NewStore())
+	watermark := m.watermark
+
+	err := m.move(context.Background(), testNow)
+	assert.EqualError(t, err, "cannot get the services: services error")
+
+	hot.On("GetServices", mock.Anything).Return([]string{"a"}, nil)
+	hot.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errors.New("search error"))
+	err = m.move(context.Background(), testNow)
+	assert.EqualError(t, err, "cannot copy the spans of a: search error")
+
+	hot = &mocks.Reader{}
+	hot.On("GetServices", mock.Anything).Return([]string{"a"}, nil)
+	hot.On("FindTraces", mock.Anything, mock.Anything).Return([]*model.Trace{{
+		Spans: []*model.Span{spanstoretest.Span(1, 1, "a", testNow.Add(-72*time.Hour-time.Minute), "")},
+	}}, nil)
+	cold := &mocks.Writer{}
+	cold.On("WriteSpan", mock.Anything, mock.Anything).Return(errors.New("write error"))
+	m.hot, m.cold = hot, cold
+	err = m.move(context.Background(), testNow)
+	assert.EqualError(t, err, "cannot copy the spans of a: write error")
+
+	hot = &mocks.Reader{}
+	hot.On("GetServices", mock.Anything).Return([]string{}, nil)
+	m.hot = hot
+	m.watermarkFile = filepath.Join(t.TempDir(), "missing", "watermark")
+	err = m.move(context.Background(), testNow)
+	assert.ErrorContains(t, err, "cannot save the watermark: open")
+
+	// the failed runs are retried
+	assert.Equal(t, watermark, m.watermark)
+}
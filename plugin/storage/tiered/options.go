@@ -0,0 +1,78 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	demoteAfter   = "tiered.demote-after"
+	moveInterval  = "tiered.move-interval"
+	moveSpans     = "tiered.move-spans"
+	watermarkFile = "tiered.watermark-file"
+
+	defaultDemoteAfter  = 72 * time.Hour
+	defaultMoveInterval = time.Hour
+)
+
+// Options are the options of the tiered storage.
+type Options struct {
+	// DemoteAfter is the age after which spans are copied to the cold storage,
+	// and searched in it.
+	DemoteAfter time.Duration `mapstructure:"demote_after"`
+	// MoveInterval is how often spans are copied to the cold storage, zero disables the copies.
+	MoveInterval time.Duration `mapstructure:"move_interval"`
+	// MoveSpans determines whether this process copies the spans to the cold storage.
+	MoveSpans bool `mapstructure:"move_spans"`
+	// WatermarkFile is the file recording the start time before which the spans have been copied,
+	// it is required to copy the spans.
+	WatermarkFile string `mapstructure:"watermark_file"`
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.Duration(
+		demoteAfter,
+		defaultDemoteAfter,
+		"The age after which spans are copied from the hot storage to the cold storage, and also searched in the cold storage. "+
+			"It must be shorter than the retention of the hot storage by more than the move interval")
+	flagSet.Duration(
+		moveInterval,
+		defaultMoveInterval,
+		"How often the spans older than the demote age are copied to the cold storage, 0 disables the copies")
+	flagSet.Bool(
+		moveSpans,
+		false,
+		"Copies the spans older than the demote age to the cold storage from this process. "+
+			"It must be enabled on exactly one of the processes writing spans, such as a collector")
+	flagSet.String(
+		watermarkFile,
+		"",
+		"The file recording the start time before which the spans have been copied to the cold storage, "+
+			"so that the copies resume where they stopped after a restart. It is required to copy the spans, "+
+			"and should be on a persistent volume")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.DemoteAfter = v.GetDuration(demoteAfter)
+	opt.MoveInterval = v.GetDuration(moveInterval)
+	opt.MoveSpans = v.GetBool(moveSpans)
+	opt.WatermarkFile = v.GetString(watermarkFile)
+}
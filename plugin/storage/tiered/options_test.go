@@ -0,0 +1,53 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kjschnei001/jaeger/pkg/config"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	opts := &Options{}
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{})
+	opts.InitFromViper(v)
+
+	assert.Equal(t, 72*time.Hour, opts.DemoteAfter)
+	assert.Equal(t, time.Hour, opts.MoveInterval)
+	assert.False(t, opts.MoveSpans)
+	assert.Empty(t, opts.WatermarkFile)
+}
+
+func TestParseOptions(t *testing.T) {
+	opts := &Options{}
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--tiered.demote-after=24h",
+		"--tiered.move-interval=0",
+		"--tiered.move-spans=true",
+		"--tiered.watermark-file=/var/lib/jaeger/watermark",
+	})
+	opts.InitFromViper(v)
+
+	assert.Equal(t, 24*time.Hour, opts.DemoteAfter)
+	assert.Zero(t, opts.MoveInterval)
+	assert.True(t, opts.MoveSpans)
+	assert.Equal(t, "/var/lib/jaeger/watermark", opts.WatermarkFile)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+// Reader searches the spans older than the demote age in both the hot and the
+// cold storages, and the more recent spans in the hot storage only. The spans
+// found in both storages are only returned once.
+type Reader struct {
+	hot         spanstore.Reader
+	cold        spanstore.Reader
+	demoteAfter time.Duration
+	now         func() time.Time
+}
+
+// NewReader creates a new Reader.
+func NewReader(hot, cold spanstore.Reader, demoteAfter time.Duration) *Reader {
+	return &Reader{
+		hot:         hot,
+		cold:        cold,
+		demoteAfter: demoteAfter,
+		now:         time.Now,
+	}
+}
+
+// GetTrace merges the spans of the trace from both storages.
+func (r *Reader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	var hotTrace, coldTrace *model.Trace
+	err := fanOut(
+		func() (err error) {
+			hotTrace, err = getTrace(ctx, r.hot, traceID)
+			return err
+		},
+		func() (err error) {
+			coldTrace, err = getTrace(ctx, r.cold, traceID)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	trace := spanstore.MergeTraces(hotTrace, coldTrace)
+	if trace == nil {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return trace, nil
+}
+
+func getTrace(ctx context.Context, reader spanstore.Reader, traceID model.TraceID) (*model.Trace, error) {
+	trace, err := reader.GetTrace(ctx, traceID)
+	if errors.Is(err, spanstore.ErrTraceNotFound) {
+		return nil, nil
+	}
+	return trace, err
+}
+
+// GetServices returns the services of both storages.
+func (r *Reader) GetServices(ctx context.Context) ([]string, error) {
+	var hotServices, coldServices []string
+	err := fanOut(
+		func() (err error) {
+			hotServices, err = r.hot.GetServices(ctx)
+			return err
+		},
+		func() (err error) {
+			coldServices, err = r.cold.GetServices(ctx)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.MergeServices(hotServices, coldServices), nil
+}
+
+// GetOperations returns the operations of both storages.
+func (r *Reader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	var hotOperations, coldOperations []spanstore.Operation
+	err := fanOut(
+		func() (err error) {
+			hotOperations, err = r.hot.GetOperations(ctx, query)
+			return err
+		},
+		func() (err error) {
+			coldOperations, err = r.cold.GetOperations(ctx, query)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.MergeOperations(hotOperations, coldOperations), nil
+}
+
+// FindTraces searches the hot storage over the whole time range of the query,
+// and the cold storage over the part older than the demote age. The traces found
+// in both storages are merged, and the most recent ones are returned first.
+func (r *Reader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	coldQuery := r.coldQuery(query)
+	var hotTraces, coldTraces []*model.Trace
+	fns := []func() error{func() (err error) {
+		hotTraces, err = r.hot.FindTraces(ctx, query)
+		return err
+	}}
+	if coldQuery != nil {
+		fns = append(fns, func() (err error) {
+			coldTraces, err = r.cold.FindTraces(ctx, coldQuery)
+			return err
+		})
+	}
+	if err := fanOut(fns...); err != nil {
+		return nil, err
+	}
+
+	return spanstore.MergeFoundTraces(query.NumTraces, nil, hotTraces, coldTraces), nil
+}
+
+// FindTraceIDs searches both storages like FindTraces, and returns the trace
+// IDs found in the hot storage first.
+func (r *Reader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	coldQuery := r.coldQuery(query)
+	var hotTraceIDs, coldTraceIDs []model.TraceID
+	fns := []func() error{func() (err error) {
+		hotTraceIDs, err = r.hot.FindTraceIDs(ctx, query)
+		return err
+	}}
+	if coldQuery != nil {
+		fns = append(fns, func() (err error) {
+			coldTraceIDs, err = r.cold.FindTraceIDs(ctx, coldQuery)
+			return err
+		})
+	}
+	if err := fanOut(fns...); err != nil {
+		return nil, err
+	}
+
+	return spanstore.MergeTraceIDs(query.NumTraces, hotTraceIDs, coldTraceIDs), nil
+}
+
+// coldQuery restricts the query to the spans older than the demote age, or
+// returns nil if the query only covers more recent spans.
+func (r *Reader) coldQuery(query *spanstore.TraceQueryParameters) *spanstore.TraceQueryParameters {
+	demoted := r.now().Add(-r.demoteAfter)
+	if !query.StartTimeMin.Before(demoted) {
+		return nil
+	}
+	coldQuery := *query
+	if coldQuery.StartTimeMax.IsZero() || coldQuery.StartTimeMax.After(demoted) {
+		coldQuery.StartTimeMax = demoted
+	}
+	return &coldQuery
+}
+
+// fanOut runs the functions concurrently and returns their errors.
+func fanOut(fns ...func() error) error {
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		go func(i int, fn func() error) {
+			defer wg.Done()
+			errs[i] = fn()
+		}(i, fn)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
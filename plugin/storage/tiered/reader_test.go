@@ -0,0 +1,220 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/internal/spanstoretest"
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/plugin/storage/memory"
+	"github.com/kjschnei001/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+var testNow = time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+func newTestReader(hot, cold spanstore.Reader) *Reader {
+	r := NewReader(hot, cold, 72*time.Hour)
+	r.now = func() time.Time { return testNow }
+	return r
+}
+
+func TestGetTrace(t *testing.T) {
+	hot, cold := memory.NewStore(), memory.NewStore()
+	old := testNow.Add(-100 * time.Hour)
+	// span 2 is in both storages, span 3 is only left in the cold storage
+	spanstoretest.WriteSpans(t, hot, spanstoretest.Span(1, 1, "a", old.Add(time.Hour), ""), spanstoretest.Span(1, 2, "a", old, ""))
+	spanstoretest.WriteSpans(t, cold, spanstoretest.Span(1, 2, "a", old, ""), spanstoretest.Span(1, 3, "b", old, ""))
+	spanstoretest.WriteSpans(t, cold, spanstoretest.Span(2, 1, "b", old, ""))
+	r := newTestReader(hot, cold)
+
+	trace, err := r.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []model.SpanID{1, 2, 3}, spanstoretest.SpanIDs(trace))
+
+	trace, err = r.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, []model.SpanID{1}, spanstoretest.SpanIDs(trace))
+
+	_, err = r.GetTrace(context.Background(), model.NewTraceID(0, 3))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestGetTraceError(t *testing.T) {
+	cold := &mocks.Reader{}
+	cold.On("GetTrace", mock.Anything, mock.Anything).Return(nil, errors.New("cold error"))
+	r := newTestReader(memory.NewStore(), cold)
+
+	_, err := r.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.EqualError(t, err, "cold error")
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	hot, cold := memory.NewStore(), memory.NewStore()
+	spanstoretest.WriteSpans(t, hot, spanstoretest.Span(1, 1, "b", testNow, ""), spanstoretest.Span(1, 2, "c", testNow, ""))
+	spanstoretest.WriteSpans(t, cold, spanstoretest.Span(2, 1, "a", testNow, ""), spanstoretest.Span(2, 2, "b", testNow, ""))
+	cold2 := spanstoretest.Span(2, 3, "b", testNow, "")
+	cold2.OperationName = "legacy"
+	spanstoretest.WriteSpans(t, cold, cold2)
+	r := newTestReader(hot, cold)
+
+	services, err := r.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, services)
+
+	operations, err := r.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "legacy"}, {Name: "op-b"}}, operations)
+
+	failing := &mocks.Reader{}
+	failing.On("GetServices", mock.Anything).Return(nil, errors.New("cold error"))
+	failing.On("GetOperations", mock.Anything, mock.Anything).Return(nil, errors.New("cold error"))
+	r = newTestReader(hot, failing)
+	_, err = r.GetServices(context.Background())
+	assert.EqualError(t, err, "cold error")
+	_, err = r.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "b"})
+	assert.EqualError(t, err, "cold error")
+}
+
+func TestFindTraces(t *testing.T) {
+	hot, cold := memory.NewStore(), memory.NewStore()
+	old := testNow.Add(-100 * time.Hour)
+	// trace 1 is recent, trace 2 was copied but is still in the hot storage,
+	// trace 3 is only left in the cold storage
+	spanstoretest.WriteSpans(t, hot, spanstoretest.Span(1, 1, "a", testNow.Add(-time.Hour), ""))
+	spanstoretest.WriteSpans(t, hot, spanstoretest.Span(2, 1, "a", old, ""), spanstoretest.Span(2, 2, "a", old.Add(30*time.Hour), ""))
+	spanstoretest.WriteSpans(t, cold, spanstoretest.Span(2, 1, "a", old, ""))
+	spanstoretest.WriteSpans(t, cold, spanstoretest.Span(3, 1, "a", old.Add(-time.Hour), ""))
+	r := newTestReader(hot, cold)
+
+	traces, err := r.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "a",
+		StartTimeMin: testNow.Add(-200 * time.Hour),
+		StartTimeMax: testNow,
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 3)
+	assert.Equal(t, model.NewTraceID(0, 1), spanstore.TraceIDOf(traces[0]))
+	assert.Equal(t, model.NewTraceID(0, 2), spanstore.TraceIDOf(traces[1]))
+	assert.ElementsMatch(t, []model.SpanID{1, 2}, spanstoretest.SpanIDs(traces[1]))
+	assert.Equal(t, model.NewTraceID(0, 3), spanstore.TraceIDOf(traces[2]))
+
+	traces, err = r.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "a",
+		StartTimeMin: testNow.Add(-200 * time.Hour),
+		StartTimeMax: testNow,
+		NumTraces:    2,
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 2), spanstore.TraceIDOf(traces[1]))
+}
+
+func TestFindTracesRecent(t *testing.T) {
+	hot := memory.NewStore()
+	spanstoretest.WriteSpans(t, hot, spanstoretest.Span(1, 1, "a", testNow.Add(-time.Hour), ""))
+	// the cold storage is not searched, the mock fails if it is called
+	r := newTestReader(hot, &mocks.Reader{})
+
+	traces, err := r.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "a",
+		StartTimeMin: testNow.Add(-24 * time.Hour),
+		StartTimeMax: testNow,
+	})
+	require.NoError(t, err)
+	assert.Len(t, traces, 1)
+}
+
+func TestFindTracesError(t *testing.T) {
+	cold := &mocks.Reader{}
+	cold.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errors.New("cold error"))
+	r := newTestReader(memory.NewStore(), cold)
+
+	_, err := r.FindTraces(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "a"})
+	assert.EqualError(t, err, "cold error")
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	demoted := testNow.Add(-72 * time.Hour)
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:  "a",
+		StartTimeMin: testNow.Add(-200 * time.Hour),
+		StartTimeMax: testNow,
+		NumTraces:    3,
+	}
+	hot, cold := &mocks.Reader{}, &mocks.Reader{}
+	hot.On("FindTraceIDs", mock.Anything, query).
+		Return([]model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2)}, nil)
+	cold.On("FindTraceIDs", mock.Anything, mock.MatchedBy(func(q *spanstore.TraceQueryParameters) bool {
+		return q.StartTimeMin.Equal(query.StartTimeMin) && q.StartTimeMax.Equal(demoted)
+	})).Return([]model.TraceID{model.NewTraceID(0, 2), model.NewTraceID(0, 3), model.NewTraceID(0, 4)}, nil)
+	r := newTestReader(hot, cold)
+
+	traceIDs, err := r.FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2), model.NewTraceID(0, 3)}, traceIDs)
+
+	hot, cold = &mocks.Reader{}, &mocks.Reader{}
+	hot.On("FindTraceIDs", mock.Anything, mock.Anything).Return(nil, nil)
+	cold.On("FindTraceIDs", mock.Anything, mock.Anything).Return(nil, errors.New("cold error"))
+	r = newTestReader(hot, cold)
+	_, err = r.FindTraceIDs(context.Background(), query)
+	assert.EqualError(t, err, "cold error")
+
+	// the cold storage is not searched for recent spans
+	recent := &spanstore.TraceQueryParameters{ServiceName: "a", StartTimeMin: testNow.Add(-time.Hour)}
+	traceIDs, err = r.FindTraceIDs(context.Background(), recent)
+	require.NoError(t, err)
+	assert.Nil(t, traceIDs)
+}
+
+func TestColdQuery(t *testing.T) {
+	demoted := testNow.Add(-72 * time.Hour)
+	r := newTestReader(nil, nil)
+	testCases := []struct {
+		name     string
+		min, max time.Time
+		expected *spanstore.TraceQueryParameters
+	}{
+		{name: "recent", min: demoted, max: testNow},
+		{name: "unbounded", expected: &spanstore.TraceQueryParameters{StartTimeMax: demoted}},
+		{
+			name:     "overlapping",
+			min:      demoted.Add(-time.Hour),
+			max:      testNow,
+			expected: &spanstore.TraceQueryParameters{StartTimeMin: demoted.Add(-time.Hour), StartTimeMax: demoted},
+		},
+		{
+			name:     "old",
+			min:      demoted.Add(-2 * time.Hour),
+			max:      demoted.Add(-time.Hour),
+			expected: &spanstore.TraceQueryParameters{StartTimeMin: demoted.Add(-2 * time.Hour), StartTimeMax: demoted.Add(-time.Hour)},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			query := &spanstore.TraceQueryParameters{StartTimeMin: testCase.min, StartTimeMax: testCase.max}
+			assert.Equal(t, testCase.expected, r.coldQuery(query))
+		})
+	}
+}
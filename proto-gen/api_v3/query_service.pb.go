@@ -26,10 +26,41 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
+// AttributeMatchMode defines how the attributes and attribute filters of a query are matched
+// against the spans of a trace. In both modes only spans of the queried service are considered.
+type AttributeMatchMode int32
+
+const (
+	// A single span must match all attributes and attribute filters.
+	AttributeMatchMode_SAME_SPAN AttributeMatchMode = 0
+	// Each attribute and attribute filter can be matched by a different span.
+	AttributeMatchMode_ANY_SPAN_IN_TRACE AttributeMatchMode = 1
+)
+
+var AttributeMatchMode_name = map[int32]string{
+	0: "SAME_SPAN",
+	1: "ANY_SPAN_IN_TRACE",
+}
+
+var AttributeMatchMode_value = map[string]int32{
+	"SAME_SPAN":         0,
+	"ANY_SPAN_IN_TRACE": 1,
+}
+
+func (x AttributeMatchMode) String() string {
+	return proto.EnumName(AttributeMatchMode_name, int32(x))
+}
+
+func (AttributeMatchMode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_5fcb6756dc1afb8d, []int{0}
+}
+
 // Request object to get a trace.
 type GetTraceRequest struct {
 	// Hex encoded 64 or 128 bit trace ID.
-	TraceId              string   `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	TraceId string `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	// Optional. Whether to return the critical path of the trace.
+	IncludeCriticalPath  bool     `protobuf:"varint,2,opt,name=include_critical_path,json=includeCriticalPath,proto3" json:"include_critical_path,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -66,6 +97,13 @@ func (m *GetTraceRequest) GetTraceId() string {
 	return ""
 }
 
+func (m *GetTraceRequest) GetIncludeCriticalPath() bool {
+	if m != nil {
+		return m.IncludeCriticalPath
+	}
+	return false
+}
+
 // Response object with spans.
 type SpansResponseChunk struct {
 	// A list of OpenTelemetry ResourceSpans.
@@ -74,10 +112,16 @@ type SpansResponseChunk struct {
 	// Base64 is chosen to keep compatibility with JSONPb codec.
 	// [1]: https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
 	// [2]: https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#otlphttp
-	ResourceSpans        []*v1.ResourceSpans `protobuf:"bytes,1,rep,name=resource_spans,json=resourceSpans,proto3" json:"resource_spans,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+	ResourceSpans []*v1.ResourceSpans `protobuf:"bytes,1,rep,name=resource_spans,json=resourceSpans,proto3" json:"resource_spans,omitempty"`
+	// Token to pass as TraceQueryParameters.page_token to get the next page of FindTraces results.
+	// It is only set on the last chunk of the response, and is empty when there are no more results.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	// The critical path of the trace, set by GetTrace when include_critical_path is requested.
+	// It is computed on the spans as stored, without clock skew adjustments.
+	CriticalPath         []*CriticalPathSection `protobuf:"bytes,3,rep,name=critical_path,json=criticalPath,proto3" json:"critical_path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
 }
 
 func (m *SpansResponseChunk) Reset()         { *m = SpansResponseChunk{} }
@@ -111,6 +155,76 @@ func (m *SpansResponseChunk) GetResourceSpans() []*v1.ResourceSpans {
 	return nil
 }
 
+func (m *SpansResponseChunk) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+func (m *SpansResponseChunk) GetCriticalPath() []*CriticalPathSection {
+	if m != nil {
+		return m.CriticalPath
+	}
+	return nil
+}
+
+// A section of the critical path of a trace, during which the span was on the critical path.
+type CriticalPathSection struct {
+	// ID of the span, encoded like the span IDs of the ResourceSpans.
+	SpanId               []byte           `protobuf:"bytes,1,opt,name=span_id,json=spanId,proto3" json:"span_id,omitempty"`
+	StartTime            *types.Timestamp `protobuf:"bytes,2,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              *types.Timestamp `protobuf:"bytes,3,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *CriticalPathSection) Reset()         { *m = CriticalPathSection{} }
+func (m *CriticalPathSection) String() string { return proto.CompactTextString(m) }
+func (*CriticalPathSection) ProtoMessage()    {}
+func (*CriticalPathSection) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5fcb6756dc1afb8d, []int{2}
+}
+func (m *CriticalPathSection) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CriticalPathSection.Unmarshal(m, b)
+}
+func (m *CriticalPathSection) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CriticalPathSection.Marshal(b, m, deterministic)
+}
+func (m *CriticalPathSection) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CriticalPathSection.Merge(m, src)
+}
+func (m *CriticalPathSection) XXX_Size() int {
+	return xxx_messageInfo_CriticalPathSection.Size(m)
+}
+func (m *CriticalPathSection) XXX_DiscardUnknown() {
+	xxx_messageInfo_CriticalPathSection.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CriticalPathSection proto.InternalMessageInfo
+
+func (m *CriticalPathSection) GetSpanId() []byte {
+	if m != nil {
+		return m.SpanId
+	}
+	return nil
+}
+
+func (m *CriticalPathSection) GetStartTime() *types.Timestamp {
+	if m != nil {
+		return m.StartTime
+	}
+	return nil
+}
+
+func (m *CriticalPathSection) GetEndTime() *types.Timestamp {
+	if m != nil {
+		return m.EndTime
+	}
+	return nil
+}
+
 // Query parameters to find traces.
 // Note that some storage implementations do not guarantee the correct implementation of all parameters.
 type TraceQueryParameters struct {
@@ -128,7 +242,18 @@ type TraceQueryParameters struct {
 	// Span max duration. REST API uses Golang's time format e.g. 10s.
 	DurationMax *types.Duration `protobuf:"bytes,7,opt,name=duration_max,json=durationMax,proto3" json:"duration_max,omitempty"`
 	// Maximum number of traces in the response.
-	NumTraces            int32    `protobuf:"varint,8,opt,name=num_traces,json=numTraces,proto3" json:"num_traces,omitempty"`
+	NumTraces int32 `protobuf:"varint,8,opt,name=num_traces,json=numTraces,proto3" json:"num_traces,omitempty"`
+	// Attribute filters compare Span and Resource attributes using an operator,
+	// e.g. "http.status_code>=500" or "db.statement~\"SELECT.*\"". The operators are
+	// =, ==, !=, >, >=, <, <=, ~, =~, !~ and ^= (prefix), and the value may be quoted.
+	// At least one span in a trace must match all specified attributes and attribute filters,
+	// unless attribute_match_mode is ANY_SPAN_IN_TRACE.
+	AttributeFilters []string `protobuf:"bytes,9,rep,name=attribute_filters,json=attributeFilters,proto3" json:"attribute_filters,omitempty"`
+	// How attributes and attribute filters are matched against the spans of a trace.
+	AttributeMatchMode AttributeMatchMode `protobuf:"varint,10,opt,name=attribute_match_mode,json=attributeMatchMode,proto3,enum=jaeger.api_v3.AttributeMatchMode" json:"attribute_match_mode,omitempty"`
+	// Optional. The next_page_token returned by a previous FindTraces call with the
+	// same query, to get the following traces.
+	PageToken            string   `protobuf:"bytes,11,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -138,7 +263,7 @@ func (m *TraceQueryParameters) Reset()         { *m = TraceQueryParameters{} }
 func (m *TraceQueryParameters) String() string { return proto.CompactTextString(m) }
 func (*TraceQueryParameters) ProtoMessage()    {}
 func (*TraceQueryParameters) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5fcb6756dc1afb8d, []int{2}
+	return fileDescriptor_5fcb6756dc1afb8d, []int{3}
 }
 func (m *TraceQueryParameters) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_TraceQueryParameters.Unmarshal(m, b)
@@ -214,6 +339,67 @@ func (m *TraceQueryParameters) GetNumTraces() int32 {
 	return 0
 }
 
+func (m *TraceQueryParameters) GetAttributeFilters() []string {
+	if m != nil {
+		return m.AttributeFilters
+	}
+	return nil
+}
+
+func (m *TraceQueryParameters) GetAttributeMatchMode() AttributeMatchMode {
+	if m != nil {
+		return m.AttributeMatchMode
+	}
+	return AttributeMatchMode_SAME_SPAN
+}
+
+func (m *TraceQueryParameters) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// Request object to get several traces.
+type GetTracesRequest struct {
+	// Hex encoded 64 or 128 bit trace IDs.
+	TraceIds             []string `protobuf:"bytes,1,rep,name=trace_ids,json=traceIds,proto3" json:"trace_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetTracesRequest) Reset()         { *m = GetTracesRequest{} }
+func (m *GetTracesRequest) String() string { return proto.CompactTextString(m) }
+func (*GetTracesRequest) ProtoMessage()    {}
+func (*GetTracesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5fcb6756dc1afb8d, []int{4}
+}
+func (m *GetTracesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetTracesRequest.Unmarshal(m, b)
+}
+func (m *GetTracesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetTracesRequest.Marshal(b, m, deterministic)
+}
+func (m *GetTracesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetTracesRequest.Merge(m, src)
+}
+func (m *GetTracesRequest) XXX_Size() int {
+	return xxx_messageInfo_GetTracesRequest.Size(m)
+}
+func (m *GetTracesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetTracesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetTracesRequest proto.InternalMessageInfo
+
+func (m *GetTracesRequest) GetTraceIds() []string {
+	if m != nil {
+		return m.TraceIds
+	}
+	return nil
+}
+
 // Request object to search traces.
 type FindTracesRequest struct {
 	Query                *TraceQueryParameters `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
@@ -226,7 +412,7 @@ func (m *FindTracesRequest) Reset()         { *m = FindTracesRequest{} }
 func (m *FindTracesRequest) String() string { return proto.CompactTextString(m) }
 func (*FindTracesRequest) ProtoMessage()    {}
 func (*FindTracesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5fcb6756dc1afb8d, []int{3}
+	return fileDescriptor_5fcb6756dc1afb8d, []int{5}
 }
 func (m *FindTracesRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FindTracesRequest.Unmarshal(m, b)
@@ -264,7 +450,7 @@ func (m *GetServicesRequest) Reset()         { *m = GetServicesRequest{} }
 func (m *GetServicesRequest) String() string { return proto.CompactTextString(m) }
 func (*GetServicesRequest) ProtoMessage()    {}
 func (*GetServicesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5fcb6756dc1afb8d, []int{4}
+	return fileDescriptor_5fcb6756dc1afb8d, []int{6}
 }
 func (m *GetServicesRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetServicesRequest.Unmarshal(m, b)
@@ -296,7 +482,7 @@ func (m *GetServicesResponse) Reset()         { *m = GetServicesResponse{} }
 func (m *GetServicesResponse) String() string { return proto.CompactTextString(m) }
 func (*GetServicesResponse) ProtoMessage()    {}
 func (*GetServicesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5fcb6756dc1afb8d, []int{5}
+	return fileDescriptor_5fcb6756dc1afb8d, []int{7}
 }
 func (m *GetServicesResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetServicesResponse.Unmarshal(m, b)
@@ -338,7 +524,7 @@ func (m *GetOperationsRequest) Reset()         { *m = GetOperationsRequest{} }
 func (m *GetOperationsRequest) String() string { return proto.CompactTextString(m) }
 func (*GetOperationsRequest) ProtoMessage()    {}
 func (*GetOperationsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5fcb6756dc1afb8d, []int{6}
+	return fileDescriptor_5fcb6756dc1afb8d, []int{8}
 }
 func (m *GetOperationsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetOperationsRequest.Unmarshal(m, b)
@@ -385,7 +571,7 @@ func (m *Operation) Reset()         { *m = Operation{} }
 func (m *Operation) String() string { return proto.CompactTextString(m) }
 func (*Operation) ProtoMessage()    {}
 func (*Operation) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5fcb6756dc1afb8d, []int{7}
+	return fileDescriptor_5fcb6756dc1afb8d, []int{9}
 }
 func (m *Operation) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Operation.Unmarshal(m, b)
@@ -431,7 +617,7 @@ func (m *GetOperationsResponse) Reset()         { *m = GetOperationsResponse{} }
 func (m *GetOperationsResponse) String() string { return proto.CompactTextString(m) }
 func (*GetOperationsResponse) ProtoMessage()    {}
 func (*GetOperationsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_5fcb6756dc1afb8d, []int{8}
+	return fileDescriptor_5fcb6756dc1afb8d, []int{10}
 }
 func (m *GetOperationsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetOperationsResponse.Unmarshal(m, b)
@@ -459,10 +645,13 @@ func (m *GetOperationsResponse) GetOperations() []*Operation {
 }
 
 func init() {
+	proto.RegisterEnum("jaeger.api_v3.AttributeMatchMode", AttributeMatchMode_name, AttributeMatchMode_value)
 	proto.RegisterType((*GetTraceRequest)(nil), "jaeger.api_v3.GetTraceRequest")
 	proto.RegisterType((*SpansResponseChunk)(nil), "jaeger.api_v3.SpansResponseChunk")
+	proto.RegisterType((*CriticalPathSection)(nil), "jaeger.api_v3.CriticalPathSection")
 	proto.RegisterType((*TraceQueryParameters)(nil), "jaeger.api_v3.TraceQueryParameters")
 	proto.RegisterMapType((map[string]string)(nil), "jaeger.api_v3.TraceQueryParameters.AttributesEntry")
+	proto.RegisterType((*GetTracesRequest)(nil), "jaeger.api_v3.GetTracesRequest")
 	proto.RegisterType((*FindTracesRequest)(nil), "jaeger.api_v3.FindTracesRequest")
 	proto.RegisterType((*GetServicesRequest)(nil), "jaeger.api_v3.GetServicesRequest")
 	proto.RegisterType((*GetServicesResponse)(nil), "jaeger.api_v3.GetServicesResponse")
@@ -474,50 +663,67 @@ func init() {
 func init() { proto.RegisterFile("query_service.proto", fileDescriptor_5fcb6756dc1afb8d) }
 
 var fileDescriptor_5fcb6756dc1afb8d = []byte{
-	// 675 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0x7f, 0x4f, 0xdb, 0x3a,
-	0x14, 0x25, 0x40, 0xa1, 0xbd, 0x6d, 0xe1, 0x3d, 0xd3, 0xa7, 0x17, 0x32, 0x8d, 0xb5, 0x61, 0x93,
-	0x2a, 0x6d, 0x4a, 0x47, 0xf9, 0x87, 0x4d, 0x4c, 0xda, 0x6f, 0x34, 0x4d, 0xb0, 0x11, 0xd8, 0xfe,
-	0x98, 0x26, 0x45, 0x86, 0xde, 0x95, 0x0c, 0xe2, 0x04, 0xdb, 0xa9, 0xda, 0xcf, 0xb1, 0xef, 0xb9,
-	0xcf, 0x30, 0xc5, 0x76, 0xb2, 0x36, 0x9d, 0x10, 0xfb, 0x2b, 0xb9, 0xf6, 0x39, 0xc7, 0xd7, 0xf7,
-	0xdc, 0x6b, 0xd8, 0xb8, 0x4e, 0x91, 0x4f, 0x02, 0x81, 0x7c, 0x14, 0x9e, 0xa3, 0x97, 0xf0, 0x58,
-	0xc6, 0xa4, 0xf9, 0x9d, 0xe2, 0x10, 0xb9, 0x47, 0x93, 0x30, 0x18, 0xed, 0x3a, 0xdd, 0x38, 0x41,
-	0x26, 0xf1, 0x0a, 0x23, 0x94, 0x7c, 0xd2, 0x53, 0x98, 0x9e, 0xe4, 0xf4, 0x1c, 0x7b, 0xa3, 0x1d,
-	0xfd, 0xa3, 0x89, 0xce, 0xbd, 0x61, 0x1c, 0x0f, 0xaf, 0x50, 0x43, 0xce, 0xd2, 0x6f, 0x3d, 0x19,
-	0x46, 0x28, 0x24, 0x8d, 0x12, 0x03, 0xd8, 0x2a, 0x03, 0x06, 0x29, 0xa7, 0x32, 0x8c, 0x99, 0xde,
-	0x77, 0x1f, 0xc1, 0xfa, 0x01, 0xca, 0xd3, 0x4c, 0xd2, 0xc7, 0xeb, 0x14, 0x85, 0x24, 0x9b, 0x50,
-	0x55, 0x47, 0x04, 0xe1, 0xc0, 0xb6, 0xda, 0x56, 0xb7, 0xe6, 0xaf, 0xaa, 0xf8, 0xdd, 0xc0, 0xbd,
-	0x00, 0x72, 0x92, 0x50, 0x26, 0x7c, 0x14, 0x49, 0xcc, 0x04, 0xbe, 0xba, 0x48, 0xd9, 0x25, 0xf1,
-	0x61, 0x8d, 0xa3, 0x88, 0x53, 0x7e, 0x8e, 0x81, 0xc8, 0xb6, 0x6d, 0xab, 0xbd, 0xd4, 0xad, 0xf7,
-	0x1f, 0x7a, 0x33, 0xf7, 0xd0, 0x27, 0x7a, 0x3a, 0xfd, 0xd1, 0x8e, 0xe7, 0x1b, 0x8e, 0x56, 0x6c,
-	0xf2, 0xe9, 0xd0, 0xfd, 0xb1, 0x0c, 0x2d, 0x95, 0xd5, 0x71, 0x56, 0xae, 0x8f, 0x94, 0xd3, 0x08,
-	0x25, 0x72, 0x41, 0x3a, 0xd0, 0x30, 0xb5, 0x0b, 0x18, 0x8d, 0xd0, 0x64, 0x58, 0x37, 0x6b, 0x47,
-	0x34, 0x42, 0xf2, 0x00, 0xd6, 0xe2, 0x04, 0xf5, 0x35, 0x35, 0x68, 0x51, 0x81, 0x9a, 0xc5, 0xaa,
-	0x82, 0x9d, 0x00, 0x50, 0x29, 0x79, 0x78, 0x96, 0x4a, 0x14, 0xf6, 0x92, 0x4a, 0x79, 0xd7, 0x9b,
-	0x71, 0xc2, 0xfb, 0x53, 0x0a, 0xde, 0x8b, 0x82, 0xf5, 0x86, 0x49, 0x3e, 0xf1, 0xa7, 0x64, 0xc8,
-	0x73, 0x58, 0x13, 0x92, 0x72, 0x19, 0x64, 0x46, 0x04, 0x51, 0xc8, 0xec, 0xe5, 0xb6, 0xd5, 0xad,
-	0xf7, 0x1d, 0x4f, 0x1b, 0xe1, 0xe5, 0x46, 0x78, 0xa7, 0xb9, 0x53, 0x7e, 0x43, 0x31, 0xb2, 0xf8,
-	0x30, 0x64, 0x65, 0x05, 0x3a, 0xb6, 0x2b, 0x7f, 0xa3, 0x40, 0xc7, 0x64, 0x1f, 0x1a, 0xb9, 0xcb,
-	0x2a, 0x83, 0x15, 0xc5, 0xdf, 0x9c, 0xe3, 0xbf, 0x36, 0x20, 0xbf, 0x9e, 0xc3, 0xb3, 0xf3, 0x67,
-	0xd8, 0x74, 0x6c, 0xaf, 0xde, 0x9e, 0x4d, 0xc7, 0xe4, 0x2e, 0x00, 0x4b, 0xa3, 0x40, 0x99, 0x2c,
-	0xec, 0x6a, 0xdb, 0xea, 0x56, 0xfc, 0x1a, 0x4b, 0x23, 0x55, 0x48, 0xe1, 0x3c, 0x83, 0xf5, 0x52,
-	0xf5, 0xc8, 0x3f, 0xb0, 0x74, 0x89, 0x13, 0xe3, 0x63, 0xf6, 0x4b, 0x5a, 0x50, 0x19, 0xd1, 0xab,
-	0x34, 0xb7, 0x4d, 0x07, 0x4f, 0x17, 0xf7, 0x2c, 0xf7, 0x08, 0xfe, 0x7d, 0x1b, 0xb2, 0x81, 0x16,
-	0xcb, 0xfb, 0xf5, 0x09, 0x54, 0xd4, 0x4c, 0x29, 0x89, 0x7a, 0x7f, 0xfb, 0x16, 0x16, 0xfa, 0x9a,
-	0xe1, 0xb6, 0x80, 0x1c, 0xa0, 0x3c, 0xd1, 0xbd, 0x93, 0x0b, 0xba, 0x3b, 0xb0, 0x31, 0xb3, 0xaa,
-	0x7b, 0x9d, 0x38, 0x50, 0x35, 0x5d, 0xa6, 0x1b, 0xbc, 0xe6, 0x17, 0xb1, 0x7b, 0x08, 0xad, 0x03,
-	0x94, 0x1f, 0xf2, 0xfe, 0x2a, 0x72, 0xb3, 0x61, 0xd5, 0x60, 0xf2, 0x51, 0x32, 0x21, 0xb9, 0x03,
-	0xb5, 0x6c, 0x56, 0x82, 0xcb, 0x90, 0x0d, 0xcc, 0x45, 0xab, 0xd9, 0xc2, 0xfb, 0x90, 0x0d, 0xdc,
-	0x7d, 0xa8, 0x15, 0x5a, 0x84, 0xc0, 0xf2, 0x54, 0xa7, 0xab, 0xff, 0x9b, 0xd9, 0xc7, 0xf0, 0x5f,
-	0x29, 0x19, 0x73, 0x83, 0x3d, 0x80, 0x62, 0x04, 0xf2, 0x21, 0xb5, 0x4b, 0xe5, 0x2a, 0x68, 0xfe,
-	0x14, 0xb6, 0xff, 0x73, 0x11, 0x1a, 0xaa, 0x86, 0xa6, 0x2a, 0xe4, 0x18, 0xaa, 0xf9, 0xbb, 0x41,
-	0xb6, 0x4a, 0x12, 0xa5, 0x07, 0xc5, 0xe9, 0x94, 0xf6, 0xe7, 0x9f, 0x10, 0x77, 0xe1, 0xb1, 0x45,
-	0x3e, 0x01, 0xfc, 0x36, 0x97, 0xb4, 0x4b, 0xa4, 0x39, 0xdf, 0x6f, 0x2b, 0xfb, 0x19, 0xea, 0x53,
-	0x6e, 0x92, 0xce, 0x7c, 0xb2, 0x25, 0xff, 0x1d, 0xf7, 0x26, 0x88, 0x96, 0x77, 0x17, 0xc8, 0x57,
-	0x68, 0xce, 0x54, 0x99, 0x6c, 0xcf, 0xd3, 0xe6, 0x1a, 0xc2, 0xb9, 0x7f, 0x33, 0x28, 0x57, 0x7f,
-	0xd9, 0x81, 0xff, 0xc3, 0xd8, 0x60, 0xb3, 0x61, 0x0a, 0xd9, 0xd0, 0x50, 0xbe, 0xac, 0xe8, 0xef,
-	0xd9, 0x8a, 0x1a, 0xc5, 0xdd, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xd4, 0x58, 0x79, 0x69, 0x52,
-	0x06, 0x00, 0x00,
+	// 946 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xff, 0x6e, 0xda, 0x56,
+	0x14, 0xae, 0x93, 0x12, 0xf0, 0x01, 0x12, 0x72, 0x43, 0x54, 0x97, 0x69, 0x2d, 0x71, 0xb7, 0x09,
+	0xad, 0x92, 0x59, 0x88, 0x26, 0xb5, 0x55, 0x27, 0x8d, 0x66, 0x69, 0x14, 0x4d, 0xb0, 0xc4, 0x64,
+	0x93, 0x36, 0x4d, 0xf2, 0x6e, 0xf0, 0x29, 0x78, 0xc1, 0xd7, 0xae, 0x7d, 0x8d, 0xc8, 0xdb, 0xec,
+	0x21, 0xf6, 0x32, 0x7b, 0x89, 0x3d, 0xc3, 0x74, 0xaf, 0xaf, 0x1d, 0x30, 0x5d, 0x9a, 0xfe, 0x85,
+	0xcf, 0xbd, 0xdf, 0x77, 0xee, 0x39, 0xdf, 0xf9, 0x01, 0xec, 0xbd, 0x4f, 0x30, 0xba, 0x71, 0x62,
+	0x8c, 0xe6, 0xde, 0x18, 0xad, 0x30, 0x0a, 0x78, 0x40, 0xea, 0x7f, 0x52, 0x9c, 0x60, 0x64, 0xd1,
+	0xd0, 0x73, 0xe6, 0x47, 0xad, 0x4e, 0x10, 0x22, 0xe3, 0x38, 0x43, 0x1f, 0x79, 0x74, 0xd3, 0x95,
+	0x98, 0x2e, 0x8f, 0xe8, 0x18, 0xbb, 0xf3, 0xc3, 0xf4, 0x23, 0x25, 0xb6, 0x9e, 0x4e, 0x82, 0x60,
+	0x32, 0xc3, 0x14, 0x72, 0x95, 0xbc, 0xeb, 0x72, 0xcf, 0xc7, 0x98, 0x53, 0x3f, 0x54, 0x80, 0x27,
+	0x45, 0x80, 0x9b, 0x44, 0x94, 0x7b, 0x01, 0x4b, 0xef, 0xcd, 0x3f, 0x60, 0xe7, 0x14, 0xf9, 0xa5,
+	0x70, 0x69, 0xe3, 0xfb, 0x04, 0x63, 0x4e, 0x1e, 0x43, 0x45, 0x3e, 0xe1, 0x78, 0xae, 0xa1, 0xb5,
+	0xb5, 0x8e, 0x6e, 0x97, 0xa5, 0x7d, 0xe6, 0x92, 0x1e, 0xec, 0x7b, 0x6c, 0x3c, 0x4b, 0x5c, 0x74,
+	0xc6, 0x91, 0xc7, 0xbd, 0x31, 0x9d, 0x39, 0x21, 0xe5, 0x53, 0x63, 0xa3, 0xad, 0x75, 0x2a, 0xf6,
+	0x9e, 0xba, 0x3c, 0x56, 0x77, 0xe7, 0x94, 0x4f, 0xcd, 0x7f, 0x34, 0x20, 0xa3, 0x90, 0xb2, 0xd8,
+	0xc6, 0x38, 0x0c, 0x58, 0x8c, 0xc7, 0xd3, 0x84, 0x5d, 0x13, 0x1b, 0xb6, 0x23, 0x8c, 0x83, 0x24,
+	0x1a, 0xa3, 0x13, 0x8b, 0x6b, 0x43, 0x6b, 0x6f, 0x76, 0xaa, 0xbd, 0xe7, 0xd6, 0x4a, 0xf2, 0x69,
+	0x98, 0x56, 0x9a, 0xf3, 0xfc, 0xd0, 0xb2, 0x15, 0x27, 0xf5, 0x58, 0x8f, 0x96, 0x4d, 0xf2, 0x15,
+	0xec, 0x30, 0x5c, 0x70, 0x27, 0xa4, 0x13, 0x74, 0x78, 0x70, 0x8d, 0x4c, 0x06, 0xa6, 0xdb, 0x75,
+	0x71, 0x7c, 0x4e, 0x27, 0x78, 0x29, 0x0e, 0xc9, 0x29, 0xd4, 0x57, 0xc3, 0xdf, 0x94, 0x4f, 0x9b,
+	0xd6, 0x4a, 0x19, 0xac, 0xe5, 0x34, 0x46, 0x38, 0x16, 0xaa, 0xd9, 0xb5, 0xf1, 0x72, 0x6e, 0x7f,
+	0x69, 0xb0, 0xf7, 0x01, 0x14, 0x79, 0x04, 0x65, 0x91, 0x53, 0xa6, 0x60, 0xcd, 0xde, 0x12, 0xe6,
+	0x99, 0x4b, 0x5e, 0x02, 0xc4, 0x9c, 0x46, 0xdc, 0x11, 0x75, 0x92, 0xc1, 0x55, 0x7b, 0x2d, 0x2b,
+	0xad, 0x91, 0x95, 0xd5, 0xc8, 0xba, 0xcc, 0x8a, 0x68, 0xeb, 0x12, 0x2d, 0x6c, 0xf2, 0x2d, 0x54,
+	0x90, 0xb9, 0x29, 0x71, 0xf3, 0xa3, 0xc4, 0x32, 0x32, 0x57, 0x58, 0xe6, 0xdf, 0x25, 0x68, 0xca,
+	0xf2, 0x5e, 0x88, 0xbe, 0x3b, 0xa7, 0x11, 0xf5, 0x91, 0x63, 0x14, 0x93, 0x03, 0xa8, 0xa9, 0x26,
+	0x74, 0x18, 0xf5, 0x51, 0x95, 0xba, 0xaa, 0xce, 0x86, 0xd4, 0x47, 0xf2, 0x25, 0x6c, 0x07, 0x21,
+	0xa6, 0xfd, 0x92, 0x82, 0x94, 0x9c, 0xf9, 0xa9, 0x84, 0x8d, 0x00, 0x28, 0xe7, 0x91, 0x77, 0x95,
+	0x70, 0x8c, 0x95, 0x96, 0x47, 0x05, 0x2d, 0x3f, 0x14, 0x82, 0xd5, 0xcf, 0x59, 0x27, 0x8c, 0x47,
+	0x37, 0xf6, 0x92, 0x1b, 0xf2, 0x3d, 0x6c, 0xdf, 0x2a, 0xe5, 0xf8, 0x1e, 0x33, 0x1e, 0x7e, 0x34,
+	0xe9, 0x5a, 0xae, 0xd6, 0xc0, 0x63, 0x45, 0x0f, 0x74, 0x61, 0x94, 0x3e, 0xc5, 0x03, 0x5d, 0x90,
+	0xd7, 0x50, 0xcb, 0xc6, 0x45, 0x46, 0xb0, 0x25, 0xf9, 0x8f, 0xd7, 0xf8, 0x3f, 0x28, 0x90, 0x5d,
+	0xcd, 0xe0, 0xe2, 0xfd, 0x15, 0x36, 0x5d, 0x18, 0xe5, 0xfb, 0xb3, 0xe9, 0x82, 0x7c, 0x0e, 0xc0,
+	0x12, 0xdf, 0x91, 0x8d, 0x1f, 0x1b, 0x95, 0xb6, 0xd6, 0x29, 0xd9, 0x3a, 0x4b, 0x7c, 0x29, 0x64,
+	0x4c, 0x9e, 0xc3, 0x6e, 0x2e, 0x96, 0xf3, 0xce, 0x9b, 0x09, 0x3d, 0x0d, 0xbd, 0xbd, 0xd9, 0xd1,
+	0xed, 0x46, 0x7e, 0xf1, 0x36, 0x3d, 0x27, 0x23, 0x68, 0xde, 0x82, 0x7d, 0xca, 0xc7, 0x53, 0xc7,
+	0x0f, 0x5c, 0x34, 0xa0, 0xad, 0x75, 0xb6, 0x7b, 0x07, 0x85, 0x52, 0xe5, 0x55, 0x19, 0x08, 0xe4,
+	0x20, 0x70, 0xd1, 0x26, 0x74, 0xed, 0x4c, 0x04, 0xb8, 0x34, 0x67, 0x55, 0xd9, 0x18, 0x7a, 0x98,
+	0xcd, 0x58, 0xeb, 0x3b, 0xd8, 0x29, 0x94, 0x97, 0x34, 0x60, 0xf3, 0x1a, 0x6f, 0x54, 0xa3, 0x89,
+	0x4f, 0xd2, 0x84, 0xd2, 0x9c, 0xce, 0x92, 0xac, 0xaf, 0x52, 0xe3, 0xd5, 0xc6, 0x0b, 0xcd, 0xec,
+	0x42, 0x23, 0xdb, 0x4b, 0x71, 0xb6, 0x98, 0x3e, 0x03, 0x3d, 0x5b, 0x4c, 0xe9, 0xb6, 0xd0, 0xed,
+	0x8a, 0xda, 0x4c, 0xb1, 0x39, 0x84, 0xdd, 0xb7, 0x1e, 0x73, 0x57, 0x19, 0x2f, 0xa1, 0x24, 0xd7,
+	0xad, 0x7c, 0xb3, 0xda, 0x7b, 0x76, 0x8f, 0xa6, 0xb4, 0x53, 0x86, 0xd9, 0x04, 0x72, 0x8a, 0x7c,
+	0x94, 0x4e, 0x43, 0xe6, 0xd0, 0x3c, 0x84, 0xbd, 0x95, 0xd3, 0x74, 0xa3, 0x91, 0x16, 0x54, 0xd4,
+	0xdc, 0xe4, 0x81, 0x65, 0xb6, 0x39, 0x80, 0xe6, 0x29, 0xf2, 0x9f, 0xb2, 0x89, 0xc9, 0x63, 0x33,
+	0xa0, 0xac, 0x30, 0xd9, 0x96, 0x55, 0xa6, 0xc8, 0x53, 0x6e, 0x8f, 0x6b, 0x8f, 0xb9, 0x4a, 0x99,
+	0x8a, 0x38, 0xf8, 0xd1, 0x63, 0xae, 0xf9, 0x1a, 0xf4, 0xdc, 0x17, 0x21, 0xf0, 0x70, 0x69, 0x76,
+	0xe5, 0xf7, 0xdd, 0xec, 0x0b, 0xd8, 0x2f, 0x04, 0xa3, 0x32, 0x78, 0x01, 0x90, 0x0f, 0x75, 0xb6,
+	0x8a, 0x8d, 0x82, 0x5c, 0x39, 0xcd, 0x5e, 0xc2, 0x7e, 0xfd, 0x0a, 0xc8, 0x7a, 0xc7, 0x90, 0x3a,
+	0xe8, 0xa3, 0xfe, 0xe0, 0xc4, 0x19, 0x9d, 0xf7, 0x87, 0x8d, 0x07, 0x64, 0x1f, 0x76, 0xfb, 0xc3,
+	0x5f, 0xa5, 0xe5, 0x9c, 0x0d, 0x9d, 0x4b, 0xbb, 0x7f, 0x7c, 0xd2, 0xd0, 0x7a, 0xff, 0x6e, 0x40,
+	0x4d, 0xea, 0xaf, 0x14, 0x25, 0x17, 0x50, 0xc9, 0xca, 0x4e, 0x9e, 0x14, 0x9e, 0x2f, 0xfc, 0x4f,
+	0xb5, 0x8a, 0x7d, 0xbb, 0xfe, 0x27, 0x63, 0x3e, 0xf8, 0x46, 0x23, 0x3f, 0x03, 0xdc, 0x36, 0x06,
+	0x69, 0x17, 0x48, 0x6b, 0x3d, 0x73, 0x5f, 0xb7, 0xbf, 0x40, 0x75, 0xa9, 0x13, 0xc8, 0xc1, 0x7a,
+	0xb0, 0x85, 0xde, 0x69, 0x99, 0x77, 0x41, 0x52, 0xf7, 0xe6, 0x03, 0xf2, 0x3b, 0xd4, 0x57, 0x2a,
+	0x44, 0x9e, 0xad, 0xd3, 0xd6, 0x9a, 0xa9, 0xf5, 0xc5, 0xdd, 0xa0, 0xcc, 0x7b, 0x6f, 0x0a, 0xbb,
+	0x6f, 0x44, 0x8d, 0x56, 0x44, 0x1f, 0x81, 0x9e, 0xcf, 0x1a, 0x79, 0xfa, 0x3f, 0xaa, 0x7f, 0xa2,
+	0x3e, 0x6f, 0x0e, 0xe0, 0x91, 0x17, 0x28, 0xa8, 0x18, 0x52, 0x8f, 0x4d, 0x14, 0xe3, 0xb7, 0xad,
+	0xf4, 0xf7, 0x6a, 0x4b, 0xae, 0xc0, 0xa3, 0xff, 0x06, 0x00, 0x0d, 0x0b, 0x56, 0xe8, 0x13, 0x09,
+	0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -780,3 +986,108 @@ var _QueryService_serviceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "query_service.proto",
 }
+
+// BatchQueryServiceClient is the client API for BatchQueryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type BatchQueryServiceClient interface {
+	// GetTraces returns the traces with the given IDs from the primary or archive storage,
+	// one SpansResponseChunk per trace. The traces that are not found are skipped.
+	// See QueryService.GetTrace for JSON unmarshalling.
+	GetTraces(ctx context.Context, in *GetTracesRequest, opts ...grpc.CallOption) (BatchQueryService_GetTracesClient, error)
+}
+
+type batchQueryServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewBatchQueryServiceClient(cc *grpc.ClientConn) BatchQueryServiceClient {
+	return &batchQueryServiceClient{cc}
+}
+
+func (c *batchQueryServiceClient) GetTraces(ctx context.Context, in *GetTracesRequest, opts ...grpc.CallOption) (BatchQueryService_GetTracesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_BatchQueryService_serviceDesc.Streams[0], "/jaeger.api_v3.BatchQueryService/GetTraces", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &batchQueryServiceGetTracesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BatchQueryService_GetTracesClient interface {
+	Recv() (*SpansResponseChunk, error)
+	grpc.ClientStream
+}
+
+type batchQueryServiceGetTracesClient struct {
+	grpc.ClientStream
+}
+
+func (x *batchQueryServiceGetTracesClient) Recv() (*SpansResponseChunk, error) {
+	m := new(SpansResponseChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BatchQueryServiceServer is the server API for BatchQueryService service.
+type BatchQueryServiceServer interface {
+	// GetTraces returns the traces with the given IDs from the primary or archive storage,
+	// one SpansResponseChunk per trace. The traces that are not found are skipped.
+	// See QueryService.GetTrace for JSON unmarshalling.
+	GetTraces(*GetTracesRequest, BatchQueryService_GetTracesServer) error
+}
+
+// UnimplementedBatchQueryServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedBatchQueryServiceServer struct {
+}
+
+func (*UnimplementedBatchQueryServiceServer) GetTraces(req *GetTracesRequest, srv BatchQueryService_GetTracesServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetTraces not implemented")
+}
+
+func RegisterBatchQueryServiceServer(s *grpc.Server, srv BatchQueryServiceServer) {
+	s.RegisterService(&_BatchQueryService_serviceDesc, srv)
+}
+
+func _BatchQueryService_GetTraces_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetTracesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BatchQueryServiceServer).GetTraces(m, &batchQueryServiceGetTracesServer{stream})
+}
+
+type BatchQueryService_GetTracesServer interface {
+	Send(*SpansResponseChunk) error
+	grpc.ServerStream
+}
+
+type batchQueryServiceGetTracesServer struct {
+	grpc.ServerStream
+}
+
+func (x *batchQueryServiceGetTracesServer) Send(m *SpansResponseChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _BatchQueryService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "jaeger.api_v3.BatchQueryService",
+	HandlerType: (*BatchQueryServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetTraces",
+			Handler:       _BatchQueryService_GetTraces_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "query_service.proto",
+}
@@ -33,6 +33,10 @@ var _ = utilities.NewDoubleArray
 var _ = descriptor.ForMessage
 var _ = metadata.Join
 
+var (
+	filter_QueryService_GetTrace_0 = &utilities.DoubleArray{Encoding: map[string]int{"trace_id": 0}, Base: []int{1, 1, 0}, Check: []int{0, 1, 2}}
+)
+
 func request_QueryService_GetTrace_0(ctx context.Context, marshaler runtime.Marshaler, client QueryServiceClient, req *http.Request, pathParams map[string]string) (QueryService_GetTraceClient, runtime.ServerMetadata, error) {
 	var protoReq GetTraceRequest
 	var metadata runtime.ServerMetadata
@@ -55,6 +59,13 @@ func request_QueryService_GetTrace_0(ctx context.Context, marshaler runtime.Mars
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", "trace_id", err)
 	}
 
+	if err := req.ParseForm(); err != nil {
+		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if err := runtime.PopulateQueryParameters(&protoReq, req.Form, filter_QueryService_GetTrace_0); err != nil {
+		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
 	stream, err := client.GetTrace(ctx, &protoReq)
 	if err != nil {
 		return nil, metadata, err
@@ -150,6 +161,34 @@ func local_request_QueryService_GetOperations_0(ctx context.Context, marshaler r
 
 }
 
+var (
+	filter_BatchQueryService_GetTraces_0 = &utilities.DoubleArray{Encoding: map[string]int{}, Base: []int(nil), Check: []int(nil)}
+)
+
+func request_BatchQueryService_GetTraces_0(ctx context.Context, marshaler runtime.Marshaler, client BatchQueryServiceClient, req *http.Request, pathParams map[string]string) (BatchQueryService_GetTracesClient, runtime.ServerMetadata, error) {
+	var protoReq GetTracesRequest
+	var metadata runtime.ServerMetadata
+
+	if err := req.ParseForm(); err != nil {
+		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if err := runtime.PopulateQueryParameters(&protoReq, req.Form, filter_BatchQueryService_GetTraces_0); err != nil {
+		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	stream, err := client.GetTraces(ctx, &protoReq)
+	if err != nil {
+		return nil, metadata, err
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return nil, metadata, err
+	}
+	metadata.HeaderMD = header
+	return stream, metadata, nil
+
+}
+
 // RegisterQueryServiceHandlerServer registers the http handlers for service QueryService to "mux".
 // UnaryRPC     :call QueryServiceServer directly.
 // StreamingRPC :currently unsupported pending https://github.com/grpc/grpc-go/issues/906.
@@ -219,6 +258,22 @@ func RegisterQueryServiceHandlerServer(ctx context.Context, mux *runtime.ServeMu
 	return nil
 }
 
+// RegisterBatchQueryServiceHandlerServer registers the http handlers for service BatchQueryService to "mux".
+// UnaryRPC     :call BatchQueryServiceServer directly.
+// StreamingRPC :currently unsupported pending https://github.com/grpc/grpc-go/issues/906.
+// Note that using this registration option will cause many gRPC library features to stop working. Consider using RegisterBatchQueryServiceHandlerFromEndpoint instead.
+func RegisterBatchQueryServiceHandlerServer(ctx context.Context, mux *runtime.ServeMux, server BatchQueryServiceServer) error {
+
+	mux.Handle("GET", pattern_BatchQueryService_GetTraces_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		err := status.Error(codes.Unimplemented, "streaming calls are not yet supported in the in-process transport")
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+		return
+	})
+
+	return nil
+}
+
 // RegisterQueryServiceHandlerFromEndpoint is same as RegisterQueryServiceHandler but
 // automatically dials to "endpoint" and closes the connection when "ctx" gets done.
 func RegisterQueryServiceHandlerFromEndpoint(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error) {
@@ -359,3 +414,72 @@ var (
 
 	forward_QueryService_GetOperations_0 = runtime.ForwardResponseMessage
 )
+
+// RegisterBatchQueryServiceHandlerFromEndpoint is same as RegisterBatchQueryServiceHandler but
+// automatically dials to "endpoint" and closes the connection when "ctx" gets done.
+func RegisterBatchQueryServiceHandlerFromEndpoint(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error) {
+	conn, err := grpc.Dial(endpoint, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if cerr := conn.Close(); cerr != nil {
+				grpclog.Infof("Failed to close conn to %s: %v", endpoint, cerr)
+			}
+			return
+		}
+		go func() {
+			<-ctx.Done()
+			if cerr := conn.Close(); cerr != nil {
+				grpclog.Infof("Failed to close conn to %s: %v", endpoint, cerr)
+			}
+		}()
+	}()
+
+	return RegisterBatchQueryServiceHandler(ctx, mux, conn)
+}
+
+// RegisterBatchQueryServiceHandler registers the http handlers for service BatchQueryService to "mux".
+// The handlers forward requests to the grpc endpoint over "conn".
+func RegisterBatchQueryServiceHandler(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	return RegisterBatchQueryServiceHandlerClient(ctx, mux, NewBatchQueryServiceClient(conn))
+}
+
+// RegisterBatchQueryServiceHandlerClient registers the http handlers for service BatchQueryService
+// to "mux". The handlers forward requests to the grpc endpoint over the given implementation of "BatchQueryServiceClient".
+// Note: the gRPC framework executes interceptors within the gRPC handler. If the passed in "BatchQueryServiceClient"
+// doesn't go through the normal gRPC flow (creating a gRPC client etc.) then it will be up to the passed in
+// "BatchQueryServiceClient" to call the correct interceptors.
+func RegisterBatchQueryServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux, client BatchQueryServiceClient) error {
+
+	mux.Handle("GET", pattern_BatchQueryService_GetTraces_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := request_BatchQueryService_GetTraces_0(rctx, inboundMarshaler, client, req, pathParams)
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		forward_BatchQueryService_GetTraces_0(ctx, mux, outboundMarshaler, w, req, func() (proto.Message, error) { return resp.Recv() }, mux.GetForwardResponseOptions()...)
+
+	})
+
+	return nil
+}
+
+var (
+	pattern_BatchQueryService_GetTraces_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2, 2, 3}, []string{"api", "v3", "batch", "traces"}, "", runtime.AssumeColonVerbOpt(true)))
+)
+
+var (
+	forward_BatchQueryService_GetTraces_0 = runtime.ForwardResponseStream
+)
@@ -0,0 +1,62 @@
+#!/bin/bash
+
+set -uxf -o pipefail
+
+usage() {
+  echo $"Usage: $0 <clickhouse_version>"
+  exit 1
+}
+
+check_arg() {
+  if [ ! $# -eq 1 ]; then
+    echo "ERROR: need exactly one argument, <clickhouse_version>"
+    usage
+  fi
+}
+
+setup_clickhouse() {
+  local tag=$1
+  local image=clickhouse/clickhouse-server
+  local params=(
+    --rm
+    --detach
+    --publish 8123:8123
+    --ulimit nofile=262144:262144
+  )
+  local cid=$(docker run ${params[@]} ${image}:${tag})
+  echo ${cid}
+}
+
+wait_for_clickhouse() {
+  local counter=0
+  local max_counter=30
+  while [[ "$(curl --silent http://localhost:8123/ping)" != "Ok." && ${counter} -le ${max_counter} ]]; do
+    echo "waiting for ClickHouse to be up..."
+    sleep 2
+    counter=$((counter+1))
+  done
+}
+
+teardown_clickhouse() {
+  local cid=$1
+  docker kill ${cid}
+  exit ${exit_status}
+}
+
+run_integration_test() {
+  local version=$1
+  local cid=$(setup_clickhouse ${version})
+  trap 'teardown_clickhouse ${cid}' EXIT
+  wait_for_clickhouse
+  STORAGE=clickhouse make storage-integration-test
+  exit_status=$?
+}
+
+main() {
+  check_arg "$@"
+
+  echo "Executing integration test for ClickHouse $1"
+  run_integration_test "$1"
+}
+
+main "$@"
@@ -17,6 +17,7 @@ package storage
 
 import (
 	"errors"
+	"net/http"
 
 	"go.uber.org/zap"
 
@@ -64,6 +65,9 @@ var (
 
 	// ErrArchiveStorageNotSupported can be returned by the ArchiveFactory when the archive storage is not supported by the backend.
 	ErrArchiveStorageNotSupported = errors.New("archive storage not supported")
+
+	// ErrDependencyWriterNotSupported can be returned when the backend does not support writing the service dependencies.
+	ErrDependencyWriterNotSupported = errors.New("dependency writer not supported")
 )
 
 // ArchiveFactory is an additional interface that can be implemented by a factory to support trace archiving.
@@ -75,6 +79,20 @@ type ArchiveFactory interface {
 	CreateArchiveSpanWriter() (spanstore.Writer, error)
 }
 
+// DependencyWriterFactory is an additional interface that can be implemented by a factory to support
+// writing the service dependencies computed outside of the storage, e.g. by the collector.
+type DependencyWriterFactory interface {
+	// CreateDependencyWriter creates a dependencystore.Writer.
+	CreateDependencyWriter() (dependencystore.Writer, error)
+}
+
+// AdminHandlerFactory is an additional interface that can be implemented by a factory to expose
+// operations on the storage, such as backups, on the admin server.
+type AdminHandlerFactory interface {
+	// AdminHandlers returns the handlers to mount on the admin server by path.
+	AdminHandlers() map[string]http.Handler
+}
+
 // MetricsFactory defines an interface for a factory that can create implementations of different metrics storage components.
 // Implementations are also encouraged to implement plugin.Configurable interface.
 //
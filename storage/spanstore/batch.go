@@ -0,0 +1,128 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// BatchReader is an optional interface implemented by Readers that can load
+// many traces by ID with fewer round trips than one GetTrace per trace.
+type BatchReader interface {
+	// GetTraces retrieves the traces with the given ids. The traces without
+	// any stored spans are omitted, so fewer traces than ids can be returned.
+	// The traces are in the order of their ids.
+	GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error)
+}
+
+// GetTraces calls reader.GetTraces if the reader implements BatchReader,
+// otherwise it calls reader.GetTrace for each trace ID.
+func GetTraces(ctx context.Context, reader Reader, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	if batchReader, ok := reader.(BatchReader); ok {
+		return batchReader.GetTraces(ctx, traceIDs)
+	}
+	traces := make([]*model.Trace, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		trace, err := reader.GetTrace(ctx, traceID)
+		if err == ErrTraceNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// UniqueTraceIDs returns the trace IDs without the repeated ones, in their original order.
+func UniqueTraceIDs(traceIDs []model.TraceID) []model.TraceID {
+	unique := make([]model.TraceID, 0, len(traceIDs))
+	seen := make(map[model.TraceID]struct{}, len(traceIDs))
+	for _, traceID := range traceIDs {
+		if _, ok := seen[traceID]; !ok {
+			seen[traceID] = struct{}{}
+			unique = append(unique, traceID)
+		}
+	}
+	return unique
+}
+
+// TraceIDOf returns the trace ID of the spans of a trace, it is zero for traces without spans.
+func TraceIDOf(trace *model.Trace) model.TraceID {
+	if len(trace.Spans) == 0 {
+		return model.TraceID{}
+	}
+	return trace.Spans[0].TraceID
+}
+
+// TracesInOrder groups the spans into traces in the order of traceIDs. The
+// IDs without spans are omitted, as are the spans of the other traces.
+func TracesInOrder(spans []*model.Span, traceIDs []model.TraceID) []*model.Trace {
+	byID := make(map[model.TraceID]*model.Trace, len(traceIDs))
+	for _, span := range spans {
+		trace, ok := byID[span.TraceID]
+		if !ok {
+			trace = &model.Trace{}
+			byID[span.TraceID] = trace
+		}
+		trace.Spans = append(trace.Spans, span)
+	}
+	traces := make([]*model.Trace, 0, len(byID))
+	for _, traceID := range traceIDs {
+		if trace, ok := byID[traceID]; ok {
+			traces = append(traces, trace)
+			// a repeated ID is returned once
+			delete(byID, traceID)
+		}
+	}
+	return traces
+}
+
+// spanKey identifies the copies of a span read from several storages.
+type spanKey struct {
+	spanID    model.SpanID
+	startTime int64
+	kind      string
+}
+
+// MergeTraces merges the spans and warnings of traces read from several storages,
+// keeping one copy of the spans found in more than one of them. Spans sharing
+// their ID with a span of another kind, such as Zipkin client and server spans,
+// are all kept. It returns nil if all the traces are nil.
+func MergeTraces(traces ...*model.Trace) *model.Trace {
+	var merged *model.Trace
+	seen := make(map[spanKey]struct{})
+	for _, trace := range traces {
+		if trace == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &model.Trace{}
+		}
+		for _, span := range trace.Spans {
+			kind, _ := span.GetSpanKind()
+			key := spanKey{spanID: span.SpanID, startTime: span.StartTime.UnixNano(), kind: kind}
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				merged.Spans = append(merged.Spans, span)
+			}
+		}
+		merged.Warnings = append(merged.Warnings, trace.Warnings...)
+	}
+	return merged
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	. "github.com/kjschnei001/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+type batchReader struct {
+	*mocks.Reader
+	traces []*model.Trace
+}
+
+func (r batchReader) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	return r.traces, nil
+}
+
+func TestGetTraces(t *testing.T) {
+	traceIDs := []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2), model.NewTraceID(0, 3)}
+	trace1 := &model.Trace{Spans: []*model.Span{{TraceID: traceIDs[0]}}}
+	trace3 := &model.Trace{Spans: []*model.Span{{TraceID: traceIDs[2]}}}
+
+	reader := &mocks.Reader{}
+	reader.On("GetTrace", context.Background(), traceIDs[0]).Return(trace1, nil).Once()
+	reader.On("GetTrace", context.Background(), traceIDs[1]).Return(nil, ErrTraceNotFound).Once()
+	reader.On("GetTrace", context.Background(), traceIDs[2]).Return(trace3, nil).Once()
+	traces, err := GetTraces(context.Background(), reader, traceIDs)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{trace1, trace3}, traces)
+
+	reader.On("GetTrace", context.Background(), traceIDs[0]).Return(nil, errors.New("storage error")).Once()
+	_, err = GetTraces(context.Background(), reader, traceIDs)
+	assert.EqualError(t, err, "storage error")
+
+	expected := []*model.Trace{trace3}
+	traces, err = GetTraces(context.Background(), batchReader{Reader: reader, traces: expected}, traceIDs)
+	require.NoError(t, err)
+	assert.Equal(t, expected, traces)
+}
+
+func TestTraceIDOf(t *testing.T) {
+	assert.Equal(t, model.NewTraceID(1, 2), TraceIDOf(&model.Trace{Spans: []*model.Span{{TraceID: model.NewTraceID(1, 2)}}}))
+	assert.Equal(t, model.TraceID{}, TraceIDOf(&model.Trace{}))
+}
+
+func TestTracesInOrder(t *testing.T) {
+	id1, id2, id3 := model.NewTraceID(0, 1), model.NewTraceID(0, 2), model.NewTraceID(0, 3)
+	spans := []*model.Span{
+		{TraceID: id1, SpanID: 1},
+		{TraceID: id3, SpanID: 2},
+		{TraceID: id1, SpanID: 3},
+		{TraceID: id2, SpanID: 4},
+	}
+	traces := TracesInOrder(spans, []model.TraceID{id3, model.NewTraceID(0, 4), id1, id3})
+	assert.Equal(t, []*model.Trace{
+		{Spans: []*model.Span{spans[1]}},
+		{Spans: []*model.Span{spans[0], spans[2]}},
+	}, traces)
+}
+
+func TestUniqueTraceIDs(t *testing.T) {
+	id1, id2 := model.NewTraceID(0, 1), model.NewTraceID(0, 2)
+	assert.Equal(t, []model.TraceID{id2, id1}, UniqueTraceIDs([]model.TraceID{id2, id1, id2, id1}))
+	assert.Empty(t, UniqueTraceIDs(nil))
+}
+
+func TestMergeTraces(t *testing.T) {
+	start := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	client := &model.Span{SpanID: 1, StartTime: start, Tags: model.KeyValues{model.String("span.kind", "client")}}
+	server := &model.Span{SpanID: 1, StartTime: start, Tags: model.KeyValues{model.String("span.kind", "server")}}
+	other := &model.Span{SpanID: 2, StartTime: start}
+	copied := *other
+
+	merged := MergeTraces(
+		&model.Trace{Spans: []*model.Span{client, other}, Warnings: []string{"a"}},
+		nil,
+		&model.Trace{Spans: []*model.Span{&copied, server}, Warnings: []string{"b"}},
+	)
+	assert.Equal(t, &model.Trace{Spans: []*model.Span{client, other, server}, Warnings: []string{"a", "b"}}, merged)
+	assert.Nil(t, MergeTraces(nil, nil))
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"sync"
+)
+
+type failuresKey struct{}
+
+// failures collects the failures reported in a context.
+type failures struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithFailures returns a context in which the readers report their partial failures, such as
+// the failures of some of the storages queried by a reader, when they return the results of
+// the others instead of failing. The failures are returned by ReportedFailures.
+func WithFailures(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failuresKey{}, &failures{})
+}
+
+// ReportFailure records a partial failure in the context, if it was created by WithFailures.
+// The failures with the same message are recorded once.
+func ReportFailure(ctx context.Context, err error) {
+	f, ok := ctx.Value(failuresKey{}).(*failures)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.errs {
+		if e.Error() == err.Error() {
+			return
+		}
+	}
+	f.errs = append(f.errs, err)
+}
+
+// ReportedFailures returns the partial failures reported in the context.
+func ReportedFailures(ctx context.Context) []error {
+	f, ok := ctx.Value(failuresKey{}).(*failures)
+	if !ok {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]error(nil), f.errs...)
+}
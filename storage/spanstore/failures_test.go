@@ -0,0 +1,37 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportFailure(t *testing.T) {
+	// the failures are ignored without WithFailures
+	ReportFailure(context.Background(), errors.New("ignored"))
+	assert.Empty(t, ReportedFailures(context.Background()))
+
+	ctx := WithFailures(context.Background())
+	assert.Empty(t, ReportedFailures(ctx))
+	failure := errors.New("the cassandra storage failed")
+	ReportFailure(ctx, failure)
+	ReportFailure(ctx, errors.New("the cassandra storage failed"))
+	ReportFailure(ctx, errors.New("the elasticsearch storage failed"))
+	assert.Equal(t, []error{failure, errors.New("the elasticsearch storage failed")}, ReportedFailures(ctx))
+}
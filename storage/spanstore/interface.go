@@ -46,10 +46,9 @@ type Reader interface {
 	// known to the backend from spans within its retention period.
 	GetOperations(ctx context.Context, query OperationQueryParameters) ([]Operation, error)
 
-	// FindTraces returns all traces matching query parameters. There's currently
-	// an implementation-dependent abiguity whether all query filters (such as
-	// multiple tags) must apply to the same span within a trace, or can be satisfied
-	// by different spans.
+	// FindTraces returns all traces matching query parameters. Whether multiple
+	// tags and tag filters must be satisfied by the same span of the queried service,
+	// or can be satisfied by different spans, is defined by query.TagMatchMode.
 	//
 	// If no matching traces are found, the function returns (nil, nil).
 	FindTraces(ctx context.Context, query *TraceQueryParameters) ([]*model.Trace, error)
@@ -71,6 +70,19 @@ type TraceQueryParameters struct {
 	DurationMin   time.Duration
 	DurationMax   time.Duration
 	NumTraces     int
+	// TagFilters are typed tag comparisons, such as `http.status_code >= 500`,
+	// that must all be satisfied in addition to the exact matches in Tags.
+	// Every filter is a Predicate on TagField, see NewTagFilter.
+	TagFilters []*Predicate
+	// TagMatchMode defines whether Tags and TagFilters must all be satisfied
+	// by a single span. The zero value is TagMatchSameSpan.
+	TagMatchMode TagMatchMode
+	// PageToken is the NextPageToken of a previous TracePage, used to request
+	// the following page from a PagedReader. It is ignored by FindTraces.
+	PageToken string
+	// Expression is an optional span-level filter evaluated in addition to the
+	// fields above. It is only passed to Readers that implement ExpressionPushdown.
+	Expression Expr
 }
 
 // OperationQueryParameters contains parameters of query operations, empty spanKind means get operations for all kinds of span.
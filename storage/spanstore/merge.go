@@ -0,0 +1,140 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+type rawTracesKey struct{}
+
+// WithRawTraces returns a context in which the readers return the spans as they are stored,
+// for instance without making unique the span IDs of the traces they merge.
+func WithRawTraces(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawTracesKey{}, true)
+}
+
+// RawTraces returns true if the context was created by WithRawTraces.
+func RawTraces(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawTracesKey{}).(bool)
+	return raw
+}
+
+// MergeServices returns the services read from several storages, sorted and without the repeated ones.
+func MergeServices(results ...[]string) []string {
+	seen := make(map[string]struct{})
+	var services []string
+	for _, result := range results {
+		for _, service := range result {
+			if _, ok := seen[service]; !ok {
+				seen[service] = struct{}{}
+				services = append(services, service)
+			}
+		}
+	}
+	sort.Strings(services)
+	return services
+}
+
+// MergeOperations returns the operations read from several storages, sorted by name and
+// span kind and without the repeated ones.
+func MergeOperations(results ...[]Operation) []Operation {
+	seen := make(map[Operation]struct{})
+	var operations []Operation
+	for _, result := range results {
+		for _, operation := range result {
+			if _, ok := seen[operation]; !ok {
+				seen[operation] = struct{}{}
+				operations = append(operations, operation)
+			}
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Name != operations[j].Name {
+			return operations[i].Name < operations[j].Name
+		}
+		return operations[i].SpanKind < operations[j].SpanKind
+	})
+	return operations
+}
+
+// MergeFoundTraces merges the traces found in several storages by MergeTraces, and returns at most
+// limit of them, or all of them if limit is not positive, the most recent ones first. If onMerged
+// is not nil, it replaces the traces found in more than one storage. The traces without spans are
+// omitted.
+func MergeFoundTraces(limit int, onMerged func(*model.Trace) *model.Trace, results ...[]*model.Trace) []*model.Trace {
+	var traces []*model.Trace
+	byID := make(map[model.TraceID]int)
+	merged := make(map[int]struct{})
+	for _, result := range results {
+		for _, trace := range result {
+			if len(trace.Spans) == 0 {
+				continue
+			}
+			traceID := TraceIDOf(trace)
+			if i, ok := byID[traceID]; ok {
+				traces[i] = MergeTraces(traces[i], trace)
+				merged[i] = struct{}{}
+				continue
+			}
+			byID[traceID] = len(traces)
+			traces = append(traces, trace)
+		}
+	}
+	if onMerged != nil {
+		for i := range merged {
+			traces[i] = onMerged(traces[i])
+		}
+	}
+	sort.SliceStable(traces, func(i, j int) bool {
+		return latestStartTime(traces[i]).After(latestStartTime(traces[j]))
+	})
+	if limit > 0 && len(traces) > limit {
+		traces = traces[:limit]
+	}
+	return traces
+}
+
+// MergeTraceIDs returns the trace IDs found in several storages without the repeated ones, in
+// the order of the storages, and at most limit of them, or all of them if limit is not positive.
+// It returns nil if no trace ID is found.
+func MergeTraceIDs(limit int, results ...[]model.TraceID) []model.TraceID {
+	var all []model.TraceID
+	for _, result := range results {
+		all = append(all, result...)
+	}
+	traceIDs := UniqueTraceIDs(all)
+	if len(traceIDs) == 0 {
+		return nil
+	}
+	if limit > 0 && len(traceIDs) > limit {
+		traceIDs = traceIDs[:limit]
+	}
+	return traceIDs
+}
+
+func latestStartTime(trace *model.Trace) time.Time {
+	var latest time.Time
+	for _, span := range trace.Spans {
+		if span.StartTime.After(latest) {
+			latest = span.StartTime
+		}
+	}
+	return latest
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+func TestRawTraces(t *testing.T) {
+	assert.False(t, RawTraces(context.Background()))
+	assert.True(t, RawTraces(WithRawTraces(context.Background())))
+}
+
+func TestMergeServices(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, MergeServices([]string{"c", "a"}, nil, []string{"b", "c"}))
+	assert.Empty(t, MergeServices())
+}
+
+func TestMergeOperations(t *testing.T) {
+	operations := MergeOperations(
+		[]Operation{{Name: "b"}, {Name: "a", SpanKind: "server"}},
+		[]Operation{{Name: "a", SpanKind: "client"}, {Name: "b"}},
+	)
+	assert.Equal(t, []Operation{{Name: "a", SpanKind: "client"}, {Name: "a", SpanKind: "server"}, {Name: "b"}}, operations)
+}
+
+func TestMergeFoundTraces(t *testing.T) {
+	start := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	span := func(traceID uint64, spanID uint64, start time.Time) *model.Span {
+		return &model.Span{TraceID: model.NewTraceID(0, traceID), SpanID: model.NewSpanID(spanID), StartTime: start}
+	}
+	hot := []*model.Trace{
+		{Spans: []*model.Span{span(1, 1, start)}},
+		{Spans: []*model.Span{span(2, 1, start.Add(time.Minute))}},
+		{},
+	}
+	cold := []*model.Trace{
+		{Spans: []*model.Span{span(3, 1, start.Add(-time.Minute))}},
+		{Spans: []*model.Span{span(1, 1, start), span(1, 2, start.Add(time.Hour))}},
+	}
+
+	var merged []model.TraceID
+	onMerged := func(trace *model.Trace) *model.Trace {
+		merged = append(merged, TraceIDOf(trace))
+		return trace
+	}
+	traces := MergeFoundTraces(0, onMerged, hot, cold)
+	require.Len(t, traces, 3)
+	assert.Equal(t, model.NewTraceID(0, 1), TraceIDOf(traces[0]), "the most recent span of trace 1 is in the cold storage")
+	assert.Len(t, traces[0].Spans, 2)
+	assert.Equal(t, model.NewTraceID(0, 2), TraceIDOf(traces[1]))
+	assert.Equal(t, model.NewTraceID(0, 3), TraceIDOf(traces[2]))
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 1)}, merged)
+
+	traces = MergeFoundTraces(2, nil, hot, cold)
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 2), TraceIDOf(traces[1]))
+}
+
+func TestMergeTraceIDs(t *testing.T) {
+	id1, id2, id3 := model.NewTraceID(0, 1), model.NewTraceID(0, 2), model.NewTraceID(0, 3)
+	assert.Equal(t, []model.TraceID{id2, id1, id3}, MergeTraceIDs(0, []model.TraceID{id2, id1}, []model.TraceID{id1, id3}))
+	assert.Equal(t, []model.TraceID{id2, id1}, MergeTraceIDs(2, []model.TraceID{id2, id1}, []model.TraceID{id1, id3}))
+	assert.Nil(t, MergeTraceIDs(2))
+}
@@ -30,6 +30,7 @@ type ReadMetricsDecorator struct {
 	findTracesMetrics    *queryMetrics
 	findTraceIDsMetrics  *queryMetrics
 	getTraceMetrics      *queryMetrics
+	getTracesMetrics     *queryMetrics
 	getServicesMetrics   *queryMetrics
 	getOperationsMetrics *queryMetrics
 }
@@ -60,6 +61,7 @@ func NewReadMetricsDecorator(spanReader spanstore.Reader, metricsFactory metrics
 		findTracesMetrics:    buildQueryMetrics("find_traces", metricsFactory),
 		findTraceIDsMetrics:  buildQueryMetrics("find_trace_ids", metricsFactory),
 		getTraceMetrics:      buildQueryMetrics("get_trace", metricsFactory),
+		getTracesMetrics:     buildQueryMetrics("get_traces", metricsFactory),
 		getServicesMetrics:   buildQueryMetrics("get_services", metricsFactory),
 		getOperationsMetrics: buildQueryMetrics("get_operations", metricsFactory),
 	}
@@ -80,6 +82,28 @@ func (m *ReadMetricsDecorator) FindTraces(ctx context.Context, traceQuery *spans
 	return retMe, err
 }
 
+// FindTracesPage implements spanstore.PagedReader#FindTracesPage. Readers that do not
+// implement spanstore.PagedReader return all the traces in the first page.
+func (m *ReadMetricsDecorator) FindTracesPage(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	start := time.Now()
+	retMe, err := spanstore.FindTracesPage(ctx, m.spanReader, traceQuery)
+	count := 0
+	if retMe != nil {
+		count = len(retMe.Traces)
+	}
+	m.findTracesMetrics.emit(err, time.Since(start), count)
+	return retMe, err
+}
+
+// FindTraceSummaries implements spanstore.SummaryReader#FindTraceSummaries. The summaries
+// of readers that do not implement spanstore.SummaryReader are computed from FindTraces.
+func (m *ReadMetricsDecorator) FindTraceSummaries(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]*spanstore.TraceSummary, error) {
+	start := time.Now()
+	retMe, err := spanstore.FindTraceSummaries(ctx, m.spanReader, traceQuery)
+	m.findTracesMetrics.emit(err, time.Since(start), len(retMe))
+	return retMe, err
+}
+
 // FindTraceIDs implements spanstore.Reader#FindTraceIDs
 func (m *ReadMetricsDecorator) FindTraceIDs(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
 	start := time.Now()
@@ -96,6 +120,15 @@ func (m *ReadMetricsDecorator) GetTrace(ctx context.Context, traceID model.Trace
 	return retMe, err
 }
 
+// GetTraces implements spanstore.BatchReader#GetTraces. The traces of readers
+// that do not implement spanstore.BatchReader are loaded one by one.
+func (m *ReadMetricsDecorator) GetTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	start := time.Now()
+	retMe, err := spanstore.GetTraces(ctx, m.spanReader, traceIDs)
+	m.getTracesMetrics.emit(err, time.Since(start), len(retMe))
+	return retMe, err
+}
+
 // GetServices implements spanstore.Reader#GetServices
 func (m *ReadMetricsDecorator) GetServices(ctx context.Context) ([]string, error) {
 	start := time.Now()
@@ -114,3 +147,12 @@ func (m *ReadMetricsDecorator) GetOperations(
 	m.getOperationsMetrics.emit(err, time.Since(start), len(retMe))
 	return retMe, err
 }
+
+// CanPushDown implements spanstore.ExpressionPushdown by delegating to the
+// wrapped reader, if it supports expression pushdown.
+func (m *ReadMetricsDecorator) CanPushDown(expr spanstore.Expr) bool {
+	if pushdown, ok := m.spanReader.(spanstore.ExpressionPushdown); ok {
+		return pushdown.CanPushDown(expr)
+	}
+	return false
+}
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/kjschnei001/jaeger/internal/metricstest"
 	"github.com/kjschnei001/jaeger/model"
@@ -143,3 +144,98 @@ func TestFailingUnderlyingCalls(t *testing.T) {
 
 	checkExpectedExistingAndNonExistentCounters(t, counters, expecteds, gauges, existingKeys, nonExistentKeys)
 }
+
+type pushdownReader struct {
+	mocks.Reader
+}
+
+func (*pushdownReader) CanPushDown(spanstore.Expr) bool {
+	return true
+}
+
+func TestCanPushDown(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	assert.False(t, NewReadMetricsDecorator(&mocks.Reader{}, mf).CanPushDown(nil))
+	assert.True(t, NewReadMetricsDecorator(&pushdownReader{}, mf).CanPushDown(nil))
+}
+
+type pagedReader struct {
+	mocks.Reader
+}
+
+func (*pagedReader) FindTracesPage(context.Context, *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	return &spanstore.TracePage{Traces: []*model.Trace{{}}, NextPageToken: "next"}, nil
+}
+
+func TestFindTracesPage(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	page, err := NewReadMetricsDecorator(&pagedReader{}, mf).FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, "next", page.NextPageToken)
+
+	mockReader := &mocks.Reader{}
+	mockReader.On("FindTraces", context.Background(), &spanstore.TraceQueryParameters{}).
+		Return([]*model.Trace{{}, {}}, nil)
+	page, err = NewReadMetricsDecorator(mockReader, mf).FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{})
+	require.NoError(t, err)
+	assert.Len(t, page.Traces, 2)
+	assert.Empty(t, page.NextPageToken)
+
+	_, err = NewReadMetricsDecorator(mockReader, mf).FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{PageToken: "next"})
+	assert.ErrorIs(t, err, spanstore.ErrPaginationNotSupported)
+
+	counters, _ := mf.Snapshot()
+	assert.EqualValues(t, 2, counters["requests|operation=find_traces|result=ok"])
+	assert.EqualValues(t, 1, counters["requests|operation=find_traces|result=err"])
+}
+
+type summaryReader struct {
+	mocks.Reader
+}
+
+func (*summaryReader) FindTraceSummaries(context.Context, *spanstore.TraceQueryParameters) ([]*spanstore.TraceSummary, error) {
+	return []*spanstore.TraceSummary{{SpanCount: 3}}, nil
+}
+
+func TestFindTraceSummaries(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	summaries, err := NewReadMetricsDecorator(&summaryReader{}, mf).FindTraceSummaries(context.Background(), &spanstore.TraceQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, []*spanstore.TraceSummary{{SpanCount: 3}}, summaries)
+
+	mockReader := &mocks.Reader{}
+	mockReader.On("FindTraces", context.Background(), &spanstore.TraceQueryParameters{}).
+		Return([]*model.Trace{{Spans: []*model.Span{{}}}}, nil).Once()
+	summaries, err = NewReadMetricsDecorator(mockReader, mf).FindTraceSummaries(context.Background(), &spanstore.TraceQueryParameters{})
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, 1, summaries[0].SpanCount)
+
+	mockReader.On("FindTraces", context.Background(), &spanstore.TraceQueryParameters{}).
+		Return(nil, errors.New("Failure")).Once()
+	_, err = NewReadMetricsDecorator(mockReader, mf).FindTraceSummaries(context.Background(), &spanstore.TraceQueryParameters{})
+	assert.Error(t, err)
+
+	counters, _ := mf.Snapshot()
+	assert.EqualValues(t, 2, counters["requests|operation=find_traces|result=ok"])
+	assert.EqualValues(t, 1, counters["requests|operation=find_traces|result=err"])
+}
+
+func TestGetTraces(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	mockReader := &mocks.Reader{}
+	trace := &model.Trace{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 1)}}}
+	mockReader.On("GetTrace", context.Background(), model.NewTraceID(0, 1)).Return(trace, nil).Once()
+	mockReader.On("GetTrace", context.Background(), model.NewTraceID(0, 2)).Return(nil, spanstore.ErrTraceNotFound).Once()
+	traces, err := NewReadMetricsDecorator(mockReader, mf).GetTraces(context.Background(), []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2)})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{trace}, traces)
+
+	mockReader.On("GetTrace", context.Background(), model.NewTraceID(0, 1)).Return(nil, errors.New("Failure")).Once()
+	_, err = NewReadMetricsDecorator(mockReader, mf).GetTraces(context.Background(), []model.TraceID{model.NewTraceID(0, 1)})
+	assert.Error(t, err)
+
+	counters, _ := mf.Snapshot()
+	assert.EqualValues(t, 1, counters["requests|operation=get_traces|result=ok"])
+	assert.EqualValues(t, 1, counters["requests|operation=get_traces|result=err"])
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+var (
+	// ErrPaginationNotSupported is returned when a page token is used with a
+	// Reader, or a query, that does not support pagination.
+	ErrPaginationNotSupported = errors.New("pagination is not supported")
+
+	// ErrInvalidPageToken is returned when a page token cannot be decoded.
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+// TracePage is a page of trace search results.
+type TracePage struct {
+	Traces []*model.Trace
+	// NextPageToken is an opaque token to pass as TraceQueryParameters.PageToken
+	// to get the next page. It is empty when there are no more results.
+	NextPageToken string
+	// Truncated is set by FindTracesPage for Readers that do not implement PagedReader
+	// when the traces were limited to query.NumTraces, so that more traces may match
+	// the query although there is no next page.
+	Truncated bool
+}
+
+// PagedReader is an optional interface implemented by Readers that support
+// cursor-based pagination of trace search results.
+type PagedReader interface {
+	// FindTracesPage returns up to query.NumTraces traces matching the query,
+	// starting after the position encoded in query.PageToken. A page may contain
+	// fewer traces than requested even if it is not the last one.
+	FindTracesPage(ctx context.Context, query *TraceQueryParameters) (*TracePage, error)
+}
+
+// FindTracesPage calls reader.FindTracesPage if the reader implements PagedReader.
+// Otherwise, the first page is all the results of reader.FindTraces, and
+// ErrPaginationNotSupported is returned for any other page.
+func FindTracesPage(ctx context.Context, reader Reader, query *TraceQueryParameters) (*TracePage, error) {
+	if pagedReader, ok := reader.(PagedReader); ok {
+		return pagedReader.FindTracesPage(ctx, query)
+	}
+	if query.PageToken != "" {
+		return nil, ErrPaginationNotSupported
+	}
+	traces, err := reader.FindTraces(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &TracePage{
+		Traces:    traces,
+		Truncated: query.NumTraces > 0 && len(traces) >= query.NumTraces,
+	}, nil
+}
+
+// EncodePageToken encodes a Reader-specific cursor as an opaque page token.
+func EncodePageToken(cursor interface{}) (string, error) {
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodePageToken decodes a page token created by EncodePageToken into cursor.
+func DecodePageToken(token string, cursor interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(b, cursor); err != nil {
+		return ErrInvalidPageToken
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	. "github.com/kjschnei001/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+type testCursor struct {
+	StartTime int64
+	TraceID   string
+}
+
+type pagedReader struct {
+	*mocks.Reader
+	page *TracePage
+}
+
+func (r pagedReader) FindTracesPage(ctx context.Context, query *TraceQueryParameters) (*TracePage, error) {
+	return r.page, nil
+}
+
+func TestPageToken(t *testing.T) {
+	token, err := EncodePageToken(testCursor{StartTime: 123, TraceID: "abc"})
+	require.NoError(t, err)
+	var cursor testCursor
+	require.NoError(t, DecodePageToken(token, &cursor))
+	assert.Equal(t, testCursor{StartTime: 123, TraceID: "abc"}, cursor)
+
+	for _, invalid := range []string{"!not base64!", "bm90IGpzb24"} {
+		assert.ErrorIs(t, DecodePageToken(invalid, &cursor), ErrInvalidPageToken, invalid)
+	}
+}
+
+func TestFindTracesPage(t *testing.T) {
+	traces := []*model.Trace{{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 1)}}}}
+
+	reader := &mocks.Reader{}
+	reader.On("FindTraces", mock.Anything, mock.Anything).Return(traces, nil)
+	page, err := FindTracesPage(context.Background(), reader, &TraceQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, &TracePage{Traces: traces}, page)
+
+	// the traces may have been limited by the reader
+	page, err = FindTracesPage(context.Background(), reader, &TraceQueryParameters{NumTraces: 1})
+	require.NoError(t, err)
+	assert.Equal(t, &TracePage{Traces: traces, Truncated: true}, page)
+
+	_, err = FindTracesPage(context.Background(), reader, &TraceQueryParameters{PageToken: "token"})
+	assert.ErrorIs(t, err, ErrPaginationNotSupported)
+
+	expected := &TracePage{Traces: traces, NextPageToken: "next"}
+	page, err = FindTracesPage(context.Background(), pagedReader{Reader: reader, page: expected}, &TraceQueryParameters{PageToken: "token"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, page)
+}
@@ -0,0 +1,374 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// Expr is a node of a span-level query expression. A trace matches an expression
+// if at least one of its spans satisfies the whole expression.
+type Expr interface {
+	// MatchSpan reports whether the span satisfies the expression.
+	MatchSpan(span *model.Span) bool
+	String() string
+}
+
+// FieldKind identifies which attribute of a span a Predicate applies to.
+type FieldKind int
+
+const (
+	// ServiceField is the span's Process.ServiceName.
+	ServiceField FieldKind = iota
+	// OperationField is the span's OperationName.
+	OperationField
+	// DurationField is the span's Duration.
+	DurationField
+	// TagField is a span tag, process tag or log field identified by Predicate.Key.
+	TagField
+)
+
+// CompareOperator is a comparison operator used in query predicates.
+type CompareOperator int
+
+const (
+	// OpEqual matches values equal to the operand.
+	OpEqual CompareOperator = iota
+	// OpNotEqual matches values different from the operand.
+	OpNotEqual
+	// OpGreater matches numeric values greater than the operand.
+	OpGreater
+	// OpGreaterOrEqual matches numeric values greater than or equal to the operand.
+	OpGreaterOrEqual
+	// OpLess matches numeric values less than the operand.
+	OpLess
+	// OpLessOrEqual matches numeric values less than or equal to the operand.
+	OpLessOrEqual
+	// OpRegex matches values against the operand as a regular expression.
+	OpRegex
+	// OpNotRegex matches values that do not match the operand regular expression.
+	OpNotRegex
+	// OpPrefix matches values starting with the operand.
+	OpPrefix
+)
+
+var operatorSymbols = map[CompareOperator]string{
+	OpEqual:          "=",
+	OpNotEqual:       "!=",
+	OpGreater:        ">",
+	OpGreaterOrEqual: ">=",
+	OpLess:           "<",
+	OpLessOrEqual:    "<=",
+	OpRegex:          "=~",
+	OpNotRegex:       "!~",
+	OpPrefix:         "^=",
+}
+
+func (op CompareOperator) String() string {
+	if s, ok := operatorSymbols[op]; ok {
+		return s
+	}
+	return fmt.Sprintf("CompareOperator(%d)", int(op))
+}
+
+// IsOrdering returns true for operators that require a numeric operand.
+func (op CompareOperator) IsOrdering() bool {
+	return op == OpGreater || op == OpGreaterOrEqual || op == OpLess || op == OpLessOrEqual
+}
+
+// IsRegex returns true for regular expression operators.
+func (op CompareOperator) IsRegex() bool {
+	return op == OpRegex || op == OpNotRegex
+}
+
+// Predicate compares a single span attribute with a literal value. Predicates are
+// created by NewPredicate, which compiles the regular expression operands.
+type Predicate struct {
+	Field FieldKind
+	// Key is the tag key, only used when Field is TagField.
+	Key      string
+	Operator CompareOperator
+	// Value is the literal operand as written in the query.
+	Value string
+	// Duration is the parsed operand when Field is DurationField.
+	Duration time.Duration
+
+	regex *regexp.Regexp
+}
+
+// NewPredicate validates the operand for the given field and operator and returns a Predicate.
+func NewPredicate(field FieldKind, key string, op CompareOperator, value string) (*Predicate, error) {
+	p := &Predicate{Field: field, Key: key, Operator: op, Value: value}
+	switch field {
+	case ServiceField, OperationField:
+		if op.IsOrdering() {
+			return nil, fmt.Errorf("operator %s is not supported for %s", op, p.fieldName())
+		}
+	case DurationField:
+		if op.IsRegex() || op == OpPrefix {
+			return nil, fmt.Errorf("operator %s is not supported for %s", op, p.fieldName())
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		p.Duration = d
+	case TagField:
+		if key == "" {
+			return nil, fmt.Errorf("tag key must not be empty")
+		}
+		if op.IsOrdering() {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("operator %s requires a numeric value for %s, got %q", op, p.fieldName(), value)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown field kind %d", field)
+	}
+	if op.IsRegex() {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value, err)
+		}
+		p.regex = re
+	}
+	return p, nil
+}
+
+func (p *Predicate) fieldName() string {
+	switch p.Field {
+	case ServiceField:
+		return "service"
+	case OperationField:
+		return "operation"
+	case DurationField:
+		return "duration"
+	default:
+		return "span." + p.Key
+	}
+}
+
+// MatchSpan implements Expr.
+func (p *Predicate) MatchSpan(span *model.Span) bool {
+	switch p.Field {
+	case ServiceField:
+		return p.matchString(span.Process.ServiceName)
+	case OperationField:
+		return p.matchString(span.OperationName)
+	case DurationField:
+		return compareOrdered(span.Duration, p.Duration, p.Operator)
+	case TagField:
+		return p.matchTag(span)
+	}
+	return false
+}
+
+// MatchKeyValue reports whether a single key-value satisfies the predicate,
+// ignoring the key.
+func (p *Predicate) MatchKeyValue(kv *model.KeyValue) bool {
+	if p.Operator.IsOrdering() || p.Operator == OpEqual || p.Operator == OpNotEqual {
+		if num, ok := keyValueAsFloat(kv); ok {
+			if operand, err := strconv.ParseFloat(p.Value, 64); err == nil {
+				return compareOrdered(num, operand, p.Operator)
+			}
+		}
+		if p.Operator.IsOrdering() {
+			return false
+		}
+	}
+	return p.matchString(kv.AsString())
+}
+
+func (p *Predicate) matchString(s string) bool {
+	switch p.Operator {
+	case OpEqual:
+		return s == p.Value
+	case OpNotEqual:
+		return s != p.Value
+	case OpRegex:
+		return p.regex != nil && p.regex.MatchString(s)
+	case OpNotRegex:
+		return p.regex != nil && !p.regex.MatchString(s)
+	case OpPrefix:
+		return strings.HasPrefix(s, p.Value)
+	}
+	return false
+}
+
+func (p *Predicate) matchTag(span *model.Span) bool {
+	found := false
+	match := func(kvs model.KeyValues) bool {
+		for i := range kvs {
+			if kvs[i].Key != p.Key {
+				continue
+			}
+			found = true
+			if p.MatchKeyValue(&kvs[i]) {
+				return true
+			}
+		}
+		return false
+	}
+	if match(span.Tags) || (span.Process != nil && match(span.Process.Tags)) {
+		return true
+	}
+	for _, log := range span.Logs {
+		if match(log.Fields) {
+			return true
+		}
+	}
+	// A negated predicate on a missing tag is satisfied, e.g. `span.error != true`
+	// matches spans that have no error tag at all.
+	return !found && (p.Operator == OpNotEqual || p.Operator == OpNotRegex)
+}
+
+func (p *Predicate) String() string {
+	value := p.Value
+	if p.Field != DurationField {
+		value = strconv.Quote(value)
+	}
+	return fmt.Sprintf("%s %s %s", p.fieldName(), p.Operator, value)
+}
+
+func keyValueAsFloat(kv *model.KeyValue) (float64, bool) {
+	switch kv.VType {
+	case model.Int64Type:
+		return float64(kv.Int64()), true
+	case model.Float64Type:
+		return kv.Float64(), true
+	case model.StringType:
+		f, err := strconv.ParseFloat(kv.VStr, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compareOrdered[T int64 | float64 | time.Duration](a, b T, op CompareOperator) bool {
+	switch op {
+	case OpEqual:
+		return a == b
+	case OpNotEqual:
+		return a != b
+	case OpGreater:
+		return a > b
+	case OpGreaterOrEqual:
+		return a >= b
+	case OpLess:
+		return a < b
+	case OpLessOrEqual:
+		return a <= b
+	}
+	return false
+}
+
+// AndExpr is satisfied when all of its operands are satisfied by the same span.
+type AndExpr struct {
+	Operands []Expr
+}
+
+// MatchSpan implements Expr.
+func (e *AndExpr) MatchSpan(span *model.Span) bool {
+	for _, op := range e.Operands {
+		if !op.MatchSpan(span) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *AndExpr) String() string {
+	return joinExprs(e.Operands, " && ")
+}
+
+// OrExpr is satisfied when any of its operands is satisfied.
+type OrExpr struct {
+	Operands []Expr
+}
+
+// MatchSpan implements Expr.
+func (e *OrExpr) MatchSpan(span *model.Span) bool {
+	for _, op := range e.Operands {
+		if op.MatchSpan(span) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *OrExpr) String() string {
+	return joinExprs(e.Operands, " || ")
+}
+
+// NotExpr negates its operand.
+type NotExpr struct {
+	Operand Expr
+}
+
+// MatchSpan implements Expr.
+func (e *NotExpr) MatchSpan(span *model.Span) bool {
+	return !e.Operand.MatchSpan(span)
+}
+
+func (e *NotExpr) String() string {
+	return "!(" + e.Operand.String() + ")"
+}
+
+func joinExprs(exprs []Expr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = "(" + e.String() + ")"
+	}
+	return strings.Join(parts, sep)
+}
+
+// MatchTrace reports whether any span of the trace satisfies the expression.
+func MatchTrace(expr Expr, trace *model.Trace) bool {
+	for _, span := range trace.Spans {
+		if expr.MatchSpan(span) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conjuncts splits an expression into the operands of its top-level AND.
+func Conjuncts(expr Expr) []Expr {
+	if and, ok := expr.(*AndExpr); ok {
+		var retMe []Expr
+		for _, op := range and.Operands {
+			retMe = append(retMe, Conjuncts(op)...)
+		}
+		return retMe
+	}
+	return []Expr{expr}
+}
+
+// ExpressionPushdown is an optional interface implemented by Readers that can
+// evaluate some query expression predicates natively. When a Reader does not
+// implement it, FindTraces receives only the legacy fields of TraceQueryParameters
+// and the query service filters the results in-process, fetching further pages from
+// PagedReaders. When the matching traces may have been cut off, e.g. by a Reader that
+// does not support pagination, the query service reports it with ReportFailure.
+type ExpressionPushdown interface {
+	// CanPushDown reports whether the reader evaluates the given conjunct of
+	// TraceQueryParameters.Expression itself.
+	CanPushDown(expr Expr) bool
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	. "github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+var exprTestSpan = &model.Span{
+	OperationName: "GET /checkout",
+	Duration:      500 * time.Millisecond,
+	Process: &model.Process{
+		ServiceName: "checkout",
+		Tags:        model.KeyValues{model.String("hostname", "host-1")},
+	},
+	Tags: model.KeyValues{
+		model.Int64("http.status_code", 503),
+		model.String("db.statement", "SELECT * FROM users"),
+		model.Bool("error", true),
+		model.Float64("ratio", 0.25),
+	},
+	Logs: []model.Log{
+		{Fields: model.KeyValues{model.String("event", "retry")}},
+	},
+}
+
+func mustPredicate(t *testing.T, field FieldKind, key string, op CompareOperator, value string) *Predicate {
+	p, err := NewPredicate(field, key, op, value)
+	require.NoError(t, err)
+	return p
+}
+
+func TestPredicateMatchSpan(t *testing.T) {
+	tests := []struct {
+		name  string
+		field FieldKind
+		key   string
+		op    CompareOperator
+		value string
+		match bool
+	}{
+		{"service equal", ServiceField, "", OpEqual, "checkout", true},
+		{"service not equal", ServiceField, "", OpNotEqual, "checkout", false},
+		{"service regex", ServiceField, "", OpRegex, "^check", true},
+		{"operation not regex", OperationField, "", OpNotRegex, "^POST", true},
+		{"duration greater", DurationField, "", OpGreater, "300ms", true},
+		{"duration less or equal", DurationField, "", OpLessOrEqual, "499ms", false},
+		{"int tag greater or equal", TagField, "http.status_code", OpGreaterOrEqual, "500", true},
+		{"int tag less", TagField, "http.status_code", OpLess, "500", false},
+		{"int tag equal", TagField, "http.status_code", OpEqual, "503", true},
+		{"float tag less", TagField, "ratio", OpLess, "0.5", true},
+		{"bool tag equal", TagField, "error", OpEqual, "true", true},
+		{"bool tag not equal", TagField, "error", OpNotEqual, "false", true},
+		{"string tag regex", TagField, "db.statement", OpRegex, "SELECT.*users", true},
+		{"process tag", TagField, "hostname", OpEqual, "host-1", true},
+		{"log field", TagField, "event", OpEqual, "retry", true},
+		{"missing tag equal", TagField, "missing", OpEqual, "x", false},
+		{"missing tag not equal", TagField, "missing", OpNotEqual, "x", true},
+		{"string tag ordering", TagField, "db.statement", OpGreater, "1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := mustPredicate(t, test.field, test.key, test.op, test.value)
+			assert.Equal(t, test.match, p.MatchSpan(exprTestSpan))
+		})
+	}
+}
+
+func TestNewPredicateErrors(t *testing.T) {
+	tests := []struct {
+		field FieldKind
+		key   string
+		op    CompareOperator
+		value string
+		err   string
+	}{
+		{ServiceField, "", OpGreater, "x", "operator > is not supported for service"},
+		{DurationField, "", OpRegex, "1s", "operator =~ is not supported for duration"},
+		{DurationField, "", OpGreater, "1", `invalid duration "1": time: missing unit in duration "1"`},
+		{TagField, "", OpEqual, "x", "tag key must not be empty"},
+		{TagField, "k", OpLess, "abc", `operator < requires a numeric value for span.k, got "abc"`},
+		{OperationField, "", OpRegex, "(", "invalid regular expression \"(\": error parsing regexp: missing closing ): `(`"},
+		{FieldKind(42), "", OpEqual, "x", "unknown field kind 42"},
+	}
+	for _, test := range tests {
+		_, err := NewPredicate(test.field, test.key, test.op, test.value)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestLogicalExprs(t *testing.T) {
+	service := mustPredicate(t, ServiceField, "", OpEqual, "checkout")
+	wrongService := mustPredicate(t, ServiceField, "", OpEqual, "payment")
+	slow := mustPredicate(t, DurationField, "", OpGreater, "1s")
+
+	assert.True(t, (&AndExpr{Operands: []Expr{service, &NotExpr{Operand: slow}}}).MatchSpan(exprTestSpan))
+	assert.False(t, (&AndExpr{Operands: []Expr{service, slow}}).MatchSpan(exprTestSpan))
+	assert.True(t, (&OrExpr{Operands: []Expr{wrongService, service}}).MatchSpan(exprTestSpan))
+	assert.False(t, (&OrExpr{Operands: []Expr{wrongService, slow}}).MatchSpan(exprTestSpan))
+
+	expr := &AndExpr{Operands: []Expr{
+		service,
+		&OrExpr{Operands: []Expr{wrongService, slow}},
+	}}
+	assert.Equal(t, `(service = "checkout") && ((service = "payment") || (duration > 1s))`, expr.String())
+	assert.Equal(t, `!(duration > 1s)`, (&NotExpr{Operand: slow}).String())
+}
+
+func TestConjuncts(t *testing.T) {
+	a := mustPredicate(t, ServiceField, "", OpEqual, "a")
+	b := mustPredicate(t, OperationField, "", OpEqual, "b")
+	c := mustPredicate(t, DurationField, "", OpGreater, "1s")
+	or := &OrExpr{Operands: []Expr{a, b}}
+
+	assert.Equal(t, []Expr{a}, Conjuncts(a))
+	assert.Equal(t, []Expr{or}, Conjuncts(or))
+	assert.Equal(t, []Expr{a, b, c}, Conjuncts(&AndExpr{Operands: []Expr{a, &AndExpr{Operands: []Expr{b, c}}}}))
+}
+
+func TestMatchTrace(t *testing.T) {
+	other := &model.Span{Process: &model.Process{ServiceName: "payment"}}
+	trace := &model.Trace{Spans: []*model.Span{other, exprTestSpan}}
+	assert.True(t, MatchTrace(mustPredicate(t, ServiceField, "", OpEqual, "checkout"), trace))
+	// both predicates are satisfied by the trace, but not by the same span
+	sameSpan := &AndExpr{Operands: []Expr{
+		mustPredicate(t, ServiceField, "", OpEqual, "payment"),
+		mustPredicate(t, TagField, "error", OpEqual, "true"),
+	}}
+	assert.False(t, MatchTrace(sameSpan, trace))
+}
+
+func TestCompareOperatorString(t *testing.T) {
+	assert.Equal(t, ">=", OpGreaterOrEqual.String())
+	assert.Equal(t, "CompareOperator(99)", CompareOperator(99).String())
+}
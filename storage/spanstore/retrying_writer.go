@@ -0,0 +1,240 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+	"github.com/kjschnei001/jaeger/pkg/metrics"
+)
+
+// retryingWriterMetrics keeps track of the retries and of the spans that could not be written.
+type retryingWriterMetrics struct {
+	Retries             metrics.Counter `metric:"retries"`
+	FailedNonRetryable  metrics.Counter `metric:"spans_failed" tags:"reason=non_retryable"`
+	FailedExhausted     metrics.Counter `metric:"spans_failed" tags:"reason=retries_exhausted"`
+	DeadLettered        metrics.Counter `metric:"spans_dead_lettered"`
+	DeadLetterErrors    metrics.Counter `metric:"dead_letter_errors"`
+	SucceededAfterRetry metrics.Counter `metric:"spans_succeeded_after_retry"`
+}
+
+// RetryOptions contains the options for constructing a RetryingWriter.
+type RetryOptions struct {
+	// InitialInterval is the wait before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the wait between two retries.
+	MaxInterval time.Duration
+	// Multiplier is applied to the wait after each retry.
+	Multiplier float64
+	// Jitter randomizes each wait by up to this fraction, between 0 and 1.
+	Jitter float64
+	// MaxElapsedTime is the time after which a span is given up on.
+	MaxElapsedTime time.Duration
+	// IsRetryable tells whether a write error is worth retrying, IsRetryableError is used when nil.
+	IsRetryable func(err error) bool
+	// DeadLetterWriter receives the spans given up on, they are dropped when nil.
+	DeadLetterWriter Writer
+	MetricsFactory   metrics.Factory
+}
+
+// RetryingWriter is a span Writer that retries the failed writes with an exponential backoff,
+// and hands the spans it gives up on to a dead-letter Writer.
+type RetryingWriter struct {
+	spanWriter Writer
+	options    RetryOptions
+	metrics    retryingWriterMetrics
+	timeNow    func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) error
+	random     func() float64
+	afterFunc  func(d time.Duration, f func())
+
+	mu sync.Mutex
+	// failedSpans are the spans being retried by RetryFailedSpan
+	failedSpans map[failedSpanKey]*failedSpan
+	// nextFailedSpansSweep is when the failed spans retried successfully are next removed
+	nextFailedSpansSweep time.Time
+}
+
+type failedSpanKey struct {
+	traceID model.TraceID
+	spanID  model.SpanID
+}
+
+// failedSpan is the backoff of a span retried by RetryFailedSpan.
+type failedSpan struct {
+	start    time.Time
+	interval time.Duration
+}
+
+// NewRetryingWriter creates a RetryingWriter.
+func NewRetryingWriter(spanWriter Writer, options RetryOptions) *RetryingWriter {
+	if options.IsRetryable == nil {
+		options.IsRetryable = IsRetryableError
+	}
+	if options.Multiplier < 1 {
+		options.Multiplier = 1
+	}
+	if options.MetricsFactory == nil {
+		options.MetricsFactory = metrics.NullFactory
+	}
+	w := &RetryingWriter{
+		spanWriter: spanWriter,
+		options:    options,
+		timeNow:    time.Now,
+		sleep:      sleepContext,
+		random:     rand.Float64,
+		afterFunc: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+		failedSpans: make(map[failedSpanKey]*failedSpan),
+	}
+	metrics.MustInit(&w.metrics, options.MetricsFactory, nil)
+	return w
+}
+
+// IsRetryableError is the default classification of write errors, which retries
+// all errors but the cancellation of the write.
+func IsRetryableError(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WriteSpan calls WriteSpan on the wrapped span writer until it succeeds, the error is not
+// retryable or MaxElapsedTime is reached. The last error is returned even when the span
+// was written to the dead-letter writer.
+func (w *RetryingWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	start := w.timeNow()
+	interval := w.options.InitialInterval
+	for retries := 0; ; retries++ {
+		err := w.spanWriter.WriteSpan(ctx, span)
+		if err == nil {
+			if retries > 0 {
+				w.metrics.SucceededAfterRetry.Inc(1)
+			}
+			return nil
+		}
+		if !w.options.IsRetryable(err) {
+			w.metrics.FailedNonRetryable.Inc(1)
+			return w.deadLetter(ctx, span, err)
+		}
+		wait := w.jitter(interval)
+		if w.timeNow().Sub(start)+wait > w.options.MaxElapsedTime {
+			w.metrics.FailedExhausted.Inc(1)
+			return w.deadLetter(ctx, span, fmt.Errorf("giving up after %d retries: %w", retries, err))
+		}
+		if sleepErr := w.sleep(ctx, wait); sleepErr != nil {
+			w.metrics.FailedExhausted.Inc(1)
+			return w.deadLetter(context.Background(), span, err)
+		}
+		w.metrics.Retries.Inc(1)
+		interval = w.nextInterval(interval)
+	}
+}
+
+// RetryFailedSpan retries a span the wrapped span writer failed to write asynchronously, i.e. after
+// its WriteSpan returned, such as a span indexed by a bulk processor. The span is written again
+// after the backoff, and its next failure is expected to be reported again, until MaxElapsedTime
+// has passed since the first failure.
+func (w *RetryingWriter) RetryFailedSpan(span *model.Span, err error) {
+	if !w.options.IsRetryable(err) {
+		w.metrics.FailedNonRetryable.Inc(1)
+		_ = w.deadLetter(context.Background(), span, err)
+		return
+	}
+	key := failedSpanKey{traceID: span.TraceID, spanID: span.SpanID}
+	now := w.timeNow()
+	w.mu.Lock()
+	w.removeRetriedSpans(now)
+	state, ok := w.failedSpans[key]
+	if !ok {
+		state = &failedSpan{start: now, interval: w.options.InitialInterval}
+		w.failedSpans[key] = state
+	}
+	wait := w.jitter(state.interval)
+	if now.Sub(state.start)+wait > w.options.MaxElapsedTime {
+		delete(w.failedSpans, key)
+		w.mu.Unlock()
+		w.metrics.FailedExhausted.Inc(1)
+		_ = w.deadLetter(context.Background(), span, err)
+		return
+	}
+	state.interval = w.nextInterval(state.interval)
+	w.mu.Unlock()
+	w.afterFunc(wait, func() {
+		w.metrics.Retries.Inc(1)
+		if err := w.spanWriter.WriteSpan(context.Background(), span); err != nil {
+			w.RetryFailedSpan(span, err)
+		}
+	})
+}
+
+// removeRetriedSpans removes the spans whose first failure is older than twice MaxElapsedTime, the
+// lock must be held. They were written by a retry, since the spans still failing after MaxElapsedTime
+// are given up on, and the failures of the retries are reported by then.
+func (w *RetryingWriter) removeRetriedSpans(now time.Time) {
+	if now.Before(w.nextFailedSpansSweep) {
+		return
+	}
+	for key, state := range w.failedSpans {
+		if now.Sub(state.start) > 2*w.options.MaxElapsedTime {
+			delete(w.failedSpans, key)
+		}
+	}
+	w.nextFailedSpansSweep = now.Add(w.options.MaxElapsedTime)
+}
+
+func (w *RetryingWriter) nextInterval(interval time.Duration) time.Duration {
+	interval = time.Duration(float64(interval) * w.options.Multiplier)
+	if w.options.MaxInterval > 0 && interval > w.options.MaxInterval {
+		interval = w.options.MaxInterval
+	}
+	return interval
+}
+
+func (w *RetryingWriter) jitter(interval time.Duration) time.Duration {
+	if w.options.Jitter <= 0 {
+		return interval
+	}
+	delta := w.options.Jitter * float64(interval)
+	return time.Duration(float64(interval) - delta + 2*delta*w.random())
+}
+
+func (w *RetryingWriter) deadLetter(ctx context.Context, span *model.Span, err error) error {
+	if w.options.DeadLetterWriter == nil {
+		return err
+	}
+	if dlErr := w.options.DeadLetterWriter.WriteSpan(ctx, span); dlErr != nil {
+		w.metrics.DeadLetterErrors.Inc(1)
+		return errors.Join(err, fmt.Errorf("failed to write span to dead-letter writer: %w", dlErr))
+	}
+	w.metrics.DeadLettered.Inc(1)
+	return err
+}
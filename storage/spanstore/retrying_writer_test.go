@@ -0,0 +1,214 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kjschnei001/jaeger/internal/metricstest"
+	"github.com/kjschnei001/jaeger/model"
+)
+
+var errNotRetryable = errors.New("mapping error")
+
+// flakyWriteSpanStore fails the first failures writes.
+type flakyWriteSpanStore struct {
+	failures int
+	err      error
+	writes   int
+}
+
+func (f *flakyWriteSpanStore) WriteSpan(ctx context.Context, span *model.Span) error {
+	f.writes++
+	if f.writes <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+type retryingWriterTest struct {
+	writer     *RetryingWriter
+	metrics    *metricstest.Factory
+	deadLetter *flakyWriteSpanStore
+	now        time.Time
+	waits      []time.Duration
+}
+
+func newRetryingWriterTest(spanWriter Writer) *retryingWriterTest {
+	test := &retryingWriterTest{
+		metrics:    metricstest.NewFactory(0),
+		deadLetter: &flakyWriteSpanStore{},
+		now:        time.Unix(0, 0),
+	}
+	test.writer = NewRetryingWriter(spanWriter, RetryOptions{
+		InitialInterval: time.Second,
+		MaxInterval:     3 * time.Second,
+		Multiplier:      2,
+		MaxElapsedTime:  10 * time.Second,
+		IsRetryable: func(err error) bool {
+			return !errors.Is(err, errNotRetryable)
+		},
+		DeadLetterWriter: test.deadLetter,
+		MetricsFactory:   test.metrics,
+	})
+	test.writer.timeNow = func() time.Time { return test.now }
+	test.writer.sleep = func(ctx context.Context, d time.Duration) error {
+		test.waits = append(test.waits, d)
+		test.now = test.now.Add(d)
+		return ctx.Err()
+	}
+	test.writer.afterFunc = func(d time.Duration, f func()) {
+		test.waits = append(test.waits, d)
+		test.now = test.now.Add(d)
+		f()
+	}
+	return test
+}
+
+func TestRetryingWriterSucceedsAfterRetries(t *testing.T) {
+	spanWriter := &flakyWriteSpanStore{failures: 3, err: errIWillAlwaysFail}
+	test := newRetryingWriterTest(spanWriter)
+
+	assert.NoError(t, test.writer.WriteSpan(context.Background(), &model.Span{}))
+	assert.Equal(t, 4, spanWriter.writes)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, test.waits)
+	assert.Equal(t, 0, test.deadLetter.writes)
+	test.metrics.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "retries", Value: 3},
+		metricstest.ExpectedMetric{Name: "spans_succeeded_after_retry", Value: 1},
+	)
+}
+
+func TestRetryingWriterGivesUp(t *testing.T) {
+	spanWriter := &flakyWriteSpanStore{failures: 100, err: errIWillAlwaysFail}
+	test := newRetryingWriterTest(spanWriter)
+
+	err := test.writer.WriteSpan(context.Background(), &model.Span{})
+	assert.EqualError(t, err, "giving up after 4 retries: "+errIWillAlwaysFail.Error())
+	// waits of 1s, 2s, 3s and 3s, another wait would exceed 10s
+	assert.Equal(t, 5, spanWriter.writes)
+	assert.Equal(t, 1, test.deadLetter.writes)
+	test.metrics.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "retries", Value: 4},
+		metricstest.ExpectedMetric{Name: "spans_failed", Tags: map[string]string{"reason": "retries_exhausted"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_dead_lettered", Value: 1},
+	)
+}
+
+func TestRetryingWriterNonRetryable(t *testing.T) {
+	spanWriter := &flakyWriteSpanStore{failures: 1, err: errNotRetryable}
+	test := newRetryingWriterTest(spanWriter)
+	test.deadLetter.failures = 1
+	test.deadLetter.err = errIWillAlwaysFail
+
+	err := test.writer.WriteSpan(context.Background(), &model.Span{})
+	assert.ErrorIs(t, err, errNotRetryable)
+	assert.ErrorIs(t, err, errIWillAlwaysFail)
+	assert.Equal(t, 1, spanWriter.writes)
+	assert.Empty(t, test.waits)
+	test.metrics.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_failed", Tags: map[string]string{"reason": "non_retryable"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "dead_letter_errors", Value: 1},
+	)
+}
+
+func TestRetryingWriterRetryFailedSpan(t *testing.T) {
+	spanWriter := &flakyWriteSpanStore{}
+	test := newRetryingWriterTest(spanWriter)
+	span := &model.Span{TraceID: model.NewTraceID(0, 1), SpanID: model.NewSpanID(1)}
+
+	// each retry is written successfully, then its failure is reported asynchronously
+	for i := 0; i < 5; i++ {
+		test.writer.RetryFailedSpan(span, errIWillAlwaysFail)
+	}
+	// waits of 1s, 2s, 3s and 3s, another wait would exceed 10s
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}, test.waits)
+	assert.Equal(t, 4, spanWriter.writes)
+	assert.Equal(t, 1, test.deadLetter.writes)
+	assert.Empty(t, test.writer.failedSpans)
+
+	test.writer.RetryFailedSpan(&model.Span{}, errNotRetryable)
+	assert.Equal(t, 4, spanWriter.writes)
+	assert.Equal(t, 2, test.deadLetter.writes)
+	test.metrics.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "retries", Value: 4},
+		metricstest.ExpectedMetric{Name: "spans_failed", Tags: map[string]string{"reason": "retries_exhausted"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_failed", Tags: map[string]string{"reason": "non_retryable"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_dead_lettered", Value: 2},
+	)
+}
+
+func TestRetryingWriterRetryFailedSpanWriteError(t *testing.T) {
+	spanWriter := &flakyWriteSpanStore{failures: 1, err: errIWillAlwaysFail}
+	test := newRetryingWriterTest(spanWriter)
+
+	test.writer.RetryFailedSpan(&model.Span{}, errIWillAlwaysFail)
+	// the retry failed synchronously and was retried again
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, test.waits)
+	assert.Equal(t, 2, spanWriter.writes)
+	assert.Equal(t, 0, test.deadLetter.writes)
+}
+
+func TestRetryingWriterRemovesRetriedSpans(t *testing.T) {
+	test := newRetryingWriterTest(&flakyWriteSpanStore{})
+	test.writer.RetryFailedSpan(&model.Span{SpanID: model.NewSpanID(1)}, errIWillAlwaysFail)
+	assert.Len(t, test.writer.failedSpans, 1)
+
+	test.now = test.now.Add(18 * time.Second)
+	test.writer.RetryFailedSpan(&model.Span{SpanID: model.NewSpanID(2)}, errIWillAlwaysFail)
+	assert.Len(t, test.writer.failedSpans, 2)
+	test.now = test.now.Add(10 * time.Second)
+	test.writer.RetryFailedSpan(&model.Span{SpanID: model.NewSpanID(3)}, errIWillAlwaysFail)
+	// the first span was written more than twice MaxElapsedTime ago
+	assert.Len(t, test.writer.failedSpans, 2)
+	assert.NotContains(t, test.writer.failedSpans, failedSpanKey{spanID: model.NewSpanID(1)})
+}
+
+func TestRetryingWriterCanceled(t *testing.T) {
+	spanWriter := &flakyWriteSpanStore{failures: 100, err: errIWillAlwaysFail}
+	test := newRetryingWriterTest(spanWriter)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, test.writer.WriteSpan(ctx, &model.Span{}), errIWillAlwaysFail)
+	assert.Equal(t, 1, spanWriter.writes)
+	assert.Equal(t, 1, test.deadLetter.writes)
+}
+
+func TestRetryingWriterJitter(t *testing.T) {
+	w := NewRetryingWriter(&noopWriteSpanStore{}, RetryOptions{Jitter: 0.5})
+	w.random = func() float64 { return 0 }
+	assert.Equal(t, 50*time.Millisecond, w.jitter(100*time.Millisecond))
+	w.random = func() float64 { return 1 }
+	assert.Equal(t, 150*time.Millisecond, w.jitter(100*time.Millisecond))
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, IsRetryableError(errIWillAlwaysFail))
+	assert.True(t, IsRetryableError(context.DeadlineExceeded))
+	assert.False(t, IsRetryableError(context.Canceled))
+}
+
+func TestSleepContext(t *testing.T) {
+	assert.NoError(t, sleepContext(context.Background(), time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sleepContext(ctx, time.Hour), context.Canceled)
+}
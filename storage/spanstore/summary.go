@@ -0,0 +1,109 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// TraceSummary is an overview of a trace that does not contain its spans.
+type TraceSummary struct {
+	TraceID model.TraceID
+	// RootServiceName and RootOperationName are those of the earliest span
+	// whose parent is not in the trace.
+	RootServiceName   string
+	RootOperationName string
+	// StartTime and Duration cover all the spans of the trace.
+	StartTime         time.Time
+	Duration          time.Duration
+	SpanCount         int
+	ServiceSpanCounts map[string]int
+	// ErrorCount is the number of spans with an error tag set to true.
+	ErrorCount int
+}
+
+// SummaryReader is an optional interface implemented by Readers that can
+// summarize the traces matching a query without loading them in full.
+type SummaryReader interface {
+	// FindTraceSummaries returns the summaries of the traces FindTraces would return.
+	FindTraceSummaries(ctx context.Context, query *TraceQueryParameters) ([]*TraceSummary, error)
+}
+
+// FindTraceSummaries calls reader.FindTraceSummaries if the reader implements
+// SummaryReader, otherwise it summarizes the results of reader.FindTraces.
+func FindTraceSummaries(ctx context.Context, reader Reader, query *TraceQueryParameters) ([]*TraceSummary, error) {
+	if summaryReader, ok := reader.(SummaryReader); ok {
+		return summaryReader.FindTraceSummaries(ctx, query)
+	}
+	traces, err := reader.FindTraces(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeTraces(traces), nil
+}
+
+// SummarizeTraces returns the summaries of the traces, in the same order.
+func SummarizeTraces(traces []*model.Trace) []*TraceSummary {
+	summaries := make([]*TraceSummary, len(traces))
+	for i, trace := range traces {
+		summaries[i] = SummarizeTrace(trace)
+	}
+	return summaries
+}
+
+// SummarizeTrace returns the summary of a trace with at least one span.
+func SummarizeTrace(trace *model.Trace) *TraceSummary {
+	spanIDs := make(map[model.SpanID]struct{}, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spanIDs[span.SpanID] = struct{}{}
+	}
+	summary := &TraceSummary{ServiceSpanCounts: make(map[string]int)}
+	var root *model.Span
+	var endTime time.Time
+	for _, span := range trace.Spans {
+		summary.TraceID = span.TraceID
+		summary.SpanCount++
+		if span.Process != nil {
+			summary.ServiceSpanCounts[span.Process.ServiceName]++
+		}
+		if span.IsError() {
+			summary.ErrorCount++
+		}
+		if summary.StartTime.IsZero() || span.StartTime.Before(summary.StartTime) {
+			summary.StartTime = span.StartTime
+		}
+		if end := span.StartTime.Add(span.Duration); end.After(endTime) {
+			endTime = end
+		}
+		parentID := span.ParentSpanID()
+		if _, ok := spanIDs[parentID]; ok && parentID != span.SpanID {
+			continue
+		}
+		if root == nil || span.StartTime.Before(root.StartTime) {
+			root = span
+		}
+	}
+	if root != nil {
+		summary.RootOperationName = root.OperationName
+		if root.Process != nil {
+			summary.RootServiceName = root.Process.ServiceName
+		}
+	}
+	summary.Duration = endTime.Sub(summary.StartTime)
+	return summary
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	. "github.com/kjschnei001/jaeger/storage/spanstore"
+	"github.com/kjschnei001/jaeger/storage/spanstore/mocks"
+)
+
+type summaryReader struct {
+	*mocks.Reader
+	summaries []*TraceSummary
+}
+
+func (r summaryReader) FindTraceSummaries(ctx context.Context, query *TraceQueryParameters) ([]*TraceSummary, error) {
+	return r.summaries, nil
+}
+
+func TestSummarizeTrace(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	start := time.Unix(100, 0)
+	trace := &model.Trace{Spans: []*model.Span{
+		{
+			TraceID:       traceID,
+			SpanID:        model.NewSpanID(2),
+			OperationName: "query",
+			References:    []model.SpanRef{model.NewChildOfRef(traceID, model.NewSpanID(1))},
+			Process:       &model.Process{ServiceName: "backend"},
+			StartTime:     start.Add(time.Second),
+			Duration:      5 * time.Second,
+			Tags:          model.KeyValues{model.Bool("error", true)},
+		},
+		{
+			TraceID:       traceID,
+			SpanID:        model.NewSpanID(1),
+			OperationName: "GET /",
+			Process:       &model.Process{ServiceName: "frontend"},
+			StartTime:     start,
+			Duration:      3 * time.Second,
+		},
+		{
+			// parent not in the trace, but later than the root
+			TraceID:       traceID,
+			SpanID:        model.NewSpanID(3),
+			OperationName: "query",
+			References:    []model.SpanRef{model.NewChildOfRef(traceID, model.NewSpanID(9))},
+			Process:       &model.Process{ServiceName: "backend"},
+			StartTime:     start.Add(2 * time.Second),
+			Duration:      time.Second,
+		},
+	}}
+
+	assert.Equal(t, &TraceSummary{
+		TraceID:           traceID,
+		RootServiceName:   "frontend",
+		RootOperationName: "GET /",
+		StartTime:         start,
+		Duration:          6 * time.Second,
+		SpanCount:         3,
+		ServiceSpanCounts: map[string]int{"frontend": 1, "backend": 2},
+		ErrorCount:        1,
+	}, SummarizeTrace(trace))
+}
+
+func TestFindTraceSummaries(t *testing.T) {
+	traces := []*model.Trace{{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 1), Process: &model.Process{ServiceName: "service"}}}}}
+
+	reader := &mocks.Reader{}
+	reader.On("FindTraces", mock.Anything, mock.Anything).Return(traces, nil).Once()
+	summaries, err := FindTraceSummaries(context.Background(), reader, &TraceQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, SummarizeTraces(traces), summaries)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, map[string]int{"service": 1}, summaries[0].ServiceSpanCounts)
+
+	reader.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errors.New("storage error")).Once()
+	_, err = FindTraceSummaries(context.Background(), reader, &TraceQueryParameters{})
+	assert.EqualError(t, err, "storage error")
+
+	expected := []*TraceSummary{{TraceID: model.NewTraceID(0, 2)}}
+	summaries, err = FindTraceSummaries(context.Background(), summaryReader{Reader: reader, summaries: expected}, &TraceQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, expected, summaries)
+}
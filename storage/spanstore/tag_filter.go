@@ -0,0 +1,119 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+var (
+	// ErrUnsupportedTagFilter is returned by readers that cannot evaluate the operator of a tag filter.
+	ErrUnsupportedTagFilter = errors.New("tag filter operator is not supported by this storage backend")
+
+	// ErrUnsatisfiableTagFilters is returned by FoldEqualityTagFilters when a single span would need
+	// different values of the same tag. Readers return no traces for such queries.
+	ErrUnsatisfiableTagFilters = errors.New("the tags and tag filters require different values of the same tag")
+)
+
+// tagFilterOperators lists the operators accepted by ParseTagFilter,
+// longest first so that e.g. '>=' is not parsed as '>'.
+var tagFilterOperators = []struct {
+	symbol string
+	op     CompareOperator
+}{
+	{"==", OpEqual},
+	{"!=", OpNotEqual},
+	{">=", OpGreaterOrEqual},
+	{"<=", OpLessOrEqual},
+	{"=~", OpRegex},
+	{"!~", OpNotRegex},
+	{"^=", OpPrefix},
+	{"=", OpEqual},
+	{">", OpGreater},
+	{"<", OpLess},
+	{"~", OpRegex},
+}
+
+// NewTagFilter returns a Predicate comparing the value of tag key with value.
+func NewTagFilter(key string, op CompareOperator, value string) (*Predicate, error) {
+	return NewPredicate(TagField, key, op, value)
+}
+
+// ParseTagFilter parses a tag filter such as `http.status_code>=500`,
+// `db.statement~"SELECT.*users"` or `error!=false`. The value may be
+// enclosed in double quotes.
+func ParseTagFilter(filter string) (*Predicate, error) {
+	i := strings.IndexAny(filter, "=!<>~^")
+	if i <= 0 {
+		return nil, fmt.Errorf("malformed tag filter, expecting key, operator and value, received: %s", filter)
+	}
+	key, rest := filter[:i], filter[i:]
+	for _, candidate := range tagFilterOperators {
+		if !strings.HasPrefix(rest, candidate.symbol) {
+			continue
+		}
+		value := strings.TrimPrefix(rest, candidate.symbol)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		return NewTagFilter(key, candidate.op, value)
+	}
+	return nil, fmt.Errorf("malformed tag filter, unknown operator in: %s", filter)
+}
+
+// MatchTagFilters reports whether the span satisfies all of the tag filters.
+func MatchTagFilters(span *model.Span, filters []*Predicate) bool {
+	for _, f := range filters {
+		if !f.MatchSpan(span) {
+			return false
+		}
+	}
+	return true
+}
+
+// FoldEqualityTagFilters returns a copy of the query in which equality tag filters
+// are merged into Tags, for readers that only support exact tag matches. It returns
+// an error wrapping ErrUnsupportedTagFilter if any other operator is used. Tags cannot
+// hold two values of the same tag: with TagMatchSameSpan such a query matches no span
+// and ErrUnsatisfiableTagFilters is returned, otherwise the tag filter is unsupported.
+func FoldEqualityTagFilters(query *TraceQueryParameters) (*TraceQueryParameters, error) {
+	if query == nil || len(query.TagFilters) == 0 {
+		return query, nil
+	}
+	folded := *query
+	folded.TagFilters = nil
+	folded.Tags = make(map[string]string, len(query.Tags)+len(query.TagFilters))
+	for k, v := range query.Tags {
+		folded.Tags[k] = v
+	}
+	for _, tagFilter := range query.TagFilters {
+		if tagFilter.Operator != OpEqual {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedTagFilter, tagFilter)
+		}
+		if value, ok := folded.Tags[tagFilter.Key]; ok && value != tagFilter.Value {
+			if query.TagMatchMode == TagMatchSameSpan {
+				return nil, fmt.Errorf("%w: %s", ErrUnsatisfiableTagFilters, tagFilter)
+			}
+			return nil, fmt.Errorf("%w: %s with another value of the same tag", ErrUnsupportedTagFilter, tagFilter)
+		}
+		folded.Tags[tagFilter.Key] = tagFilter.Value
+	}
+	return &folded, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+func TestParseTagFilter(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{`http.status_code>=500`, `span.http.status_code >= "500"`},
+		{`http.status_code<500`, `span.http.status_code < "500"`},
+		{`error!=false`, `span.error != "false"`},
+		{`error=true`, `span.error = "true"`},
+		{`error==true`, `span.error = "true"`},
+		{`db.statement~"SELECT.*"`, `span.db.statement =~ "SELECT.*"`},
+		{`db.statement!~^INSERT`, `span.db.statement !~ "^INSERT"`},
+		{`http.url^=https://`, `span.http.url ^= "https://"`},
+		{`peer.service="a b"`, `span.peer.service = "a b"`},
+	}
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			tagFilter, err := ParseTagFilter(test.filter)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, tagFilter.String())
+		})
+	}
+}
+
+func TestParseTagFilterErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		err    string
+	}{
+		{`error`, `malformed tag filter, expecting key, operator and value, received: error`},
+		{`=x`, `malformed tag filter, expecting key, operator and value, received: =x`},
+		{`k^x`, `malformed tag filter, unknown operator in: k^x`},
+		{`k>x`, `operator > requires a numeric value for span.k, got "x"`},
+	}
+	for _, test := range tests {
+		_, err := ParseTagFilter(test.filter)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestMatchTagFilters(t *testing.T) {
+	serverError := mustPredicate(t, TagField, "http.status_code", OpGreaterOrEqual, "500")
+	selectStmt := mustPredicate(t, TagField, "db.statement", OpPrefix, "SELECT")
+	insertStmt := mustPredicate(t, TagField, "db.statement", OpPrefix, "INSERT")
+	assert.True(t, MatchTagFilters(exprTestSpan, nil))
+	assert.True(t, MatchTagFilters(exprTestSpan, []*Predicate{serverError, selectStmt}))
+	assert.False(t, MatchTagFilters(exprTestSpan, []*Predicate{serverError, insertStmt}))
+}
+
+func TestFoldEqualityTagFilters(t *testing.T) {
+	query := &TraceQueryParameters{
+		ServiceName: "svc",
+		Tags:        map[string]string{"a": "1"},
+		TagFilters:  []*Predicate{mustPredicate(t, TagField, "b", OpEqual, "2")},
+	}
+	folded, err := FoldEqualityTagFilters(query)
+	require.NoError(t, err)
+	assert.Equal(t, &TraceQueryParameters{
+		ServiceName: "svc",
+		Tags:        map[string]string{"a": "1", "b": "2"},
+	}, folded)
+	assert.Equal(t, map[string]string{"a": "1"}, query.Tags, "original query must not be modified")
+
+	noFilters := &TraceQueryParameters{ServiceName: "svc"}
+	folded, err = FoldEqualityTagFilters(noFilters)
+	require.NoError(t, err)
+	assert.Same(t, noFilters, folded)
+
+	// the same value of a tag can be required twice, but not different values
+	query.TagFilters = append(query.TagFilters, mustPredicate(t, TagField, "a", OpEqual, "1"))
+	folded, err = FoldEqualityTagFilters(query)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, folded.Tags)
+	conflict := append(query.TagFilters, mustPredicate(t, TagField, "b", OpEqual, "3"))
+	_, err = FoldEqualityTagFilters(&TraceQueryParameters{TagFilters: conflict})
+	assert.ErrorIs(t, err, ErrUnsatisfiableTagFilters)
+	_, err = FoldEqualityTagFilters(&TraceQueryParameters{TagFilters: conflict, TagMatchMode: TagMatchAnySpanInTrace})
+	assert.ErrorIs(t, err, ErrUnsupportedTagFilter)
+
+	query.TagFilters = append(query.TagFilters, mustPredicate(t, TagField, "c", OpGreater, "3"))
+	_, err = FoldEqualityTagFilters(query)
+	assert.ErrorIs(t, err, ErrUnsupportedTagFilter)
+	assert.EqualError(t, err, `tag filter operator is not supported by this storage backend: span.c > "3"`)
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kjschnei001/jaeger/model"
+)
+
+// ErrUnsupportedTagMatchMode is returned by readers that cannot evaluate the TagMatchMode of a query.
+var ErrUnsupportedTagMatchMode = errors.New("tag match mode is not supported by this storage backend")
+
+// TagMatchMode defines how the tags and tag filters of a query are matched against the spans of a trace.
+// In both modes only spans of the queried service are considered.
+type TagMatchMode int
+
+const (
+	// TagMatchSameSpan requires a single span to satisfy all tags and tag filters.
+	TagMatchSameSpan TagMatchMode = iota
+	// TagMatchAnySpanInTrace allows each tag and tag filter to be satisfied by a different span.
+	TagMatchAnySpanInTrace
+)
+
+const (
+	tagMatchSameSpanName       = "SameSpan"
+	tagMatchAnySpanInTraceName = "AnySpanInTrace"
+)
+
+func (m TagMatchMode) String() string {
+	switch m {
+	case TagMatchSameSpan:
+		return tagMatchSameSpanName
+	case TagMatchAnySpanInTrace:
+		return tagMatchAnySpanInTraceName
+	}
+	return fmt.Sprintf("TagMatchMode(%d)", int(m))
+}
+
+// ParseTagMatchMode converts a string to a TagMatchMode. The empty string is TagMatchSameSpan.
+func ParseTagMatchMode(s string) (TagMatchMode, error) {
+	switch s {
+	case "", tagMatchSameSpanName:
+		return TagMatchSameSpan, nil
+	case tagMatchAnySpanInTraceName:
+		return TagMatchAnySpanInTrace, nil
+	}
+	return TagMatchSameSpan, fmt.Errorf("unknown tag match mode %q, expecting %s or %s", s, tagMatchSameSpanName, tagMatchAnySpanInTraceName)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m TagMatchMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *TagMatchMode) UnmarshalText(text []byte) error {
+	mode, err := ParseTagMatchMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// TagConditionCount returns the number of tags and tag filters in the query.
+// Readers only need to care about TagMatchMode when it is greater than one.
+func (p *TraceQueryParameters) TagConditionCount() int {
+	return len(p.Tags) + len(p.TagFilters)
+}
+
+// MatchSpanTags reports whether the span has all of the tags, in its own tags,
+// process tags or log fields, and satisfies all of the tag filters.
+func MatchSpanTags(span *model.Span, tags map[string]string, filters []*Predicate) bool {
+	for k, v := range tags {
+		if !hasTag(span, k, v) {
+			return false
+		}
+	}
+	return MatchTagFilters(span, filters)
+}
+
+// MatchTraceTags reports whether the spans of the trace that belong to the queried
+// service satisfy the tags and tag filters of the query according to its TagMatchMode.
+func MatchTraceTags(trace *model.Trace, query *TraceQueryParameters) bool {
+	var spans []*model.Span
+	for _, span := range trace.Spans {
+		if query.ServiceName == "" || (span.Process != nil && span.Process.ServiceName == query.ServiceName) {
+			spans = append(spans, span)
+		}
+	}
+	if query.TagMatchMode != TagMatchAnySpanInTrace {
+		for _, span := range spans {
+			if MatchSpanTags(span, query.Tags, query.TagFilters) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(spans) == 0 {
+		return false
+	}
+	for k, v := range query.Tags {
+		if !anySpan(spans, func(span *model.Span) bool { return hasTag(span, k, v) }) {
+			return false
+		}
+	}
+	for _, f := range query.TagFilters {
+		if !anySpan(spans, f.MatchSpan) {
+			return false
+		}
+	}
+	return true
+}
+
+func anySpan(spans []*model.Span, match func(*model.Span) bool) bool {
+	for _, span := range spans {
+		if match(span) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTag(span *model.Span, key, value string) bool {
+	// (NB): KeyValues.FindKey is not used because there can be multiple tags with the same key
+	match := func(kvs model.KeyValues) bool {
+		for i := range kvs {
+			if kvs[i].Key == key && kvs[i].AsString() == value {
+				return true
+			}
+		}
+		return false
+	}
+	if match(span.Tags) || (span.Process != nil && match(span.Process.Tags)) {
+		return true
+	}
+	for _, log := range span.Logs {
+		if match(log.Fields) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kjschnei001/jaeger/model"
+	. "github.com/kjschnei001/jaeger/storage/spanstore"
+)
+
+func TestParseTagMatchMode(t *testing.T) {
+	for _, mode := range []TagMatchMode{TagMatchSameSpan, TagMatchAnySpanInTrace} {
+		parsed, err := ParseTagMatchMode(mode.String())
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	parsed, err := ParseTagMatchMode("")
+	require.NoError(t, err)
+	assert.Equal(t, TagMatchSameSpan, parsed)
+
+	_, err = ParseTagMatchMode("sometimes")
+	assert.EqualError(t, err, `unknown tag match mode "sometimes", expecting SameSpan or AnySpanInTrace`)
+	assert.Equal(t, "TagMatchMode(7)", TagMatchMode(7).String())
+}
+
+func TestTagMatchModeJSON(t *testing.T) {
+	var query TraceQueryParameters
+	require.NoError(t, json.Unmarshal([]byte(`{"TagMatchMode": "AnySpanInTrace"}`), &query))
+	assert.Equal(t, TagMatchAnySpanInTrace, query.TagMatchMode)
+
+	out, err := json.Marshal(TagMatchAnySpanInTrace)
+	require.NoError(t, err)
+	assert.Equal(t, `"AnySpanInTrace"`, string(out))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"TagMatchMode": "sometimes"}`), &query))
+}
+
+func TestMatchTraceTags(t *testing.T) {
+	process := &model.Process{ServiceName: "svc"}
+	trace := &model.Trace{Spans: []*model.Span{
+		{Process: process, Tags: model.KeyValues{model.String("a", "1"), model.Int64("code", 503)}},
+		{Process: process, Logs: []model.Log{{Fields: model.KeyValues{model.String("b", "2")}}}},
+		{Process: &model.Process{ServiceName: "other"}, Tags: model.KeyValues{model.String("c", "3")}},
+	}}
+	serverError := mustPredicate(t, TagField, "code", OpGreaterOrEqual, "500")
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		filters  []*Predicate
+		sameSpan bool
+		anySpan  bool
+	}{
+		{"single tag", map[string]string{"a": "1"}, nil, true, true},
+		{"tags on the same span", map[string]string{"a": "1", "code": "503"}, nil, true, true},
+		{"tags on different spans", map[string]string{"a": "1", "b": "2"}, nil, false, true},
+		{"tag filter on another span", map[string]string{"b": "2"}, []*Predicate{serverError}, false, true},
+		{"tag of another service", map[string]string{"a": "1", "c": "3"}, nil, false, false},
+		{"missing tag", map[string]string{"a": "2"}, nil, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := &TraceQueryParameters{ServiceName: "svc", Tags: test.tags, TagFilters: test.filters}
+			assert.Equal(t, test.sameSpan, MatchTraceTags(trace, query), "SameSpan")
+			query.TagMatchMode = TagMatchAnySpanInTrace
+			assert.Equal(t, test.anySpan, MatchTraceTags(trace, query), "AnySpanInTrace")
+		})
+	}
+}